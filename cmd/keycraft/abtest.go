@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// abtestFlags returns the flags of the abtest command.
+func abtestFlags() []cli.Flag {
+	common := commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition", "baseline",
+		"load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties",
+		"roll-quality", "fatigue", "strict-targets", "weights-file", "weights", "reference-glob", "reference-list")
+	return append(append(common, corpusBuildFlags()...),
+		&cli.IntFlag{
+			Name:    "folds",
+			Aliases: []string{"k"},
+			Usage:   "Number of parts to split the corpus into (2 = in half).",
+			Value:   2,
+			Action: func(ctx context.Context, c *cli.Command, value int) error {
+				if value < 2 {
+					return fmt.Errorf("--folds must be at least 2 (got %d)", value)
+				}
+				return nil
+			},
+		},
+		generationFlags("seed")[0],
+	)
+}
+
+// abtestCommand defines the CLI command for testing whether a layout beats another across
+// splits of the corpus.
+var abtestCommand = &cli.Command{
+	Name:  "abtest",
+	Usage: "Check whether layout A beats layout B consistently across splits of the corpus",
+	Description: "Splits the corpus into --folds parts at random, scores both layouts on the whole corpus " +
+		"and on each part like rank does, and reports per weighted metric on how many parts A is better " +
+		"or worse than B. A layout that wins on every part is robustly better for this corpus; mixed " +
+		"results mean the difference is within the noise of the corpus.",
+	Flags:         abtestFlags(),
+	ArgsUsage:     "<layoutA> <layoutB>",
+	Action:        abtestAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// abtestAction compares two layouts across the folds of the corpus.
+func abtestAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildABTestInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	printSeed(input.Seed)
+
+	result, err := kc.RunABTest(input)
+	if err != nil {
+		return fmt.Errorf("could not compare layouts: %w", err)
+	}
+	tui.RenderABTest(result, input.Corpus.Name)
+	return nil
+}
+
+// buildABTestInput loads the two layouts of the arguments, and the corpus, targets, weights
+// and reference layouts of the flags.
+func buildABTestInput(c *cli.Command) (kc.ABTestInput, error) {
+	if c.NArg() != 2 {
+		return kc.ABTestInput{}, fmt.Errorf("expected 2 layouts, got %d", c.NArg())
+	}
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.ABTestInput{}, err
+	}
+	a, err := loadLayout(c.Args().Get(0))
+	if err != nil {
+		return kc.ABTestInput{}, err
+	}
+	b, err := loadLayout(c.Args().Get(1))
+	if err != nil {
+		return kc.ABTestInput{}, err
+	}
+
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.ABTestInput{}, fmt.Errorf("could not load corpus: %w", err)
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.ABTestInput{}, fmt.Errorf("could not load target loads: %w", err)
+	}
+	weights, err := loadWeightsFromFlags(c)
+	if err != nil {
+		return kc.ABTestInput{}, fmt.Errorf("could not load weights: %w", err)
+	}
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.ABTestInput{}, fmt.Errorf("could not load reference layouts: %w", err)
+	}
+	seed, err := kc.ParseSeed(c.String("seed"))
+	if err != nil {
+		return kc.ABTestInput{}, err
+	}
+
+	return kc.ABTestInput{
+		LayoutsDir: layoutDir,
+		Reference:  reference,
+		A:          a,
+		B:          b,
+		Corpus:     corpus,
+		Targets:    targets,
+		Weights:    weights,
+		Folds:      int(c.Int("folds")),
+		Seed:       seed,
+	}, nil
+}
@@ -3,6 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	kc "github.com/rbscholtus/keycraft/internal/keycraft"
 	"github.com/rbscholtus/keycraft/internal/tui"
@@ -33,6 +38,12 @@ var analyseFlags = []cli.Flag{
 		Value:    false,
 		Category: "Display",
 	},
+	&cli.BoolFlag{
+		Name:     "per-key",
+		Usage:    "Show boards with each key's share of load, SFB, LSB and scissors (in % of the corpus).",
+		Value:    false,
+		Category: "Display",
+	},
 	&cli.IntFlag{
 		Name:     "trigram-rows",
 		Usage:    "Maximum number of trigrams to display in trigram table.",
@@ -48,11 +59,85 @@ var analyseFlags = []cli.Flag{
 			return nil
 		},
 	},
+	&cli.StringFlag{
+		Name: "sections",
+		Usage: "Comma-separated sections of the report to show: " + strings.Join(kc.AnalyseSections, ", ") +
+			", or the groups " + strings.Join(slices.Sorted(maps.Keys(kc.AnalyseSectionGroups)), ", ") +
+			". Selecting per-key implies --per-key. Default: all sections.",
+		Category: "Display",
+	},
+	&cli.IntFlag{
+		Name:     "page",
+		Usage:    "Page of the detail, unsupported and trigram tables to show, of --rows or --trigram-rows rows each.",
+		Value:    1,
+		Category: "Display",
+		Action: func(ctx context.Context, c *cli.Command, value int) error {
+			if isShellCompletion() {
+				return nil
+			}
+			if value < 1 {
+				return fmt.Errorf("--page must be at least 1 (got %d)", value)
+			}
+			return nil
+		},
+	},
+}
+
+// detailsOutputFlag writes the metric details tables to CSV or JSON files.
+var detailsOutputFlag = &cli.StringFlag{
+	Name: "details-output",
+	Usage: "Also write each metric details table (SFB, LSB, ..., ALT, 2RL, 3RL, RED) of each layout to its " +
+		"own CSV or JSON file, with all n-grams and columns and without rounding: csv:<dir> or json:<dir>. " +
+		"Tables left out by --sections are not written.",
+	Category: "Output",
+}
+
+// vsRandomFlag compares the analysed layouts with random layouts.
+var vsRandomFlag = &cli.IntFlag{
+	Name: "vs-random",
+	Usage: "Compare each layout with this many random layouts with the same keys and characters, and report " +
+		"the percentage of them it beats, using the weights and reference layouts of rank (0 = off). The " +
+		"scores of the random layouts are cached per corpus and weights.",
+	Category: "Display",
+	Action: func(ctx context.Context, c *cli.Command, value int) error {
+		if value < 0 {
+			return fmt.Errorf("--vs-random must not be negative (got %d)", value)
+		}
+		return nil
+	},
+}
+
+// legendFlag adds the legend of the finger, column and row names to the analysis.
+var legendFlag = &cli.BoolFlag{
+	Name:     "legend",
+	Usage:    "Show the legend of the finger (F0-F9), column (C0-C11) and row (R0-R3) names after the analysis, as the legend command does.",
+	Category: "Display",
+}
+
+// focusKeyFlag narrows the analysis down to the n-grams of one character.
+var focusKeyFlag = &cli.StringFlag{
+	Name: "focus-key",
+	Usage: "Show only the n-grams with this character (or \"space\") in the detail and trigram tables, and " +
+		"its role: its SFB, LSB and scissor partners, and the directions of its rolls. Also applies to --details-output.",
+	Category: "Display",
+	Action: func(ctx context.Context, c *cli.Command, value string) error {
+		if isShellCompletion() {
+			return nil
+		}
+		_, err := kc.ParseFocusKey(value)
+		return err
+	},
 }
 
+// randomBaselineSeed is the seed of the random layouts of --vs-random. It is fixed, so that
+// a cached baseline is used for every run.
+const randomBaselineSeed = 1
+
 // analyseFlagsSlice returns all flags for the analyse command.
 func analyseFlagsSlice() []cli.Flag {
-	return append(viewCmdFlags(), analyseFlags...)
+	weightFlags := commonFlags("weights-file", "weights", "reference-glob", "reference-list")
+	return append(append(append(append(viewCmdFlags(), analyseFlags...), checkFlags...), boardFlag, detailsOutputFlag,
+		vsRandomFlag, legendFlag, focusKeyFlag), weightFlags...)
 }
 
 // analyseCommand defines the "analyse" CLI command.
@@ -66,6 +151,8 @@ var analyseCommand = &cli.Command{
 	ArgsUsage:     "<layout1> <layout2> ...",
 	Action:        analyseAction,
 	ShellComplete: layoutShellComplete,
+	// Inline layouts can have commas as keys
+	DisableSliceFlagSeparator: true,
 }
 
 // analyseAction coordinates the loading of corpus and target data, executes a
@@ -79,6 +166,14 @@ func analyseAction(ctx context.Context, c *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("could not parse user input: %w", err)
 	}
+	thresholds, err := loadThresholdsFromFlags(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	detailsFormat, detailsDir, err := parseDetailsOutput(c.String("details-output"))
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
 
 	result, err := kc.AnalyseLayouts(input)
 	if err != nil {
@@ -89,14 +184,132 @@ func analyseAction(ctx context.Context, c *cli.Command) error {
 		MaxRows:         c.Int("rows"),
 		CompactTrigrams: c.Bool("compact-trigrams"),
 		TrigramRows:     c.Int("trigram-rows"),
+		PerKey:          c.Bool("per-key"),
+		Page:            c.Int("page"),
+	}
+	if spec := c.String("sections"); spec != "" {
+		if displayOpts.Sections, err = kc.ParseAnalyseSections(spec); err != nil {
+			return fmt.Errorf("could not parse user input: %w", err)
+		}
+		displayOpts.PerKey = displayOpts.PerKey || slices.Contains(displayOpts.Sections, "per-key")
+	}
+	if key := c.String("focus-key"); key != "" {
+		if displayOpts.FocusKey, err = kc.ParseFocusKey(key); err != nil {
+			return fmt.Errorf("could not parse user input: %w", err)
+		}
 	}
 
-	return tui.RenderAnalyse(result, displayOpts)
+	if !c.Bool("quiet") {
+		if err := tui.RenderAnalyse(result, displayOpts); err != nil {
+			return err
+		}
+		for _, an := range result.Analysers {
+			printFingerLoadWarnings(an)
+		}
+		if c.Bool("legend") {
+			if err := renderLegends(result.Analysers); err != nil {
+				return err
+			}
+		}
+	}
+	if detailsDir != "" {
+		n, err := saveMetricDetails(detailsFormat, detailsDir, result.Analysers, displayOpts)
+		if err != nil {
+			return err
+		}
+		if !c.Bool("quiet") {
+			fmt.Printf("Saved %d metric details tables to %s\n", n, detailsDir)
+		}
+	}
+	return checkThresholds(thresholds, result.Analysers)
+}
+
+// renderLegends renders the legend of each layout type of the analysed layouts, in the order
+// of the layouts.
+func renderLegends(analysers []*kc.Analyser) error {
+	var shown []kc.LayoutType
+	for _, an := range analysers {
+		if slices.Contains(shown, an.Layout.LayoutType) {
+			continue
+		}
+		shown = append(shown, an.Layout.LayoutType)
+		fmt.Println()
+		if err := tui.RenderLegend(os.Stdout, an.Layout.LayoutType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDetailsOutput returns the format ("csv" or "json") and the directory of a
+// --details-output specification, "<format>:<dir>", or "" for both if it is empty.
+func parseDetailsOutput(spec string) (string, string, error) {
+	if spec == "" {
+		return "", "", nil
+	}
+	format, dir, ok := strings.Cut(spec, ":")
+	format = strings.ToLower(format)
+	if !ok || (format != "csv" && format != "json") || dir == "" {
+		return "", "", fmt.Errorf("invalid --details-output %q: must be csv:<dir> or json:<dir>", spec)
+	}
+	return format, dir, nil
+}
+
+// saveMetricDetails writes the metric details tables that opts shows of each layout to
+// "<layout>-<metric>.<format>" in dir, creating dir if needed. It returns the number of files.
+func saveMetricDetails(format, dir string, analysers []*kc.Analyser, opts kc.AnalyseDisplayOptions) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("could not create details directory: %w", err)
+	}
+	n := 0
+	for _, an := range analysers {
+		for _, ma := range an.AllMetricsDetails() {
+			if !opts.Shows(ma.Metric) {
+				continue
+			}
+			if opts.FocusKey != 0 {
+				ma = ma.Focused(opts.FocusKey)
+			}
+			path := filepath.Join(dir, an.Layout.Name+"-"+ma.Metric+"."+format)
+			if err := saveMetricDetailsFile(format, path, ma); err != nil {
+				return n, fmt.Errorf("could not save %s details of %s: %w", ma.Metric, an.Layout.Name, err)
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// saveMetricDetailsFile writes metric details to a CSV or JSON file.
+func saveMetricDetailsFile(format, path string, ma *kc.MetricDetails) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer kc.CloseFile(file)
+
+	if format == "json" {
+		return tui.WriteMetricDetailsJSON(file, ma)
+	}
+	return tui.WriteMetricDetailsCSV(file, ma)
 }
 
 // buildAnalyseInput gathers all input parameters for layout analysis.
 func buildAnalyseInput(c *cli.Command) (kc.AnalyseInput, error) {
-	if c.NArg() < 1 {
+	// The board geometry must be in use before the inline layouts are parsed
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.AnalyseInput{}, err
+	}
+	board, err := loadBoardFromFlags(c)
+	if err != nil {
+		return kc.AnalyseInput{}, err
+	}
+
+	inline, err := loadInlineLayouts(c)
+	if err != nil {
+		return kc.AnalyseInput{}, err
+	}
+	if c.NArg()+len(inline) < 1 {
 		return kc.AnalyseInput{}, fmt.Errorf("need at least 1 layout")
 	}
 
@@ -110,9 +323,41 @@ func buildAnalyseInput(c *cli.Command) (kc.AnalyseInput, error) {
 		return kc.AnalyseInput{}, fmt.Errorf("could not load target loads: %w", err)
 	}
 
+	var vsRandom *kc.RandomBaselineInput
+	if count := int(c.Int("vs-random")); count > 0 {
+		vsRandom, err = buildRandomBaselineInput(c, count)
+		if err != nil {
+			return kc.AnalyseInput{}, err
+		}
+	}
+
 	return kc.AnalyseInput{
 		LayoutFiles: getLayoutArgs(c),
+		Layouts:     inline,
 		Corpus:      corpus,
 		TargetLoads: targets,
+		Board:       board,
+		VsRandom:    vsRandom,
+	}, nil
+}
+
+// buildRandomBaselineInput gathers the weights and reference layouts of --vs-random. The
+// corpus and targets are those of the analysis.
+func buildRandomBaselineInput(c *cli.Command, count int) (*kc.RandomBaselineInput, error) {
+	weights, err := loadWeightsFromFlags(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not load weights: %w", err)
+	}
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not load reference layouts: %w", err)
+	}
+	return &kc.RandomBaselineInput{
+		LayoutsDir: layoutDir,
+		Reference:  reference,
+		Weights:    weights,
+		Count:      count,
+		Seed:       randomBaselineSeed,
+		CacheDir:   filepath.Join(corpusDir, "baselines"),
 	}, nil
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/urfave/cli/v3"
+)
+
+// calibrateFlags defines the measurements accepted by the calibrate command.
+var calibrateFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "key-pitch",
+		Aliases:  []string{"kp"},
+		Usage:    "Distance between key centres in mm: 1 value, or 2 values for horizontal, vertical.",
+		Value:    "19.05",
+		Category: "Measurements",
+	},
+	&cli.StringFlag{
+		Name:    "row-stagger",
+		Aliases: []string{"rs"},
+		Usage: "Horizontal offset of the top, home and bottom rows in mm " +
+			"(e.g., \"0,4.75,14.25\" for a standard row-staggered board).",
+		Value:    "0,0,0",
+		Category: "Measurements",
+	},
+	&cli.StringFlag{
+		Name:    "column-stagger",
+		Aliases: []string{"cs"},
+		Usage: "Vertical offset of each column in mm, positive = lower: 6 values for the left hand " +
+			"(outer to inner, mirrored) or 12 values.",
+		Value:    "0,0,0,0,0,0",
+		Category: "Measurements",
+	},
+	&cli.StringFlag{
+		Name:    "thumbs",
+		Aliases: []string{"tk"},
+		Usage: "Positions of the 6 thumb keys as distance@angle from the home thumb key of each hand, " +
+			"distance in mm and angle in degrees counter-clockwise from the right. " +
+			"Default: the thumb geometry of each layout type, scaled to the key pitch.",
+		Category: "Measurements",
+	},
+}
+
+// calibrateCommand defines the CLI command for creating a board geometry file.
+var calibrateCommand = &cli.Command{
+	Name:    "calibrate",
+	Aliases: []string{"cal"},
+	Usage:   "Create a board geometry file from measured key pitch and stagger",
+	Description: "Writes a geometry file to the data/config directory. Pass it to other commands " +
+		"with --geometry-file to compute key distances from your board's measurements instead " +
+		"of the built-in rowstag, anglemod, ortho and colstag presets.",
+	Flags:     calibrateFlags,
+	ArgsUsage: "<geometry-file>",
+	Action:    calibrateAction,
+}
+
+// calibrateAction builds a board geometry from the measurements, saves it, and prints
+// a few of the resulting key distances.
+func calibrateAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	geometry, path, err := buildCalibrateInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	if err := geometry.SaveToFile(path); err != nil {
+		return fmt.Errorf("could not save board geometry: %w", err)
+	}
+	fmt.Printf("Saved board geometry to: %s\n", path)
+
+	kc.UseBoardGeometry(geometry)
+	layout := kc.NewSplitLayout("calibration", kc.ORTHO, [42]rune{})
+	fmt.Println("Calibrated distances (in standard key units of 19.05 mm):")
+	for _, sample := range []struct {
+		name   string
+		k1, k2 uint8
+	}{
+		{"Index, top to bottom row", 4, 28},
+		{"Index, home row to inner column", 16, 17},
+		{"Middle to index, home row", 15, 16},
+		{"Pinky to ring, top row", 1, 2},
+		{"Thumb, home to inner key", 37, 38},
+	} {
+		dist := layout.MustDistance(sample.k1, sample.k2)
+		fmt.Printf("  %-32s %.2f\n", sample.name+":", dist.Distance)
+	}
+	return nil
+}
+
+// buildCalibrateInput parses the measurements and the geometry file name.
+func buildCalibrateInput(c *cli.Command) (*kc.BoardGeometry, string, error) {
+	if c.NArg() != 1 {
+		return nil, "", fmt.Errorf("expected exactly 1 geometry file name, got %d", c.NArg())
+	}
+
+	geometry := kc.NewBoardGeometry()
+	if err := geometry.SetKeyPitch(c.String("key-pitch")); err != nil {
+		return nil, "", fmt.Errorf("invalid key pitch: %w", err)
+	}
+	if err := geometry.SetRowStagger(c.String("row-stagger")); err != nil {
+		return nil, "", fmt.Errorf("invalid row stagger: %w", err)
+	}
+	if err := geometry.SetColumnStagger(c.String("column-stagger")); err != nil {
+		return nil, "", fmt.Errorf("invalid column stagger: %w", err)
+	}
+	if thumbs := c.String("thumbs"); thumbs != "" {
+		if err := geometry.SetThumbs(thumbs); err != nil {
+			return nil, "", fmt.Errorf("invalid thumbs: %w", err)
+		}
+	}
+
+	return geometry, filepath.Join(configDir, c.Args().First()), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/urfave/cli/v3"
+)
+
+// Process exit codes, for scripts that run keycraft.
+const (
+	exitError       = 1 // Invalid input or a failure to complete the command
+	exitCheckFailed = 2 // The command completed, but a threshold or reference check failed
+	exitInterrupted = 3 // The command was interrupted, after saving a partial result
+)
+
+// exitCodeError is an error that exits the process with a specific exit code.
+type exitCodeError struct {
+	err  error
+	code int
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// checkFailed returns an error that exits the process with exitCheckFailed.
+func checkFailed(format string, args ...any) error {
+	return &exitCodeError{err: fmt.Errorf(format, args...), code: exitCheckFailed}
+}
+
+// exitCode returns the process exit code for an error returned by a command.
+func exitCode(err error) int {
+	var ece *exitCodeError
+	if errors.As(err, &ece) {
+		return ece.code
+	}
+	return exitError
+}
+
+// checkFlags defines flags for using analyse and rank in scripts.
+var checkFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:     "quiet",
+		Aliases:  []string{"q"},
+		Usage:    "Do not print the results; only report violated --fail-if thresholds and errors.",
+		Category: "Scripting",
+	},
+	&cli.StringSliceFlag{
+		Name: "fail-if",
+		Usage: "Exit with code 2 if a layout's metric meets a condition, e.g. \"SFB>1.5\" or " +
+			"\"ALT<=30\" (operators >, >=, <, <=). Repeat or separate with commas for several conditions.",
+		Category: "Scripting",
+	},
+}
+
+// loadThresholdsFromFlags parses and validates the --fail-if thresholds.
+func loadThresholdsFromFlags(c *cli.Command) ([]kc.Threshold, error) {
+	thresholds, err := kc.ParseThresholds(c.StringSlice("fail-if"))
+	if err != nil {
+		return nil, err
+	}
+	metrics := make([]string, len(thresholds))
+	for i, th := range thresholds {
+		metrics[i] = th.Metric
+	}
+	if err := validateMetrics(metrics); err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// checkThresholds reports the thresholds violated by the analysed layouts on stderr, and
+// returns an error with exit code exitCheckFailed if there are any.
+func checkThresholds(thresholds []kc.Threshold, analysers []*kc.Analyser) error {
+	violations := kc.CheckThresholds(thresholds, analysers)
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "%s: %s is %.4g (fail if %s)\n", v.Layout, v.Metric, v.Actual, v.Threshold)
+	}
+	if len(violations) > 0 {
+		return checkFailed("%d metric threshold(s) violated", len(violations))
+	}
+	return nil
+}
@@ -2,10 +2,20 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
 	"github.com/urfave/cli/v3"
 )
 
@@ -25,7 +35,7 @@ func TestCorpusCommand_NoArgs(t *testing.T) {
 	// We test this implicitly through buildCorpusInput
 	app := &cli.Command{
 		Name:  "test",
-		Flags: append(commonFlags("corpus"), corpusFlags...),
+		Flags: corpusCmdFlags(),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			// If we got here, validation passed
 			return nil
@@ -70,7 +80,7 @@ func TestCorpusCommand_BuildInput(t *testing.T) {
 
 	app := &cli.Command{
 		Name:  "test",
-		Flags: append(commonFlags("corpus"), corpusFlags...),
+		Flags: corpusCmdFlags(),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			input, err := buildCorpusInput(cmd)
 			if err != nil {
@@ -95,6 +105,258 @@ func TestCorpusCommand_BuildInput(t *testing.T) {
 	}
 }
 
+// TestCorpusCommand_ExcludeWords verifies that --exclude-words builds the corpus without the
+// listed words, without changing the default build, and rejects a missing file.
+func TestCorpusCommand_ExcludeWords(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "exclude.txt", "# filler\nthe\nFIVE\n")
+
+	var corpus *kc.Corpus
+	app := &cli.Command{
+		Name:  "test",
+		Flags: corpusCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildCorpusInput(cmd)
+			corpus = input.Corpus
+			return err
+		},
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "--exclude-words", "exclude.txt"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if corpus.Words["the"] != 0 || corpus.Words["five"] != 0 || corpus.Words["quick"] == 0 {
+		t.Errorf("words the=%d five=%d quick=%d, want the and five excluded",
+			corpus.Words["the"], corpus.Words["five"], corpus.Words["quick"])
+	}
+	if corpus.Trigrams[kc.Trigram{'t', 'h', 'e'}] != 0 {
+		t.Error("trigram the should not be counted")
+	}
+
+	// The default build of the corpus keeps the words
+	if err := app.Run(context.Background(), []string{"test", "--exclude-words", ""}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if corpus.Words["the"] == 0 {
+		t.Error("the excluded words stuck to the default build of the corpus")
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "--exclude-words", "missing.txt"}); err == nil {
+		t.Error("expected error for a missing excluded words file, got nil")
+	}
+}
+
+// TestCorpusCommand_Substitute verifies that the corpus command lists the characters that
+// the default substitution table would replace, and that --substitute replaces them.
+func TestCorpusCommand_Substitute(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestConfigFile(t, corpusDir, "default.txt", "don’t stop — it’s fine\n")
+	writeTestConfigFile(t, configDir, defaultSubstitutionsFile, "’ '\n— -\n")
+
+	var input kc.CorpusInput
+	app := &cli.Command{
+		Name:  "test",
+		Flags: corpusCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, err = buildCorpusInput(cmd)
+			return err
+		},
+	}
+
+	if err := app.Run(context.Background(), []string{"test"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	result := kc.Must(kc.DisplayCorpus(input))
+	if result.Substituted || len(result.Substitutions) != 2 || result.Substitutions[0].From != '’' ||
+		result.Substitutions[0].Count != 2 {
+		t.Errorf("substitutions %+v, want 2 apostrophes and 1 dash to be substitutable", result.Substitutions)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "--substitute", defaultSubstitutionsFile}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	corpus := input.Corpus
+	if corpus.Unigrams['’'] != 0 || corpus.Unigrams['\''] != 2 || corpus.Words["don’t"]+corpus.Words["it’s"] != 0 {
+		t.Errorf("unigrams %v and words %v, want the apostrophes replaced", corpus.Unigrams, corpus.Words)
+	}
+	result = kc.Must(kc.DisplayCorpus(input))
+	if !result.Substituted || len(result.Substitutions) != 2 {
+		t.Errorf("substitutions %+v, want the 2 entries applied", result.Substitutions)
+	}
+
+	// The default build of the corpus keeps the apostrophes
+	if err := app.Run(context.Background(), []string{"test", "--substitute", ""}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.Corpus.Unigrams['’'] != 2 || input.Corpus.Substitutions != nil {
+		t.Errorf("unigrams %v, want the default build with the apostrophes", input.Corpus.Unigrams)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "--substitute", "missing.txt"}); err == nil {
+		t.Error("expected error for a missing substitutions file, got nil")
+	}
+}
+
+// TestCorpusCommand_Preview verifies that --preview shows the top n-grams of each kind, and
+// that --export writes them, or the top --corpus-rows without --preview.
+func TestCorpusCommand_Preview(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	app := &cli.Command{Name: "test", Flags: corpusCmdFlags(), Action: corpusAction}
+
+	for _, tt := range []struct {
+		args []string
+		rows int // Rows of n-grams per kind in the export
+	}{
+		{[]string{"--preview", "3"}, 3},
+		{[]string{"--preview", "0", "--corpus-rows", "2"}, 2},
+	} {
+		path := filepath.Join(t.TempDir(), "ngrams.csv")
+		if err := app.Run(context.Background(), append(append([]string{"test"}, tt.args...), "--export", path)); err != nil {
+			t.Fatalf("corpus %v failed: %v", tt.args, err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("could not open export: %v", err)
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		kc.CloseFile(f)
+		if err != nil {
+			t.Fatalf("could not read export: %v", err)
+		}
+		if len(records) != 1+4*tt.rows || records[0][0] != "Kind" || records[1][0] != "unigram" || records[1][1] != "1" {
+			t.Errorf("corpus %v: export %v, want a header and %d n-grams of each kind", tt.args, records, tt.rows)
+		}
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "--preview", "-1"}); err == nil {
+		t.Error("expected error for a negative --preview, got nil")
+	}
+}
+
+// TestCorpusCommand_SkipgramDistance verifies that --skipgram-distance builds the corpus
+// with weighted skipgrams, and that invalid skipgram flags are rejected.
+func TestCorpusCommand_SkipgramDistance(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	var corpus *kc.Corpus
+	app := &cli.Command{
+		Name:  "test",
+		Flags: corpusCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildCorpusInput(cmd)
+			corpus = input.Corpus
+			return err
+		},
+	}
+	run := func(args ...string) error {
+		return app.Run(context.Background(), append([]string{"test"}, args...))
+	}
+
+	// Before --skipgram-distance is set, as flags stay set between runs
+	if err := run("--skipgram-decay", "0.5"); err == nil {
+		t.Error("expected error for --skipgram-decay without --skipgram-distance, got nil")
+	}
+
+	if err := run("--skipgram-distance", "1"); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	plain := corpus.TotalSkipgramsCount
+	if err := run("--skipgram-distance", "3", "--skipgram-decay", "0.5"); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if corpus.Skipgram.MaxDistance != 3 || corpus.Skipgram.Decay != 0.5 || corpus.TotalSkipgramsCount <= plain {
+		t.Errorf("skipgrams = %+v, %d, want distance 3 and more than %d", corpus.Skipgram, corpus.TotalSkipgramsCount, plain)
+	}
+	// The default build of the corpus keeps its skipgrams
+	if err := run("--skipgram-distance", "1"); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if corpus.Skipgram != (kc.SkipgramPolicy{}) || corpus.TotalSkipgramsCount != plain {
+		t.Errorf("skipgrams = %+v, %d, want the default %d", corpus.Skipgram, corpus.TotalSkipgramsCount, plain)
+	}
+
+	for _, args := range [][]string{{"--skipgram-distance", "9"}, {"--skipgram-distance", "2", "--skipgram-decay", "0"}} {
+		if err := run(args...); err == nil {
+			t.Errorf("%v: expected error, got nil", args)
+		}
+	}
+}
+
+// TestCorpusFetchCommand_InvalidInput verifies that corpus fetch rejects an unknown preset,
+// more than one preset and a missing presets file.
+func TestCorpusFetchCommand_InvalidInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestConfigFile(t, configDir, "corpus_presets.txt",
+		"test test.txt http://127.0.0.1:1/test.txt - Test license\n")
+
+	app := &cli.Command{
+		Commands: []*cli.Command{corpusCommand},
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "corpus", "fetch"}); err != nil {
+		t.Errorf("expected the presets to be listed, got %v", err)
+	}
+	for _, args := range [][]string{
+		{"test", "corpus", "fetch", "nope"},
+		{"test", "corpus", "fetch", "test", "test"},
+		{"test", "corpus", "fetch", "--presets-file", "missing.txt"},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
+	}
+}
+
+// TestCorpusFetchCommand_Pin verifies that corpus fetch --pin writes the checksum of the
+// download into the presets file, and that later downloads are verified against it.
+func TestCorpusFetchCommand_Pin(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	text := "the quick brown fox\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(text))
+	}))
+	defer server.Close()
+	writeTestConfigFile(t, configDir, "corpus_presets.txt",
+		"test test.txt "+server.URL+"/test.txt - Test license\n")
+
+	app := &cli.Command{
+		Commands: []*cli.Command{corpusCommand},
+	}
+	if err := app.Run(context.Background(), []string{"test", "corpus", "fetch", "--pin", "test"}); err != nil {
+		t.Fatalf("corpus fetch --pin: %v", err)
+	}
+	presets, err := kc.LoadCorpusPresets(filepath.Join(configDir, "corpus_presets.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(text))
+	if presets[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("pinned checksum %q, want %x", presets[0].SHA256, sum)
+	}
+
+	// A changed download no longer matches the pinned checksum
+	text = "another corpus\n"
+	if err := app.Run(context.Background(), []string{"test", "corpus", "fetch", "--force", "test"}); err == nil {
+		t.Error("expected a checksum error for a changed download")
+	}
+}
+
 // TestCorpusCommand_CorpusRowsFlag verifies --corpus-rows flag is correctly applied.
 func TestCorpusCommand_CorpusRowsFlag(t *testing.T) {
 	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
@@ -118,7 +380,7 @@ func TestCorpusCommand_CorpusRowsFlag(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			app := &cli.Command{
 				Name:  "test",
-				Flags: append(commonFlags("corpus"), corpusFlags...),
+				Flags: corpusCmdFlags(),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					input, err := buildCorpusInput(cmd)
 					if (err != nil) != tt.wantErr {
@@ -168,7 +430,7 @@ func TestCorpusCommand_CoverageFlag(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			app := &cli.Command{
 				Name:  "test",
-				Flags: append(commonFlags("corpus"), corpusFlags...),
+				Flags: corpusCmdFlags(),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					// Coverage flag is validated but not stored in CorpusInput
 					// It's used during corpus loading. Just verify it parses correctly.
@@ -213,7 +475,7 @@ func TestCorpusCommand_CorpusRowsInvalid(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			app := &cli.Command{
 				Name:  "test",
-				Flags: append(commonFlags("corpus"), corpusFlags...),
+				Flags: corpusCmdFlags(),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					_, err := buildCorpusInput(cmd)
 					if err == nil {
@@ -251,7 +513,7 @@ func TestCorpusCommand_CoverageInvalid(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			app := &cli.Command{
 				Name:  "test",
-				Flags: append(commonFlags("corpus"), corpusFlags...),
+				Flags: corpusCmdFlags(),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					coverage := cmd.Float64("coverage")
 					if coverage < 0.1 || coverage > 100.0 {
@@ -466,6 +728,124 @@ func TestViewCommand_MultipleLayouts(t *testing.T) {
 	}
 }
 
+// TestViewCommand_Pins verifies that view shows the pins file of a layout in the config
+// directory, and the pins and provenance saved with an optimized layout.
+func TestViewCommand_Pins(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "test-opt.klf", minimalLayoutContent+
+		"\noptimized-from: test\nseed: 42\ncorpus: default.txt\n"+
+		"pins: .*.......... ............ ............ ......\n")
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "test.pin",
+		"* . . . . . . . . . . .\n. . . . . . . . . . . .\n. . . . . . . . . . . .\n. . . . . .\n")
+
+	var result *kc.ViewResult
+	cmd := &cli.Command{
+		Name:  "view",
+		Flags: viewCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildViewInput(cmd)
+			if err != nil {
+				return err
+			}
+			result, err = kc.ViewLayouts(input)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "view", "test", "test-opt"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if pins := result.Pins[0]; pins == nil || !pins[0] || pins[1] {
+		t.Errorf("pins of test = %v, want those of test.pin", pins)
+	}
+	if pins := result.Pins[1]; pins == nil || pins[0] || !pins[1] {
+		t.Errorf("pins of test-opt = %v, want those of its provenance", pins)
+	}
+	p := result.Analysers[1].Layout.Provenance
+	if p == nil || p.Source != "test" || p.Seed != 42 || p.Corpus != "default.txt" {
+		t.Errorf("provenance of test-opt = %+v", p)
+	}
+}
+
+// TestViewCommand_Scope verifies that --scope restricts the corpus to the characters of the
+// scope, and rejects an invalid scope.
+func TestViewCommand_Scope(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	var corpus *kc.Corpus
+	cmd := &cli.Command{
+		Name:  "view",
+		Flags: viewCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildViewInput(cmd)
+			corpus = input.Corpus
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "view", "--scope", "alphas", "test"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if corpus.Name != "default (alphas)" {
+		t.Errorf("corpus = %q, want default (alphas)", corpus.Name)
+	}
+	for u := range corpus.Unigrams {
+		if kc.RuneClassOf(rune(u)) != kc.LetterClass {
+			t.Errorf("unigram %q is not a letter", u)
+		}
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "view", "--scope", "symbols", "test"}); err == nil {
+		t.Error("expected error for an invalid scope, got nil")
+	}
+}
+
+// TestViewCommand_IncludeSpace verifies that --include-space analyses the corpus with the
+// space between words, and that the default build of the corpus keeps leaving it out.
+func TestViewCommand_IncludeSpace(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	var corpus *kc.Corpus
+	cmd := &cli.Command{
+		Name:  "view",
+		Flags: viewCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildViewInput(cmd)
+			corpus = input.Corpus
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "view", "--include-space", "test"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if !corpus.Spaced || corpus.Unigrams[' '] == 0 {
+		t.Errorf("corpus spaced = %v with %d spaces, want space in the n-grams", corpus.Spaced, corpus.Unigrams[' '])
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "view", "--include-space=false", "test"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if corpus.Spaced || corpus.Unigrams[' '] != 0 {
+		t.Errorf("corpus spaced = %v with %d spaces, want the default build", corpus.Spaced, corpus.Unigrams[' '])
+	}
+}
+
 // ============================================================================
 // ANALYSE COMMAND TESTS
 // ============================================================================
@@ -492,6 +872,53 @@ func TestAnalyseCommand_NoArgs_ReturnsError(t *testing.T) {
 	}
 }
 
+// TestAnalyseCommand_VsRandom verifies that --vs-random compares the layouts with random
+// layouts, with the scores of the random layouts cached in the corpus directory.
+func TestAnalyseCommand_VsRandom(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+
+	var result *kc.AnalyseResult
+	cmd := &cli.Command{
+		Name:  "analyse",
+		Flags: analyseFlagsSlice(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildAnalyseInput(cmd)
+			if err != nil {
+				return err
+			}
+			result, err = kc.AnalyseLayouts(input)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "analyse", "--vs-random", "10", "test"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if len(result.VsRandom) != 1 || len(result.VsRandom[0].Baseline.Scores) != 10 {
+		t.Fatalf("vs random = %+v, want 10 random layouts", result.VsRandom)
+	}
+	if files, _ := filepath.Glob(filepath.Join(corpusDir, "baselines", "random-*.json")); len(files) != 1 {
+		t.Errorf("cached baselines = %v, want 1", files)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "analyse", "test"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if result.VsRandom != nil {
+		t.Errorf("vs random without --vs-random = %+v", result.VsRandom)
+	}
+	if err := app.Run(context.Background(), []string{"test", "analyse", "--vs-random", "-1", "test"}); err == nil {
+		t.Error("expected error for a negative --vs-random")
+	}
+}
+
 // TestAnalyseCommand_BuildInput verifies that buildAnalyseInput() correctly builds input structure
 // with layout files, corpus, targets, and parses display options (rows, compact-trigrams).
 func TestAnalyseCommand_BuildInput(t *testing.T) {
@@ -678,6 +1105,45 @@ func TestAnalyseCommand_TrigramRowsInvalid(t *testing.T) {
 	}
 }
 
+// TestAnalyseCommand_FailIf verifies that violated --fail-if thresholds return an error with
+// the check-failed exit code, and that invalid thresholds are input errors.
+func TestAnalyseCommand_FailIf(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	tests := []struct {
+		name     string
+		failIf   string
+		wantCode int // 0 = no error
+	}{
+		{"not violated", "SFB>100", 0},
+		{"violated", "SFB>=0", exitCheckFailed},
+		{"one of several violated", "SFB>100,ALT>=0", exitCheckFailed},
+		{"unknown metric", "XYZ>1", exitError},
+		{"invalid threshold", "SFB=1", exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &cli.Command{
+				Commands: []*cli.Command{analyseCommand},
+			}
+			err := app.Run(context.Background(), []string{"test", "analyse", "--quiet", "--fail-if", tt.failIf, "test"})
+			switch {
+			case tt.wantCode == 0 && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantCode != 0 && err == nil:
+				t.Errorf("expected error with exit code %d, got nil", tt.wantCode)
+			case tt.wantCode != 0 && exitCode(err) != tt.wantCode:
+				t.Errorf("exit code = %d, want %d (error: %v)", exitCode(err), tt.wantCode, err)
+			}
+		})
+	}
+}
+
 // ============================================================================
 // RANK COMMAND TESTS
 // ============================================================================
@@ -742,23 +1208,122 @@ func TestRankCommand_BuildInput(t *testing.T) {
 	}
 }
 
-// TestRankCommand_BuildDisplayOptions verifies that buildDisplayOptions() correctly parses
-// display flags and creates options with weights, output format, and metric display settings.
-func TestRankCommand_BuildDisplayOptions(t *testing.T) {
+// TestRankCommand_InlineLayouts verifies that --inline layouts are ranked, keeping commas as
+// keys, and that only they are ranked when no layout files are given.
+func TestRankCommand_InlineLayouts(t *testing.T) {
 	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
 	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
 	writeTestCorpus(t, corpusDir, "default.txt")
 
-	// Create weights file
-	weightsContent := `SFB=-10.0`
-	writeTestConfigFile(t, configDir, "weights.txt", weightsContent)
+	for _, tt := range []struct {
+		args      []string
+		wantFiles int
+	}{
+		{[]string{}, 0},
+		{[]string{"test", "--deltas", "mine"}, 1},
+	} {
+		cmd := &cli.Command{
+			Name:                      "rank",
+			Flags:                     rankFlagsSlice(),
+			DisableSliceFlagSeparator: true,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				input, err := buildRankingInput(cmd, kc.NewWeights(), false)
+				if err != nil {
+					t.Fatalf("buildRankingInput failed: %v", err)
+				}
+				if len(input.LayoutFiles) != tt.wantFiles {
+					t.Errorf("args %v: got layout files %v, want %d", tt.args, input.LayoutFiles, tt.wantFiles)
+				}
+				if len(input.Layouts) != 1 || input.Layouts[0].Name != "mine" || input.Layouts[0].Runes[32] != ',' {
+					t.Errorf("args %v: got inline layouts %v", tt.args, input.Layouts)
+				}
+				return nil
+			},
+		}
+		app := &cli.Command{Commands: []*cli.Command{cmd}}
+		args := append([]string{"test", "rank", "--inline", "ortho:~qwertyuiop~~asdfghjkl;'~zxcvbnm,./~~~_~~~; name=mine"}, tt.args...)
+		if err := app.Run(context.Background(), args); err != nil {
+			t.Fatalf("app.Run failed: %v", err)
+		}
+	}
+}
 
-	cmd := &cli.Command{
-		Name:  "rank",
-		Flags: rankFlagsSlice(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			opts, err := buildDisplayOptions(cmd)
+// TestRankCommand_ReferenceFlags verifies that --reference-glob and --reference-list
+// are combined into the reference set used for normalisation, and that a listed layout
+// that doesn't exist is an error.
+func TestRankCommand_ReferenceFlags(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "qwerty.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+	writeTestConfigFile(t, configDir, "refs.txt", "# normalisation layouts\nqwerty\n")
+
+	cmd := &cli.Command{
+		Name:  "rank",
+		Flags: rankFlagsSlice(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildRankingInput(cmd, nil, false)
+			if err != nil {
+				t.Fatalf("buildRankingInput failed: %v", err)
+			}
+
+			if input.Reference.IsDefault() {
+				t.Fatal("expected custom reference set")
+			}
+			for _, name := range []string{"qwerty", "test", "test-opt"} {
+				if !input.Reference.Contains(name) {
+					t.Errorf("expected %s in reference set", name)
+				}
+			}
+			if input.Reference.Contains("dvorak") {
+				t.Error("expected dvorak not in reference set")
+			}
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "rank", "--rg", "test*", "--rl", "refs.txt", "test.klf"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+
+	writeTestConfigFile(t, configDir, "typo.txt", "qwerty\nqwertz\n")
+	cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+		_, err := buildRankingInput(cmd, nil, false)
+		return err
+	}
+	err = app.Run(context.Background(), []string{"test", "rank", "--rg", "", "--rl", "typo.txt", "test.klf"})
+	if err == nil || !strings.Contains(err.Error(), "qwertz") {
+		t.Errorf("expected error for the missing layout qwertz in the reference list, got %v", err)
+	}
+}
+
+// TestRankCommand_BuildDisplayOptions verifies that buildDisplayOptions() correctly parses
+// display flags and creates options with weights, output format, and metric display settings.
+func TestRankCommand_BuildDisplayOptions(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	// Create weights file
+	weightsContent := `SFB=-10.0`
+	writeTestConfigFile(t, configDir, "weights.txt", weightsContent)
+
+	cmd := &cli.Command{
+		Name:  "rank",
+		Flags: rankFlagsSlice(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			opts, err := buildDisplayOptions(cmd)
 			if err != nil {
 				t.Fatalf("buildDisplayOptions failed: %v", err)
 			}
@@ -931,6 +1496,50 @@ func TestRankCommand_DeltasFlag(t *testing.T) {
 	}
 }
 
+// TestRankCommand_DeltaModeFlag verifies that --delta-mode selects the unit of the deltas
+// and rejects unknown modes.
+func TestRankCommand_DeltaModeFlag(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+
+	tests := []struct {
+		value   string
+		want    tui.DeltaMode
+		wantErr bool
+	}{
+		{"absolute", tui.DeltaAbsolute, false},
+		{"percent", tui.DeltaPercent, false},
+		{"Normalized", tui.DeltaNormalised, false},
+		{"ratio", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cmd := &cli.Command{
+				Name:  "rank",
+				Flags: rankFlagsSlice(),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					opts, err := buildDisplayOptions(cmd)
+					if (err != nil) != tt.wantErr {
+						t.Fatalf("buildDisplayOptions error = %v, wantErr %v", err, tt.wantErr)
+					}
+					if opts.DeltaMode != tt.want {
+						t.Errorf("delta mode = %q, want %q", opts.DeltaMode, tt.want)
+					}
+					return nil
+				},
+			}
+			app := &cli.Command{Commands: []*cli.Command{cmd}}
+			if err := app.Run(context.Background(), []string{"test", "rank", "--delta-mode", tt.value}); err != nil {
+				t.Fatalf("app.Run failed: %v", err)
+			}
+		})
+	}
+}
+
 // TestRankCommand_OutputFlag verifies that the --output flag accepts valid format values
 // and rejects invalid ones.
 func TestRankCommand_OutputFlag(t *testing.T) {
@@ -978,435 +1587,2172 @@ func TestRankCommand_OutputFlag(t *testing.T) {
 	}
 }
 
-// ============================================================================
-// FLIP COMMAND TESTS
-// ============================================================================
-
-// TestFlipCommand_NoArgs_ReturnsError verifies that the flip command requires exactly one layout
-// argument and rejects execution with no arguments via flipAction().
-func TestFlipCommand_NoArgs_ReturnsError(t *testing.T) {
-	cmd := &cli.Command{
-		Name:   "flip",
-		Action: flipAction,
-	}
-
-	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
-	}
+// TestRankCommand_GroupBy verifies that --group-by is parsed into the display options, and
+// rejects invalid groupings and combinations with deltas and HTML output.
+func TestRankCommand_GroupBy(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-	err := app.Run(context.Background(), []string{"test", "flip"})
-	if err == nil {
-		t.Error("expected error for flip with no args, got nil")
-	}
-}
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
 
-// TestFlipCommand_MultipleArgs_ReturnsError verifies that the flip command rejects multiple
-// layout arguments via flipAction(), as it only processes one layout at a time.
-func TestFlipCommand_MultipleArgs_ReturnsError(t *testing.T) {
-	cmd := &cli.Command{
-		Name:   "flip",
-		Action: flipAction,
+	tests := []struct {
+		name    string
+		args    []string
+		want    kc.GroupBy
+		wantErr bool
+	}{
+		{"default", nil, kc.GroupNone, false},
+		{"tag", []string{"--group-by", "tag"}, kc.GroupByTag, false},
+		{"board csv", []string{"--group-by", "Board", "-o", "csv"}, kc.GroupByBoard, false},
+		{"invalid", []string{"--group-by", "family"}, kc.GroupNone, true},
+		{"with deltas", []string{"--group-by", "author", "-d", "rows"}, kc.GroupNone, true},
+		{"html", []string{"--group-by", "author", "-o", "html"}, kc.GroupNone, true},
 	}
 
-	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got kc.GroupBy
+			cmd := &cli.Command{
+				Name:  "rank",
+				Flags: rankFlagsSlice(),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					opts, err := buildDisplayOptions(cmd)
+					got = opts.GroupBy
+					return err
+				},
+			}
+			app := &cli.Command{Commands: []*cli.Command{cmd}}
 
-	err := app.Run(context.Background(), []string{"test", "flip", "layout1", "layout2"})
-	if err == nil {
-		t.Error("expected error for flip with multiple args, got nil")
+			err := app.Run(context.Background(), append(append([]string{"test", "rank"}, tt.args...), "test.klf"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("group-by = %v, want %v", got, tt.want)
+			}
+		})
 	}
 }
 
-// ============================================================================
-// OPTIMIZE COMMAND TESTS
-// ============================================================================
-
-// TestOptimizeCommand_NoArgs_ReturnsError verifies that the optimize command requires exactly one
-// layout argument and rejects execution with no arguments via buildOptimizeInput().
-func TestOptimizeCommand_NoArgs_ReturnsError(t *testing.T) {
+// TestRankCommand_Transpose verifies that --transpose is parsed into the display options, and
+// rejects combinations with deltas, --group-by and HTML output.
+func TestRankCommand_Transpose(t *testing.T) {
 	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
 	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
 	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
 
-	cmd := &cli.Command{
-		Name:  "optimize",
-		Flags: optimizeCmdFlags(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, err := buildOptimizeInput(cmd, nil, false)
-			return err
-		},
+	tests := []struct {
+		name    string
+		args    []string
+		want    bool
+		wantErr bool
+	}{
+		{"default", nil, false, false},
+		{"table", []string{"--transpose"}, true, false},
+		{"csv", []string{"--transpose", "-o", "csv"}, true, false},
+		{"with deltas", []string{"--transpose", "-d", "median"}, false, true},
+		{"grouped", []string{"--transpose", "--group-by", "tag"}, false, true},
+		{"html", []string{"--transpose", "-o", "html"}, false, true},
 	}
 
-	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			cmd := &cli.Command{
+				Name:  "rank",
+				Flags: rankFlagsSlice(),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					opts, err := buildDisplayOptions(cmd)
+					got = opts.Transpose
+					return err
+				},
+			}
+			app := &cli.Command{Commands: []*cli.Command{cmd}}
 
-	err := app.Run(context.Background(), []string{"test", "optimize"})
-	if err == nil {
-		t.Error("expected error for optimize with no args, got nil")
+			err := app.Run(context.Background(), append(append([]string{"test", "rank"}, tt.args...), "test.klf"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("transpose = %v, want %v", got, tt.want)
+			}
+		})
 	}
 }
 
-// TestOptimizeCommand_MultipleArgs_ReturnsError verifies that the optimize command rejects multiple
-// layout arguments via buildOptimizeInput(), as it only optimizes one layout at a time.
-func TestOptimizeCommand_MultipleArgs_ReturnsError(t *testing.T) {
+// TestRankCommand_Cache verifies that rank keeps the metrics in the --cache file of the corpus
+// directory, and that "none" keeps no cache.
+func TestRankCommand_Cache(t *testing.T) {
 	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
 	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
 	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
 
-	cmd := &cli.Command{
-		Name:  "optimize",
-		Flags: optimizeCmdFlags(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, err := buildOptimizeInput(cmd, nil, false)
-			return err
-		},
+	run := func(args ...string) {
+		t.Helper()
+		cmd := &cli.Command{Name: "rank", Flags: rankFlagsSlice(), Action: rankAction}
+		app := &cli.Command{Commands: []*cli.Command{cmd}}
+		if err := app.Run(context.Background(), append(append([]string{"test", "rank", "--quiet"}, args...), "test")); err != nil {
+			t.Fatalf("rank %v failed: %v", args, err)
+		}
 	}
 
-	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+	run("--cache", "none")
+	if entries, _ := os.ReadDir(corpusDir); len(entries) != 2 {
+		t.Errorf("expected no cache file with --cache none, got %d files", len(entries))
 	}
-
-	err := app.Run(context.Background(), []string{"test", "optimize", "layout1", "layout2"})
-	if err == nil {
-		t.Error("expected error for optimize with multiple args, got nil")
+	run("--cache", "metrics.json")
+	run("--cache", "metrics.json")
+	if _, err := os.Stat(filepath.Join(corpusDir, "metrics.json")); err != nil {
+		t.Errorf("expected cache file in the corpus directory: %v", err)
 	}
 }
 
-// TestOptimizeCommand_BuildInput verifies that buildOptimizeInput() correctly builds input structure
-// with layout, corpus, targets, weights, and optimizer parameters (generations, maxtime).
-func TestOptimizeCommand_BuildInput(t *testing.T) {
+// TestRankCommand_Interactive verifies that the commands of the rank prompt change the
+// ranking without analysing the layouts again, and that invalid commands don't stop it.
+func TestRankCommand_Interactive(t *testing.T) {
 	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
 	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-	// Create test layout, corpus, and weights
 	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
 	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
 
-	// Create weights file
-	weightsContent := `SFB=-10.0`
-	writeTestConfigFile(t, configDir, "weights.txt", weightsContent)
-
+	var session *rankSession
 	cmd := &cli.Command{
-		Name:  "optimize",
-		Flags: optimizeCmdFlags(),
+		Name:  "rank",
+		Flags: rankFlagsSlice(),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			// skipLayoutLoad=false: load layout from args (optimize command behavior)
-			input, err := buildOptimizeInput(cmd, nil, false)
+			opts, err := buildDisplayOptions(cmd)
 			if err != nil {
-				t.Fatalf("buildOptimizeInput failed: %v", err)
-			}
-
-			if input.Layout == nil {
-				t.Error("expected non-nil layout")
-			}
-
-			if input.Corpus == nil {
-				t.Error("expected non-nil corpus")
-			}
-
-			if input.Targets == nil {
-				t.Error("expected non-nil targets")
-			}
-
-			if input.Weights == nil {
-				t.Error("expected non-nil weights")
-			}
-
-			if input.NumGenerations != 500 {
-				t.Errorf("NumGenerations = %d, want 500", input.NumGenerations)
+				t.Fatalf("buildDisplayOptions failed: %v", err)
 			}
-
-			if input.MaxTime != 3 {
-				t.Errorf("MaxTime = %d, want 3", input.MaxTime)
+			input, err := buildRankingInput(cmd, opts.Weights, false)
+			if err != nil {
+				t.Fatalf("buildRankingInput failed: %v", err)
 			}
-
+			session = newRankSession(kc.Must(kc.ComputeRankings(input)), opts)
 			return nil
 		},
 	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+	if err := app.Run(context.Background(), []string{"test", "rank", "--output", "csv", "test", "alt"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	analyser := session.rankings.Scores[0].Analyser
+	weights := session.opts.Weights
 
-	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+	var out strings.Builder
+	commands := "sort lsb\nweights lsb=-2\nmetrics sfb,lsb\ntoggle sfb\ndeltas alt\nmode pct\nfilter t*\n" +
+		"bogus\nweights xyz=1\ndeltas missing\nquit\nshow\n"
+	if err := session.run(strings.NewReader(commands), &out); err != nil {
+		t.Fatalf("run failed: %v", err)
 	}
 
-	err := app.Run(context.Background(), []string{"test", "optimize", "test.klf", "--generations", "500", "--maxtime", "3"})
-	if err != nil {
-		t.Fatalf("app.Run failed: %v", err)
+	opts := session.opts
+	if opts.SortBy != "LSB" || opts.DeltasOption != tui.DeltasCustom || opts.BaseLayoutName != "alt" ||
+		opts.DeltaMode != tui.DeltaPercent {
+		t.Errorf("got sort %q, deltas %q to %q in %q", opts.SortBy, opts.DeltasOption, opts.BaseLayoutName, opts.DeltaMode)
+	}
+	if !slices.Equal(opts.GetMetrics(), []string{"LSB"}) {
+		t.Errorf("metrics = %v, want [LSB]", opts.GetMetrics())
+	}
+	if opts.Weights.Get("LSB") != -2 || weights.Get("LSB") != 0 {
+		t.Errorf("LSB weight = %v, started with %v; want -2 and 0", opts.Weights.Get("LSB"), weights.Get("LSB"))
+	}
+	if session.rankings.Scores[0].Analyser != analyser {
+		t.Error("expected the layouts not to be analysed again")
+	}
+	// The base layout of the deltas is shown even if it doesn't match the filter
+	if visible := session.visible().Scores; len(visible) != 2 {
+		t.Errorf("got %d layouts after filtering, want test and the base layout alt", len(visible))
+	}
+	if got := strings.Count(out.String(), "error: "); got != 3 {
+		t.Errorf("got %d errors, want 3 for the invalid commands:\n%s", got, out.String())
+	}
+
+	if _, err := session.execute("weights reset"); err != nil || session.opts.Weights != weights {
+		t.Errorf("weights reset: %v, expected the weights the ranking started with", err)
 	}
 }
 
-// ============================================================================
-// GENERATE COMMAND TESTS
-// ============================================================================
+// TestRankCommand_Corpora verifies that --corpora ranks the layouts against every corpus,
+// and rejects invalid corpus weights.
+func TestRankCommand_Corpora(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-// TestGenerateCommand_NoArgs_ReturnsError verifies that the generate command requires exactly one
-// config file argument and rejects execution with no arguments.
-func TestGenerateCommand_NoArgs_ReturnsError(t *testing.T) {
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "en.txt")
+	writeTestCorpus(t, corpusDir, "nl.txt")
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"two corpora", "en.txt:2,nl.txt", false},
+		{"invalid weight", "en.txt:0,nl.txt", true},
+		{"missing corpus", "en.txt,missing.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			app := &cli.Command{
+				Name: "test",
+				// Fresh flag instances, to avoid polluting shared flag state
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "corpora"},
+					&cli.BoolFlag{Name: "quiet"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					input, buildErr := buildRankingInput(cmd, kc.NewWeights(), false)
+					if buildErr != nil {
+						t.Fatalf("buildRankingInput failed: %v", buildErr)
+					}
+					if input.Corpus != nil {
+						t.Error("--corpora should not load the --corpus corpus")
+					}
+					err = rankCorpora(cmd, input, tui.RankingDisplayOptions{OutputFormat: tui.OutputCSV},
+						strings.Split(cmd.String("corpora"), ","), nil)
+					return nil
+				},
+			}
+			_ = app.Run(context.Background(), []string{"test", "--quiet", "--corpora", tt.value, "test", "alt"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rankCorpora() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSimilarityCommand_Input verifies that the similarity command validates its flags and
+// arguments before comparing layouts.
+func TestSimilarityCommand_Input(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"defaults", nil, false},
+		{"linkage", []string{"--output", "linkage"}, false},
+		{"invalid output", []string{"--output", "json"}, true},
+		{"threshold above 1", []string{"--threshold", "1.5"}, true},
+		{"too many dirs", []string{"a", "b"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			var input kc.SimilarityInput
+			app := &cli.Command{
+				Name: "test",
+				// Fresh flag instances, to avoid polluting shared flag state
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "corpus", Value: "default.txt"},
+					&cli.Float64Flag{Name: "threshold", Value: 0.6},
+					&cli.BoolFlag{Name: "mirror"},
+					&cli.StringFlag{Name: "output", Value: "table"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					input, _, err = buildSimilarityInput(cmd)
+					return nil
+				},
+			}
+			_ = app.Run(context.Background(), append([]string{"test"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildSimilarityInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if _, err := kc.CompareLayouts(input); err != nil {
+					t.Errorf("CompareLayouts failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestRankCommand_MetricsFile verifies that --metrics-file registers the imported columns
+// before weights are parsed, so they can be weighted and are displayed after the metrics.
+func TestRankCommand_MetricsFile(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+	allMetrics := slices.Clone(kc.MetricsMap["all"])
+	defer func() { kc.MetricsMap["all"] = allMetrics }()
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+	writeTestConfigFile(t, configDir, "community.csv", "layout,learned-in-days\ntest,14\n")
+
+	cmd := &cli.Command{
+		Name:  "rank",
+		Flags: rankFlagsSlice(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			external, err := loadExternalMetricsFromFlags(cmd)
+			if err != nil {
+				t.Fatalf("loadExternalMetricsFromFlags failed: %v", err)
+			}
+			opts, err := buildDisplayOptions(cmd)
+			if err != nil {
+				t.Fatalf("buildDisplayOptions failed: %v", err)
+			}
+			if got := opts.Weights.Get("LEARNED-IN-DAYS"); got != -0.5 {
+				t.Errorf("weight = %v, want -0.5", got)
+			}
+			opts.ExtraMetrics = external.Columns
+			if metrics := opts.GetMetrics(); !slices.Equal(metrics, []string{"SFB", "LEARNED-IN-DAYS"}) {
+				t.Errorf("metrics = %v, want [SFB LEARNED-IN-DAYS]", metrics)
+			}
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "rank", "--mf", "community.csv",
+		"-w", "learned-in-days=-0.5", "test.klf"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+
+	cmd.Action = rankAction
+	err = app.Run(context.Background(), []string{"test", "rank", "--mf", "missing.csv", "test.klf"})
+	if err == nil {
+		t.Error("expected error for a missing metrics file")
+	}
+}
+
+// ============================================================================
+// FLIP COMMAND TESTS
+// ============================================================================
+
+// TestFlipCommand_NoArgs_ReturnsError verifies that the flip command requires exactly one layout
+// argument and rejects execution with no arguments via flipAction().
+func TestFlipCommand_NoArgs_ReturnsError(t *testing.T) {
+	cmd := &cli.Command{
+		Name:   "flip",
+		Action: flipAction,
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "flip"})
+	if err == nil {
+		t.Error("expected error for flip with no args, got nil")
+	}
+}
+
+// TestFlipCommand_MultipleArgs_ReturnsError verifies that the flip command rejects multiple
+// layout arguments via flipAction(), as it only processes one layout at a time.
+func TestFlipCommand_MultipleArgs_ReturnsError(t *testing.T) {
+	cmd := &cli.Command{
+		Name:   "flip",
+		Action: flipAction,
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "flip", "layout1", "layout2"})
+	if err == nil {
+		t.Error("expected error for flip with multiple args, got nil")
+	}
+}
+
+// TestOptimizeCommand_DryRunLogFile verifies that optimize refuses --dry-run with the
+// --log-file of the root command, before the log file is created.
+func TestOptimizeCommand_DryRunLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	app := &cli.Command{
+		Name: "keycraft",
+		// Fresh flag instances, to avoid polluting shared flag state
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "log-level", Value: "warn"},
+			&cli.StringFlag{Name: "log-file"},
+		},
+		Before: setupLogging,
+		After:  closeLogging,
+		Commands: []*cli.Command{{
+			Name:   "optimize",
+			Flags:  []cli.Flag{&cli.BoolFlag{Name: "dry-run"}},
+			Action: optimizeAction,
+		}},
+	}
+	err := app.Run(context.Background(), []string{"keycraft", "--log-file", path, "optimize", "--dry-run", "qwerty"})
+	if err == nil || !strings.Contains(err.Error(), "--log-file") {
+		t.Errorf("expected error for optimize --dry-run with --log-file, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected optimize --dry-run not to create the log file: %v", err)
+	}
+}
+
+// TestFlipCommand_Output verifies that flip saves to the --out directory under the --name,
+// and refuses to overwrite an existing layout unless --force is given.
+func TestFlipCommand_Output(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	outDir := filepath.Join(t.TempDir(), "out")
+
+	run := func(args ...string) error {
+		app := &cli.Command{
+			Name: "flip",
+			// Fresh flag instances, to avoid polluting shared flag state
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "out"},
+				&cli.StringFlag{Name: "name"},
+				&cli.BoolFlag{Name: "force"},
+				&cli.BoolFlag{Name: "dry-run"},
+			},
+			Action: flipAction,
+		}
+		return app.Run(context.Background(), append([]string{"flip", "--out", outDir}, args...))
+	}
+
+	if err := run("--dry-run", "test"); err != nil {
+		t.Fatalf("flip --dry-run failed: %v", err)
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("expected flip --dry-run not to create the output directory: %v", err)
+	}
+	if err := run("test"); err != nil {
+		t.Fatalf("flip failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "test-flipped.klf")); err != nil {
+		t.Errorf("expected flipped layout in the output directory: %v", err)
+	}
+	if err := run("test"); err == nil {
+		t.Error("expected error when the flipped layout exists")
+	}
+	if err := run("--force", "test"); err != nil {
+		t.Errorf("flip --force failed: %v", err)
+	}
+	if err := run("--name", "mirror.klf", "test"); err != nil {
+		t.Fatalf("flip --name failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "mirror.klf")); err != nil {
+		t.Errorf("expected layout saved under --name: %v", err)
+	}
+	if err := run("--name", "../escape", "test"); err == nil {
+		t.Error("expected error for a name with a path separator")
+	}
+}
+
+// TestABTestCommand verifies that abtest compares two layouts on the folds of the corpus,
+// and rejects a wrong number of layouts or folds.
+func TestABTestCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"two layouts", []string{"--seed", "1", "test", "alt"}, false},
+		{"three folds", []string{"--seed", "1", "--folds", "3", "test", "alt"}, false},
+		{"one layout", []string{"test"}, true},
+		{"one fold", []string{"--folds", "1", "test", "alt"}, true},
+		{"unknown layout", []string{"test", "missing"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Fresh flag instances, to avoid polluting shared flag state
+			app := &cli.Command{Name: "abtest", Flags: abtestFlags(), Action: abtestAction}
+			err := app.Run(context.Background(), append([]string{"abtest", "--corpus", "default.txt"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("abtest %v: error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStatsCommand verifies that stats summarises the layouts of the layouts directory or of
+// a given directory, and rejects invalid metrics, output formats and bins.
+func TestStatsCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	other := t.TempDir()
+	writeTestLayout(t, other, "test.klf", minimalLayoutContent)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"defaults", nil, false},
+		{"directory csv", []string{"--metrics", "SFB,ALT", "--output", "csv", other}, false},
+		{"all metrics", []string{"--metrics", "all", "--bins", "3"}, false},
+		{"invalid metric", []string{"--metrics", "NOPE"}, true},
+		{"invalid output", []string{"--output", "html"}, true},
+		{"no bins", []string{"--bins", "0"}, true},
+		{"empty directory", []string{t.TempDir()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Fresh flag instances, to avoid polluting shared flag state
+			app := &cli.Command{
+				Name: "stats",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "corpus", Value: "default.txt"},
+					&cli.StringFlag{Name: "metrics", Value: "basic"},
+					&cli.IntFlag{Name: "bins", Value: 10},
+					&cli.StringFlag{Name: "output", Value: "table"},
+				},
+				Action: statsAction,
+			}
+			err := app.Run(context.Background(), append([]string{"stats"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("stats %v: error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// ============================================================================
+// OPTIMIZE COMMAND TESTS
+// ============================================================================
+
+// TestOptimizeCommand_NoArgs_ReturnsError verifies that the optimize command requires exactly one
+// layout argument and rejects execution with no arguments via buildOptimizeInput().
+func TestOptimizeCommand_NoArgs_ReturnsError(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, err := buildOptimizeInput(cmd, nil, false)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "optimize"})
+	if err == nil {
+		t.Error("expected error for optimize with no args, got nil")
+	}
+}
+
+// TestOptimizeCommand_MultipleArgs_ReturnsError verifies that the optimize command rejects multiple
+// layout arguments via buildOptimizeInput(), as it only optimizes one layout at a time.
+func TestOptimizeCommand_MultipleArgs_ReturnsError(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, err := buildOptimizeInput(cmd, nil, false)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "optimize", "layout1", "layout2"})
+	if err == nil {
+		t.Error("expected error for optimize with multiple args, got nil")
+	}
+}
+
+// TestOptimizeCommand_BuildInput verifies that buildOptimizeInput() correctly builds input structure
+// with layout, corpus, targets, weights, and optimizer parameters (generations, maxtime).
+func TestOptimizeCommand_BuildInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	// Create test layout, corpus, and weights
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+
+	// Create weights file
+	weightsContent := `SFB=-10.0`
+	writeTestConfigFile(t, configDir, "weights.txt", weightsContent)
+
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			// skipLayoutLoad=false: load layout from args (optimize command behavior)
+			input, err := buildOptimizeInput(cmd, nil, false)
+			if err != nil {
+				t.Fatalf("buildOptimizeInput failed: %v", err)
+			}
+
+			if input.Layout == nil {
+				t.Error("expected non-nil layout")
+			}
+
+			if input.Corpus == nil {
+				t.Error("expected non-nil corpus")
+			}
+
+			if input.Targets == nil {
+				t.Error("expected non-nil targets")
+			}
+
+			if input.Weights == nil {
+				t.Error("expected non-nil weights")
+			}
+
+			if input.NumGenerations != 500 {
+				t.Errorf("NumGenerations = %d, want 500", input.NumGenerations)
+			}
+
+			if input.MaxTime != 3 {
+				t.Errorf("MaxTime = %d, want 3", input.MaxTime)
+			}
+
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "optimize", "test.klf", "--generations", "500", "--maxtime", "3"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+}
+
+// TestOptimizeCommand_MultipleCorpora verifies that --corpus can be repeated with weights,
+// producing one weighted corpus per flag and a blended corpus for the optimiser.
+func TestOptimizeCommand_MultipleCorpora(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "en.txt")
+	writeTestCorpus(t, corpusDir, "nl.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", `SFB=-10.0`)
+
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildOptimizeInput(cmd, nil, false)
+			if err != nil {
+				return err
+			}
+
+			if len(input.Corpora) != 2 {
+				t.Fatalf("expected 2 corpora, got %d", len(input.Corpora))
+			}
+			if input.Corpora[0].Corpus.Name != "en" || input.Corpora[0].Weight != 0.6 {
+				t.Errorf("unexpected first corpus: %s (weight %g)", input.Corpora[0].Corpus.Name, input.Corpora[0].Weight)
+			}
+			if input.Corpora[1].Corpus.Name != "nl" || input.Corpora[1].Weight != 1 {
+				t.Errorf("unexpected second corpus: %s (weight %g)", input.Corpora[1].Corpus.Name, input.Corpora[1].Weight)
+			}
+			if input.Corpus == nil || input.Corpus.Name != "en + nl" {
+				t.Errorf("expected blended corpus, got %v", input.Corpus)
+			}
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "optimize", "-c", "en.txt:0.6", "-c", "nl.txt", "test.klf"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+
+	err = app.Run(context.Background(), []string{"test", "optimize", "-c", "en.txt:0", "test.klf"})
+	if err == nil || !strings.Contains(err.Error(), "invalid weight") {
+		t.Errorf("expected invalid weight error, got %v", err)
+	}
+}
+
+// TestOptimizeCommand_SwapClasses verifies that --swap-classes pins the keys of other classes
+// and restricts the swaps of the optimiser to keys of the same class.
+func TestOptimizeCommand_SwapClasses(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", `SFB=-10.0`)
+
+	var input kc.OptimizeInput
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, err = buildOptimizeInput(cmd, nil, false)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "optimize", "test.klf"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.SwapClasses != nil {
+		t.Errorf("SwapClasses = %v, want nil by default", input.SwapClasses)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "optimize", "--swap-classes", "punct", "test.klf"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.SwapClasses == nil || !input.SwapClasses[kc.PunctClass] || input.SwapClasses[kc.LetterClass] {
+		t.Errorf("SwapClasses = %v, want punct", input.SwapClasses)
+	}
+	for i, r := range input.Layout.Runes {
+		if want := kc.RuneClassOf(r) != kc.PunctClass; input.Pinned[i] != want {
+			t.Errorf("key %d (%q): pinned = %v, want %v", i, r, input.Pinned[i], want)
+		}
+	}
+
+	err := app.Run(context.Background(), []string{"test", "optimize", "--swap-classes", "digits", "test.klf"})
+	if err == nil || !strings.Contains(err.Error(), "swap class") {
+		t.Errorf("expected invalid swap class error, got %v", err)
+	}
+}
+
+// TestOptimizeCommand_PlaceRemaining verifies that --place-remaining keeps the keys pinned with
+// --pins, but not those pinned by --free or --swap-classes.
+func TestOptimizeCommand_PlaceRemaining(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", `SFB=-10.0`)
+
+	var input kc.OptimizeInput
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, err = buildOptimizeInput(cmd, nil, false)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "optimize", "test.klf"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.PlaceRemaining != nil {
+		t.Errorf("PlaceRemaining = %v, want nil by default", input.PlaceRemaining)
+	}
+
+	for _, tt := range []struct {
+		flags []string
+		kept  string // Characters pinned for the placement besides empty keys and space
+	}{
+		{[]string{"--pins", ";", "--swap-classes", "letters"}, ";"},
+		{[]string{"--free", "abc"}, ""},
+	} {
+		args := append(append([]string{"test", "optimize", "--place-remaining"}, tt.flags...), "test.klf")
+		if err := app.Run(context.Background(), args); err != nil {
+			t.Fatalf("app.Run %v failed: %v", tt.flags, err)
+		}
+		if input.PlaceRemaining == nil {
+			t.Fatalf("%v: PlaceRemaining = nil, want the pins to keep", tt.flags)
+		}
+		for i, r := range input.Layout.Runes {
+			if want := r == 0 || r == ' ' || strings.ContainsRune(tt.kept, r); input.PlaceRemaining[i] != want {
+				t.Errorf("%v: key %d (%q): kept = %v, want %v", tt.flags, i, r, input.PlaceRemaining[i], want)
+			}
+		}
+	}
+}
+
+// TestOptimizeCommand_Taboo verifies that --taboo parses the taboo patterns into the input.
+func TestOptimizeCommand_Taboo(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", `SFB=-10.0`)
+
+	var input kc.OptimizeInput
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, err = buildOptimizeInput(cmd, nil, false)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "optimize", "--taboo", "TH,ing", "test.klf"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.Taboo == nil || input.Taboo.String() != "th,ing" {
+		t.Errorf("Taboo = %v, want th,ing", input.Taboo)
+	}
+
+	err := app.Run(context.Background(), []string{"test", "optimize", "--taboo", "thing", "test.klf"})
+	if err == nil || !strings.Contains(err.Error(), "taboo") {
+		t.Errorf("expected invalid taboo pattern error, got %v", err)
+	}
+}
+
+// TestOptimizeCommand_SpillKeys verifies that --spill-keys frees the empty keys of its region
+// and pins the other empty keys, and rejects regions without empty keys and pins that don't
+// agree with it.
+func TestOptimizeCommand_SpillKeys(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", `SFB=-10.0`)
+	writeTestConfigFile(t, configDir, "free.pins", strings.Repeat(". . . . . . . . . . . .\n", 3)+". . . . . .\n")
+
+	var input kc.OptimizeInput
+	cmd := &cli.Command{
+		Name:  "optimize",
+		Flags: optimizeCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, err = buildOptimizeInput(cmd, nil, false)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "optimize", "--spill-keys", "cols:0", "test.klf"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.SpillKeys == nil || !input.SpillKeys[0] || input.SpillKeys[35] {
+		t.Errorf("SpillKeys = %v, want the empty keys of column 0", input.SpillKeys)
+	}
+	if input.Pinned[0] || !input.Pinned[35] {
+		t.Errorf("r0c0 pinned %v, r2c11 pinned %v; want the spill-over key free and the dead key pinned",
+			input.Pinned[0], input.Pinned[35])
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--spill-keys", "cols:1-10"}, "no empty keys"},
+		{[]string{"--spill-keys", "cols:0", "--pins-file", "free.pins"}, "which are dead"},
+		{[]string{"--spill-keys", "cols:0", "--swap-classes", "letters"}, "pinned by"},
+	} {
+		args := append(append([]string{"test", "optimize"}, tc.args...), "test.klf")
+		if err := app.Run(context.Background(), args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: expected an error containing %q, got %v", tc.args, tc.want, err)
+		}
+	}
+}
+
+// ============================================================================
+// GENERATE COMMAND TESTS
+// ============================================================================
+
+// TestGenerateCommand_NoArgs_ReturnsError verifies that the generate command requires exactly one
+// config file argument and rejects execution with no arguments.
+func TestGenerateCommand_NoArgs_ReturnsError(t *testing.T) {
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, err := buildGenerateInput(cmd)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "generate"})
+	if err == nil {
+		t.Error("expected error for generate with no args, got nil")
+	}
+	if !strings.Contains(err.Error(), "expected exactly 1 config file argument") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// TestGenerateCommand_MultipleArgs_ReturnsError verifies that the generate command rejects
+// multiple config file arguments.
+func TestGenerateCommand_MultipleArgs_ReturnsError(t *testing.T) {
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, err := buildGenerateInput(cmd)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "generate", "file1.gen", "file2.gen"})
+	if err == nil {
+		t.Error("expected error for generate with multiple args, got nil")
+	}
+	if !strings.Contains(err.Error(), "expected exactly 1 config file argument") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// TestGenerateCommand_WrongExtension_ReturnsError verifies that the generate command rejects
+// config files without .gen extension.
+func TestGenerateCommand_WrongExtension_ReturnsError(t *testing.T) {
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, err := buildGenerateInput(cmd)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "generate", "config.klg"})
+	if err == nil {
+		t.Error("expected error for wrong extension, got nil")
+	}
+	if !strings.Contains(err.Error(), "must have .gen extension") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// TestGenerateCommand_BuildInput verifies that buildGenerateInput() correctly builds input structure
+// with config path, max-layouts, and seed from CLI flags.
+func TestGenerateCommand_BuildInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	// Create test config file
+	genContent := `rowstag
+~ 0 0 0 0 0   0 0 0 0 0 ~
+~ 0 0 0 0 0   0 e a i o ~
+~ 0 0 0 0 0   0 0 0 0 0 ~
+      ~ ~ 0   _ ~ ~
+
+charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
+`
+	writeTestConfigFile(t, configDir, "test.gen", genContent)
+
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildGenerateInput(cmd)
+			if err != nil {
+				t.Fatalf("buildGenerateInput failed: %v", err)
+			}
+
+			// Verify config path is resolved
+			if !strings.HasSuffix(input.ConfigPath, "test.gen") {
+				t.Errorf("ConfigPath = %q, want to end with test.gen", input.ConfigPath)
+			}
+
+			// Verify flag defaults
+			if input.MaxLayouts != 100 {
+				t.Errorf("MaxLayouts = %d, want 100", input.MaxLayouts)
+			}
+
+			if input.Seed != 42 {
+				t.Errorf("Seed = %d, want 42", input.Seed)
+			}
+
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "generate", "test.gen", "--max-layouts", "100", "--seed", "42"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+}
+
+// TestGenerateCommand_FlagDefaults verifies that generate command flags have correct default values.
+func TestGenerateCommand_FlagDefaults(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	// Create test config file
+	genContent := `rowstag
+~ 0 0 0 0 0   0 0 0 0 0 ~
+~ 0 0 0 0 0   0 e a i o ~
+~ 0 0 0 0 0   0 0 0 0 0 ~
+      ~ ~ 0   _ ~ ~
+
+charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
+`
+	writeTestConfigFile(t, configDir, "test.gen", genContent)
+
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildGenerateInput(cmd)
+			if err != nil {
+				t.Fatalf("buildGenerateInput failed: %v", err)
+			}
+
+			// Verify defaults
+			if input.MaxLayouts != 5000 {
+				t.Errorf("MaxLayouts default = %d, want 5000", input.MaxLayouts)
+			}
+
+			if input.Seed <= 0 {
+				t.Errorf("Seed default = %d, want a new random seed", input.Seed)
+			}
+
+			// Verify optimize-related flags
+			if cmd.Bool("optimize") != false {
+				t.Error("optimize default should be false")
+			}
+
+			if cmd.Uint("generations") != 1000 {
+				t.Errorf("generations default = %d, want 1000", cmd.Uint("generations"))
+			}
+
+			if cmd.String("pins") != "" {
+				t.Errorf("pins default = %q, want empty", cmd.String("pins"))
+			}
+
+			if cmd.Bool("keep-unoptimized") != false {
+				t.Error("keep-unoptimized default should be false")
+			}
+
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "generate", "test.gen"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+}
+
+// TestGenerateCommand_ConfigFileResolution verifies that config files are resolved correctly
+// from both direct paths and data/config/ directory.
+func TestGenerateCommand_ConfigFileResolution(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	// Create test config file in configDir
+	genContent := `rowstag
+~ 0 0 0 0 0   0 0 0 0 0 ~
+~ 0 0 0 0 0   0 e a i o ~
+~ 0 0 0 0 0   0 0 0 0 0 ~
+      ~ ~ 0   _ ~ ~
+
+charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
+`
+	writeTestConfigFile(t, configDir, "example.gen", genContent)
+
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			input, err := buildGenerateInput(cmd)
+			if err != nil {
+				t.Fatalf("buildGenerateInput failed: %v", err)
+			}
+
+			// Verify path was resolved to configDir
+			expectedPath := filepath.Join(configDir, "example.gen")
+			if input.ConfigPath != expectedPath {
+				t.Errorf("ConfigPath = %q, want %q", input.ConfigPath, expectedPath)
+			}
+
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	// Pass just the filename, should be resolved to configDir
+	err := app.Run(context.Background(), []string{"test", "generate", "example.gen"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+}
+
+// TestGenerateCommand_ConfigFileNotFound verifies that appropriate error is returned
+// when config file is not found.
+func TestGenerateCommand_ConfigFileNotFound(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	cmd := &cli.Command{
+		Name:  "generate",
+		Flags: generateCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			_, err := buildGenerateInput(cmd)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "generate", "nonexistent.gen"})
+	if err == nil {
+		t.Error("expected error for nonexistent file, got nil")
+	}
+	if !strings.Contains(err.Error(), "config file not found") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// ============================================================================
+// RANDOM COMMAND TESTS
+// ============================================================================
+
+// TestRandomCommand_Constraints verifies that the random command samples layouts of the
+// --constraints file, and requires a constraints file or a board.
+func TestRandomCommand_Constraints(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	genContent := `colstag
+~ 0 0 0 0 0   0 0 o 0 0 ~
+~ 1 1 0 0 0   0 i a e 0 ~
+~ 0 0 0 0 0   0 0 0 0 0 ~
+      ~ ~ 0   _ ~ ~
+
+charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
+set1=tn
+`
+	writeTestConfigFile(t, configDir, "vowels.gen", genContent)
+
+	var input kc.RandomLayoutsInput
+	cmd := &cli.Command{
+		Name:  "random",
+		Flags: randomCmdFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, err = buildRandomLayoutsInput(cmd)
+			return err
+		},
+	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	if err := app.Run(context.Background(), []string{"test", "random", "--constraints", "vowels.gen",
+		"--count", "5", "--seed", "3"}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if input.Count != 5 || input.Seed != 3 || input.Config.Template[14].GroupNum != 1 {
+		t.Errorf("input = %+v", input)
+	}
+
+	for _, args := range [][]string{
+		{"test", "random"},
+		{"test", "random", "--constraints", "vowels.txt"},
+		{"test", "random", "--constraints", "vowels.gen", "--count", "0"},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// TestRandomCommand_Save verifies that the random command ranks the random layouts and
+// saves the best ones with --save.
+func TestRandomCommand_Save(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+	outDir := t.TempDir()
+
+	app := &cli.Command{Commands: []*cli.Command{randomCommand}}
+	err := app.Run(context.Background(), []string{"test", "random", "--board", "corne", "--count", "10",
+		"--seed", "1", "--save", "2", "--out", outDir, "test"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(outDir, "_*.klf"))
+	if err != nil || len(files) != 2 {
+		t.Errorf("saved layouts = %v, want 2", files)
+	}
+
+	// A --save beyond the count is rejected
+	err = app.Run(context.Background(), []string{"test", "random", "--board", "corne", "--count", "3",
+		"--save", "4", "--out", outDir})
+	if err == nil {
+		t.Error("expected error for --save beyond --count")
+	}
+}
+
+// ============================================================================
+// PLOT-HISTORY COMMAND TESTS
+// ============================================================================
+
+// TestPlotHistoryCommand_NoArgs_ReturnsError verifies that plot-history requires exactly
+// one history file argument.
+func TestPlotHistoryCommand_NoArgs_ReturnsError(t *testing.T) {
+	app := &cli.Command{
+		Commands: []*cli.Command{plotHistoryCommand},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "plot-history"})
+	if err == nil {
+		t.Error("expected error for plot-history with no args, got nil")
+	}
+}
+
+// TestPlotHistoryCommand_InvalidFormat verifies that an unknown --format is rejected.
+func TestPlotHistoryCommand_InvalidFormat(t *testing.T) {
 	cmd := &cli.Command{
-		Name:  "generate",
-		Flags: generateCmdFlags(),
+		Name:  "plot-history",
+		Flags: plotHistoryFlags,
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, err := buildGenerateInput(cmd)
+			_, err := buildHistoryPlotOptions(cmd)
+			return err
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "plot-history", "--format", "png", "h.jsonl"})
+	if err == nil || !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("expected invalid format error, got %v", err)
+	}
+}
+
+// TestPlotHistoryCommand_SVGOutput verifies that a history file is rendered to an SVG file.
+func TestPlotHistoryCommand_SVGOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	history := `{"iteration":0,"cost":1.5,"metrics":{"SFB":2.1}}
+{"iteration":10,"cost":0.9,"metrics":{"SFB":1.4}}
+{"iteration":25,"cost":0.4,"metrics":{"SFB":1.1}}
+`
+	historyPath := writeTestConfigFile(t, tmpDir, "history.jsonl", history)
+	outPath := filepath.Join(tmpDir, "history.svg")
+
+	app := &cli.Command{
+		Commands: []*cli.Command{plotHistoryCommand},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "plot-history",
+		"--format", "svg", "--metric", "sfb", "--output", outPath, historyPath})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("could not read output: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "<svg") || !strings.Contains(string(data), "SFB by iteration") {
+		t.Errorf("unexpected SVG output:\n%s", data)
+	}
+}
+
+// ============================================================================
+// SWAP-MATRIX COMMAND TESTS
+// ============================================================================
+
+// TestSwapMatrixCommand_NoArgs_ReturnsError verifies that swap-matrix requires exactly
+// one layout argument.
+func TestSwapMatrixCommand_NoArgs_ReturnsError(t *testing.T) {
+	app := &cli.Command{
+		Commands: []*cli.Command{swapMatrixCommand},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "swap-matrix"})
+	if err == nil {
+		t.Error("expected error for swap-matrix with no args, got nil")
+	}
+}
+
+// TestSwapMatrixCommand_BuildOptions verifies format and --top validation.
+func TestSwapMatrixCommand_BuildOptions(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    tui.SwapMatrixOptions
+		wantErr string
+	}{
+		{nil, tui.SwapMatrixOptions{OutputFormat: tui.OutputTable, Top: 10}, ""},
+		{[]string{"--fmt", "CSV", "-n", "3"}, tui.SwapMatrixOptions{OutputFormat: tui.OutputCSV, Top: 3}, ""},
+		{[]string{"--format", "html"}, tui.SwapMatrixOptions{OutputFormat: tui.OutputHTML, Top: 10}, ""},
+		{[]string{"--format", "json"}, tui.SwapMatrixOptions{}, "invalid format"},
+		{[]string{"--top", "0"}, tui.SwapMatrixOptions{}, "--top must be at least 1"},
+	}
+
+	for _, tt := range tests {
+		var got tui.SwapMatrixOptions
+		cmd := &cli.Command{
+			Name:  "swap-matrix",
+			Flags: swapMatrixFlags,
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				var err error
+				got, err = buildSwapMatrixOptions(cmd)
+				return err
+			},
+		}
+		app := &cli.Command{
+			Commands: []*cli.Command{cmd},
+		}
+
+		err := app.Run(context.Background(), append([]string{"test", "swap-matrix"}, tt.args...))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("args %v: expected error containing %q, got %v", tt.args, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("args %v: unexpected error: %v", tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("args %v: got %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+// ============================================================================
+// CALIBRATE COMMAND TESTS
+// ============================================================================
+
+// TestCalibrateCommand_NoArgs_ReturnsError verifies that calibrate requires a file name.
+func TestCalibrateCommand_NoArgs_ReturnsError(t *testing.T) {
+	app := &cli.Command{
+		Commands: []*cli.Command{calibrateCommand},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "calibrate"})
+	if err == nil {
+		t.Error("expected error for calibrate with no args, got nil")
+	}
+}
+
+// TestCalibrateCommand_WritesGeometryFile verifies that the measurements are saved to the
+// config directory and can be loaded back with --geometry-file.
+func TestCalibrateCommand_WritesGeometryFile(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+	defer kc.UseBoardGeometry(nil)
+
+	app := &cli.Command{
+		Commands: []*cli.Command{calibrateCommand},
+	}
+	err := app.Run(context.Background(), []string{"test", "calibrate",
+		"--kp", "18,17", "--cs", "4,4,1.5,0,1.5,3", "choc.geo"})
+	if err != nil {
+		t.Fatalf("calibrate failed: %v", err)
+	}
+
+	geometry, err := kc.NewBoardGeometryFromFile(filepath.Join(configDir, "choc.geo"))
+	if err != nil {
+		t.Fatalf("could not load saved geometry: %v", err)
+	}
+	if geometry.PitchX != 18 || geometry.PitchY != 17 || geometry.ColumnStagger[11] != 4 {
+		t.Errorf("unexpected saved geometry: %+v", geometry)
+	}
+
+	kc.UseBoardGeometry(nil)
+	cmd := &cli.Command{
+		Name:  "view",
+		Flags: commonFlags("geometry-file"),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return loadBoardGeometryFromFlags(cmd)
+		},
+	}
+	app = &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+	if err := app.Run(context.Background(), []string{"test", "view", "--gf", "choc.geo"}); err != nil {
+		t.Fatalf("could not use geometry file: %v", err)
+	}
+	layout := kc.NewSplitLayout("test", kc.ROWSTAG, [42]rune{})
+	if got, want := layout.MustDistance(15, 16).ColDist, 18/kc.StandardKeyPitch; got != want {
+		t.Errorf("calibrated column distance %v, want %v", got, want)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "view", "--gf", "missing.geo"}); err == nil {
+		t.Error("expected error for a missing geometry file, got nil")
+	}
+}
+
+// ============================================================================
+// SHORTCUTS COMMAND TESTS
+// ============================================================================
+
+// TestShortcutsCommand_NoArgs_ReturnsError verifies that shortcuts requires a layout.
+func TestShortcutsCommand_NoArgs_ReturnsError(t *testing.T) {
+	app := &cli.Command{
+		Commands: []*cli.Command{shortcutsCommand},
+	}
+
+	err := app.Run(context.Background(), []string{"test", "shortcuts"})
+	if err == nil {
+		t.Error("expected error for shortcuts with no args, got nil")
+	}
+}
+
+// TestShortcutsCommand_BuildInput verifies that the shortcuts file is loaded from the
+// config directory and that each layout argument is loaded.
+func TestShortcutsCommand_BuildInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestConfigFile(t, configDir, "vim.txt", "modifier ctrl = left-thumb\n50 dd\n20 ctrl+r\n")
+
+	cmd := &cli.Command{
+		Name:  "shortcuts",
+		Flags: shortcutsFlagsSlice(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			set, layouts, err := buildShortcutsInput(cmd)
+			if err != nil {
+				t.Fatalf("buildShortcutsInput failed: %v", err)
+			}
+			if set.Name != "vim" || len(set.Shortcuts) != 2 {
+				t.Errorf("unexpected shortcut set %q with %d shortcuts", set.Name, len(set.Shortcuts))
+			}
+			if set.Holders["ctrl"] != kc.HoldLeftThumb {
+				t.Errorf("ctrl held by %v, want left-thumb", set.Holders["ctrl"])
+			}
+			if len(layouts) != 2 {
+				t.Errorf("expected 2 layouts, got %d", len(layouts))
+			}
+			return nil
+		},
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{cmd},
+	}
+	err := app.Run(context.Background(), []string{"test", "shortcuts", "--sf", "vim.txt", "test", "test.klf"})
+	if err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+
+	cmd.Action = shortcutsAction
+	if err := app.Run(context.Background(), []string{"test", "shortcuts", "test"}); err == nil {
+		t.Error("expected error for a missing shortcuts file, got nil")
+	}
+}
+
+// TestVerifyCommand_UpdateAndVerify verifies that updated reference values pass, and that a
+// changed reference value fails.
+func TestVerifyCommand_UpdateAndVerify(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "test.txt")
+	path := writeTestConfigFile(t, configDir, "verify.txt", "corpus test.txt\ntest SFB 0\ntest ALT 0\n")
+
+	app := &cli.Command{
+		Commands: []*cli.Command{verifyCommand},
+	}
+	if err := app.Run(context.Background(), []string{"test", "verify", "--update"}); err != nil {
+		t.Fatalf("verify --update failed: %v", err)
+	}
+	if err := app.Run(context.Background(), []string{"test", "verify"}); err != nil {
+		t.Fatalf("verify failed after update: %v", err)
+	}
+
+	spec, err := kc.NewVerifySpecFromFile(path)
+	if err != nil {
+		t.Fatalf("could not load updated verify file: %v", err)
+	}
+	spec.Checks[1].Expected += 1
+	if err := spec.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Run(context.Background(), []string{"test", "verify"}); err == nil {
+		t.Error("expected error for a changed reference value, got nil")
+	}
+	if err := app.Run(context.Background(), []string{"test", "verify", "--vf", "missing.txt"}); err == nil {
+		t.Error("expected error for a missing verify file, got nil")
+	}
+}
+
+// TestPublishReportCommand verifies that publish-report loads the baselines, repeats the
+// analysis flags in its reproduction commands, and rejects an invalid format or baseline.
+func TestPublishReportCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-10.0")
+
+	var input kc.PublishInput
+	var format tui.OutputFormat
+	cmd := &cli.Command{
+		Name:  "publish-report",
+		Flags: publishReportFlags(),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			input, format, err = buildPublishInput(cmd)
 			return err
 		},
 	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
+
+	args := []string{"test", "publish-report", "--baselines", "alt", "--weights", "SFB=-5,LSB=-1", "-o", "html", "test"}
+	if err := app.Run(context.Background(), args); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if format != tui.OutputHTML {
+		t.Errorf("format = %q, want html", format)
+	}
+	if len(input.Baselines) != 1 || input.Baselines[0].Name != "alt" {
+		t.Errorf("baselines = %v, want alt", input.Baselines)
+	}
+	want := []string{
+		"keycraft view --corpus default.txt test",
+		"keycraft analyse --corpus default.txt --weights SFB=-5,LSB=-1 test",
+		"keycraft rank --corpus default.txt --weights SFB=-5,LSB=-1 test alt",
+	}
+	if !slices.Equal(input.Commands, want) {
+		t.Errorf("commands = %q, want %q", input.Commands, want)
+	}
+
+	for _, args := range [][]string{
+		{"--baselines", "missing", "test"},
+		{"--baselines", "alt", "--output", "pdf", "test"},
+		{"--baselines", "alt"},
+	} {
+		if err := app.Run(context.Background(), append([]string{"test", "publish-report"}, args...)); err == nil {
+			t.Errorf("publish-report %v: expected an error", args)
+		}
+	}
+}
+
+// TestShellArg verifies that arguments of reproduction commands are quoted when needed.
+func TestShellArg(t *testing.T) {
+	for arg, want := range map[string]string{
+		"default.txt":  "default.txt",
+		"SFB=-5,LSB=1": "SFB=-5,LSB=1",
+		"my corpus":    "'my corpus'",
+		"it's":         `'it'\''s'`,
+	} {
+		if got := shellArg(arg); got != want {
+			t.Errorf("shellArg(%q) = %s, want %s", arg, got, want)
+		}
+	}
+}
+
+// TestWeightsFitCommand verifies that weights fit writes a weights file that can be loaded,
+// and that the layout directories are required.
+func TestWeightsFitCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	preferDir, avoidDir := t.TempDir(), t.TempDir()
+	writeTestLayout(t, preferDir, "alt.klf", alternativeLayoutContent)
+	writeTestLayout(t, avoidDir, "test.klf", minimalLayoutContent)
+
+	app := &cli.Command{
+		Commands: []*cli.Command{weightsCommand},
+	}
+	err := app.Run(context.Background(), []string{"test", "weights", "fit",
+		"--prefer", preferDir, "--avoid", avoidDir, "--metrics", "sfb,lsb,sfs", "--of", "fit.txt"})
+	if err != nil {
+		t.Fatalf("weights fit failed: %v", err)
+	}
+	if _, err := kc.NewWeightsFromParams(filepath.Join(configDir, "fit.txt"), ""); err != nil {
+		t.Errorf("could not load fitted weights: %v", err)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "weights", "fit", "--prefer", preferDir}); err == nil {
+		t.Error("expected error without --avoid, got nil")
+	}
+	if err := app.Run(context.Background(), []string{"test", "weights", "fit",
+		"--prefer", preferDir, "--avoid", avoidDir, "--metrics", "XYZ"}); err == nil {
+		t.Error("expected error for an invalid metric, got nil")
+	}
+}
+
+// TestWeightsReportCommand verifies that weights report saves the normalised weights to a
+// weights file that can be loaded.
+func TestWeightsReportCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "weights.txt", "SFB=-3\nLSB=-1\n")
+
+	app := &cli.Command{
+		Commands: []*cli.Command{weightsCommand},
+	}
+	err := app.Run(context.Background(), []string{"test", "weights", "report", "--normalise", "--of", "normalised.txt"})
+	if err != nil {
+		t.Fatalf("weights report failed: %v", err)
+	}
+	weights, err := kc.NewWeightsFromParams(filepath.Join(configDir, "normalised.txt"), "")
+	if err != nil {
+		t.Fatalf("could not load normalised weights: %v", err)
+	}
+	if weights.Get("SFB") != -0.75 || weights.Get("LSB") != -0.25 {
+		t.Errorf("normalised weights SFB %v and LSB %v, want -0.75 and -0.25", weights.Get("SFB"), weights.Get("LSB"))
+	}
+}
+
+// TestRunsCompareCommand_ArgCount verifies that runs compare requires two run directories,
+// and reports a directory that is not a run.
+func TestRunsCompareCommand_ArgCount(t *testing.T) {
+	app := &cli.Command{
+		Commands: []*cli.Command{runsCommand},
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "runs", "compare", t.TempDir()}); err == nil {
+		t.Error("expected error for runs compare with one run, got nil")
+	}
+	err := app.Run(context.Background(), []string{"test", "runs", "compare", t.TempDir(), t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "could not load run") {
+		t.Errorf("expected error for directories without runs, got %v", err)
+	}
+}
+
+// TestTuneBLSCommand_InvalidInput verifies that tune-bls rejects missing layouts and invalid
+// search settings before probing any parameters.
+func TestTuneBLSCommand_InvalidInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+
+	app := &cli.Command{
+		Commands: []*cli.Command{tuneBLSCommand},
+	}
+
+	for _, args := range [][]string{
+		{"test", "tune-bls"},
+		{"test", "tune-bls", "--search", "anneal", "test"},
+		{"test", "tune-bls", "--probes", "0", "test"},
+		{"test", "tune-bls", "--samples", "0", "test"},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
+	}
+}
+
+// TestExperimentCommand_InvalidInput verifies that the experiment commands reject a missing
+// experiment file argument, an experiment without corpora, and reports without results.
+func TestExperimentCommand_InvalidInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	path := filepath.Join(t.TempDir(), "exp.toml")
+	if err := os.WriteFile(path, []byte("layout = test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &cli.Command{
+		Commands: []*cli.Command{experimentCommand},
+	}
+
+	for _, args := range [][]string{
+		{"test", "experiment", "run"},
+		{"test", "experiment", "run", path},
+		{"test", "experiment", "report", path},
+		{"test", "experiment", "report", "--by", "colour", path},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
+	}
+}
+
+// TestMagicRulesCommand_InvalidInput verifies that magic-rules rejects a missing layout, an
+// invalid number of rules, and a magic key that is not on the layout.
+func TestMagicRulesCommand_InvalidInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+
+	app := &cli.Command{
+		Commands: []*cli.Command{magicRulesCommand},
+	}
+
+	for _, args := range [][]string{
+		{"test", "magic-rules"},
+		{"test", "magic-rules", "--rules", "0", "test"},
+		{"test", "magic-rules", "--key", "**", "test"},
+		{"test", "magic-rules", "--key", "*", "test"},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
+	}
+}
+
+// TestWeightsTimingCommand_InvalidInput verifies that weights timing rejects a missing
+// export, an unknown layout and an export without alternation.
+func TestWeightsTimingCommand_InvalidInput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	export := writeTestConfigFile(t, configDir, "export.csv", "bigram,ms,count\ned,150,50\n")
 
 	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+		Commands: []*cli.Command{weightsCommand},
 	}
 
-	err := app.Run(context.Background(), []string{"test", "generate"})
-	if err == nil {
-		t.Error("expected error for generate with no args, got nil")
-	}
-	if !strings.Contains(err.Error(), "expected exactly 1 config file argument") {
-		t.Errorf("unexpected error message: %v", err)
+	for _, args := range [][]string{
+		{"test", "weights", "timing", "--layout", "test"},
+		{"test", "weights", "timing", "--layout", "test", filepath.Join(configDir, "missing.csv")},
+		{"test", "weights", "timing", "--layout", "nope", export},
+		{"test", "weights", "timing", "--layout", "test", export},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
 	}
 }
 
-// TestGenerateCommand_MultipleArgs_ReturnsError verifies that the generate command rejects
-// multiple config file arguments.
-func TestGenerateCommand_MultipleArgs_ReturnsError(t *testing.T) {
-	cmd := &cli.Command{
-		Name:  "generate",
-		Flags: generateCmdFlags(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, err := buildGenerateInput(cmd)
-			return err
-		},
-	}
+// TestAnalyseCommand_InvalidSections verifies that analyse rejects unknown sections and an
+// invalid page.
+func TestAnalyseCommand_InvalidSections(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
 
 	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+		Commands: []*cli.Command{analyseCommand},
 	}
 
-	err := app.Run(context.Background(), []string{"test", "generate", "file1.gen", "file2.gen"})
-	if err == nil {
-		t.Error("expected error for generate with multiple args, got nil")
-	}
-	if !strings.Contains(err.Error(), "expected exactly 1 config file argument") {
-		t.Errorf("unexpected error message: %v", err)
+	for _, args := range [][]string{
+		{"test", "analyse", "--sections", "stats,nope", "test"},
+		{"test", "analyse", "--page", "0", "test"},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
 	}
 }
 
-// TestGenerateCommand_WrongExtension_ReturnsError verifies that the generate command rejects
-// config files without .gen extension.
-func TestGenerateCommand_WrongExtension_ReturnsError(t *testing.T) {
-	cmd := &cli.Command{
-		Name:  "generate",
-		Flags: generateCmdFlags(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, err := buildGenerateInput(cmd)
-			return err
-		},
-	}
+// TestAnalyseCommand_DetailsOutput verifies that --details-output writes a CSV or JSON file for
+// each metric details table shown, and rejects other formats.
+func TestAnalyseCommand_DetailsOutput(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
 
 	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+		Commands: []*cli.Command{analyseCommand},
 	}
 
-	err := app.Run(context.Background(), []string{"test", "generate", "config.klg"})
-	if err == nil {
-		t.Error("expected error for wrong extension, got nil")
+	dir := filepath.Join(t.TempDir(), "details")
+	args := []string{"test", "analyse", "--quiet", "--sections", "bigrams,alt", "--details-output", "csv:" + dir, "test"}
+	if err := app.Run(context.Background(), args); err != nil {
+		t.Fatalf("analyse failed: %v", err)
 	}
-	if !strings.Contains(err.Error(), "must have .gen extension") {
-		t.Errorf("unexpected error message: %v", err)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"test-ALT.csv", "test-FSB.csv", "test-HSB.csv", "test-LSB.csv", "test-SFB.csv"}
+	if !slices.Equal(names, want) {
+		t.Errorf("files = %v, want %v", names, want)
 	}
-}
 
-// TestGenerateCommand_BuildInput verifies that buildGenerateInput() correctly builds input structure
-// with config path, max-layouts, and seed from CLI flags.
-func TestGenerateCommand_BuildInput(t *testing.T) {
-	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
-	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+	data, err := os.ReadFile(filepath.Join(dir, "test-SFB.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header, _, _ := strings.Cut(string(data), "\n"); header != "SFB,Count,%,Dist,Fgr,Hd,Δrow" {
+		t.Errorf("header = %q", header)
+	}
 
-	// Create test config file
-	genContent := `rowstag
-~ 0 0 0 0 0   0 0 0 0 0 ~
-~ 0 0 0 0 0   0 e a i o ~
-~ 0 0 0 0 0   0 0 0 0 0 ~
-      ~ ~ 0   _ ~ ~
+	jsonDir := filepath.Join(t.TempDir(), "json")
+	args = []string{"test", "analyse", "--quiet", "--sections", "sfb", "--details-output", "json:" + jsonDir, "test"}
+	if err := app.Run(context.Background(), args); err != nil {
+		t.Fatalf("analyse failed: %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(jsonDir, "test-SFB.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var details kc.MetricDetailsJSON
+	if err := json.Unmarshal(data, &details); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if details.Schema != kc.MetricDetailsSchemaVersion || details.Metric != "SFB" {
+		t.Errorf("details = %+v", details)
+	}
 
-charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
-`
-	writeTestConfigFile(t, configDir, "test.gen", genContent)
+	for _, spec := range []string{"html:" + dir, "csv:", dir} {
+		if err := app.Run(context.Background(), []string{"test", "analyse", "--quiet", "--details-output", spec, "test"}); err == nil {
+			t.Errorf("expected error for --details-output %q, got nil", spec)
+		}
+	}
+}
 
-	cmd := &cli.Command{
-		Name:  "generate",
-		Flags: generateCmdFlags(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			input, err := buildGenerateInput(cmd)
-			if err != nil {
-				t.Fatalf("buildGenerateInput failed: %v", err)
-			}
+// TestAnalyseCommand_FocusKey verifies that --focus-key narrows the metric details down to
+// the n-grams with the character, and rejects more than one character.
+func TestAnalyseCommand_FocusKey(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-			// Verify config path is resolved
-			if !strings.HasSuffix(input.ConfigPath, "test.gen") {
-				t.Errorf("ConfigPath = %q, want to end with test.gen", input.ConfigPath)
-			}
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
 
-			// Verify flag defaults
-			if input.MaxLayouts != 100 {
-				t.Errorf("MaxLayouts = %d, want 100", input.MaxLayouts)
-			}
+	app := &cli.Command{
+		Commands: []*cli.Command{analyseCommand},
+	}
 
-			if input.Seed != 42 {
-				t.Errorf("Seed = %d, want 42", input.Seed)
-			}
+	dir := filepath.Join(t.TempDir(), "details")
+	args := []string{"test", "analyse", "--focus-key", "e", "--sections", "2rl,trigrams", "--details-output", "json:" + dir, "test"}
+	if err := app.Run(context.Background(), args); err != nil {
+		t.Fatalf("analyse failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "test-2RL.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var details kc.MetricDetailsJSON
+	if err := json.Unmarshal(data, &details); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(details.Rows) == 0 {
+		t.Fatal("no 2RL trigrams with e")
+	}
+	for _, row := range details.Rows {
+		if !strings.ContainsRune(row.NGram, 'e') {
+			t.Errorf("2RL trigram %q has no e", row.NGram)
+		}
+	}
 
-			return nil
-		},
+	if err := app.Run(context.Background(), []string{"test", "analyse", "--quiet", "--focus-key", "ab", "test"}); err == nil {
+		t.Error("expected error for --focus-key ab, got nil")
 	}
+}
 
+// TestGeometryCommand verifies that geometry writes the key pairs of a layout type as CSV,
+// and rejects a missing or unknown layout type and an unknown format.
+func TestGeometryCommand(t *testing.T) {
 	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+		Commands: []*cli.Command{geometryCommand},
 	}
 
-	err := app.Run(context.Background(), []string{"test", "generate", "test.gen", "--max-layouts", "100", "--seed", "42"})
+	path := filepath.Join(t.TempDir(), "colstag.csv")
+	if err := app.Run(context.Background(), []string{"test", "geometry", "--fmt", "csv", "-o", path, "colstag"}); err != nil {
+		t.Fatalf("geometry failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		t.Fatalf("app.Run failed: %v", err)
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 313 {
+		t.Errorf("got %d CSV lines, want a header and 312 key pairs", lines)
+	}
+
+	for _, args := range [][]string{
+		{"test", "geometry"},
+		{"test", "geometry", "hexagonal"},
+		{"test", "geometry", "--fmt", "html", "ortho"},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
 	}
 }
 
-// TestGenerateCommand_FlagDefaults verifies that generate command flags have correct default values.
-func TestGenerateCommand_FlagDefaults(t *testing.T) {
+// TestPinsGenerateCommand verifies that pins generate writes a pins file that optimize can
+// load, refuses to overwrite it without --force, and rejects an invalid policy.
+func TestPinsGenerateCommand(t *testing.T) {
 	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
 	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-	// Create test config file
-	genContent := `rowstag
-~ 0 0 0 0 0   0 0 0 0 0 ~
-~ 0 0 0 0 0   0 e a i o ~
-~ 0 0 0 0 0   0 0 0 0 0 ~
-      ~ ~ 0   _ ~ ~
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	app := &cli.Command{Commands: []*cli.Command{pinsCommand}}
+	run := func(args ...string) error {
+		return app.Run(context.Background(), append([]string{"test", "pins", "generate"}, args...))
+	}
 
-charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
-`
-	writeTestConfigFile(t, configDir, "test.gen", genContent)
+	if err := run("--policy", "pin-thumbs", "test"); err != nil {
+		t.Fatalf("pins generate failed: %v", err)
+	}
+	pinned, err := kc.LoadPins(filepath.Join(configDir, "test.pin"))
+	if err != nil {
+		t.Fatalf("could not load generated pins: %v", err)
+	}
+	for i, p := range pinned {
+		if p != (i >= 36) {
+			t.Errorf("key %d pinned = %v, want only the thumb keys pinned", i, p)
+		}
+	}
 
-	cmd := &cli.Command{
-		Name:  "generate",
-		Flags: generateCmdFlags(),
-		Action: func(ctx context.Context, cmd *cli.Command) error {
-			input, err := buildGenerateInput(cmd)
-			if err != nil {
-				t.Fatalf("buildGenerateInput failed: %v", err)
-			}
+	if err := run("--policy", "pin-punct", "test"); err == nil {
+		t.Error("expected error for an existing pins file without --force, got nil")
+	}
+	if err := run("--policy", "pin-punct", "--force", "test"); err != nil {
+		t.Errorf("pins generate --force failed: %v", err)
+	}
+	if err := run("--policy", "pin-punct", "-o", "other.pin", "--dry-run", "test"); err != nil {
+		t.Errorf("pins generate --dry-run failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "other.pin")); err == nil {
+		t.Error("dry run wrote a pins file")
+	}
 
-			// Verify defaults
-			if input.MaxLayouts != 5000 {
-				t.Errorf("MaxLayouts default = %d, want 5000", input.MaxLayouts)
-			}
+	for _, args := range [][]string{{"--policy", "pin-all", "test"}, {"--policy", "pin-punct"}, {"--policy", "pin-punct", "missing"}} {
+		if err := run(args...); err == nil {
+			t.Errorf("expected error for %v, got nil", args)
+		}
+	}
+}
 
-			if input.Seed != 0 {
-				t.Errorf("Seed default = %d, want 0", input.Seed)
-			}
+// TestPinsCheckCommand verifies that pins check accepts a pins file of a layout, and rejects
+// pins files with the wrong dimensions.
+func TestPinsCheckCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-			// Verify optimize-related flags
-			if cmd.Bool("optimize") != false {
-				t.Error("optimize default should be false")
-			}
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	writeTestCorpus(t, corpusDir, "default.txt")
+	writeTestConfigFile(t, configDir, "short.pin", "* * *\n")
+	app := &cli.Command{Commands: []*cli.Command{pinsCommand}}
+	run := func(args ...string) error {
+		return app.Run(context.Background(), append([]string{"test", "pins"}, args...))
+	}
 
-			if cmd.Uint("generations") != 1000 {
-				t.Errorf("generations default = %d, want 1000", cmd.Uint("generations"))
-			}
+	if err := run("generate", "--policy", "pin-thumbs", "test"); err != nil {
+		t.Fatalf("pins generate failed: %v", err)
+	}
+	if err := run("check", "test.pin", "test"); err != nil {
+		t.Errorf("pins check failed: %v", err)
+	}
 
-			if cmd.String("pins") != "" {
-				t.Errorf("pins default = %q, want empty", cmd.String("pins"))
-			}
+	for _, args := range [][]string{{"check", "short.pin", "test"}, {"check", "missing.pin", "test"}, {"check", "test.pin"}} {
+		if err := run(args...); err == nil {
+			t.Errorf("expected error for %v, got nil", args)
+		}
+	}
+}
 
-			if cmd.Bool("keep-unoptimized") != false {
-				t.Error("keep-unoptimized default should be false")
-			}
+// TestLegendCommand verifies that legend takes a layout type or a layout, and rejects other
+// arguments.
+func TestLegendCommand(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
 
-			return nil
+	writeTestLayout(t, layoutDir, "alt.klf", alternativeLayoutContent)
+
+	var layoutType kc.LayoutType
+	cmd := &cli.Command{
+		Name: "legend",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var err error
+			layoutType, err = buildLegendInput(cmd)
+			return err
 		},
 	}
+	app := &cli.Command{Commands: []*cli.Command{cmd}}
 
-	app := &cli.Command{
-		Commands: []*cli.Command{cmd},
+	for args, want := range map[string]kc.LayoutType{"": kc.ROWSTAG, "anglemod": kc.ANGLEMOD, "alt": kc.COLSTAG} {
+		if err := app.Run(context.Background(), strings.Fields("test legend "+args)); err != nil {
+			t.Fatalf("legend %s failed: %v", args, err)
+		}
+		if layoutType != want {
+			t.Errorf("legend %s: layout type = %v, want %v", args, layoutType, want)
+		}
 	}
 
-	err := app.Run(context.Background(), []string{"test", "generate", "test.gen"})
-	if err != nil {
-		t.Fatalf("app.Run failed: %v", err)
+	for _, args := range []string{"missing", "rowstag colstag"} {
+		if err := app.Run(context.Background(), strings.Fields("test legend "+args)); err == nil {
+			t.Errorf("expected error for legend %s, got nil", args)
+		}
 	}
 }
 
-// TestGenerateCommand_ConfigFileResolution verifies that config files are resolved correctly
-// from both direct paths and data/config/ directory.
-func TestGenerateCommand_ConfigFileResolution(t *testing.T) {
-	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
-	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+// TestConvertFilesCommand verifies that convert-files converts a directory of layouts to KLE
+// and back, and fails when a file can't be converted.
+func TestConvertFilesCommand(t *testing.T) {
+	dir, outDir := t.TempDir(), t.TempDir()
+	writeTestLayout(t, dir, "test.klf", minimalLayoutContent)
 
-	// Create test config file in configDir
-	genContent := `rowstag
-~ 0 0 0 0 0   0 0 0 0 0 ~
-~ 0 0 0 0 0   0 e a i o ~
-~ 0 0 0 0 0   0 0 0 0 0 ~
-      ~ ~ 0   _ ~ ~
+	app := &cli.Command{
+		Commands: []*cli.Command{convertFilesCommand},
+	}
+	if err := app.Run(context.Background(), []string{"test", "convert-files", "--from", "klf", "--to", "kle", "--out", outDir, dir}); err != nil {
+		t.Fatalf("convert-files to kle failed: %v", err)
+	}
+	if err := app.Run(context.Background(), []string{"test", "convert-files", "--from", "kle", "--to", "klf", outDir}); err != nil {
+		t.Fatalf("convert-files to klf failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "test.klf")); err != nil {
+		t.Errorf("expected converted layout in the output directory: %v", err)
+	}
 
-charset=etaoinshrdlcumwfgypbvkjxqz,./;'_
-`
-	writeTestConfigFile(t, configDir, "example.gen", genContent)
+	writeTestLayout(t, dir, "bad.klf", "rowstag\nq w e\n")
+	if err := app.Run(context.Background(), []string{"test", "convert-files", "--out", outDir, "--force", dir}); err == nil {
+		t.Error("expected error for a layout that can't be converted, got nil")
+	}
+	for _, args := range [][]string{
+		{"test", "convert-files"},
+		{"test", "convert-files", "--from", "kle", "--to", "kle", dir},
+		{"test", "convert-files", "--to", "xml", dir},
+	} {
+		if err := app.Run(context.Background(), args); err == nil {
+			t.Errorf("expected error for %v, got nil", args[1:])
+		}
+	}
+}
 
+// TestGenerateCommand_Board verifies that generate needs no config file with --board, puts
+// the geometry of the board in use, and rejects an unknown board.
+func TestGenerateCommand_Board(t *testing.T) {
+	defer kc.UseBoardGeometry(nil)
+
+	var input kc.GenerateInput
 	cmd := &cli.Command{
 		Name:  "generate",
 		Flags: generateCmdFlags(),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			input, err := buildGenerateInput(cmd)
-			if err != nil {
-				t.Fatalf("buildGenerateInput failed: %v", err)
-			}
-
-			// Verify path was resolved to configDir
-			expectedPath := filepath.Join(configDir, "example.gen")
-			if input.ConfigPath != expectedPath {
-				t.Errorf("ConfigPath = %q, want %q", input.ConfigPath, expectedPath)
-			}
-
-			return nil
+			var err error
+			input, err = buildGenerateInput(cmd)
+			return err
 		},
 	}
-
 	app := &cli.Command{
 		Commands: []*cli.Command{cmd},
 	}
 
-	// Pass just the filename, should be resolved to configDir
-	err := app.Run(context.Background(), []string{"test", "generate", "example.gen"})
-	if err != nil {
-		t.Fatalf("app.Run failed: %v", err)
+	if err := app.Run(context.Background(), []string{"test", "generate", "--board", "ferris"}); err != nil {
+		t.Fatalf("generate --board failed: %v", err)
+	}
+	if input.Board == nil || input.Board.Name != "ferris" || input.ConfigPath != "" {
+		t.Errorf("input = %+v, want the ferris board without a config file", input)
+	}
+	// With choc spacing, keys are closer than on the built-in colstag board
+	boardDist := kc.NewSplitLayout("test", kc.COLSTAG, [42]rune{}).MustDistance(13, 16).ColDist
+	kc.UseBoardGeometry(nil)
+	if defaultDist := kc.NewSplitLayout("test", kc.COLSTAG, [42]rune{}).MustDistance(13, 16).ColDist; boardDist >= defaultDist {
+		t.Errorf("distance on the ferris board = %v, want less than %v", boardDist, defaultDist)
+	}
+	if err := app.Run(context.Background(), []string{"test", "generate", "--board", "planck"}); err == nil {
+		t.Error("expected error for an unknown board, got nil")
 	}
 }
 
-// TestGenerateCommand_ConfigFileNotFound verifies that appropriate error is returned
-// when config file is not found.
-func TestGenerateCommand_ConfigFileNotFound(t *testing.T) {
-	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
-	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
-
+// TestGenerateCommand_Seed verifies that --seed takes a number or "random", and rejects
+// anything else.
+func TestGenerateCommand_Seed(t *testing.T) {
+	var input kc.GenerateInput
 	cmd := &cli.Command{
 		Name:  "generate",
 		Flags: generateCmdFlags(),
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			_, err := buildGenerateInput(cmd)
+			var err error
+			input, err = buildGenerateInput(cmd)
 			return err
 		},
 	}
-
 	app := &cli.Command{
 		Commands: []*cli.Command{cmd},
 	}
+	defer kc.UseBoardGeometry(nil)
 
-	err := app.Run(context.Background(), []string{"test", "generate", "nonexistent.gen"})
-	if err == nil {
-		t.Error("expected error for nonexistent file, got nil")
+	if err := app.Run(context.Background(), []string{"test", "generate", "--board", "corne", "--seed", "7"}); err != nil || input.Seed != 7 {
+		t.Errorf("--seed 7: seed %d, error %v", input.Seed, err)
 	}
-	if !strings.Contains(err.Error(), "config file not found") {
-		t.Errorf("unexpected error message: %v", err)
+	if err := app.Run(context.Background(), []string{"test", "generate", "--board", "corne", "--seed", "random"}); err != nil || input.Seed <= 0 {
+		t.Errorf("--seed random: seed %d, error %v", input.Seed, err)
+	}
+	if err := app.Run(context.Background(), []string{"test", "generate", "--board", "corne", "--seed", "-3"}); err == nil {
+		t.Error("expected error for a negative seed, got nil")
 	}
 }
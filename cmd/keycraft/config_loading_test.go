@@ -3,10 +3,13 @@ package main
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
 
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
 	"github.com/urfave/cli/v3"
 )
 
@@ -549,6 +552,52 @@ func TestLoadLayout_EnsuresKlfExtension(t *testing.T) {
 	}
 }
 
+// TestLoadLayout_ByID verifies that loadLayout() accepts a layout id (or a prefix of it)
+// in place of a layout name.
+func TestLoadLayout_ByID(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	original, err := loadLayout("test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	layout, err := loadLayout(original.ID()[:6])
+	if err != nil {
+		t.Fatalf("could not load layout by id: %v", err)
+	}
+	if layout.Name != "test" {
+		t.Errorf("layout name = %q, want %q", layout.Name, "test")
+	}
+}
+
+// TestLoadLayout_UnresolvedID verifies that a layout id that cannot be resolved, such as
+// one that matches no layout or several, is reported with a warning.
+func TestLoadLayout_UnresolvedID(t *testing.T) {
+	origLayoutDir, origCorpusDir, origConfigDir := setupTestDirs(t)
+	defer restoreTestDirs(origLayoutDir, origCorpusDir, origConfigDir)
+
+	writeTestLayout(t, layoutDir, "test.klf", minimalLayoutContent)
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(newConsoleHandler(&buf, slog.LevelWarn)))
+
+	id := "0000"
+	if original, err := loadLayout("test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if strings.HasPrefix(original.ID(), id) {
+		id = "ffff"
+	}
+	if _, err := loadLayout(id); err == nil {
+		t.Errorf("expected error for layout id %s that matches no layout", id)
+	}
+	if !strings.Contains(buf.String(), "no layout with id "+id) {
+		t.Errorf("warning = %q, want it to say that no layout has id %s", buf.String(), id)
+	}
+}
+
 // TestLoadLayout_MissingFile verifies that an error is returned when attempting to load
 // a layout file that doesn't exist.
 func TestLoadLayout_MissingFile(t *testing.T) {
@@ -876,3 +925,127 @@ func TestError_MalformedFlags(t *testing.T) {
 		})
 	}
 }
+
+// TestTargetLoads_MetricVersion verifies that --metric-version selects older metric
+// definitions and rejects unknown versions.
+func TestTargetLoads_MetricVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"1", 1, false},
+		{fmt.Sprint(kc.CurrentMetricVersion), kc.CurrentMetricVersion, false},
+		{"0", 0, true},
+		{fmt.Sprint(kc.CurrentMetricVersion + 1), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			app := &cli.Command{
+				Name:  "test",
+				Flags: commonFlags("load-targets-file", "metric-version"),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					targets, err := loadTargetLoadsFromFlags(cmd)
+					if tt.wantErr {
+						if err == nil {
+							t.Errorf("expected error for metric version %s, got nil", tt.value)
+						}
+						return nil
+					}
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if targets.MetricVersion != tt.want {
+						t.Errorf("metric version = %d, want %d", targets.MetricVersion, tt.want)
+					}
+					return nil
+				},
+			}
+			_ = app.Run(context.Background(), []string{"test", "--load-targets-file", "", "--metric-version", tt.value})
+		})
+	}
+}
+
+// TestTargetLoads_SFSDefinition verifies that --sfs-definition selects which same-finger
+// skipgrams SFS counts and rejects unknown definitions.
+func TestTargetLoads_SFSDefinition(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    kc.SFSDefinition
+		wantErr bool
+	}{
+		{"all", kc.SFSAll, false},
+		{"alternating", kc.SFSAlternating, false},
+		{"same-hand", kc.SFSSameHand, false},
+		{"other", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			app := &cli.Command{
+				Name:  "test",
+				Flags: commonFlags("load-targets-file", "sfs-definition"),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					targets, err := loadTargetLoadsFromFlags(cmd)
+					if tt.wantErr {
+						if err == nil {
+							t.Errorf("expected error for SFS definition %s, got nil", tt.value)
+						}
+						return nil
+					}
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if targets.SFSDefinition != tt.want {
+						t.Errorf("SFS definition = %q, want %q", targets.SFSDefinition, tt.want)
+					}
+					return nil
+				},
+			}
+			_ = app.Run(context.Background(), []string{"test", "--load-targets-file", "", "--sfs-definition", tt.value})
+		})
+	}
+}
+
+// TestTargetLoads_StrictTargets verifies that --strict-targets turns misconfigured target
+// loads into an error, and that they only warn without it.
+func TestTargetLoads_StrictTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"consistent", []string{"--strict-targets"}, false},
+		{"conflicting hand load warns", []string{"--target-hand-load", "60,40"}, false},
+		{"conflicting hand load", []string{"--strict-targets", "--target-hand-load", "60,40"}, true},
+		{"scaled row load", []string{"--strict-targets", "--target-row-load", "1,2,1"}, true},
+		{"negative pinky penalties", []string{"--strict-targets", "--pinky-penalties", "2,1.5,1,-1,2,1.5"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &cli.Command{
+				Name: "test",
+				// Fresh flag instances, to avoid polluting shared flag state
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "target-hand-load"},
+					&cli.StringFlag{Name: "target-row-load"},
+					&cli.StringFlag{Name: "pinky-penalties"},
+					&cli.BoolFlag{Name: "strict-targets"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					_, err := loadTargetLoadsFromFlags(cmd)
+					if tt.wantErr && err == nil {
+						t.Errorf("expected error for %v, got nil", tt.args)
+					}
+					if !tt.wantErr && err != nil {
+						t.Errorf("unexpected error for %v: %v", tt.args, err)
+					}
+					return nil
+				},
+			}
+			_ = app.Run(context.Background(), append([]string{"test"}, tt.args...))
+		})
+	}
+}
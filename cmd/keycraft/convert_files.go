@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// convertFilesFlags defines flags specific to the convert-files command.
+var convertFilesFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "from",
+		Usage:    "Format of the layout files to convert: " + strings.Join(kc.LayoutFormatNames(), ", ") + ".",
+		Value:    "klf",
+		Category: "Conversion",
+	},
+	&cli.StringFlag{
+		Name:     "to",
+		Usage:    "Format to convert the layout files to: " + strings.Join(kc.LayoutFormatNames(), ", ") + ".",
+		Value:    "kle",
+		Category: "Conversion",
+	},
+	&cli.StringFlag{
+		Name: "layout-type",
+		Usage: "Layout type of KLE files whose notes don't give one: rowstag, anglemod, ortho " +
+			"or colstag.",
+		Value:    "rowstag",
+		Category: "Conversion",
+	},
+	&cli.StringFlag{
+		Name:     "out",
+		Usage:    "Directory to save the converted files to (default: the directory of each file). Created if it doesn't exist.",
+		Category: "Output",
+	},
+	&cli.BoolFlag{
+		Name:     "force",
+		Usage:    "Overwrite existing files instead of skipping them.",
+		Category: "Output",
+	},
+}
+
+// convertFilesCommand defines the CLI command for converting layout files between formats.
+var convertFilesCommand = &cli.Command{
+	Name:      "convert-files",
+	Usage:     "Convert layout files and directories between file formats",
+	Flags:     convertFilesFlags,
+	ArgsUsage: "<file or dir> [<file or dir>...]",
+	Action:    convertFilesAction,
+}
+
+// convertFilesAction converts the given files, and the files of the --from format in the
+// given directories, and renders a summary. It fails if any file could not be converted.
+func convertFilesAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildConvertInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	result, err := kc.ConvertLayoutFiles(input)
+	if err != nil {
+		return fmt.Errorf("could not convert layout files: %w", err)
+	}
+	if err := tui.RenderConvert(result); err != nil {
+		return err
+	}
+
+	if failed := result.NumFailed(); failed > 0 {
+		return fmt.Errorf("could not convert %d of %d files", failed, len(result.Files))
+	}
+	return nil
+}
+
+// buildConvertInput gathers all input parameters for converting layout files.
+func buildConvertInput(c *cli.Command) (kc.ConvertInput, error) {
+	if c.NArg() == 0 {
+		return kc.ConvertInput{}, fmt.Errorf("expected at least 1 file or directory")
+	}
+
+	from, err := kc.ParseLayoutFormat(c.String("from"))
+	if err != nil {
+		return kc.ConvertInput{}, err
+	}
+	to, err := kc.ParseLayoutFormat(c.String("to"))
+	if err != nil {
+		return kc.ConvertInput{}, err
+	}
+	if from.Name == to.Name {
+		return kc.ConvertInput{}, fmt.Errorf("--from and --to must be different formats, got %s for both", from.Name)
+	}
+
+	layoutType, ok := kc.ParseLayoutType(c.String("layout-type"))
+	if !ok {
+		return kc.ConvertInput{}, fmt.Errorf("invalid layout type %q: must be one of: rowstag, anglemod, ortho, colstag", c.String("layout-type"))
+	}
+
+	return kc.ConvertInput{
+		Paths:      c.Args().Slice(),
+		From:       from,
+		To:         to,
+		OutDir:     c.String("out"),
+		Force:      c.Bool("force"),
+		LayoutType: layoutType,
+	}, nil
+}
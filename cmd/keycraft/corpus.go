@@ -3,6 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	kc "github.com/rbscholtus/keycraft/internal/keycraft"
 	"github.com/rbscholtus/keycraft/internal/tui"
@@ -27,11 +32,33 @@ var corpusFlags = []cli.Flag{
 			return nil
 		},
 	},
+	&cli.IntFlag{
+		Name:    "preview",
+		Aliases: []string{"pv"},
+		Usage: "Show only the top unigrams, bigrams, skipgrams and trigrams side by side, this many of " +
+			"each, with the share of the corpus that they cover together (0 = the full statistics).",
+		Category: "Display",
+		Action: func(ctx context.Context, c *cli.Command, value int) error {
+			if isShellCompletion() {
+				return nil
+			}
+			if value < 0 {
+				return fmt.Errorf("--preview must not be negative (got %d)", value)
+			}
+			return nil
+		},
+	},
+	&cli.StringFlag{
+		Name: "export",
+		Usage: "Also write the n-grams of the preview to this CSV file (TSV if it ends in .tsv), without " +
+			"rounding. Without --preview, the top --corpus-rows n-grams of each kind are written.",
+		Category: "Output",
+	},
 	&cli.Float64Flag{
 		Name: "coverage",
 		Usage: "Corpus word coverage percentage (0.1-100.0). Filters " +
 			"low-frequency words. Forces cache rebuild.",
-		Value:    98.0,
+		Value:    defaultCoverage,
 		Category: "",
 		Action: func(ctx context.Context, c *cli.Command, value float64) error {
 			if isShellCompletion() {
@@ -45,10 +72,17 @@ var corpusFlags = []cli.Flag{
 	},
 }
 
+// defaultCoverage is the default of the --coverage flag.
+const defaultCoverage = 98.0
+
+// defaultSubstitutionsFile is the substitution table that the corpus command reports on
+// when the corpus was built without one.
+const defaultSubstitutionsFile = "substitutions.txt"
+
 // corpusCmdFlags returns all flags for the corpus command.
 func corpusCmdFlags() []cli.Flag {
-	commonFlags := commonFlags("corpus")
-	return append(commonFlags, corpusFlags...)
+	commonFlags := commonFlags("corpus", "bigram-weighting")
+	return append(append(commonFlags, corpusBuildFlags()...), corpusFlags...)
 }
 
 // corpusCommand defines the CLI command for displaying corpus statistics.
@@ -59,6 +93,34 @@ var corpusCommand = &cli.Command{
 	Flags:         corpusCmdFlags(),
 	Action:        corpusAction,
 	ShellComplete: layoutShellComplete,
+	Commands:      []*cli.Command{corpusFetchCommand},
+}
+
+// corpusFetchCommand defines the CLI command for downloading a well-known corpus.
+var corpusFetchCommand = &cli.Command{
+	Name:  "fetch",
+	Usage: "Download a well-known corpus into the corpus directory",
+	Description: "Downloads the corpus of a preset from the presets file, verifies its checksum " +
+		"if the preset pins one, and decompresses it. Corpora that were fetched before are kept. " +
+		"Without a preset, lists the presets with their licenses. With --pin, the checksum of the " +
+		"download is written into the presets file, so that later downloads are verified.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "presets-file",
+			Usage: "Corpus presets file (from data/config directory).",
+			Value: "corpus_presets.txt",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Download the corpus again, even if it was fetched before.",
+		},
+		&cli.BoolFlag{
+			Name:  "pin",
+			Usage: "Download the corpus and write its checksum into the presets file, replacing the one it pins.",
+		},
+	},
+	ArgsUsage: "[<preset>]",
+	Action:    corpusFetchAction,
 }
 
 // corpusAction processes a text corpus to extract and display n-gram frequency
@@ -82,7 +144,34 @@ func corpusAction(ctx context.Context, c *cli.Command) error {
 	}
 
 	// 3. Render (presentation layer in tui package)
-	return tui.RenderCorpus(result)
+	if err := tui.RenderCorpus(result); err != nil {
+		return err
+	}
+
+	if path := c.String("export"); path != "" {
+		preview := result.Preview
+		if preview == nil {
+			preview = input.Corpus.Preview(input.NRows)
+		}
+		if err := exportCorpusPreview(path, preview); err != nil {
+			return err
+		}
+		fmt.Printf("\nSaved the n-grams to %s\n", path)
+	}
+	return nil
+}
+
+// exportCorpusPreview writes the n-grams of a corpus preview to a CSV or TSV file.
+func exportCorpusPreview(path string, preview *kc.CorpusPreview) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create export file %s: %w", path, err)
+	}
+	defer kc.CloseFile(f)
+	if err := tui.WriteCorpusPreviewCSV(f, preview, strings.EqualFold(filepath.Ext(path), ".tsv")); err != nil {
+		return fmt.Errorf("could not write n-grams to %s: %w", path, err)
+	}
+	return nil
 }
 
 // buildCorpusInput gathers all input parameters for corpus display.
@@ -99,8 +188,83 @@ func buildCorpusInput(c *cli.Command) (kc.CorpusInput, error) {
 
 	nrows := c.Int("corpus-rows")
 
+	// Report on the default table if it exists; --substitute was loaded with the corpus
+	var substitutions map[rune]rune
+	if c.String("substitute") == "" {
+		path := filepath.Join(configDir, defaultSubstitutionsFile)
+		if _, err := os.Stat(path); err == nil {
+			if substitutions, err = kc.LoadSubstitutions(path); err != nil {
+				return kc.CorpusInput{}, fmt.Errorf("could not load substitutions: %w", err)
+			}
+		}
+	}
+
 	return kc.CorpusInput{
-		Corpus: corpus,
-		NRows:  nrows,
+		Corpus:        corpus,
+		NRows:         nrows,
+		Substitutions: substitutions,
+		Preview:       int(c.Int("preview")),
 	}, nil
 }
+
+// corpusFetchAction downloads the corpus of a preset, or lists the presets.
+func corpusFetchAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+	if c.NArg() > 1 {
+		return fmt.Errorf("expected at most 1 preset, got %d", c.NArg())
+	}
+
+	presets, err := kc.LoadCorpusPresets(filepath.Join(configDir, c.String("presets-file")))
+	if err != nil {
+		return fmt.Errorf("could not load corpus presets: %w", err)
+	}
+	if c.NArg() == 0 {
+		tui.RenderCorpusPresets(presets)
+		return nil
+	}
+
+	name := strings.ToLower(c.Args().First())
+	i := slices.IndexFunc(presets, func(p kc.CorpusPreset) bool { return p.Name == name })
+	if i < 0 {
+		names := make([]string, len(presets))
+		for j, p := range presets {
+			names[j] = p.Name
+		}
+		return fmt.Errorf("unknown corpus preset %q: must be one of %s", name, strings.Join(names, ", "))
+	}
+	preset := presets[i]
+
+	fmt.Printf("Fetching %s from %s\nLicense: %s\n", preset.Name, preset.URL, preset.License)
+	pin := c.Bool("pin")
+	if pin {
+		// Replace the pinned checksum with that of a new download
+		preset.SHA256 = ""
+	}
+	result, err := kc.FetchCorpus(ctx, preset, corpusDir, c.Bool("force") || pin)
+	if err != nil {
+		return fmt.Errorf("could not fetch corpus: %w", err)
+	}
+	if result.Cached {
+		fmt.Printf("%s was fetched before; use --force to download it again\n", result.Path)
+		return nil
+	}
+	checksum := "not pinned"
+	switch {
+	case pin:
+		path := filepath.Join(configDir, c.String("presets-file"))
+		if err := kc.PinCorpusPreset(path, preset.Name, result.SHA256); err != nil {
+			return fmt.Errorf("could not pin the checksum: %w", err)
+		}
+		checksum = "pinned in " + path
+	case result.Checked:
+		checksum = "verified"
+	default:
+		slog.Warn(fmt.Sprintf("The %s preset pins no checksum, so the download was not verified; "+
+			"use --pin to pin it", preset.Name))
+	}
+	fmt.Printf("Downloaded %s bytes, SHA-256 %s (%s)\n", tui.Comma(result.Bytes), result.SHA256, checksum)
+	fmt.Printf("Saved the corpus to %s; use it with --corpus %s\n", result.Path, preset.File)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// dedupeFlags defines flags specific to the dedupe command.
+var dedupeFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:     "max-diff",
+		Aliases:  []string{"n"},
+		Usage:    "Maximum number of differing keys for layouts to count as near-duplicates (0 = exact only).",
+		Value:    2,
+		Category: "Display",
+	},
+}
+
+// dedupeCommand defines the CLI command for finding duplicate layouts in a directory.
+var dedupeCommand = &cli.Command{
+	Name:      "dedupe",
+	Usage:     "Report identical and near-identical layouts in a directory",
+	Flags:     dedupeFlags,
+	ArgsUsage: "[<dir>]",
+	Action:    dedupeAction,
+}
+
+// dedupeAction scans a layout directory (default: the layouts directory) and
+// renders clusters of identical and near-identical layouts.
+func dedupeAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildDedupeInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	result, err := kc.FindDuplicateLayouts(input)
+	if err != nil {
+		return fmt.Errorf("could not find duplicate layouts: %w", err)
+	}
+
+	return tui.RenderDedupe(result)
+}
+
+// buildDedupeInput gathers all input parameters for duplicate detection.
+func buildDedupeInput(c *cli.Command) (kc.DedupeInput, error) {
+	if c.NArg() > 1 {
+		return kc.DedupeInput{}, fmt.Errorf("expected at most 1 directory, got %d", c.NArg())
+	}
+
+	dir := layoutDir
+	if c.NArg() == 1 {
+		dir = c.Args().First()
+	}
+
+	maxDiff := c.Int("max-diff")
+	if maxDiff < 0 {
+		return kc.DedupeInput{}, fmt.Errorf("max-diff must not be negative. Got: %d", maxDiff)
+	}
+
+	return kc.DedupeInput{
+		LayoutsDir: dir,
+		MaxDiff:    int(maxDiff),
+	}, nil
+}
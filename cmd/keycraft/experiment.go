@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// experimentCommand groups the commands that run and report optimization experiments.
+var experimentCommand = &cli.Command{
+	Name:  "experiment",
+	Usage: "Run optimization experiments defined in a file, and report their results",
+	Description: "An experiment file defines a layout to optimize, and the corpora, weights files, " +
+		"algorithms and seeds to optimize it with; every combination is a trial. The results of " +
+		"the trials are stored as JSONL next to the experiment file.",
+	Commands: []*cli.Command{experimentRunCommand, experimentReportCommand},
+}
+
+// experimentResultsFlag selects the results file of an experiment.
+var experimentResultsFlag = &cli.StringFlag{
+	Name:  "results",
+	Usage: "JSONL results file of the experiment. Default: the experiment file with extension .results.jsonl.",
+}
+
+// experimentRunCommand defines the CLI command for running the trials of an experiment.
+var experimentRunCommand = &cli.Command{
+	Name:  "run",
+	Usage: "Run the trials of an experiment that have no results yet",
+	Description: "Runs the trials of the experiment on a pool of workers, each trial a sequential " +
+		"BLS, and appends the result of every finished trial to the results file. Trials that " +
+		"already have results are skipped, so an interrupted experiment resumes where it stopped.",
+	Flags: append(commonFlags("bigram-weighting", "geometry-file", "baseline", "load-targets-file",
+		"target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties", "roll-quality", "fatigue", "strict-targets",
+		"reference-glob", "reference-list"),
+		experimentResultsFlag,
+		&cli.UintFlag{
+			Name:  "workers",
+			Usage: "Number of trials to run at the same time (0 = one per CPU).",
+		},
+	),
+	ArgsUsage: "<experiment-file>",
+	Action:    experimentRunAction,
+}
+
+// experimentReportCommand defines the CLI command for aggregating the results of an experiment.
+var experimentReportCommand = &cli.Command{
+	Name:  "report",
+	Usage: "Aggregate the results of an experiment",
+	Description: "Groups the results of an experiment by the given dimensions, and shows the " +
+		"number of runs, the mean, spread and lowest best cost, the mean gain over the initial " +
+		"layout, and the mean duration of each group.",
+	Flags: []cli.Flag{
+		experimentResultsFlag,
+		&cli.StringFlag{
+			Name:  "by",
+			Usage: "Comma-separated dimensions to group by: " + strings.Join(kc.ExperimentDimensions, ", ") + ".",
+			Value: "corpus,weights,algorithm",
+		},
+	},
+	ArgsUsage: "<experiment-file>",
+	Action:    experimentReportAction,
+}
+
+// experimentResultsPath returns the results file of the experiment file given as argument.
+func experimentResultsPath(c *cli.Command) (string, error) {
+	if c.NArg() != 1 {
+		return "", fmt.Errorf("expected exactly 1 experiment file, got %d", c.NArg())
+	}
+	if path := c.String("results"); path != "" {
+		return path, nil
+	}
+	path := c.Args().First()
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".results.jsonl", nil
+}
+
+// experimentRunAction runs the remaining trials of an experiment, saving each result as it
+// finishes. Ctrl+C stops the experiment after saving the finished trials, and the command
+// exits with exitInterrupted.
+func experimentRunAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	resultsPath, err := experimentResultsPath(c)
+	if err != nil {
+		return err
+	}
+	exp, err := kc.NewExperimentFromFile(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("could not load experiment: %w", err)
+	}
+	records, err := kc.LoadExperimentRecords(resultsPath)
+	if err != nil {
+		return err
+	}
+	jobs, err := buildExperimentJobs(c, exp, records)
+	if err != nil {
+		return fmt.Errorf("could not prepare experiment: %w", err)
+	}
+
+	total := len(exp.Trials())
+	fmt.Printf("Experiment %s: %d trials, %d with results, %d to run\n", exp.Name, total, total-len(jobs), len(jobs))
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(resultsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open experiment results: %w", err)
+	}
+	defer kc.CloseFile(file)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	saved := 0
+	err = kc.RunExperiment(ctx, jobs, int(c.Uint("workers")), func(r kc.ExperimentRecord) error {
+		saved++
+		fmt.Printf("[%d/%d] %s, %s, %s, seed %d: best cost %.4f in %.1fs\n", saved, len(jobs),
+			r.Corpus, r.Weights, r.Algorithm, r.Seed, r.BestCost, float64(r.ElapsedMs)/1000)
+		return kc.WriteExperimentRecord(file, r)
+	})
+	if errors.Is(err, context.Canceled) {
+		return &exitCodeError{
+			err:  fmt.Errorf("experiment interrupted after %d of %d trials; run it again to resume", saved, len(jobs)),
+			code: exitInterrupted,
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved the results to %s\n", resultsPath)
+	return nil
+}
+
+// buildExperimentJobs loads the layout, corpora and weights of an experiment, and returns a
+// job for every trial without a result in records.
+func buildExperimentJobs(c *cli.Command, exp *kc.Experiment, records []kc.ExperimentRecord) ([]kc.ExperimentJob, error) {
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return nil, err
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not load target loads: %w", err)
+	}
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return nil, fmt.Errorf("could not load reference layouts: %w", err)
+	}
+	weighting, err := loadBigramWeightingFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := loadLayout(exp.Layout)
+	if err != nil {
+		return nil, fmt.Errorf("could not load layout: %w", err)
+	}
+	pinsPath := exp.PinsFile
+	if pinsPath != "" {
+		pinsPath = filepath.Join(configDir, pinsPath)
+	}
+	pinned, err := kc.LoadPinsFromParams(pinsPath, exp.Pins, exp.Free, layout)
+	if err != nil {
+		return nil, fmt.Errorf("could not load pins: %w", err)
+	}
+	var tuning *kc.BLSTuning
+	if exp.BLSParams != "" {
+		if tuning, err = kc.ParseBLSTuning(exp.BLSParams); err != nil {
+			return nil, fmt.Errorf("could not parse BLS parameters: %w", err)
+		}
+	}
+
+	corpora := map[string]*kc.Corpus{}
+	for _, name := range exp.Corpora {
+		corpus, err := loadCorpus(name, kc.CorpusBuildOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not load corpus: %w", err)
+		}
+		corpora[name] = corpus.Weighted(weighting)
+	}
+	weights := map[string]*kc.Weights{}
+	for _, name := range exp.Weights {
+		w, err := kc.NewWeightsFromParams(filepath.Join(configDir, name), "")
+		if err != nil {
+			return nil, fmt.Errorf("could not load weights: %w", err)
+		}
+		weights[name] = w
+	}
+
+	done := map[string]bool{}
+	for _, r := range records {
+		done[r.Key()] = true
+	}
+	var jobs []kc.ExperimentJob
+	for _, trial := range exp.Trials() {
+		record := kc.ExperimentRecord{
+			Experiment:      exp.Name,
+			Layout:          layout.Name,
+			ExperimentTrial: trial,
+			Generations:     exp.Generations,
+			MaxTime:         exp.MaxTime,
+			BLSParams:       exp.BLSParams,
+		}
+		if done[record.Key()] {
+			continue
+		}
+		jobs = append(jobs, kc.ExperimentJob{
+			Record: record,
+			Input: kc.OptimizeInput{
+				Layout:         layout,
+				LayoutsDir:     layoutDir,
+				Corpus:         corpora[trial.Corpus],
+				Targets:        targets,
+				Weights:        weights[trial.Weights],
+				Reference:      reference,
+				Pinned:         pinned,
+				NumGenerations: exp.Generations,
+				MaxTime:        exp.MaxTime,
+				Seed:           trial.Seed,
+				CompoundMoves:  kc.ExperimentAlgorithms[trial.Algorithm],
+				Tuning:         tuning,
+			},
+		})
+	}
+	return jobs, nil
+}
+
+// experimentReportAction aggregates and renders the results of an experiment.
+func experimentReportAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	resultsPath, err := experimentResultsPath(c)
+	if err != nil {
+		return err
+	}
+	records, err := kc.LoadExperimentRecords(resultsPath)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("no experiment results in %s", resultsPath)
+	}
+
+	by := strings.Split(c.String("by"), ",")
+	for i := range by {
+		by[i] = strings.ToLower(strings.TrimSpace(by[i]))
+	}
+	groups, err := kc.AggregateExperiment(records, by)
+	if err != nil {
+		return fmt.Errorf("could not aggregate experiment results: %w", err)
+	}
+	tui.RenderExperimentReport(groups, by, len(records))
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"slices"
 	"strings"
 
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
 	"github.com/urfave/cli/v3"
 )
 
@@ -22,6 +23,47 @@ var commonFlagsMap = map[string]cli.Flag{
 		Value:    "default.txt",
 		Category: "", // General/uncategorized
 	},
+	"bigram-weighting": &cli.StringFlag{
+		Name:    "bigram-weighting",
+		Aliases: []string{"bw"},
+		Usage: "Reweight corpus bigrams, and trigrams and skipgrams alike, by word position or word " +
+			"frequency class, as comma-separated class=weight pairs (e.g., \"initial=2,final=1.5,common=2\"). " +
+			"Classes: initial, final (first/last two or three letters of a word), common (words " +
+			"covering 50% of occurrences).",
+		Category: "",
+	},
+	"geometry-file": &cli.StringFlag{
+		Name:    "geometry-file",
+		Aliases: []string{"gf"},
+		Usage: "Board geometry file with measured key pitch and stagger (from data/config directory, " +
+			"see the calibrate command). Replaces the built-in distances of all layout types.",
+		Category: "",
+	},
+	"metric-version": &cli.IntFlag{
+		Name:    "metric-version",
+		Aliases: []string{"mv"},
+		Usage: fmt.Sprintf("Version of the metric definitions to compute (1-%d), for comparing with results "+
+			"published by older versions. See the changelog of metric definitions in the README.",
+			kc.CurrentMetricVersion),
+		Value:    kc.CurrentMetricVersion,
+		Category: "",
+	},
+	"sfs-definition": &cli.StringFlag{
+		Name:    "sfs-definition",
+		Aliases: []string{"sfsd"},
+		Usage: "Which same-finger skipgrams SFS counts: \"all\", \"alternating\" (only those with a key of " +
+			"the other hand in between), or \"same-hand\" (only those with a key of the same hand in between), " +
+			"for comparing with analysers that define SFS differently.",
+		Value:    string(kc.SFSAll),
+		Category: "",
+	},
+	"baseline": &cli.StringFlag{
+		Name:    "baseline",
+		Aliases: []string{"bl"},
+		Usage: "Layout to measure the learning cost from (e.g., qwerty): MOVE counts the characters " +
+			"on a different key, MOVE-FREQ the % of typed characters on a different key.",
+		Category: "",
+	},
 	"load-targets-file": &cli.StringFlag{
 		Name:    "load-targets-file",
 		Aliases: []string{"ltf"},
@@ -60,6 +102,28 @@ var commonFlagsMap = map[string]cli.Flag{
 			"12 values (left, then right). Higher = more penalty. Overrides load_targets file.",
 		Category: "Targets and Weights",
 	},
+	"roll-quality": &cli.StringFlag{
+		Name:    "roll-quality",
+		Aliases: []string{"rq"},
+		Usage: "Weights of the components of ROLLQ: 4 values for direction (inward over outward), " +
+			"length (3-key over 2-key rolls), row (home row over other rows, and one row over " +
+			"crossing rows) and finger strength. Overrides load_targets file.",
+		Category: "Targets and Weights",
+	},
+	"fatigue": &cli.StringFlag{
+		Name:    "fatigue",
+		Aliases: []string{"ft"},
+		Usage: "Settings of FATIGUE: 2 values for the window (number of recent keystrokes) and the " +
+			"limit (uses of a finger within the window above which a keystroke counts). " +
+			"Overrides load_targets file.",
+		Category: "Targets and Weights",
+	},
+	"strict-targets": &cli.BoolFlag{
+		Name: "strict-targets",
+		Usage: "Fail on misconfigured target loads, such as finger loads that don't add up to the " +
+			"hand loads, instead of warning and proceeding.",
+		Category: "Targets and Weights",
+	},
 	"weights-file": &cli.StringFlag{
 		Name:    "weights-file",
 		Aliases: []string{"wf"},
@@ -72,7 +136,31 @@ var commonFlagsMap = map[string]cli.Flag{
 		Name:    "weights",
 		Aliases: []string{"w"},
 		Usage: "Custom metric weights as comma-separated pairs " +
-			"(e.g., \"SFB=-10,LSB=-5\"). Overrides weights file.",
+			"(e.g., \"SFB=-10,LSB=-5\"). Overrides weights file. \"equal\" uses the built-in equal " +
+			"weights instead of the weights file, as a neutral baseline (e.g., \"equal,SFB=-2\").",
+		Category: "Targets and Weights",
+	},
+	"inline": &cli.StringSliceFlag{
+		Name: "inline",
+		Usage: "Layout given inline instead of a layout file, as \"<type>:<42 keys>\" with optional " +
+			"\"; name=<name>\" (e.g., \"colstag:~qwfpbjluy;~~arstgmneio'~zxcdvkh,./~~~_~~~; name=mytest\"). " +
+			"Keys are one string of characters (\"~\" for empty, \"_\" for space) or 42 keys separated by " +
+			"spaces as in a .klf file. Repeat for several layouts.",
+		Category: "",
+	},
+	"reference-glob": &cli.StringFlag{
+		Name:    "reference-glob",
+		Aliases: []string{"rg"},
+		Usage: "Comma-separated glob patterns selecting the layouts used to normalise metrics " +
+			"(e.g., \"qwerty,*mak*\"). Combined with --reference-list. " +
+			"Default: all layouts except _*, *-flipped*, *-best*, *-opt*.",
+		Category: "Targets and Weights",
+	},
+	"reference-list": &cli.StringFlag{
+		Name:    "reference-list",
+		Aliases: []string{"rl"},
+		Usage: "File listing the layouts used to normalise metrics, one name per line " +
+			"(from data/config directory), each of which must exist. Combined with --reference-glob.",
 		Category: "Targets and Weights",
 	},
 }
@@ -83,6 +171,117 @@ func commonFlags(keys ...string) []cli.Flag {
 	return flags(commonFlagsMap, keys...)
 }
 
+// saveFlagsMap holds the flags of the commands that save layouts (flip, optimize, generate),
+// keyed by their primary name.
+var saveFlagsMap = map[string]cli.Flag{
+	"out": &cli.StringFlag{
+		Name:     "out",
+		Usage:    "Directory to save layouts to (default: the layouts directory). Created if it doesn't exist.",
+		Category: "Output",
+	},
+	"name": &cli.StringFlag{
+		Name: "name",
+		Usage: "Name of the saved layout instead of the default suffixed name. The generate command " +
+			"prefixes the generated names with it.",
+		Category: "Output",
+	},
+	"force": &cli.BoolFlag{
+		Name:     "force",
+		Usage:    "Overwrite existing layout files instead of refusing to save.",
+		Category: "Output",
+	},
+	"dry-run": &cli.BoolFlag{
+		Name: "dry-run",
+		Usage: "Do all the work and show what would be saved, including the board and metrics " +
+			"of the layouts, without writing any file.",
+		Category: "Output",
+	},
+}
+
+// corpusBuildFlagsMap holds the flags that change how a corpus is built, for the commands that
+// load a corpus, keyed by their primary name. A corpus built with them is cached apart from
+// the default build of the corpus.
+var corpusBuildFlagsMap = map[string]cli.Flag{
+	"exclude-words": &cli.StringFlag{
+		Name: "exclude-words",
+		Usage: "File with words and tokens to remove from the corpus text before counting n-grams, " +
+			"one per line (from data/config directory).",
+		Category: "",
+	},
+	"case-sensitive": &cli.BoolFlag{
+		Name: "case-sensitive",
+		Usage: "Keep the case of letters in the corpus n-grams, so that uppercase letters are analysed " +
+			"as Shift plus their lowercase key on layouts with a shift: line.",
+		Category: "",
+	},
+	"include-space": &cli.BoolFlag{
+		Name: "include-space",
+		Usage: "Include the space between words in the corpus n-grams, so that pressing space counts " +
+			"towards all metrics, such as alternations and rolls onto the space thumb.",
+		Category: "",
+	},
+	"skipgram-distance": &cli.IntFlag{
+		Name: "skipgram-distance",
+		Usage: fmt.Sprintf("Count skipgrams that skip up to this many characters (1-%d), weighted by "+
+			"--skipgram-decay for each further skipped character. Stored in the corpus cache, so "+
+			"that SFS and other skipgram metrics are comparable between corpora built with the same "+
+			"policy.", kc.MaxSkipgramDistance),
+		Value:    1,
+		Category: "",
+	},
+	"skipgram-decay": &cli.Float64Flag{
+		Name: "skipgram-decay",
+		Usage: "Weight factor of a skipgram for each character it skips beyond the first, with " +
+			"--skipgram-distance above 1 (0-1, e.g. 0.5 weights skipgrams 1, 0.5, 0.25, ...).",
+		Value:    0.5,
+		Category: "",
+	},
+	"substitute": &cli.StringFlag{
+		Name: "substitute",
+		Usage: "Replace characters in the corpus text before counting n-grams, using the substitution " +
+			"table in this file (from data/config directory), such as typographic quotes and dashes by " +
+			"their ASCII counterparts. The table is stored in the corpus cache. Without the flag, the " +
+			"corpus command lists the characters that " + defaultSubstitutionsFile + " would replace.",
+		Category: "",
+	},
+}
+
+// corpusBuildFlags returns the flags that change how a corpus is built, in a fixed order.
+func corpusBuildFlags() []cli.Flag {
+	return flags(corpusBuildFlagsMap, "exclude-words", "case-sensitive", "include-space", "skipgram-distance",
+		"skipgram-decay", "substitute")
+}
+
+// boardFlag selects a built-in board preset, for the commands that support them (generate,
+// analyse).
+var boardFlag = &cli.StringFlag{
+	Name: "board",
+	Usage: "Built-in board to use: " + strings.Join(kc.BoardPresetNames(), ", ") + ". Sets the layout type, " +
+		"the keys of the board and its key geometry (unless --geometry-file is given).",
+}
+
+// scopeFlag restricts analysis to a subset of the characters, for the commands that compare
+// layouts (view, analyse, rank).
+var scopeFlag = &cli.StringFlag{
+	Name: "scope",
+	Usage: "Characters to analyse: alphas (letters only), alphas+punct (letters, punctuation and " +
+		"symbols) or full. N-grams with other characters are left out of the corpus, so that " +
+		"layouts that only place letters are compared fairly with layouts that also place punctuation.",
+	Value: "full",
+	Action: func(ctx context.Context, c *cli.Command, value string) error {
+		if isShellCompletion() {
+			return nil
+		}
+		_, err := kc.ParseAnalysisScope(value)
+		return err
+	},
+}
+
+// saveFlags returns the flags for saving layouts, in a fixed order.
+func saveFlags() []cli.Flag {
+	return flags(saveFlagsMap, "out", "name", "force", "dry-run")
+}
+
 // isShellCompletion returns true if the current invocation is for shell completion.
 // This is used to skip validation during completion to avoid error messages.
 func isShellCompletion() bool {
@@ -104,7 +303,7 @@ func getLayoutArgs(c *cli.Command) []string {
 		// 	}
 		// }
 
-		layouts[i] = filepath.Join(layoutDir, ensureKlf(layouts[i]))
+		layouts[i] = filepath.Join(layoutDir, ensureKlf(resolveLayoutName(layouts[i])))
 	}
 	return layouts
 }
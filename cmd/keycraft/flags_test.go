@@ -14,13 +14,24 @@ import (
 func TestAllSharedFlagsExist(t *testing.T) {
 	expectedFlags := []string{
 		"corpus",
+		"bigram-weighting",
+		"geometry-file",
+		"metric-version",
+		"sfs-definition",
+		"baseline",
 		"load-targets-file",
 		"target-hand-load",
 		"target-finger-load",
 		"target-row-load",
 		"pinky-penalties",
+		"roll-quality",
+		"fatigue",
+		"strict-targets",
 		"weights-file",
 		"weights",
+		"reference-glob",
+		"reference-list",
+		"inline",
 	}
 
 	for _, flagName := range expectedFlags {
@@ -30,18 +41,29 @@ func TestAllSharedFlagsExist(t *testing.T) {
 	}
 }
 
-// TestNoExtraSharedFlags verifies that appFlagsMap contains only the 8 expected shared flags
+// TestNoExtraSharedFlags verifies that appFlagsMap contains only the expected shared flags
 // and no unexpected flags have been added. Prevents flag definition drift.
 func TestNoExtraSharedFlags(t *testing.T) {
 	expectedFlags := map[string]bool{
 		"corpus":             true,
+		"bigram-weighting":   true,
+		"geometry-file":      true,
+		"metric-version":     true,
+		"sfs-definition":     true,
+		"baseline":           true,
 		"load-targets-file":  true,
 		"target-hand-load":   true,
 		"target-finger-load": true,
 		"target-row-load":    true,
 		"pinky-penalties":    true,
+		"roll-quality":       true,
+		"fatigue":            true,
+		"strict-targets":     true,
 		"weights-file":       true,
 		"weights":            true,
+		"reference-glob":     true,
+		"reference-list":     true,
+		"inline":             true,
 	}
 
 	for flagName := range commonFlagsMap {
@@ -66,13 +88,20 @@ func TestSharedFlagDefaults(t *testing.T) {
 		expectedVal  any
 	}{
 		{"corpus", "string", "default.txt"},
+		{"bigram-weighting", "string", ""},
+		{"geometry-file", "string", ""},
+		{"baseline", "string", ""},
 		{"load-targets-file", "string", "load_targets.txt"},
 		{"target-hand-load", "string", ""},
 		{"target-finger-load", "string", ""},
 		{"target-row-load", "string", ""},
 		{"pinky-penalties", "string", ""},
+		{"roll-quality", "string", ""},
+		{"fatigue", "string", ""},
 		{"weights-file", "string", "weights.txt"},
 		{"weights", "string", ""},
+		{"reference-glob", "string", ""},
+		{"reference-list", "string", ""},
 	}
 
 	for _, tt := range tests {
@@ -105,13 +134,20 @@ func TestSharedFlagAliases(t *testing.T) {
 		expectedAliases []string
 	}{
 		{"corpus", []string{"c"}},
+		{"bigram-weighting", []string{"bw"}},
+		{"geometry-file", []string{"gf"}},
+		{"baseline", []string{"bl"}},
 		{"load-targets-file", []string{"ltf"}},
 		{"target-hand-load", []string{"thl"}},
 		{"target-finger-load", []string{"tfl"}},
 		{"target-row-load", []string{"trl"}},
 		{"pinky-penalties", []string{"pp"}},
+		{"roll-quality", []string{"rq"}},
+		{"fatigue", []string{"ft"}},
 		{"weights-file", []string{"wf"}},
 		{"weights", []string{"w"}},
+		{"reference-glob", []string{"rg"}},
+		{"reference-list", []string{"rl"}},
 	}
 
 	for _, tt := range tests {
@@ -148,13 +184,21 @@ func TestSharedFlagCategories(t *testing.T) {
 		expectedCategory string
 	}{
 		{"corpus", ""},
+		{"bigram-weighting", ""},
+		{"geometry-file", ""},
 		{"load-targets-file", "Targets and Weights"},
 		{"target-hand-load", "Targets and Weights"},
 		{"target-finger-load", "Targets and Weights"},
 		{"target-row-load", "Targets and Weights"},
 		{"pinky-penalties", "Targets and Weights"},
+		{"roll-quality", "Targets and Weights"},
+		{"fatigue", "Targets and Weights"},
+		{"strict-targets", "Targets and Weights"},
 		{"weights-file", "Targets and Weights"},
 		{"weights", "Targets and Weights"},
+		{"reference-glob", "Targets and Weights"},
+		{"reference-list", "Targets and Weights"},
+		{"inline", ""},
 	}
 
 	for _, tt := range tests {
@@ -176,6 +220,20 @@ func TestSharedFlagCategories(t *testing.T) {
 	}
 }
 
+// TestRankCorporaFlagCategory verifies that --corpora is listed with --corpus in the help of
+// rank.
+func TestRankCorporaFlagCategory(t *testing.T) {
+	i := slices.IndexFunc(rankFlags, func(f cli.Flag) bool { return f.Names()[0] == "corpora" })
+	if i < 0 {
+		t.Fatal("rank has no corpora flag")
+	}
+	corpora := rankFlags[i].(interface{ GetCategory() string }).GetCategory()
+	corpus := commonFlagsMap["corpus"].(interface{ GetCategory() string }).GetCategory()
+	if corpora != corpus {
+		t.Errorf("corpora has category %q, want %q of corpus", corpora, corpus)
+	}
+}
+
 // TestCommandSpecificFlagsComplete verifies that each command-specific flag collection
 // contains exactly the expected flags - no more, no less. This bidirectional test ensures
 // no flags are missing and no unexpected flags exist, using a single source of truth.
@@ -192,22 +250,47 @@ func TestCommandSpecificFlagsComplete(t *testing.T) {
 		{
 			name:          "corpusFlags",
 			flags:         &corpusFlags,
-			expectedFlags: []string{"corpus-rows", "coverage"},
+			expectedFlags: []string{"corpus-rows", "preview", "export", "coverage"},
 		},
 		{
 			name:          "analyseFlags",
 			flags:         &analyseFlags,
-			expectedFlags: []string{"rows", "compact-trigrams", "trigram-rows"},
+			expectedFlags: []string{"rows", "compact-trigrams", "per-key", "trigram-rows", "sections", "page"},
 		},
 		{
 			name:          "rankFlags",
 			flags:         &rankFlags,
-			expectedFlags: []string{"metrics", "deltas", "output", "link-base"},
+			expectedFlags: []string{"metrics", "deltas", "delta-mode", "output", "metrics-file", "corpora", "link-base"},
 		},
 		{
 			name:          "optimizeFlags",
 			flags:         &optimizeFlags,
-			expectedFlags: []string{"pins-file", "pins", "free", "generations", "maxtime", "seed", "log-file"},
+			expectedFlags: []string{"pins-file", "pins", "free", "spill-keys", "region", "swap-classes", "preserve-shortcuts", "max-changes", "change-weight", "generations", "maxtime", "seed", "compound-moves", "place-remaining", "taboo", "score-cache-size", "subsample", "bls-params", "history-file", "run-dir", "tui"},
+		},
+		{
+			name:          "calibrateFlags",
+			flags:         &calibrateFlags,
+			expectedFlags: []string{"key-pitch", "row-stagger", "column-stagger", "thumbs"},
+		},
+		{
+			name:          "shortcutsFlags",
+			flags:         &shortcutsFlags,
+			expectedFlags: []string{"shortcuts-file", "rows"},
+		},
+		{
+			name:          "checkFlags",
+			flags:         &checkFlags,
+			expectedFlags: []string{"quiet", "fail-if"},
+		},
+		{
+			name:          "weightsFitFlags",
+			flags:         &weightsFitFlags,
+			expectedFlags: []string{"prefer", "avoid", "metrics", "regularisation", "output-file"},
+		},
+		{
+			name:          "verifyFlags",
+			flags:         &verifyFlags,
+			expectedFlags: []string{"verify-file", "update", "all"},
 		},
 		{
 			name:          "generateFlags",
@@ -270,6 +353,7 @@ func TestCommandSpecificFlagsComplete(t *testing.T) {
 func TestFlagDefaults_CommandSpecific(t *testing.T) {
 	optimizeFlags := optFlags()
 	genFlags := generationFlags()
+	buildFlags := corpusBuildFlags()
 	tests := []struct {
 		name        string
 		flags       *[]cli.Flag
@@ -278,18 +362,27 @@ func TestFlagDefaults_CommandSpecific(t *testing.T) {
 	}{
 		{"corpus-rows", &corpusFlags, "corpus-rows", int64(100)},
 		{"coverage", &corpusFlags, "coverage", 98.0},
+		{"exclude-words", &buildFlags, "exclude-words", ""},
 		{"rows", &analyseFlags, "rows", int64(10)},
 		{"compact-trigrams", &analyseFlags, "compact-trigrams", false},
+		{"per-key", &analyseFlags, "per-key", false},
 		{"trigram-rows", &analyseFlags, "trigram-rows", int64(50)},
+		{"sections", &analyseFlags, "sections", ""},
+		{"page", &analyseFlags, "page", int64(1)},
 		{"metrics", &rankFlags, "metrics", "weighted"},
 		{"deltas", &rankFlags, "deltas", "none"},
+		{"delta-mode", &rankFlags, "delta-mode", "absolute"},
 		{"output", &rankFlags, "output", "table"},
 		{"generations_optimize", &optimizeFlags, "generations", uint64(1000)},
 		{"maxtime", &optimizeFlags, "maxtime", uint64(5)},
-		{"seed_optimize", &optimizeFlags, "seed", int64(0)},
+		{"seed_optimize", &optimizeFlags, "seed", "random"},
+		{"compound-moves", &optimizeFlags, "compound-moves", false},
+		{"score-cache-size", &optimizeFlags, "score-cache-size", uint64(1000000)},
+		{"subsample", &optimizeFlags, "subsample", 0.0},
+		{"tui", &optimizeFlags, "tui", false},
 		{"max-layouts", &genFlags, "max-layouts", int64(5000)},
 		{"optimize", &genFlags, "optimize", false},
-		{"seed_generate", &genFlags, "seed", uint64(0)},
+		{"seed_generate", &genFlags, "seed", "random"},
 		{"keep-unoptimized", &genFlags, "keep-unoptimized", false},
 	}
 
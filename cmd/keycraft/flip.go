@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"os"
 
 	"github.com/urfave/cli/v3"
 )
@@ -14,12 +14,13 @@ var flipCommand = &cli.Command{
 	Aliases:       []string{"f"},
 	Usage:         "Flip a keyboard layout horizontally and save as new layout",
 	ArgsUsage:     "<layout>",
+	Flags:         saveFlags(),
 	Action:        flipAction,
 	ShellComplete: layoutShellComplete,
 }
 
 // flipAction loads a keyboard layout, performs a horizontal mirror transformation,
-// and saves the resulting layout to a new file with a "-flipped" suffix.
+// and saves the resulting layout to a new file with a "-flipped" suffix, or the --name.
 func flipAction(ctx context.Context, c *cli.Command) error {
 	if isShellCompletion() {
 		return nil
@@ -29,6 +30,11 @@ func flipAction(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("expected exactly 1 layout, got %d", c.Args().Len())
 	}
 
+	out, err := layoutOutputFromFlags(c)
+	if err != nil {
+		return err
+	}
+
 	layoutArg := c.Args().First()
 
 	// Load the layout using helper function
@@ -37,19 +43,27 @@ func flipAction(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("could not load layout: %w", err)
 	}
 
-	// Flip the layout horizontally
+	// Flip the layout horizontally, and name it with a "-flipped" suffix by default
 	layout.FlipHorizontal()
-
-	// Update the name with "-flipped" suffix
-	layout.Name = layout.Name + "-flipped"
+	layout.Name = out.layoutName(layout.Name + "-flipped")
 
 	// Save to new file
-	outputPath := filepath.Join(layoutDir, layout.Name+".klf")
-
+	outputPath, err := out.path(layout.Name)
+	if err != nil {
+		return err
+	}
+	if out.dryRun {
+		fmt.Printf("Dry run: would save flipped layout to: %s\n\n", outputPath)
+		if err := layout.WriteKlf(os.Stdout); err != nil {
+			return fmt.Errorf("could not write flipped layout: %w", err)
+		}
+		fmt.Println()
+		return nil
+	}
 	if err := layout.SaveToFile(outputPath); err != nil {
 		return fmt.Errorf("could not save flipped layout: %w", err)
 	}
 
-	fmt.Printf("Flipped layout and saved to: %s.klf\n", layout.Name)
+	fmt.Printf("Flipped layout and saved to: %s\n", outputPath)
 	return nil
 }
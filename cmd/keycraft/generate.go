@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -26,11 +27,12 @@ var generateFlagsMap = map[string]cli.Flag{
 		Value:    5000,
 		Category: "Generation",
 	},
-	"seed": &cli.Uint64Flag{
-		Name:     "seed",
-		Aliases:  []string{"s"},
-		Usage:    "Random seed for random position allocation (0 = timestamp)",
-		Value:    0,
+	"seed": &cli.StringFlag{
+		Name:    "seed",
+		Aliases: []string{"s"},
+		Usage: "Random seed for random position allocation and optimization: a positive number, or random " +
+			"for a new seed. The seed is printed at the start.",
+		Value:    "random",
 		Category: "Generation",
 	},
 	"optimize": &cli.BoolFlag{
@@ -77,16 +79,16 @@ func generationFlags(keys ...string) []cli.Flag {
 
 // generateCmdFlags returns all flags for the generate command
 func generateCmdFlags() []cli.Flag {
-	optF := optFlags("pins", "generations", "maxtime")
-	return append(append(commonFlags(), optF...), generationFlags()...)
+	optF := optFlags("pins", "spill-keys", "generations", "maxtime", "score-cache-size")
+	return append(append(append(append(append(commonFlags(), corpusBuildFlags()...), optF...), generationFlags()...), saveFlags()...), boardFlag)
 }
 
 // generateCommand defines the "generate" CLI command for creating layouts from config files
 var generateCommand = &cli.Command{
 	Name:      "generate",
 	Aliases:   []string{"g"},
-	Usage:     "Generate layouts from config file",
-	ArgsUsage: "<config.gen>",
+	Usage:     "Generate layouts from config file, or for a --board",
+	ArgsUsage: "[<config.gen>]",
 	Flags:     generateCmdFlags(),
 	Action:    generateAction,
 }
@@ -102,11 +104,26 @@ func generateAction(ctx context.Context, c *cli.Command) error {
 	if err != nil {
 		return err
 	}
-
-	// Step 2: Parse and validate config file
-	config, err := kc.ParseConfigFile(genInput.ConfigPath)
+	printSeed(genInput.Seed)
+	out, err := layoutOutputFromFlags(c)
 	if err != nil {
-		return fmt.Errorf("could not parse config file: %w", err)
+		return err
+	}
+
+	// Step 2: Parse and validate config file, or use the config of the board
+	var config *kc.GenerationConfig
+	if genInput.ConfigPath == "" {
+		config = genInput.Board.GenerationConfig()
+	} else {
+		config, err = kc.ParseConfigFile(genInput.ConfigPath)
+		if err != nil {
+			return fmt.Errorf("could not parse config file: %w", err)
+		}
+		if genInput.Board != nil {
+			if err := genInput.Board.ApplyToConfig(config); err != nil {
+				return fmt.Errorf("could not generate layouts for the board: %w", err)
+			}
+		}
 	}
 
 	if err := kc.ValidateConfig(config); err != nil {
@@ -122,28 +139,34 @@ func generateAction(ctx context.Context, c *cli.Command) error {
 		if err != nil {
 			return fmt.Errorf("could not build optimize input: %w", err)
 		}
+		optInput.Seed = genInput.Seed // The layouts are optimized with seeds derived from it
 	}
 
 	// Step 4: Generate layouts
-	result, err := kc.GenerateFromConfig(config, genInput, layoutDir)
+	result, err := kc.GenerateFromConfig(config, genInput, out.dir)
 	if err != nil {
 		return fmt.Errorf("could not generate layouts: %w", err)
 	}
 
 	// Print warnings
 	for _, warning := range result.Warnings {
-		fmt.Printf("Warning: %s\n", warning)
+		slog.Warn(warning)
 	}
 
 	fmt.Printf("Generated %d layouts (total permutations: %d)\n", result.Generated, result.TotalPerms)
 
 	// Step 5: Optimize if requested
 	if genInput.Optimize {
-		err := optimiseLayout(result, config, c, optInput, genInput)
+		err := optimiseLayout(ctx, result, config, c, optInput, genInput, out)
 		if err != nil {
 			return fmt.Errorf("could not optimise generated layout: %w", err)
 		}
 	}
+	if out.dryRun {
+		fmt.Printf("Dry run: would save %d layouts to: %s\n", len(result.LayoutPaths), out.dir)
+	} else {
+		fmt.Printf("Saved %d layouts to: %s\n", len(result.LayoutPaths), out.dir)
+	}
 
 	// Step 6: Build RankingInput and display rankings
 	rankingInput, err := buildRankingInput(c, optInput.Weights, true)
@@ -151,8 +174,12 @@ func generateAction(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("could not build ranking input: %w", err)
 	}
 
-	// Set layout files from generation result
-	rankingInput.LayoutFiles = result.LayoutPaths
+	// Set layout files from generation result, or the layouts themselves with --dry-run
+	if out.dryRun {
+		rankingInput.Layouts = result.Layouts
+	} else {
+		rankingInput.LayoutFiles = result.LayoutPaths
+	}
 
 	// Compute and display rankings
 	rankings, err := kc.ComputeRankings(rankingInput)
@@ -183,12 +210,49 @@ type optResult struct {
 	err           error
 }
 
-func optimiseLayout(result *kc.GenerationResult, config *kc.GenerationConfig, c *cli.Command, optInput kc.OptimizeInput, genInput kc.GenerateInput) error {
+func optimiseLayout(ctx context.Context, result *kc.GenerationResult, config *kc.GenerationConfig, c *cli.Command, optInput kc.OptimizeInput, genInput kc.GenerateInput, out layoutOutput) error {
 	numLayouts := len(result.Layouts)
+
+	var region *kc.Region
+	if spec := c.String("spill-keys"); spec != "" {
+		var err error
+		if region, err = kc.ParseSpillKeys(spec); err != nil {
+			return err
+		}
+	}
+
+	// Refuse to overwrite an optimized layout, or to use spill-over keys that aren't
+	// unused positions, before optimizing any
+	paths := make([]string, numLayouts)
+	pins := make([]kc.PinnedKeys, numLayouts)
+	spills := make([]*kc.Region, numLayouts)
+	pinsStr := c.String("pins")
+	for i, layout := range result.Layouts {
+		path, err := out.path(layout.Name + "-opt")
+		if err != nil {
+			return err
+		}
+		paths[i] = path
+
+		if pinsStr == "" {
+			pins[i] = kc.ComputeDefaultPins(config, layout)
+		} else {
+			pins[i] = computeCustomPins(layout, pinsStr, config)
+		}
+		if region != nil {
+			if spills[i], err = layout.SpillKeys(region); err == nil {
+				err = pins[i].SetSpillKeys(spills[i], layout, false)
+			}
+			if err != nil {
+				return fmt.Errorf("could not set spill-over keys: %w", err)
+			}
+		}
+	}
+
 	fmt.Printf("Optimizing %d layouts...\n", numLayouts)
 
 	// Compute shared reference stats once (avoids loading ~1256 layouts per goroutine)
-	medians, iqrs, filteredWeights, err := kc.ComputeReferenceStats(layoutDir, optInput.Corpus, optInput.Targets, optInput.Weights)
+	medians, iqrs, filteredWeights, err := kc.ComputeReferenceStats(layoutDir, optInput.Corpus, optInput.Targets, optInput.Weights, optInput.Reference)
 	if err != nil {
 		return fmt.Errorf("could not compute reference stats: %w", err)
 	}
@@ -226,21 +290,15 @@ func optimiseLayout(result *kc.GenerationResult, config *kc.GenerationConfig, c
 		index  int
 		layout *kc.SplitLayout
 		pinned kc.PinnedKeys
+		spill  *kc.Region
+		path   string // Where to save the optimized layout
 	}
 
 	work := make(chan workItem, numLayouts)
 	results := make([]optResult, numLayouts)
 
-	pinsStr := c.String("pins")
 	for i, layout := range result.Layouts {
-		var pinned kc.PinnedKeys
-		if pinsStr == "" {
-			pinned = kc.ComputeDefaultPins(config, layout)
-		} else {
-			pinned = computeCustomPins(layout, pinsStr, config)
-		}
-
-		work <- workItem{index: i, layout: layout, pinned: pinned}
+		work <- workItem{index: i, layout: layout, pinned: pins[i], spill: spills[i], path: paths[i]}
 	}
 	close(work)
 
@@ -255,9 +313,11 @@ func optimiseLayout(result *kc.GenerationResult, config *kc.GenerationConfig, c
 				localInput := optInput
 				localInput.Layout = item.layout
 				localInput.Pinned = &item.pinned
+				localInput.SpillKeys = item.spill
+				localInput.Seed = kc.DeriveSeed(optInput.Seed, item.index)
 
 				// Run optimization (nil writer = no console output)
-				optimizeResult, err := kc.OptimizeLayout(localInput, nil)
+				optimizeResult, err := kc.OptimizeLayout(ctx, localInput, nil)
 				tracker.Increment(1)
 				if err != nil {
 					results[item.index] = optResult{err: fmt.Errorf("optimization failed for %s: %w", item.layout.Name, err)}
@@ -265,16 +325,15 @@ func optimiseLayout(result *kc.GenerationResult, config *kc.GenerationConfig, c
 				}
 
 				bestLayout := optimizeResult.BestLayout
-				optimizedPath := filepath.Join(layoutDir, bestLayout.Name+".klf")
-				if err := bestLayout.SaveToFile(optimizedPath); err != nil {
+				if err := out.save(bestLayout, item.path); err != nil {
 					results[item.index] = optResult{err: fmt.Errorf("failed to save optimized layout %s: %w", bestLayout.Name, err)}
 					continue
 				}
 
 				results[item.index] = optResult{
 					bestLayout:    bestLayout,
-					optimizedPath: optimizedPath,
-					originalPath:  filepath.Join(layoutDir, item.layout.Name+".klf"),
+					optimizedPath: item.path,
+					originalPath:  result.LayoutPaths[item.index],
 				}
 			}
 		})
@@ -297,9 +356,9 @@ func optimiseLayout(result *kc.GenerationResult, config *kc.GenerationConfig, c
 		result.Layouts[i] = res.bestLayout
 
 		// Delete original if not keeping unoptimized
-		if !genInput.KeepUnoptimized {
+		if !genInput.KeepUnoptimized && !out.dryRun {
 			if err := os.Remove(res.originalPath); err != nil {
-				fmt.Printf("Warning: could not delete original layout %s: %v\n", res.originalPath, err)
+				slog.Warn(fmt.Sprintf("could not delete original layout %s: %v", res.originalPath, err))
 			}
 		}
 	}
@@ -336,29 +395,49 @@ func computeCustomPins(layout *kc.SplitLayout, pinsStr string, config *kc.Genera
 
 // buildGenerateInput validates arguments, resolves config path, and captures generation flags.
 func buildGenerateInput(c *cli.Command) (kc.GenerateInput, error) {
-	// Validate exactly one argument (config file)
-	if c.Args().Len() != 1 {
-		return kc.GenerateInput{}, fmt.Errorf("expected exactly 1 config file argument, got %d", c.Args().Len())
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.GenerateInput{}, err
+	}
+	board, err := loadBoardFromFlags(c)
+	if err != nil {
+		return kc.GenerateInput{}, err
 	}
 
-	// Check .gen extension (case-insensitive)
-	configPath := c.Args().Get(0)
-	if !strings.HasSuffix(strings.ToLower(configPath), ".gen") {
-		return kc.GenerateInput{}, fmt.Errorf("config file must have .gen extension, got: %s", configPath)
+	// Validate exactly one argument (config file), which is optional with --board
+	if c.Args().Len() > 1 || (c.Args().Len() == 0 && board == nil) {
+		return kc.GenerateInput{}, fmt.Errorf("expected exactly 1 config file argument, got %d", c.Args().Len())
 	}
 
-	// Resolve config file path
-	resolvedPath, err := resolveConfigPath(configPath)
+	seed, err := kc.ParseSeed(c.String("seed"))
 	if err != nil {
 		return kc.GenerateInput{}, err
 	}
 
+	var resolvedPath string
+	if c.Args().Len() == 1 {
+		// Check .gen extension (case-insensitive)
+		configPath := c.Args().Get(0)
+		if !strings.HasSuffix(strings.ToLower(configPath), ".gen") {
+			return kc.GenerateInput{}, fmt.Errorf("config file must have .gen extension, got: %s", configPath)
+		}
+
+		// Resolve config file path
+		resolvedPath, err = resolveConfigPath(configPath)
+		if err != nil {
+			return kc.GenerateInput{}, err
+		}
+	}
+
 	return kc.GenerateInput{
 		ConfigPath:      resolvedPath,
+		Board:           board,
 		MaxLayouts:      c.Int("max-layouts"),
-		Seed:            c.Uint64("seed"),
+		Seed:            seed,
 		Optimize:        c.Bool("optimize"),
 		KeepUnoptimized: c.Bool("keep-unoptimized"),
+		NamePrefix:      c.String("name"),
+		Force:           c.Bool("force"),
+		DryRun:          c.Bool("dry-run"),
 	}, nil
 }
 
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// geometryCommand defines the CLI command for inspecting the key geometry of a layout type.
+var geometryCommand = &cli.Command{
+	Name:    "geometry",
+	Aliases: []string{"geo"},
+	Usage:   "Show the fingers of the keys and the distances between keys of a layout type",
+	Description: "Lists the finger of every key position, and the row, column and finger " +
+		"distance and the Euclidean distance of every pair of keys on the same hand, as used " +
+		"by the metrics. With --geometry-file, the distances follow the calibrated board.",
+	Flags: append(commonFlags("geometry-file"),
+		&cli.StringFlag{
+			Name:    "format",
+			Aliases: []string{"fmt"},
+			Usage:   "Output format: \"table\" or \"csv\" (key pairs only).",
+			Value:   "table",
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "File to write the geometry to. Writes to stdout if empty.",
+		},
+	),
+	ArgsUsage: "<layout-type>",
+	Action:    geometryAction,
+	ShellComplete: func(ctx context.Context, c *cli.Command) {
+		for _, name := range kc.LayoutTypeStrings {
+			fmt.Println(name)
+		}
+	},
+}
+
+// geometryAction renders the key geometry of a layout type.
+func geometryAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	layoutType, format, err := buildGeometryInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if path := c.String("output"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not create output file %s: %w", path, err)
+		}
+		defer kc.CloseFile(f)
+		w = f
+	}
+
+	return tui.RenderGeometry(w, layoutType, format)
+}
+
+// buildGeometryInput validates the layout type and output format, and puts the board
+// geometry in use.
+func buildGeometryInput(c *cli.Command) (kc.LayoutType, tui.OutputFormat, error) {
+	if c.NArg() != 1 {
+		return 0, "", fmt.Errorf("expected exactly 1 layout type, got %d", c.NArg())
+	}
+	layoutType, ok := kc.ParseLayoutType(c.Args().First())
+	if !ok {
+		return 0, "", fmt.Errorf("invalid layout type %q: must be one of: rowstag, anglemod, ortho, colstag", c.Args().First())
+	}
+
+	var format tui.OutputFormat
+	switch strings.ToLower(c.String("format")) {
+	case "table":
+		format = tui.OutputTable
+	case "csv":
+		format = tui.OutputCSV
+	default:
+		return 0, "", fmt.Errorf("invalid format %q: must be one of: table, csv", c.String("format"))
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return 0, "", err
+	}
+	return layoutType, format, nil
+}
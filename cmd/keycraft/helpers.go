@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"log/slog"
 	"maps"
 	"os"
 	"path/filepath"
@@ -12,23 +13,194 @@ import (
 	"github.com/urfave/cli/v3"
 )
 
-// loadCorpus loads a corpus from corpusDir.
-// forceReload bypasses cache, and coverage filters low-frequency words.
-func loadCorpus(filename string, forceReload bool, coverage float64) (*kc.Corpus, error) {
+// loadCorpus loads a corpus from corpusDir, building it with the given options if its cache
+// cannot be used.
+func loadCorpus(filename string, opts kc.CorpusBuildOptions) (*kc.Corpus, error) {
 	if filename == "" {
 		return nil, fmt.Errorf("corpus file is required")
 	}
 
 	corpusName := strings.TrimSuffix(filename, filepath.Ext(filename))
 	path := filepath.Join(corpusDir, filename)
+	slog.Debug("loading corpus", "path", path, "rebuild", opts.Rebuild, "coverage", opts.Coverage, "cased", opts.Cased,
+		"spaced", opts.Spaced, "skipgrams", opts.Skipgram.String(), "substitutions", len(opts.Substitutions))
 
-	return kc.NewCorpusFromFile(corpusName, path, forceReload, coverage)
+	return kc.NewCorpusFromFileWith(corpusName, path, opts)
+}
+
+// corpusBuildOptionsFromFlags returns the options for building a corpus from the --coverage,
+// --exclude-words, --case-sensitive, --include-space, --skipgram-distance,
+// --skipgram-decay and --substitute flags.
+func corpusBuildOptionsFromFlags(c *cli.Command) (kc.CorpusBuildOptions, error) {
+	excluded, err := loadExcludedWordsFromFlags(c)
+	if err != nil {
+		return kc.CorpusBuildOptions{}, err
+	}
+	skipgrams, err := skipgramPolicyFromFlags(c)
+	if err != nil {
+		return kc.CorpusBuildOptions{}, err
+	}
+	substitutions, err := loadSubstitutionsFromFlags(c)
+	if err != nil {
+		return kc.CorpusBuildOptions{}, err
+	}
+	return kc.CorpusBuildOptions{
+		Rebuild:       rebuildCorpus(c),
+		Coverage:      corpusCoverage(c),
+		Excluded:      excluded,
+		Cased:         c.Bool("case-sensitive"),
+		Spaced:        c.Bool("include-space"),
+		Skipgram:      skipgrams,
+		Substitutions: substitutions,
+	}, nil
+}
+
+// skipgramPolicyFromFlags parses the --skipgram-distance and --skipgram-decay flags, returning
+// the default policy if --skipgram-distance is not set.
+func skipgramPolicyFromFlags(c *cli.Command) (kc.SkipgramPolicy, error) {
+	if !c.IsSet("skipgram-distance") {
+		if c.IsSet("skipgram-decay") {
+			return kc.SkipgramPolicy{}, fmt.Errorf("--skipgram-decay requires --skipgram-distance")
+		}
+		return kc.SkipgramPolicy{}, nil
+	}
+	return kc.NewSkipgramPolicy(int(c.Int("skipgram-distance")), c.Float64("skipgram-decay"))
+}
+
+// loadExcludedWordsFromFlags loads the words to exclude from the corpus from the file given by
+// the --exclude-words flag, if set.
+func loadExcludedWordsFromFlags(c *cli.Command) (map[string]bool, error) {
+	name := c.String("exclude-words")
+	if name == "" {
+		return nil, nil
+	}
+	excluded, err := kc.LoadExcludedWords(filepath.Join(configDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("could not load excluded words: %w", err)
+	}
+	return excluded, nil
+}
+
+// loadSubstitutionsFromFlags loads the substitution table from the file given by the
+// --substitute flag, if set.
+func loadSubstitutionsFromFlags(c *cli.Command) (map[rune]rune, error) {
+	name := c.String("substitute")
+	if name == "" {
+		return nil, nil
+	}
+	substitutions, err := kc.LoadSubstitutions(filepath.Join(configDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("could not load substitutions: %w", err)
+	}
+	return substitutions, nil
+}
+
+// corpusCoverage returns the --coverage flag of the corpus command, or its default for the
+// commands without it, which build a corpus when it has no cache for their options.
+func corpusCoverage(c *cli.Command) float64 {
+	if c.IsSet("coverage") {
+		return c.Float64("coverage")
+	}
+	return defaultCoverage
+}
+
+// rebuildCorpus reports whether the flags that change how a corpus is built are set, so that
+// its cache must be rebuilt.
+func rebuildCorpus(c *cli.Command) bool {
+	return c.IsSet("coverage")
 }
 
 // loadCorpusFromFlags loads the corpus specified by the --corpus flag,
-// considering the --coverage flag if set.
+// considering the --coverage, --exclude-words, --case-sensitive, --include-space and
+// --skipgram-distance and --substitute flags if set, reweights its bigrams according to the --bigram-weighting
+// flag, and restricts it to the characters of the --scope flag.
 func loadCorpusFromFlags(c *cli.Command) (*kc.Corpus, error) {
-	return loadCorpus(c.String("corpus"), c.IsSet("coverage"), c.Float64("coverage"))
+	weighting, err := loadBigramWeightingFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := corpusBuildOptionsFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := kc.ParseAnalysisScope(c.String("scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	corpus, err := loadCorpus(c.String("corpus"), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return corpus.Weighted(weighting).Scoped(scope), nil
+}
+
+// loadCorporaFromFlags loads the corpora specified by a repeatable --corpus flag, each
+// given as "file[:weight]". Falls back to the single --corpus flag of other commands.
+// The --coverage, --exclude-words and --bigram-weighting flags apply to every corpus.
+func loadCorporaFromFlags(c *cli.Command) ([]kc.WeightedCorpus, error) {
+	return loadCorpora(c, corpusSpecs(c))
+}
+
+// corpusSpecs returns the "file[:weight]" specifications of the --corpus flag, which is
+// repeated for several corpora.
+func corpusSpecs(c *cli.Command) []string {
+	specs := c.StringSlice("corpus")
+	if len(specs) == 0 {
+		specs = []string{c.String("corpus")}
+	}
+	return specs
+}
+
+// loadCorpora loads the corpora of "file[:weight]" specifications, applying the
+// --bigram-weighting, --coverage, --exclude-words and --scope flags to each.
+func loadCorpora(c *cli.Command, specs []string) ([]kc.WeightedCorpus, error) {
+	weighting, err := loadBigramWeightingFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := corpusBuildOptionsFromFlags(c)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := kc.ParseAnalysisScope(c.String("scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	corpora := make([]kc.WeightedCorpus, 0, len(specs))
+	for _, spec := range specs {
+		filename, weight, err := kc.ParseCorpusSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		corpus, err := loadCorpus(filename, opts)
+		if err != nil {
+			return nil, fmt.Errorf("could not load corpus %s: %w", filename, err)
+		}
+		corpora = append(corpora, kc.WeightedCorpus{Corpus: corpus.Weighted(weighting).Scoped(scope), Weight: weight})
+	}
+
+	// Corpora without their source file keep the skipgram policy they were cached with
+	for _, wc := range corpora[1:] {
+		if wc.Corpus.Skipgram != corpora[0].Corpus.Skipgram {
+			slog.Warn(fmt.Sprintf("Corpora %s and %s count skipgrams differently, so their skipgram metrics "+
+				"are not comparable.", corpora[0].Corpus.Name, wc.Corpus.Name))
+			break
+		}
+	}
+
+	return corpora, nil
+}
+
+// loadBigramWeightingFromFlags parses the --bigram-weighting flag.
+func loadBigramWeightingFromFlags(c *cli.Command) (*kc.BigramWeighting, error) {
+	weighting, err := kc.NewBigramWeightingFromString(c.String("bigram-weighting"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse bigram weighting: %w", err)
+	}
+	return weighting, nil
 }
 
 // loadLayout loads a layout from a file.
@@ -52,6 +224,7 @@ func loadLayout(filename string) (*kc.SplitLayout, error) {
 	// }
 
 	if path == "" {
+		filename = resolveLayoutName(filename)
 		layoutName = ensureNoKlf(filename)
 		filename = ensureKlf(filename)
 		path = filepath.Join(layoutDir, filename)
@@ -60,6 +233,39 @@ func loadLayout(filename string) (*kc.SplitLayout, error) {
 	return kc.NewLayoutFromFile(layoutName, path)
 }
 
+// loadInlineLayouts parses the layouts of the --inline flag.
+func loadInlineLayouts(c *cli.Command) ([]*kc.SplitLayout, error) {
+	var layouts []*kc.SplitLayout
+	for _, s := range c.StringSlice("inline") {
+		layout, err := kc.NewLayoutFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse inline layout: %w", err)
+		}
+		layouts = append(layouts, layout)
+	}
+	return layouts, nil
+}
+
+// resolveLayoutName resolves a layout argument given as a layout id. If no layout
+// file with that name exists in layoutDir and the argument looks like a layout id
+// (see the id command), the name of the layout with that id is returned.
+// Otherwise, such as for an id that matches several layouts, a warning says why,
+// and the argument is returned unchanged.
+func resolveLayoutName(arg string) string {
+	if !kc.IsLayoutIDPrefix(arg) {
+		return arg
+	}
+	if _, err := os.Stat(filepath.Join(layoutDir, ensureKlf(arg))); err == nil {
+		return arg
+	}
+	found, err := kc.FindLayoutByID(layoutDir, arg)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Could not resolve '%s' as a layout id: %v", arg, err))
+		return arg
+	}
+	return found
+}
+
 // ensureKlf appends .klf extension if not present (case-insensitive check).
 func ensureKlf(name string) string {
 	if strings.ToLower(filepath.Ext(name)) != ".klf" {
@@ -76,6 +282,46 @@ func ensureNoKlf(name string) string {
 	return name
 }
 
+// loadBoardGeometryFromFlags puts the board geometry of the --geometry-file flag in use,
+// if set. It must be called before any layouts are loaded.
+func loadBoardGeometryFromFlags(c *cli.Command) error {
+	name := c.String("geometry-file")
+	if name == "" {
+		return nil
+	}
+
+	geometry, err := kc.NewBoardGeometryFromFile(filepath.Join(configDir, name))
+	if err != nil {
+		return fmt.Errorf("could not load board geometry: %w", err)
+	}
+	kc.UseBoardGeometry(geometry)
+	return nil
+}
+
+// loadBoardFromFlags returns the board preset of the --board flag, or nil if not set, and
+// puts its geometry in use unless --geometry-file is set. It must be called before any
+// layouts are loaded.
+func loadBoardFromFlags(c *cli.Command) (*kc.BoardPreset, error) {
+	name := c.String("board")
+	if name == "" {
+		return nil, nil
+	}
+
+	board, err := kc.ParseBoardPreset(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.String("geometry-file") == "" {
+		kc.UseBoardGeometry(board.Geometry)
+	}
+	return board, nil
+}
+
+// printSeed prints the seed of a stochastic command, so that the run can be reproduced.
+func printSeed(seed int64) {
+	fmt.Printf("Seed: %d (use --seed %d to reproduce this run)\n", seed, seed)
+}
+
 // loadTargetLoadsFromFlags loads TargetLoads from flags and config file.
 // Command-line flags override config file values.
 func loadTargetLoadsFromFlags(c *cli.Command) (*kc.TargetLoads, error) {
@@ -90,12 +336,13 @@ func loadTargetLoadsFromFlags(c *cli.Command) (*kc.TargetLoads, error) {
 	} else {
 		configPath := filepath.Join(configDir, configFile)
 		targets, err = kc.NewTargetLoadsFromFile(configPath)
-		if err != nil {
+		if err == nil {
+			slog.Debug("loaded target loads", "path", configPath)
+		} else {
 			// Check if user didn't explicitly set the flag
 			if !c.IsSet("load-targets-file") {
 				// Using default file and it's missing - warn and use defaults
-				fmt.Fprintf(os.Stderr, "Warning: Config file '%s' not found at %s. Using hardcoded defaults.\n",
-					configFile, configPath)
+				slog.Warn(fmt.Sprintf("Config file '%s' not found at %s. Using hardcoded defaults.", configFile, configPath))
 				targets = kc.NewTargetLoads()
 			} else {
 				// User explicitly requested this file - fail with error
@@ -129,9 +376,60 @@ func loadTargetLoadsFromFlags(c *cli.Command) (*kc.TargetLoads, error) {
 		}
 	}
 
+	if c.IsSet("roll-quality") {
+		if err := targets.SetRollQuality(c.String("roll-quality")); err != nil {
+			return nil, fmt.Errorf("could not set roll quality: %w", err)
+		}
+	}
+
+	if c.IsSet("fatigue") {
+		if err := targets.SetFatigue(c.String("fatigue")); err != nil {
+			return nil, fmt.Errorf("could not set fatigue: %w", err)
+		}
+	}
+
+	if problems := targets.Problems(); len(problems) > 0 {
+		if c.Bool("strict-targets") {
+			return nil, fmt.Errorf("misconfigured target loads: %s", strings.Join(problems, "; "))
+		}
+		for _, problem := range problems {
+			slog.Warn(problem)
+		}
+	}
+
+	if c.IsSet("metric-version") {
+		if err := targets.SetMetricVersion(int(c.Int("metric-version"))); err != nil {
+			return nil, fmt.Errorf("could not set metric version: %w", err)
+		}
+	}
+
+	if c.IsSet("sfs-definition") {
+		definition, err := kc.ParseSFSDefinition(c.String("sfs-definition"))
+		if err != nil {
+			return nil, err
+		}
+		targets.SFSDefinition = definition
+	}
+
+	if name := c.String("baseline"); name != "" {
+		baseline, err := loadLayout(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not load baseline layout: %w", err)
+		}
+		targets.Baseline = baseline
+	}
+
 	return targets, nil
 }
 
+// printFingerLoadWarnings logs why an analysed layout can't meet its target or maximum
+// finger loads, if it can't.
+func printFingerLoadWarnings(an *kc.Analyser) {
+	for _, warning := range an.FingerLoadWarnings() {
+		slog.Warn(warning)
+	}
+}
+
 // loadWeightsFromFlags loads weights from the --weights-file and --weights flags.
 // Weights specified via --weights take precedence over file-based weights.
 func loadWeightsFromFlags(c *cli.Command) (*kc.Weights, error) {
@@ -142,6 +440,47 @@ func loadWeightsFromFlags(c *cli.Command) (*kc.Weights, error) {
 	return kc.NewWeightsFromParams(weightsPath, c.String("weights"))
 }
 
+// corpusDescription returns the --corpus flags as given, for the provenance of an
+// optimized layout.
+func corpusDescription(c *cli.Command) string {
+	return strings.Join(corpusSpecs(c), ", ")
+}
+
+// weightsDescription returns the weights flags as given, for the provenance of an
+// optimized layout: the weights file, followed by the --weights overrides. The equal
+// weights don't use the weights file.
+func weightsDescription(c *cli.Command) string {
+	if equal, _ := kc.UsesEqualWeights(c.String("weights")); equal {
+		return c.String("weights")
+	}
+	var parts []string
+	if file := c.String("weights-file"); file != "" {
+		parts = append(parts, file)
+	}
+	if weights := c.String("weights"); weights != "" {
+		parts = append(parts, weights)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// loadReferenceSetFromFlags builds the normalisation reference set from the
+// --reference-glob and --reference-list flags. Without either flag, the default
+// reference set is returned. A listed layout that is not in layoutDir is an error.
+func loadReferenceSetFromFlags(c *cli.Command) (*kc.ReferenceSet, error) {
+	listPath := c.String("reference-list")
+	if listPath != "" {
+		listPath = filepath.Join(configDir, listPath)
+	}
+	reference, err := kc.NewReferenceSetFromParams(c.String("reference-glob"), listPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := reference.CheckNames(layoutDir); err != nil {
+		return nil, fmt.Errorf("invalid reference list %s: %w", listPath, err)
+	}
+	return reference, nil
+}
+
 // getValues returns a slice containing the values associated with the provided keys.
 // If no keys are specified, it returns all values present in the map in non-deterministic order.
 // It panics if a specified key is not found in the map.
@@ -160,3 +499,61 @@ func flags[K comparable, V any](m map[K]V, keys ...K) []V {
 	}
 	return values
 }
+
+// layoutOutput is where and under which name a command saves a layout, from the --out,
+// --name, --force and --dry-run flags.
+type layoutOutput struct {
+	dir    string // Directory to save to
+	name   string // Name of the saved layout, or "" for the default name
+	force  bool   // Whether to overwrite existing files
+	dryRun bool   // Whether to only show what would be saved
+}
+
+// layoutOutputFromFlags reads the --out, --name, --force and --dry-run flags, creating the
+// output directory if needed.
+func layoutOutputFromFlags(c *cli.Command) (layoutOutput, error) {
+	out := layoutOutput{dir: layoutDir, name: c.String("name"), force: c.Bool("force"), dryRun: c.Bool("dry-run")}
+	if strings.ContainsAny(out.name, `/\`) {
+		return layoutOutput{}, fmt.Errorf("layout name must not contain a path separator: %s", out.name)
+	}
+	out.name = ensureNoKlf(out.name)
+	if dir := c.String("out"); dir != "" {
+		if out.dryRun {
+			out.dir = dir
+			return out, nil
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return layoutOutput{}, fmt.Errorf("could not create output directory %s: %w", dir, err)
+		}
+		out.dir = dir
+	}
+	return out, nil
+}
+
+// layoutName returns the name to save a layout under: --name if given, or defaultName.
+func (o layoutOutput) layoutName(defaultName string) string {
+	if o.name != "" {
+		return o.name
+	}
+	return defaultName
+}
+
+// path returns the path to save a layout with the given name to, and an error if the file
+// exists and --force is not set, so a command can refuse before doing any work.
+func (o layoutOutput) path(name string) (string, error) {
+	path := filepath.Join(o.dir, name+".klf")
+	if !o.force {
+		if _, err := os.Stat(path); err == nil {
+			return "", fmt.Errorf("layout file %s already exists (use --force to overwrite)", path)
+		}
+	}
+	return path, nil
+}
+
+// save saves a layout to its path, unless --dry-run is given.
+func (o layoutOutput) save(layout *kc.SplitLayout, path string) error {
+	if o.dryRun {
+		return nil
+	}
+	return layout.SaveToFile(path)
+}
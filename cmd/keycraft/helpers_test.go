@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/urfave/cli/v3"
 )
 
 // writeTestLayout creates a layout file with the given content for testing.
@@ -225,3 +230,80 @@ func BenchmarkFloatConv(b *testing.B) {
 		})
 	}
 }
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLogLevel(tt.input)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("parseLogLevel(%q) = %v, %v; want %v, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newConsoleHandler(&buf, slog.LevelInfo))
+	logger.Debug("hidden")
+	logger.Info("loaded", "path", "a.txt")
+	logger.With("layout", "qwerty").WithGroup("cache").Warn("slow", "hits", 3)
+
+	want := "Info: loaded path=a.txt\nWarning: slow layout=qwerty cache.hits=3\n"
+	if buf.String() != want {
+		t.Errorf("console log = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	app := &cli.Command{
+		Name: "test",
+		// Fresh flag instances, to avoid polluting shared flag state
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "log-level", Value: "warn"},
+			&cli.StringFlag{Name: "log-file"},
+		},
+		Before: setupLogging,
+		After:  closeLogging,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			slog.Debug("not logged")
+			slog.Info("logged to the file only")
+			return nil
+		},
+	}
+	if err := app.Run(context.Background(), []string{"test", "--log-file", path}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read log file: %v", err)
+	}
+	if got := string(data); !strings.Contains(got, `"msg":"logged to the file only"`) || strings.Contains(got, "not logged") {
+		t.Errorf("log file = %q", got)
+	}
+
+	// The file is only created when a record is written to it
+	quiet := filepath.Join(t.TempDir(), "quiet.jsonl")
+	app.Action = func(ctx context.Context, cmd *cli.Command) error { return nil }
+	if err := app.Run(context.Background(), []string{"test", "--log-file", quiet}); err != nil {
+		t.Fatalf("app.Run failed: %v", err)
+	}
+	if _, err := os.Stat(quiet); !os.IsNotExist(err) {
+		t.Errorf("expected no log file without records: %v", err)
+	}
+
+	if err := app.Run(context.Background(), []string{"test", "--log-level", "loud"}); err == nil {
+		t.Error("expected error for an invalid log level")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/urfave/cli/v3"
+)
+
+// idCommand defines the CLI command for printing short layout identifiers.
+var idCommand = &cli.Command{
+	Name:    "id",
+	Aliases: []string{"hash"},
+	Usage:   "Print a short stable identifier of one or more layouts",
+	Description: "The identifier is derived from the layout type and keys only, so it " +
+		"ignores the layout name. Other commands accept an identifier (or a prefix of at " +
+		"least 4 characters) wherever a layout name is expected. Without arguments, all " +
+		"layouts are listed. Identical layouts stored under different names are reported.",
+	ArgsUsage:     "<layout1> <layout2> ...",
+	Action:        idAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// idAction prints the identifier of each requested layout (or all layouts) and
+// warns about layouts in layoutDir that share an identifier.
+func idAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	names := c.Args().Slice()
+	if len(names) == 0 {
+		ids, err := kc.LayoutIDs(layoutDir)
+		if err != nil {
+			return fmt.Errorf("could not compute layout ids: %w", err)
+		}
+		names = slices.Sorted(maps.Keys(ids))
+	}
+
+	ids := make(map[string]bool, len(names))
+	for _, name := range names {
+		layout, err := loadLayout(name)
+		if err != nil {
+			return fmt.Errorf("could not load layout: %w", err)
+		}
+		id := layout.ID()
+		ids[id] = true
+		fmt.Printf("%s  %s\n", id, layout.Name)
+	}
+
+	duplicates, err := kc.DuplicateLayouts(layoutDir)
+	if err != nil {
+		return fmt.Errorf("could not check for duplicate layouts: %w", err)
+	}
+	for _, id := range slices.Sorted(maps.Keys(duplicates)) {
+		if ids[id] {
+			slog.Warn(fmt.Sprintf("layouts %s are identical (id %s)", strings.Join(duplicates[id], ", "), id))
+		}
+	}
+
+	return nil
+}
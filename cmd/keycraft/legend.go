@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// legendCommand defines the CLI command for explaining the finger, column and row names.
+var legendCommand = &cli.Command{
+	Name:  "legend",
+	Usage: "Show the finger (F0-F9), column (C0-C11) and row (R0-R3) names used in tables",
+	Description: "Renders the finger of every key over a board, with the names of the columns " +
+		"and rows as used by the metrics and tables, and spells out the finger names. Takes a " +
+		"layout type or a layout, and shows rowstag keys by default.",
+	ArgsUsage: "[<layout-type> | <layout>]",
+	Action:    legendAction,
+	ShellComplete: func(ctx context.Context, c *cli.Command) {
+		for _, name := range kc.LayoutTypeStrings {
+			fmt.Println(name)
+		}
+		layoutShellComplete(ctx, c)
+	},
+}
+
+// legendAction renders the legend of a layout type.
+func legendAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	layoutType, err := buildLegendInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	return tui.RenderLegend(os.Stdout, layoutType)
+}
+
+// buildLegendInput returns the layout type of the argument, which is a layout type or the
+// name of a layout, or rowstag without an argument.
+func buildLegendInput(c *cli.Command) (kc.LayoutType, error) {
+	switch c.NArg() {
+	case 0:
+		return kc.ROWSTAG, nil
+	case 1:
+	default:
+		return 0, fmt.Errorf("expected at most 1 layout type or layout, got %d", c.NArg())
+	}
+	if layoutType, ok := kc.ParseLayoutType(c.Args().First()); ok {
+		return layoutType, nil
+	}
+	layout, err := loadLayout(c.Args().First())
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a layout type (rowstag, anglemod, ortho, colstag) or a layout: %w",
+			c.Args().First(), err)
+	}
+	return layout.LayoutType, nil
+}
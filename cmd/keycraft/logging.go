@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+)
+
+// logFlags configure the log of every command. They are flags of the root command, and apply
+// to all subcommands.
+var logFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "log-level",
+		Aliases:  []string{"ll"},
+		Usage:    "Messages to show on stderr: \"debug\", \"info\" or \"warn\".",
+		Value:    "warn",
+		Category: "Logging",
+	},
+	&cli.StringFlag{
+		Name:    "log-file",
+		Aliases: []string{"lf"},
+		Usage: "JSONL file to also write the log to, with info messages and above (debug messages " +
+			"with --log-level debug), such as the progress and statistics of the optimizer.",
+		Category: "Logging",
+	},
+}
+
+// logFile is the file of --log-file, closed when the command ends.
+var logFile *lazyFile
+
+func init() {
+	// Log warnings until the flags of the command are parsed, such as in tests
+	slog.SetDefault(slog.New(newConsoleHandler(nil, slog.LevelWarn)))
+}
+
+// setupLogging sets up the log of the command from the --log-level and --log-file flags.
+func setupLogging(ctx context.Context, c *cli.Command) (context.Context, error) {
+	level, err := parseLogLevel(c.String("log-level"))
+	if err != nil {
+		return ctx, err
+	}
+
+	var handler slog.Handler = newConsoleHandler(nil, level)
+	if path := c.String("log-file"); path != "" {
+		logFile = &lazyFile{path: path}
+		fileHandler := slog.NewJSONHandler(logFile, &slog.HandlerOptions{Level: min(level, slog.LevelInfo)})
+		handler = slog.NewMultiHandler(handler, fileHandler)
+	}
+	slog.SetDefault(slog.New(handler))
+	return ctx, nil
+}
+
+// closeLogging closes the file of --log-file, if any.
+func closeLogging(ctx context.Context, c *cli.Command) error {
+	if logFile == nil {
+		return nil
+	}
+	slog.SetDefault(slog.New(newConsoleHandler(nil, slog.LevelWarn)))
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
+
+// lazyFile is a file that is created when it is first written to, so that the root command
+// can set up --log-file before the subcommand runs, and a subcommand that refuses to run,
+// such as optimize with --dry-run, creates no file. The log handlers serialise the writes.
+type lazyFile struct {
+	path string
+	f    *os.File
+	err  error // Error of creating the file
+}
+
+// Write writes p to the file, creating it first if it doesn't exist yet.
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if l.f == nil && l.err == nil {
+		l.f, l.err = os.Create(l.path)
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+	return l.f.Write(p)
+}
+
+// Close closes the file, if it was created, or returns the error of creating it.
+func (l *lazyFile) Close() error {
+	if l.err != nil {
+		return fmt.Errorf("could not create log file %s: %w", l.path, l.err)
+	}
+	if l.f == nil {
+		return nil
+	}
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("could not close log file: %w", err)
+	}
+	return nil
+}
+
+// parseLogLevel parses the value of --log-level.
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	default:
+		return 0, fmt.Errorf("invalid log level %q; must be one of: debug, info, warn", s)
+	}
+}
+
+// consoleHandler writes log records as lines of text, such as "Warning: message key=value".
+type consoleHandler struct {
+	w      io.Writer   // Writer to write to, or nil for os.Stderr at the time of writing
+	level  slog.Level  // Minimum level to write
+	attrs  []slog.Attr // Attributes added with WithAttrs
+	prefix string      // Prefix of attribute keys added with WithGroup
+	mu     *sync.Mutex
+}
+
+// newConsoleHandler returns a handler that writes records of at least the level to w, or to
+// os.Stderr if w is nil.
+func newConsoleHandler(w io.Writer, level slog.Level) *consoleHandler {
+	return &consoleHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+// Enabled reports whether the handler writes records of the level.
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+// Handle writes a record as a line of text.
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case r.Level >= slog.LevelInfo:
+		b.WriteString("Info: ")
+	default:
+		b.WriteString("Debug: ")
+	}
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s%s=%v", h.prefix, a.Key, a.Value)
+		return true
+	})
+	b.WriteByte('\n')
+
+	w := h.w
+	if w == nil {
+		w = os.Stderr
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that writes the attributes with every record.
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that prefixes the keys of later attributes with the group name.
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
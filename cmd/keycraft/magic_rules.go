@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// magicRulesCommand defines the CLI command for suggesting the rules of a magic key.
+var magicRulesCommand = &cli.Command{
+	Name:    "magic-rules",
+	Aliases: []string{"mr"},
+	Usage:   "Suggest the rules of a magic key that absorb the most SFB and SFS of a layout",
+	Description: "Evaluates every bigram of the corpus as a rule of a magic key (after the first " +
+		"character, the magic key types the second), and lists the rules that absorb the most " +
+		"same-finger bigrams and skipgrams, at most one per previous character. The layout's " +
+		"magic key is used if it has one, and its rules are replaced.",
+	Flags: append(append(commonFlags("corpus", "bigram-weighting", "geometry-file", "load-targets-file",
+		"target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties", "roll-quality", "fatigue", "strict-targets", "metric-version", "sfs-definition"),
+		corpusBuildFlags()...),
+		&cli.StringFlag{
+			Name:    "key",
+			Aliases: []string{"k"},
+			Usage:   "Character of the magic key on the layout, if the layout has no magic key.",
+			Value:   "*",
+		},
+		&cli.IntFlag{
+			Name:    "rules",
+			Aliases: []string{"n"},
+			Usage:   "Maximum number of rules to suggest.",
+			Value:   10,
+		},
+	),
+	ArgsUsage:     "<layout>",
+	Action:        magicRulesAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// magicRulesAction suggests the rules of a magic key for a layout and renders them.
+func magicRulesAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildMagicRulesInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	result, err := kc.SuggestMagicRules(input)
+	if err != nil {
+		return fmt.Errorf("could not suggest magic key rules: %w", err)
+	}
+	tui.RenderMagicRules(result)
+	return nil
+}
+
+// buildMagicRulesInput gathers the layout, corpus and options of the magic-rules command.
+func buildMagicRulesInput(c *cli.Command) (kc.MagicRulesInput, error) {
+	if c.NArg() != 1 {
+		return kc.MagicRulesInput{}, fmt.Errorf("expected exactly 1 layout, got %d", c.NArg())
+	}
+	key := []rune(c.String("key"))
+	if len(key) != 1 {
+		return kc.MagicRulesInput{}, fmt.Errorf("--key must be a single character, got %q", c.String("key"))
+	}
+	if c.Int("rules") < 1 {
+		return kc.MagicRulesInput{}, fmt.Errorf("--rules must be at least 1 (got %d)", c.Int("rules"))
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.MagicRulesInput{}, err
+	}
+	layout, err := loadLayout(c.Args().First())
+	if err != nil {
+		return kc.MagicRulesInput{}, fmt.Errorf("could not load layout: %w", err)
+	}
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.MagicRulesInput{}, fmt.Errorf("could not load corpus: %w", err)
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.MagicRulesInput{}, fmt.Errorf("could not load target loads: %w", err)
+	}
+
+	return kc.MagicRulesInput{
+		Layout:   layout,
+		Corpus:   corpus,
+		Targets:  targets,
+		MagicKey: key[0],
+		MaxRules: int(c.Int("rules")),
+	}, nil
+}
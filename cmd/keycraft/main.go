@@ -42,6 +42,9 @@ func main() {
 		Authors: []any{
 			&mail.Address{Name: "Barend Scholtus", Address: "barend.scholtus@gmail.com"},
 		},
+		Flags:  logFlags,
+		Before: setupLogging,
+		After:  closeLogging,
 		Commands: []*cli.Command{
 			corpusCommand,
 			viewCommand,
@@ -49,13 +52,34 @@ func main() {
 			rankCommand,
 			flipCommand,
 			optimizeCommand,
+			pinsCommand,
 			generateCommand,
+			randomCommand,
+			tuneBLSCommand,
+			experimentCommand,
+			idCommand,
+			dedupeCommand,
+			convertFilesCommand,
+			similarityCommand,
+			abtestCommand,
+			statsCommand,
+			plotHistoryCommand,
+			swapMatrixCommand,
+			magicRulesCommand,
+			publishReportCommand,
+			calibrateCommand,
+			geometryCommand,
+			legendCommand,
+			shortcutsCommand,
+			verifyCommand,
+			weightsCommand,
+			runsCommand,
 		},
 	}
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %[1]v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
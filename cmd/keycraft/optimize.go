@@ -3,8 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	kc "github.com/rbscholtus/keycraft/internal/keycraft"
 	"github.com/rbscholtus/keycraft/internal/tui"
@@ -34,6 +41,52 @@ var optimizeFlagsMap = map[string]cli.Flag{
 			"All others are pinned.",
 		Category: "Optimization",
 	},
+	"spill-keys": &cli.StringFlag{
+		Name:    "spill-keys",
+		Aliases: []string{"sk"},
+		Usage: "Empty keys the optimizer may move characters to, as a region (e.g., \"cols:0,cols:11\" " +
+			"or \"thumbs\"), or none. All other empty keys are dead and stay empty; pins must agree.",
+		Category: "Optimization",
+	},
+	"region": &cli.StringFlag{
+		Name:    "region",
+		Aliases: []string{"rgn"},
+		Usage: "Only swap keys within a region of the board; all other keys stay fixed. " +
+			"One or more of left-hand, right-hand, top-row, home-row, bottom-row, thumbs, " +
+			"cols:N-M (e.g., \"cols:10-11\" or \"top-row,thumbs\"). Combined with pins.",
+		Category: "Optimization",
+	},
+	"swap-classes": &cli.StringFlag{
+		Name:    "swap-classes",
+		Aliases: []string{"swc"},
+		Usage: "Which keys to move, each only with keys of the same class: letters, punct, " +
+			"both (\"letters,punct\"), or all for any keys. Keys of other classes stay fixed.",
+		Value:    "all",
+		Category: "Optimization",
+	},
+	"preserve-shortcuts": &cli.StringFlag{
+		Name:    "preserve-shortcuts",
+		Aliases: []string{"ps"},
+		Usage: "Pin the keys of Ctrl-based shortcuts where they are on the input layout: a preset " +
+			"(zxcv, edit or common) or the characters to pin (e.g., \"zxcvas\"). Combined with pins and free.",
+		Category: "Optimization",
+	},
+	"max-changes": &cli.UintFlag{
+		Name:    "max-changes",
+		Aliases: []string{"mc"},
+		Usage: "Maximum number of key positions at which the best layout may differ from the " +
+			"input layout (0 = unlimited), to limit the relearning cost of migrating.",
+		Value:    0,
+		Category: "Optimization",
+	},
+	"change-weight": &cli.FloatFlag{
+		Name:    "change-weight",
+		Aliases: []string{"cw"},
+		Usage: "Cost of each key position at which a layout differs from the input layout, " +
+			"added to its score, to trade metrics against relearning cost (0 = none).",
+		Value:    0,
+		Category: "Optimization",
+	},
 	"generations": &cli.UintFlag{
 		Name:     "generations",
 		Aliases:  []string{"g"},
@@ -48,17 +101,76 @@ var optimizeFlagsMap = map[string]cli.Flag{
 		Value:    5,
 		Category: "Optimization",
 	},
-	"seed": &cli.Int64Flag{
+	"seed": &cli.StringFlag{
 		Name:     "seed",
 		Aliases:  []string{"s"},
-		Usage:    "Random seed for reproducible results. Uses current timestamp if 0.",
+		Usage:    "Random seed for reproducible results: a positive number, or random for a new seed. The seed is printed at the start.",
+		Value:    "random",
+		Category: "Optimization",
+	},
+	"compound-moves": &cli.BoolFlag{
+		Name:    "compound-moves",
+		Aliases: []string{"cm"},
+		Usage: "Also move keys in 3-cycles and row/column rotations, to escape local optima " +
+			"that no sequence of improving swaps can leave. Slower per generation.",
+		Value:    false,
+		Category: "Optimization",
+	},
+	"place-remaining": &cli.BoolFlag{
+		Name:    "place-remaining",
+		Aliases: []string{"pr"},
+		Usage: "After optimizing, place the punctuation and symbols of the corpus on the punctuation " +
+			"keys: the most frequent ones get a key, where they cost least. Keys pinned with " +
+			"--pins or --pins-file stay; --free, --region and --swap-classes don't apply.",
+		Value:    false,
+		Category: "Optimization",
+	},
+	"taboo": &cli.StringFlag{
+		Name:    "taboo",
+		Aliases: []string{"tb"},
+		Usage: "Bigrams and trigrams that the best layout must not type as same-finger bigrams or " +
+			"scissors, separated by commas (e.g., \"th,ing\"). Swaps that do are never made.",
+		Category: "Optimization",
+	},
+	"score-cache-size": &cli.UintFlag{
+		Name:     "score-cache-size",
+		Aliases:  []string{"scs"},
+		Usage:    "Maximum number of layout scores kept in the score cache (0 = unbounded).",
+		Value:    1000000,
+		Category: "Optimization",
+	},
+	"subsample": &cli.FloatFlag{
+		Name: "subsample",
+		Usage: "Score candidate layouts against a random sample of this fraction of the corpus, " +
+			"redrawn every generation (0 = full corpus). New best layouts are confirmed against " +
+			"the full corpus. Speeds up optimizing with huge corpora.",
 		Value:    0,
 		Category: "Optimization",
 	},
-	"log-file": &cli.StringFlag{
-		Name:     "log-file",
-		Aliases:  []string{"lf"},
-		Usage:    "JSONL log file path for detailed optimization metrics.",
+	"bls-params": &cli.StringFlag{
+		Name: "bls-params",
+		Usage: "BLS parameters to use instead of the defaults, e.g. \"l0=0.1,t=50,tabu=1,random=0.4\" " +
+			"(see tune-bls). Parameters that are left out keep their default.",
+		Category: "Optimization",
+	},
+	"history-file": &cli.StringFlag{
+		Name:     "history-file",
+		Aliases:  []string{"hf"},
+		Usage:    "JSONL file recording every new-best layout with its score and metrics (see plot-history).",
+		Category: "Optimization",
+	},
+	"run-dir": &cli.StringFlag{
+		Name:    "run-dir",
+		Aliases: []string{"rd"},
+		Usage: "Directory to record the run in: a manifest with the parameters, the history of " +
+			"new-best layouts and the best layout (see runs compare).",
+		Category: "Optimization",
+	},
+	"tui": &cli.BoolFlag{
+		Name: "tui",
+		Usage: "Show a live dashboard of the optimization instead of progress lines: cost sparklines, " +
+			"metric costs and board of the best layout, perturbation types and cache hit rate.",
+		Value:    false,
 		Category: "Optimization",
 	},
 }
@@ -69,9 +181,26 @@ func optFlags(keys ...string) []cli.Flag {
 	return flags(optimizeFlagsMap, keys...)
 }
 
+// optimizeCorpusFlag replaces the shared --corpus flag of the optimize command, so that
+// a layout can be optimised for several corpora at once.
+var optimizeCorpusFlag = &cli.StringSliceFlag{
+	Name:    "corpus",
+	Aliases: []string{"c"},
+	Usage: "Corpus file for calculating metrics (from data/corpus directory). Repeat with " +
+		"weights to optimize for several corpora (e.g., \"-c en.txt:0.6 -c nl.txt:0.4\").",
+	Value:    []string{"default.txt"},
+	Category: "", // General/uncategorized
+}
+
 // optimizeCmdFlags returns all flags for the optimise command
 func optimizeCmdFlags() []cli.Flag {
-	return append(commonFlags(), optFlags()...)
+	common := commonFlags()
+	for i, f := range common {
+		if f == commonFlagsMap["corpus"] {
+			common[i] = optimizeCorpusFlag
+		}
+	}
+	return append(append(append(common, corpusBuildFlags()...), optFlags()...), saveFlags()...)
 }
 
 // optimizeCommand defines the "optimize" CLI command for running Breakout Local Search (BLS)
@@ -89,42 +218,113 @@ var optimizeCommand = &cli.Command{
 // optimizeAction manages the full optimization workflow: it builds the
 // optimization input from CLI flags, executes the BLS algorithm, persists the
 // best discovered layout, and generates a comparative ranking against the
-// original layout.
+// original layout. Ctrl+C stops the search, after which the best layout so far
+// is saved and reported, and the command exits with exitInterrupted.
 func optimizeAction(ctx context.Context, c *cli.Command) error {
 	if isShellCompletion() {
 		return nil
 	}
 
+	// Refuse before anything is logged, which would create the log file
+	if c.Bool("dry-run") && (c.String("log-file") != "" || c.String("history-file") != "" || c.String("run-dir") != "") {
+		return fmt.Errorf("cannot use --dry-run with --log-file, --history-file or --run-dir, which write files")
+	}
+
 	input, err := buildOptimizeInput(c, nil, false)
 	if err != nil {
 		return fmt.Errorf("could not parse user input: %w", err)
 	}
+	printSeed(input.Seed)
+	printFingerLoadWarnings(kc.NewAnalyser(input.Layout, input.Corpus, input.Targets))
+
+	// Refuse to overwrite a layout before searching, rather than after
+	out, err := layoutOutputFromFlags(c)
+	if err != nil {
+		return err
+	}
+	bestName := out.layoutName(input.Layout.Name + "-opt")
+	bestPath, err := out.path(bestName)
+	if err != nil {
+		return err
+	}
+
+	// Record the run in a run directory if requested
+	historyFilePath := c.String("history-file")
+	runDir := c.String("run-dir")
+	if runDir != "" {
+		if historyFilePath != "" {
+			return fmt.Errorf("cannot use both --run-dir and --history-file")
+		}
+		if err := os.MkdirAll(runDir, 0755); err != nil {
+			return fmt.Errorf("could not create run directory %s: %w", runDir, err)
+		}
+		historyFilePath = filepath.Join(runDir, kc.RunHistoryFile)
+	}
 
-	// Open log file if requested
-	logFilePath := c.String("log-file")
-	if logFilePath != "" {
-		f, err := os.Create(logFilePath)
+	// Open history file if requested
+	if historyFilePath != "" {
+		f, err := os.Create(historyFilePath)
 		if err != nil {
-			return fmt.Errorf("could not create log file %s: %w", logFilePath, err)
+			return fmt.Errorf("could not create history file %s: %w", historyFilePath, err)
 		}
 		defer kc.CloseFile(f)
-		input.LogFile = f
+		input.HistoryFile = f
 	}
 
-	optResult, err := kc.OptimizeLayout(input, os.Stdout)
+	// Stop the search on Ctrl+C or SIGTERM, keeping the best layout found so far
+	searchCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Show either the live dashboard or progress lines
+	var console io.Writer = os.Stdout
+	if c.Bool("tui") {
+		input.Progress = tui.NewDashboard(os.Stdout, input.Layout.Name).Update
+		console = nil
+	}
+
+	started := time.Now()
+	optResult, err := kc.OptimizeLayout(searchCtx, input, console)
 	if err != nil {
 		return fmt.Errorf("could not optimize layout: %w", err)
 	}
 
 	origPath := filepath.Join(layoutDir, optResult.OriginalLayout.Name+".klf")
-	bestPath := filepath.Join(layoutDir, optResult.BestLayout.Name+".klf")
-	if err := optResult.BestLayout.SaveToFile(bestPath); err != nil {
+	optResult.BestLayout.Name = bestName
+	optResult.BestLayout.Provenance = &kc.Provenance{
+		Source:  optResult.OriginalLayout.Name,
+		Seed:    input.Seed,
+		Corpus:  corpusDescription(c),
+		Weights: weightsDescription(c),
+		Pins:    input.Pinned,
+	}
+	if err := out.save(optResult.BestLayout, bestPath); err != nil {
 		return fmt.Errorf("could not save best layout to %s: %w", bestPath, err)
 	}
+	reportPath := strings.TrimSuffix(bestPath, ".klf") + ".changes.md"
+	if !out.dryRun {
+		if err := saveOptimizeReport(reportPath, optResult, input); err != nil {
+			return err
+		}
+	}
 
+	if runDir != "" {
+		if err := saveRun(c, runDir, input, optResult, started); err != nil {
+			return err
+		}
+	}
+
+	// The best layout is safe, so a second Ctrl+C may end the report below
+	stop()
+
+	// Without --dry-run, compare the saved best layout
 	layoutsToCompare := []string{origPath, bestPath}
+	var unsaved []*kc.SplitLayout
+	if out.dryRun {
+		layoutsToCompare, unsaved = []string{origPath}, []*kc.SplitLayout{optResult.BestLayout}
+	}
 	viewResult, err := kc.ViewLayouts(kc.ViewInput{
 		LayoutFiles: layoutsToCompare,
+		Layouts:     unsaved,
 		Corpus:      input.Corpus,
 		Targets:     input.Targets,
 	})
@@ -135,34 +335,133 @@ func optimizeAction(ctx context.Context, c *cli.Command) error {
 	if err := tui.RenderView(viewResult); err != nil {
 		return fmt.Errorf("could not render view: %w", err)
 	}
+	if len(optResult.Placed) > 0 {
+		fmt.Printf("Placed %q on the punctuation keys, leaving out %q.\n",
+			string(optResult.Placed), string(optResult.Dropped))
+	} else if input.PlaceRemaining != nil {
+		fmt.Println("Placed no new characters on the punctuation keys.")
+	}
+	if len(optResult.Taboo) > 0 {
+		slog.Warn(fmt.Sprintf("The best layout still types %s as same-finger bigrams or scissors.",
+			tabooList(optResult.Taboo)))
+	}
+	if input.MaxChanges > 0 || input.ChangeWeight > 0 {
+		fmt.Printf("The best layout differs from %s at %d key positions.\n",
+			optResult.OriginalLayout.Name, kc.KeyDiff(optResult.OriginalLayout, optResult.BestLayout))
+	}
 
-	rankingInput := kc.RankingInput{
-		LayoutsDir:  layoutDir,
-		LayoutFiles: layoutsToCompare,
-		Corpus:      input.Corpus,
-		Targets:     input.Targets,
-		Weights:     input.Weights,
+	// Rank the original and best layouts against each corpus separately, so the
+	// trade-offs of a multi-corpus objective remain visible
+	corpora := input.Corpora
+	if len(corpora) <= 1 {
+		corpora = []kc.WeightedCorpus{{Corpus: input.Corpus, Weight: 1}}
+	}
+	for _, wc := range corpora {
+		rankingInput := kc.RankingInput{
+			LayoutsDir:  layoutDir,
+			LayoutFiles: layoutsToCompare,
+			Layouts:     unsaved,
+			Corpus:      wc.Corpus,
+			Targets:     input.Targets,
+			Weights:     input.Weights,
+			Reference:   input.Reference,
+		}
+
+		rankingResult, err := kc.ComputeRankings(rankingInput)
+		if err != nil {
+			return fmt.Errorf("could not compute layout rankings: %w", err)
+		}
+
+		displayOpts := tui.RankingDisplayOptions{
+			OutputFormat:   tui.OutputTable,
+			MetricsOption:  tui.MetricsWeighted,
+			ShowWeights:    true,
+			Weights:        input.Weights,
+			DeltasOption:   tui.DeltasCustom,
+			BaseLayoutName: optResult.OriginalLayout.Name,
+		}
+		if len(corpora) > 1 {
+			displayOpts.CorpusName = fmt.Sprintf("%s (weight %g)", wc.Corpus.Name, wc.Weight)
+		}
+
+		if err := tui.RenderRankingTable(rankingResult, displayOpts); err != nil {
+			return fmt.Errorf("could not render layout rankings: %w", err)
+		}
+	}
+
+	if optResult.Interrupted && out.dryRun {
+		return &exitCodeError{err: fmt.Errorf("optimization interrupted"), code: exitInterrupted}
+	}
+	if optResult.Interrupted {
+		return &exitCodeError{
+			err:  fmt.Errorf("optimization interrupted, saved the best layout so far to %s", bestPath),
+			code: exitInterrupted,
+		}
+	}
+	if out.dryRun {
+		fmt.Printf("Dry run: would save best layout to: %s\n", bestPath)
+		return nil
 	}
+	fmt.Printf("Saved best layout to: %s\n", bestPath)
+	fmt.Printf("Saved what changed to: %s\n", reportPath)
+	return nil
+}
 
-	rankingResult, err := kc.ComputeRankings(rankingInput)
+// saveOptimizeReport writes the key moves, the metrics before and after, and the score
+// trajectory of an optimization next to the best layout.
+func saveOptimizeReport(path string, result *kc.OptimizeResult, input kc.OptimizeInput) error {
+	report := kc.NewOptimizeReport(result, input.Corpus, input.Targets, tui.WeightedMetrics(input.Weights))
+	f, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("could not compute layout rankings: %w", err)
+		return fmt.Errorf("could not create report file %s: %w", path, err)
+	}
+	defer kc.CloseFile(f)
+	if err := tui.WriteOptimizeReport(f, report); err != nil {
+		return fmt.Errorf("could not save report to %s: %w", path, err)
 	}
+	return nil
+}
 
-	displayOpts := tui.RankingDisplayOptions{
-		OutputFormat:   tui.OutputTable,
-		MetricsOption:  tui.MetricsWeighted,
-		ShowWeights:    true,
-		Weights:        input.Weights,
-		DeltasOption:   tui.DeltasCustom,
-		BaseLayoutName: optResult.OriginalLayout.Name,
+// saveRun writes the best layout and the manifest of a run to its run directory.
+func saveRun(c *cli.Command, dir string, input kc.OptimizeInput, result *kc.OptimizeResult, started time.Time) error {
+	path := filepath.Join(dir, kc.RunLayoutFile)
+	if err := result.BestLayout.SaveToFile(path); err != nil {
+		return fmt.Errorf("could not save best layout to %s: %w", path, err)
 	}
+	return kc.WriteRunManifest(dir, kc.RunManifest{
+		Layout:      result.OriginalLayout.Name,
+		Started:     started,
+		ElapsedMs:   time.Since(started).Milliseconds(),
+		Interrupted: result.Interrupted,
+		Params:      runParams(c, input),
+	})
+}
 
-	if err := tui.RenderRankingTable(rankingResult, displayOpts); err != nil {
-		return fmt.Errorf("could not render layout rankings: %w", err)
+// runParams returns the values of the flags that affect the search, and the seed it used,
+// for the manifest of a run.
+func runParams(c *cli.Command, input kc.OptimizeInput) map[string]string {
+	params := map[string]string{
+		"layout": input.Layout.Name,
+		"seed":   strconv.FormatInt(input.Seed, 10),
+	}
+	for _, f := range c.Flags {
+		name := f.Names()[0]
+		switch name {
+		case "seed", "run-dir", "history-file", "tui", "out", "name", "force", "dry-run", "help":
+			continue
+		}
+		params[name] = fmt.Sprint(c.Value(name))
 	}
+	return params
+}
 
-	return nil
+// tabooList returns taboo bigrams as a list for messages, such as "th, ng".
+func tabooList(bigrams []kc.Bigram) string {
+	list := make([]string, len(bigrams))
+	for i, bi := range bigrams {
+		list[i] = bi.String()
+	}
+	return strings.Join(list, ", ")
 }
 
 // buildOptimizeInput gathers all input parameters for layout optimization.
@@ -170,7 +469,11 @@ func optimizeAction(ctx context.Context, c *cli.Command) error {
 //   - layout: if provided, uses this layout; if nil and skipLayoutLoad is false, loads from args
 //   - skipLayoutLoad: if true, skips layout loading and pin computation (for generate command)
 func buildOptimizeInput(c *cli.Command, layout *kc.SplitLayout, skipLayoutLoad bool) (kc.OptimizeInput, error) {
-	corpus, err := loadCorpusFromFlags(c)
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.OptimizeInput{}, err
+	}
+
+	corpora, err := loadCorporaFromFlags(c)
 	if err != nil {
 		return kc.OptimizeInput{}, fmt.Errorf("could not load corpus: %w", err)
 	}
@@ -190,11 +493,20 @@ func buildOptimizeInput(c *cli.Command, layout *kc.SplitLayout, skipLayoutLoad b
 		return kc.OptimizeInput{}, fmt.Errorf("number of generations must be above 0. Got: %d", numGenerations)
 	}
 
+	if w := c.Float("change-weight"); w < 0 {
+		return kc.OptimizeInput{}, fmt.Errorf("change weight must not be negative. Got: %g", w)
+	}
+
 	maxTime := c.Uint("maxtime")
 	if maxTime <= 0 {
 		return kc.OptimizeInput{}, fmt.Errorf("maximum time must be above 0. Got: %d", maxTime)
 	}
 
+	seed, err := kc.ParseSeed(c.String("seed"))
+	if err != nil {
+		return kc.OptimizeInput{}, err
+	}
+
 	// If layout not provided and not skipping, load from args (optimize command behavior)
 	if layout == nil && !skipLayoutLoad {
 		if c.Args().Len() != 1 {
@@ -207,7 +519,10 @@ func buildOptimizeInput(c *cli.Command, layout *kc.SplitLayout, skipLayoutLoad b
 	}
 
 	// Load pins (only when we have a layout)
-	var pinned *kc.PinnedKeys
+	var pinned, placementPins *kc.PinnedKeys
+	var swapClasses *kc.SwapClasses
+	var taboo *kc.TabooPatterns
+	var spill *kc.Region
 	if !skipLayoutLoad {
 		pinsPath := c.String("pins-file")
 		if pinsPath != "" {
@@ -217,18 +532,101 @@ func buildOptimizeInput(c *cli.Command, layout *kc.SplitLayout, skipLayoutLoad b
 		if err != nil {
 			return kc.OptimizeInput{}, fmt.Errorf("could not load pins: %w", err)
 		}
+
+		if spec := c.String("spill-keys"); spec != "" {
+			region, err := kc.ParseSpillKeys(spec)
+			if err != nil {
+				return kc.OptimizeInput{}, err
+			}
+			if spill, err = layout.SpillKeys(region); err == nil {
+				err = pinned.SetSpillKeys(spill, layout, pinsPath != "")
+			}
+			if err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not set spill-over keys: %w", err)
+			}
+		}
+
+		if c.Bool("place-remaining") {
+			placementPins, err = kc.LoadPinsFromParams(pinsPath, c.String("pins"), "", layout)
+			if err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not load pins: %w", err)
+			}
+		}
+
+		if spec := c.String("region"); spec != "" {
+			region, err := kc.ParseRegion(spec)
+			if err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not parse region: %w", err)
+			}
+			pinned.PinOutside(region)
+		}
+
+		swapClasses, err = kc.ParseSwapClasses(c.String("swap-classes"))
+		if err != nil {
+			return kc.OptimizeInput{}, fmt.Errorf("could not parse swap classes: %w", err)
+		}
+		if swapClasses != nil {
+			pinned.PinOtherClasses(swapClasses, layout)
+		}
+
+		if spec := c.String("preserve-shortcuts"); spec != "" {
+			keys, err := kc.ParseShortcutKeys(spec)
+			if err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not parse shortcut keys: %w", err)
+			}
+			if err := pinned.PinShortcuts(keys, layout); err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not preserve shortcuts: %w", err)
+			}
+		}
+		if spill != nil {
+			if err := pinned.CheckSpillKeys(spill); err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not set spill-over keys: %w", err)
+			}
+		}
+
+		if spec := c.String("taboo"); spec != "" {
+			taboo, err = kc.ParseTabooPatterns(spec)
+			if err != nil {
+				return kc.OptimizeInput{}, fmt.Errorf("could not parse taboo patterns: %w", err)
+			}
+		}
+	}
+
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.OptimizeInput{}, fmt.Errorf("could not load reference layouts: %w", err)
+	}
+
+	var tuning *kc.BLSTuning
+	if spec := c.String("bls-params"); spec != "" {
+		tuning, err = kc.ParseBLSTuning(spec)
+		if err != nil {
+			return kc.OptimizeInput{}, fmt.Errorf("could not parse BLS parameters: %w", err)
+		}
 	}
 
 	return kc.OptimizeInput{
 		Layout:         layout,
 		LayoutsDir:     layoutDir,
-		Corpus:         corpus,
+		Corpus:         kc.BlendCorpora(corpora),
+		Corpora:        corpora,
 		Targets:        targets,
 		Weights:        weights,
+		Reference:      reference,
 		Pinned:         pinned,
+		SwapClasses:    swapClasses,
 		NumGenerations: int(numGenerations),
 		MaxTime:        int(maxTime),
-		Seed:           c.Int64("seed"),
+		Seed:           seed,
 		UseParallel:    true,
+		CompoundMoves:  c.Bool("compound-moves"),
+		ScoreCacheSize: int(c.Uint("score-cache-size")),
+		Subsample:      c.Float("subsample"),
+		Tuning:         tuning,
+		MaxChanges:     int(c.Uint("max-changes")),
+		ChangeWeight:   c.Float("change-weight"),
+		PlaceRemaining: placementPins,
+		Taboo:          taboo,
+		SpillKeys:      spill,
 	}, nil
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/urfave/cli/v3"
+)
+
+// pinsCommand groups the subcommands for pins files.
+var pinsCommand = &cli.Command{
+	Name:     "pins",
+	Usage:    "Work with pins files, which fix keys during optimization",
+	Commands: []*cli.Command{pinsGenerateCommand, pinsCheckCommand},
+}
+
+// pinsGenerateCommand defines the CLI command for writing the pins file of a layout.
+var pinsGenerateCommand = &cli.Command{
+	Name:  "generate",
+	Usage: "Write a pins file for a layout from a pins policy",
+	Description: "Pins the keys of the layout that the rules of the policy match, and writes them " +
+		"in the pins file format, with the characters of the layout in comments above the rows. " +
+		"The file is saved as <layout>.pin in the config directory by default, where view shows " +
+		"it and optimize --pins-file finds it.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "policy",
+			Usage:    "Comma-separated rules of the keys to pin: " + pinRulesUsage() + ".",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Pins file to write (from data/config directory). Default: <layout>.pin.",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Overwrite an existing pins file instead of refusing to save.",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Show the pins file that would be written, without writing it.",
+		},
+	},
+	ArgsUsage:     "<layout>",
+	Action:        pinsGenerateAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// pinRulesUsage lists the rules of pins policies with what they pin.
+func pinRulesUsage() string {
+	rules := make([]string, len(kc.PinRules))
+	for i, rule := range kc.PinRules {
+		rules[i] = fmt.Sprintf("%s (%s)", rule.Name, rule.Description)
+	}
+	return strings.Join(rules, ", ")
+}
+
+// pinsGenerateAction writes the pins file of a layout.
+func pinsGenerateAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	layout, policy, path, err := buildPinsGenerateInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	pinned := policy.Pins(layout)
+	comment := fmt.Sprintf("Pins of %s, policy %s", layout.Name, policy)
+
+	if c.Bool("dry-run") {
+		if err := kc.WritePins(os.Stdout, pinned, layout, comment); err != nil {
+			return fmt.Errorf("could not show pins: %w", err)
+		}
+		fmt.Printf("\nDry run: would save pins to: %s\n", path)
+		return nil
+	}
+
+	if _, err := os.Stat(path); err == nil && !c.Bool("force") {
+		return fmt.Errorf("pins file %s already exists; use --force to overwrite", path)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create pins file: %w", err)
+	}
+	defer kc.CloseFile(file)
+	if err := kc.WritePins(file, pinned, layout, comment); err != nil {
+		return fmt.Errorf("could not save pins: %w", err)
+	}
+
+	count := 0
+	for _, p := range pinned {
+		if p {
+			count++
+		}
+	}
+	fmt.Printf("Saved %d pinned keys to: %s\n", count, path)
+	return nil
+}
+
+// buildPinsGenerateInput loads the layout of the argument, parses the --policy flag, and
+// returns the path of the pins file to write.
+func buildPinsGenerateInput(c *cli.Command) (*kc.SplitLayout, kc.PinPolicy, string, error) {
+	if c.NArg() != 1 {
+		return nil, nil, "", fmt.Errorf("expected exactly 1 layout, got %d", c.NArg())
+	}
+	layout, err := loadLayout(c.Args().First())
+	if err != nil {
+		return nil, nil, "", err
+	}
+	policy, err := kc.ParsePinPolicy(c.String("policy"))
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	name := c.String("output")
+	if name == "" {
+		name = layout.Name + ".pin"
+	}
+	return layout, policy, filepath.Join(configDir, name), nil
+}
+
+// pinsCheckCommand defines the CLI command for checking a pins file against a layout.
+var pinsCheckCommand = &cli.Command{
+	Name:  "check",
+	Usage: "Check a pins file against a layout, and show the free keys the optimiser would see",
+	Description: "Verifies that the pins file has rows of 12, 12, 12 and 6 keys, shows its pins " +
+		"with the characters of the layout, and warns about pinned empty keys and about free " +
+		"characters that don't occur in the corpus, which the optimiser can't improve the layout with.",
+	Flags:         append(commonFlags("corpus"), corpusBuildFlags()...),
+	ArgsUsage:     "<pins> <layout>",
+	Action:        pinsCheckAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// pinsCheckAction checks a pins file against a layout.
+func pinsCheckAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	if c.NArg() != 2 {
+		return fmt.Errorf("expected a pins file and a layout, got %d arguments", c.NArg())
+	}
+	path := filepath.Join(configDir, c.Args().Get(0))
+	pinned, err := kc.LoadPins(path)
+	if err != nil {
+		return err
+	}
+	layout, err := loadLayout(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	check := kc.CheckPins(pinned, layout, corpus)
+	if err := kc.WritePins(os.Stdout, pinned, layout, fmt.Sprintf("Pins of %s on %s", c.Args().Get(0), layout.Name)); err != nil {
+		return fmt.Errorf("could not show pins: %w", err)
+	}
+	fmt.Printf("\nFree keys: %d/42, of which %d with characters in corpus %s\n", check.Free, check.FreeInCorpus, corpus.Name)
+	for _, warning := range check.Warnings {
+		slog.Warn(warning)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// plotHistoryFlags defines flags specific to the plot-history command.
+var plotHistoryFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "format",
+		Aliases:  []string{"fmt"},
+		Usage:    "Chart format: \"ascii\" or \"svg\".",
+		Value:    "ascii",
+		Category: "Display",
+	},
+	&cli.StringFlag{
+		Name:     "metric",
+		Aliases:  []string{"m"},
+		Usage:    "Metric to plot from the recorded snapshots (e.g. SFB). Plots the BLS cost if empty.",
+		Category: "Display",
+	},
+	&cli.StringFlag{
+		Name:     "output",
+		Aliases:  []string{"o"},
+		Usage:    "File to write the chart to. Writes to stdout if empty.",
+		Category: "Display",
+	},
+	&cli.IntFlag{
+		Name:     "width",
+		Usage:    "Chart width in columns (ascii) or pixels (svg). Uses a default if 0.",
+		Category: "Display",
+	},
+	&cli.IntFlag{
+		Name:     "height",
+		Usage:    "Chart height in rows (ascii) or pixels (svg). Uses a default if 0.",
+		Category: "Display",
+	},
+}
+
+// plotHistoryCommand defines the CLI command for plotting an optimization history
+// recorded with `optimize --history-file`.
+var plotHistoryCommand = &cli.Command{
+	Name:      "plot-history",
+	Aliases:   []string{"ph"},
+	Usage:     "Render a convergence chart from an optimization history file",
+	Flags:     plotHistoryFlags,
+	ArgsUsage: "<history.jsonl>",
+	Action:    plotHistoryAction,
+}
+
+// plotHistoryAction loads a history file and renders it as an ASCII or SVG chart.
+func plotHistoryAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected exactly 1 history file, got %d", c.NArg())
+	}
+
+	opts, err := buildHistoryPlotOptions(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	entries, err := kc.LoadHistoryFile(c.Args().First())
+	if err != nil {
+		return fmt.Errorf("could not load history: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if path := c.String("output"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not create output file %s: %w", path, err)
+		}
+		defer kc.CloseFile(f)
+		w = f
+	}
+
+	if err := tui.RenderHistory(w, entries, opts); err != nil {
+		return fmt.Errorf("could not render history: %w", err)
+	}
+	return nil
+}
+
+// buildHistoryPlotOptions validates and collects the chart options from flags.
+func buildHistoryPlotOptions(c *cli.Command) (tui.HistoryPlotOptions, error) {
+	format := tui.HistoryFormat(c.String("format"))
+	if format != tui.HistoryASCII && format != tui.HistorySVG {
+		return tui.HistoryPlotOptions{}, fmt.Errorf("invalid format %q: must be \"ascii\" or \"svg\"", format)
+	}
+	if c.Int("width") < 0 || c.Int("height") < 0 {
+		return tui.HistoryPlotOptions{}, fmt.Errorf("width and height must not be negative")
+	}
+
+	return tui.HistoryPlotOptions{
+		Format: format,
+		Metric: c.String("metric"),
+		Width:  int(c.Int("width")),
+		Height: int(c.Int("height")),
+	}, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// publishAnalysisFlags are the flags that change the numbers of a publish report. They are
+// repeated in its reproduction commands; view does not take the weights and reference flags.
+var publishAnalysisFlags = []string{"corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition",
+	"baseline", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties",
+	"roll-quality", "fatigue", "strict-targets", "weights-file", "weights", "reference-glob", "reference-list"}
+
+// publishReportFlags returns the flags of the publish-report command.
+func publishReportFlags() []cli.Flag {
+	return append(append(commonFlags(publishAnalysisFlags...), corpusBuildFlags()...),
+		&cli.StringFlag{
+			Name:    "baselines",
+			Aliases: []string{"b"},
+			Usage:   "Comma-separated layouts to compare the layout with.",
+			Value:   strings.Join(kc.DefaultPublishBaselines, ","),
+		},
+		&cli.IntFlag{
+			Name:    "rows",
+			Aliases: []string{"r"},
+			Usage:   "Number of SFBs and LSBs to list.",
+			Value:   10,
+			Action: func(ctx context.Context, c *cli.Command, value int) error {
+				if value < 1 {
+					return fmt.Errorf("--rows must be at least 1 (got %d)", value)
+				}
+				return nil
+			},
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Output format: \"markdown\" or \"html\" (a self-contained page).",
+			Value:   string(tui.OutputMarkdown),
+		},
+	)
+}
+
+// publishReportCommand defines the CLI command for the report that is published with a new
+// layout.
+var publishReportCommand = &cli.Command{
+	Name:  "publish-report",
+	Usage: "Write the report to attach when announcing a layout",
+	Description: "Writes a Markdown or HTML document with the board, the metrics of the layout against " +
+		"popular baselines, its most frequent SFBs and LSBs, a description of the corpus, how the layout " +
+		"was optimized if its file says so, and the command lines that reproduce the numbers. Redirect " +
+		"the output to save it.",
+	Flags:         publishReportFlags(),
+	ArgsUsage:     "<layout>",
+	Action:        publishReportAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// publishReportAction writes the publish report of a layout to stdout.
+func publishReportAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, format, err := buildPublishInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	report, err := kc.BuildPublishReport(input)
+	if err != nil {
+		return fmt.Errorf("could not build publish report: %w", err)
+	}
+	return tui.WritePublishReport(os.Stdout, report, format)
+}
+
+// buildPublishInput loads the layout of the argument and the baselines, and the corpus,
+// targets, weights and reference layouts of the flags.
+func buildPublishInput(c *cli.Command) (kc.PublishInput, tui.OutputFormat, error) {
+	if c.NArg() != 1 {
+		return kc.PublishInput{}, "", fmt.Errorf("expected exactly 1 layout, got %d", c.NArg())
+	}
+	format := tui.OutputFormat(strings.ToLower(c.String("output")))
+	if format != tui.OutputMarkdown && format != tui.OutputHTML {
+		return kc.PublishInput{}, "", fmt.Errorf("invalid output format; must be one of: markdown, html")
+	}
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.PublishInput{}, "", err
+	}
+	layout, err := loadLayout(c.Args().First())
+	if err != nil {
+		return kc.PublishInput{}, "", err
+	}
+	var names []string
+	for _, name := range strings.Split(c.String("baselines"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, ensureNoKlf(name))
+		}
+	}
+	baselines := make([]*kc.SplitLayout, 0, len(names))
+	for _, name := range names {
+		baseline, err := loadLayout(name)
+		if err != nil {
+			return kc.PublishInput{}, "", fmt.Errorf("could not load baseline: %w", err)
+		}
+		baselines = append(baselines, baseline)
+	}
+
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.PublishInput{}, "", fmt.Errorf("could not load corpus: %w", err)
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.PublishInput{}, "", fmt.Errorf("could not load target loads: %w", err)
+	}
+	weights, err := loadWeightsFromFlags(c)
+	if err != nil {
+		return kc.PublishInput{}, "", fmt.Errorf("could not load weights: %w", err)
+	}
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.PublishInput{}, "", fmt.Errorf("could not load reference layouts: %w", err)
+	}
+
+	return kc.PublishInput{
+		LayoutsDir: layoutDir,
+		Reference:  reference,
+		Layout:     layout,
+		Baselines:  baselines,
+		Corpus:     corpus,
+		Targets:    targets,
+		Weights:    weights,
+		TopN:       int(c.Int("rows")),
+		Commands:   reproduceCommands(c, layout.Name, names),
+	}, format, nil
+}
+
+// reproduceCommands returns the view, analyse and rank command lines that reproduce the
+// numbers of the publish report, with the analysis and corpus build flags that differ from
+// their defaults.
+// The corpus is always given, so that the commands say what they are based on.
+func reproduceCommands(c *cli.Command, layout string, baselines []string) []string {
+	var viewArgs, args []string
+	names := slices.Clone(publishAnalysisFlags)
+	for _, f := range corpusBuildFlags() {
+		names = append(names, f.Names()[0])
+	}
+	for _, name := range names {
+		value := fmt.Sprint(c.Value(name))
+		def, ok := commonFlagsMap[name]
+		if !ok {
+			def = corpusBuildFlagsMap[name]
+		}
+		if name != "corpus" && value == fmt.Sprint(flagDefault(def)) {
+			continue
+		}
+		flag := "--" + name + " " + shellArg(value)
+		if _, ok := c.Value(name).(bool); ok {
+			flag = "--" + name + "=" + value
+		}
+		args = append(args, flag)
+		if !slices.Contains([]string{"weights-file", "weights", "reference-glob", "reference-list"}, name) {
+			viewArgs = append(viewArgs, flag)
+		}
+	}
+	line := func(cmd string, flags []string, layouts ...string) string {
+		for i, l := range layouts {
+			layouts[i] = shellArg(l)
+		}
+		return strings.Join(append(append([]string{"keycraft", cmd}, flags...), layouts...), " ")
+	}
+	return []string{
+		line("view", viewArgs, layout),
+		line("analyse", args, layout),
+		line("rank", args, append([]string{layout}, baselines...)...),
+	}
+}
+
+// flagDefault returns the default value of a flag.
+func flagDefault(f cli.Flag) any {
+	switch f := f.(type) {
+	case *cli.StringFlag:
+		return f.Value
+	case *cli.IntFlag:
+		return f.Value
+	case *cli.Float64Flag:
+		return f.Value
+	case *cli.BoolFlag:
+		return f.Value
+	}
+	return nil
+}
+
+// plainShellArg matches arguments that need no quoting in a POSIX shell.
+var plainShellArg = regexp.MustCompile(`^[A-Za-z0-9._,:=/+%@-]+$`)
+
+// shellArg quotes an argument of a command line for a POSIX shell, if needed.
+func shellArg(s string) string {
+	if plainShellArg.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// randomFlagsMap contains flags specific to the random command, keyed by their primary name.
+var randomFlagsMap = map[string]cli.Flag{
+	"count": &cli.IntFlag{
+		Name:     "count",
+		Aliases:  []string{"n"},
+		Usage:    "Number of random layouts to sample",
+		Value:    100,
+		Category: "Generation",
+	},
+	"constraints": &cli.StringFlag{
+		Name: "constraints",
+		Usage: "Generation config (.gen file) with the constraints of the random layouts, such as vowels " +
+			"on one hand or pinned punctuation. Searched in the config directory if not found. " +
+			"Optional with --board.",
+		Category: "Generation",
+	},
+	"top": &cli.IntFlag{
+		Name:     "top",
+		Usage:    "Number of the best random layouts to show in the rankings (0 = none)",
+		Value:    10,
+		Category: "Display",
+	},
+	"save": &cli.IntFlag{
+		Name:     "save",
+		Usage:    "Number of the best random layouts to save, for example as starting points for optimize",
+		Category: "Output",
+	},
+}
+
+// randomCmdFlags returns all flags for the random command.
+func randomCmdFlags() []cli.Flag {
+	common := commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition", "baseline",
+		"load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties",
+		"roll-quality", "fatigue", "strict-targets", "weights-file", "weights", "reference-glob", "reference-list")
+	random := append(flags(randomFlagsMap, "count", "constraints"), generationFlags("seed")...)
+	random = append(random, flags(randomFlagsMap, "top", "save")...)
+	return append(append(append(append(common, corpusBuildFlags()...), random...), flags(saveFlagsMap, "out", "force", "dry-run")...), boardFlag)
+}
+
+// randomCommand defines the "random" CLI command for sampling random layouts.
+var randomCommand = &cli.Command{
+	Name:          "random",
+	Usage:         "Sample random layouts that satisfy constraints, rank them, and compare layouts with them",
+	Flags:         randomCmdFlags(),
+	ArgsUsage:     "[<layout1> <layout2> ...]",
+	Action:        randomAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// randomAction samples random layouts, ranks them together with the given layouts, and
+// reports for each given layout how many of the random layouts it beats.
+func randomAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildRandomLayoutsInput(c)
+	if err != nil {
+		return err
+	}
+	printSeed(input.Seed)
+	out, err := layoutOutputFromFlags(c)
+	if err != nil {
+		return err
+	}
+	save := int(c.Int("save"))
+	if save < 0 || save > input.Count {
+		return fmt.Errorf("save must be between 0 and the count of %d, got %d", input.Count, save)
+	}
+
+	layouts, err := kc.RandomLayouts(input)
+	if err != nil {
+		return fmt.Errorf("could not sample random layouts: %w", err)
+	}
+	fmt.Printf("Sampled %d random layouts\n", len(layouts))
+
+	// Rank the random layouts after the layouts of the args
+	rankingInput, err := buildRankingInput(c, nil, true)
+	if err != nil {
+		return fmt.Errorf("could not build ranking input: %w", err)
+	}
+	for _, arg := range c.Args().Slice() {
+		rankingInput.LayoutFiles = append(rankingInput.LayoutFiles, filepath.Join(layoutDir, ensureKlf(resolveLayoutName(arg))))
+	}
+	rankingInput.Layouts = layouts
+	rankings, err := kc.ComputeRankings(rankingInput)
+	if err != nil {
+		return fmt.Errorf("could not compute rankings: %w", err)
+	}
+
+	numArgs := c.Args().Len()
+	given := rankings.Scores[:numArgs]
+	random := slices.Clone(rankings.Scores[numArgs:])
+	slices.SortStableFunc(random, func(a, b kc.LayoutScore) int { return cmp.Compare(b.Score, a.Score) })
+	randomScores := make([]float64, len(random))
+	for i, ls := range random {
+		randomScores[i] = ls.Score
+	}
+
+	if err := saveRandomLayouts(random[:save], out); err != nil {
+		return err
+	}
+
+	// Show the given layouts and the best random layouts
+	top := min(max(int(c.Int("top")), 0), len(random))
+	shown := &kc.RankingResult{
+		Scores:  append(slices.Clone(given), random[:top]...),
+		Medians: rankings.Medians,
+		IQRs:    rankings.IQRs,
+	}
+	if len(shown.Scores) > 0 {
+		displayOpts := tui.RankingDisplayOptions{
+			OutputFormat:  tui.OutputTable,
+			MetricsOption: tui.MetricsWeighted,
+			Weights:       rankingInput.Weights,
+			DeltasOption:  tui.DeltasNone,
+			CorpusName:    rankingInput.Corpus.Name,
+		}
+		if err := tui.RenderRankingTable(shown, displayOpts); err != nil {
+			return fmt.Errorf("could not render rankings: %w", err)
+		}
+	}
+
+	tui.RenderRandomBaseline(kc.NewRandomBaseline(randomScores), given)
+	return nil
+}
+
+// buildRandomLayoutsInput loads the constraints of the --constraints file or the --board,
+// and captures the --count and --seed flags.
+func buildRandomLayoutsInput(c *cli.Command) (kc.RandomLayoutsInput, error) {
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.RandomLayoutsInput{}, err
+	}
+	board, err := loadBoardFromFlags(c)
+	if err != nil {
+		return kc.RandomLayoutsInput{}, err
+	}
+
+	count := int(c.Int("count"))
+	if count < 1 {
+		return kc.RandomLayoutsInput{}, fmt.Errorf("count must be at least 1, got %d", count)
+	}
+	seed, err := kc.ParseSeed(c.String("seed"))
+	if err != nil {
+		return kc.RandomLayoutsInput{}, err
+	}
+
+	// Use the constraints of the .gen file, on the board if given, or the config of the board
+	configPath := c.String("constraints")
+	var config *kc.GenerationConfig
+	switch {
+	case configPath != "":
+		if !strings.HasSuffix(strings.ToLower(configPath), ".gen") {
+			return kc.RandomLayoutsInput{}, fmt.Errorf("constraints file must have .gen extension, got: %s", configPath)
+		}
+		resolvedPath, err := resolveConfigPath(configPath)
+		if err != nil {
+			return kc.RandomLayoutsInput{}, err
+		}
+		config, err = kc.ParseConfigFile(resolvedPath)
+		if err != nil {
+			return kc.RandomLayoutsInput{}, fmt.Errorf("could not parse constraints file: %w", err)
+		}
+		if board != nil {
+			if err := board.ApplyToConfig(config); err != nil {
+				return kc.RandomLayoutsInput{}, fmt.Errorf("could not sample layouts for the board: %w", err)
+			}
+		}
+	case board != nil:
+		config = board.GenerationConfig()
+	default:
+		return kc.RandomLayoutsInput{}, fmt.Errorf("expected a --constraints file or a --board")
+	}
+
+	return kc.RandomLayoutsInput{Config: config, Count: count, Seed: seed}, nil
+}
+
+// saveRandomLayouts saves the best random layouts, refusing before saving any of them if a
+// file exists and --force is not set.
+func saveRandomLayouts(best []kc.LayoutScore, out layoutOutput) error {
+	if len(best) == 0 {
+		return nil
+	}
+	paths := make([]string, len(best))
+	for i, ls := range best {
+		path, err := out.path(ls.Name)
+		if err != nil {
+			return err
+		}
+		paths[i] = path
+	}
+	for i, ls := range best {
+		if err := out.save(ls.Analyser.Layout, paths[i]); err != nil {
+			return fmt.Errorf("could not save layout %s: %w", ls.Name, err)
+		}
+	}
+	if out.dryRun {
+		fmt.Printf("Dry run: would save %d layouts to: %s\n", len(best), out.dir)
+	} else {
+		fmt.Printf("Saved %d layouts to: %s\n", len(best), out.dir)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"maps"
 	"os"
 	"path/filepath"
@@ -33,13 +34,37 @@ var rankFlags = []cli.Flag{
 		Value:    "none",
 		Category: "Display",
 	},
+	&cli.StringFlag{
+		Name:    "delta-mode",
+		Aliases: []string{"dm"},
+		Usage: "Unit of the deltas: \"absolute\" (difference, in percentage points for most metrics), " +
+			"\"percent\" (change relative to the compared value), or \"normalised\" (difference in " +
+			"IQRs of the metric across the reference layouts, comparable between metrics).",
+		Value:    "absolute",
+		Category: "Display",
+	},
 	&cli.StringFlag{
 		Name:     "output",
 		Aliases:  []string{"o"},
-		Usage:    "Output format: \"table\", \"html\", or \"csv\".",
+		Usage:    "Output format: \"table\", \"html\" (a sortable, self-contained page), or \"csv\".",
 		Value:    "table",
 		Category: "Display",
 	},
+	&cli.StringFlag{
+		Name:    "metrics-file",
+		Aliases: []string{"mf"},
+		Usage: "CSV or TSV file in the config directory with extra metric columns per layout " +
+			"(header row, layout name in the first column). The columns are displayed and can be weighted " +
+			"with --weights like computed metrics.",
+		Category: "Display",
+	},
+	&cli.StringFlag{
+		Name: "corpora",
+		Usage: "Comma-separated corpus files to rank against separately, each with an optional weight " +
+			"(e.g., \"en.txt:2,code.txt,nl.txt\"). Shows the score per corpus, the weighted mean of the " +
+			"scores, and the worst score, instead of the metrics. Overrides --corpus.",
+		Category: "", // General/uncategorized, with --corpus
+	},
 	&cli.StringFlag{
 		Name:     "link-base",
 		Usage:    "When --output html, wrap each Name cell in <a href=\"<base><name>.html\">…</a>. Example: --link-base layouts/",
@@ -47,10 +72,38 @@ var rankFlags = []cli.Flag{
 	},
 }
 
+// groupByFlag groups the ranked layouts by the tags or author settings of their layout
+// files, or by their board.
+var groupByFlag = &cli.StringFlag{
+	Name: "group-by",
+	Usage: "Group the layouts by \"tag\" (the first of the tags setting of the layout file, which " +
+		"names its family), \"author\" (the author setting), or \"board\", with the best and median " +
+		"of each group. Supports table and csv output, without deltas.",
+	Value:    "none",
+	Category: "Display",
+}
+
+// transposeFlag shows the ranked layouts as columns and their metrics as rows.
+var transposeFlag = &cli.BoolFlag{
+	Name: "transpose",
+	Usage: "Show the metrics as rows and the layouts as columns, which fits a few layouts with " +
+		"many metrics. Supports table and csv output, without deltas or --group-by.",
+	Category: "Display",
+}
+
+// cacheFlag sets the file that keeps the metrics of ranked layouts between runs.
+var cacheFlag = &cli.StringFlag{
+	Name: "cache",
+	Usage: "File in the corpus directory that keeps the metrics of analysed layouts between runs, " +
+		"so only new or changed layouts are analysed again. The metrics are kept per layout, corpus " +
+		"and targets. \"none\" analyses every layout.",
+	Value: ".metrics-cache.json",
+}
+
 // rankFlagsSlice returns all flags for the rank command.
 func rankFlagsSlice() []cli.Flag {
-	commonFlags := commonFlags("corpus", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties", "weights-file", "weights")
-	return append(commonFlags, rankFlags...)
+	commonFlags := commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition", "baseline", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties", "roll-quality", "fatigue", "strict-targets", "weights-file", "weights", "reference-glob", "reference-list", "inline")
+	return append(append(append(append(commonFlags, corpusBuildFlags()...), scopeFlag), append(rankFlags, groupByFlag, transposeFlag, cacheFlag, interactiveFlag)...), checkFlags...)
 }
 
 // rankCommand defines the "rank" CLI command for comparing and ranking layouts.
@@ -63,6 +116,8 @@ var rankCommand = &cli.Command{
 	ArgsUsage:     "<layout1> <layout2> ...",
 	Action:        rankAction,
 	ShellComplete: layoutShellComplete,
+	// Inline layouts can have commas as keys
+	DisableSliceFlagSeparator: true,
 }
 
 // rankAction handles the rank command, loading data and displaying layout rankings.
@@ -72,29 +127,102 @@ func rankAction(ctx context.Context, c *cli.Command) error {
 		return nil
 	}
 
-	// 1. Build display options (includes loading weights)
+	// 1. Load external metrics, which must be registered before weights are parsed
+	external, err := loadExternalMetricsFromFlags(c)
+	if err != nil {
+		return fmt.Errorf("could not load external metrics: %w", err)
+	}
+
+	// 2. Build display options (includes loading weights)
 	displayOpts, err := buildDisplayOptions(c)
 	if err != nil {
 		return fmt.Errorf("could not parse display options: %w", err)
 	}
+	thresholds, err := loadThresholdsFromFlags(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input for rankings: %w", err)
+	}
 
-	// 2. Parse all CLI flags and build input (using weights from displayOpts)
+	// 3. Parse all CLI flags and build input (using weights from displayOpts)
 	input, err := buildRankingInput(c, displayOpts.Weights, false)
 	if err != nil {
 		return fmt.Errorf("could not parse user input for rankings: %w", err)
 	}
+	if external != nil {
+		input.External = external
+		displayOpts.ExtraMetrics = external.Columns
+	}
+	input.Cache, err = openMetricsCacheFromFlags(c)
+	if err != nil {
+		return err
+	}
+	defer saveMetricsCache(input.Cache)
+
+	// Rank against several corpora separately if requested
+	if spec := c.String("corpora"); spec != "" {
+		if c.Bool("interactive") {
+			return fmt.Errorf("--interactive cannot be used with --corpora")
+		}
+		if c.Bool("transpose") {
+			return fmt.Errorf("--transpose cannot be used with --corpora")
+		}
+		return rankCorpora(c, input, displayOpts, strings.Split(spec, ","), thresholds)
+	}
 
 	// Set corpus name for display (used in table title when deltas are not shown)
 	displayOpts.CorpusName = input.Corpus.Name
+	displayOpts.MetricVersion = int(c.Int("metric-version"))
 
-	// 3. Compute rankings (business logic)
+	// 4. Compute rankings (business logic)
 	rankings, err := kc.ComputeRankings(input)
 	if err != nil {
 		return fmt.Errorf("could not compute rankings: %w", err)
 	}
 
-	// 4. Render results (presentation layer)
-	return tui.RenderRankingTable(rankings, displayOpts)
+	// 5. Render results (presentation layer), or start the prompt to change them
+	if c.Bool("interactive") {
+		if err := newRankSession(rankings, displayOpts).run(os.Stdin, os.Stdout); err != nil {
+			return fmt.Errorf("could not read commands: %w", err)
+		}
+	} else if !c.Bool("quiet") {
+		if err := tui.RenderRankingTable(rankings, displayOpts); err != nil {
+			return err
+		}
+	}
+
+	// 6. Check the --fail-if thresholds of the ranked layouts
+	analysers := make([]*kc.Analyser, len(rankings.Scores))
+	for i, ls := range rankings.Scores {
+		analysers[i] = ls.Analyser
+	}
+	return checkThresholds(thresholds, analysers)
+}
+
+// rankCorpora ranks layouts against each corpus of --corpora separately, renders the score
+// per corpus with the combined and worst scores, and checks the --fail-if thresholds against
+// every corpus.
+func rankCorpora(c *cli.Command, input kc.RankingInput, displayOpts tui.RankingDisplayOptions,
+	specs []string, thresholds []kc.Threshold) error {
+	corpora, err := loadCorpora(c, specs)
+	if err != nil {
+		return fmt.Errorf("could not load corpora: %w", err)
+	}
+	rows, err := kc.ComputeCorpusRankings(input, corpora)
+	if err != nil {
+		return fmt.Errorf("could not compute rankings: %w", err)
+	}
+
+	if !c.Bool("quiet") {
+		if err := tui.RenderCorpusRanking(rows, corpora, displayOpts.OutputFormat); err != nil {
+			return err
+		}
+	}
+
+	var analysers []*kc.Analyser
+	for _, row := range rows {
+		analysers = append(analysers, row.Analysers...)
+	}
+	return checkThresholds(thresholds, analysers)
 }
 
 // buildRankingInput gathers all input parameters.
@@ -102,9 +230,18 @@ func rankAction(ctx context.Context, c *cli.Command) error {
 //   - weights: if provided, uses these weights; if nil, should be loaded by caller
 //   - skipLayoutsFromArgs: if true, skips loading layouts from args (for generate command)
 func buildRankingInput(c *cli.Command, weights *kc.Weights, skipLayoutsFromArgs bool) (kc.RankingInput, error) {
-	corpus, err := loadCorpusFromFlags(c)
-	if err != nil {
-		return kc.RankingInput{}, fmt.Errorf("could not load corpus: %w", err)
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.RankingInput{}, err
+	}
+
+	// The corpora of --corpora are loaded by rankCorpora
+	var corpus *kc.Corpus
+	if c.String("corpora") == "" {
+		var err error
+		corpus, err = loadCorpusFromFlags(c)
+		if err != nil {
+			return kc.RankingInput{}, fmt.Errorf("could not load corpus: %w", err)
+		}
 	}
 
 	targets, err := loadTargetLoadsFromFlags(c)
@@ -120,8 +257,19 @@ func buildRankingInput(c *cli.Command, weights *kc.Weights, skipLayoutsFromArgs
 		}
 	}
 
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.RankingInput{}, fmt.Errorf("could not load reference layouts: %w", err)
+	}
+
 	var layouts []string
+	var inline []*kc.SplitLayout
 	if !skipLayoutsFromArgs {
+		inline, err = loadInlineLayouts(c)
+		if err != nil {
+			return kc.RankingInput{}, err
+		}
+
 		// Check if deltas references a specific layout (not "none", "rows", or "median")
 		deltasValue := c.String("deltas")
 		deltasValueLower := strings.ToLower(deltasValue)
@@ -130,7 +278,7 @@ func buildRankingInput(c *cli.Command, weights *kc.Weights, skipLayoutsFromArgs
 			baseLayout = deltasValue
 		}
 
-		layouts, err = getLayoutsFromArgs(c, baseLayout)
+		layouts, err = getLayoutsFromArgs(c, baseLayout, inline)
 		if err != nil {
 			return kc.RankingInput{}, fmt.Errorf("could not get layouts from args: %w", err)
 		}
@@ -139,12 +287,50 @@ func buildRankingInput(c *cli.Command, weights *kc.Weights, skipLayoutsFromArgs
 	return kc.RankingInput{
 		LayoutsDir:  layoutDir,
 		LayoutFiles: layouts,
+		Layouts:     inline,
 		Corpus:      corpus,
 		Targets:     targets,
 		Weights:     weights,
+		Reference:   reference,
 	}, nil
 }
 
+// openMetricsCacheFromFlags opens the metrics cache of the --cache flag, or returns nil if
+// it is "none".
+func openMetricsCacheFromFlags(c *cli.Command) (*kc.MetricsCache, error) {
+	name := c.String("cache")
+	if name == "" || strings.EqualFold(name, "none") {
+		return nil, nil
+	}
+	return kc.OpenMetricsCache(filepath.Join(corpusDir, name))
+}
+
+// saveMetricsCache saves the metrics cache, with a warning if that fails, as the ranking
+// itself succeeded.
+func saveMetricsCache(cache *kc.MetricsCache) {
+	hits, misses := cache.Stats()
+	slog.Debug("metrics cache", "hits", hits, "analysed", misses)
+	if err := cache.Save(); err != nil {
+		slog.Warn(fmt.Sprintf("could not save metrics cache: %v", err))
+	}
+}
+
+// loadExternalMetricsFromFlags loads and registers the metrics of the --metrics-file
+// flag, or returns nil if it is not set.
+func loadExternalMetricsFromFlags(c *cli.Command) (*kc.ExternalMetrics, error) {
+	name := c.String("metrics-file")
+	if name == "" {
+		return nil, nil
+	}
+
+	external, err := kc.NewExternalMetricsFromFile(filepath.Join(configDir, name))
+	if err != nil {
+		return nil, err
+	}
+	external.Register()
+	return external, nil
+}
+
 // buildDisplayOptions gathers display configuration.
 func buildDisplayOptions(c *cli.Command) (tui.RankingDisplayOptions, error) {
 	// Load weights for display and delta coloring
@@ -168,47 +354,29 @@ func buildDisplayOptions(c *cli.Command) (tui.RankingDisplayOptions, error) {
 		}
 	}
 
-	metricsValue := strings.ToLower(c.String("metrics"))
-
-	var metricsOpt tui.MetricsOption
-	var customMetrics []string
-
-	// Check if it's "weighted" (special case - computed dynamically)
-	if metricsValue == "weighted" {
-		metricsOpt = tui.MetricsWeighted
-	} else if _, ok := kc.MetricsMap[metricsValue]; ok {
-		// Check if it's a predefined metrics set
-		metricsOpt = tui.MetricsOption(metricsValue)
-	} else {
-		// Treat as custom comma-separated list
-		metricsOpt = tui.MetricsCustom
-		customMetrics = strings.Split(metricsValue, ",")
-		for i := range customMetrics {
-			customMetrics[i] = strings.TrimSpace(customMetrics[i])
-			customMetrics[i] = strings.ToUpper(customMetrics[i])
-		}
+	metricsOpt, customMetrics, err := parseMetricsOption(c.String("metrics"))
+	if err != nil {
+		return tui.RankingDisplayOptions{}, err
+	}
+	deltasOpt, baseLayoutName := parseDeltasOption(c.String("deltas"))
+	deltaMode, err := parseDeltaMode(c.String("delta-mode"))
+	if err != nil {
+		return tui.RankingDisplayOptions{}, err
+	}
 
-		// Validate that all custom metrics exist
-		if err := validateMetrics(customMetrics); err != nil {
-			return tui.RankingDisplayOptions{}, fmt.Errorf("could not validate metrics: %w", err)
+	groupBy := kc.GroupNone
+	if value := c.String("group-by"); value != "" {
+		groupBy, err = kc.ParseGroupBy(value)
+		if err != nil {
+			return tui.RankingDisplayOptions{}, err
 		}
 	}
-
-	deltasValue := c.String("deltas")
-	deltasValueLower := strings.ToLower(deltasValue)
-	var deltasOpt tui.DeltasOption
-	var baseLayoutName string
-
-	switch deltasValueLower {
-	case "none":
-		deltasOpt = tui.DeltasNone
-	case "rows":
-		deltasOpt = tui.DeltasRows
-	case "median":
-		deltasOpt = tui.DeltasMedian
-	default:
-		deltasOpt = tui.DeltasCustom
-		baseLayoutName = ensureNoKlf(deltasValue)
+	if groupBy != kc.GroupNone && (deltasOpt != tui.DeltasNone || outputFmt == tui.OutputHTML) {
+		return tui.RankingDisplayOptions{}, fmt.Errorf("--group-by requires --deltas none and table or csv output")
+	}
+	transpose := c.Bool("transpose")
+	if transpose && (deltasOpt != tui.DeltasNone || groupBy != kc.GroupNone || outputFmt == tui.OutputHTML) {
+		return tui.RankingDisplayOptions{}, fmt.Errorf("--transpose requires --deltas none, no --group-by, and table or csv output")
 	}
 
 	return tui.RankingDisplayOptions{
@@ -219,10 +387,70 @@ func buildDisplayOptions(c *cli.Command) (tui.RankingDisplayOptions, error) {
 		Weights:        weights,
 		DeltasOption:   deltasOpt,
 		BaseLayoutName: baseLayoutName,
+		DeltaMode:      deltaMode,
 		LinkBase:       c.String("link-base"),
+		GroupBy:        groupBy,
+		Transpose:      transpose,
 	}, nil
 }
 
+// parseMetricsOption parses the value of --metrics: "weighted", a predefined metrics set,
+// or a comma-separated list of metrics, which is returned as well.
+func parseMetricsOption(value string) (tui.MetricsOption, []string, error) {
+	value = strings.ToLower(value)
+
+	// Check if it's "weighted" (special case - computed dynamically)
+	if value == "weighted" {
+		return tui.MetricsWeighted, nil, nil
+	}
+	// Check if it's a predefined metrics set
+	if _, ok := kc.MetricsMap[value]; ok {
+		return tui.MetricsOption(value), nil, nil
+	}
+
+	// Treat as custom comma-separated list
+	customMetrics := strings.Split(value, ",")
+	for i := range customMetrics {
+		customMetrics[i] = strings.TrimSpace(customMetrics[i])
+		customMetrics[i] = strings.ToUpper(customMetrics[i])
+	}
+
+	// Validate that all custom metrics exist
+	if err := validateMetrics(customMetrics); err != nil {
+		return "", nil, fmt.Errorf("could not validate metrics: %w", err)
+	}
+	return tui.MetricsCustom, customMetrics, nil
+}
+
+// parseDeltasOption parses the value of --deltas: "none", "rows", "median", or the name of
+// the layout to compare against, which is returned as well.
+func parseDeltasOption(value string) (tui.DeltasOption, string) {
+	switch strings.ToLower(value) {
+	case "none":
+		return tui.DeltasNone, ""
+	case "rows":
+		return tui.DeltasRows, ""
+	case "median":
+		return tui.DeltasMedian, ""
+	default:
+		return tui.DeltasCustom, ensureNoKlf(value)
+	}
+}
+
+// parseDeltaMode parses the value of --delta-mode.
+func parseDeltaMode(value string) (tui.DeltaMode, error) {
+	switch strings.ToLower(value) {
+	case "absolute", "abs":
+		return tui.DeltaAbsolute, nil
+	case "percent", "pct":
+		return tui.DeltaPercent, nil
+	case "normalised", "normalized", "norm":
+		return tui.DeltaNormalised, nil
+	default:
+		return "", fmt.Errorf("invalid delta mode %q: must be one of: absolute, percent, normalised", value)
+	}
+}
+
 // validateMetrics checks that all provided metrics exist in the "all" metrics set.
 func validateMetrics(metrics []string) error {
 	allMetrics := kc.MetricsMap["all"]
@@ -245,10 +473,15 @@ func validateMetrics(metrics []string) error {
 	return nil
 }
 
-// getLayoutsFromArgs returns layouts from CLI args, or all .klf files if no args provided.
-func getLayoutsFromArgs(c *cli.Command, baseLayout string) ([]string, error) {
+// getLayoutsFromArgs returns layouts from CLI args, or all .klf files if no args and no
+// inline layouts are provided. The base layout of the deltas is added unless it is one of
+// the inline layouts.
+func getLayoutsFromArgs(c *cli.Command, baseLayout string, inline []*kc.SplitLayout) ([]string, error) {
 	var layouts []string
-	if c.Args().Len() == 0 {
+	if slices.ContainsFunc(inline, func(sl *kc.SplitLayout) bool { return sl.Name == baseLayout }) {
+		baseLayout = ""
+	}
+	if c.Args().Len() == 0 && len(inline) == 0 {
 		var err error
 		layouts, err = allLayoutFiles()
 		if err != nil {
@@ -268,7 +501,7 @@ func getLayoutsFromArgs(c *cli.Command, baseLayout string) ([]string, error) {
 			// }
 
 			// Otherwise assume it's a name in layoutDir
-			layouts[i] = filepath.Join(layoutDir, ensureKlf(arg))
+			layouts[i] = filepath.Join(layoutDir, ensureKlf(resolveLayoutName(arg)))
 		}
 	}
 
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// interactiveFlag starts a prompt after ranking, to change the ranking without analysing
+// the layouts again.
+var interactiveFlag = &cli.BoolFlag{
+	Name: "interactive",
+	Usage: "After ranking, start a prompt to re-sort, change weights, choose metrics, filter " +
+		"layouts and show deltas without analysing the layouts again. Type \"help\" for the commands.",
+	Category: "Display",
+}
+
+// rankPromptHelp lists the commands of the rank prompt.
+const rankPromptHelp = `Commands:
+  show                       Show the ranking again
+  sort <metric>|score        Sort by a metric (best first by the sign of its weight), or by score
+  weights <metric=weight,..> Change weights and score the layouts again; "weights reset" undoes all changes
+  metrics <set>|<m1,m2,..>   Show a metrics set ("weighted", "basic", ...) or a list of metrics
+  toggle <metric>            Show or hide one metric
+  deltas <mode>|<layout>     Show deltas: "none", "rows", "median", or compared to a layout
+  mode <unit>                Unit of the deltas: "absolute", "percent" or "normalised"
+  filter [<glob>,..]         Only show layouts whose name matches a glob; no glob shows all layouts
+  help                       Show this help
+  quit                       Leave the prompt`
+
+// rankSession is the state of the rank prompt: the rankings of the analysed layouts and
+// the current display options.
+type rankSession struct {
+	rankings *kc.RankingResult
+	weights  *kc.Weights // The weights the ranking started with, for "weights reset"
+	opts     tui.RankingDisplayOptions
+	filter   []string // Globs of the layout names to show (nil = all)
+}
+
+// newRankSession starts a session on the rankings, displayed with opts.
+func newRankSession(rankings *kc.RankingResult, opts tui.RankingDisplayOptions) *rankSession {
+	return &rankSession{rankings: rankings, weights: opts.Weights, opts: opts}
+}
+
+// run reads commands from in until "quit" or the end of the input, and renders the ranking
+// after every command that changes it. Invalid commands are reported to out, and the
+// prompt continues.
+func (s *rankSession) run(in io.Reader, out io.Writer) error {
+	if err := s.render(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "rank> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "quit" || line == "exit" || line == "q" {
+			return nil
+		}
+		changed, err := s.execute(line)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+		if !changed {
+			fmt.Fprintln(out, rankPromptHelp)
+			continue
+		}
+		if err := s.render(); err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+// execute runs one command of the prompt. It returns false if the ranking need not be
+// shown again, which is only the case for "help".
+func (s *rankSession) execute(line string) (bool, error) {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(cmd) {
+	case "help", "?":
+		return false, nil
+
+	case "show":
+		return true, nil
+
+	case "sort":
+		if arg == "" || strings.EqualFold(arg, "score") {
+			s.opts.SortBy = ""
+			return true, nil
+		}
+		metric := strings.ToUpper(arg)
+		if err := validateMetrics([]string{metric}); err != nil {
+			return false, err
+		}
+		s.opts.SortBy = metric
+		return true, nil
+
+	case "weights", "weight", "w":
+		if arg == "" {
+			return false, fmt.Errorf("expected metric=weight pairs, or \"reset\"")
+		}
+		weights := s.weights
+		if !strings.EqualFold(arg, "reset") {
+			weights = s.opts.Weights.Clone()
+			if err := weights.AddWeightsFromString(arg); err != nil {
+				return false, err
+			}
+		}
+		s.opts.Weights = weights
+		s.rankings.Rescore(weights)
+		return true, nil
+
+	case "metrics", "m":
+		metricsOpt, customMetrics, err := parseMetricsOption(arg)
+		if err != nil {
+			return false, err
+		}
+		s.opts.MetricsOption, s.opts.CustomMetrics = metricsOpt, customMetrics
+		return true, nil
+
+	case "toggle", "t":
+		metric := strings.ToUpper(arg)
+		if err := validateMetrics([]string{metric}); err != nil {
+			return false, err
+		}
+		metrics := s.opts.GetMetrics()
+		if i := slices.Index(metrics, metric); i >= 0 {
+			metrics = slices.Delete(metrics, i, i+1)
+		} else {
+			metrics = append(metrics, metric)
+		}
+		s.opts.MetricsOption, s.opts.CustomMetrics = tui.MetricsCustom, metrics
+		return true, nil
+
+	case "deltas", "d":
+		deltasOpt, baseLayoutName := parseDeltasOption(arg)
+		if deltasOpt == tui.DeltasCustom && !slices.ContainsFunc(s.rankings.Scores,
+			func(ls kc.LayoutScore) bool { return ls.Name == baseLayoutName }) {
+			return false, fmt.Errorf("layout %q is not ranked; rank it to compare against it", baseLayoutName)
+		}
+		if s.opts.GroupBy != kc.GroupNone && deltasOpt != tui.DeltasNone {
+			return false, fmt.Errorf("deltas cannot be shown with --group-by")
+		}
+		if s.opts.Transpose && deltasOpt != tui.DeltasNone {
+			return false, fmt.Errorf("deltas cannot be shown with --transpose")
+		}
+		s.opts.DeltasOption, s.opts.BaseLayoutName = deltasOpt, baseLayoutName
+		return true, nil
+
+	case "mode":
+		deltaMode, err := parseDeltaMode(arg)
+		if err != nil {
+			return false, err
+		}
+		s.opts.DeltaMode = deltaMode
+		return true, nil
+
+	case "filter", "f":
+		s.filter = nil
+		for glob := range strings.SplitSeq(arg, ",") {
+			if glob = strings.TrimSpace(glob); glob == "" {
+				continue
+			}
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return false, fmt.Errorf("invalid glob %q: %w", glob, err)
+			}
+			s.filter = append(s.filter, glob)
+		}
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("unknown command %q; type \"help\" for the commands", cmd)
+	}
+}
+
+// visible returns the rankings of the layouts that match the filter, and the base layout
+// of the deltas, with the medians and IQRs of all layouts.
+func (s *rankSession) visible() *kc.RankingResult {
+	if s.filter == nil {
+		return s.rankings
+	}
+	result := &kc.RankingResult{Medians: s.rankings.Medians, IQRs: s.rankings.IQRs}
+	for _, ls := range s.rankings.Scores {
+		if s.matches(ls.Name) || (s.opts.DeltasOption == tui.DeltasCustom && ls.Name == s.opts.BaseLayoutName) {
+			result.Scores = append(result.Scores, ls)
+		}
+	}
+	return result
+}
+
+// matches reports whether a layout name matches one of the globs of the filter.
+func (s *rankSession) matches(name string) bool {
+	return slices.ContainsFunc(s.filter, func(glob string) bool {
+		ok, _ := filepath.Match(glob, name)
+		return ok
+	})
+}
+
+// render shows the ranking with the current display options.
+func (s *rankSession) render() error {
+	return tui.RenderRankingTable(s.visible(), s.opts)
+}
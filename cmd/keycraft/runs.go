@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// runsCommand groups the commands that work with optimization runs recorded with
+// `optimize --run-dir`.
+var runsCommand = &cli.Command{
+	Name:     "runs",
+	Usage:    "Work with optimization runs recorded with optimize --run-dir",
+	Commands: []*cli.Command{runsCompareCommand},
+}
+
+// runsCompareCommand defines the CLI command for comparing two optimization runs.
+var runsCompareCommand = &cli.Command{
+	Name:  "compare",
+	Usage: "Compare the parameters, convergence and best layouts of two optimization runs",
+	Description: "Loads two run directories written by optimize --run-dir, and shows the " +
+		"parameters that differ between the runs, their convergence curves, and their best " +
+		"layouts with the metrics that differ.",
+	ArgsUsage: "<run-dir> <run-dir>",
+	Action:    runsCompareAction,
+}
+
+// runsCompareAction loads two runs and renders their comparison.
+func runsCompareAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	if c.NArg() != 2 {
+		return fmt.Errorf("expected exactly 2 run directories, got %d", c.NArg())
+	}
+
+	var runs [2]*kc.Run
+	for i, dir := range c.Args().Slice() {
+		run, err := kc.LoadRun(dir)
+		if err != nil {
+			return fmt.Errorf("could not load run: %w", err)
+		}
+		runs[i] = run
+	}
+
+	tui.RenderRunComparison(kc.CompareRuns(runs[0], runs[1]))
+	return nil
+}
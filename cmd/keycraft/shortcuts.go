@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// shortcutsFlags defines flags specific to the shortcuts command.
+var shortcutsFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "shortcuts-file",
+		Aliases: []string{"sf"},
+		Usage: "File in the config directory with editor/IDE shortcuts, one \"<count> <keys>\" per line " +
+			"(e.g., \"100 ctrl+c\" or \"30 dd\").",
+		Value: "shortcuts.txt",
+	},
+	&cli.IntFlag{
+		Name:     "rows",
+		Aliases:  []string{"r"},
+		Usage:    "Maximum number of shortcuts to display (0 = all).",
+		Value:    10,
+		Category: "Display",
+	},
+}
+
+// shortcutsFlagsSlice returns all flags for the shortcuts command.
+func shortcutsFlagsSlice() []cli.Flag {
+	return append(commonFlags("geometry-file"), shortcutsFlags...)
+}
+
+// shortcutsCommand defines the CLI command for analysing editor shortcut sequences.
+var shortcutsCommand = &cli.Command{
+	Name:    "shortcuts",
+	Aliases: []string{"sc"},
+	Usage:   "Analyse how layouts type frequent editor and IDE shortcuts",
+	Description: "Types each shortcut of a shortcuts file on the layouts, with modifiers such as Ctrl " +
+		"held down by a configurable finger. Reports the effort of each shortcut, how many can be " +
+		"typed with one hand (e.g. with the other hand on the mouse) and how many need a finger twice, " +
+		"which shows the impact of moving keys like Z, X, C and V.",
+	Flags:         shortcutsFlagsSlice(),
+	ArgsUsage:     "<layout1> <layout2> ...",
+	Action:        shortcutsAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// shortcutsAction loads the shortcuts and layouts and renders the analysis.
+func shortcutsAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	set, layouts, err := buildShortcutsInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	analyses := make([]*kc.ShortcutAnalysis, len(layouts))
+	for i, layout := range layouts {
+		analyses[i] = kc.AnalyseShortcuts(layout, set)
+	}
+
+	return tui.RenderShortcuts(set, analyses, int(c.Int("rows")))
+}
+
+// buildShortcutsInput loads the shortcut set and the layouts to analyse.
+func buildShortcutsInput(c *cli.Command) (*kc.ShortcutSet, []*kc.SplitLayout, error) {
+	if c.NArg() < 1 {
+		return nil, nil, fmt.Errorf("need at least 1 layout")
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return nil, nil, err
+	}
+
+	name := c.String("shortcuts-file")
+	set, err := kc.NewShortcutSetFromFile(strings.TrimSuffix(name, filepath.Ext(name)), filepath.Join(configDir, name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load shortcuts: %w", err)
+	}
+
+	layouts := make([]*kc.SplitLayout, 0, c.NArg())
+	for _, arg := range c.Args().Slice() {
+		layout, err := loadLayout(arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not load layout: %w", err)
+		}
+		layouts = append(layouts, layout)
+	}
+	return set, layouts, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// similarityFlags defines flags specific to the similarity command.
+var similarityFlags = []cli.Flag{
+	&cli.Float64Flag{
+		Name:    "threshold",
+		Aliases: []string{"t"},
+		Usage: "Minimum average similarity of the layouts in a family, from 0 to 1. The similarity of two " +
+			"layouts is the share of typed characters that they put on the same key.",
+		Value:    0.6,
+		Category: "Display",
+	},
+	&cli.BoolFlag{
+		Name:     "mirror",
+		Usage:    "Count a layout and the mirror image of another as the same, so flipped variants join one family.",
+		Category: "Display",
+	},
+	&cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage: "Output format: \"table\" (the families), \"csv\" (the similarity of every pair of layouts), " +
+			"or \"linkage\" (the merge steps of the clustering as CSV, for drawing a dendrogram).",
+		Value:    "table",
+		Category: "Display",
+	},
+}
+
+// similarityCommand defines the CLI command for grouping the layouts in a directory into
+// families of similar layouts.
+var similarityCommand = &cli.Command{
+	Name:      "similarity",
+	Aliases:   []string{"sim"},
+	Usage:     "Group the layouts in a directory into families of similar layouts",
+	Flags:     append(append(commonFlags("corpus"), corpusBuildFlags()...), similarityFlags...),
+	ArgsUsage: "[<dir>]",
+	Action:    similarityAction,
+}
+
+// similarityAction compares all layouts in a directory (default: the layouts directory),
+// clusters them, and renders the families, the similarity matrix, or the clustering steps.
+func similarityAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, format, err := buildSimilarityInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	result, err := kc.CompareLayouts(input)
+	if err != nil {
+		return fmt.Errorf("could not compare layouts: %w", err)
+	}
+
+	return tui.RenderSimilarity(result, format)
+}
+
+// buildSimilarityInput gathers all input parameters for comparing layouts.
+func buildSimilarityInput(c *cli.Command) (kc.SimilarityInput, tui.OutputFormat, error) {
+	if c.NArg() > 1 {
+		return kc.SimilarityInput{}, "", fmt.Errorf("expected at most 1 directory, got %d", c.NArg())
+	}
+
+	var format tui.OutputFormat
+	switch strings.ToLower(c.String("output")) {
+	case "table":
+		format = tui.OutputTable
+	case "csv":
+		format = tui.OutputCSV
+	case "linkage":
+		format = tui.OutputLinkage
+	default:
+		return kc.SimilarityInput{}, "", fmt.Errorf("invalid output format; must be one of: table, csv, linkage")
+	}
+
+	threshold := c.Float64("threshold")
+	if threshold < 0 || threshold > 1 {
+		return kc.SimilarityInput{}, "", fmt.Errorf("threshold must be between 0 and 1. Got: %g", threshold)
+	}
+
+	dir := layoutDir
+	if c.NArg() == 1 {
+		dir = c.Args().First()
+	}
+
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.SimilarityInput{}, "", fmt.Errorf("could not load corpus: %w", err)
+	}
+
+	return kc.SimilarityInput{
+		LayoutsDir: dir,
+		Corpus:     corpus,
+		Threshold:  threshold,
+		Mirror:     c.Bool("mirror"),
+	}, format, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// statsFlags defines flags specific to the stats command.
+var statsFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "metrics",
+		Aliases: []string{"m"},
+		Usage: "Metrics to summarise: a metric set (e.g., \"basic\" or \"all\") " +
+			"or a comma-separated list.",
+		Value:    "basic",
+		Category: "Display",
+	},
+	&cli.IntFlag{
+		Name:  "bins",
+		Usage: "Number of bins of the histograms.",
+		Value: 10,
+		Action: func(ctx context.Context, c *cli.Command, value int) error {
+			if value < 1 {
+				return fmt.Errorf("--bins must be at least 1 (got %d)", value)
+			}
+			return nil
+		},
+		Category: "Display",
+	},
+	&cli.StringFlag{
+		Name:     "output",
+		Aliases:  []string{"o"},
+		Usage:    "Output format: \"table\" or \"csv\".",
+		Value:    "table",
+		Category: "Display",
+	},
+}
+
+// statsCommand defines the CLI command for summarising the metrics of the layouts in a
+// directory.
+var statsCommand = &cli.Command{
+	Name:  "stats",
+	Usage: "Show the distribution of each metric across the layouts in a directory",
+	Description: "Analyses all layouts in a directory (default: the layouts directory), and shows for " +
+		"each metric the minimum, quartiles and maximum, a histogram, and the layouts with the " +
+		"minimum and maximum value. Use it to see what a good value of a metric is in the population " +
+		"of layouts before setting weights or targets.",
+	Flags: append(commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition",
+		"load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties",
+		"roll-quality", "fatigue", "strict-targets"), append(append(corpusBuildFlags(), scopeFlag), statsFlags...)...),
+	ArgsUsage: "[<dir>]",
+	Action:    statsAction,
+}
+
+// statsAction analyses the layouts in a directory and renders the distribution of each metric.
+func statsAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, format, err := buildLayoutStatsInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	stats, err := kc.ComputeLayoutStats(input)
+	if err != nil {
+		return fmt.Errorf("could not compute layout statistics: %w", err)
+	}
+
+	return tui.RenderLayoutStats(stats, input.LayoutsDir, input.Corpus.Name, format)
+}
+
+// buildLayoutStatsInput gathers all input parameters for summarising the layouts of a directory.
+func buildLayoutStatsInput(c *cli.Command) (kc.LayoutStatsInput, tui.OutputFormat, error) {
+	if c.NArg() > 1 {
+		return kc.LayoutStatsInput{}, "", fmt.Errorf("expected at most 1 directory, got %d", c.NArg())
+	}
+
+	var format tui.OutputFormat
+	switch strings.ToLower(c.String("output")) {
+	case "table":
+		format = tui.OutputTable
+	case "csv":
+		format = tui.OutputCSV
+	default:
+		return kc.LayoutStatsInput{}, "", fmt.Errorf("invalid output format; must be one of: table, csv")
+	}
+
+	metrics, err := parseMetricsList(c.String("metrics"))
+	if err != nil {
+		return kc.LayoutStatsInput{}, "", err
+	}
+
+	dir := layoutDir
+	if c.NArg() == 1 {
+		dir = c.Args().First()
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.LayoutStatsInput{}, "", err
+	}
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.LayoutStatsInput{}, "", fmt.Errorf("could not load corpus: %w", err)
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.LayoutStatsInput{}, "", fmt.Errorf("could not load target loads: %w", err)
+	}
+
+	return kc.LayoutStatsInput{
+		LayoutsDir: dir,
+		Corpus:     corpus,
+		Targets:    targets,
+		Metrics:    metrics,
+		Bins:       int(c.Int("bins")),
+	}, format, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// swapMatrixFlags defines flags specific to the swap-matrix command.
+var swapMatrixFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "format",
+		Aliases: []string{"fmt"},
+		Usage: "Output format: \"table\" (best and worst swaps), \"csv\" or \"html\" " +
+			"(full 42x42 matrix).",
+		Value:    "table",
+		Category: "Display",
+	},
+	&cli.IntFlag{
+		Name:     "top",
+		Aliases:  []string{"n"},
+		Usage:    "Number of most beneficial and most harmful swaps to list or highlight.",
+		Value:    10,
+		Category: "Display",
+	},
+	&cli.StringFlag{
+		Name:     "output",
+		Aliases:  []string{"o"},
+		Usage:    "File to write the matrix to. Writes to stdout if empty.",
+		Category: "Display",
+	},
+}
+
+// swapMatrixFlagsSlice returns all flags for the swap-matrix command.
+func swapMatrixFlagsSlice() []cli.Flag {
+	commonFlags := commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition", "baseline", "load-targets-file", "target-hand-load", "target-finger-load",
+		"target-row-load", "pinky-penalties", "roll-quality", "fatigue", "strict-targets", "weights-file", "weights", "reference-glob", "reference-list")
+	return append(append(commonFlags, corpusBuildFlags()...), swapMatrixFlags...)
+}
+
+// swapMatrixCommand defines the CLI command for computing the score impact of every single swap.
+var swapMatrixCommand = &cli.Command{
+	Name:    "swap-matrix",
+	Aliases: []string{"sm"},
+	Usage:   "Compute the score change of every possible single key swap of a layout",
+	Description: "Scores the layout with each pair of keys swapped, using the same scorer as " +
+		"the optimiser. A positive gain means the swap improves the layout.",
+	Flags:         swapMatrixFlagsSlice(),
+	ArgsUsage:     "<layout>",
+	Action:        swapMatrixAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// swapMatrixAction computes the swap impact matrix of a layout and renders it.
+func swapMatrixAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildSwapMatrixInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	opts, err := buildSwapMatrixOptions(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	matrix, err := kc.ComputeSwapMatrix(input)
+	if err != nil {
+		return fmt.Errorf("could not compute swap matrix: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if path := c.String("output"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("could not create output file %s: %w", path, err)
+		}
+		defer kc.CloseFile(f)
+		w = f
+	}
+
+	return tui.RenderSwapMatrix(w, matrix, opts)
+}
+
+// buildSwapMatrixInput gathers all input parameters for the swap matrix computation.
+func buildSwapMatrixInput(c *cli.Command) (kc.SwapMatrixInput, error) {
+	if c.NArg() != 1 {
+		return kc.SwapMatrixInput{}, fmt.Errorf("expected exactly 1 layout, got %d", c.NArg())
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.SwapMatrixInput{}, err
+	}
+
+	layout, err := loadLayout(c.Args().First())
+	if err != nil {
+		return kc.SwapMatrixInput{}, fmt.Errorf("could not load layout: %w", err)
+	}
+
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.SwapMatrixInput{}, fmt.Errorf("could not load corpus: %w", err)
+	}
+
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.SwapMatrixInput{}, fmt.Errorf("could not load target loads: %w", err)
+	}
+
+	weights, err := loadWeightsFromFlags(c)
+	if err != nil {
+		return kc.SwapMatrixInput{}, fmt.Errorf("could not load weights: %w", err)
+	}
+
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.SwapMatrixInput{}, fmt.Errorf("could not load reference layouts: %w", err)
+	}
+
+	return kc.SwapMatrixInput{
+		Layout:     layout,
+		LayoutsDir: layoutDir,
+		Corpus:     corpus,
+		Targets:    targets,
+		Weights:    weights,
+		Reference:  reference,
+	}, nil
+}
+
+// buildSwapMatrixOptions validates and collects the rendering options from flags.
+func buildSwapMatrixOptions(c *cli.Command) (tui.SwapMatrixOptions, error) {
+	var format tui.OutputFormat
+	switch strings.ToLower(c.String("format")) {
+	case "table":
+		format = tui.OutputTable
+	case "csv":
+		format = tui.OutputCSV
+	case "html":
+		format = tui.OutputHTML
+	default:
+		return tui.SwapMatrixOptions{}, fmt.Errorf("invalid format %q: must be one of: table, csv, html", c.String("format"))
+	}
+
+	top := c.Int("top")
+	if top < 1 {
+		return tui.SwapMatrixOptions{}, fmt.Errorf("--top must be at least 1 (got %d)", top)
+	}
+
+	return tui.SwapMatrixOptions{OutputFormat: format, Top: int(top)}, nil
+}
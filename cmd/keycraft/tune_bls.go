@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// tuneBLSFlags defines flags specific to the tune-bls command.
+var tuneBLSFlags = []cli.Flag{
+	&cli.UintFlag{
+		Name:     "generations",
+		Aliases:  []string{"g"},
+		Usage:    "Number of optimization iterations of each probe.",
+		Value:    100,
+		Category: "Tuning",
+	},
+	&cli.UintFlag{
+		Name:     "maxtime",
+		Aliases:  []string{"mt"},
+		Usage:    "Maximum time of each probe in minutes.",
+		Value:    1,
+		Category: "Tuning",
+	},
+	&cli.StringFlag{
+		Name:     "search",
+		Usage:    "Search \"random\" parameter sets, or a \"grid\" of 81 sets around the defaults.",
+		Value:    "random",
+		Category: "Tuning",
+	},
+	&cli.UintFlag{
+		Name:     "samples",
+		Usage:    "Number of random parameter sets to probe besides the defaults.",
+		Value:    12,
+		Category: "Tuning",
+	},
+	&cli.UintFlag{
+		Name:     "probes",
+		Usage:    "Number of probes (seeds) per parameter set; the mean best cost ranks the sets.",
+		Value:    3,
+		Category: "Tuning",
+	},
+	&cli.IntFlag{
+		Name:     "rows",
+		Aliases:  []string{"r"},
+		Usage:    "Number of parameter sets to show (0 = all).",
+		Value:    10,
+		Category: "Display",
+	},
+}
+
+// tuneBLSCmdFlags returns all flags for the tune-bls command.
+func tuneBLSCmdFlags() []cli.Flag {
+	flags := commonFlags()
+	for i, f := range flags {
+		if f == commonFlagsMap["corpus"] {
+			flags[i] = optimizeCorpusFlag
+		}
+	}
+	flags = append(flags, corpusBuildFlags()...)
+	flags = append(flags, optFlags("pins-file", "pins", "free", "region", "swap-classes", "preserve-shortcuts", "max-changes", "change-weight", "seed", "compound-moves", "score-cache-size", "subsample")...)
+	return append(flags, tuneBLSFlags...)
+}
+
+// tuneBLSCommand defines the CLI command for searching BLS parameters for a workload.
+var tuneBLSCommand = &cli.Command{
+	Name:  "tune-bls",
+	Usage: "Search BLS parameters that optimize a layout best for your corpus and weights",
+	Description: "Runs short optimizations (probes) of the layout with the default BLS parameters " +
+		"and with other parameter sets, and ranks the sets by the mean best cost of their probes. " +
+		"Use the best set with optimize --bls-params.",
+	Flags:         tuneBLSCmdFlags(),
+	ArgsUsage:     "<layout>",
+	Action:        tuneBLSAction,
+	ShellComplete: layoutShellComplete,
+}
+
+// tuneBLSAction runs the parameter search and renders the ranked parameter sets.
+func tuneBLSAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildTuneInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	printSeed(input.Seed)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	input.Progress = func(done, total int) {
+		fmt.Printf("\rProbing BLS parameters: %d of %d probes", done, total)
+	}
+	result, err := kc.TuneBLS(ctx, input)
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("could not tune BLS parameters: %w", err)
+	}
+
+	tui.RenderTuneResult(result, int(c.Int("rows")))
+	return nil
+}
+
+// buildTuneInput gathers the parameters of a BLS parameter search from flags.
+func buildTuneInput(c *cli.Command) (kc.TuneInput, error) {
+	search := c.String("search")
+	if search != "random" && search != "grid" {
+		return kc.TuneInput{}, fmt.Errorf("invalid search %q: must be \"random\" or \"grid\"", search)
+	}
+	if c.Uint("probes") < 1 {
+		return kc.TuneInput{}, fmt.Errorf("number of probes must be at least 1")
+	}
+	if search == "random" && c.Uint("samples") < 1 {
+		return kc.TuneInput{}, fmt.Errorf("number of samples must be at least 1")
+	}
+
+	opt, err := buildOptimizeInput(c, nil, false)
+	if err != nil {
+		return kc.TuneInput{}, err
+	}
+	return kc.TuneInput{
+		Optimize:  opt,
+		ProbeTime: time.Duration(c.Uint("maxtime")) * time.Minute,
+		Grid:      search == "grid",
+		Samples:   int(c.Uint("samples")),
+		Probes:    int(c.Uint("probes")),
+		Seed:      opt.Seed,
+	}, nil
+}
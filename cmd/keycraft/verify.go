@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// verifyFlags defines flags specific to the verify command.
+var verifyFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "verify-file",
+		Aliases: []string{"vf"},
+		Usage:   "File in the config directory with the reference metric values.",
+		Value:   "verify.txt",
+	},
+	&cli.BoolFlag{
+		Name:  "update",
+		Usage: "Replace the reference values with the computed metrics, after a deliberate change to metric definitions.",
+	},
+	&cli.BoolFlag{
+		Name:     "all",
+		Usage:    "Show all checks instead of only the failed ones.",
+		Category: "Display",
+	},
+}
+
+// verifyCommand defines the CLI command for checking metrics against reference values.
+var verifyCommand = &cli.Command{
+	Name:  "verify",
+	Usage: "Check computed metrics of well-known layouts against reference values",
+	Description: "Analyses the layouts of a verify file against its reference corpus, with default " +
+		"load targets, and compares the metrics to the stored reference values within their " +
+		"tolerances. Fails if any metric differs, so that numbers can be trusted to be comparable " +
+		"across versions.",
+	Flags:  verifyFlags,
+	Action: verifyAction,
+}
+
+// verifyAction runs the checks of the verify file, or updates its reference values.
+func verifyAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	path := filepath.Join(configDir, c.String("verify-file"))
+	spec, analysers, err := buildVerifyInput(path)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	if c.Bool("update") {
+		if err := spec.Update(analysers); err != nil {
+			return fmt.Errorf("could not update reference values: %w", err)
+		}
+		if err := spec.SaveToFile(path); err != nil {
+			return err
+		}
+		fmt.Printf("Updated %d reference values in %s\n", len(spec.Checks), path)
+		return nil
+	}
+
+	results := spec.Verify(analysers)
+	tui.RenderVerify(spec, results, c.Bool("all"))
+	for _, res := range results {
+		if !res.Pass {
+			return checkFailed("metrics differ from the reference values in %s", path)
+		}
+	}
+	return nil
+}
+
+// buildVerifyInput loads the verify file, its corpus and layouts, and analyses the layouts.
+func buildVerifyInput(path string) (*kc.VerifySpec, map[string]*kc.Analyser, error) {
+	spec, err := kc.NewVerifySpecFromFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The corpus cache is used as is, so the coverage only matters when building it
+	corpus, err := loadCorpus(spec.Corpus, kc.CorpusBuildOptions{Coverage: 98})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load corpus: %w", err)
+	}
+
+	analysers := make(map[string]*kc.Analyser)
+	for _, name := range spec.Layouts() {
+		layout, err := loadLayout(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not load layout: %w", err)
+		}
+		analysers[name] = kc.NewAnalyser(layout, corpus, kc.NewTargetLoads())
+	}
+	return spec, analysers, nil
+}
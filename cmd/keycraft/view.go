@@ -11,7 +11,7 @@ import (
 
 // optimizeCmdFlags returns all flags for the optimise command
 func viewCmdFlags() []cli.Flag {
-	return commonFlags("corpus", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties")
+	return append(commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition", "baseline", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties", "roll-quality", "fatigue", "strict-targets", "inline"), append(corpusBuildFlags(), scopeFlag)...)
 }
 
 // viewCommand defines the CLI command for viewing keyboard layout analysis.
@@ -23,6 +23,8 @@ var viewCommand = &cli.Command{
 	ArgsUsage:     "<layout1> <layout2> ...",
 	Action:        viewAction,
 	ShellComplete: layoutShellComplete,
+	// Inline layouts can have commas as keys
+	DisableSliceFlagSeparator: true,
 }
 
 // viewAction gathers the necessary corpus and target load parameters, performs
@@ -47,7 +49,16 @@ func viewAction(ctx context.Context, c *cli.Command) error {
 
 // buildViewInput gathers all input parameters for layout viewing.
 func buildViewInput(c *cli.Command) (kc.ViewInput, error) {
-	if c.NArg() < 1 {
+	// The board geometry must be in use before the inline layouts are parsed
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.ViewInput{}, err
+	}
+
+	inline, err := loadInlineLayouts(c)
+	if err != nil {
+		return kc.ViewInput{}, err
+	}
+	if c.NArg()+len(inline) < 1 {
 		return kc.ViewInput{}, fmt.Errorf("need at least 1 layout")
 	}
 
@@ -63,7 +74,9 @@ func buildViewInput(c *cli.Command) (kc.ViewInput, error) {
 
 	return kc.ViewInput{
 		LayoutFiles: getLayoutArgs(c),
+		Layouts:     inline,
 		Corpus:      corpus,
 		Targets:     targets,
+		PinsDir:     configDir,
 	}, nil
 }
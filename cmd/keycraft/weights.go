@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+	"github.com/rbscholtus/keycraft/internal/tui"
+	"github.com/urfave/cli/v3"
+)
+
+// weightsFitFlags defines flags specific to the weights fit command.
+var weightsFitFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:     "prefer",
+		Usage:    "Directory with .klf layouts that the learned weights should rank high.",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:     "avoid",
+		Usage:    "Directory with .klf layouts that the learned weights should rank low.",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:    "metrics",
+		Aliases: []string{"m"},
+		Usage: "Metrics to learn weights for: a metric set (e.g., \"basic\" or \"extended\") " +
+			"or a comma-separated list.",
+		Value: "basic",
+	},
+	&cli.FloatFlag{
+		Name:  "regularisation",
+		Usage: "L2 penalty that keeps weights small when few layouts are given (0 = none).",
+		Value: 0.01,
+		Action: func(ctx context.Context, c *cli.Command, value float64) error {
+			if value < 0 {
+				return fmt.Errorf("--regularisation must be at least 0 (got %g)", value)
+			}
+			return nil
+		},
+	},
+	&cli.StringFlag{
+		Name:    "output-file",
+		Aliases: []string{"of"},
+		Usage:   "Weights file to write the learned weights to (in data/config directory).",
+		Value:   "weights_fit.txt",
+	},
+}
+
+// weightsFitFlagsSlice returns all flags for the weights fit command.
+func weightsFitFlagsSlice() []cli.Flag {
+	commonFlags := commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition", "baseline", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load", "pinky-penalties", "roll-quality", "fatigue", "strict-targets", "reference-glob", "reference-list")
+	return append(append(commonFlags, corpusBuildFlags()...), weightsFitFlags...)
+}
+
+// weightsCommand groups the commands that work with metric weights.
+var weightsCommand = &cli.Command{
+	Name:     "weights",
+	Usage:    "Work with metric weights for ranking and optimizing",
+	Commands: []*cli.Command{weightsFitCommand, weightsTimingCommand, weightsReportCommand},
+}
+
+// weightsFitCommand defines the CLI command for learning weights from example layouts.
+var weightsFitCommand = &cli.Command{
+	Name:  "fit",
+	Usage: "Learn weights under which preferred layouts outscore avoided layouts",
+	Description: "Analyses the layouts in the --prefer and --avoid directories, and fits weights " +
+		"for the chosen metrics, normalised like the rank command, so that every preferred layout " +
+		"scores higher than every avoided layout where possible. The weights are written to a " +
+		"weights file for use with --weights-file.",
+	Flags:  weightsFitFlagsSlice(),
+	Action: weightsFitAction,
+}
+
+// weightsFitAction learns weights, renders them, and saves them to the output file.
+func weightsFitAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildWeightsFitInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+
+	fit, err := kc.FitWeights(input)
+	if err != nil {
+		return fmt.Errorf("could not fit weights: %w", err)
+	}
+	tui.RenderWeightFit(fit)
+
+	path := filepath.Join(configDir, c.String("output-file"))
+	header := []string{
+		fmt.Sprintf("Weights learned with 'keycraft weights fit' from the layouts in %s (preferred) and %s (avoided)",
+			input.PreferredDir, input.AvoidedDir),
+		fmt.Sprintf("Corpus: %s; %d of %d layout pairs ordered as preferred", input.Corpus.Name, fit.Ordered, fit.Pairs),
+	}
+	if err := fit.SaveToFile(path, header); err != nil {
+		return err
+	}
+	fmt.Printf("Saved weights to %s; use them with --weights-file %s\n", path, c.String("output-file"))
+	return nil
+}
+
+// buildWeightsFitInput gathers the input parameters for learning weights.
+func buildWeightsFitInput(c *cli.Command) (kc.FitWeightsInput, error) {
+	metrics, err := parseMetricsList(c.String("metrics"))
+	if err != nil {
+		return kc.FitWeightsInput{}, err
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.FitWeightsInput{}, err
+	}
+
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.FitWeightsInput{}, fmt.Errorf("could not load corpus: %w", err)
+	}
+
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.FitWeightsInput{}, fmt.Errorf("could not load target loads: %w", err)
+	}
+
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.FitWeightsInput{}, fmt.Errorf("could not load reference set: %w", err)
+	}
+
+	return kc.FitWeightsInput{
+		LayoutsDir:     layoutDir,
+		Reference:      reference,
+		Corpus:         corpus,
+		Targets:        targets,
+		PreferredDir:   c.String("prefer"),
+		AvoidedDir:     c.String("avoid"),
+		Metrics:        metrics,
+		Regularisation: c.Float("regularisation"),
+	}, nil
+}
+
+// weightsTimingCommand defines the CLI command for fitting weights to measured bigram latencies.
+var weightsTimingCommand = &cli.Command{
+	Name:  "timing",
+	Usage: "Fit weights to the bigram latencies of a typing-test export",
+	Description: "Classifies the measured bigrams of the export by the layout they were typed on " +
+		"(SFB, FSB, HSB, LSB, inward and outward rolls, alternation), and measures the cost of each " +
+		"class as its mean latency minus that of alternation. The costs are turned into weights, " +
+		"normalised like the rank command, under which scores follow the typing time that the " +
+		"metrics add up to, and written to a weights file for use with --weights-file.",
+	Flags: append(append(commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition",
+		"load-targets-file", "target-hand-load", "target-finger-load", "target-row-load",
+		"pinky-penalties", "roll-quality", "fatigue", "strict-targets", "reference-glob", "reference-list"),
+		corpusBuildFlags()...),
+		&cli.StringFlag{
+			Name:    "layout",
+			Aliases: []string{"l"},
+			Usage:   "Layout the latencies were measured on.",
+			Value:   "qwerty",
+		},
+		&cli.UintFlag{
+			Name:  "min-samples",
+			Usage: "Minimum number of measurements of a class of bigrams to fit its cost.",
+			Value: 20,
+		},
+		&cli.StringFlag{
+			Name:    "output-file",
+			Aliases: []string{"of"},
+			Usage:   "Weights file to write the fitted weights to (in data/config directory).",
+			Value:   "weights_timing.txt",
+		},
+	),
+	ArgsUsage: "<export-file>",
+	Action:    weightsTimingAction,
+}
+
+// weightsTimingAction fits weights to the bigram latencies of an export, renders them, and
+// saves them to the output file.
+func weightsTimingAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected exactly 1 timing export, got %d", c.NArg())
+	}
+
+	input, err := buildTimingFitInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	fit, err := kc.FitTimingCosts(input)
+	if err != nil {
+		return fmt.Errorf("could not fit weights: %w", err)
+	}
+	tui.RenderTimingFit(fit)
+
+	path := filepath.Join(configDir, c.String("output-file"))
+	header := []string{
+		fmt.Sprintf("Weights fitted with 'keycraft weights timing' to the bigram latencies in %s, typed on %s",
+			c.Args().First(), input.Layout.Name),
+		fmt.Sprintf("Corpus: %s; other metrics are not measured and keep a weight of 0", input.Corpus.Name),
+	}
+	if err := fit.SaveToFile(path, header); err != nil {
+		return err
+	}
+	fmt.Printf("Saved weights to %s; use them with --weights-file %s\n", path, c.String("output-file"))
+	return nil
+}
+
+// buildTimingFitInput gathers the input parameters for fitting weights to bigram latencies.
+func buildTimingFitInput(c *cli.Command) (kc.TimingFitInput, error) {
+	timings, err := kc.LoadBigramTimings(c.Args().First())
+	if err != nil {
+		return kc.TimingFitInput{}, err
+	}
+
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.TimingFitInput{}, err
+	}
+	layout, err := loadLayout(c.String("layout"))
+	if err != nil {
+		return kc.TimingFitInput{}, fmt.Errorf("could not load layout: %w", err)
+	}
+
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.TimingFitInput{}, fmt.Errorf("could not load corpus: %w", err)
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.TimingFitInput{}, fmt.Errorf("could not load target loads: %w", err)
+	}
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.TimingFitInput{}, fmt.Errorf("could not load reference set: %w", err)
+	}
+
+	return kc.TimingFitInput{
+		LayoutsDir: layoutDir,
+		Reference:  reference,
+		Corpus:     corpus,
+		Targets:    targets,
+		Layout:     layout,
+		Timings:    timings,
+		MinCount:   uint64(c.Uint("min-samples")),
+	}, nil
+}
+
+// weightsReportCommand defines the CLI command for reporting the influence of weights.
+var weightsReportCommand = &cli.Command{
+	Name:  "report",
+	Usage: "Report the effective influence of each weight after normalisation",
+	Description: "Loads the weights like the rank command, and reports for each weighted metric its IQR " +
+		"among the reference layouts, how far the layouts typically are from the median in IQRs, and the " +
+		"influence of its weight on the scores: the weight times that spread, and its share of all " +
+		"influence. Weights that the scorer ignores, such as those of metrics without an IQR, are " +
+		"flagged. With --normalise, the weights are scaled so that their absolute values sum to 1, to " +
+		"compare weights files; --output-file saves them.",
+	Flags: append(append(commonFlags("corpus", "bigram-weighting", "geometry-file", "metric-version", "sfs-definition",
+		"baseline", "load-targets-file", "target-hand-load", "target-finger-load", "target-row-load",
+		"pinky-penalties", "roll-quality", "fatigue", "strict-targets", "weights-file", "weights", "reference-glob",
+		"reference-list"), corpusBuildFlags()...),
+		&cli.BoolFlag{
+			Name:  "normalise",
+			Usage: "Scale the weights so that the sum of their absolute values is 1.",
+		},
+		&cli.StringFlag{
+			Name:    "output-file",
+			Aliases: []string{"of"},
+			Usage:   "Weights file to write the (normalised) weights to (in data/config directory).",
+		},
+	),
+	Action: weightsReportAction,
+}
+
+// weightsReportAction reports the influence of the weights, and saves them to the output
+// file if one is given.
+func weightsReportAction(ctx context.Context, c *cli.Command) error {
+	if isShellCompletion() {
+		return nil
+	}
+
+	input, err := buildWeightReportInput(c)
+	if err != nil {
+		return fmt.Errorf("could not parse user input: %w", err)
+	}
+	report, err := kc.BuildWeightReport(input)
+	if err != nil {
+		return fmt.Errorf("could not build weights report: %w", err)
+	}
+	tui.RenderWeightReport(report)
+
+	file := c.String("output-file")
+	if file == "" {
+		return nil
+	}
+	path := filepath.Join(configDir, file)
+	header := []string{fmt.Sprintf("Weights from %s", weightsDescription(c))}
+	if report.Normalised {
+		header[0] += ", normalised to a sum of absolute values of 1 with 'keycraft weights report'"
+	}
+	if err := report.Weights.SaveToFile(path, header, report.Weights.Metrics()); err != nil {
+		return err
+	}
+	fmt.Printf("Saved weights to %s; use them with --weights-file %s\n", path, file)
+	return nil
+}
+
+// buildWeightReportInput gathers the input parameters for reporting the influence of weights.
+func buildWeightReportInput(c *cli.Command) (kc.WeightReportInput, error) {
+	if err := loadBoardGeometryFromFlags(c); err != nil {
+		return kc.WeightReportInput{}, err
+	}
+	corpus, err := loadCorpusFromFlags(c)
+	if err != nil {
+		return kc.WeightReportInput{}, fmt.Errorf("could not load corpus: %w", err)
+	}
+	targets, err := loadTargetLoadsFromFlags(c)
+	if err != nil {
+		return kc.WeightReportInput{}, fmt.Errorf("could not load target loads: %w", err)
+	}
+	weights, err := loadWeightsFromFlags(c)
+	if err != nil {
+		return kc.WeightReportInput{}, fmt.Errorf("could not load weights: %w", err)
+	}
+	reference, err := loadReferenceSetFromFlags(c)
+	if err != nil {
+		return kc.WeightReportInput{}, fmt.Errorf("could not load reference set: %w", err)
+	}
+
+	return kc.WeightReportInput{
+		LayoutsDir: layoutDir,
+		Reference:  reference,
+		Corpus:     corpus,
+		Targets:    targets,
+		Weights:    weights,
+		Normalise:  c.Bool("normalise"),
+	}, nil
+}
+
+// parseMetricsList returns the metrics of a metric set, or of a comma-separated list.
+func parseMetricsList(value string) ([]string, error) {
+	if metrics, ok := kc.MetricsMap[strings.ToLower(value)]; ok {
+		return metrics, nil
+	}
+	var metrics []string
+	for metric := range strings.SplitSeq(value, ",") {
+		if metric = strings.ToUpper(strings.TrimSpace(metric)); metric != "" {
+			metrics = append(metrics, metric)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics given")
+	}
+	if err := validateMetrics(metrics); err != nil {
+		return nil, fmt.Errorf("could not validate metrics: %w", err)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,117 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ABTestInput captures the inputs for testing whether a layout beats another across splits
+// of the corpus.
+type ABTestInput struct {
+	LayoutsDir string        // Layouts used for the medians/IQRs that normalise the scores
+	Reference  *ReferenceSet // Layouts of LayoutsDir used for medians/IQRs (nil = default naming rule)
+	A, B       *SplitLayout  // Layouts to compare
+	Corpus     *Corpus       // The corpus that is split
+	Targets    *TargetLoads  // Load targets (row, finger, pinky penalties)
+	Weights    *Weights      // Metric weights for weighted scoring, and the polarity of the metrics
+	Folds      int           // Number of folds to split the corpus into (at least 2)
+	Seed       int64         // Seed of the split
+}
+
+// ABTestResult is the comparison of two layouts on the whole corpus and on each fold.
+type ABTestResult struct {
+	A, B    string      // Names of the layouts
+	Folds   int         // Number of folds
+	Score   ABTestRow   // Weighted score of the layouts
+	Metrics []ABTestRow // Weighted metrics, in the order of MetricsMap["all"]
+}
+
+// ABTestRow compares a metric or the score of two layouts. Wins and losses are those of A,
+// by the polarity of the weight of the metric: a higher score is better, and so is a higher
+// value of a metric with a positive weight.
+type ABTestRow struct {
+	Metric string    // Metric name, or "Score"
+	A, B   float64   // Values of the layouts on the whole corpus
+	Deltas []float64 // Value of A minus that of B on each fold
+	Wins   int       // Number of folds on which A is better
+	Losses int       // Number of folds on which A is worse
+}
+
+// Ties returns the number of folds on which the layouts are equal.
+func (r ABTestRow) Ties() int {
+	return len(r.Deltas) - r.Wins - r.Losses
+}
+
+// Consistent reports whether the same layout is better on every fold.
+func (r ABTestRow) Consistent() bool {
+	return r.Wins == len(r.Deltas) || r.Losses == len(r.Deltas)
+}
+
+// abTestTolerance is the difference below which values are considered equal.
+const abTestTolerance = 1e-9
+
+// RunABTest splits the corpus into folds (see Corpus.Split), and compares the weighted score
+// and the weighted metrics of two layouts on the whole corpus and on each fold. The scores
+// are normalised with the medians/IQRs of the reference layouts on the whole corpus, so that
+// the scores of the folds are comparable.
+func RunABTest(input ABTestInput) (*ABTestResult, error) {
+	if input.Folds < 2 {
+		return nil, fmt.Errorf("folds must be at least 2, got %d", input.Folds)
+	}
+	targets := withDefaultTargets(input.Targets)
+	reference, err := loadReferenceAnalysers(input.LayoutsDir, input.Corpus, targets, input.Reference)
+	if err != nil {
+		return nil, err
+	}
+	medians, iqrs := computeMediansAndIQR(reference, nil)
+
+	var metrics []string
+	for _, metric := range MetricsMap["all"] {
+		if input.Weights.Get(metric) != 0 {
+			metrics = append(metrics, metric)
+		}
+	}
+	result := &ABTestResult{A: input.A.Name, B: input.B.Name, Folds: input.Folds, Score: ABTestRow{Metric: "Score"}}
+	for _, metric := range metrics {
+		result.Metrics = append(result.Metrics, ABTestRow{Metric: metric})
+	}
+
+	// compare adds the values of the layouts on a corpus to the rows
+	compare := func(corpus *Corpus, whole bool) {
+		analysers := []*Analyser{NewAnalyser(input.A, corpus, targets), NewAnalyser(input.B, corpus, targets)}
+		scores := computeScores(analysers, medians, iqrs, input.Weights)
+		result.Score.add(scores[0].Score, scores[1].Score, 1, whole)
+		for i, metric := range metrics {
+			result.Metrics[i].add(analysers[0].Metrics[metric], analysers[1].Metrics[metric], input.Weights.Get(metric), whole)
+		}
+	}
+	compare(input.Corpus, true)
+	for _, fold := range input.Corpus.Split(input.Folds, NewLockedRNG(uint64(input.Seed), 0)) {
+		compare(fold, false)
+	}
+
+	// Show the metrics on which the layouts differ the most consistently first
+	slices.SortStableFunc(result.Metrics, func(a, b ABTestRow) int {
+		return max(b.Wins, b.Losses) - max(a.Wins, a.Losses)
+	})
+	return result, nil
+}
+
+// add records the values a and b of the layouts on the whole corpus, or the difference on a
+// fold. The weight gives the polarity of the values.
+func (r *ABTestRow) add(a, b, weight float64, whole bool) {
+	if whole {
+		r.A, r.B = a, b
+		return
+	}
+	delta := a - b
+	r.Deltas = append(r.Deltas, delta)
+	switch {
+	case delta > abTestTolerance:
+		r.Wins += IfThen(weight > 0, 1, 0)
+		r.Losses += IfThen(weight > 0, 0, 1)
+	case delta < -abTestTolerance:
+		r.Wins += IfThen(weight > 0, 0, 1)
+		r.Losses += IfThen(weight > 0, 1, 0)
+	}
+}
@@ -0,0 +1,55 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestRunABTest checks that a layout is compared with itself as equal on every fold, and that
+// the wins and losses of two layouts mirror each other.
+func TestRunABTest(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+	a := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	c := Must(NewLayoutFromFile("c", filepath.Join(dir, "c.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs\n")
+	input := ABTestInput{LayoutsDir: dir, A: a, B: a, Corpus: corpus, Weights: NewWeights(), Folds: 2, Seed: 1}
+
+	self := Must(RunABTest(input))
+	if self.Score.Ties() != 2 || len(self.Score.Deltas) != 2 {
+		t.Errorf("a vs a: score has %d ties of %d folds, want 2 of 2", self.Score.Ties(), len(self.Score.Deltas))
+	}
+	for _, row := range self.Metrics {
+		if row.Wins != 0 || row.Losses != 0 {
+			t.Errorf("a vs a: %s has %d wins and %d losses, want none", row.Metric, row.Wins, row.Losses)
+		}
+	}
+
+	input.B = c
+	ac := Must(RunABTest(input))
+	input.A, input.B = c, a
+	ca := Must(RunABTest(input))
+	if ac.Score.Wins != ca.Score.Losses || ac.Score.Losses != ca.Score.Wins {
+		t.Errorf("a vs c has %d/%d wins/losses, c vs a %d/%d; want mirrored",
+			ac.Score.Wins, ac.Score.Losses, ca.Score.Wins, ca.Score.Losses)
+	}
+
+	// The folds are drawn from the seed
+	again := Must(RunABTest(input))
+	if !slices.Equal(again.Score.Deltas, ca.Score.Deltas) {
+		t.Errorf("seed 1 gave score deltas %v and %v, want the same", ca.Score.Deltas, again.Score.Deltas)
+	}
+	input.Seed = 2
+	if other := Must(RunABTest(input)); slices.Equal(other.Score.Deltas, ca.Score.Deltas) {
+		t.Errorf("seeds 1 and 2 gave the same score deltas %v", other.Score.Deltas)
+	}
+
+	input.Folds = 1
+	if _, err := RunABTest(input); err == nil {
+		t.Error("expected an error for 1 fold")
+	}
+}
@@ -1,48 +1,166 @@
 package keycraft
 
 import (
+	"fmt"
+	"maps"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 )
 
 // AnalyseInput contains parameters needed for layout analysis computation.
 // This is pure computational input - no display/rendering concerns.
 type AnalyseInput struct {
-	LayoutFiles []string     // Full filepaths to layout files to analyse
-	Corpus      *Corpus      // Text corpus for analysis
-	TargetLoads *TargetLoads // User target loads
+	LayoutFiles []string             // Full filepaths to layout files to analyse
+	Layouts     []*SplitLayout       // Layouts to analyse after the files, that need not be saved (e.g. given inline)
+	Corpus      *Corpus              // Text corpus for analysis
+	TargetLoads *TargetLoads         // User target loads
+	Board       *BoardPreset         // Board the layouts must fit (nil = any)
+	VsRandom    *RandomBaselineInput // Random layouts to compare with (nil = none), with the corpus and targets of the input
 }
 
 // AnalyseResult contains the computational results of layout analysis.
 // Display-agnostic - just the data.
 type AnalyseResult struct {
-	Analysers []*Analyser // Analysis results for each layout
+	Analysers []*Analyser         // Analysis results for each layout
+	VsRandom  []*RandomComparison // Comparison of each layout with random layouts (nil without VsRandom)
 }
 
 // AnalyseDisplayOptions contains rendering/display preferences.
 // These don't affect the computation, only how results are presented.
 type AnalyseDisplayOptions struct {
-	MaxRows         int  // Maximum rows to show in detail tables
-	CompactTrigrams bool // Whether to use compact trigram display
-	TrigramRows     int  // Number of trigram rows to display
+	MaxRows         int      // Maximum rows to show in detail tables
+	CompactTrigrams bool     // Whether to use compact trigram display
+	TrigramRows     int      // Number of trigram rows to display
+	PerKey          bool     // Whether to show per-key attribution boards
+	Sections        []string // Sections to show, from AnalyseSections (nil = all)
+	Page            int      // Page of the detail and trigram tables (0 or 1 = the first rows)
+	FocusKey        rune     // Show only the n-grams with this character, and its role (0 = all n-grams)
+}
+
+// AnalyseSections lists the sections of the analyse report, in report order. The metric
+// sections are the detail tables of AllMetricsDetails; per-key is only shown with PerKey.
+var AnalyseSections = []string{
+	"board", "hand", "row", "stats", "runs", "fatigue", "per-key",
+	"sfb", "lsb", "fsb", "hsb", "sfs", "lss", "fss", "hss", "alt", "2rl", "3rl", "red",
+	"unsupported", "trigrams",
+}
+
+// AnalyseSectionGroups names groups of sections of the analyse report.
+var AnalyseSectionGroups = map[string][]string{
+	"overview":  {"board", "hand", "row", "stats", "runs", "fatigue"},
+	"bigrams":   {"sfb", "lsb", "fsb", "hsb"},
+	"skipgrams": {"sfs", "lss", "fss", "hss"},
+	"details":   {"sfb", "lsb", "fsb", "hsb", "sfs", "lss", "fss", "hss", "alt", "2rl", "3rl", "red"},
+}
+
+// ParseAnalyseSections parses a comma-separated list of sections and section groups of the
+// analyse report, such as "stats,bigrams,trigrams", into sections in report order.
+func ParseAnalyseSections(spec string) ([]string, error) {
+	selected := make(map[string]bool)
+	for name := range strings.SplitSeq(strings.ToLower(spec), ",") {
+		name = strings.TrimSpace(name)
+		if group, ok := AnalyseSectionGroups[name]; ok {
+			for _, section := range group {
+				selected[section] = true
+			}
+			continue
+		}
+		if !slices.Contains(AnalyseSections, name) {
+			return nil, fmt.Errorf("unknown section %q: must be one of %s, or a group: %s", name,
+				strings.Join(AnalyseSections, ", "), strings.Join(slices.Sorted(maps.Keys(AnalyseSectionGroups)), ", "))
+		}
+		selected[name] = true
+	}
+
+	sections := make([]string, 0, len(selected))
+	for _, section := range AnalyseSections {
+		if selected[section] {
+			sections = append(sections, section)
+		}
+	}
+	return sections, nil
+}
+
+// Shows reports whether a section of the analyse report is shown.
+func (opts AnalyseDisplayOptions) Shows(section string) bool {
+	if section == "per-key" && !opts.PerKey {
+		return false
+	}
+	return opts.Sections == nil || slices.Contains(opts.Sections, strings.ToLower(section))
 }
 
 // AnalyseLayouts performs detailed layout analysis.
 // Pure computation - no I/O, no rendering, no display logic.
 func AnalyseLayouts(input AnalyseInput) (*AnalyseResult, error) {
-	analysers := make([]*Analyser, 0, len(input.LayoutFiles))
-	for _, path := range input.LayoutFiles {
-		// Extract layout name from filename (remove directory and extension)
-		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
-		layout, err := NewLayoutFromFile(name, path)
-		if err != nil {
+	analysers, err := analyseLayoutFiles(input.LayoutFiles, input.Corpus, input.TargetLoads)
+	if err != nil {
+		return nil, err
+	}
+	for _, layout := range input.Layouts {
+		analysers = append(analysers, NewAnalyser(layout, input.Corpus, withDefaultTargets(input.TargetLoads)))
+	}
+	if input.Board != nil {
+		for _, an := range analysers {
+			if err := input.Board.CheckLayout(an.Layout); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var vsRandom []*RandomComparison
+	if input.VsRandom != nil {
+		vsInput := *input.VsRandom
+		vsInput.Corpus, vsInput.Targets = input.Corpus, input.TargetLoads
+		if vsRandom, err = CompareWithRandom(analysers, vsInput); err != nil {
 			return nil, err
 		}
-		analyser := NewAnalyser(layout, input.Corpus, input.TargetLoads)
-		analysers = append(analysers, analyser)
 	}
 
 	return &AnalyseResult{
 		Analysers: analysers,
+		VsRandom:  vsRandom,
 	}, nil
 }
+
+// analyseLayoutFiles loads and analyses layout files concurrently, with at most GOMAXPROCS
+// layouts at a time. The analysers are in the order of the paths. If layouts fail to load,
+// the error of the first of them is returned.
+func analyseLayoutFiles(paths []string, corpus *Corpus, targets *TargetLoads) ([]*Analyser, error) {
+	// Fill in the defaults before the analysers share the targets
+	targets = withDefaultTargets(targets)
+
+	var (
+		analysers = make([]*Analyser, len(paths))
+		errs      = make([]error, len(paths))
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, runtime.GOMAXPROCS(0)) // Semaphore to limit concurrent goroutines
+	)
+	for i, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Extract layout name from filename (remove directory and extension)
+			name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			layout, err := NewLayoutFromFile(name, path)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			analysers[i] = NewAnalyser(layout, corpus, targets)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return analysers, nil
+}
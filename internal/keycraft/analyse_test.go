@@ -0,0 +1,59 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAnalyseLayouts_KeepsInputOrder(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"b.klf": testLayoutVariantKlf,
+		"c.klf": testLayoutKlf,
+	})
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+
+	names := []string{"c", "a", "b", "a"}
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name+".klf")
+	}
+	result := Must(AnalyseLayouts(AnalyseInput{LayoutFiles: paths, Corpus: corpus}))
+	for i, an := range result.Analysers {
+		if an.Layout.Name != names[i] {
+			t.Errorf("analyser %d is for layout %s, want %s", i, an.Layout.Name, names[i])
+		}
+	}
+
+	// The error of the first failing layout in input order is returned
+	paths = []string{paths[0], filepath.Join(dir, "x.klf"), filepath.Join(dir, "y.klf")}
+	_, err := AnalyseLayouts(AnalyseInput{LayoutFiles: paths, Corpus: corpus})
+	if err == nil {
+		t.Fatal("expected an error for missing layouts")
+	}
+	if _, want := NewLayoutFromFile("x", paths[1]); err.Error() != want.Error() {
+		t.Errorf("error %q, want %q", err, want)
+	}
+}
+
+func TestParseAnalyseSections(t *testing.T) {
+	sections := Must(ParseAnalyseSections("Trigrams, bigrams,stats,sfb"))
+	want := []string{"stats", "sfb", "lsb", "fsb", "hsb", "trigrams"}
+	if !slices.Equal(sections, want) {
+		t.Errorf("sections %v, want %v", sections, want)
+	}
+
+	opts := AnalyseDisplayOptions{Sections: sections}
+	if !opts.Shows("SFB") || !opts.Shows("trigrams") || opts.Shows("board") || opts.Shows("SFS") {
+		t.Errorf("Shows does not match sections %v", sections)
+	}
+	if all := (AnalyseDisplayOptions{}); !all.Shows("board") || !all.Shows("RED") || all.Shows("per-key") {
+		t.Error("without sections, all sections but per-key should be shown")
+	}
+
+	if _, err := ParseAnalyseSections("stats,nope"); err == nil {
+		t.Error("expected an error for an unknown section")
+	}
+}
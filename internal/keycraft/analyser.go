@@ -19,11 +19,11 @@ var MetricsMap = map[string][]string{
 		"SFB", "LSB", "FSB", "HSB",
 		"SFS", "LSS", "FSS", "HSS",
 		"ALT", "ALT-NML", "ALT-SFS",
-		"RED", "RED-NML", "RED-WEAK", "RED-SFS",
+		"RED", "RED-NML", "RED-WEAK", "RED-SFS", "RED-SEV",
 		"2RL", "2RL-IN", "2RL-OUT", "2RL-SFB",
 		"3RL", "3RL-IN", "3RL-OUT", "3RL-SFB",
-		"FLW", "IN:OUT",
-		"HLD", "FLD", "RLD", "POH",
+		"FLW", "IN:OUT", "ROLLQ",
+		"HLD", "FLD", "RLD", "POH", "ROH", "MOH", "IOH",
 	},
 	"fingers": {
 		"F0", "F1", "F2", "F3", "F4",
@@ -35,14 +35,28 @@ var MetricsMap = map[string][]string{
 		"SFB", "LSB", "FSB", "HSB",
 		"SFS", "LSS", "FSS", "HSS",
 		// Trigram metrics
-		"RED", "RED-NML", "RED-WEAK", "RED-SFS",
+		"RED", "RED-NML", "RED-WEAK", "RED-SFS", "RED-SEV",
 		"ALT", "ALT-NML", "ALT-SFS",
 		"2RL", "2RL-IN", "2RL-OUT", "2RL-SFB",
-		"3RL", "3RL-IN", "3RL-OUT", "3RL-SFB",
+		"3RL", "3RL-IN", "3RL-OUT", "3RL-SFB", "3RL-SFB-ADJ", "3RL-SFB-SKP",
 		// Flow metrics
-		"FLW", "IN:OUT",
+		"FLW", "IN:OUT", "ROLLQ",
 		// Load deviation metrics
-		"HLD", "FLD", "RLD", "POH",
+		"HLD", "FLD", "RLD", "POH", "ROH", "MOH", "IOH",
+		// Comfort zone metrics
+		"ZONE-A", "ZONE-B", "ZONE-C", "ZONE-D",
+		// Shift metrics
+		"SHIFT", "SHIFT-SF",
+		// Number row metrics
+		"NUM", "NUM-SFB", "NUM-MISS",
+		// Magic key metrics
+		"MAGIC", "MAGIC-SFB", "MAGIC-SFS",
+		// Hand run metrics
+		"HRUN-AVG", "HRUN-P95",
+		// Finger repetition pressure
+		"FATIGUE",
+		// Learning cost relative to a baseline layout
+		"MOVE", "MOVE-FREQ",
 		// Hand distribution
 		"H0", "H1",
 		// Finger distribution
@@ -57,10 +71,22 @@ var MetricsMap = map[string][]string{
 // TargetLoads encapsulates user targets for load distributions and penalties.
 // These targets are used to evaluate how well a layout matches target typing patterns.
 type TargetLoads struct {
-	TargetHandLoad   *[2]float64  // Target distribution: [left, right] hands (scaled to 100%)
-	TargetFingerLoad *[10]float64 // Target distribution: F0-F9 fingers (scaled to 100%, thumbs=0)
-	TargetRowLoad    *[3]float64  // Target distribution: [top, home, bottom] rows (scaled to 100%)
-	PinkyPenalties   *[12]float64 // Penalty weights for pinky off-home positions (not scaled)
+	TargetHandLoad   *[2]float64         // Target distribution: [left, right] hands (scaled to 100%)
+	TargetFingerLoad *[10]float64        // Target distribution: F0-F9 fingers (scaled to 100%, thumbs=0)
+	TargetRowLoad    *[3]float64         // Target distribution: [top, home, bottom] rows (scaled to 100%)
+	PinkyPenalties   *[12]float64        // Penalty weights for pinky off-home positions (not scaled)
+	RingPenalties    *[6]float64         // Penalty weights for ring finger off-home positions (not scaled)
+	MiddlePenalties  *[6]float64         // Penalty weights for middle finger off-home positions (not scaled)
+	IndexPenalties   *[12]float64        // Penalty weights for index finger off-home positions (not scaled)
+	MaxFingerLoad    *[10]float64        // Maximum load of each finger F0-F9 (not scaled, nil = no maximum)
+	RollQuality      *RollQualityWeights // Weights of the components of ROLLQ
+	Fatigue          *FatigueSettings    // Window and limit of FATIGUE
+	ComfortZones     *ComfortZones       // Comfort tier of each key position, for ZONE-A to ZONE-D
+	MetricVersion    int                 // Version of the metric definitions (0 = CurrentMetricVersion)
+	SFSDefinition    SFSDefinition       // Which same-finger skipgrams SFS counts ("" = SFSAll)
+	Baseline         *SplitLayout        // Layout that MOVE and MOVE-FREQ count changes from (nil = none)
+
+	givenSums map[string]float64 // Sums of the loads as given, before scaling, by setting name
 }
 
 // DefaultTargetHandLoad returns the default target hand load distribution (as percentages).
@@ -124,6 +150,29 @@ func DefaultPinkyPenalties() *[12]float64 {
 	}
 }
 
+// DefaultRingPenalties returns the default ring finger off-home penalty weights.
+// Order per hand: top, home, bottom. Default values: [1.5, 0.0, 1.5] per hand.
+func DefaultRingPenalties() *[6]float64 {
+	return &[6]float64{1.5, 0.0, 1.5, 1.5, 0.0, 1.5}
+}
+
+// DefaultMiddlePenalties returns the default middle finger off-home penalty weights.
+// Order per hand: top, home, bottom. Default values: [1.0, 0.0, 1.5] per hand.
+func DefaultMiddlePenalties() *[6]float64 {
+	return &[6]float64{1.0, 0.0, 1.5, 1.0, 0.0, 1.5}
+}
+
+// DefaultIndexPenalties returns the default index finger off-home penalty weights.
+// Order per hand as for pinkies: top-outer, top-inner, home-outer, home-inner, bottom-outer,
+// bottom-inner, where the outer column is the home column of the index finger.
+// Default values: [1.0, 1.5, 0.0, 1.0, 1.0, 2.0] per hand.
+func DefaultIndexPenalties() *[12]float64 {
+	return &[12]float64{
+		1.0, 1.5, 0.0, 1.0, 1.0, 2.0, // Left index (columns 4 and 5)
+		1.0, 1.5, 0.0, 1.0, 1.0, 2.0, // Right index (columns 7 and 6)
+	}
+}
+
 // MetricDetails contains detailed analysis results for a single metric.
 // Includes per-n-gram counts, distances, and custom attributes (e.g., hand, finger, direction).
 type MetricDetails struct {
@@ -135,6 +184,7 @@ type MetricDetails struct {
 	TotalNGrams  uint64                    // Total count of relevant n-grams
 	TotalDist    float64                   // Sum of weighted distances
 	Custom       map[string]map[string]any // Additional per-n-gram attributes
+	Unsupported  map[string]uint64         // Corpus n-grams skipped because a character is not on the layout (nil if not tracked)
 }
 
 // TrigramInfo holds a trigram with its frequency for performance.
@@ -152,13 +202,50 @@ type Analyser struct {
 	Targets *TargetLoads       // Target load distributions and penalty weights
 	Metrics map[string]float64 // Computed metrics (e.g., "SFB", "ALT", "FLD")
 
+	HandRuns      []uint64    // Number of same-hand runs by length (index = run length); nil if not computed
+	FingerFatigue [10]float64 // FATIGUE by finger (F0-F9)
+
 	// Pre-filtered n-grams (injected by Scorer to avoid redundant filtering)
 	relevantTrigrams []TrigramInfo // Only trigrams with all 3 runes on layout
+	trigramTable     *trigramTable // relevantTrigrams translated to rune indices (optional)
+
+	counts analyserCounts // Raw counts behind the metrics, updated by ApplySwap
+	swaps  *swapIndex     // Lookup tables for ApplySwap (nil until the first swap)
+	shift  *shiftFold     // Folded corpus for the shift metrics (nil without shifted runes)
+	magic  *magicFold     // Corpus typed with the magic key, for the magic metrics (nil without one)
+	stream bool           // Whether the word list and stream metrics are computed
 }
 
 // NewAnalyser creates an Analyser and computes all metrics for the given layout.
 // If targets is nil or any of its fields are nil, uses defaults.
 func NewAnalyser(layout *SplitLayout, corpus *Corpus, targets *TargetLoads) *Analyser {
+	targets = withDefaultTargets(targets)
+	an := &Analyser{
+		Layout:  layout,
+		Corpus:  corpus,
+		Targets: targets,
+		Metrics: make(map[string]float64, 60),
+	}
+	an.useShiftedCorpus()
+	an.useMagicCorpus()
+	an.analyseHand()
+	an.analyseBigrams()
+	an.analyseSkipgrams()
+	an.analyseTrigrams()
+	an.analyseRollQuality()
+	an.analyseShift()
+	an.analyseNumbers()
+	an.analyseMagic()
+	an.analyseMoves()
+	an.analyseHandRuns()
+	an.analyseFatigue()
+	an.stream = true
+	return an
+}
+
+// withDefaultTargets fills the nil fields of targets with the defaults, and returns targets,
+// or the default targets if targets is nil.
+func withDefaultTargets(targets *TargetLoads) *TargetLoads {
 	if targets == nil {
 		targets = &TargetLoads{}
 	}
@@ -174,17 +261,31 @@ func NewAnalyser(layout *SplitLayout, corpus *Corpus, targets *TargetLoads) *Ana
 	if targets.PinkyPenalties == nil {
 		targets.PinkyPenalties = DefaultPinkyPenalties()
 	}
-	an := &Analyser{
-		Layout:  layout,
-		Corpus:  corpus,
-		Targets: targets,
-		Metrics: make(map[string]float64, 60),
+	targets.fillDefaultOffHomePenalties()
+	if targets.RollQuality == nil {
+		targets.RollQuality = DefaultRollQuality()
+	}
+	if targets.Fatigue == nil {
+		targets.Fatigue = DefaultFatigue()
+	}
+	if targets.ComfortZones == nil {
+		targets.ComfortZones = DefaultComfortZones()
+	}
+	return targets
+}
+
+// fillDefaultOffHomePenalties fills the nil off-home penalties of the ring, middle and index
+// fingers with the defaults.
+func (tl *TargetLoads) fillDefaultOffHomePenalties() {
+	if tl.RingPenalties == nil {
+		tl.RingPenalties = DefaultRingPenalties()
+	}
+	if tl.MiddlePenalties == nil {
+		tl.MiddlePenalties = DefaultMiddlePenalties()
+	}
+	if tl.IndexPenalties == nil {
+		tl.IndexPenalties = DefaultIndexPenalties()
 	}
-	an.analyseHand()
-	an.analyseBigrams()
-	an.analyseSkipgrams()
-	an.analyseTrigrams()
-	return an
 }
 
 // analyseHand computes usage metrics for hands, fingers, columns, and rows from unigrams.
@@ -192,39 +293,55 @@ func NewAnalyser(layout *SplitLayout, corpus *Corpus, targets *TargetLoads) *Ana
 //   - HLD: Hand Load Deviation - sum of absolute deviations from target hand loads
 //   - FLD: Finger Load Deviation - sum of absolute deviations from target finger loads (pinkies: only positive deviations)
 //   - RLD: Row Load Deviation - weighted deviations from target row loads
+//   - POH, ROH, MOH, IOH: weighted off-home usage of the pinky, ring, middle and index fingers
+//
+// And the comfort zone metrics ZONE-A to ZONE-D (see setZoneMetrics).
 func (an *Analyser) analyseHand() {
+	an.counts.keys = [42]uint64{}
+	for uniGr, uniCnt := range an.Corpus.Unigrams {
+		key, ok := an.Layout.GetKeyInfo(rune(uniGr))
+		if !ok {
+			continue
+		}
+		an.counts.keys[key.Index] += uniCnt
+	}
+	an.setHandMetrics()
+}
+
+// pofIndex maps (row, column) to the PinkyPenalties array index.
+// Array order per hand: top-outer, top-inner, home-outer, home-inner, bottom-outer, bottom-inner
+// Left hand: col 0 is outer, col 1 is inner; Right hand: col 11 is outer, col 10 is inner
+var pofIndex = map[[2]uint8]int{
+	// Left pinky (indices 0-5)
+	{0, 0}: 0, // top-outer
+	{0, 1}: 1, // top-inner
+	{1, 0}: 2, // home-outer
+	{1, 1}: 3, // home-inner
+	{2, 0}: 4, // bottom-outer
+	{2, 1}: 5, // bottom-inner
+	// Right pinky (indices 6-11)
+	{0, 11}: 6,  // top-outer
+	{0, 10}: 7,  // top-inner
+	{1, 11}: 8,  // home-outer
+	{1, 10}: 9,  // home-inner
+	{2, 11}: 10, // bottom-outer
+	{2, 10}: 11, // bottom-inner
+}
+
+// setHandMetrics computes the metrics of analyseHand from the unigram counts per key.
+func (an *Analyser) setHandMetrics() {
 	var totalUnigramCount uint64
-	var pinkyOffWeighted float64
+	var pinkyOffWeighted, ringOffWeighted, middleOffWeighted, indexOffWeighted float64
 	var handCount [2]uint64
 	var fingerCount [10]uint64
 	var columnCount [12]uint64
 	var rowCount [4]uint64
 
-	// Map (row, column) to PinkyWeights array index
-	// Array order per hand: top-outer, top-inner, home-outer, home-inner, bottom-outer, bottom-inner
-	// Left hand: col 0 is outer, col 1 is inner; Right hand: col 11 is outer, col 10 is inner
-	pofIndex := map[[2]uint8]int{
-		// Left pinky (indices 0-5)
-		{0, 0}: 0, // top-outer
-		{0, 1}: 1, // top-inner
-		{1, 0}: 2, // home-outer
-		{1, 1}: 3, // home-inner
-		{2, 0}: 4, // bottom-outer
-		{2, 1}: 5, // bottom-inner
-		// Right pinky (indices 6-11)
-		{0, 11}: 6,  // top-outer
-		{0, 10}: 7,  // top-inner
-		{1, 11}: 8,  // home-outer
-		{1, 10}: 9,  // home-inner
-		{2, 11}: 10, // bottom-outer
-		{2, 10}: 11, // bottom-inner
-	}
-
-	for uniGr, uniCnt := range an.Corpus.Unigrams {
-		key, ok := an.Layout.GetKeyInfo(rune(uniGr))
-		if !ok {
+	for idx, uniCnt := range an.counts.keys {
+		if uniCnt == 0 {
 			continue
 		}
+		key := NewKeyInfo(uint8(idx/12), uint8(idx%12), an.Layout.LayoutType)
 
 		// Count main row keys (rows 0-2) for load distribution calculations
 		if key.Row < 3 {
@@ -233,11 +350,24 @@ func (an *Analyser) analyseHand() {
 			fingerCount[key.Finger] += uniCnt
 			columnCount[key.Column] += uniCnt
 
-			// POH: weighted pinky penalty
-			if key.Finger == LP || key.Finger == RP {
+			// POH, ROH, MOH and IOH: weighted off-home penalties. Ring and middle finger keys
+			// are weighted by row, and index finger keys by row and whether they are in the
+			// home column of the finger, so that angle-modded keys count for their finger.
+			switch key.Finger {
+			case LP, RP:
 				if idx, ok := pofIndex[[2]uint8{key.Row, key.Column}]; ok {
 					pinkyOffWeighted += an.Targets.PinkyPenalties[idx] * float64(uniCnt)
 				}
+			case LR, RR:
+				ringOffWeighted += an.Targets.RingPenalties[3*key.Hand+key.Row] * float64(uniCnt)
+			case LM, RM:
+				middleOffWeighted += an.Targets.MiddlePenalties[3*key.Hand+key.Row] * float64(uniCnt)
+			case LI, RI:
+				idx := 6*key.Hand + 2*key.Row
+				if key.Column != fingerHomeKeys[key.Finger]%12 {
+					idx++
+				}
+				indexOffWeighted += an.Targets.IndexPenalties[idx] * float64(uniCnt)
 			}
 		}
 
@@ -250,8 +380,11 @@ func (an *Analyser) analyseHand() {
 		totFactor = 100 / float64(totalUnigramCount)
 	}
 
-	// POH - pinky off home
+	// POH, ROH, MOH and IOH - pinky, ring, middle and index off home
 	an.Metrics["POH"] = pinkyOffWeighted * totFactor
+	an.Metrics["ROH"] = ringOffWeighted * totFactor
+	an.Metrics["MOH"] = middleOffWeighted * totFactor
+	an.Metrics["IOH"] = indexOffWeighted * totFactor
 
 	// Hx and HLD
 	for i, c := range handCount {
@@ -262,6 +395,7 @@ func (an *Analyser) analyseHand() {
 		math.Abs(an.Metrics["H1"]-an.Targets.TargetHandLoad[1])
 
 	// Fx and FLD
+	an.Metrics["FLD"] = 0
 	for i, c := range fingerCount {
 		fi := "F" + strconv.Itoa(i)
 		an.Metrics[fi] = float64(c) * totFactor
@@ -274,6 +408,10 @@ func (an *Analyser) analyseHand() {
 		} else {
 			an.Metrics["FLD"] += math.Abs(an.Metrics[fi] - an.Targets.TargetFingerLoad[i])
 		}
+		// Loads above a finger's maximum are penalised on top of the deviation
+		if caps := an.Targets.MaxFingerLoad; caps != nil && an.Metrics[fi] > caps[i] {
+			an.Metrics["FLD"] += maxFingerLoadPenalty * (an.Metrics[fi] - caps[i])
+		}
 	}
 
 	// Cx
@@ -289,6 +427,7 @@ func (an *Analyser) analyseHand() {
 		mainRows  = 3
 	)
 
+	an.Metrics["RLD"] = 0
 	for i, c := range rowCount {
 		ri := "R" + strconv.Itoa(i)
 		an.Metrics[ri] = float64(c) * totFactor
@@ -305,10 +444,12 @@ func (an *Analyser) analyseHand() {
 			}
 		}
 	}
+
+	an.setZoneMetrics()
 }
 
 // analyseBigrams computes bigram-based metrics from corpus frequencies:
-//   - SFB: Same Finger Bigrams
+//   - SFB: Same Finger Bigrams (of different keys, or also same-key repeats in metric version 1)
 //   - LSB: Lateral Stretch Bigrams
 //   - FSB: Full Scissor Bigrams
 //   - HSB: Half Scissor Bigrams
@@ -322,8 +463,16 @@ func (an *Analyser) analyseBigrams() {
 			count1 += cnt
 		}
 	}
+	if an.countsRepeats() {
+		// Repeats do not depend on key positions, so ApplySwap keeps them as is
+		for _, r := range an.Layout.Runes {
+			if r != 0 {
+				count1 += an.Corpus.Bigrams[Bigram{r, r}]
+			}
+		}
+	}
 
-	for _, lsb := range an.Layout.LSBs {
+	for _, lsb := range an.lsbs() {
 		bi := Bigram{an.Layout.Runes[lsb.KeyIdx1], an.Layout.Runes[lsb.KeyIdx2]}
 		if cnt, ok := an.Corpus.Bigrams[bi]; ok {
 			count2 += cnt
@@ -344,11 +493,17 @@ func (an *Analyser) analyseBigrams() {
 		}
 	}
 
+	an.counts.bigrams = [4]uint64{count1, count2, count3, count4}
+	an.setBigramMetrics()
+}
+
+// setBigramMetrics computes the metrics of analyseBigrams from the bigram counts.
+func (an *Analyser) setBigramMetrics() {
 	factor := 100 / float64(an.Corpus.TotalBigramsCount)
-	an.Metrics["SFB"] = float64(count1) * factor
-	an.Metrics["LSB"] = float64(count2) * factor
-	an.Metrics["FSB"] = float64(count3) * factor
-	an.Metrics["HSB"] = float64(count4) * factor
+	an.Metrics["SFB"] = float64(an.counts.bigrams[0]) * factor
+	an.Metrics["LSB"] = float64(an.counts.bigrams[1]) * factor
+	an.Metrics["FSB"] = float64(an.counts.bigrams[2]) * factor
+	an.Metrics["HSB"] = float64(an.counts.bigrams[3]) * factor
 }
 
 // analyseSkipgrams computes skipgram-based metrics (same patterns as bigrams, but for skipgrams):
@@ -359,15 +514,26 @@ func (an *Analyser) analyseBigrams() {
 func (an *Analyser) analyseSkipgrams() {
 	var count1, count2, count3, count4 uint64
 
-	// SFS calculation using pre-computed cache
-	for _, sfb := range an.Layout.SFBs {
-		skp := Skipgram{an.Layout.Runes[sfb.KeyIdx1], an.Layout.Runes[sfb.KeyIdx2]}
-		if cnt, ok := an.Corpus.Skipgrams[skp]; ok {
-			count1 += cnt
+	// SFS calculation using pre-computed cache, or the trigrams if the key in between matters
+	if an.bridgedSFS() {
+		count1 = an.countBridgedSFS()
+	} else {
+		for _, sfb := range an.Layout.SFBs {
+			skp := Skipgram{an.Layout.Runes[sfb.KeyIdx1], an.Layout.Runes[sfb.KeyIdx2]}
+			if cnt, ok := an.Corpus.Skipgrams[skp]; ok {
+				count1 += cnt
+			}
+		}
+		if an.countsRepeats() {
+			for _, r := range an.Layout.Runes {
+				if r != 0 {
+					count1 += an.Corpus.Skipgrams[Skipgram{r, r}]
+				}
+			}
 		}
 	}
 
-	for _, lsb := range an.Layout.LSBs {
+	for _, lsb := range an.lsbs() {
 		skp := Skipgram{an.Layout.Runes[lsb.KeyIdx1], an.Layout.Runes[lsb.KeyIdx2]}
 		if cnt, ok := an.Corpus.Skipgrams[skp]; ok {
 			count2 += cnt
@@ -388,11 +554,22 @@ func (an *Analyser) analyseSkipgrams() {
 		}
 	}
 
+	an.counts.skipgrams = [4]uint64{count1, count2, count3, count4}
+	an.setSkipgramMetrics()
+}
+
+// setSkipgramMetrics computes the metrics of analyseSkipgrams from the skipgram counts.
+func (an *Analyser) setSkipgramMetrics() {
 	factor := 100 / float64(an.Corpus.TotalSkipgramsCount)
-	an.Metrics["SFS"] = float64(count1) * factor
-	an.Metrics["LSS"] = float64(count2) * factor
-	an.Metrics["FSS"] = float64(count3) * factor
-	an.Metrics["HSS"] = float64(count4) * factor
+	if an.bridgedSFS() {
+		// Counted from the trigrams
+		an.Metrics["SFS"] = 100 * float64(an.counts.skipgrams[0]) / float64(an.Corpus.TotalTrigramsCount)
+	} else {
+		an.Metrics["SFS"] = float64(an.counts.skipgrams[0]) * factor
+	}
+	an.Metrics["LSS"] = float64(an.counts.skipgrams[1]) * factor
+	an.Metrics["FSS"] = float64(an.counts.skipgrams[2]) * factor
+	an.Metrics["HSS"] = float64(an.counts.skipgrams[3]) * factor
 }
 
 // analyseTrigrams computes trigram-based flow metrics by categorizing each trigram:
@@ -403,108 +580,168 @@ func (an *Analyser) analyseSkipgrams() {
 //
 // Each category includes subcategories (e.g., RED-WEAK, ALT-SFS, 2RL-IN).
 func (an *Analyser) analyseTrigrams() {
-	var rl2SFB, rl2In, rl2Out, altSFS, altNml, rl3SFB, rl3In, rl3Out, redWeak, redSFS, redNml uint64
-
-	// Use pre-filtered trigrams if available (injected by Scorer), otherwise filter on-the-fly
-	trigrams := an.relevantTrigrams
-	if trigrams == nil {
-		// Fallback: pre-filter trigrams now (for non-Scorer callers)
-		trigrams = make([]TrigramInfo, 0, len(an.Corpus.Trigrams)/10)
-		for tri, cnt := range an.Corpus.Trigrams {
-			_, ok0 := an.Layout.GetKeyInfo(tri[0])
-			_, ok1 := an.Layout.GetKeyInfo(tri[1])
-			_, ok2 := an.Layout.GetKeyInfo(tri[2])
-			if ok0 && ok1 && ok2 {
-				trigrams = append(trigrams, TrigramInfo{
-					Count: cnt,
-					Runes: [3]rune{tri[0], tri[1], tri[2]},
-				})
-			}
-		}
+	an.counts.trigrams = [numTrigramClasses]uint64{}
+	an.counts.redSpan = 0
+	if an.trigramTable != nil {
+		an.trigramTable.classify(an.Layout, &an.counts)
+		an.setTrigramMetrics()
+		return
 	}
-
-	for _, ti := range trigrams {
-		cnt := ti.Count
+	for _, ti := range an.trigrams() {
 		// Look up fresh KeyInfo from current layout
 		k0, _ := an.Layout.GetKeyInfo(ti.Runes[0])
 		k1, _ := an.Layout.GetKeyInfo(ti.Runes[1])
 		k2, _ := an.Layout.GetKeyInfo(ti.Runes[2])
+		class := classifyTrigram(k0, k1, k2)
+		an.counts.trigrams[class] += ti.Count
+		an.counts.redSpan += ti.Count * redirectSpan(class, k0, k1, k2)
+	}
+	an.setTrigramMetrics()
+}
 
-		// Extract key properties for classification
-		h0, h1, h2 := k0.Hand, k1.Hand, k2.Hand
-		f0, f1, f2 := k0.Finger, k1.Finger, k2.Finger
+// trigrams returns the trigrams with all 3 runes on the layout. It uses the pre-filtered
+// trigrams if available (injected by Scorer), otherwise filters on-the-fly.
+func (an *Analyser) trigrams() []TrigramInfo {
+	if an.relevantTrigrams != nil {
+		return an.relevantTrigrams
+	}
 
-		// Classify trigram by hand pattern
-		switch h0 {
-		case h2:
-			if h0 != h1 { // Alternation (two hands alternate)
-				if f0 == f2 && k0.Index != k2.Index {
-					altSFS += cnt
-				} else {
-					altNml += cnt
-				}
-			} else { // One-hand trigram
-				switch {
-				case f0 == f1 || f1 == f2: // Contains same-finger (SFB/SFS)
-					rl3SFB += cnt
-				case (f0 < f1) == (f1 < f2): // Monotonic finger sequence (roll)
-					if (f0 < f1) == (h0 == LEFT) {
-						rl3In += cnt
-					} else {
-						rl3Out += cnt
-					}
-				default: // Non-monotonic (redirection)
-					if (f0 < LI || f0 > RI) &&
-						(f1 < LI || f1 > RI) &&
-						(f2 < LI || f2 > RI) {
-						redWeak += cnt
-					} else if f0 == f2 && k0.Index != k2.Index {
-						redSFS += cnt
-					} else {
-						redNml += cnt
-					}
-				}
-			}
-		case h1: // 2-roll with h0 == h1 (inlined for performance)
-			switch {
-			case f0 == f1: // Same finger
-				rl2SFB += cnt
-			case (f0 < f1) == (h1 == LEFT):
-				rl2In += cnt
-			default:
-				rl2Out += cnt
+	// Fallback: pre-filter trigrams now (for non-Scorer callers)
+	trigrams := make([]TrigramInfo, 0, len(an.Corpus.Trigrams)/10)
+	for tri, cnt := range an.Corpus.Trigrams {
+		_, ok0 := an.Layout.GetKeyInfo(tri[0])
+		_, ok1 := an.Layout.GetKeyInfo(tri[1])
+		_, ok2 := an.Layout.GetKeyInfo(tri[2])
+		if ok0 && ok1 && ok2 {
+			trigrams = append(trigrams, TrigramInfo{
+				Count: cnt,
+				Runes: [3]rune{tri[0], tri[1], tri[2]},
+			})
+		}
+	}
+	return trigrams
+}
+
+// Trigram classes counted by analyseTrigrams.
+const (
+	triALTSFS = iota
+	triALTNML
+	tri3RLSFB
+	tri3RLSFBSkip
+	tri3RLIn
+	tri3RLOut
+	triREDWeak
+	triREDSFS
+	triREDNml
+	tri2RLSFB
+	tri2RLIn
+	tri2RLOut
+	numTrigramClasses
+)
+
+// classifyTrigram returns the class of a trigram typed with the given keys.
+func classifyTrigram(k0, k1, k2 KeyInfo) int {
+	// Extract key properties for classification
+	h0, h1, h2 := k0.Hand, k1.Hand, k2.Hand
+	f0, f1, f2 := k0.Finger, k1.Finger, k2.Finger
+
+	// Classify trigram by hand pattern
+	switch h0 {
+	case h2:
+		if h0 != h1 { // Alternation (two hands alternate)
+			if f0 == f2 && k0.Index != k2.Index {
+				return triALTSFS
 			}
-		default: // 2-roll with h1 == h2 (inlined for performance)
-			switch {
-			case f1 == f2: // Same finger
-				rl2SFB += cnt
-			case (f1 < f2) == (h2 == LEFT):
-				rl2In += cnt
-			default:
-				rl2Out += cnt
+			return triALTNML
+		}
+		// One-hand trigram
+		switch {
+		case f0 == f1 && f1 == f2: // One finger, so also a same-finger skipgram
+			return tri3RLSFBSkip
+		case f0 == f1 || f1 == f2: // Contains a same-finger bigram of adjacent keys
+			return tri3RLSFB
+		case (f0 < f1) == (f1 < f2): // Monotonic finger sequence (roll)
+			if (f0 < f1) == (h0 == LEFT) {
+				return tri3RLIn
 			}
+			return tri3RLOut
+		}
+		// Non-monotonic (redirection)
+		switch {
+		case (f0 < LI || f0 > RI) && (f1 < LI || f1 > RI) && (f2 < LI || f2 > RI):
+			return triREDWeak
+		case f0 == f2 && k0.Index != k2.Index:
+			return triREDSFS
+		}
+		return triREDNml
+	case h1: // 2-roll with h0 == h1
+		switch {
+		case f0 == f1: // Same finger
+			return tri2RLSFB
+		case (f0 < f1) == (h1 == LEFT):
+			return tri2RLIn
 		}
+		return tri2RLOut
+	default: // 2-roll with h1 == h2
+		switch {
+		case f1 == f2: // Same finger
+			return tri2RLSFB
+		case (f1 < f2) == (h2 == LEFT):
+			return tri2RLIn
+		}
+		return tri2RLOut
+	}
+}
+
+// maxRedirectSeverity is the highest severity of a redirection (see redirectSpan): from the
+// pinky to the thumb and back, a span of 8 fingers.
+const maxRedirectSeverity = 4
+
+// redirectSpan returns the finger span of a trigram of the given class if it is a
+// redirection, or 0 otherwise: the number of fingers between the first and the second key,
+// plus those between the second and the third. The shortest redirections, such as
+// middle→index→middle, have a span of 2, and pinky→index→ring has a span of 5. RED-SEV
+// weights each redirection by half its span, its severity.
+func redirectSpan(class int, k0, k1, k2 KeyInfo) uint64 {
+	if class != triREDWeak && class != triREDSFS && class != triREDNml {
+		return 0
+	}
+	span := func(a, b uint8) uint64 {
+		if a > b {
+			return uint64(a - b)
+		}
+		return uint64(b - a)
 	}
+	return span(k0.Finger, k1.Finger) + span(k1.Finger, k2.Finger)
+}
 
+// setTrigramMetrics computes the metrics of analyseTrigrams from the trigram counts.
+func (an *Analyser) setTrigramMetrics() {
 	factor := 100 / float64(an.Corpus.TotalTrigramsCount)
+	pct := func(class int) float64 {
+		return float64(an.counts.trigrams[class]) * factor
+	}
 
-	an.Metrics["RED-WEAK"] = float64(redWeak) * factor
-	an.Metrics["RED-SFS"] = float64(redSFS) * factor
-	an.Metrics["RED-NML"] = float64(redNml) * factor
+	an.Metrics["RED-WEAK"] = pct(triREDWeak)
+	an.Metrics["RED-SFS"] = pct(triREDSFS)
+	an.Metrics["RED-NML"] = pct(triREDNml)
 	an.Metrics["RED"] = an.Metrics["RED-NML"] + an.Metrics["RED-SFS"] + an.Metrics["RED-WEAK"]
+	an.Metrics["RED-SEV"] = float64(an.counts.redSpan) / 2 * factor
 
-	an.Metrics["ALT-SFS"] = float64(altSFS) * factor
-	an.Metrics["ALT-NML"] = float64(altNml) * factor
+	an.Metrics["ALT-SFS"] = pct(triALTSFS)
+	an.Metrics["ALT-NML"] = pct(triALTNML)
 	an.Metrics["ALT"] = an.Metrics["ALT-NML"] + an.Metrics["ALT-SFS"]
 
-	an.Metrics["2RL-SFB"] = float64(rl2SFB) * factor
-	an.Metrics["2RL-IN"] = float64(rl2In) * factor
-	an.Metrics["2RL-OUT"] = float64(rl2Out) * factor
+	an.Metrics["2RL-SFB"] = pct(tri2RLSFB)
+	an.Metrics["2RL-IN"] = pct(tri2RLIn)
+	an.Metrics["2RL-OUT"] = pct(tri2RLOut)
 	an.Metrics["2RL"] = an.Metrics["2RL-SFB"] + an.Metrics["2RL-IN"] + an.Metrics["2RL-OUT"]
 
-	an.Metrics["3RL-SFB"] = float64(rl3SFB) * factor
-	an.Metrics["3RL-IN"] = float64(rl3In) * factor
-	an.Metrics["3RL-OUT"] = float64(rl3Out) * factor
+	an.Metrics["3RL-SFB-ADJ"] = pct(tri3RLSFB)
+	an.Metrics["3RL-SFB-SKP"] = pct(tri3RLSFBSkip)
+	an.Metrics["3RL-SFB"] = an.Metrics["3RL-SFB-ADJ"] + an.Metrics["3RL-SFB-SKP"]
+	an.Metrics["3RL-IN"] = pct(tri3RLIn)
+	an.Metrics["3RL-OUT"] = pct(tri3RLOut)
 	an.Metrics["3RL"] = an.Metrics["3RL-SFB"] + an.Metrics["3RL-IN"] + an.Metrics["3RL-OUT"]
 
 	an.Metrics["FLW"] = an.Metrics["2RL-IN"] + an.Metrics["2RL-OUT"] + an.Metrics["3RL-IN"] + an.Metrics["3RL-OUT"] + an.Metrics["ALT-NML"]
@@ -534,7 +771,7 @@ func (an *Analyser) AllMetricsDetails() []*MetricDetails {
 // (ALT, SFB, LSB, FSB, HSB, or regular). Useful for understanding corpus characteristics.
 func (an *Analyser) AllCorpusDetails(nRows int) []*MetricDetails {
 	lsbLookup := make(map[[2]uint8]bool, len(an.Layout.LSBs))
-	for _, lsb := range an.Layout.LSBs {
+	for _, lsb := range an.lsbs() {
 		lsbLookup[[2]uint8{lsb.KeyIdx1, lsb.KeyIdx2}] = true
 	}
 
@@ -596,18 +833,27 @@ func (an *Analyser) AllCorpusDetails(nRows int) []*MetricDetails {
 	return []*MetricDetails{ma}
 }
 
+// sameFingerDistance returns the distance between two keys typed by the same finger, which
+// is zero for a repeat of one key.
+func (an *Analyser) sameFingerDistance(k1, k2 uint8) *KeyPairDistance {
+	if k1 == k2 {
+		return &KeyPairDistance{}
+	}
+	return an.Layout.MustDistance(k1, k2)
+}
+
 // SFBiDetails performs detailed Same Finger Bigram (SFB) analysis.
-// Identifies bigrams typed with the same finger on different keys, reporting frequency,
-// distance, hand, finger, and row distance for each.
+// Identifies bigrams typed with the same finger on different keys (and same-key repeats in
+// metric version 1), reporting frequency, distance, hand, finger, and row distance for each.
 func (an *Analyser) SFBiDetails() *MetricDetails {
 	ma := &MetricDetails{
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalBigramsCount,
 		Metric:       "SFB",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		Unsupported:  make(map[string]uint64),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 
 	for bi, biCnt := range an.Corpus.Bigrams {
@@ -616,14 +862,14 @@ func (an *Analyser) SFBiDetails() *MetricDetails {
 		key2, ok2 := an.Layout.GetKeyInfo(bi[1])
 
 		if !ok1 || !ok2 {
-			// ma.Unsupported[biStr] += biCnt
+			ma.Unsupported[biStr] += biCnt
 			continue
 		}
 
-		if key1.Finger == key2.Finger && key1.Index != key2.Index {
+		if key1.Finger == key2.Finger && (key1.Index != key2.Index || an.countsRepeats()) {
 			ma.NGramCount[biStr] = biCnt
 			ma.TotalNGrams += biCnt
-			kpDist := an.Layout.MustDistance(key1.Index, key2.Index)
+			kpDist := an.sameFingerDistance(key1.Index, key2.Index)
 			ma.NGramDist[biStr] = kpDist.Distance
 			ma.TotalDist += kpDist.Distance * float64(biCnt)
 
@@ -646,13 +892,12 @@ func (an *Analyser) LSBiDetails() *MetricDetails {
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalBigramsCount,
 		Metric:       "LSB",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 
-	for _, lsb := range an.Layout.LSBs {
+	for _, lsb := range an.lsbs() {
 		rune1 := an.Layout.Runes[lsb.KeyIdx1]
 		bi := Bigram{rune1, an.Layout.Runes[lsb.KeyIdx2]}
 		if biCnt, ok := an.Corpus.Bigrams[bi]; ok {
@@ -684,19 +929,17 @@ func (an *Analyser) ScissBiDetails() (*MetricDetails, *MetricDetails) {
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalBigramsCount,
 		Metric:       "FSB",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 	ma2 := &MetricDetails{
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalBigramsCount,
 		Metric:       "HSB",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 
 	for _, sci := range an.Layout.FScissors {
@@ -753,10 +996,15 @@ func (an *Analyser) SFSkpDetails() *MetricDetails {
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalSkipgramsCount,
 		Metric:       "SFS",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		Unsupported:  make(map[string]uint64),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
+	}
+
+	if an.bridgedSFS() {
+		an.bridgedSFSDetails(ma)
+		return ma
 	}
 
 	for skp, skpCnt := range an.Corpus.Skipgrams {
@@ -765,14 +1013,14 @@ func (an *Analyser) SFSkpDetails() *MetricDetails {
 		key2, ok2 := an.Layout.GetKeyInfo(skp[1])
 
 		if !ok1 || !ok2 {
-			// ma.Unsupported[skpStr] += skpCnt
+			ma.Unsupported[skpStr] += skpCnt
 			continue
 		}
 
-		if key1.Finger == key2.Finger && key1.Index != key2.Index {
+		if key1.Finger == key2.Finger && (key1.Index != key2.Index || an.countsRepeats()) {
 			ma.NGramCount[skpStr] = skpCnt
 			ma.TotalNGrams += skpCnt
-			kpDist := an.Layout.MustDistance(key1.Index, key2.Index)
+			kpDist := an.sameFingerDistance(key1.Index, key2.Index)
 			ma.NGramDist[skpStr] = kpDist.Distance
 			ma.TotalDist += kpDist.Distance * float64(skpCnt)
 
@@ -795,13 +1043,12 @@ func (an *Analyser) LSSkpDetails() *MetricDetails {
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalSkipgramsCount,
 		Metric:       "LSS",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 
-	for _, lsb := range an.Layout.LSBs {
+	for _, lsb := range an.lsbs() {
 		rune1 := an.Layout.Runes[lsb.KeyIdx1]
 		skp := Skipgram{rune1, an.Layout.Runes[lsb.KeyIdx2]}
 		if skpCnt, ok := an.Corpus.Skipgrams[skp]; ok {
@@ -832,19 +1079,17 @@ func (an *Analyser) ScissSkpDetails() (*MetricDetails, *MetricDetails) {
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalSkipgramsCount,
 		Metric:       "FSS",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 	ma2 := &MetricDetails{
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalSkipgramsCount,
 		Metric:       "HSS",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 
 	for _, sci := range an.Layout.FScissors {
@@ -900,7 +1145,7 @@ func (an *Analyser) ScissSkpDetails() (*MetricDetails, *MetricDetails) {
 //   - RED: Redirections, with subcategories (NML, SFS, WEAK)
 //   - ALT: Alternations (hand switches), with subcategories ALT-NML and ALT-SFS
 //   - 2RL: Two-key rolls, with directions (IN, OUT, SFB)
-//   - 3RL: Three-key rolls, with directions (IN, OUT, SFB)
+//   - 3RL: Three-key rolls, with directions (IN, OUT, SFB-ADJ, SFB-SKP)
 //
 // Returns four MetricDetails, one for each category.
 func (an *Analyser) TrigramDetails() (*MetricDetails, *MetricDetails, *MetricDetails, *MetricDetails) {
@@ -908,37 +1153,34 @@ func (an *Analyser) TrigramDetails() (*MetricDetails, *MetricDetails, *MetricDet
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalTrigramsCount,
 		Metric:       "ALT",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		Unsupported:  make(map[string]uint64),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 	rl2 := &MetricDetails{
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalTrigramsCount,
 		Metric:       "2RL",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 	rl3 := &MetricDetails{
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalTrigramsCount,
 		Metric:       "3RL",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 	red := &MetricDetails{
 		Corpus:       an.Corpus,
 		CorpusNGramC: an.Corpus.TotalTrigramsCount,
 		Metric:       "RED",
-		// Unsupported:  make(map[string]uint64),
-		NGramCount: make(map[string]uint64),
-		NGramDist:  make(map[string]float64),
-		Custom:     make(map[string]map[string]any),
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
 	}
 
 	for tri, cnt := range an.Corpus.Trigrams {
@@ -947,7 +1189,7 @@ func (an *Analyser) TrigramDetails() (*MetricDetails, *MetricDetails, *MetricDet
 		r1, ok1 := an.Layout.GetKeyInfo(tri[1])
 		r2, ok2 := an.Layout.GetKeyInfo(tri[2])
 		if !ok0 || !ok1 || !ok2 {
-			// alt.Unsupported[triStr] += cnt
+			alt.Unsupported[triStr] += cnt
 			continue
 		}
 
@@ -998,7 +1240,11 @@ func (an *Analyser) TrigramDetails() (*MetricDetails, *MetricDetails, *MetricDet
 					if _, ok := rl3.Custom[triStr]; !ok {
 						rl3.Custom[triStr] = make(map[string]any)
 					}
-					rl3.Custom[triStr]["Dir"] = "SFB"
+					if f0 == f2 {
+						rl3.Custom[triStr]["Dir"] = "SFB-SKP"
+					} else {
+						rl3.Custom[triStr]["Dir"] = "SFB-ADJ"
+					}
 				} else if (f0 < f1) == (f1 < f2) {
 					rl3.NGramCount[triStr] = cnt
 					rl3.TotalNGrams += cnt
@@ -1025,6 +1271,7 @@ func (an *Analyser) TrigramDetails() (*MetricDetails, *MetricDetails, *MetricDet
 					} else {
 						red.Custom[triStr]["Dir"] = "NML"
 					}
+					red.Custom[triStr]["Sev"] = float64(redirectSpan(triREDNml, r0, r1, r2)) / 2
 				}
 			}
 		case h1:
@@ -148,7 +148,7 @@ func BenchmarkAnalyserWithScorer(b *testing.B) {
 	}
 
 	// Create Scorer (pre-filters trigrams once)
-	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights)
+	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights, nil)
 	if err != nil {
 		b.Fatalf("Failed to create scorer: %v", err)
 	}
@@ -313,7 +313,7 @@ func BenchmarkAnalyserTrigramsWithCache(b *testing.B) {
 		b.Fatalf("Failed to load weights: %v", err)
 	}
 
-	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights)
+	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights, nil)
 	if err != nil {
 		b.Fatalf("Failed to create scorer: %v", err)
 	}
@@ -332,6 +332,38 @@ func BenchmarkAnalyserTrigramsWithCache(b *testing.B) {
 	}
 }
 
+// BenchmarkAnalyserTrigramsWithTable benchmarks trigram analysis with the pre-filtered cache
+// translated to rune indices, as injected by Scorer.
+//
+// Run with:
+//
+//	go test -bench=BenchmarkAnalyserTrigramsWithTable -benchmem ./internal/keycraft
+func BenchmarkAnalyserTrigramsWithTable(b *testing.B) {
+	corpus, err := NewCorpusFromFile("default", "../../data/corpus/default.txt", false, 98.0)
+	if err != nil {
+		b.Fatalf("Failed to load corpus: %v", err)
+	}
+
+	layout, err := NewLayoutFromFile("qwerty", "../../data/layouts/qwerty.klf")
+	if err != nil {
+		b.Fatalf("Failed to load layout: %v", err)
+	}
+
+	scorer := NewScorerWithStats(corpus, NewTargetLoads(), nil, nil, nil)
+	scorer.prepareTrigramCache(layout)
+
+	for b.Loop() {
+		a := &Analyser{
+			Layout:           layout,
+			Corpus:           corpus,
+			Metrics:          make(map[string]float64),
+			relevantTrigrams: scorer.trigramCache,
+			trigramTable:     scorer.trigramTable,
+		}
+		a.analyseTrigrams()
+	}
+}
+
 // BenchmarkAnalyserWithScorerAllCached benchmarks full analysis with all n-gram caches enabled.
 //
 // Run with:
@@ -360,7 +392,7 @@ func BenchmarkAnalyserWithScorerAllCached(b *testing.B) {
 		b.Fatalf("Failed to load weights: %v", err)
 	}
 
-	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights)
+	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights, nil)
 	if err != nil {
 		b.Fatalf("Failed to create scorer: %v", err)
 	}
@@ -402,7 +434,7 @@ func BenchmarkAnalyserWithScorerNoCaches(b *testing.B) {
 		b.Fatalf("Failed to load weights: %v", err)
 	}
 
-	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights)
+	scorer, err := NewScorer("../../data/layouts", corpus, targets, weights, nil)
 	if err != nil {
 		b.Fatalf("Failed to create scorer: %v", err)
 	}
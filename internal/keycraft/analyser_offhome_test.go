@@ -0,0 +1,42 @@
+package keycraft
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// TestOffHome_Calculation checks that ROH, MOH and IOH weigh the characters typed by the
+// ring, middle and index fingers with their penalties, and that the index finger keys are
+// weighed by whether they are in the home column of the finger.
+func TestOffHome_Calculation(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	corpus := NewCorpus("test")
+	// w, s, x: left ring top, home and bottom; d, e: left middle home and top;
+	// t, f: left index top-inner and home-outer
+	corpus.addTextWithWords("wwsxdetf")
+
+	metrics := NewAnalyser(layout, corpus, NewTargetLoads()).Metrics
+	for metric, want := range map[string]float64{
+		"ROH": 100 * (1.5 + 1.5 + 0 + 1.5) / 8,
+		"MOH": 100 * (0 + 1.0) / 8,
+		"IOH": 100 * (1.5 + 0) / 8,
+	} {
+		if math.Abs(metrics[metric]-want) > 1e-9 {
+			t.Errorf("%s = %.4f, want %.4f", metric, metrics[metric], want)
+		}
+	}
+
+	// On angle-mod, c is typed by the left index finger, off its home column
+	angleMod := Must(NewLayoutFromFile("b", filepath.Join(writeTestLayouts(t, map[string]string{
+		"b.klf": "anglemod" + testLayoutKlf[len("rowstag"):],
+	}), "b.klf")))
+	corpus = NewCorpus("test")
+	corpus.addTextWithWords("cv")
+	targets := NewTargetLoads()
+	Must0(targets.SetIndexPenalties("0, 0, 0, 0, 1, 3"))
+	if got, want := NewAnalyser(angleMod, corpus, targets).Metrics["IOH"], 100*(3.0+1.0)/2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("IOH on angle-mod = %.4f, want %.4f", got, want)
+	}
+}
@@ -1,11 +1,13 @@
 package keycraft
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,10 +24,19 @@ type BLSParams struct {
 
 	// Perturbation distribution (probabilities for non-directed perturbations, should sum to 1.0)
 
-	PatternWeight float64 // Weight for pattern-guided perturbation (targets bad patterns)
-	ColumnWeight  float64 // Weight for column swaps (structural moves)
-	RandomWeight  float64 // Weight for random perturbation (strong diversification)
-	RecencyWeight float64 // Weight for recency-based perturbation (history-based)
+	PatternWeight  float64 // Weight for pattern-guided perturbation (targets bad patterns)
+	ColumnWeight   float64 // Weight for column swaps (structural moves)
+	RandomWeight   float64 // Weight for random perturbation (strong diversification)
+	RecencyWeight  float64 // Weight for recency-based perturbation (history-based)
+	CycleWeight    float64 // Weight for 3-cycles (moves three keys at once)
+	RotationWeight float64 // Weight for rotating the free keys of a row or column by one position
+
+	Perturbations []WeightedPerturbation // Custom perturbation strategies with their weights (see AddPerturbation)
+
+	// Compound moves in steepest descent
+
+	CompoundDescent    bool // At a local optimum of pairwise swaps, look for an improving 3-cycle
+	CompoundCandidates int  // Number of least harmful swaps extended to 3-cycles
 
 	// Pattern analysis parameters
 
@@ -42,6 +53,8 @@ type BLSParams struct {
 
 	UseParallel     bool // Enable parallel evaluation in steepest descent
 	ParallelWorkers int  // Number of parallel workers (0 = use runtime.NumCPU())
+	UseSwapCache    bool // Reuse per-pair deltas between descent rounds
+	UseSwapBounds   bool // Skip the trigram analysis of swaps that cannot beat the best swap so far
 }
 
 // DefaultBLSParams returns recommended BLS parameters for keyboard layout optimization.
@@ -62,6 +75,9 @@ func DefaultBLSParams(numFreeKeys int) BLSParams {
 		RandomWeight:  0.40,
 		RecencyWeight: 0.20,
 
+		// Compound moves (disabled by default, see EnableCompoundMoves)
+		CompoundCandidates: 5,
+
 		// Pattern analysis
 		TopKProblematic: 8,
 
@@ -74,9 +90,22 @@ func DefaultBLSParams(numFreeKeys int) BLSParams {
 		// Parallelism control
 		UseParallel:     true, // Disabled by default
 		ParallelWorkers: 4,    // Use runtime.NumCPU() when enabled
+		UseSwapCache:    true,
+		UseSwapBounds:   true,
 	}
 }
 
+// EnableCompoundMoves adds 3-cycles and row/column rotations to the perturbations,
+// taking their share from random perturbation, and makes steepest descent look for
+// improving 3-cycles at every local optimum of pairwise swaps. Some improvements cannot
+// be reached by any sequence of improving swaps, only by moving three or more keys at once.
+func (p *BLSParams) EnableCompoundMoves() {
+	p.CycleWeight = 0.10
+	p.RotationWeight = 0.05
+	p.RandomWeight = max(0, p.RandomWeight-p.CycleWeight-p.RotationWeight)
+	p.CompoundDescent = true
+}
+
 // PerturbationType identifies the type of perturbation to apply.
 type PerturbationType int
 
@@ -86,6 +115,9 @@ const (
 	ColumnPerturb                                // Swaps entire columns
 	RecencyPerturb                               // Selects least recently used swaps
 	RandomPerturb                                // Completely random swap selection
+	CyclePerturb                                 // Moves three keys in a cycle
+	RotationPerturb                              // Rotates the free keys of a row or column
+	CustomPerturb                                // Applies a custom strategy (see PerturbationStrategy)
 )
 
 // String returns the name of the perturbation type.
@@ -101,6 +133,12 @@ func (pt PerturbationType) String() string {
 		return "Recency"
 	case RandomPerturb:
 		return "Random"
+	case CyclePerturb:
+		return "Cycle"
+	case RotationPerturb:
+		return "Rotation"
+	case CustomPerturb:
+		return "Custom"
 	default:
 		return "Unknown"
 	}
@@ -108,14 +146,17 @@ func (pt PerturbationType) String() string {
 
 // BLSState tracks the current state of the search algorithm.
 type BLSState struct {
-	omega       int          // Consecutive non-improving local optima visited
-	L           int          // Current jump magnitude (number of perturbation swaps)
-	lastOptCost float64      // Cost of the previous local optimum
-	tabuMatrix  [][]int      // tabuMatrix[i][j] = iteration when swap(i,j) was last performed
-	iteration   int          // Global iteration counter
-	bestCost    float64      // Best cost found so far
-	bestLayout  *SplitLayout // Best layout found
-	startTime   time.Time    // Start time of optimization
+	omega       int               // Consecutive non-improving local optima visited
+	L           int               // Current jump magnitude (number of perturbation swaps)
+	lastOptCost float64           // Cost of the previous local optimum
+	tabuMatrix  [][]int           // tabuMatrix[i][j] = iteration when swap(i,j) was last performed
+	iteration   int               // Global iteration counter
+	bestCost    float64           // Best cost found so far
+	bestLayout  *SplitLayout      // Best layout found
+	trajectory  []TrajectoryPoint // Cost of every new best layout, starting with the input layout
+	startTime   time.Time         // Start time of optimization
+	interrupted bool              // Whether the search was cancelled before it finished
+	done        bool              // Whether the search has ended
 }
 
 // BLS implements the Breakout Local Search algorithm for keyboard layout optimization.
@@ -124,11 +165,23 @@ type BLS struct {
 	state      BLSState
 	scorer     *Scorer
 	corpus     *Corpus
-	pinned     *PinnedKeys // Flags indicating which keys are pinned (cannot be swapped)
+	pinned     *PinnedKeys    // Flags indicating which keys are pinned (cannot be swapped)
+	classes    *[42]RuneClass // Rune classes of the positions, if swaps are restricted to a class (see RestrictSwapClasses)
 	rng        *rand.Rand
-	numFree    int        // Number of free (non-pinned) keys
-	validPairs [][2]uint8 // Pre-calculated valid key pairs (excludes pinned keys)
-	logger     *BLSLogger // Logger for dual output (can be nil)
+	moveRng    *LockedSource    // Random source of the custom perturbations (see PerturbationStrategy)
+	numFree    int              // Number of free (non-pinned) keys
+	validPairs [][2]uint8       // Pre-calculated valid key pairs (excludes pinned keys)
+	logger     *BLSLogger       // Logger for dual output (can be nil)
+	ctx        context.Context  // Cancels the search (set by Optimize)
+	swapCache  *SwapCache       // Per-pair delta cache for steepest descent (nil if disabled)
+	history    *HistoryRecorder // Records every new-best layout (can be nil)
+
+	// Progress reporting (see SetProgressFunc)
+	progress       func(BLSProgress)
+	progressLayout *SplitLayout       // Best layout of the last report
+	progressShown  *SplitLayout       // progressLayout without the placeholders of spill-over keys
+	progressCosts  map[string]float64 // Metric costs of progressLayout
+	perturbations  map[string]int     // Number of perturbation moves of each type
 
 	// Pre-filtered bigrams for pattern analysis (computed per layout in Optimize())
 	relevantBigrams []BigramCount // Only bigrams with both chars on layout, sorted by frequency
@@ -165,15 +218,55 @@ func NewBLS(params BLSParams, scorer *Scorer, corpus *Corpus, pinned *PinnedKeys
 		}
 	}
 
+	var swapCache *SwapCache
+	if params.UseSwapCache {
+		swapCache = NewSwapCache()
+	}
+
 	return &BLS{
 		params:     params,
 		scorer:     scorer,
 		corpus:     corpus,
 		pinned:     pinned,
 		rng:        rand.New(rand.NewSource(params.Seed)),
+		moveRng:    NewLockedRNG(uint64(DeriveSeed(params.Seed, 0)), 0),
 		numFree:    numFree,
 		validPairs: validPairs,
+		swapCache:  swapCache,
+
+		perturbations: make(map[string]int),
+	}
+}
+
+// SetHistoryRecorder enables recording of every accepted new-best layout,
+// including the initial layout. Use nil to disable recording.
+func (bls *BLS) SetHistoryRecorder(h *HistoryRecorder) {
+	bls.history = h
+}
+
+// recordHistory adds the cost of the current best layout to the trajectory, and records the
+// layout if a history recorder is set. Write errors are ignored so that a broken history file
+// cannot abort a long run.
+func (bls *BLS) recordHistory() {
+	bls.state.trajectory = append(bls.state.trajectory, TrajectoryPoint{
+		Iteration: bls.state.iteration,
+		Elapsed:   time.Since(bls.state.startTime),
+		Cost:      bls.state.bestCost,
+	})
+	if bls.history == nil {
+		return
 	}
+	_ = bls.history.Record(bls.state.iteration, bls.state.bestCost, bls.state.bestLayout,
+		time.Since(bls.state.startTime))
+}
+
+// SwapCacheStats returns the hit and miss counts of the descent delta cache.
+// Both are zero when the cache is disabled.
+func (bls *BLS) SwapCacheStats() (hits, misses uint64) {
+	if bls.swapCache == nil {
+		return 0, 0
+	}
+	return bls.swapCache.Stats()
 }
 
 // prefilterBigrams filters and sorts corpus bigrams that are relevant to the given layout.
@@ -200,9 +293,12 @@ func (bls *BLS) prefilterBigrams(layout *SplitLayout) {
 
 // Optimize runs the BLS algorithm on the given layout and returns the best layout found.
 // Progress can optionally be reported to the provided logger (use nil to disable all logging).
-func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
-	// Store logger for use in descent methods
+// When ctx is cancelled, the search stops at the next round of steepest descent and the best
+// layout found so far is returned; Interrupted then reports true.
+func (bls *BLS) Optimize(ctx context.Context, layout *SplitLayout, logger *BLSLogger) *SplitLayout {
+	// Store logger and context for use in descent methods
 	bls.logger = logger
+	bls.ctx = ctx
 
 	// Pre-filter and sort bigrams for pattern analysis
 	bls.prefilterBigrams(layout)
@@ -219,12 +315,14 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 	for i := range bls.state.tabuMatrix {
 		bls.state.tabuMatrix[i] = make([]int, 42)
 	}
+	clear(bls.perturbations)
 
 	// Make a working copy of the layout
 	current := layout.Clone()
 
-	// Compute initial cost
-	bls.state.bestCost = bls.scorer.Score(current)
+	// Compute initial cost. Best costs are always against the full corpus, also when the
+	// scorer subsamples it.
+	bls.state.bestCost = bls.scorer.ScoreExact(current)
 	bls.state.bestLayout = current.Clone()
 	bls.state.lastOptCost = bls.state.bestCost
 
@@ -232,11 +330,20 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 		logger.LogStart(bls.params, layout, bls.numFree)
 		logger.LogInitialCost(bls.state.bestCost)
 	}
+	bls.recordHistory()
+	bls.reportProgress(bls.state.bestCost)
 
 	// Main optimization loop
 	for bls.state.iteration < bls.params.MaxIterations {
-		// Check time limit
+		// Check for cancellation and the time limit
 		elapsed := time.Since(bls.state.startTime)
+		if bls.cancelled() {
+			bls.state.interrupted = true
+			if logger != nil {
+				logger.LogInterrupted(elapsed)
+			}
+			break
+		}
 		if elapsed >= bls.params.MaxTime {
 			if logger != nil {
 				logger.LogTimeLimit(elapsed)
@@ -244,11 +351,14 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 			break
 		}
 
+		// Score this iteration against a new corpus sample (if subsampling)
+		bls.scorer.Resample()
+
 		// Phase 1: Steepest Descent to local optimum
 		bls.steepestDescent(current)
 
 		// Compute cost of local optimum
-		currentCost := bls.scorer.Score(current)
+		currentCost := bls.scorer.ScoreExact(current)
 		bls.state.iteration++
 
 		// Check if we improved
@@ -262,6 +372,7 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 				logger.LogImprovement(bls.state.iteration, bls.state.bestCost, prevBest,
 					current, time.Since(bls.state.startTime))
 			}
+			bls.recordHistory()
 		} else if math.Abs(currentCost-bls.state.lastOptCost) > 1e-9 {
 			// Escaped to a different local optimum (but not better)
 			bls.state.omega++
@@ -285,6 +396,11 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 			bls.state.L = bls.params.L0
 		}
 
+		// A cancelled descent stops short of a local optimum, so do not perturb it
+		if bls.cancelled() {
+			continue
+		}
+
 		// Phase 2: Perturbation
 		bls.state.lastOptCost = currentCost
 		bls.perturb(current, bls.state.L)
@@ -295,8 +411,12 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 			logger.LogProgress(bls.state.iteration, currentCost, bls.state.bestCost,
 				bls.state.L, bls.state.omega)
 		}
+		bls.reportProgress(currentCost)
 	}
 
+	bls.state.done = true
+	bls.reportProgress(bls.state.lastOptCost)
+
 	if logger != nil {
 		elapsed := time.Since(bls.state.startTime)
 		logger.LogEnd(bls.state.bestCost, bls.state.iteration, elapsed, bls.state.bestLayout)
@@ -306,14 +426,37 @@ func (bls *BLS) Optimize(layout *SplitLayout, logger *BLSLogger) *SplitLayout {
 	return bls.state.bestLayout
 }
 
+// Interrupted reports whether the last call to Optimize was cancelled before it finished.
+func (bls *BLS) Interrupted() bool {
+	return bls.state.interrupted
+}
+
+// Trajectory returns the iteration and cost of every new best layout of the last search,
+// starting with the input layout.
+func (bls *BLS) Trajectory() []TrajectoryPoint {
+	return bls.state.trajectory
+}
+
+// cancelled reports whether the context of the running search has been cancelled.
+func (bls *BLS) cancelled() bool {
+	return bls.ctx != nil && bls.ctx.Err() != nil
+}
+
 // steepestDescent performs local search until a local optimum is reached.
 // Uses best-improvement strategy: evaluates all valid swaps and applies the best one.
 // Dispatches to parallel or sequential implementation based on params.
+// With compound descent enabled, an improving 3-cycle found at the local optimum is
+// applied and the descent continues from there.
 func (bls *BLS) steepestDescent(layout *SplitLayout) {
-	if bls.params.UseParallel {
-		bls.steepestDescentParallel(layout)
-	} else {
-		bls.steepestDescentSequential(layout)
+	for {
+		if bls.params.UseParallel {
+			bls.steepestDescentParallel(layout)
+		} else {
+			bls.steepestDescentSequential(layout)
+		}
+		if !bls.params.CompoundDescent || bls.cancelled() || !bls.applyImprovingCycle(layout) {
+			return
+		}
 	}
 }
 
@@ -322,23 +465,33 @@ func (bls *BLS) steepestDescentSequential(layout *SplitLayout) {
 	improved := true
 	swapCount := 0
 	startCost := bls.scorer.Score(layout)
+	cache := bls.swapCache
+	if cache != nil {
+		// The layout may have been perturbed since the previous descent
+		cache.Reset()
+	}
 
-	for improved {
+	for improved && !bls.cancelled() {
 		improved = false
 		bestDelta := 0.0
 		var bestI, bestJ uint8
+		usedCache := false
 
 		// Evaluate all possible swaps using pre-calculated pairs
 		costBefore := bls.scorer.Score(layout)
+		bounds := bls.swapBounds(layout)
 		for _, pair := range bls.validPairs {
 			i, j := pair[0], pair[1]
+			if bls.scorer.introducesTaboo(layout, i, j) {
+				continue // Never accept a swap that types a taboo bigram as an SFB or scissor
+			}
 
-			// Compute delta by scoring after swap
-			layout.Swap(i, j)
-			costAfter := bls.scorer.Score(layout)
-			layout.Swap(i, j) // Swap back
-
-			delta := costAfter - costBefore
+			delta, cached := bls.cachedDelta(i, j)
+			if cached {
+				usedCache = true
+			} else {
+				delta = bls.boundedSwapDelta(layout, bounds, i, j, costBefore, bestDelta)
+			}
 
 			if delta < bestDelta {
 				bestDelta = delta
@@ -349,6 +502,11 @@ func (bls *BLS) steepestDescentSequential(layout *SplitLayout) {
 		}
 
 		if improved {
+			if !bls.verifySwap(layout, bestI, bestJ, costBefore) {
+				// Stale cached delta; it has been refreshed, so re-run the round
+				continue
+			}
+
 			// Apply best swap
 			layout.Swap(bestI, bestJ)
 			swapCount++
@@ -357,6 +515,10 @@ func (bls *BLS) steepestDescentSequential(layout *SplitLayout) {
 			bls.state.tabuMatrix[bestI][bestJ] = bls.state.iteration
 			bls.state.tabuMatrix[bestJ][bestI] = bls.state.iteration
 			bls.state.iteration++
+		} else if usedCache {
+			// Confirm the local optimum with a fresh evaluation of all pairs
+			cache.Reset()
+			improved = true
 		}
 	}
 
@@ -367,6 +529,73 @@ func (bls *BLS) steepestDescentSequential(layout *SplitLayout) {
 	}
 }
 
+// cachedDelta returns the cached delta for swap (i, j), if the swap cache is enabled
+// and holds an entry for the pair.
+func (bls *BLS) cachedDelta(i, j uint8) (float64, bool) {
+	if bls.swapCache == nil {
+		return 0, false
+	}
+	return bls.swapCache.Get(i, j)
+}
+
+// swapDelta scores swap (i, j) on the layout, restores the layout, and records the
+// resulting delta in the swap cache (if enabled).
+func (bls *BLS) swapDelta(layout *SplitLayout, i, j uint8, costBefore float64) float64 {
+	layout.Swap(i, j)
+	costAfter := bls.scorer.Score(layout)
+	layout.Swap(i, j) // Swap back
+
+	delta := costAfter - costBefore
+	if bls.swapCache != nil {
+		bls.swapCache.Put(i, j, delta)
+	}
+	return delta
+}
+
+// swapBounds returns the bounds for ruling out swaps of the layout early, or nil if they
+// are disabled or the scorer cannot bound its costs.
+func (bls *BLS) swapBounds(layout *SplitLayout) *SwapBounds {
+	if !bls.params.UseSwapBounds {
+		return nil
+	}
+	return bls.scorer.NewSwapBounds(layout)
+}
+
+// boundedSwapDelta is swapDelta for finding the best swap of a descent round: a swap that
+// cannot improve on bestDelta is ruled out early, and a lower bound of its delta is returned
+// instead, without recording it in the swap cache.
+func (bls *BLS) boundedSwapDelta(layout *SplitLayout, bounds *SwapBounds, i, j uint8,
+	costBefore, bestDelta float64) float64 {
+	if bounds == nil {
+		return bls.swapDelta(layout, i, j, costBefore)
+	}
+	layout.Swap(i, j)
+	costAfter, exact := bls.scorer.ScoreSwap(layout, bounds, i, j, costBefore+bestDelta)
+	layout.Swap(i, j) // Swap back
+
+	delta := costAfter - costBefore
+	if exact && bls.swapCache != nil {
+		bls.swapCache.Put(i, j, delta)
+	}
+	return delta
+}
+
+// verifySwap checks that the selected swap (i, j) still improves the layout before it
+// is applied. Without a swap cache every delta is fresh and the swap is accepted as is.
+// With a swap cache the delta is recomputed; if it no longer improves the layout, the
+// refreshed delta is stored and false is returned. On success, cached deltas of pairs
+// touching i or j are invalidated.
+func (bls *BLS) verifySwap(layout *SplitLayout, i, j uint8, costBefore float64) bool {
+	if bls.swapCache == nil {
+		return true
+	}
+	if bls.swapDelta(layout, i, j, costBefore) >= 0 {
+		return false
+	}
+	bls.swapCache.Invalidate(i, j)
+	return true
+}
+
 // swapResult holds the result of evaluating a single swap.
 type swapResult struct {
 	i     uint8
@@ -380,18 +609,26 @@ func (bls *BLS) steepestDescentParallel(layout *SplitLayout) {
 	swapCount := 0
 	startCost := bls.scorer.Score(layout)
 
+	cache := bls.swapCache
+	if cache != nil {
+		// The layout may have been perturbed since the previous descent
+		cache.Reset()
+	}
+
 	// Determine number of workers
 	numWorkers := bls.params.ParallelWorkers
 	if numWorkers <= 0 {
 		numWorkers = runtime.NumCPU()
 	}
 
-	for improved {
+	for improved && !bls.cancelled() {
 		improved = false
 		bestDelta := 0.0
 		var bestI, bestJ uint8
+		var usedCache atomic.Bool
 
 		costBefore := bls.scorer.Score(layout)
+		bounds := bls.swapBounds(layout)
 
 		// Distribute work into chunks
 		numPairs := len(bls.validPairs)
@@ -420,13 +657,16 @@ func (bls *BLS) steepestDescentParallel(layout *SplitLayout) {
 
 				for _, pair := range pairs {
 					i, j := pair[0], pair[1]
+					if bls.scorer.introducesTaboo(localLayout, i, j) {
+						continue
+					}
 
-					// Compute delta by scoring after swap
-					localLayout.Swap(i, j)
-					costAfter := bls.scorer.Score(localLayout)
-					localLayout.Swap(i, j) // Swap back
-
-					delta := costAfter - costBefore
+					delta, cached := bls.cachedDelta(i, j)
+					if cached {
+						usedCache.Store(true)
+					} else {
+						delta = bls.boundedSwapDelta(localLayout, bounds, i, j, costBefore, localBestDelta)
+					}
 
 					if delta < localBestDelta {
 						localBestDelta = delta
@@ -459,6 +699,11 @@ func (bls *BLS) steepestDescentParallel(layout *SplitLayout) {
 		}
 
 		if improved {
+			if !bls.verifySwap(layout, bestI, bestJ, costBefore) {
+				// Stale cached delta; it has been refreshed, so re-run the round
+				continue
+			}
+
 			// Apply best swap
 			layout.Swap(bestI, bestJ)
 			swapCount++
@@ -467,6 +712,10 @@ func (bls *BLS) steepestDescentParallel(layout *SplitLayout) {
 			bls.state.tabuMatrix[bestI][bestJ] = bls.state.iteration
 			bls.state.tabuMatrix[bestJ][bestI] = bls.state.iteration
 			bls.state.iteration++
+		} else if usedCache.Load() {
+			// Confirm the local optimum with a fresh evaluation of all pairs
+			cache.Reset()
+			improved = true
 		}
 	}
 
@@ -485,7 +734,7 @@ func (bls *BLS) perturb(layout *SplitLayout, L int) {
 	startCost := bls.scorer.Score(layout)
 
 	for range L {
-		pertType := bls.selectPerturbationType()
+		pertType, custom := bls.selectPerturbationType()
 
 		var swapI, swapJ uint8
 		var valid bool
@@ -508,6 +757,16 @@ func (bls *BLS) perturb(layout *SplitLayout, L int) {
 		case RandomPerturb:
 			swapI, swapJ, valid = bls.selectRandomSwap(layout)
 			strategies["random"]++
+		case CyclePerturb:
+			totalSwaps += bls.applyRandomCycle(layout)
+			strategies["cycle"]++
+		case RotationPerturb:
+			totalSwaps += bls.applyRotation(layout)
+			strategies["rotation"]++
+		case CustomPerturb:
+			wp := bls.params.Perturbations[custom]
+			totalSwaps += bls.applyCustomPerturbation(layout, wp.Strategy)
+			strategies[wp.Strategy.Name()]++
 		}
 
 		if valid {
@@ -522,14 +781,18 @@ func (bls *BLS) perturb(layout *SplitLayout, L int) {
 		bls.state.iteration++
 
 		// Check if perturbation led to a new best
-		cost := bls.scorer.Score(layout)
-		if cost < bls.state.bestCost {
+		if cost, ok := bls.improvesBest(layout, bls.scorer.Score(layout)); ok {
 			bls.state.bestCost = cost
 			bls.state.bestLayout = layout.Clone()
 			bls.state.omega = 0
+			bls.recordHistory()
 		}
 	}
 
+	for strategy, n := range strategies {
+		bls.perturbations[strategy] += n
+	}
+
 	// Log perturbation completion
 	if bls.logger != nil {
 		endCost := bls.scorer.Score(layout)
@@ -537,10 +800,24 @@ func (bls *BLS) perturb(layout *SplitLayout, L int) {
 	}
 }
 
+// improvesBest reports whether a layout with the given cost improves on the best cost, and
+// returns its exact cost. When the scorer subsamples the corpus, an apparent improvement is
+// confirmed against the full corpus, so that noise in the sample cannot set the best cost.
+func (bls *BLS) improvesBest(layout *SplitLayout, cost float64) (float64, bool) {
+	if cost >= bls.state.bestCost {
+		return cost, false
+	}
+	if bls.scorer.Subsampled() {
+		cost = bls.scorer.ScoreExact(layout)
+	}
+	return cost, cost < bls.state.bestCost
+}
+
 // selectPerturbationType chooses which perturbation type to use based on search state.
 // Uses adaptive probability: directed perturbation is more likely early on,
 // stronger diversification becomes more likely as search stagnates.
-func (bls *BLS) selectPerturbationType() PerturbationType {
+// For CustomPerturb, it also returns the index of the custom strategy in params.Perturbations.
+func (bls *BLS) selectPerturbationType() (PerturbationType, int) {
 	// Calculate probability of directed perturbation
 	P := math.Exp(-float64(bls.state.omega) / float64(bls.params.T))
 	if P < bls.params.P0 {
@@ -550,31 +827,56 @@ func (bls *BLS) selectPerturbationType() PerturbationType {
 	r := bls.rng.Float64()
 
 	if r < P {
-		return DirectedPerturb
+		return DirectedPerturb, 0
 	}
 
 	// Distribute remaining probability among other perturbations
 	remaining := 1.0 - P
 	r = (r - P) / remaining // Normalize to [0, 1]
+	if len(bls.params.Perturbations) > 0 {
+		r *= bls.params.perturbationWeights() // Custom weights are relative to the built-in ones
+	}
 
 	cumulative := 0.0
 
 	cumulative += bls.params.PatternWeight
 	if r < cumulative {
-		return PatternGuidedPerturb
+		return PatternGuidedPerturb, 0
 	}
 
 	cumulative += bls.params.ColumnWeight
 	if r < cumulative {
-		return ColumnPerturb
+		return ColumnPerturb, 0
 	}
 
 	cumulative += bls.params.RandomWeight
 	if r < cumulative {
-		return RandomPerturb
+		return RandomPerturb, 0
+	}
+
+	cumulative += bls.params.CycleWeight
+	if r < cumulative {
+		return CyclePerturb, 0
+	}
+
+	cumulative += bls.params.RotationWeight
+	if r < cumulative {
+		return RotationPerturb, 0
+	}
+
+	cumulative += bls.params.RecencyWeight
+	if r < cumulative || len(bls.params.Perturbations) == 0 {
+		return RecencyPerturb, 0
+	}
+
+	for i, wp := range bls.params.Perturbations {
+		cumulative += wp.Weight
+		if r < cumulative {
+			return CustomPerturb, i
+		}
 	}
 
-	return RecencyPerturb
+	return RecencyPerturb, 0
 }
 
 // selectDirectedSwap selects a swap that minimizes cost degradation (tabu search style).
@@ -784,7 +1086,7 @@ func (bls *BLS) findBetterPosition(layout *SplitLayout, keyIdx uint8) uint8 {
 	candidates := []uint8{}
 
 	for pos := range uint8(42) {
-		if pos == keyIdx || bls.pinned[pos] {
+		if pos == keyIdx || bls.pinned[pos] || !bls.sameClass(keyIdx, pos) {
 			continue
 		}
 
@@ -800,7 +1102,7 @@ func (bls *BLS) findBetterPosition(layout *SplitLayout, keyIdx uint8) uint8 {
 	if len(candidates) == 0 {
 		// Fallback: any valid position
 		for pos := range uint8(42) {
-			if pos != keyIdx && !bls.pinned[pos] {
+			if pos != keyIdx && !bls.pinned[pos] && bls.sameClass(keyIdx, pos) {
 				candidates = append(candidates, pos)
 			}
 		}
@@ -828,7 +1130,7 @@ func (bls *BLS) applyColumnSwap(layout *SplitLayout) {
 		posA := row*12 + uint8(colA)
 		posB := row*12 + uint8(colB)
 
-		if !bls.pinned[posA] && !bls.pinned[posB] {
+		if !bls.pinned[posA] && !bls.pinned[posB] && bls.sameClass(posA, posB) {
 			layout.Swap(posA, posB)
 		}
 	}
@@ -0,0 +1,67 @@
+package keycraft
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// countdownContext is a context that reports cancellation after n checks of Err.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestOptimize_CancelledBeforeStart(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	best := bls.Optimize(ctx, layout, nil)
+	if !bls.Interrupted() {
+		t.Error("expected the search to be interrupted")
+	}
+	if bls.state.iteration != 0 {
+		t.Errorf("ran %d iterations after cancellation", bls.state.iteration)
+	}
+	if best.Runes != layout.Runes {
+		t.Error("the best layout of a cancelled search should be the initial layout")
+	}
+}
+
+// TestOptimize_CancelledDuringSearch checks that cancelling the search, also in the middle
+// of a descent, stops it early with the best layout found so far.
+func TestOptimize_CancelledDuringSearch(t *testing.T) {
+	for _, checks := range []int{1, 5, 20} {
+		bls, layout := newTestMovesBLS(t)
+		bls.params.MaxIterations = 1000
+
+		best := bls.Optimize(&countdownContext{Context: context.Background(), n: checks}, layout, nil)
+		if !bls.Interrupted() {
+			t.Errorf("%d checks: expected the search to be interrupted", checks)
+		}
+		if bls.state.iteration >= bls.params.MaxIterations {
+			t.Errorf("%d checks: search ran all %d iterations", checks, bls.state.iteration)
+		}
+		if cost := bls.scorer.Score(best); math.Abs(cost-bls.state.bestCost) > 1e-9 {
+			t.Errorf("%d checks: best layout costs %v, want the best cost %v", checks, cost, bls.state.bestCost)
+		}
+		if initial := bls.scorer.Score(layout); bls.state.bestCost > initial+1e-9 {
+			t.Errorf("%d checks: best cost %v is worse than the initial cost %v", checks, bls.state.bestCost, initial)
+		}
+	}
+
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 2
+	bls.Optimize(context.Background(), layout, nil)
+	if bls.Interrupted() {
+		t.Error("a completed search should not be interrupted")
+	}
+}
@@ -1,34 +1,36 @@
 package keycraft
 
 import (
-	"encoding/json"
+	"context"
 	"io"
+	"log/slog"
 	"time"
 )
 
 // BLSLogger provides dual-format logging for BLS optimization.
-// Console output is human-readable, file output is JSONL for analysis.
+// Console output is human-readable, log output is structured for analysis, such as the
+// JSONL of --log-file.
 type BLSLogger struct {
-	console   io.Writer // Human-readable output (can be nil)
-	file      io.Writer // JSONL structured output (can be nil)
+	console   io.Writer    // Human-readable output (can be nil)
+	log       *slog.Logger // Structured output (can be nil)
 	startTime time.Time
 }
 
-// NewBLSLogger creates a new logger with separate console and file outputs.
-// Either writer can be nil to disable that output channel.
-func NewBLSLogger(console, file io.Writer) *BLSLogger {
+// NewBLSLogger creates a new logger with separate console and structured outputs.
+// Either can be nil to disable that output channel.
+func NewBLSLogger(console io.Writer, log *slog.Logger) *BLSLogger {
 	return &BLSLogger{
 		console:   console,
-		file:      file,
+		log:       log,
 		startTime: time.Now(),
 	}
 }
 
-// LogEvent represents a single log entry in JSONL format.
+// LogEvent represents a single entry of the structured log. The event is the message of the
+// log record, and the fields that are set are its attributes.
 type LogEvent struct {
-	Event     string    `json:"event"`
-	Timestamp time.Time `json:"timestamp"`
-	ElapsedMs int64     `json:"elapsed_ms"`
+	Event     string `json:"event"`
+	ElapsedMs int64  `json:"elapsed_ms"`
 
 	// Optimization state (present in most events)
 	Iteration *int     `json:"iteration,omitempty"`
@@ -70,20 +72,25 @@ type LogEvent struct {
 
 // BLSLogParams captures BLS parameters for the start event.
 type BLSLogParams struct {
-	L0            int     `json:"l0"`
-	LMax          int     `json:"l_max"`
-	T             int     `json:"t"`
-	TabuMin       int     `json:"tabu_min"`
-	TabuMax       int     `json:"tabu_max"`
-	MaxIterations int     `json:"max_iterations"`
-	MaxTimeMs     int64   `json:"max_time_ms"`
-	Seed          int64   `json:"seed"`
-	UseParallel   bool    `json:"use_parallel"`
-	Workers       int     `json:"workers"`
-	PatternWeight float64 `json:"pattern_weight"`
-	ColumnWeight  float64 `json:"column_weight"`
-	RandomWeight  float64 `json:"random_weight"`
-	RecencyWeight float64 `json:"recency_weight"`
+	L0              int     `json:"l0"`
+	LMax            int     `json:"l_max"`
+	T               int     `json:"t"`
+	TabuMin         int     `json:"tabu_min"`
+	TabuMax         int     `json:"tabu_max"`
+	MaxIterations   int     `json:"max_iterations"`
+	MaxTimeMs       int64   `json:"max_time_ms"`
+	Seed            int64   `json:"seed"`
+	UseParallel     bool    `json:"use_parallel"`
+	Workers         int     `json:"workers"`
+	PatternWeight   float64 `json:"pattern_weight"`
+	ColumnWeight    float64 `json:"column_weight"`
+	RandomWeight    float64 `json:"random_weight"`
+	RecencyWeight   float64 `json:"recency_weight"`
+	CycleWeight     float64 `json:"cycle_weight"`
+	RotationWeight  float64 `json:"rotation_weight"`
+	CompoundDescent bool    `json:"compound_descent"`
+
+	CustomWeights map[string]float64 `json:"custom_weights,omitempty"` // Weights of the custom perturbations by name
 }
 
 // CacheStatsLog captures cache statistics for the end event.
@@ -92,25 +99,61 @@ type CacheStatsLog struct {
 	Misses      uint64  `json:"misses"`
 	HitRate     float64 `json:"hit_rate"`
 	UniqueKeys  int     `json:"unique_keys"`
+	Evictions   int64   `json:"evictions"`
 	MemoryBytes int64   `json:"memory_bytes"`
 }
 
-// writeJSON writes a log event to the file output as JSONL.
-func (l *BLSLogger) writeJSON(event LogEvent) {
-	if l.file == nil {
+// writeLog writes a log event to the structured log, at info level, or at debug level for
+// the frequent events of the search.
+func (l *BLSLogger) writeLog(level slog.Level, event LogEvent) {
+	if l.log == nil || !l.log.Enabled(context.Background(), level) {
 		return
 	}
 
-	event.Timestamp = time.Now()
 	event.ElapsedMs = time.Since(l.startTime).Milliseconds()
+	l.log.LogAttrs(context.Background(), level, event.Event, event.attrs()...)
+}
 
-	data, err := json.Marshal(event)
-	if err != nil {
-		return // Silently ignore JSON errors
+// attrs returns the fields of the event that are set, besides the event itself, as log
+// attributes.
+func (e LogEvent) attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.Int64("elapsed_ms", e.ElapsedMs)}
+	addInt := func(key string, v *int) {
+		if v != nil {
+			attrs = append(attrs, slog.Int(key, *v))
+		}
+	}
+	addFloat := func(key string, v *float64) {
+		if v != nil {
+			attrs = append(attrs, slog.Float64(key, *v))
+		}
+	}
+	addAny := func(key string, v any, set bool) {
+		if set {
+			attrs = append(attrs, slog.Any(key, v))
+		}
 	}
 
-	data = append(data, '\n')
-	_, _ = l.file.Write(data)
+	addInt("iteration", e.Iteration)
+	addFloat("cost", e.Cost)
+	addFloat("best_cost", e.BestCost)
+	addFloat("delta", e.Delta)
+	addInt("jump_magnitude", e.JumpMagnitude)
+	addInt("omega", e.Omega)
+	addAny("perturbation_type", e.PerturbationType, e.PerturbationType != "")
+	addAny("layout_name", e.LayoutName, e.LayoutName != "")
+	addInt("free_keys", e.FreeKeys)
+	addInt("total_keys", e.TotalKeys)
+	addAny("layout", e.Layout, e.Layout != nil)
+	addAny("params", e.Params, e.Params != nil)
+	addAny("cache_stats", e.CacheStats, e.CacheStats != nil)
+	addAny("message", e.Message, e.Message != "")
+	addInt("swap_count", e.SwapCount)
+	addFloat("start_cost", e.StartCost)
+	addFloat("end_cost", e.EndCost)
+	addAny("perturb_strategies", e.PerturbStrategies, e.PerturbStrategies != nil)
+	addInt("perturb_swaps", e.PerturbSwaps)
+	return attrs
 }
 
 // LogStart logs the start of optimization.
@@ -123,38 +166,54 @@ func (l *BLSLogger) LogStart(params BLSParams, layout *SplitLayout, numFree int)
 	}
 
 	totalKeys := 42
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelInfo, LogEvent{
 		Event:      "start",
 		LayoutName: layout.Name,
 		FreeKeys:   &numFree,
 		TotalKeys:  &totalKeys,
 		Layout:     layoutToStrings(layout),
 		Params: &BLSLogParams{
-			L0:            params.L0,
-			LMax:          params.LMax,
-			T:             params.T,
-			TabuMin:       params.TabuMin,
-			TabuMax:       params.TabuMax,
-			MaxIterations: params.MaxIterations,
-			MaxTimeMs:     params.MaxTime.Milliseconds(),
-			Seed:          params.Seed,
-			UseParallel:   params.UseParallel,
-			Workers:       params.ParallelWorkers,
-			PatternWeight: params.PatternWeight,
-			ColumnWeight:  params.ColumnWeight,
-			RandomWeight:  params.RandomWeight,
-			RecencyWeight: params.RecencyWeight,
+			L0:              params.L0,
+			LMax:            params.LMax,
+			T:               params.T,
+			TabuMin:         params.TabuMin,
+			TabuMax:         params.TabuMax,
+			MaxIterations:   params.MaxIterations,
+			MaxTimeMs:       params.MaxTime.Milliseconds(),
+			Seed:            params.Seed,
+			UseParallel:     params.UseParallel,
+			Workers:         params.ParallelWorkers,
+			PatternWeight:   params.PatternWeight,
+			ColumnWeight:    params.ColumnWeight,
+			RandomWeight:    params.RandomWeight,
+			RecencyWeight:   params.RecencyWeight,
+			CycleWeight:     params.CycleWeight,
+			RotationWeight:  params.RotationWeight,
+			CompoundDescent: params.CompoundDescent,
+			CustomWeights:   customWeights(params.Perturbations),
 		},
 	})
 }
 
+// customWeights returns the weights of custom perturbations by name, or nil if there are none.
+func customWeights(perturbations []WeightedPerturbation) map[string]float64 {
+	if len(perturbations) == 0 {
+		return nil
+	}
+	weights := make(map[string]float64, len(perturbations))
+	for _, wp := range perturbations {
+		weights[wp.Strategy.Name()] = wp.Weight
+	}
+	return weights
+}
+
 // LogInitialCost logs the initial cost after it's calculated.
 func (l *BLSLogger) LogInitialCost(cost float64) {
 	if l.console != nil {
 		MustFprintf(l.console, "Initial cost: %.4f\n", cost)
 	}
 
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelInfo, LogEvent{
 		Event: "initial_cost",
 		Cost:  &cost,
 	})
@@ -170,7 +229,7 @@ func (l *BLSLogger) LogImprovement(iteration int, newCost, prevBest float64, lay
 		MustFprintln(l.console, layout)
 	}
 
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelInfo, LogEvent{
 		Event:      "improvement",
 		Iteration:  &iteration,
 		Cost:       &newCost,
@@ -188,7 +247,7 @@ func (l *BLSLogger) LogStrongPerturbation(iteration, jumpMagnitude int) {
 			iteration, jumpMagnitude)
 	}
 
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelDebug, LogEvent{
 		Event:         "strong_perturbation",
 		Iteration:     &iteration,
 		JumpMagnitude: &jumpMagnitude,
@@ -202,7 +261,7 @@ func (l *BLSLogger) LogProgress(iteration int, currentCost, bestCost float64, ju
 			iteration, currentCost, bestCost, jumpMagnitude, omega)
 	}
 
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelDebug, LogEvent{
 		Event:         "progress",
 		Iteration:     &iteration,
 		Cost:          &currentCost,
@@ -218,16 +277,28 @@ func (l *BLSLogger) LogTimeLimit(elapsed time.Duration) {
 		MustFprintf(l.console, "\nTime limit reached: %v\n", elapsed)
 	}
 
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelInfo, LogEvent{
 		Event:   "time_limit",
 		Message: elapsed.String(),
 	})
 }
 
+// LogInterrupted logs when the search is cancelled.
+func (l *BLSLogger) LogInterrupted(elapsed time.Duration) {
+	if l.console != nil {
+		MustFprintf(l.console, "\nInterrupted after %v, keeping the best layout so far\n", elapsed.Round(time.Second))
+	}
+
+	l.writeLog(slog.LevelInfo, LogEvent{
+		Event:   "interrupted",
+		Message: elapsed.String(),
+	})
+}
+
 // LogDescent logs the completion of a steepest descent phase.
 func (l *BLSLogger) LogDescent(iteration int, swapCount int, startCost, endCost float64) {
-	// Only log to file (console would be too verbose)
-	l.writeJSON(LogEvent{
+	// Only log (console would be too verbose)
+	l.writeLog(slog.LevelDebug, LogEvent{
 		Event:     "descent",
 		Iteration: &iteration,
 		SwapCount: &swapCount,
@@ -238,8 +309,8 @@ func (l *BLSLogger) LogDescent(iteration int, swapCount int, startCost, endCost
 
 // LogPerturb logs the completion of a perturbation phase.
 func (l *BLSLogger) LogPerturb(iteration int, strategies map[string]int, totalSwaps int, startCost, endCost float64) {
-	// Only log to file (console would be too verbose)
-	l.writeJSON(LogEvent{
+	// Only log (console would be too verbose)
+	l.writeLog(slog.LevelDebug, LogEvent{
 		Event:             "perturb",
 		Iteration:         &iteration,
 		PerturbStrategies: strategies,
@@ -258,7 +329,7 @@ func (l *BLSLogger) LogEnd(bestCost float64, totalIterations int, elapsed time.D
 		MustFprintf(l.console, "Total time: %v\n", elapsed.Round(time.Second))
 	}
 
-	l.writeJSON(LogEvent{
+	l.writeLog(slog.LevelInfo, LogEvent{
 		Event:      "end",
 		Iteration:  &totalIterations,
 		BestCost:   &bestCost,
@@ -268,20 +339,21 @@ func (l *BLSLogger) LogEnd(bestCost float64, totalIterations int, elapsed time.D
 }
 
 // LogCacheStats logs cache statistics (typically at end of optimization).
-func (l *BLSLogger) LogCacheStats(hits, misses uint64, uniqueKeys int, memoryBytes int64) {
+func (l *BLSLogger) LogCacheStats(hits, misses uint64, uniqueKeys int, evictions, memoryBytes int64) {
 	hitRate := 0.0
 	if hits+misses > 0 {
 		hitRate = float64(hits) / float64(hits+misses)
 	}
 
-	// Console output is handled by Scorer.LogStats, so only write JSON here
-	l.writeJSON(LogEvent{
+	// Console output is handled by Scorer.LogStats, so only write the log here
+	l.writeLog(slog.LevelInfo, LogEvent{
 		Event: "cache_stats",
 		CacheStats: &CacheStatsLog{
 			Hits:        hits,
 			Misses:      misses,
 			HitRate:     hitRate,
 			UniqueKeys:  uniqueKeys,
+			Evictions:   evictions,
 			MemoryBytes: memoryBytes,
 		},
 	})
@@ -292,9 +364,9 @@ func (l *BLSLogger) HasConsole() bool {
 	return l.console != nil
 }
 
-// HasFile returns true if file output is enabled.
-func (l *BLSLogger) HasFile() bool {
-	return l.file != nil
+// HasLog returns true if structured output is enabled.
+func (l *BLSLogger) HasLog() bool {
+	return l.log != nil
 }
 
 // Console returns the console writer (for backward compatibility).
@@ -314,7 +386,7 @@ func layoutToStrings(layout *SplitLayout) []string {
 	row0 := make([]rune, 12)
 	for i := 0; i < 12; i++ {
 		r := layout.Runes[i]
-		if r == 0 {
+		if r == 0 || isSpillPlaceholder(r) {
 			r = ' '
 		}
 		row0[i] = r
@@ -325,7 +397,7 @@ func layoutToStrings(layout *SplitLayout) []string {
 	row1 := make([]rune, 12)
 	for i := 0; i < 12; i++ {
 		r := layout.Runes[12+i]
-		if r == 0 {
+		if r == 0 || isSpillPlaceholder(r) {
 			r = ' '
 		}
 		row1[i] = r
@@ -336,7 +408,7 @@ func layoutToStrings(layout *SplitLayout) []string {
 	row2 := make([]rune, 12)
 	for i := 0; i < 12; i++ {
 		r := layout.Runes[24+i]
-		if r == 0 {
+		if r == 0 || isSpillPlaceholder(r) {
 			r = ' '
 		}
 		row2[i] = r
@@ -347,7 +419,7 @@ func layoutToStrings(layout *SplitLayout) []string {
 	row3 := make([]rune, 6)
 	for i := 0; i < 6; i++ {
 		r := layout.Runes[36+i]
-		if r == 0 {
+		if r == 0 || isSpillPlaceholder(r) {
 			r = ' '
 		}
 		row3[i] = r
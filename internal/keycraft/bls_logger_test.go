@@ -0,0 +1,68 @@
+package keycraft
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logRecords parses the JSON lines of a log.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestBLSLogger_Levels(t *testing.T) {
+	layout := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+
+	for _, tt := range []struct {
+		level slog.Level
+		want  []string
+	}{
+		{slog.LevelInfo, []string{"start", "improvement", "end"}},
+		{slog.LevelDebug, []string{"start", "progress", "improvement", "end"}},
+	} {
+		var buf bytes.Buffer
+		logger := NewBLSLogger(nil, slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tt.level})))
+		logger.LogStart(DefaultBLSParams(30), layout, 30)
+		logger.LogProgress(10, 2.5, 2.0, 3, 1)
+		logger.LogImprovement(11, 1.5, 2.0, layout, time.Second)
+		logger.LogEnd(1.5, 20, time.Second, layout)
+
+		records := logRecords(t, &buf)
+		if len(records) != len(tt.want) {
+			t.Fatalf("level %v: got %d records, want %d", tt.level, len(records), len(tt.want))
+		}
+		for i, msg := range tt.want {
+			if records[i]["msg"] != msg {
+				t.Errorf("level %v: record %d is %v, want %s", tt.level, i, records[i]["msg"], msg)
+			}
+		}
+		improvement := records[len(records)-2]
+		if improvement["iteration"] != 11.0 || improvement["delta"] != -0.5 || improvement["layout_name"] != "test" {
+			t.Errorf("improvement record = %v", improvement)
+		}
+		if _, ok := improvement["omega"]; ok {
+			t.Error("expected fields that are not set to be left out")
+		}
+	}
+
+	// Without a log, only the console is written to
+	var console bytes.Buffer
+	logger := NewBLSLogger(&console, nil)
+	logger.LogInitialCost(2.5)
+	if !strings.Contains(console.String(), "Initial cost: 2.5000") {
+		t.Errorf("console = %q", console.String())
+	}
+}
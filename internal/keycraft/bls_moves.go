@@ -0,0 +1,178 @@
+package keycraft
+
+import (
+	"slices"
+)
+
+// Compound moves for BLS: 3-cycles and row/column rotations. They move three or more
+// keys at once and are built from sequences of swaps, so they respect pinned keys in
+// the same way as pairwise swaps.
+
+// applyCycle moves the key at a to b, the key at b to c, and the key at c to a.
+func applyCycle(layout *SplitLayout, a, b, c uint8) {
+	layout.Swap(a, b)
+	layout.Swap(a, c)
+}
+
+// undoCycle reverts applyCycle(layout, a, b, c).
+func undoCycle(layout *SplitLayout, a, b, c uint8) {
+	layout.Swap(a, c)
+	layout.Swap(a, b)
+}
+
+// freeKeys returns the positions of all keys that are not pinned, in ascending order.
+func (bls *BLS) freeKeys() []uint8 {
+	free := make([]uint8, 0, bls.numFree)
+	for i := range uint8(42) {
+		if !bls.pinned[i] {
+			free = append(free, i)
+		}
+	}
+	return free
+}
+
+// applyRandomCycle applies a 3-cycle of three random free keys of the same rune class and
+// returns the number of swaps it took (0 if there are no three such keys).
+func (bls *BLS) applyRandomCycle(layout *SplitLayout) int {
+	free := bls.freeKeys()
+	if len(free) < 3 {
+		return 0
+	}
+
+	perm := bls.rng.Perm(len(free))
+	keys := []uint8{free[perm[0]]}
+	for _, k := range perm[1:] {
+		if len(keys) < 3 && bls.sameClass(keys[0], free[k]) {
+			keys = append(keys, free[k])
+		}
+	}
+	if len(keys) < 3 {
+		return 0
+	}
+	a, b, c := keys[0], keys[1], keys[2]
+	applyCycle(layout, a, b, c)
+
+	bls.state.tabuMatrix[a][c] = bls.state.iteration
+	bls.state.tabuMatrix[c][a] = bls.state.iteration
+	bls.state.tabuMatrix[a][b] = bls.state.iteration
+	bls.state.tabuMatrix[b][a] = bls.state.iteration
+	return 2
+}
+
+// applyRotation shifts the free keys of a random row (one hand's half of the top, home or
+// bottom row) or column (top, home and bottom row) by one position in a random direction. Pinned keys stay in
+// place and are skipped over, as are keys of another rune class than the first free key. It returns the
+// number of swaps it took.
+func (bls *BLS) applyRotation(layout *SplitLayout) int {
+	var positions []uint8
+	if bls.rng.Intn(2) == 0 {
+		row := uint8(bls.rng.Intn(3))
+		firstCol := uint8(6 * bls.rng.Intn(2))
+		for col := firstCol; col < firstCol+6; col++ {
+			positions = append(positions, 12*row+col)
+		}
+	} else {
+		col := uint8(bls.rng.Intn(12))
+		for row := range uint8(3) {
+			positions = append(positions, 12*row+col)
+		}
+	}
+
+	positions = slices.DeleteFunc(positions, func(pos uint8) bool { return bls.pinned[pos] })
+	if len(positions) > 0 {
+		first := positions[0]
+		positions = slices.DeleteFunc(positions, func(pos uint8) bool { return !bls.sameClass(first, pos) })
+	}
+	if len(positions) < 2 {
+		return 0
+	}
+	if bls.rng.Intn(2) == 0 {
+		slices.Reverse(positions)
+	}
+
+	// Bubbling the first key to the end shifts all other keys back by one position
+	for k := 0; k+1 < len(positions); k++ {
+		i, j := positions[k], positions[k+1]
+		layout.Swap(i, j)
+		bls.state.tabuMatrix[i][j] = bls.state.iteration
+		bls.state.tabuMatrix[j][i] = bls.state.iteration
+	}
+	return len(positions) - 1
+}
+
+// applyImprovingCycle looks for a 3-cycle that improves the layout, which is at a local
+// optimum of pairwise swaps, and applies the best one found. Not all 3-cycles are
+// evaluated: the CompoundCandidates least harmful swaps are each extended with every
+// other free key, in both directions. It returns whether a 3-cycle was applied.
+func (bls *BLS) applyImprovingCycle(layout *SplitLayout) bool {
+	numCandidates := bls.params.CompoundCandidates
+	if numCandidates <= 0 || bls.numFree < 3 {
+		return false
+	}
+
+	costBefore := bls.scorer.Score(layout)
+	candidates := make([]swapResult, 0, len(bls.validPairs))
+	for _, pair := range bls.validPairs {
+		i, j := pair[0], pair[1]
+		delta, cached := bls.cachedDelta(i, j)
+		if !cached {
+			delta = bls.swapDelta(layout, i, j, costBefore)
+		}
+		candidates = append(candidates, swapResult{i: i, j: j, delta: delta})
+	}
+	slices.SortStableFunc(candidates, func(x, y swapResult) int {
+		switch {
+		case x.delta < y.delta:
+			return -1
+		case x.delta > y.delta:
+			return 1
+		}
+		return 0
+	})
+	candidates = candidates[:min(numCandidates, len(candidates))]
+
+	bestDelta := 0.0
+	var best [3]uint8
+	found := false
+	free := bls.freeKeys()
+	taboo := len(bls.scorer.TabooViolations(layout))
+	for _, cand := range candidates {
+		for _, k := range free {
+			if k == cand.i || k == cand.j || !bls.sameClass(cand.i, k) {
+				continue
+			}
+			for _, cycle := range [][3]uint8{{cand.i, cand.j, k}, {cand.j, cand.i, k}} {
+				applyCycle(layout, cycle[0], cycle[1], cycle[2])
+				delta := bls.scorer.Score(layout) - costBefore
+				introduced := len(bls.scorer.TabooViolations(layout)) > taboo
+				undoCycle(layout, cycle[0], cycle[1], cycle[2])
+
+				if delta < bestDelta && !introduced {
+					bestDelta = delta
+					best = cycle
+					found = true
+				}
+			}
+		}
+	}
+
+	if !found {
+		return false
+	}
+
+	a, b, c := best[0], best[1], best[2]
+	applyCycle(layout, a, b, c)
+	if bls.swapCache != nil {
+		bls.swapCache.Reset()
+	}
+	bls.state.tabuMatrix[a][c] = bls.state.iteration
+	bls.state.tabuMatrix[c][a] = bls.state.iteration
+	bls.state.tabuMatrix[a][b] = bls.state.iteration
+	bls.state.tabuMatrix[b][a] = bls.state.iteration
+	bls.state.iteration++
+
+	if bls.logger != nil {
+		bls.logger.LogDescent(bls.state.iteration, 2, costBefore, costBefore+bestDelta)
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestMovesBLS creates a BLS on the small test layout with empty keys, spaces and
+// the key 'a' pinned, ready for applying moves outside Optimize.
+func newTestMovesBLS(t *testing.T) (*BLS, *SplitLayout) {
+	t.Helper()
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"c.klf":   testLayoutVariantKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	scorer := Must(NewScorer(dir, corpus, NewTargetLoads(), NewWeights(), nil))
+
+	pinned := &PinnedKeys{}
+	numFree := 0
+	for i, r := range layout.Runes {
+		if r == 0 || r == ' ' || r == 'a' {
+			pinned[i] = true
+		} else {
+			numFree++
+		}
+	}
+
+	params := DefaultBLSParams(numFree)
+	params.Seed = 1
+	params.UseParallel = false
+	bls := NewBLS(params, scorer, corpus, pinned)
+	bls.state = BLSState{tabuMatrix: make([][]int, 42)}
+	for i := range bls.state.tabuMatrix {
+		bls.state.tabuMatrix[i] = make([]int, 42)
+	}
+	return bls, layout
+}
+
+// assertPermutation checks that moved only permutes free keys of the original layout.
+func assertPermutation(t *testing.T, pinned *PinnedKeys, original, moved *SplitLayout) {
+	t.Helper()
+	for i := range 42 {
+		if pinned[i] && moved.Runes[i] != original.Runes[i] {
+			t.Errorf("pinned key %d changed from %q to %q", i, original.Runes[i], moved.Runes[i])
+		}
+	}
+	want := slices.Clone(original.Runes[:])
+	got := slices.Clone(moved.Runes[:])
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("keys are not a permutation of the original layout")
+	}
+	for r, pos := range moved.RuneInfo {
+		if moved.Runes[pos.Index] != r {
+			t.Errorf("rune info of %q is out of sync", r)
+		}
+	}
+}
+
+func TestApplyCycle(t *testing.T) {
+	_, layout := newTestMovesBLS(t)
+	a, b, c := uint8(1), uint8(2), uint8(3)
+	ra, rb, rc := layout.Runes[a], layout.Runes[b], layout.Runes[c]
+
+	applyCycle(layout, a, b, c)
+	if layout.Runes[b] != ra || layout.Runes[c] != rb || layout.Runes[a] != rc {
+		t.Errorf("cycle gave %q %q %q, want %q %q %q",
+			layout.Runes[a], layout.Runes[b], layout.Runes[c], rc, ra, rb)
+	}
+
+	undoCycle(layout, a, b, c)
+	if layout.Runes[a] != ra || layout.Runes[b] != rb || layout.Runes[c] != rc {
+		t.Errorf("undo did not restore the keys")
+	}
+}
+
+func TestCompoundPerturbations_KeepPinnedKeys(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	original := layout.Clone()
+
+	swaps := 0
+	for range 50 {
+		swaps += bls.applyRandomCycle(layout)
+		swaps += bls.applyRotation(layout)
+	}
+	if swaps == 0 {
+		t.Fatal("no keys were moved")
+	}
+	assertPermutation(t, bls.pinned, original, layout)
+}
+
+func TestApplyImprovingCycle(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.CompoundDescent = true
+	original := layout.Clone()
+
+	bls.steepestDescent(layout)
+	cost := bls.scorer.Score(layout)
+
+	// The descent ends at a local optimum of both swaps and 3-cycles
+	if bls.applyImprovingCycle(layout) {
+		t.Error("found an improving 3-cycle after compound descent")
+	}
+	if got := bls.scorer.Score(layout); got != cost {
+		t.Errorf("layout changed without an improvement: cost %.6f, want %.6f", got, cost)
+	}
+	if cost > bls.scorer.Score(original) {
+		t.Errorf("descent worsened the layout")
+	}
+	assertPermutation(t, bls.pinned, original, layout)
+}
+
+func TestEnableCompoundMoves(t *testing.T) {
+	params := DefaultBLSParams(30)
+	params.EnableCompoundMoves()
+
+	if !params.CompoundDescent || params.CycleWeight <= 0 || params.RotationWeight <= 0 {
+		t.Errorf("compound moves not enabled: %+v", params)
+	}
+	sum := params.PatternWeight + params.ColumnWeight + params.RandomWeight +
+		params.RecencyWeight + params.CycleWeight + params.RotationWeight
+	if sum < 0.999 || sum > 1.001 {
+		t.Errorf("perturbation weights sum to %.3f, want 1", sum)
+	}
+}
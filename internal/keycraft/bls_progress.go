@@ -0,0 +1,55 @@
+package keycraft
+
+import (
+	"maps"
+	"time"
+)
+
+// BLSProgress is a snapshot of a running search, passed to the progress function of a BLS
+// after the initial cost, after every iteration, and when the search ends.
+type BLSProgress struct {
+	Iteration     int                // Iterations done so far
+	MaxIterations int                // Iteration limit of the search
+	Elapsed       time.Duration      // Time since the start of the search
+	MaxTime       time.Duration      // Time limit of the search
+	CurrentCost   float64            // Cost of the latest local optimum (the initial cost at first)
+	BestCost      float64            // Best cost found so far
+	BestLayout    *SplitLayout       // Best layout found so far; must not be modified
+	BestCosts     map[string]float64 // Share of every scored metric in the cost of the best layout
+	Perturbations map[string]int     // Number of perturbation moves of each type so far
+	Scorer        ScorerStats        // Statistics of the scorer
+	Done          bool               // Whether this is the final snapshot of the search
+}
+
+// SetProgressFunc makes Optimize call f with a snapshot of the search after the initial cost,
+// after every iteration and when the search ends, on the goroutine running Optimize. Use nil
+// to disable progress reporting.
+func (bls *BLS) SetProgressFunc(f func(BLSProgress)) {
+	bls.progress = f
+}
+
+// reportProgress calls the progress function, if set. The metric costs of the best layout
+// are only recomputed when it has changed.
+func (bls *BLS) reportProgress(currentCost float64) {
+	if bls.progress == nil {
+		return
+	}
+	if bls.progressLayout != bls.state.bestLayout {
+		bls.progressLayout = bls.state.bestLayout
+		bls.progressShown = bls.state.bestLayout.withoutSpillPlaceholders()
+		bls.progressCosts = bls.scorer.MetricCosts(bls.state.bestLayout)
+	}
+	bls.progress(BLSProgress{
+		Iteration:     bls.state.iteration,
+		MaxIterations: bls.params.MaxIterations,
+		Elapsed:       time.Since(bls.state.startTime),
+		MaxTime:       bls.params.MaxTime,
+		CurrentCost:   currentCost,
+		BestCost:      bls.state.bestCost,
+		BestLayout:    bls.progressShown,
+		BestCosts:     bls.progressCosts,
+		Perturbations: maps.Clone(bls.perturbations),
+		Scorer:        bls.scorer.GetStats(),
+		Done:          bls.state.done,
+	})
+}
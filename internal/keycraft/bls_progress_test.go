@@ -0,0 +1,64 @@
+package keycraft
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestScorer_MetricCosts(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	costs := bls.scorer.MetricCosts(layout)
+	if len(costs) == 0 {
+		t.Fatal("expected metric costs")
+	}
+	sum := 0.0
+	for _, cost := range costs {
+		sum += cost
+	}
+	if want := bls.scorer.ScoreExact(layout); math.Abs(sum-want) > 1e-9 {
+		t.Errorf("metric costs add up to %v, want %v", sum, want)
+	}
+}
+
+func TestOptimize_Progress(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 50
+
+	var reports []BLSProgress
+	bls.SetProgressFunc(func(p BLSProgress) { reports = append(reports, p) })
+	best := bls.Optimize(context.Background(), layout, nil)
+
+	if len(reports) < 3 {
+		t.Fatalf("got %d progress reports, want the initial, one per iteration and the final one", len(reports))
+	}
+	first, last := reports[0], reports[len(reports)-1]
+	if first.Iteration != 0 || first.Done {
+		t.Errorf("first report at iteration %d (done %v), want the initial cost", first.Iteration, first.Done)
+	}
+	if !last.Done || last.BestCost != bls.state.bestCost {
+		t.Errorf("last report done %v with best cost %v, want the final best cost %v",
+			last.Done, last.BestCost, bls.state.bestCost)
+	}
+	if last.BestLayout.Runes != best.Runes {
+		t.Error("last report should hold the best layout")
+	}
+	sum, perturbations := 0.0, 0
+	for _, cost := range last.BestCosts {
+		sum += cost
+	}
+	for _, n := range last.Perturbations {
+		perturbations += n
+	}
+	if math.Abs(sum-last.BestCost) > 1e-9 {
+		t.Errorf("metric costs of the best layout add up to %v, want %v", sum, last.BestCost)
+	}
+	if perturbations == 0 {
+		t.Error("expected perturbations to be counted")
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].BestCost > reports[i-1].BestCost {
+			t.Errorf("best cost increased from %v to %v", reports[i-1].BestCost, reports[i].BestCost)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PerturbationStrategy is a custom perturbation move of BLS, such as rotating the vowels
+// among their keys or swapping keys of similar frequency. It is added to the built-in
+// perturbations with a weight (see BLSParams.AddPerturbation), and chosen as often as its
+// weight relative to theirs.
+type PerturbationStrategy interface {
+	// Name identifies the strategy in logs and progress reports.
+	Name() string
+
+	// Swaps returns the key swaps of one move on the layout, as pairs of key positions that
+	// are applied in order. free lists the positions of the keys that are not pinned, and rng
+	// is the random source of the custom moves, derived from the seed of the search so that
+	// runs with a seed are reproducible. Swaps of
+	// pinned keys, and of keys of different rune classes if swaps are restricted to a class,
+	// are skipped. Swaps must not change the layout itself.
+	Swaps(layout *SplitLayout, free []uint8, rng *LockedSource) [][2]uint8
+}
+
+// WeightedPerturbation is a custom perturbation strategy with its weight among the
+// perturbations of BLS.
+type WeightedPerturbation struct {
+	Strategy PerturbationStrategy
+	Weight   float64
+}
+
+// builtinPerturbations are the names of the built-in perturbations in logs and progress
+// reports, which custom strategies can't use.
+var builtinPerturbations = []string{"directed", "pattern", "column", "recency", "random", "cycle", "rotation"}
+
+// AddPerturbation adds a custom perturbation strategy with the given weight. The weights of
+// the built-in perturbations other than directed perturbation sum to 1, so a weight of 0.25
+// makes the strategy a fifth of those moves; set the built-in weights to 0 to use the custom
+// strategies only. Names must be unique, and differ from those of the built-in perturbations.
+func (p *BLSParams) AddPerturbation(s PerturbationStrategy, weight float64) error {
+	name := s.Name()
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("perturbation strategy has no name")
+	}
+	if weight <= 0 {
+		return fmt.Errorf("weight of perturbation %s must be positive (got %g)", name, weight)
+	}
+	for _, builtin := range builtinPerturbations {
+		if strings.EqualFold(name, builtin) {
+			return fmt.Errorf("perturbation %s is built in", name)
+		}
+	}
+	for _, wp := range p.Perturbations {
+		if strings.EqualFold(name, wp.Strategy.Name()) {
+			return fmt.Errorf("perturbation %s is added twice", name)
+		}
+	}
+	p.Perturbations = append(p.Perturbations, WeightedPerturbation{s, weight})
+	return nil
+}
+
+// perturbationWeights returns the sum of the weights of the non-directed perturbations,
+// built-in and custom.
+func (p *BLSParams) perturbationWeights() float64 {
+	sum := p.PatternWeight + p.ColumnWeight + p.RandomWeight + p.RecencyWeight + p.CycleWeight + p.RotationWeight
+	for _, wp := range p.Perturbations {
+		sum += wp.Weight
+	}
+	return sum
+}
+
+// applyCustomPerturbation applies the swaps of one move of a custom strategy, and returns the
+// number of swaps applied. Swaps that the search does not allow are skipped.
+func (bls *BLS) applyCustomPerturbation(layout *SplitLayout, s PerturbationStrategy) int {
+	applied := 0
+	for _, pair := range s.Swaps(layout, bls.freeKeys(), bls.moveRng) {
+		i, j := pair[0], pair[1]
+		if i == j || i >= 42 || j >= 42 || bls.pinned[i] || bls.pinned[j] || !bls.sameClass(i, j) {
+			continue
+		}
+		layout.Swap(i, j)
+		bls.state.tabuMatrix[i][j] = bls.state.iteration
+		bls.state.tabuMatrix[j][i] = bls.state.iteration
+		applied++
+	}
+	return applied
+}
@@ -0,0 +1,137 @@
+package keycraft
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// vowelRotation rotates the vowels among their keys, the pinned 'a' included.
+type vowelRotation struct{}
+
+func (vowelRotation) Name() string { return "vowels" }
+
+func (vowelRotation) Swaps(layout *SplitLayout, free []uint8, rng *LockedSource) [][2]uint8 {
+	var keys []uint8
+	for _, r := range "aeiou" {
+		if ki, ok := layout.GetKeyInfo(r); ok {
+			keys = append(keys, ki.Index)
+		}
+	}
+	var swaps [][2]uint8
+	for i := 1; i < len(keys); i++ {
+		swaps = append(swaps, [2]uint8{keys[0], keys[i]})
+	}
+	return swaps
+}
+
+// randomSwap swaps two random free keys, recording the keys it picked.
+type randomSwap struct{ picks *[]uint8 }
+
+func (randomSwap) Name() string { return "random-swap" }
+
+func (s randomSwap) Swaps(layout *SplitLayout, free []uint8, rng *LockedSource) [][2]uint8 {
+	i, j := free[rng.IntN(len(free))], free[rng.IntN(len(free))]
+	*s.picks = append(*s.picks, i, j)
+	return [][2]uint8{{i, j}}
+}
+
+type namedStrategy string
+
+func (s namedStrategy) Name() string { return string(s) }
+
+func (namedStrategy) Swaps(*SplitLayout, []uint8, *LockedSource) [][2]uint8 { return nil }
+
+func TestBLSParams_AddPerturbation(t *testing.T) {
+	params := DefaultBLSParams(30)
+	if err := params.AddPerturbation(vowelRotation{}, 0.25); err != nil {
+		t.Fatalf("AddPerturbation failed: %v", err)
+	}
+	if got := params.perturbationWeights(); got < 1.249 || got > 1.251 {
+		t.Errorf("perturbation weights sum to %.3f, want 1.25", got)
+	}
+
+	tests := []struct {
+		strategy PerturbationStrategy
+		weight   float64
+		want     string
+	}{
+		{namedStrategy(""), 1, "no name"},
+		{namedStrategy("swap"), 0, "positive"},
+		{namedStrategy("Random"), 1, "built in"},
+		{namedStrategy("VOWELS"), 1, "twice"},
+	}
+	for _, tt := range tests {
+		err := params.AddPerturbation(tt.strategy, tt.weight)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("AddPerturbation(%q, %v) error = %v, want it to contain %q", tt.strategy.Name(), tt.weight, err, tt.want)
+		}
+	}
+	if len(params.Perturbations) != 1 {
+		t.Errorf("%d perturbations added, want 1", len(params.Perturbations))
+	}
+}
+
+func TestOptimize_CustomPerturbation(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 30
+	bls.params.PatternWeight, bls.params.ColumnWeight, bls.params.RandomWeight = 0, 0, 0
+	bls.params.RecencyWeight, bls.params.CycleWeight, bls.params.RotationWeight = 0, 0, 0
+	Must0(bls.params.AddPerturbation(vowelRotation{}, 1))
+
+	var last BLSProgress
+	bls.SetProgressFunc(func(p BLSProgress) { last = p })
+	original := layout.Clone()
+	best := bls.Optimize(context.Background(), layout, nil)
+
+	if last.Perturbations["vowels"] == 0 {
+		t.Errorf("perturbations %v, want the custom strategy to be used", last.Perturbations)
+	}
+	for _, builtin := range []string{"pattern", "column", "random", "cycle", "rotation"} {
+		if last.Perturbations[builtin] != 0 {
+			t.Errorf("perturbations %v, want no %s perturbation with a weight of 0", last.Perturbations, builtin)
+		}
+	}
+	assertPermutation(t, bls.pinned, original, best)
+}
+
+func TestOptimize_CustomPerturbationSeed(t *testing.T) {
+	// run optimizes with a random custom strategy only, and returns the keys it picked
+	run := func(seed int64) []uint8 {
+		setup, layout := newTestMovesBLS(t)
+		params := setup.params
+		params.MaxIterations, params.Seed = 30, seed
+		params.PatternWeight, params.ColumnWeight, params.RandomWeight = 0, 0, 0
+		params.RecencyWeight, params.CycleWeight, params.RotationWeight = 0, 0, 0
+		var picks []uint8
+		Must0(params.AddPerturbation(randomSwap{&picks}, 1))
+		NewBLS(params, setup.scorer, setup.corpus, setup.pinned).Optimize(context.Background(), layout, nil)
+		return picks
+	}
+
+	// The random source of the strategy is derived from the seed of the search
+	first := run(1)
+	if len(first) == 0 {
+		t.Fatal("the custom strategy was not used")
+	}
+	if again := run(1); !slices.Equal(again, first) {
+		t.Errorf("seed 1 picked %v and %v, want the same keys", first, again)
+	}
+	if other := run(2); slices.Equal(other, first) {
+		t.Errorf("seeds 1 and 2 picked the same keys %v", first)
+	}
+}
+
+func TestApplyCustomPerturbation_SkipsPinnedKeys(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	original := layout.Clone()
+
+	// Every swap of the rotation involves the pinned 'a'
+	if n := bls.applyCustomPerturbation(layout, vowelRotation{}); n != 0 {
+		t.Errorf("%d swaps applied, want 0", n)
+	}
+	if layout.Runes != original.Runes {
+		t.Error("layout changed by swaps of a pinned key")
+	}
+}
@@ -0,0 +1,273 @@
+package keycraft
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BLSTuning is a set of the BLS parameters that matter most for the workload, relative to
+// the number of free keys where DefaultBLSParams scales them.
+type BLSTuning struct {
+	L0           float64 // Initial jump magnitude, as a fraction of the free keys
+	T            int     // Stagnation threshold before strong perturbation
+	Tabu         float64 // Mean tabu tenure, as a fraction of the free keys (the range is ±0.1)
+	RandomWeight float64 // Weight of random perturbation; the other types share the rest as by default
+}
+
+// DefaultBLSTuning returns the tuning of DefaultBLSParams.
+func DefaultBLSTuning() BLSTuning {
+	return BLSTuning{L0: 0.1, T: 50, Tabu: 1.0, RandomWeight: 0.4}
+}
+
+// Apply sets the tuned parameters of p for the number of free keys.
+func (t BLSTuning) Apply(p *BLSParams, numFree int) {
+	p.L0 = int(t.L0 * float64(numFree))
+	p.T = t.T
+	p.TabuMin = int((t.Tabu - 0.1) * float64(numFree))
+	p.TabuMax = int((t.Tabu + 0.1) * float64(numFree))
+
+	// Scale the other perturbation weights so that they keep their default proportions
+	def := DefaultBLSParams(numFree)
+	scale := (1 - t.RandomWeight) / (1 - def.RandomWeight)
+	p.RandomWeight = t.RandomWeight
+	p.PatternWeight = def.PatternWeight * scale
+	p.ColumnWeight = def.ColumnWeight * scale
+	p.RecencyWeight = def.RecencyWeight * scale
+}
+
+// String returns the tuning in the format read by ParseBLSTuning.
+func (t BLSTuning) String() string {
+	return fmt.Sprintf("l0=%g,t=%d,tabu=%g,random=%g", t.L0, t.T, t.Tabu, t.RandomWeight)
+}
+
+// ParseBLSTuning parses a comma-separated list of tuned parameters, such as
+// "l0=0.1,t=50,tabu=1,random=0.4". Parameters that are left out keep their default.
+func ParseBLSTuning(spec string) (*BLSTuning, error) {
+	t := DefaultBLSTuning()
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid BLS parameter %q: expected name=value", part)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		if name == "t" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid BLS parameter t=%s: must be a whole number of at least 1", value)
+			}
+			t.T = n
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BLS parameter %s=%s: %w", name, value, err)
+		}
+		switch name {
+		case "l0":
+			if v <= 0 || v > 1 {
+				return nil, fmt.Errorf("invalid BLS parameter l0=%s: must be above 0 and at most 1", value)
+			}
+			t.L0 = v
+		case "tabu":
+			if v < 0.1 || v > 5 {
+				return nil, fmt.Errorf("invalid BLS parameter tabu=%s: must be between 0.1 and 5", value)
+			}
+			t.Tabu = v
+		case "random":
+			if v < 0 || v > 1 {
+				return nil, fmt.Errorf("invalid BLS parameter random=%s: must be between 0 and 1", value)
+			}
+			t.RandomWeight = v
+		default:
+			return nil, fmt.Errorf("unknown BLS parameter %q (use l0, t, tabu or random)", name)
+		}
+	}
+	return &t, nil
+}
+
+// TuneInput holds the parameters of a BLS parameter search.
+type TuneInput struct {
+	Optimize  OptimizeInput // Layout, corpus, weights and pins of the probes, and their iterations
+	ProbeTime time.Duration // Time limit of each probe
+	Grid      bool          // Search a grid of parameters instead of random parameter sets
+	Samples   int           // Number of random parameter sets
+	Probes    int           // Number of probes (seeds) per parameter set
+	Seed      int64         // Seed of the random search and of the probes
+
+	Progress func(done, total int) // Optional: called after every probe
+}
+
+// TuneTrial is the outcome of the probes of one parameter set.
+type TuneTrial struct {
+	Tuning  BLSTuning
+	Costs   []float64 // Best cost of each probe
+	Mean    float64   // Mean best cost of the probes
+	Best    float64   // Lowest best cost of the probes
+	Default bool      // Whether these are the default parameters
+}
+
+// TuneResult holds the parameter sets of a search, from the lowest mean cost.
+type TuneResult struct {
+	Trials      []TuneTrial
+	InitialCost float64 // Cost of the layout the probes start from
+}
+
+// tuneGrid lists the values of each parameter in a grid search, centred on the defaults.
+var tuneGrid = struct {
+	L0, Tabu, RandomWeight []float64
+	T                      []int
+}{
+	L0:           []float64{0.05, 0.1, 0.2},
+	T:            []int{25, 50, 100},
+	Tabu:         []float64{0.5, 1.0, 1.5},
+	RandomWeight: []float64{0.2, 0.4, 0.6},
+}
+
+// tuneCandidates returns the parameter sets of a search, starting with the defaults.
+func tuneCandidates(input TuneInput) []BLSTuning {
+	candidates := []BLSTuning{DefaultBLSTuning()}
+	if input.Grid {
+		for _, l0 := range tuneGrid.L0 {
+			for _, t := range tuneGrid.T {
+				for _, tabu := range tuneGrid.Tabu {
+					for _, random := range tuneGrid.RandomWeight {
+						c := BLSTuning{L0: l0, T: t, Tabu: tabu, RandomWeight: random}
+						if c != candidates[0] {
+							candidates = append(candidates, c)
+						}
+					}
+				}
+			}
+		}
+		return candidates
+	}
+
+	rng := NewLockedRNG(uint64(input.Seed), 0)
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	for range input.Samples {
+		candidates = append(candidates, BLSTuning{
+			L0:           round(0.03 + rng.Float64()*0.27),
+			T:            int(math.Round(10 * math.Pow(20, rng.Float64()))), // Log-uniform in [10, 200]
+			Tabu:         round(0.3 + rng.Float64()*1.7),
+			RandomWeight: round(0.1 + rng.Float64()*0.7),
+		})
+	}
+	return candidates
+}
+
+// TuneBLS runs short optimizations ("probes") of the layout of the input with several BLS
+// parameter sets, and ranks the sets by the mean best cost of their probes. All sets are
+// probed with the same seeds, so that their results differ by their parameters rather than by
+// chance. Probes run concurrently, except when the scorer subsamples the corpus. Cancelling
+// ctx stops the search.
+func TuneBLS(ctx context.Context, input TuneInput) (*TuneResult, error) {
+	if input.Probes < 1 {
+		return nil, fmt.Errorf("number of probes must be at least 1")
+	}
+	if !input.Grid && input.Samples < 1 {
+		return nil, fmt.Errorf("number of samples must be at least 1")
+	}
+	numFree := 0
+	for _, isPinned := range input.Optimize.Pinned {
+		if !isPinned {
+			numFree++
+		}
+	}
+	if numFree == 0 {
+		return nil, fmt.Errorf("no free keys to optimize")
+	}
+
+	opt := input.Optimize
+	opt.Seed, opt.UseParallel = input.Seed, false
+	targets := withDefaultTargets(opt.Targets)
+	scorer, err := newOptimizeScorer(opt, targets, input.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := tuneCandidates(input)
+	trials := make([]TuneTrial, len(candidates))
+	for i, c := range candidates {
+		trials[i] = TuneTrial{Tuning: c, Costs: make([]float64, input.Probes), Default: i == 0}
+	}
+
+	type probe struct{ trial, seed int }
+	probes := make(chan probe)
+	go func() {
+		defer close(probes)
+		for p := range input.Probes {
+			for i := range trials {
+				select {
+				case probes <- probe{i, p}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	workers := runtime.GOMAXPROCS(0)
+	if scorer.Subsampled() {
+		workers = 1 // Resampling is not safe for concurrent searches
+	}
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	total := len(trials) * input.Probes
+	for range workers {
+		wg.Go(func() {
+			for p := range probes {
+				probeInput := opt
+				probeInput.Seed = DeriveSeed(input.Seed, p.seed)
+				probeInput.Tuning = &trials[p.trial].Tuning
+				params := optimizeParams(probeInput, numFree)
+				params.MaxTime = input.ProbeTime
+
+				bls := NewBLS(params, scorer, opt.Corpus, opt.Pinned)
+				if opt.SwapClasses != nil {
+					bls.RestrictSwapClasses(opt.Layout)
+				}
+				bls.Optimize(ctx, opt.Layout, nil)
+
+				mu.Lock()
+				trials[p.trial].Costs[p.seed] = bls.state.bestCost
+				done++
+				if input.Progress != nil {
+					input.Progress(done, total)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("parameter search stopped: %w", err)
+	}
+
+	for i := range trials {
+		trials[i].Mean, trials[i].Best = 0, math.Inf(1)
+		for _, cost := range trials[i].Costs {
+			trials[i].Mean += cost / float64(input.Probes)
+			trials[i].Best = min(trials[i].Best, cost)
+		}
+	}
+	slices.SortStableFunc(trials, func(a, b TuneTrial) int { return cmp.Compare(a.Mean, b.Mean) })
+
+	return &TuneResult{Trials: trials, InitialCost: scorer.ScoreExact(opt.Layout)}, nil
+}
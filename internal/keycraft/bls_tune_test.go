@@ -0,0 +1,119 @@
+package keycraft
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestBLSTuning_DefaultsMatchParams checks that applying the default tuning leaves the
+// default BLS parameters unchanged.
+func TestBLSTuning_DefaultsMatchParams(t *testing.T) {
+	for _, numFree := range []int{10, 30, 40} {
+		want := DefaultBLSParams(numFree)
+		got := DefaultBLSParams(numFree)
+		DefaultBLSTuning().Apply(&got, numFree)
+		if got.L0 != want.L0 || got.T != want.T || got.TabuMin != want.TabuMin || got.TabuMax != want.TabuMax {
+			t.Errorf("%d free keys: got L0 %d, T %d, tabu %d-%d, want L0 %d, T %d, tabu %d-%d", numFree,
+				got.L0, got.T, got.TabuMin, got.TabuMax, want.L0, want.T, want.TabuMin, want.TabuMax)
+		}
+		for _, w := range [][2]float64{
+			{got.RandomWeight, want.RandomWeight},
+			{got.PatternWeight, want.PatternWeight},
+			{got.ColumnWeight, want.ColumnWeight},
+			{got.RecencyWeight, want.RecencyWeight},
+		} {
+			if d := w[0] - w[1]; d > 1e-9 || d < -1e-9 {
+				t.Errorf("%d free keys: perturbation weight %g, want %g", numFree, w[0], w[1])
+			}
+		}
+	}
+}
+
+// TestParseBLSTuning checks parsing tunings, including their String form, and rejecting
+// malformed and out-of-range values.
+func TestParseBLSTuning(t *testing.T) {
+	want := BLSTuning{L0: 0.14, T: 78, Tabu: 0.41, RandomWeight: 0.21}
+	got, err := ParseBLSTuning(want.String())
+	if err != nil || *got != want {
+		t.Fatalf("ParseBLSTuning(%q) = %v, %v, want %v", want.String(), got, err, want)
+	}
+
+	got, err = ParseBLSTuning(" T=20 ")
+	if err != nil || *got != (BLSTuning{L0: 0.1, T: 20, Tabu: 1.0, RandomWeight: 0.4}) {
+		t.Errorf("ParseBLSTuning(\" T=20 \") = %v, %v, want only T changed", got, err)
+	}
+
+	for _, spec := range []string{"l0", "l0=x", "l0=0", "t=0", "t=1.5", "tabu=9", "random=-1", "decay=1"} {
+		if _, err := ParseBLSTuning(spec); err == nil {
+			t.Errorf("ParseBLSTuning(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// TestTuneBLS checks that a random search probes the defaults and every sample with every
+// seed, and ranks the parameter sets by their mean cost.
+func TestTuneBLS(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	pinned := &PinnedKeys{}
+	for i, r := range layout.Runes {
+		pinned[i] = r == 0 || r == ' '
+	}
+
+	calls := 0
+	result, err := TuneBLS(context.Background(), TuneInput{
+		Optimize: OptimizeInput{
+			Layout:         layout,
+			LayoutsDir:     dir,
+			Corpus:         corpus,
+			Weights:        NewWeights(),
+			Pinned:         pinned,
+			NumGenerations: 3,
+		},
+		ProbeTime: time.Minute,
+		Samples:   2,
+		Probes:    2,
+		Seed:      1,
+		Progress:  func(done, total int) { calls++ },
+	})
+	if err != nil {
+		t.Fatalf("TuneBLS: %v", err)
+	}
+	if len(result.Trials) != 3 || calls != 6 {
+		t.Fatalf("got %d trials and %d progress calls, want 3 and 6", len(result.Trials), calls)
+	}
+
+	defaults := 0
+	for i, trial := range result.Trials {
+		if trial.Default {
+			defaults++
+			if trial.Tuning != DefaultBLSTuning() {
+				t.Errorf("default trial has tuning %v", trial.Tuning)
+			}
+		}
+		if len(trial.Costs) != 2 || trial.Best > trial.Mean || trial.Mean > result.InitialCost {
+			t.Errorf("trial %d: costs %v, mean %g, best %g, initial cost %g",
+				i, trial.Costs, trial.Mean, trial.Best, result.InitialCost)
+		}
+		if i > 0 && trial.Mean < result.Trials[i-1].Mean {
+			t.Errorf("trial %d has mean %g below trial %d (%g)", i, trial.Mean, i-1, result.Trials[i-1].Mean)
+		}
+	}
+	if defaults != 1 {
+		t.Errorf("got %d default trials, want 1", defaults)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := TuneBLS(ctx, TuneInput{Optimize: OptimizeInput{Layout: layout, LayoutsDir: dir, Corpus: corpus,
+		Weights: NewWeights(), Pinned: pinned}, Samples: 1, Probes: 1}); err == nil {
+		t.Error("TuneBLS with a cancelled context succeeded, want an error")
+	}
+}
@@ -2,8 +2,10 @@ package keycraft
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -21,11 +23,28 @@ type PinnedKeys [42]bool
 //   - input: OptimizeInput with all optimization parameters
 //   - consoleWriter: Where to write human-readable progress (use os.Stdout or nil)
 //
-// When input.Medians and input.IQRs are both non-nil, a lightweight Scorer is created
-// using pre-computed stats (skipping LoadAnalysers). Otherwise, a full Scorer is created.
+// When input.Corpora holds more than one corpus, layouts are scored against each corpus
+// separately and the costs are combined by weight; input.Corpus (typically a blend of the
+// corpora) then only guides perturbations and history metrics. Otherwise, when input.Medians
+// and input.IQRs are both non-nil, a lightweight Scorer is created using pre-computed stats
+// (skipping LoadAnalysers), or else a full Scorer is created.
 //
-// Returns the optimized layout.
-func OptimizeLayoutBLS(input OptimizeInput, consoleWriter io.Writer) (*SplitLayout, error) {
+// Cancelling ctx stops the search early. Returns the optimized layout, and whether the
+// search was interrupted, in which case the layout is the best one found so far.
+func OptimizeLayoutBLS(ctx context.Context, input OptimizeInput, consoleWriter io.Writer) (*SplitLayout, bool, error) {
+	if input.SpillKeys != nil {
+		input.Layout = input.Layout.withSpillPlaceholders(input.SpillKeys)
+	}
+	bls, bestLayout, err := runBLS(ctx, input, consoleWriter)
+	if err != nil {
+		return nil, false, err
+	}
+	return bestLayout.withoutSpillPlaceholders(), bls.Interrupted(), nil
+}
+
+// runBLS runs the optimization of OptimizeLayoutBLS, and returns the optimizer with the
+// state of the search, and the optimized layout.
+func runBLS(ctx context.Context, input OptimizeInput, consoleWriter io.Writer) (*BLS, *SplitLayout, error) {
 	// Count free keys
 	numFree := 0
 	for _, isPinned := range input.Pinned {
@@ -35,10 +54,58 @@ func OptimizeLayoutBLS(input OptimizeInput, consoleWriter io.Writer) (*SplitLayo
 	}
 
 	if numFree == 0 {
-		return nil, fmt.Errorf("no free keys to optimize")
+		return nil, nil, fmt.Errorf("no free keys to optimize")
+	}
+
+	params := optimizeParams(input, numFree)
+	for _, wp := range input.Perturbations {
+		if err := params.AddPerturbation(wp.Strategy, wp.Weight); err != nil {
+			return nil, nil, fmt.Errorf("could not add perturbation: %w", err)
+		}
+	}
+	targets := withDefaultTargets(input.Targets)
+	scorer, err := newOptimizeScorer(input, targets, params.Seed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create BLS optimizer
+	bls := NewBLS(params, scorer, input.Corpus, input.Pinned)
+	if input.SwapClasses != nil {
+		bls.RestrictSwapClasses(input.Layout)
+	}
+	if input.HistoryFile != nil {
+		bls.SetHistoryRecorder(NewHistoryRecorder(input.HistoryFile, input.Corpus, targets))
+	}
+	bls.SetProgressFunc(input.Progress)
+
+	// Create logger with dual output: the console, and the log shared by all commands
+	logger := NewBLSLogger(consoleWriter, slog.Default().With("input_layout", input.Layout.Name))
+
+	// Run optimization
+	bestLayout := bls.Optimize(ctx, input.Layout, logger)
+
+	// Log scorer statistics if console writer provided
+	if consoleWriter != nil {
+		scorer.LogStats(consoleWriter)
+		if hits, misses := bls.SwapCacheStats(); hits+misses > 0 {
+			MustFprintf(consoleWriter, "Swap delta cache:        %s reused, %s scored (%.1f%% reused)\n\n",
+				formatInt(int64(hits)), formatInt(int64(misses)),
+				100*float64(hits)/float64(hits+misses))
+		}
 	}
 
-	// Create parameters with defaults, then override from arguments
+	// Log cache stats
+	stats := scorer.GetStats()
+	logger.LogCacheStats(uint64(stats.CacheHits), uint64(stats.CacheMisses),
+		stats.UniqueLayouts, stats.CacheEvictions, int64(stats.CacheSizeBytes))
+
+	return bls, bestLayout, nil
+}
+
+// optimizeParams returns the BLS parameters for the input: the defaults, overridden by the
+// generations, time limit, seed, tuning and compound moves of the input.
+func optimizeParams(input OptimizeInput, numFree int) BLSParams {
 	params := DefaultBLSParams(numFree)
 	if input.NumGenerations > 0 {
 		params.MaxIterations = input.NumGenerations
@@ -52,68 +119,53 @@ func OptimizeLayoutBLS(input OptimizeInput, consoleWriter io.Writer) (*SplitLayo
 		params.Seed = time.Now().UnixNano()
 	}
 	params.UseParallel = input.UseParallel
-
-	// Create scorer - use provided targets or defaults
-	targets := input.Targets
-	if targets == nil {
-		targets = &TargetLoads{
-			TargetHandLoad:   DefaultTargetHandLoad(),
-			TargetFingerLoad: DefaultTargetFingerLoad(),
-			TargetRowLoad:    DefaultTargetRowLoad(),
-			PinkyPenalties:   DefaultPinkyPenalties(),
-		}
-	}
-	if targets.TargetHandLoad == nil {
-		targets.TargetHandLoad = DefaultTargetHandLoad()
-	}
-	if targets.TargetFingerLoad == nil {
-		targets.TargetFingerLoad = DefaultTargetFingerLoad()
-	}
-	if targets.TargetRowLoad == nil {
-		targets.TargetRowLoad = DefaultTargetRowLoad()
+	if input.Tuning != nil {
+		input.Tuning.Apply(&params, numFree)
 	}
-	if targets.PinkyPenalties == nil {
-		targets.PinkyPenalties = DefaultPinkyPenalties()
+	if input.CompoundMoves {
+		params.EnableCompoundMoves()
 	}
+	return params
+}
 
-	// Create scorer: use pre-computed stats if available, otherwise load from disk
+// newOptimizeScorer creates the scorer for the input: a multi-corpus scorer for several
+// corpora, a lightweight scorer from pre-computed stats if available, or else a full scorer.
+// Subsampling draws its samples from seed. Changes from the input layout are penalised as
+// set by the input.
+func newOptimizeScorer(input OptimizeInput, targets *TargetLoads, seed int64) (*Scorer, error) {
 	var scorer *Scorer
-	if input.Medians != nil && input.IQRs != nil {
+	if len(input.Corpora) > 1 {
+		var err error
+		scorer, err = NewMultiCorpusScorer(input.LayoutsDir, input.Corpora, targets, input.Weights, input.Reference)
+		if err != nil {
+			return nil, fmt.Errorf("could not create scorer: %w", err)
+		}
+	} else if input.Medians != nil && input.IQRs != nil {
 		scorer = NewScorerWithStats(input.Corpus, targets, input.Medians, input.IQRs, input.FilteredWeights)
 	} else {
 		var err error
-		scorer, err = NewScorer(input.LayoutsDir, input.Corpus, targets, input.Weights)
+		scorer, err = NewScorer(input.LayoutsDir, input.Corpus, targets, input.Weights, input.Reference)
 		if err != nil {
 			return nil, fmt.Errorf("could not create scorer: %w", err)
 		}
 	}
-
-	// Create BLS optimizer
-	bls := NewBLS(params, scorer, input.Corpus, input.Pinned)
-
-	// Create logger with dual output
-	logger := NewBLSLogger(consoleWriter, input.LogFile)
-
-	// Run optimization
-	bestLayout := bls.Optimize(input.Layout, logger)
-
-	// Log scorer statistics if console writer provided
-	if consoleWriter != nil {
-		scorer.LogStats(consoleWriter)
+	if input.ScoreCacheSize > 0 {
+		scorer.SetCacheSize(input.ScoreCacheSize)
 	}
-
-	// Log cache stats to JSONL if file writer provided
-	if input.LogFile != nil {
-		hits := uint64(scorer.cacheHits.Load())
-		misses := uint64(scorer.cacheMisses.Load())
-		scorer.cacheMu.RLock()
-		uniqueKeys := len(scorer.scoreCache)
-		scorer.cacheMu.RUnlock()
-		memoryBytes := int64(uniqueKeys) * 8 // Rough estimate: 8 bytes per float64
-		logger.LogCacheStats(hits, misses, uniqueKeys, memoryBytes)
+	if input.Subsample > 0 {
+		if err := scorer.SetSubsample(input.Subsample, seed); err != nil {
+			return nil, err
+		}
 	}
-
-	return bestLayout, nil
+	if input.MaxChanges > 0 || input.ChangeWeight > 0 {
+		if err := scorer.SetChangePenalty(input.Layout, input.MaxChanges, input.ChangeWeight); err != nil {
+			return nil, err
+		}
+	}
+	if input.Taboo != nil {
+		scorer.SetTabooPatterns(input.Taboo, input.Layout)
+	}
+	return scorer, nil
 }
 
 // LoadPins loads a pins file specifying which keys should be fixed during optimization.
@@ -265,3 +317,38 @@ func LoadPinsFromParams(path, pins, free string, sl *SplitLayout) (*PinnedKeys,
 
 	return pinned, nil
 }
+
+// ShortcutPresets maps the preset names accepted by ParseShortcutKeys to the keys of their
+// Ctrl-based shortcuts.
+var ShortcutPresets = map[string]string{
+	"zxcv":   "zxcv",          // Undo, cut, copy and paste
+	"edit":   "azxcvy",        // zxcv, plus select all and redo
+	"common": "afnopqstwzxcv", // edit without redo, plus find, new, open, print, quit, save, tab and close
+}
+
+// ParseShortcutKeys returns the keys of a shortcut preset (see ShortcutPresets), or the
+// characters of spec itself if it is not a preset name. Keys are lowercased.
+func ParseShortcutKeys(spec string) (string, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return "", fmt.Errorf("shortcut keys are empty")
+	}
+	if keys, ok := ShortcutPresets[spec]; ok {
+		return keys, nil
+	}
+	return spec, nil
+}
+
+// PinShortcuts pins the keys of the shortcut characters where they are on the layout, so
+// that the shortcuts stay where the user's fingers expect them, whether the layout is
+// QWERTY-like, Dvorak or angle-modded. Shortcuts stay pinned even if they were freed.
+func (p *PinnedKeys) PinShortcuts(keys string, sl *SplitLayout) error {
+	for _, r := range keys {
+		key, ok := sl.RuneInfo[unicode.ToLower(r)]
+		if !ok {
+			return fmt.Errorf("cannot preserve unavailable shortcut key: %q (%U)", r, r)
+		}
+		p[key.Index] = true
+	}
+	return nil
+}
@@ -557,7 +557,7 @@ func createBenchBLS(b *testing.B) (*BLS, *SplitLayout) {
 	params := DefaultBLSParams(numFree)
 
 	// Create scorer
-	scorer, err := NewScorer("../../data/layouts", corpus, &TargetLoads{TargetRowLoad: DefaultTargetRowLoad(), TargetFingerLoad: DefaultTargetFingerLoad(), TargetHandLoad: DefaultTargetHandLoad(), PinkyPenalties: DefaultPinkyPenalties()}, NewWeights())
+	scorer, err := NewScorer("../../data/layouts", corpus, &TargetLoads{TargetRowLoad: DefaultTargetRowLoad(), TargetFingerLoad: DefaultTargetFingerLoad(), TargetHandLoad: DefaultTargetHandLoad(), PinkyPenalties: DefaultPinkyPenalties()}, NewWeights(), nil)
 	if err != nil {
 		b.Fatalf("Failed to create scorer: %v", err)
 	}
@@ -638,7 +638,7 @@ func TestIdentifyProblematicKeysEquivalence(t *testing.T) {
 	params := DefaultBLSParams(numFree)
 
 	// Create scorer
-	scorer, err := NewScorer("../../data/layouts", corpus, &TargetLoads{TargetRowLoad: DefaultTargetRowLoad(), TargetFingerLoad: DefaultTargetFingerLoad(), TargetHandLoad: DefaultTargetHandLoad(), PinkyPenalties: DefaultPinkyPenalties()}, NewWeights())
+	scorer, err := NewScorer("../../data/layouts", corpus, &TargetLoads{TargetRowLoad: DefaultTargetRowLoad(), TargetFingerLoad: DefaultTargetFingerLoad(), TargetHandLoad: DefaultTargetHandLoad(), PinkyPenalties: DefaultPinkyPenalties()}, NewWeights(), nil)
 	if err != nil {
 		t.Skipf("Skipping test - layouts not available: %v", err)
 	}
@@ -696,3 +696,44 @@ func TestIdentifyProblematicKeysEquivalence(t *testing.T) {
 		}
 	}
 }
+
+// TestParseShortcutKeys tests presets and literal shortcut keys.
+func TestParseShortcutKeys(t *testing.T) {
+	tests := map[string]string{
+		"zxcv":   "zxcv",
+		" Edit ": "azxcvy",
+		"ZXCVS":  "zxcvs",
+		"common": ShortcutPresets["common"],
+	}
+	for spec, want := range tests {
+		got, err := ParseShortcutKeys(spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+		} else if got != want {
+			t.Errorf("%q: got %q, want %q", spec, got, want)
+		}
+	}
+	if _, err := ParseShortcutKeys(" "); err == nil {
+		t.Error("expected error for empty shortcut keys")
+	}
+}
+
+// TestPinnedKeys_PinShortcuts tests that shortcut keys are pinned where they are, even when freed.
+func TestPinnedKeys_PinShortcuts(t *testing.T) {
+	layout := createTestLayout()
+	pinned := Must(LoadPinsFromParams("", "", "zb", layout))
+
+	if err := pinned.PinShortcuts("ZXCV", layout); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, p := range pinned {
+		want := layout.Runes[i] != 'b'
+		if p != want {
+			t.Errorf("key %d (%q): pinned = %v, want %v", i, layout.Runes[i], p, want)
+		}
+	}
+
+	if err := pinned.PinShortcuts("zä", layout); err == nil {
+		t.Error("expected error for a shortcut key that is not on the layout")
+	}
+}
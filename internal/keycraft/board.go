@@ -0,0 +1,261 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BoardPreset describes a popular split keyboard in terms of the 42 key positions of a
+// layout: the layout type, which of the positions the board has, and its key geometry.
+type BoardPreset struct {
+	Name        string
+	Description string
+	LayoutType  LayoutType
+	Keys        [42]bool       // Key positions of the board
+	NumberKeys  [12]bool       // Keys of the number row of the board, by column (none = no number row)
+	Geometry    *BoardGeometry // Key pitch and stagger of the board
+}
+
+// boardKeys returns the key positions of a board with 3 rows of 5 or 6 keys per hand, and
+// 2 or 3 thumb keys per hand. Boards with 2 thumb keys have the home and inner thumb keys.
+func boardKeys(outerColumns bool, thumbKeys int) [42]bool {
+	return boardRowKeys([3]bool{true, true, true}, outerColumns, thumbKeys)
+}
+
+// boardRowKeys returns the key positions of a board that has the given rows (top, home and
+// bottom) of the 3-row grid, as boardKeys does for boards with all 3 rows.
+func boardRowKeys(rows [3]bool, outerColumns bool, thumbKeys int) [42]bool {
+	var keys [42]bool
+	for i := range 36 {
+		col := i % 12
+		keys[i] = rows[i/12] && (outerColumns || (col != 0 && col != 11))
+	}
+	keys[37], keys[38], keys[39], keys[40] = true, true, true, true
+	keys[36], keys[41] = thumbKeys == 3, thumbKeys == 3
+	return keys
+}
+
+// boardNumberKeys returns the keys of a number row of 5 or 6 keys per hand.
+func boardNumberKeys(outerColumns bool) [12]bool {
+	var keys [12]bool
+	for col := range keys {
+		keys[col] = outerColumns || (col != 0 && col != 11)
+	}
+	return keys
+}
+
+// columnStaggeredGeometry returns the geometry of a column-staggered board with the given
+// key pitch and the column stagger of the left hand (mirrored for the right hand), in mm.
+func columnStaggeredGeometry(pitchX, pitchY float64, stagger [6]float64) *BoardGeometry {
+	g := &BoardGeometry{PitchX: pitchX, PitchY: pitchY}
+	for col, offset := range stagger {
+		g.ColumnStagger[col] = offset
+		g.ColumnStagger[11-col] = offset
+	}
+	return g
+}
+
+// BoardPresets are the built-in boards of --board. Outer columns and thumb keys that are
+// typically used for modifiers are still positions of the board. The number row of 4-row
+// boards is the number row of their layouts (see SetNumberRow).
+var BoardPresets = []BoardPreset{
+	{
+		Name:        "ferris",
+		Description: "Ferris Sweep: 34 keys, 3x5 and 2 thumb keys per hand, choc spacing",
+		LayoutType:  COLSTAG,
+		Keys:        boardKeys(false, 2),
+		Geometry:    columnStaggeredGeometry(18, 17, [6]float64{8.5, 8.5, 2, 0, 2.5, 5}),
+	},
+	{
+		Name:        "corne",
+		Description: "Corne: 42 keys, 3x6 and 3 thumb keys per hand",
+		LayoutType:  COLSTAG,
+		Keys:        boardKeys(true, 3),
+		Geometry:    columnStaggeredGeometry(19, 19, [6]float64{7.1, 7.1, 2.4, 0, 2.4, 4.8}),
+	},
+	{
+		Name:        "lily58",
+		Description: "Lily58: the number row, 3x6 and 3 thumb keys per hand of its 58 keys",
+		LayoutType:  COLSTAG,
+		Keys:        boardKeys(true, 3),
+		NumberKeys:  boardNumberKeys(true),
+		Geometry:    columnStaggeredGeometry(19, 19, [6]float64{6.3, 6.3, 2.4, 0, 2.4, 4.8}),
+	},
+	{
+		Name:        "preonic",
+		Description: "Preonic: the number row, 3x6 and the 3 inner keys of the bottom row per hand of its 60 keys",
+		LayoutType:  ORTHO,
+		Keys:        boardKeys(true, 3),
+		NumberKeys:  boardNumberKeys(true),
+		Geometry:    NewBoardGeometry(),
+	},
+	{
+		Name:        "atreus",
+		Description: "Atreus: 3x5 and 2 thumb keys per hand of its 42 or 44 keys (the bottom row is not used)",
+		LayoutType:  COLSTAG,
+		Keys:        boardKeys(false, 2),
+		Geometry:    columnStaggeredGeometry(19, 19, [6]float64{6.5, 6.5, 2.5, 0, 2.5, 5}),
+	},
+	{
+		Name:        "2row",
+		Description: "Experimental 2-row board: the top and home rows of 5 keys and 2 thumb keys per hand (24 keys)",
+		LayoutType:  ORTHO,
+		Keys:        boardRowKeys([3]bool{true, true, false}, false, 2),
+		Geometry:    NewBoardGeometry(),
+	},
+}
+
+// BoardPresetNames returns the names of the built-in boards.
+func BoardPresetNames() []string {
+	names := make([]string, len(BoardPresets))
+	for i, b := range BoardPresets {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// ParseBoardPreset returns the built-in board with the given name.
+func ParseBoardPreset(name string) (*BoardPreset, error) {
+	i := slices.IndexFunc(BoardPresets, func(b BoardPreset) bool {
+		return strings.EqualFold(b.Name, name)
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("unknown board %q; must be one of: %s", name, strings.Join(BoardPresetNames(), ", "))
+	}
+	return &BoardPresets[i], nil
+}
+
+// NumKeys returns the number of key positions of the board.
+func (b *BoardPreset) NumKeys() int {
+	n := 0
+	for _, ok := range b.Keys {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// HasNumberRow reports whether the board has a number row.
+func (b *BoardPreset) HasNumberRow() bool {
+	return b.NumberKeys != [12]bool{}
+}
+
+// CheckLayout returns an error if the layout has characters on keys that the board doesn't
+// have, including those of its number row.
+func (b *BoardPreset) CheckLayout(sl *SplitLayout) error {
+	var missing []string
+	for i, r := range sl.Runes {
+		if r != 0 && !b.Keys[i] {
+			missing = append(missing, string(r))
+		}
+	}
+	if sl.NumberRow != nil {
+		for col, r := range sl.NumberRow {
+			if r != 0 && !b.NumberKeys[col] {
+				missing = append(missing, string(r))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("layout %s does not fit the %s board: %s are on keys that the board doesn't have",
+			sl.Name, b.Name, strings.Join(missing, " "))
+	}
+	return nil
+}
+
+// SetBoard records the board the layout was made for, and analyses the layout with the
+// geometry of the board, recomputing key distances and the derived caches. A board geometry
+// in use, as set by --geometry-file or --board, takes precedence over that of the layout. It
+// returns an error if the layout does not fit the board.
+func (sl *SplitLayout) SetBoard(b *BoardPreset) error {
+	if err := b.CheckLayout(sl); err != nil {
+		return err
+	}
+	sl.Board = b.Name
+	thumbs := DefaultThumbGeometry(sl.LayoutType)
+	if sl.Thumbs != nil {
+		thumbs = *sl.Thumbs
+	}
+	sl.SetThumbGeometry(thumbs)
+	return nil
+}
+
+// geometry returns the board geometry the layout is analysed with: the board geometry in
+// use, or else the geometry of the board of the layout, or nil for the built-in presets.
+func (sl *SplitLayout) geometry() *BoardGeometry {
+	if boardGeometry != nil || sl.Board == "" {
+		return boardGeometry
+	}
+	if b, err := ParseBoardPreset(sl.Board); err == nil {
+		return b.Geometry
+	}
+	return nil
+}
+
+// numberRow returns the number row of the layouts generated for the board: the digits 1 to 9
+// and 0 from the second column, on the keys of the board, or nil if it has no number row.
+func (b *BoardPreset) numberRow() *[12]rune {
+	if !b.HasNumberRow() {
+		return nil
+	}
+	var row [12]rune
+	for i, r := range "1234567890" {
+		if b.NumberKeys[i+1] {
+			row[i+1] = r
+		}
+	}
+	return &row
+}
+
+// ApplyToConfig makes a generation config generate layouts for the board: it sets the
+// layout type of the board, and returns an error if the template allocates keys that the
+// board doesn't have.
+func (b *BoardPreset) ApplyToConfig(config *GenerationConfig) error {
+	var missing []string
+	for i, spec := range config.Template {
+		if spec.Type != PositionUnused && !b.Keys[i] {
+			missing = append(missing, fmt.Sprintf("row %d col %d", i/12+1, i%12+1))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the template allocates keys that the %s board doesn't have (use ~ for them): %s",
+			b.Name, strings.Join(missing, ", "))
+	}
+	config.LayoutType = b.LayoutType
+	config.Board = b.Name
+	return nil
+}
+
+// boardCharset is the charset of the generation configs of the built-in boards: the
+// letters and 4 punctuation characters on the 30 main keys, and space on a thumb key.
+const boardCharset = "etaoinshrdlcumwfgypbvkjxqz,.'/_"
+
+// GenerationConfig returns a generation config for the board, for generating layouts
+// without a .gen file: the letters and the punctuation characters ,.'/ are placed randomly
+// on the main keys of the board in the 5 inner columns of each hand, and space on the inner
+// right thumb key. Outer columns and the other thumb keys are left empty. Boards with fewer
+// than 30 such keys, such as 2-row boards, get the most frequent characters only, and 4-row
+// boards get the digits on their number row.
+func (b *BoardPreset) GenerationConfig() *GenerationConfig {
+	config := &GenerationConfig{
+		LayoutType: b.LayoutType,
+		Groups:     make(map[int][]rune),
+		FilePath:   "board " + b.Name,
+		LineNums:   make(map[string]int),
+		Board:      b.Name,
+		NumberRow:  b.numberRow(),
+	}
+	random := 0
+	for i := range 36 {
+		if col := i % 12; b.Keys[i] && col != 0 && col != 11 {
+			config.Template[i] = PositionSpec{Type: PositionRandom}
+			random++
+		}
+	}
+	config.Template[39] = PositionSpec{Type: PositionFixed, FixedChar: ' '}
+	charset := parseCharsetValue(boardCharset)
+	config.Charset = append(charset[:min(random, len(charset)-1)], ' ')
+	return config
+}
@@ -0,0 +1,145 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBoardPresets(t *testing.T) {
+	for name, want := range map[string]int{"ferris": 34, "corne": 42, "lily58": 42, "preonic": 42, "atreus": 34, "2row": 24} {
+		board := Must(ParseBoardPreset(name))
+		if got := board.NumKeys(); got != want {
+			t.Errorf("%s has %d keys, want %d", name, got, want)
+		}
+
+		// The generation config of the board fits the board, and generates a valid layout
+		config := board.GenerationConfig()
+		if err := ValidateConfig(config); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if err := board.ApplyToConfig(config); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		perms, _, _ := GeneratePermutations(config, 1)
+		layout := GenerateLayout(config, perms[0], 1, 0)
+		if err := board.CheckLayout(layout); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if layout.Board != board.Name {
+			t.Errorf("%s: generated layout has board %q", name, layout.Board)
+		}
+		if (layout.NumberRow != nil) != board.HasNumberRow() {
+			t.Errorf("%s: generated layout has number row %v", name, layout.NumberRow)
+		}
+	}
+
+	// A 4-row board gets the digits on its number row
+	layout := GenerateLayout(Must(ParseBoardPreset("lily58")).GenerationConfig(), nil, 1, 0)
+	if layout.NumberRow == nil || string(layout.NumberRow[1:11]) != "1234567890" {
+		t.Errorf("lily58 number row = %v, want the digits 1 to 0", layout.NumberRow)
+	}
+
+	// A 2-row board gets the most frequent characters on its 20 main keys, and space
+	config := Must(ParseBoardPreset("2row")).GenerationConfig()
+	if got := string(config.Charset); got != "etaoinshrdlcumwfgypb " {
+		t.Errorf("2row charset = %q, want the 20 most frequent characters and space", got)
+	}
+
+	if _, err := ParseBoardPreset("planck"); err == nil {
+		t.Error("ParseBoardPreset(planck): expected an error")
+	}
+}
+
+func TestBoardPreset_CheckLayout(t *testing.T) {
+	ferris := Must(ParseBoardPreset("Ferris"))
+	sl := NewSplitLayout("qwerty", ROWSTAG, qwertyRunes())
+	err := ferris.CheckLayout(sl)
+	if err == nil || !strings.Contains(err.Error(), "1 [ 2 ' 3 4") {
+		t.Errorf("error = %v, want the keys of the outer columns", err)
+	}
+	if err := Must(ParseBoardPreset("corne")).CheckLayout(sl); err != nil {
+		t.Errorf("corne: %v", err)
+	}
+
+	// A number row only fits a 4-row board
+	Must0(sl.SetNumberRow([12]rune{0, '!', '@', '#', '$', '%', '^', '&', '*', '(', ')', 0}))
+	err = Must(ParseBoardPreset("corne")).CheckLayout(sl)
+	if err == nil || !strings.Contains(err.Error(), "! @ #") {
+		t.Errorf("error = %v, want the keys of the number row", err)
+	}
+	if err := Must(ParseBoardPreset("lily58")).CheckLayout(sl); err != nil {
+		t.Errorf("lily58: %v", err)
+	}
+
+	config := Must(ParseConfigString("rowstag\n" + strings.Repeat("~ 0 ~ ~ ~ ~  ~ ~ ~ ~ ~ ~\n", 3) +
+		"0 ~ ~  ~ ~ ~\ncharset=abcd\n"))
+	err = ferris.ApplyToConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "row 4 col 1") {
+		t.Errorf("error = %v, want the outer thumb key", err)
+	}
+	if config.LayoutType != ROWSTAG {
+		t.Errorf("layout type = %v, want it unchanged after an error", config.LayoutType)
+	}
+}
+
+func TestBoardSetting(t *testing.T) {
+	sl := NewSplitLayout("qwerty", ROWSTAG, qwertyRunes())
+	sl.Board = "corne"
+	path := filepath.Join(t.TempDir(), "qwerty.klf")
+	Must0(sl.SaveToFile(path))
+	saved := Must(NewLayoutFromFile("qwerty", path))
+	if saved.Board != "corne" {
+		t.Errorf("board = %q, want corne", saved.Board)
+	}
+	if got := GroupByBoard.Key(saved); got != "corne" {
+		t.Errorf("group-by board key = %q, want corne", got)
+	}
+
+	// The layout must fit the board, and the board must be known
+	for _, board := range []string{"ferris", "planck"} {
+		sl.Board = board
+		Must0(sl.SaveToFile(path))
+		if _, err := NewLayoutFromFile("qwerty", path); err == nil {
+			t.Errorf("board %s: expected an error", board)
+		}
+	}
+}
+
+func TestBoardSetting_Geometry(t *testing.T) {
+	defer UseBoardGeometry(nil)
+
+	ferris := Must(ParseBoardPreset("ferris"))
+	runes := qwertyRunes()
+	for i := range 36 {
+		if !ferris.Keys[i] {
+			runes[i] = 0
+		}
+	}
+	sl := NewSplitLayout("qwerty", COLSTAG, runes)
+	Must0(sl.SetBoard(ferris))
+	path := filepath.Join(t.TempDir(), "qwerty.klf")
+	Must0(sl.SaveToFile(path))
+
+	// A layout with a board is analysed with the geometry of the board: q (1) to s (14)
+	want := newKeyDistances(ferris.Geometry, COLSTAG, VariantNone, DefaultThumbGeometry(COLSTAG))[KeyPair{1, 14}]
+	saved := Must(NewLayoutFromFile("qwerty", path))
+	if got := *saved.MustDistance(1, 14); got != want {
+		t.Errorf("q-s distance = %+v, want %+v of the ferris geometry", got, want)
+	}
+	if plain := *NewSplitLayout("qwerty", COLSTAG, runes).MustDistance(1, 14); plain == want {
+		t.Fatal("the ferris geometry should change the q-s distance")
+	}
+	if layoutCacheKey(saved) == layoutCacheKey(NewSplitLayout("qwerty", COLSTAG, runes)) {
+		t.Error("the board should be part of the cache key")
+	}
+
+	// A board geometry in use takes precedence
+	g := NewBoardGeometry()
+	UseBoardGeometry(g)
+	want = newKeyDistances(g, COLSTAG, VariantNone, DefaultThumbGeometry(COLSTAG))[KeyPair{1, 14}]
+	saved = Must(NewLayoutFromFile("qwerty", path))
+	if got := *saved.MustDistance(1, 14); got != want {
+		t.Errorf("q-s distance = %+v, want %+v of the geometry in use", got, want)
+	}
+}
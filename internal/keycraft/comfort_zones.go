@@ -0,0 +1,84 @@
+package keycraft
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// ComfortZones assigns each of the 42 key positions to a comfort tier, from 0 (tier A, the
+// most comfortable) to 3 (tier D, the least comfortable).
+type ComfortZones [42]uint8
+
+// ZoneMetrics are the metrics of the comfort tiers A-D, in the order of the tiers.
+var ZoneMetrics = []string{"ZONE-A", "ZONE-B", "ZONE-C", "ZONE-D"}
+
+// defaultComfortZones are the comfort zones of DefaultComfortZones.
+var defaultComfortZones = Must(parseComfortZones(
+	"DCBBBC CBBBCD " + // top row
+		"CBAAAB BAAABC " + // home row
+		"DDCCBC CBCCDD " + // bottom row
+		"CAB BAC", // thumbs: outer, home, inner (left), inner, home, outer (right)
+))
+
+// DefaultComfortZones returns the default comfort zones: the home keys of the ring, middle
+// and index fingers and the home thumb keys are tier A, and the corners of the board tier D.
+func DefaultComfortZones() *ComfortZones {
+	zones := *defaultComfortZones
+	return &zones
+}
+
+// SetComfortZones parses and sets the comfort zones from a string of 42 tiers A-D, one for
+// each key position in layout order (the top, home and bottom rows of 12 keys, then the 6
+// thumb keys). Whitespace is ignored, so the tiers can be grouped by row and hand.
+func (tl *TargetLoads) SetComfortZones(s string) error {
+	zones, err := parseComfortZones(s)
+	if err != nil {
+		return err
+	}
+	tl.ComfortZones = zones
+	return nil
+}
+
+// parseComfortZones parses a string of 42 tiers A-D, ignoring whitespace.
+func parseComfortZones(s string) (*ComfortZones, error) {
+	var zones ComfortZones
+	n := 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		tier := unicode.ToUpper(r) - 'A'
+		if tier < 0 || tier > 3 {
+			return nil, fmt.Errorf("invalid comfort tier %q at position %d; must be one of A, B, C, D", r, n)
+		}
+		if n < len(zones) {
+			zones[n] = uint8(tier)
+		}
+		n++
+	}
+	if n != len(zones) {
+		return nil, fmt.Errorf("comfort-zones must have a tier for each of the 42 key positions (got %d)", n)
+	}
+	return &zones, nil
+}
+
+// setZoneMetrics computes ZONE-A to ZONE-D, the % of keystrokes typed with keys in each
+// comfort tier, from the unigram counts per key.
+func (an *Analyser) setZoneMetrics() {
+	zones := defaultComfortZones
+	if an.Targets != nil && an.Targets.ComfortZones != nil {
+		zones = an.Targets.ComfortZones
+	}
+	var tierCount [4]uint64
+	var total uint64
+	for idx, cnt := range an.counts.keys {
+		tierCount[zones[idx]] += cnt
+		total += cnt
+	}
+	for tier, metric := range ZoneMetrics {
+		an.Metrics[metric] = 0
+		if total > 0 {
+			an.Metrics[metric] = 100 * float64(tierCount[tier]) / float64(total)
+		}
+	}
+}
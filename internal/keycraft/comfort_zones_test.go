@@ -0,0 +1,72 @@
+package keycraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetComfortZones(t *testing.T) {
+	tl := &TargetLoads{}
+	if err := tl.SetComfortZones(strings.Repeat("ab cd ", 10) + "dd"); err != nil {
+		t.Fatal(err)
+	}
+	if tl.ComfortZones[0] != 0 || tl.ComfortZones[3] != 3 || tl.ComfortZones[41] != 3 {
+		t.Errorf("comfort zones = %v", *tl.ComfortZones)
+	}
+
+	for _, s := range []string{strings.Repeat("A", 41), strings.Repeat("A", 43), strings.Repeat("A", 41) + "E"} {
+		if err := tl.SetComfortZones(s); err == nil {
+			t.Errorf("SetComfortZones(%q): expected an error", s)
+		}
+	}
+}
+
+func TestAnalyser_ZoneMetrics(t *testing.T) {
+	var runes [42]rune
+	runes[0], runes[13], runes[15] = 'q', 'a', 'd'
+	sl := NewSplitLayout("test", ORTHO, runes)
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("dad qa d")
+
+	// d: 3 (A), a: 2 (B), q: 1 (D)
+	an := NewAnalyser(sl, corpus, nil)
+	for metric, want := range map[string]float64{"ZONE-A": 50, "ZONE-B": 100.0 / 3, "ZONE-C": 0, "ZONE-D": 100.0 / 6} {
+		if got := an.Metrics[metric]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", metric, got, want)
+		}
+	}
+
+	// Swapping keys moves their keystrokes to the tiers of the other key
+	sl.Swap(0, 15)
+	want := NewAnalyser(sl, corpus, nil)
+	sl.Swap(0, 15)
+	an.ApplySwap(0, 15)
+	assertSameMetrics(t, an, want)
+	if got := an.Metrics["ZONE-D"]; got != 50 {
+		t.Errorf("ZONE-D after swap = %v, want 50", got)
+	}
+}
+
+func TestNewTargetLoadsFromFile_ComfortZones(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	Must0(os.WriteFile(path, []byte("comfort-zones = "+strings.Repeat("C", 42)+"\n"), 0644))
+	targets := Must(NewTargetLoadsFromFile(path))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox")
+	an := NewAnalyser(NewSplitLayout("test", ORTHO, qwertyRunes()), corpus, targets)
+	if got := an.Metrics["ZONE-C"]; got != 100 {
+		t.Errorf("ZONE-C = %v, want 100", got)
+	}
+
+	Must0(os.WriteFile(path, []byte("comfort-zones = ABC\n"), 0644))
+	if _, err := NewTargetLoadsFromFile(path); err == nil || !strings.Contains(err.Error(), "comfort-zones") {
+		t.Errorf("error = %v, want an invalid comfort-zones", err)
+	}
+
+	if got := NewTargetLoads().ComfortZones; *got != *DefaultComfortZones() {
+		t.Errorf("default comfort zones = %v", *got)
+	}
+}
@@ -0,0 +1,160 @@
+package keycraft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// LayoutFormat is a file format for layouts.
+type LayoutFormat struct {
+	Name string // Name of the format, as given to convert-files
+	Ext  string // File extension, with the dot
+	Load func(name, path string, layoutType LayoutType) (*SplitLayout, error)
+	Save func(sl *SplitLayout, path string) error
+}
+
+// LayoutFormats are the supported layout file formats: the .klf files of keycraft, and the
+// JSON files of keyboard-layout-editor.com.
+var LayoutFormats = []LayoutFormat{
+	{
+		Name: "klf",
+		Ext:  ".klf",
+		Load: func(name, path string, _ LayoutType) (*SplitLayout, error) {
+			return NewLayoutFromFile(name, path)
+		},
+		Save: (*SplitLayout).SaveToFile,
+	},
+	{
+		Name: "kle",
+		Ext:  ".json",
+		Load: NewLayoutFromKLE,
+		Save: (*SplitLayout).SaveToKLE,
+	},
+}
+
+// LayoutFormatNames returns the names of the supported layout file formats.
+func LayoutFormatNames() []string {
+	names := make([]string, len(LayoutFormats))
+	for i, f := range LayoutFormats {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// ParseLayoutFormat returns the layout file format with the given name.
+func ParseLayoutFormat(name string) (LayoutFormat, error) {
+	i := slices.IndexFunc(LayoutFormats, func(f LayoutFormat) bool {
+		return strings.EqualFold(f.Name, name)
+	})
+	if i < 0 {
+		return LayoutFormat{}, fmt.Errorf("unknown layout format %q; must be one of: %s",
+			name, strings.Join(LayoutFormatNames(), ", "))
+	}
+	return LayoutFormats[i], nil
+}
+
+// ConvertInput contains parameters for converting layout files between formats.
+type ConvertInput struct {
+	Paths      []string     // Files and directories to convert; directories convert all files of the From format
+	From       LayoutFormat // Format of the source files
+	To         LayoutFormat // Format of the converted files
+	OutDir     string       // Directory of the converted files ("" = the directory of each source file)
+	Force      bool         // Overwrite existing files
+	LayoutType LayoutType   // Layout type of source files that don't specify one (KLE files)
+}
+
+// ConvertedFile is the outcome of converting one layout file.
+type ConvertedFile struct {
+	Source  string
+	Target  string
+	Skipped bool  // The target exists and was not overwritten
+	Err     error // Why the file could not be converted (nil = converted)
+}
+
+// ConvertResult contains the outcome of converting layout files.
+type ConvertResult struct {
+	From, To string
+	Files    []ConvertedFile
+}
+
+// NumConverted returns the number of files that were converted.
+func (r *ConvertResult) NumConverted() int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Err == nil && !f.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// NumFailed returns the number of files that could not be converted.
+func (r *ConvertResult) NumFailed() int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// ConvertLayoutFiles converts layout files from one format to another. Each file is
+// converted on its own: a file that fails to load or save is reported in the result and
+// doesn't stop the others. An error is returned only if the paths can't be read.
+func ConvertLayoutFiles(input ConvertInput) (*ConvertResult, error) {
+	if input.From.Name == input.To.Name {
+		return nil, fmt.Errorf("source and target format are both %s", input.From.Name)
+	}
+	if input.OutDir != "" {
+		if err := os.MkdirAll(input.OutDir, 0755); err != nil {
+			return nil, fmt.Errorf("could not create output directory: %w", err)
+		}
+	}
+
+	var sources []string
+	for _, path := range input.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			sources = append(sources, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read layout directory %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), input.From.Ext) {
+				sources = append(sources, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(sources)
+
+	result := &ConvertResult{From: input.From.Name, To: input.To.Name}
+	for _, source := range sources {
+		name := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+		dir := input.OutDir
+		if dir == "" {
+			dir = filepath.Dir(source)
+		}
+		file := ConvertedFile{Source: source, Target: filepath.Join(dir, name+input.To.Ext)}
+
+		if _, err := os.Stat(file.Target); err == nil && !input.Force {
+			file.Skipped = true
+		} else if sl, err := input.From.Load(name, source, input.LayoutType); err != nil {
+			file.Err = err
+		} else if err := input.To.Save(sl, file.Target); err != nil {
+			file.Err = err
+		}
+		result.Files = append(result.Files, file)
+	}
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertLayoutFiles(t *testing.T) {
+	dir := t.TempDir()
+	Must0(os.WriteFile(filepath.Join(dir, "a.klf"), []byte(testLayoutKlf), 0644))
+	Must0(os.WriteFile(filepath.Join(dir, "bad.klf"), []byte("rowstag\nq w e\n"), 0644))
+	Must0(os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a layout"), 0644))
+
+	klf := Must(ParseLayoutFormat("klf"))
+	kle := Must(ParseLayoutFormat("KLE"))
+	out := filepath.Join(dir, "kle")
+	result := Must(ConvertLayoutFiles(ConvertInput{Paths: []string{dir}, From: klf, To: kle, OutDir: out}))
+	if len(result.Files) != 2 || result.NumConverted() != 1 || result.NumFailed() != 1 {
+		t.Fatalf("files = %+v, want a.klf converted and bad.klf failed", result.Files)
+	}
+	if result.Files[0].Target != filepath.Join(out, "a.json") || result.Files[1].Err == nil {
+		t.Errorf("files = %+v", result.Files)
+	}
+
+	// Converting back gives the same layout; existing files are skipped unless forced
+	back := ConvertInput{Paths: []string{out}, From: kle, To: klf, OutDir: out}
+	result = Must(ConvertLayoutFiles(back))
+	if result.NumConverted() != 1 {
+		t.Fatalf("files = %+v", result.Files)
+	}
+	want := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	if got := Must(NewLayoutFromFile("a", filepath.Join(out, "a.klf"))); got.ID() != want.ID() {
+		t.Errorf("converted layout %s, want %s", got, want)
+	}
+	if result = Must(ConvertLayoutFiles(back)); !result.Files[0].Skipped {
+		t.Errorf("existing file was not skipped: %+v", result.Files[0])
+	}
+	back.Force = true
+	if result = Must(ConvertLayoutFiles(back)); result.NumConverted() != 1 {
+		t.Errorf("existing file was not overwritten: %+v", result.Files[0])
+	}
+
+	if _, err := ParseLayoutFormat("xml"); err == nil {
+		t.Error("ParseLayoutFormat(xml): expected an error")
+	}
+}
@@ -2,10 +2,15 @@ package keycraft
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"unicode"
@@ -143,17 +148,74 @@ type Corpus struct {
 	Words map[string]uint64
 	// TotalWordsCount is the total number of word instances observed across the entire corpus.
 	TotalWordsCount uint64
+
+	// WordInitialBigrams maps each bigram formed by the first two characters of a word to
+	// the number of times it starts a word. Recorded for all words, before coverage pruning.
+	// Nil for corpora cached before word-position tables were introduced.
+	WordInitialBigrams map[Bigram]uint64
+	// WordFinalBigrams maps each bigram formed by the last two characters of a word to
+	// the number of times it ends a word. Recorded for all words, before coverage pruning.
+	WordFinalBigrams map[Bigram]uint64
+
+	// Stream holds the start of the corpus text in typing order, lowercased, with lines
+	// separated by newlines and capped at about StreamSize bytes. It is used by metrics that
+	// depend on more keystrokes than a trigram. Empty for corpora cached before it was recorded.
+	Stream string `json:",omitempty"`
+
+	// Version is the format version of the cache that the corpus was built for (see
+	// CorpusCacheVersion). 0 for corpora cached before versions were recorded.
+	Version int `json:",omitempty"`
+
+	// Cased is whether the n-gram tables keep the case of the text, so that an uppercase
+	// letter is analysed as Shift plus its lowercase key. Words and Stream are lowercased
+	// regardless.
+	Cased bool `json:",omitempty"`
+
+	// Spaced is whether the n-gram tables include the space between words, so that the space
+	// key press counts towards every metric, such as alternations and rolls onto the thumb.
+	// A run of whitespace is typed as one space, and n-grams do not cross line breaks.
+	Spaced bool `json:",omitempty"`
+
+	// Skipgram is how the skipgrams were counted (see SkipgramPolicy). The zero value is the
+	// default of one skipped character.
+	Skipgram SkipgramPolicy `json:",omitzero"`
+
+	// Substitutions is the substitution table that was applied to the text before counting
+	// n-grams (see LoadSubstitutions), with the number of characters replaced by every entry.
+	// Nil if the corpus was built without one.
+	Substitutions []CharSubstitution `json:",omitempty"`
+
+	// skipgramWeights holds the weighted skipgrams of a weighted Skipgram policy while the
+	// corpus is built, until finishSkipgrams rounds them to skipgram counts.
+	skipgramWeights map[Skipgram]float64
+
+	// substitutions and substituted hold the substitution table and the number of characters
+	// it replaced while the corpus is built, until finishSubstitutions records them.
+	substitutions map[rune]rune
+	substituted   map[rune]uint64
 }
 
+// StreamSize is the size in bytes of the text recorded in Corpus.Stream.
+const StreamSize = 1 << 20
+
+// CorpusCacheVersion is the format version of corpus caches. It is bumped when a corpus
+// records a table that older caches lack, so that those caches are rebuilt from their source
+// file. Version 1 records Stream.
+const CorpusCacheVersion = 1
+
 // NewCorpus creates and returns a new empty Corpus with the given name.
 func NewCorpus(name string) *Corpus {
 	return &Corpus{
 		Name:      name,
+		Version:   CorpusCacheVersion,
 		Unigrams:  make(map[Unigram]uint64),
 		Bigrams:   make(map[Bigram]uint64),
 		Trigrams:  make(map[Trigram]uint64),
 		Skipgrams: make(map[Skipgram]uint64),
 		Words:     make(map[string]uint64),
+
+		WordInitialBigrams: make(map[Bigram]uint64),
+		WordFinalBigrams:   make(map[Bigram]uint64),
 	}
 }
 
@@ -205,31 +267,93 @@ func (c *Corpus) String() string {
 }
 
 // NewCorpusFromFile creates a new Corpus with the given name by loading data from the specified text file.
+// A file with a word and its count on every line is read as a word-frequency list instead,
+// and the n-grams are synthesised from the words and their counts.
 // It attempts to load from a cached JSON file if it exists and is newer than the source text file.
 // If no valid cache is found, it loads from the text file and saves a JSON cache for future use.
 // If forceReload is true, it skips loading from JSON and always rebuilds from text.
 func NewCorpusFromFile(name, path string, forceReload bool, coveragePercent float64) (*Corpus, error) {
-	// Compute the JSON filename in the same directory as filename
-	jsonPath := path + ".json"
+	return NewCorpusFromFileWith(name, path, CorpusBuildOptions{Rebuild: forceReload, Coverage: coveragePercent})
+}
+
+// CorpusBuildOptions are the options for building a corpus from its text file or
+// word-frequency list.
+type CorpusBuildOptions struct {
+	// Rebuild skips loading the corpus from its JSON cache and always rebuilds it.
+	Rebuild bool
+	// Coverage is the percentage of word occurrences kept when pruning low-frequency words
+	// (0 or 100 keeps all words).
+	Coverage float64
+	// Excluded are the words and tokens removed from the text before counting n-grams
+	// (see LoadExcludedWords).
+	Excluded map[string]bool
+	// Cased keeps the case of the text in the n-gram tables (see Corpus.Cased). Word-frequency
+	// lists are always lowercased.
+	Cased bool
+	// Spaced includes the space between words in the n-gram tables (see Corpus.Spaced).
+	// N-grams synthesised from word-frequency lists never include space.
+	Spaced bool
+	// Skipgram is how skipgrams are counted (see Corpus.Skipgram).
+	Skipgram SkipgramPolicy
+	// Substitutions is the substitution table whose characters are replaced in the text
+	// before anything else (see LoadSubstitutions). It is recorded in Corpus.Substitutions.
+	Substitutions map[rune]rune
+}
+
+// NewCorpusFromFileWith is NewCorpusFromFile, building the corpus with the given options.
+// A corpus built with options that change its n-gram tables, apart from the coverage, is
+// cached separately (see CorpusBuildOptions.CachePath), so that it does not replace the
+// cache that the other builds of the corpus use.
+func NewCorpusFromFileWith(name, path string, opts CorpusBuildOptions) (*Corpus, error) {
+	srcInfo, srcErr := os.Stat(path)
+	wordCounts := false
+	if srcErr == nil {
+		var err error
+		if wordCounts, err = isWordCountsFile(path); err != nil {
+			return nil, fmt.Errorf("could not load corpus from file: %w", err)
+		}
+	}
+	// Word-frequency lists are always lowercased, and their n-grams never include space
+	opts.Cased = opts.Cased && !wordCounts
+	opts.Spaced = opts.Spaced && !wordCounts
+	jsonPath := opts.CachePath(path)
 
-	// If forceReload is false, try to load from JSON cache if it exists and is newer than source file
-	if !forceReload {
+	// Unless rebuilding, try to load from JSON cache if it exists and is newer than source file.
+	// A cache of an older format, or that was built with other options, such as by an older
+	// version that cached every build in the same file, is rebuilt.
+	if !opts.Rebuild {
 		jsonInfo, jsonErr := os.Stat(jsonPath)
-		srcInfo, srcErr := os.Stat(path)
 		if jsonErr == nil && (os.IsNotExist(srcErr) || (srcErr == nil && jsonInfo.ModTime().After(srcInfo.ModTime()))) {
 			corpus, err := LoadJSON(jsonPath)
 			if err != nil {
 				return nil, fmt.Errorf("could not load corpus from cache: %w", err)
 			}
-			return corpus, nil
+			if (corpus.Version == CorpusCacheVersion && opts.builtWith(corpus)) || srcErr != nil {
+				return corpus, nil
+			}
 		}
 	}
 
-	// Otherwise, load from the text file and save JSON cache
+	// Otherwise, load from the text file or word-frequency list and save JSON cache
+	var err error
 	c := NewCorpus(name)
-	if err := c.loadFromFileWithWords(path, coveragePercent); err != nil {
+	c.Cased = opts.Cased
+	c.Spaced = opts.Spaced
+	c.Skipgram = opts.Skipgram
+	c.setSubstitutions(opts.Substitutions)
+	if wordCounts {
+		err = c.loadFromWordCounts(path, opts.Coverage, opts.Excluded)
+	} else {
+		err = c.loadFromFileWithWords(path, opts.Coverage, opts.Excluded)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("could not load corpus from file: %w", err)
 	}
+	c.finishSkipgrams()
+	c.finishSubstitutions()
+	if err := os.MkdirAll(filepath.Dir(jsonPath), 0755); err != nil {
+		return nil, fmt.Errorf("could not create corpus cache directory: %w", err)
+	}
 	if err := c.SaveJSON(jsonPath); err != nil {
 		return nil, fmt.Errorf("could not save corpus cache: %w", err)
 	}
@@ -237,6 +361,61 @@ func NewCorpusFromFile(name, path string, forceReload bool, coveragePercent floa
 	return c, nil
 }
 
+// CachePath returns the path of the JSON cache of the corpus file at path built with the
+// options. The default build is cached next to the file, with ".json" appended to its name;
+// builds with other options are cached in the "builds" directory next to it, named by a
+// hash of their options.
+func (o CorpusBuildOptions) CachePath(path string) string {
+	key := o.cacheKey()
+	if key == "" {
+		return path + ".json"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(filepath.Dir(path), "builds", filepath.Base(path)+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// builtWith reports whether a cached corpus was built with the options, as far as it records
+// them.
+func (o CorpusBuildOptions) builtWith(c *Corpus) bool {
+	if c.Cased != o.Cased || c.Spaced != o.Spaced || c.Skipgram != o.Skipgram ||
+		len(c.Substitutions) != len(o.Substitutions) {
+		return false
+	}
+	for _, sub := range c.Substitutions {
+		if to, ok := o.Substitutions[rune(sub.From)]; !ok || Unigram(to) != sub.To {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheKey describes the options that change the n-gram tables of a corpus, apart from the
+// coverage, or returns "" for the default build.
+func (o CorpusBuildOptions) cacheKey() string {
+	var parts []string
+	if len(o.Excluded) > 0 {
+		parts = append(parts, "exclude="+strings.Join(slices.Sorted(maps.Keys(o.Excluded)), "\x00"))
+	}
+	if o.Cased {
+		parts = append(parts, "cased")
+	}
+	if o.Spaced {
+		parts = append(parts, "spaced")
+	}
+	if o.Skipgram.weighted() {
+		parts = append(parts, "skipgrams="+o.Skipgram.String())
+	}
+	if len(o.Substitutions) > 0 {
+		var subs []string
+		for from, to := range o.Substitutions {
+			subs = append(subs, string([]rune{from, to}))
+		}
+		slices.Sort(subs)
+		parts = append(parts, "substitute="+strings.Join(subs, "\x00"))
+	}
+	return strings.Join(parts, "\n")
+}
+
 // addUnigram increments the count of the given unigram in the corpus.
 func (c *Corpus) addUnigram(r rune) {
 	u := Unigram(r)
@@ -273,6 +452,24 @@ func (c *Corpus) addWord(word string) {
 	c.TotalWordsCount++
 }
 
+// addWordPositions records the word-initial and word-final bigrams of a word.
+func (c *Corpus) addWordPositions(word string) {
+	if first, last, ok := wordEndBigrams(word); ok {
+		c.WordInitialBigrams[first]++
+		c.WordFinalBigrams[last]++
+	}
+}
+
+// wordEndBigrams returns the first and last bigram of a word.
+// Words of fewer than two characters have neither, and ok is false.
+func wordEndBigrams(word string) (first, last Bigram, ok bool) {
+	runes := []rune(word)
+	if len(runes) < 2 {
+		return Bigram{}, Bigram{}, false
+	}
+	return Bigram{runes[0], runes[1]}, Bigram{runes[len(runes)-2], runes[len(runes)-1]}, true
+}
+
 // addText processes text and extracts n-grams (unigrams, bigrams, trigrams, skipgrams).
 // Text is lowercased, and n-grams containing whitespace are skipped (word boundaries reset the window).
 //
@@ -308,8 +505,9 @@ func (c *Corpus) addText(text string) {
 // addTextWithWords processes text, extracting both words and n-grams.
 // Words are defined as sequences of letters and numbers, with support for apostrophes
 // in contractions (e.g., "don't", "she'll") and possessives (e.g., "John's", "users'").
+// Words are lowercased, and so are the n-grams unless the corpus is cased.
 func (c *Corpus) addTextWithWords(text string) {
-	text = strings.ToLower(text)
+	lower := strings.ToLower(text)
 
 	// Helper function to check if a rune is an apostrophe (ASCII or Unicode)
 	isApostrophe := func(r rune) bool {
@@ -317,7 +515,7 @@ func (c *Corpus) addTextWithWords(text string) {
 	}
 
 	// Extract words (alphanumeric sequences with apostrophes)
-	words := strings.FieldsFunc(text, func(r rune) bool {
+	words := strings.FieldsFunc(lower, func(r rune) bool {
 		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !isApostrophe(r)
 	})
 
@@ -339,15 +537,33 @@ func (c *Corpus) addTextWithWords(text string) {
 		}
 		if word != "" {
 			c.addWord(word)
+			c.addWordPositions(word)
 		}
 	}
 
-	// Extract n-grams using sliding window
+	if c.Cased {
+		c.addNgrams(text)
+	} else {
+		c.addNgrams(lower)
+	}
+}
+
+// addNgrams extracts the n-grams of text using a sliding window. N-grams containing
+// whitespace are skipped (word boundaries reset the window), unless the corpus is spaced:
+// then a run of whitespace between two characters is typed as one space. With a weighted
+// skipgram policy, the skipgrams are added with their weights (see SkipgramPolicy).
+func (c *Corpus) addNgrams(text string) {
 	var prev1, prev2 rune
+	var window []rune // Characters before r, for weighted skipgrams
+	weighted := c.Skipgram.weighted()
+	if c.Spaced {
+		text = strings.Join(strings.Fields(text), " ")
+	}
 	for _, r := range text {
-		if unicode.IsSpace(r) {
+		if unicode.IsSpace(r) && !c.Spaced {
 			prev1 = 0
 			prev2 = 0
+			window = window[:0]
 			continue
 		}
 
@@ -358,9 +574,18 @@ func (c *Corpus) addTextWithWords(text string) {
 
 			if prev2 != 0 {
 				c.addTrigram(prev2, prev1, r)
-				c.addSkipgram(prev2, r)
+				if !weighted {
+					c.addSkipgram(prev2, r)
+				}
 			}
 		}
+		if weighted {
+			c.addWeightedSkipgrams(window, r, 1)
+			if len(window) > c.Skipgram.MaxDistance {
+				window = append(window[:0], window[1:]...)
+			}
+			window = append(window, r)
+		}
 
 		prev2 = prev1
 		prev1 = r
@@ -394,31 +619,48 @@ func (c *Corpus) loadFromFile(path string) error {
 	return nil
 }
 
-// loadFromFileWithWords loads text from a file, extracting both words and n-grams.
+// loadFromFileWithWords loads text from a file, extracting both words and n-grams, and
+// records the start of the text in Stream. Characters of the substitution table are
+// replaced first, then excluded words and tokens are removed.
 // After loading, prunes the word list to keep only the most frequent words covering
 // the specified percentage of total word occurrences.
 //
 //nolint:unused
-func (c *Corpus) loadFromFileWithWords(path string, coveragePercent float64) error {
+func (c *Corpus) loadFromFileWithWords(path string, coveragePercent float64, excluded map[string]bool) error {
 	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("could not open file: %w", err)
 	}
 	defer CloseFile(file)
 
+	var stream strings.Builder
+	var removed int
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := c.substitute(scanner.Text(), 1)
+		if len(excluded) > 0 {
+			var n int
+			line, n = excludeWords(line, excluded)
+			removed += n
+		}
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 		c.addTextWithWords(line)
+		if stream.Len() < StreamSize {
+			stream.WriteString(strings.ToLower(line))
+			stream.WriteByte('\n')
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("could not read file: %w", err)
 	}
 
+	c.Stream = stream.String()
+	if len(excluded) > 0 {
+		fmt.Printf("Excluded %d occurrences of %d listed words and tokens\n\n", removed, len(excluded))
+	}
 	c.pruneWordsByCoverage(coveragePercent)
 
 	return nil
@@ -628,19 +870,48 @@ func (c *Corpus) TopWords(n int) []CountPair[string] {
 type CorpusInput struct {
 	Corpus *Corpus
 	NRows  int
+	// Substitutions is a substitution table to report on if the corpus was built without
+	// one, or nil.
+	Substitutions map[rune]rune
+	// Preview is the number of n-grams of each kind to show side by side instead of the
+	// full statistics (0 = the full statistics).
+	Preview int
 }
 
 // CorpusResult contains corpus statistics ready for display.
 type CorpusResult struct {
 	Corpus *Corpus
 	NRows  int
+	// Substitutions are the characters that were replaced when the corpus was built, or if
+	// it was built without a substitution table, those that the table of the input would
+	// replace.
+	Substitutions []CharSubstitution
+	// Substituted is whether the corpus was built with a substitution table.
+	Substituted bool
+	// Preview is the most frequent n-grams of each kind, or nil for the full statistics.
+	Preview *CorpusPreview
 }
 
 // DisplayCorpus performs pure computation for corpus display.
-// This is a simple pass-through since corpus statistics are already computed.
+// Besides the characters of a substitution table, this is a simple pass-through since
+// corpus statistics are already computed.
 func DisplayCorpus(input CorpusInput) (*CorpusResult, error) {
-	return &CorpusResult{
-		Corpus: input.Corpus,
-		NRows:  input.NRows,
-	}, nil
+	result := &CorpusResult{
+		Corpus:      input.Corpus,
+		NRows:       input.NRows,
+		Substituted: len(input.Corpus.Substitutions) > 0,
+	}
+	if result.Substituted {
+		for _, sub := range input.Corpus.Substitutions {
+			if sub.Count > 0 {
+				result.Substitutions = append(result.Substitutions, sub)
+			}
+		}
+	} else {
+		result.Substitutions = input.Corpus.SubstitutionCandidates(input.Substitutions)
+	}
+	if input.Preview > 0 {
+		result.Preview = input.Corpus.Preview(input.Preview)
+	}
+	return result, nil
 }
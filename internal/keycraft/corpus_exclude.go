@@ -0,0 +1,64 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// LoadExcludedWords reads the words and tokens to exclude from a corpus from a file, one per
+// line. Empty lines and lines starting with '#' are ignored. Words are lowercased, as the
+// corpus text is.
+func LoadExcludedWords(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open excluded words file %s: %w", path, err)
+	}
+	defer CloseFile(file)
+
+	excluded := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.IndexFunc(line, unicode.IsSpace) >= 0 {
+			return nil, fmt.Errorf("line %d: excluded word %q contains whitespace", lineNum, line)
+		}
+		excluded[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read excluded words file %s: %w", path, err)
+	}
+	if len(excluded) == 0 {
+		return nil, fmt.Errorf("excluded words file %s has no words", path)
+	}
+	return excluded, nil
+}
+
+// excludeWords removes the excluded tokens from a line of text, and returns the line and the
+// number of tokens removed. Tokens are separated by whitespace, and compared in lowercase; a
+// token is removed if it is excluded, or if it is once the punctuation around it is trimmed,
+// so that "Filler," is removed along with "filler". Lines without excluded tokens are
+// returned as is.
+func excludeWords(line string, excluded map[string]bool) (string, int) {
+	tokens := strings.Fields(line)
+	kept := tokens[:0:0]
+	for _, token := range tokens {
+		lower := strings.ToLower(token)
+		core := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsNumber(r) })
+		if !excluded[lower] && !excluded[core] {
+			kept = append(kept, token)
+		}
+	}
+	removed := len(tokens) - len(kept)
+	if removed == 0 {
+		return line, 0
+	}
+	return strings.Join(kept, " "), removed
+}
@@ -0,0 +1,73 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExcludeWords(t *testing.T) {
+	excluded := map[string]bool{"lorem": true, "<div>": true}
+	tests := []struct {
+		line, want string
+		removed    int
+	}{
+		{"keep  this   line", "keep  this   line", 0},
+		{"lorem ipsum, lorem.", "ipsum,", 2},
+		{"<div>hello <div> world", "<div>hello world", 1},
+		{"lorem", "", 1},
+	}
+	for _, tt := range tests {
+		got, removed := excludeWords(tt.line, excluded)
+		if got != tt.want || removed != tt.removed {
+			t.Errorf("excludeWords(%q) = %q, %d, want %q, %d", tt.line, got, removed, tt.want, tt.removed)
+		}
+	}
+}
+
+func TestLoadExcludedWords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exclude.txt")
+	Must0(os.WriteFile(path, []byte("# boilerplate\nLorem\n\n&nbsp;\n"), 0644))
+	excluded := Must(LoadExcludedWords(path))
+	if len(excluded) != 2 || !excluded["lorem"] || !excluded["&nbsp;"] {
+		t.Errorf("excluded words %v, want lorem and &nbsp;", excluded)
+	}
+
+	for name, content := range map[string]string{"empty.txt": "# nothing\n", "space.txt": "two words\n"} {
+		path := filepath.Join(dir, name)
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		if _, err := LoadExcludedWords(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestNewCorpusFromFileWith_Excluded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	Must0(os.WriteFile(path, []byte("Lorem ipsum dolor\nlorem lorem\n"), 0644))
+	opts := CorpusBuildOptions{Rebuild: true, Coverage: 100, Excluded: map[string]bool{"lorem": true}}
+	corpus := Must(NewCorpusFromFileWith("corpus", path, opts))
+	if corpus.Words["lorem"] != 0 || corpus.Words["ipsum"] != 1 {
+		t.Errorf("words %v, want lorem excluded", corpus.Words)
+	}
+	if corpus.TotalUnigramsCount != uint64(len("ipsumdolor")) {
+		t.Errorf("%d unigrams, want %d", corpus.TotalUnigramsCount, len("ipsumdolor"))
+	}
+
+	// The build has a cache of its own, which leaves the default build alone
+	if _, err := os.Stat(opts.CachePath(path)); err != nil || opts.CachePath(path) == path+".json" {
+		t.Errorf("cache %s of the build: %v", opts.CachePath(path), err)
+	}
+	past := time.Now().Add(-time.Hour)
+	Must0(os.Chtimes(path, past, past))
+	plain := Must(NewCorpusFromFile("corpus", path, false, 100))
+	if plain.Words["lorem"] != 3 {
+		t.Errorf("default build has %d lorems, want 3", plain.Words["lorem"])
+	}
+	opts.Rebuild = false
+	if cached := Must(NewCorpusFromFileWith("corpus", path, opts)); cached.Words["lorem"] != 0 {
+		t.Errorf("cached build has %d lorems, want 0", cached.Words["lorem"])
+	}
+}
@@ -0,0 +1,212 @@
+package keycraft
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CorpusPreset is a well-known corpus that can be downloaded.
+type CorpusPreset struct {
+	Name    string // Name to fetch the corpus by
+	File    string // File name of the corpus in the corpus directory
+	URL     string // Location of the corpus; .gz, .bz2 and .xz files are decompressed
+	SHA256  string // Checksum of the downloaded file, in hex ("" = not pinned)
+	License string // License or terms of use of the corpus
+}
+
+// LoadCorpusPresets reads the corpus presets from a file. Every line is
+// "<name> <file> <url> <sha256> <license>", with "-" for a checksum that is not pinned, and
+// the license running to the end of the line. Empty lines and lines starting with '#' are
+// ignored.
+func LoadCorpusPresets(path string) ([]CorpusPreset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open corpus presets file %s: %w", path, err)
+	}
+	defer CloseFile(file)
+
+	var presets []CorpusPreset
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("line %d: expected <name> <file> <url> <sha256> <license>, got %q", lineNum, line)
+		}
+		p := CorpusPreset{Name: strings.ToLower(fields[0]), File: fields[1], URL: fields[2],
+			License: strings.Join(fields[4:], " ")}
+		if fields[3] != "-" {
+			if sum, err := hex.DecodeString(fields[3]); err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("line %d: invalid SHA-256 checksum %q", lineNum, fields[3])
+			}
+			p.SHA256 = strings.ToLower(fields[3])
+		}
+		if filepath.Base(p.File) != p.File {
+			return nil, fmt.Errorf("line %d: corpus file %q must be a file name", lineNum, p.File)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("line %d: duplicate corpus preset %q", lineNum, p.Name)
+		}
+		seen[p.Name] = true
+		presets = append(presets, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read corpus presets file %s: %w", path, err)
+	}
+	return presets, nil
+}
+
+// PinCorpusPreset writes a checksum into the line of a preset in a corpus presets file, in
+// place of "-" or the checksum it pinned before. Other lines are kept as they are.
+func PinCorpusPreset(path, name, sum string) error {
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid SHA-256 checksum %q", sum)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read corpus presets file %s: %w", path, err)
+	}
+	lines := strings.Split(string(data), "\n")
+	found := false
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || strings.HasPrefix(fields[0], "#") || !strings.EqualFold(fields[0], name) {
+			continue
+		}
+		fields[3] = strings.ToLower(sum)
+		lines[i] = strings.Join(fields, " ")
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("corpus preset %q not found in %s", name, path)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return fmt.Errorf("could not write corpus presets file %s: %w", path, err)
+	}
+	return nil
+}
+
+// FetchResult describes a fetched corpus.
+type FetchResult struct {
+	Path    string // Path of the corpus file
+	SHA256  string // Checksum of the downloaded file, in hex
+	Bytes   int64  // Size of the downloaded file
+	Cached  bool   // Whether the corpus file already existed, so nothing was downloaded
+	Checked bool   // Whether the checksum matched a pinned checksum
+}
+
+// FetchCorpus downloads the corpus of a preset into dir, verifies its checksum if the preset
+// pins one, and decompresses it. A corpus that is already in dir is kept, unless force is
+// set. The corpus is written to a temporary file first, so that an interrupted download
+// leaves no partial corpus behind.
+func FetchCorpus(ctx context.Context, preset CorpusPreset, dir string, force bool) (*FetchResult, error) {
+	dest := filepath.Join(dir, preset.File)
+	if _, err := os.Stat(dest); err == nil && !force {
+		return &FetchResult{Path: dest, Cached: true}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, preset.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not request %s: %w", preset.URL, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %w", preset.URL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download %s: %s", preset.URL, resp.Status)
+	}
+
+	// Download to a temporary file, hashing the data as it arrives
+	download, err := os.CreateTemp(dir, preset.File+".download-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create download file: %w", err)
+	}
+	defer func() { _ = os.Remove(download.Name()) }()
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(download, hash), resp.Body)
+	if closeErr := download.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not download %s: %w", preset.URL, err)
+	}
+
+	result := &FetchResult{Path: dest, SHA256: hex.EncodeToString(hash.Sum(nil)), Bytes: n}
+	if preset.SHA256 != "" {
+		if result.SHA256 != preset.SHA256 {
+			return nil, fmt.Errorf("checksum of %s is %s, want %s", preset.URL, result.SHA256, preset.SHA256)
+		}
+		result.Checked = true
+	}
+
+	// Decompress into another temporary file, and move it in place
+	corpus, err := os.CreateTemp(dir, preset.File+".corpus-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create corpus file: %w", err)
+	}
+	defer func() { _ = os.Remove(corpus.Name()) }()
+	err = decompressCorpus(ctx, download.Name(), path.Ext(req.URL.Path), corpus)
+	if closeErr := corpus.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress %s: %w", preset.URL, err)
+	}
+	if err := os.Rename(corpus.Name(), dest); err != nil {
+		return nil, fmt.Errorf("could not save corpus: %w", err)
+	}
+	return result, nil
+}
+
+// decompressCorpus writes the decompressed contents of a downloaded file to w, by the
+// extension of its URL. Gzip and bzip2 files are decompressed natively; xz files need the xz
+// tool. Other files are copied as they are.
+func decompressCorpus(ctx context.Context, src, ext string, w io.Writer) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer CloseFile(file)
+
+	var r io.Reader = file
+	switch strings.ToLower(ext) {
+	case ".gz":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	case ".bz2":
+		r = bzip2.NewReader(file)
+	case ".xz":
+		if _, err := exec.LookPath("xz"); err != nil {
+			return fmt.Errorf("xz files need the xz tool, which is not on the PATH: %w", err)
+		}
+		cmd := exec.CommandContext(ctx, "xz", "--decompress", "--stdout")
+		cmd.Stdin, cmd.Stdout = file, w
+		return cmd.Run()
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,115 @@
+package keycraft
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCorpusPresets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "presets.txt")
+	sum := sha256.Sum256([]byte("x"))
+	content := "# presets\nShai shai.txt https://example.com/shai.txt.xz - Some license text\n" +
+		"other other.txt https://example.com/o.gz " + hex.EncodeToString(sum[:]) + " CC-BY\n"
+	Must0(os.WriteFile(path, []byte(content), 0644))
+	presets := Must(LoadCorpusPresets(path))
+	if len(presets) != 2 || presets[0].Name != "shai" || presets[0].SHA256 != "" ||
+		presets[0].License != "Some license text" || presets[1].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("presets %+v", presets)
+	}
+
+	for name, content := range map[string]string{
+		"short.txt":     "shai shai.txt https://example.com/shai.txt -\n",
+		"checksum.txt":  "shai shai.txt https://example.com/shai.txt abc license\n",
+		"path.txt":      "shai ../shai.txt https://example.com/shai.txt - license\n",
+		"duplicate.txt": "a a.txt https://example.com/a - l\nA b.txt https://example.com/b - l\n",
+	} {
+		path := filepath.Join(dir, name)
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		if _, err := LoadCorpusPresets(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPinCorpusPreset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "presets.txt")
+	content := "# shai comment - x y\nshai shai.txt https://example.com/shai.txt.xz - Some license text\n" +
+		"other other.txt https://example.com/o.gz - CC-BY\n"
+	Must0(os.WriteFile(path, []byte(content), 0644))
+	sum := sha256.Sum256([]byte("x"))
+	Must0(PinCorpusPreset(path, "Shai", hex.EncodeToString(sum[:])))
+
+	presets := Must(LoadCorpusPresets(path))
+	if presets[0].SHA256 != hex.EncodeToString(sum[:]) || presets[0].License != "Some license text" ||
+		presets[1].SHA256 != "" {
+		t.Errorf("presets %+v", presets)
+	}
+	if got := string(Must(os.ReadFile(path))); !strings.HasPrefix(got, "# shai comment - x y\n") {
+		t.Errorf("presets file %q, want the comment kept", got)
+	}
+
+	if err := PinCorpusPreset(path, "missing", hex.EncodeToString(sum[:])); err == nil {
+		t.Error("expected an error for an unknown preset")
+	}
+	if err := PinCorpusPreset(path, "shai", "abc"); err == nil {
+		t.Error("expected an error for an invalid checksum")
+	}
+}
+
+func TestFetchCorpus(t *testing.T) {
+	text := "the quick brown fox\n"
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	Must(w.Write([]byte(text)))
+	Must0(w.Close())
+	sum := sha256.Sum256(gz.Bytes())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/corpus.txt.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(gz.Bytes())
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	preset := CorpusPreset{Name: "test", File: "test.txt", URL: server.URL + "/corpus.txt.gz",
+		SHA256: hex.EncodeToString(sum[:])}
+	result := Must(FetchCorpus(context.Background(), preset, dir, false))
+	if !result.Checked || result.Cached || result.Bytes != int64(gz.Len()) {
+		t.Errorf("result %+v", result)
+	}
+	if got := string(Must(os.ReadFile(result.Path))); got != text {
+		t.Errorf("corpus %q, want %q", got, text)
+	}
+
+	// A corpus that was fetched before is kept
+	if result := Must(FetchCorpus(context.Background(), preset, dir, false)); !result.Cached {
+		t.Error("expected the corpus to be cached")
+	}
+
+	// A wrong checksum or a missing file fails, and leaves no files behind
+	bad := preset
+	bad.File, bad.SHA256 = "bad.txt", hex.EncodeToString(make([]byte, sha256.Size))
+	if _, err := FetchCorpus(context.Background(), bad, dir, false); err == nil {
+		t.Error("expected an error for a wrong checksum")
+	}
+	bad.URL, bad.SHA256 = server.URL+"/missing.txt", ""
+	if _, err := FetchCorpus(context.Background(), bad, dir, false); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if entries := Must(os.ReadDir(dir)); len(entries) != 1 {
+		t.Errorf("files %v, want only test.txt", entries)
+	}
+}
@@ -0,0 +1,65 @@
+package keycraft
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// NGramCoverage is an n-gram of the corpus preview with its count, its share of the n-grams
+// of its kind, and the share that it and the more frequent n-grams cover together, in percent.
+type NGramCoverage struct {
+	NGram string
+	Count uint64
+	Pct   float64
+	Cumul float64
+}
+
+// CorpusPreview lists the most frequent unigrams, bigrams, skipgrams and trigrams of a corpus
+// with their cumulative coverage, to inspect what the corpus contains.
+type CorpusPreview struct {
+	Columns []NGramColumn // Unigrams, bigrams, skipgrams and trigrams, in that order
+}
+
+// NGramColumn is the most frequent n-grams of one kind of a corpus preview.
+type NGramColumn struct {
+	Kind   string // "Unigrams", "Bigrams", "Skipgrams" or "Trigrams"
+	NGrams []NGramCoverage
+}
+
+// Preview returns the n most frequent n-grams of each kind. N-grams with the same count are
+// ordered by their characters, so that the preview is the same for every run.
+func (c *Corpus) Preview(n int) *CorpusPreview {
+	return &CorpusPreview{Columns: []NGramColumn{
+		{"Unigrams", topCoverage(c.Unigrams, c.TotalUnigramsCount, n)},
+		{"Bigrams", topCoverage(c.Bigrams, c.TotalBigramsCount, n)},
+		{"Skipgrams", topCoverage(c.Skipgrams, c.TotalSkipgramsCount, n)},
+		{"Trigrams", topCoverage(c.Trigrams, c.TotalTrigramsCount, n)},
+	}}
+}
+
+// topCoverage returns the n most frequent n-grams of a count map with their coverage of
+// total n-grams.
+func topCoverage[K interface {
+	comparable
+	fmt.Stringer
+}](counts map[K]uint64, total uint64, n int) []NGramCoverage {
+	ngrams := make([]NGramCoverage, 0, len(counts))
+	for k, count := range counts {
+		ngrams = append(ngrams, NGramCoverage{NGram: k.String(), Count: count})
+	}
+	slices.SortFunc(ngrams, func(a, b NGramCoverage) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.NGram, b.NGram))
+	})
+	ngrams = ngrams[:min(n, len(ngrams))]
+	cumul := 0.0
+	for i := range ngrams {
+		if total > 0 {
+			ngrams[i].Pct = 100 * float64(ngrams[i].Count) / float64(total)
+		}
+		cumul += ngrams[i].Pct
+		ngrams[i].Cumul = cumul
+	}
+	return ngrams
+}
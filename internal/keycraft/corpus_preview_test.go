@@ -0,0 +1,36 @@
+package keycraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCorpus_Preview(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("abab cd\n")
+	preview := corpus.Preview(2)
+
+	if len(preview.Columns) != 4 {
+		t.Fatalf("%d columns, want unigrams, bigrams, skipgrams and trigrams", len(preview.Columns))
+	}
+	for _, col := range preview.Columns {
+		if len(col.NGrams) > 2 {
+			t.Errorf("%s: %d n-grams, want at most 2", col.Kind, len(col.NGrams))
+		}
+	}
+
+	// a and b occur twice each; the tie is broken by the characters
+	unigrams := preview.Columns[0].NGrams
+	if len(unigrams) != 2 || unigrams[0].NGram != "a" || unigrams[1].NGram != "b" || unigrams[0].Count != 2 {
+		t.Fatalf("unigrams %+v, want a and b twice", unigrams)
+	}
+	wantPct := 100 * 2 / float64(corpus.TotalUnigramsCount)
+	if math.Abs(unigrams[0].Pct-wantPct) > 1e-9 || math.Abs(unigrams[1].Cumul-2*wantPct) > 1e-9 {
+		t.Errorf("unigrams %+v, want %.2f%% each and %.2f%% together", unigrams, wantPct, 2*wantPct)
+	}
+
+	bigrams := preview.Columns[1].NGrams
+	if bigrams[0].NGram != "ab" || bigrams[0].Count != 2 {
+		t.Errorf("bigrams %+v, want ab first", bigrams)
+	}
+}
@@ -0,0 +1,76 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxSkipgramDistance is the most characters that a skipgram can skip.
+const MaxSkipgramDistance = 8
+
+// SkipgramPolicy is how the skipgrams of a corpus are counted when it is built. By default
+// a skipgram skips the one character between its two characters. With a MaxDistance of k,
+// skipgrams that skip up to k characters are counted too, a skipgram that skips d characters
+// counting Decay^(d-1) times: with a Decay of 0.5, "t_e" counts 1, "t__e" 0.5 and "t___e"
+// 0.25. As SFS and the other skipgram metrics are percentages of all skipgrams, only these
+// weights relative to one another matter, and the metrics of corpora are only comparable if
+// the corpora were built with the same policy.
+type SkipgramPolicy struct {
+	MaxDistance int     `json:",omitempty"` // Most characters that a skipgram skips (0 = 1)
+	Decay       float64 `json:",omitempty"` // Weight factor for each further skipped character
+}
+
+// NewSkipgramPolicy returns the policy that counts skipgrams up to maxDistance characters
+// apart with the given decay, or the default policy for a maxDistance of 1.
+func NewSkipgramPolicy(maxDistance int, decay float64) (SkipgramPolicy, error) {
+	if maxDistance < 1 || maxDistance > MaxSkipgramDistance {
+		return SkipgramPolicy{}, fmt.Errorf("skipgram distance must be between 1 and %d, got %d",
+			MaxSkipgramDistance, maxDistance)
+	}
+	if maxDistance == 1 {
+		return SkipgramPolicy{}, nil
+	}
+	if decay <= 0 || decay > 1 {
+		return SkipgramPolicy{}, fmt.Errorf("skipgram decay must be above 0 and at most 1, got %g", decay)
+	}
+	return SkipgramPolicy{MaxDistance: maxDistance, Decay: decay}, nil
+}
+
+// weighted reports whether skipgrams over more than one character are counted, with weights.
+func (p SkipgramPolicy) weighted() bool {
+	return p.MaxDistance > 1
+}
+
+// String describes the policy, or returns "" for the default policy.
+func (p SkipgramPolicy) String() string {
+	if !p.weighted() {
+		return ""
+	}
+	return fmt.Sprintf("skipgrams up to %d apart, decay %g", p.MaxDistance, p.Decay)
+}
+
+// addWeightedSkipgrams adds the skipgrams that end in r, count times, with the weights of
+// the skipgram policy. prev holds the characters before r, in typing order; its last
+// character forms a bigram with r, and is not part of a skipgram.
+func (c *Corpus) addWeightedSkipgrams(prev []rune, r rune, count uint64) {
+	if c.skipgramWeights == nil {
+		c.skipgramWeights = make(map[Skipgram]float64)
+	}
+	weight := float64(count)
+	for d := 1; d <= c.Skipgram.MaxDistance && d < len(prev); d++ {
+		c.skipgramWeights[Skipgram{prev[len(prev)-1-d], r}] += weight
+		weight *= c.Skipgram.Decay
+	}
+}
+
+// finishSkipgrams rounds the weighted skipgrams of a weighted skipgram policy to the
+// skipgram counts, once all text has been added.
+func (c *Corpus) finishSkipgrams() {
+	for skp, weight := range c.skipgramWeights {
+		if n := uint64(math.Round(weight)); n > 0 {
+			c.Skipgrams[skp] += n
+			c.TotalSkipgramsCount += n
+		}
+	}
+	c.skipgramWeights = nil
+}
@@ -0,0 +1,70 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSkipgramPolicy(t *testing.T) {
+	if p := Must(NewSkipgramPolicy(1, 0.5)); p != (SkipgramPolicy{}) {
+		t.Errorf("policy of distance 1 = %+v, want the default", p)
+	}
+	if p := Must(NewSkipgramPolicy(3, 0.5)); p.String() != "skipgrams up to 3 apart, decay 0.5" {
+		t.Errorf("String() = %q", p.String())
+	}
+	for _, bad := range [][2]float64{{0, 0.5}, {MaxSkipgramDistance + 1, 0.5}, {2, 0}, {2, 1.5}} {
+		if _, err := NewSkipgramPolicy(int(bad[0]), bad[1]); err == nil {
+			t.Errorf("NewSkipgramPolicy(%v, %v): expected error", bad[0], bad[1])
+		}
+	}
+}
+
+func TestCorpus_WeightedSkipgrams(t *testing.T) {
+	want := map[Skipgram]uint64{{'a', 'c'}: 4, {'a', 'd'}: 2, {'a', 'e'}: 1, {'b', 'e'}: 2}
+
+	text := NewCorpus("text")
+	text.Skipgram = Must(NewSkipgramPolicy(3, 0.5))
+	text.addTextWithWords("abcde abcde\nabcde abcde")
+	text.finishSkipgrams()
+
+	words := NewCorpus("words")
+	words.Skipgram = text.Skipgram
+	words.addWordCount("abcde", 4)
+	words.finishSkipgrams()
+
+	for _, c := range []*Corpus{text, words} {
+		for skp, n := range want {
+			if got := c.Skipgrams[skp]; got != n {
+				t.Errorf("%s: count of %c_%c = %d, want %d", c.Name, skp[0], skp[1], got, n)
+			}
+		}
+		// 3 skipgrams over 1 character, 2 over 2 and 1 over 3, 4 times
+		if c.TotalSkipgramsCount != 4*3+2*2+1 {
+			t.Errorf("%s: total skipgrams = %d, want 17", c.Name, c.TotalSkipgramsCount)
+		}
+	}
+}
+
+func TestNewCorpusFromFileWith_Skipgrams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	Must0(os.WriteFile(path, []byte("abcde abcde abcde abcde\n"), 0644))
+	policy := Must(NewSkipgramPolicy(2, 0.5))
+	corpus := Must(NewCorpusFromFileWith("corpus", path, CorpusBuildOptions{Rebuild: true, Coverage: 100, Skipgram: policy}))
+	if corpus.Skipgram != policy || corpus.Skipgrams[Skipgram{'a', 'd'}] != 2 {
+		t.Errorf("corpus = %+v, %v", corpus.Skipgram, corpus.Skipgrams)
+	}
+
+	// The cache of the build keeps the policy, and the default build counts plain skipgrams
+	past := time.Now().Add(-time.Hour)
+	Must0(os.Chtimes(path, past, past))
+	cached := Must(NewCorpusFromFileWith("corpus", path, CorpusBuildOptions{Coverage: 100, Skipgram: policy}))
+	if cached.Skipgram != policy || cached.TotalSkipgramsCount != corpus.TotalSkipgramsCount {
+		t.Errorf("cached corpus = %+v, %d skipgrams", cached.Skipgram, cached.TotalSkipgramsCount)
+	}
+	plain := Must(NewCorpusFromFile("corpus", path, false, 100))
+	if plain.Skipgram.weighted() || plain.Skipgrams[Skipgram{'a', 'd'}] != 0 {
+		t.Errorf("default corpus = %+v, %v", plain.Skipgram, plain.Skipgrams)
+	}
+}
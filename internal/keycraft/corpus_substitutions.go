@@ -0,0 +1,148 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharSubstitution is an entry of a substitution table: a character of the corpus text that
+// is replaced by another before counting n-grams, such as a typographic apostrophe by the
+// ASCII one. Count is how often the character occurs in the corpus text.
+type CharSubstitution struct {
+	From  Unigram
+	To    Unigram
+	Count uint64
+}
+
+// LoadSubstitutions reads a substitution table from a file. Every line has the character to
+// replace and its replacement, separated by whitespace, e.g. "’ '". A character is given as
+// is, or as a code point such as U+00A0 for characters that are hard to tell apart. Empty
+// lines and lines starting with '#' are ignored.
+func LoadSubstitutions(path string) (map[rune]rune, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open substitutions file %s: %w", path, err)
+	}
+	defer CloseFile(file)
+
+	table := make(map[rune]rune)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a character and its replacement, got %q", lineNum, line)
+		}
+		from, err := parseSubstitutionChar(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		to, err := parseSubstitutionChar(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if from == to {
+			return nil, fmt.Errorf("line %d: %q is replaced by itself", lineNum, from)
+		}
+		if _, ok := table[from]; ok {
+			return nil, fmt.Errorf("line %d: %q is replaced more than once", lineNum, from)
+		}
+		table[from] = to
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read substitutions file %s: %w", path, err)
+	}
+	if len(table) == 0 {
+		return nil, fmt.Errorf("substitutions file %s has no substitutions", path)
+	}
+	for from, to := range table {
+		if _, ok := table[to]; ok {
+			return nil, fmt.Errorf("%q is replaced by %q, which is replaced itself", from, to)
+		}
+	}
+	return table, nil
+}
+
+// parseSubstitutionChar parses a character of a substitution table: a single character, or
+// a code point such as U+2019.
+func parseSubstitutionChar(s string) (rune, error) {
+	if utf8.RuneCountInString(s) == 1 {
+		r, _ := utf8.DecodeRuneInString(s)
+		return r, nil
+	}
+	if hex, ok := strings.CutPrefix(strings.ToUpper(s), "U+"); ok {
+		if cp, err := strconv.ParseUint(hex, 16, 32); err == nil && utf8.ValidRune(rune(cp)) {
+			return rune(cp), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid character %q: must be a single character or a code point like U+2019", s)
+}
+
+// SubstitutionCandidates returns the entries of a substitution table whose characters occur
+// in the corpus, most frequent first, with their unigram counts. These are the characters
+// that the table would replace if the corpus were built with it.
+func (c *Corpus) SubstitutionCandidates(table map[rune]rune) []CharSubstitution {
+	var candidates []CharSubstitution
+	for from, to := range table {
+		if cnt := c.Unigrams[Unigram(from)]; cnt > 0 {
+			candidates = append(candidates, CharSubstitution{Unigram(from), Unigram(to), cnt})
+		}
+	}
+	sortSubstitutions(candidates)
+	return candidates
+}
+
+// substitute replaces the characters of the substitution table of the corpus in text, which
+// is counted n times, and records how often each was replaced.
+func (c *Corpus) substitute(text string, n uint64) string {
+	if len(c.substitutions) == 0 {
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if to, ok := c.substitutions[r]; ok {
+			c.substituted[r] += n
+			return to
+		}
+		return r
+	}, text)
+}
+
+// setSubstitutions sets the substitution table to apply while the corpus is built.
+func (c *Corpus) setSubstitutions(table map[rune]rune) {
+	c.substitutions = table
+	c.substituted = make(map[rune]uint64, len(table))
+}
+
+// finishSubstitutions records the substitution table of a built corpus in Substitutions,
+// with the number of characters replaced by every entry.
+func (c *Corpus) finishSubstitutions() {
+	if len(c.substitutions) == 0 {
+		return
+	}
+	c.Substitutions = make([]CharSubstitution, 0, len(c.substitutions))
+	for from, to := range c.substitutions {
+		c.Substitutions = append(c.Substitutions, CharSubstitution{Unigram(from), Unigram(to), c.substituted[from]})
+	}
+	sortSubstitutions(c.Substitutions)
+	c.substitutions, c.substituted = nil, nil
+}
+
+// sortSubstitutions sorts substitutions by count, most frequent first, then by character.
+func sortSubstitutions(subs []CharSubstitution) {
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Count != subs[j].Count {
+			return subs[i].Count > subs[j].Count
+		}
+		return subs[i].From < subs[j].From
+	})
+}
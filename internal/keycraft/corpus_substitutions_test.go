@@ -0,0 +1,86 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadSubstitutions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "subst.txt")
+	Must0(os.WriteFile(path, []byte("# quotes\n’ '\n\nU+00a0 U+0020\n— -\n"), 0644))
+	table := Must(LoadSubstitutions(path))
+	if len(table) != 3 || table['’'] != '\'' || table[' '] != ' ' || table['—'] != '-' {
+		t.Errorf("substitutions %q, want ’, no-break space and —", table)
+	}
+
+	for name, content := range map[string]string{
+		"empty.txt":   "# nothing\n",
+		"fields.txt":  "’\n",
+		"long.txt":    "’’ '\n",
+		"code.txt":    "U+ZZ '\n",
+		"self.txt":    "' '\n",
+		"twice.txt":   "’ '\n’ `\n",
+		"chained.txt": "’ '\n' `\n",
+	} {
+		path := filepath.Join(dir, name)
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		if _, err := LoadSubstitutions(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestNewCorpusFromFileWith_Substitutions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	Must0(os.WriteFile(path, []byte("Don’t stop — it’s fine\n"), 0644))
+	table := map[rune]rune{'’': '\'', '—': '-', '“': '"'}
+
+	plain := Must(NewCorpusFromFile("corpus", path, true, 100))
+	candidates := plain.SubstitutionCandidates(table)
+	if len(candidates) != 2 || candidates[0] != (CharSubstitution{'’', '\'', 2}) || candidates[1] != (CharSubstitution{'—', '-', 1}) {
+		t.Errorf("candidates %+v, want ’ twice and — once", candidates)
+	}
+
+	opts := CorpusBuildOptions{Rebuild: true, Coverage: 100, Excluded: map[string]bool{"don't": true}, Substitutions: table}
+	corpus := Must(NewCorpusFromFileWith("corpus", path, opts))
+	if corpus.Unigrams['’'] != 0 || corpus.Unigrams['\''] != 1 || corpus.Unigrams['-'] != 1 {
+		t.Errorf("unigrams %v, want ’ and — replaced", corpus.Unigrams)
+	}
+	if corpus.Words["don't"] != 0 || corpus.Words["it's"] != 1 {
+		t.Errorf("words %v, want it's and the replaced don't excluded", corpus.Words)
+	}
+	want := []CharSubstitution{{'’', '\'', 2}, {'—', '-', 1}, {'“', '"', 0}}
+	if len(corpus.Substitutions) != len(want) {
+		t.Fatalf("substitutions %+v, want %+v", corpus.Substitutions, want)
+	}
+	for i, sub := range want {
+		if corpus.Substitutions[i] != sub {
+			t.Errorf("substitution %d = %+v, want %+v", i, corpus.Substitutions[i], sub)
+		}
+	}
+
+	// The table is stored with the corpus, whose cache is only used for the same table
+	cached := Must(LoadJSON(opts.CachePath(path)))
+	if len(cached.Substitutions) != len(want) || cached.Substitutions[0] != want[0] || cached.Unigrams['’'] != 0 {
+		t.Errorf("cached substitutions %+v, want %+v", cached.Substitutions, want)
+	}
+	opts.Rebuild = false
+	if !opts.builtWith(cached) {
+		t.Error("the cached corpus was not built with its table")
+	}
+	opts.Substitutions = map[rune]rune{'’': '\'', '—': '_', '“': '"'}
+	if opts.builtWith(cached) {
+		t.Error("the cached corpus was built with another table")
+	}
+
+	// The default build keeps the characters
+	past := time.Now().Add(-time.Hour)
+	Must0(os.Chtimes(path, past, past))
+	plain = Must(NewCorpusFromFile("corpus", path, false, 100))
+	if plain.Substitutions != nil || plain.Unigrams['’'] != 2 {
+		t.Errorf("default corpus has substitutions %+v and %d ’", plain.Substitutions, plain.Unigrams['’'])
+	}
+}
@@ -1,7 +1,10 @@
 package keycraft
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestAddTextWithWords_Apostrophes(t *testing.T) {
@@ -217,3 +220,48 @@ func TestAddTextWithWords_OnlyApostrophes(t *testing.T) {
 		t.Errorf("Expected no words for apostrophe-only input, got %d words: %v", len(corpus.Words), corpus.Words)
 	}
 }
+
+func TestNewCorpusFromFileWith_Spaced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte("ab  cd\te\n fg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corpus := Must(NewCorpusFromFileWith("corpus", path, CorpusBuildOptions{Rebuild: true, Coverage: 100, Spaced: true}))
+	if !corpus.Spaced || corpus.Unigrams[' '] != 2 || corpus.Words["cd"] != 1 {
+		t.Errorf("spaced corpus = %v, %v", corpus.Spaced, corpus.Unigrams)
+	}
+	// A run of whitespace is one space, and n-grams do not cross line breaks
+	for ngram, got := range map[string]uint64{
+		"b ": corpus.Bigrams[Bigram{'b', ' '}], " c": corpus.Bigrams[Bigram{' ', 'c'}],
+		"b c": corpus.Trigrams[Trigram{'b', ' ', 'c'}], "b_c": corpus.Skipgrams[Skipgram{'b', 'c'}],
+		" e": corpus.Bigrams[Bigram{' ', 'e'}],
+	} {
+		if got != 1 {
+			t.Errorf("count of %q = %d, want 1", ngram, got)
+		}
+	}
+	if n := corpus.Bigrams[Bigram{'e', ' '}] + corpus.Bigrams[Bigram{' ', 'f'}] + corpus.Bigrams[Bigram{'e', 'f'}]; n != 0 {
+		t.Errorf("%d n-grams cross the line break", n)
+	}
+
+	// The cache of the build keeps the space, and the default build leaves it out
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	cached := Must(NewCorpusFromFileWith("corpus", path, CorpusBuildOptions{Coverage: 100, Spaced: true}))
+	if !cached.Spaced || cached.Unigrams[' '] != 2 {
+		t.Errorf("cached corpus = %v, %v", cached.Spaced, cached.Unigrams)
+	}
+	if plain := Must(NewCorpusFromFile("corpus", path, false, 100)); plain.Spaced || plain.Unigrams[' '] != 0 {
+		t.Errorf("default corpus = %v, %v", plain.Spaced, plain.Unigrams)
+	}
+
+	// Pressing space counts towards the metrics, here as alternations onto the right thumb
+	sl := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	plain := Must(NewCorpusFromFile("corpus", path, true, 100))
+	spaced := NewAnalyser(sl, corpus, nil)
+	if got, want := spaced.Metrics["ALT"], NewAnalyser(sl, plain, nil).Metrics["ALT"]; got <= want {
+		t.Errorf("ALT = %v with space, want more than %v without", got, want)
+	}
+}
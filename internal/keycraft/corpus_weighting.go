@@ -0,0 +1,193 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CommonWordsCoverage is the percentage of word occurrences covered by the most frequent
+// words that make up the "common" word frequency class.
+const CommonWordsCoverage = 50.0
+
+// BigramWeighting emphasises bigrams by their position within words, or by the frequency
+// class of the words they occur in. Each weight multiplies the count of the matching
+// bigram occurrences; a weight of 1 leaves counts unchanged and 0 ignores them. An
+// occurrence that matches several classes (e.g. "of" is both word-initial and word-final)
+// receives each extra weight. Trigrams and skipgrams are weighted alike (see
+// Corpus.Weighted), so that the metrics of all n-grams see the same emphasis.
+type BigramWeighting struct {
+	Initial float64 // Bigrams formed by the first two characters of a word ("th" in "the")
+	Final   float64 // Bigrams formed by the last two characters of a word ("he" in "the")
+	Common  float64 // Bigrams inside the most common words (see CommonWordsCoverage)
+}
+
+// NewBigramWeighting returns a neutral weighting that leaves all counts unchanged.
+func NewBigramWeighting() *BigramWeighting {
+	return &BigramWeighting{Initial: 1, Final: 1, Common: 1}
+}
+
+// NewBigramWeightingFromString parses comma-separated class=weight pairs
+// (e.g., "initial=2,final=1.5,common=2"). Classes not mentioned keep weight 1.
+func NewBigramWeightingFromString(weightingStr string) (*BigramWeighting, error) {
+	w := NewBigramWeighting()
+	weightingStr = strings.ToLower(strings.TrimSpace(weightingStr))
+	if weightingStr == "" {
+		return w, nil
+	}
+
+	for pair := range strings.SplitSeq(weightingStr, ",") {
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid bigram weighting format: %s", pair)
+		}
+		class := strings.TrimSpace(parts[0])
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || weight < 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+			return nil, fmt.Errorf("invalid weight value for class %s: must be a number >= 0", class)
+		}
+
+		switch class {
+		case "initial":
+			w.Initial = weight
+		case "final":
+			w.Final = weight
+		case "common":
+			w.Common = weight
+		default:
+			return nil, fmt.Errorf("invalid bigram class %q: must be one of: initial, final, common", class)
+		}
+	}
+
+	return w, nil
+}
+
+// IsNeutral reports whether the weighting leaves all counts unchanged.
+func (w *BigramWeighting) IsNeutral() bool {
+	return w == nil || (w.Initial == 1 && w.Final == 1 && w.Common == 1)
+}
+
+// String describes the non-neutral weights, e.g. "initial=2,final=1.5".
+func (w *BigramWeighting) String() string {
+	if w.IsNeutral() {
+		return "none"
+	}
+	var parts []string
+	for _, cw := range []struct {
+		class  string
+		weight float64
+	}{{"initial", w.Initial}, {"final", w.Final}, {"common", w.Common}} {
+		if cw.weight != 1 {
+			parts = append(parts, fmt.Sprintf("%s=%g", cw.class, cw.weight))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// CommonWords returns the most frequent words that together cover coveragePercent of
+// all word occurrences in the corpus.
+func (c *Corpus) CommonWords(coveragePercent float64) []CountPair[string] {
+	words := SortedMap(c.Words)
+	target := uint64(float64(c.TotalWordsCount) * coveragePercent / 100.0)
+
+	var covered uint64
+	for i, wc := range words {
+		if covered >= target {
+			return words[:i]
+		}
+		covered += wc.Count
+	}
+	return words
+}
+
+// Weighted returns a copy of the corpus whose bigram, trigram and skipgram tables are
+// reweighted by w. The other tables are shared with the receiver. The name of the copy
+// mentions the weighting.
+func (c *Corpus) Weighted(w *BigramWeighting) *Corpus {
+	if w.IsNeutral() {
+		return c
+	}
+
+	bigrams := make(map[Bigram]float64)
+	initial, final := c.wordPositionBigrams()
+	for bi, cnt := range initial {
+		bigrams[bi] += (w.Initial - 1) * float64(cnt)
+	}
+	for bi, cnt := range final {
+		bigrams[bi] += (w.Final - 1) * float64(cnt)
+	}
+
+	// The trigrams formed by the first and last three characters of a word, and the
+	// skipgrams of their outer characters, are weighted like the bigrams at the same ends.
+	// They are counted from the word list, which lacks the rarest words pruned by word
+	// coverage filtering.
+	trigrams := make(map[Trigram]float64)
+	skipgrams := make(map[Skipgram]float64)
+	addTrigram := func(runes []rune, extra float64) {
+		trigrams[Trigram{runes[0], runes[1], runes[2]}] += extra
+		skipgrams[Skipgram{runes[0], runes[2]}] += extra
+	}
+	for word, cnt := range c.Words {
+		if runes := []rune(word); len(runes) >= 3 {
+			addTrigram(runes[:3], (w.Initial-1)*float64(cnt))
+			addTrigram(runes[len(runes)-3:], (w.Final-1)*float64(cnt))
+		}
+	}
+
+	if w.Common != 1 {
+		for _, wc := range c.CommonWords(CommonWordsCoverage) {
+			runes := []rune(wc.Key)
+			extra := (w.Common - 1) * float64(wc.Count)
+			for i := 1; i < len(runes); i++ {
+				bigrams[Bigram{runes[i-1], runes[i]}] += extra
+			}
+			for i := 2; i < len(runes); i++ {
+				addTrigram(runes[i-2:], extra)
+			}
+		}
+	}
+
+	weighted := *c
+	weighted.Name = fmt.Sprintf("%s (%s)", c.Name, w)
+	weighted.Bigrams, weighted.TotalBigramsCount = reweighted(c.Bigrams, bigrams)
+	weighted.Trigrams, weighted.TotalTrigramsCount = reweighted(c.Trigrams, trigrams)
+	weighted.Skipgrams, weighted.TotalSkipgramsCount = reweighted(c.Skipgrams, skipgrams)
+	return &weighted
+}
+
+// reweighted returns a copy of n-gram counts with the extra counts added, rounded and
+// without the n-grams whose count drops to 0, and the total of the new counts.
+func reweighted[K comparable](counts map[K]uint64, extra map[K]float64) (map[K]uint64, uint64) {
+	newCounts := make(map[K]uint64, len(counts))
+	var total uint64
+	for ngram, cnt := range counts {
+		newCnt := uint64(math.Round(math.Max(0, float64(cnt)+extra[ngram])))
+		if newCnt == 0 {
+			continue
+		}
+		newCounts[ngram] = newCnt
+		total += newCnt
+	}
+	return newCounts, total
+}
+
+// wordPositionBigrams returns the word-initial and word-final bigram tables. Corpus caches
+// built before these tables were recorded derive them from the word list instead, which
+// lacks the rarest words pruned by word coverage filtering.
+func (c *Corpus) wordPositionBigrams() (map[Bigram]uint64, map[Bigram]uint64) {
+	if c.WordInitialBigrams != nil && c.WordFinalBigrams != nil {
+		return c.WordInitialBigrams, c.WordFinalBigrams
+	}
+
+	initial := make(map[Bigram]uint64)
+	final := make(map[Bigram]uint64)
+	for word, cnt := range c.Words {
+		if first, last, ok := wordEndBigrams(word); ok {
+			initial[first] += cnt
+			final[last] += cnt
+		}
+	}
+	return initial, final
+}
@@ -0,0 +1,147 @@
+package keycraft
+
+import (
+	"testing"
+)
+
+func TestCorpus_WordPositionTables(t *testing.T) {
+	c := NewCorpus("test")
+	c.addTextWithWords("the then a theory, ok")
+
+	// "a" is too short to have an initial or final bigram
+	wantInitial := map[Bigram]uint64{{'t', 'h'}: 3, {'o', 'k'}: 1}
+	wantFinal := map[Bigram]uint64{{'h', 'e'}: 1, {'e', 'n'}: 1, {'r', 'y'}: 1, {'o', 'k'}: 1}
+	for bi, want := range wantInitial {
+		if got := c.WordInitialBigrams[bi]; got != want {
+			t.Errorf("initial %s: expected %d, got %d", bi, want, got)
+		}
+	}
+	for bi, want := range wantFinal {
+		if got := c.WordFinalBigrams[bi]; got != want {
+			t.Errorf("final %s: expected %d, got %d", bi, want, got)
+		}
+	}
+	if len(c.WordInitialBigrams) != len(wantInitial) || len(c.WordFinalBigrams) != len(wantFinal) {
+		t.Errorf("unexpected tables: initial %v, final %v", c.WordInitialBigrams, c.WordFinalBigrams)
+	}
+}
+
+func TestNewBigramWeightingFromString(t *testing.T) {
+	w, err := NewBigramWeightingFromString("Initial=2, final=1.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *w != (BigramWeighting{Initial: 2, Final: 1.5, Common: 1}) {
+		t.Errorf("unexpected weighting: %+v", *w)
+	}
+	if w.String() != "initial=2,final=1.5" {
+		t.Errorf("unexpected string: %s", w)
+	}
+
+	if w, err := NewBigramWeightingFromString(""); err != nil || !w.IsNeutral() {
+		t.Errorf("expected neutral weighting for empty string, got %+v, %v", w, err)
+	}
+
+	for _, bad := range []string{"initial", "middle=2", "final=-1", "common=x", "initial=nan"} {
+		if _, err := NewBigramWeightingFromString(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCorpus_Weighted(t *testing.T) {
+	c := NewCorpus("test")
+	c.addTextWithWords("the other")
+
+	// Neutral weighting returns the corpus itself
+	if c.Weighted(NewBigramWeighting()) != c {
+		t.Error("expected neutral weighting to return the same corpus")
+	}
+
+	w := c.Weighted(&BigramWeighting{Initial: 3, Final: 0, Common: 1})
+	// "th" starts "the" only (it is inside "other"); "he" ends "the"; "er" ends "other"
+	want := map[Bigram]uint64{
+		{'t', 'h'}: 2 + 2*1,
+		{'h', 'e'}: 2 - 1,
+		{'o', 't'}: 1 + 2*1,
+		{'e', 'r'}: 0,
+	}
+	for bi, cnt := range want {
+		if got := w.Bigrams[bi]; got != cnt {
+			t.Errorf("bigram %s: expected %d, got %d", bi, cnt, got)
+		}
+	}
+	if _, ok := w.Bigrams[Bigram{'e', 'r'}]; ok {
+		t.Error("expected bigrams with zero weight to be removed")
+	}
+
+	// "the" starts and ends "the" and is inside "other"; "oth" starts "other", "her" ends it
+	for tri, cnt := range map[Trigram]uint64{{'t', 'h', 'e'}: 2 + 2*1 - 1, {'o', 't', 'h'}: 1 + 2*1} {
+		if got := w.Trigrams[tri]; got != cnt {
+			t.Errorf("trigram %s: expected %d, got %d", tri, cnt, got)
+		}
+	}
+	for sk, cnt := range map[Skipgram]uint64{{'t', 'e'}: 2 + 2*1 - 1, {'o', 'h'}: 1 + 2*1} {
+		if got := w.Skipgrams[sk]; got != cnt {
+			t.Errorf("skipgram %s: expected %d, got %d", sk, cnt, got)
+		}
+	}
+	if _, ok := w.Trigrams[Trigram{'h', 'e', 'r'}]; ok {
+		t.Error("expected trigrams with zero weight to be removed")
+	}
+	if _, ok := w.Skipgrams[Skipgram{'h', 'r'}]; ok {
+		t.Error("expected skipgrams with zero weight to be removed")
+	}
+
+	var total, triTotal, skipTotal uint64
+	for _, cnt := range w.Bigrams {
+		total += cnt
+	}
+	for _, cnt := range w.Trigrams {
+		triTotal += cnt
+	}
+	for _, cnt := range w.Skipgrams {
+		skipTotal += cnt
+	}
+	if w.TotalBigramsCount != total || w.TotalTrigramsCount != triTotal || w.TotalSkipgramsCount != skipTotal {
+		t.Errorf("expected totals %d, %d, %d, got %d, %d, %d", total, triTotal, skipTotal,
+			w.TotalBigramsCount, w.TotalTrigramsCount, w.TotalSkipgramsCount)
+	}
+	if w.Name != "test (initial=3,final=0)" {
+		t.Errorf("unexpected name %q", w.Name)
+	}
+
+	// The original corpus is unchanged
+	if c.Bigrams[Bigram{'t', 'h'}] != 2 || c.TotalBigramsCount != 6 || c.Trigrams[Trigram{'t', 'h', 'e'}] != 2 {
+		t.Errorf("original corpus modified: %v, %v", c.Bigrams, c.Trigrams)
+	}
+}
+
+func TestCorpus_Weighted_Common(t *testing.T) {
+	c := NewCorpus("test")
+	c.addTextWithWords("aaa aaa aaa bbb")
+
+	// "aaa" alone covers 75% of word occurrences, so it is the only common word
+	w := c.Weighted(&BigramWeighting{Initial: 1, Final: 1, Common: 2})
+	if w.Bigrams[Bigram{'a', 'a'}] != 12 || w.Bigrams[Bigram{'b', 'b'}] != 2 {
+		t.Errorf("unexpected weighted bigrams: %v", w.Bigrams)
+	}
+	if w.Trigrams[Trigram{'a', 'a', 'a'}] != 6 || w.Trigrams[Trigram{'b', 'b', 'b'}] != 1 {
+		t.Errorf("unexpected weighted trigrams: %v", w.Trigrams)
+	}
+	if w.Skipgrams[Skipgram{'a', 'a'}] != 6 || w.Skipgrams[Skipgram{'b', 'b'}] != 1 {
+		t.Errorf("unexpected weighted skipgrams: %v", w.Skipgrams)
+	}
+}
+
+func TestCorpus_Weighted_DerivesPositionsFromWords(t *testing.T) {
+	c := NewCorpus("test")
+	c.addTextWithWords("the the")
+	// Simulate a corpus cache built before word-position tables were recorded
+	c.WordInitialBigrams, c.WordFinalBigrams = nil, nil
+
+	w := c.Weighted(&BigramWeighting{Initial: 2, Final: 1, Common: 1})
+	if w.Bigrams[Bigram{'t', 'h'}] != 4 {
+		t.Errorf("expected derived initial weighting, got %v", w.Bigrams)
+	}
+}
@@ -0,0 +1,135 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// wordCountsSniffLines is the number of lines that isWordCountsFile checks.
+const wordCountsSniffLines = 100
+
+// parseWordCount parses a line of a word-frequency list, such as "the,23135851162" or
+// "the\t23135851162". The word and count are separated by a comma, tab or spaces.
+func parseWordCount(line string) (string, uint64, bool) {
+	fields := wordCountFields(line)
+	if len(fields) != 2 {
+		return "", 0, false
+	}
+	count, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToLower(fields[0]), count, true
+}
+
+// wordCountFields splits a line of a word-frequency list into its columns.
+func wordCountFields(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == '\t' || r == ' ' })
+}
+
+// isWordCountsFile reports whether a corpus file is a word-frequency list rather than text:
+// every one of its first lines is a word and a count, except for an optional header line of
+// two columns, such as "word,count".
+func isWordCountsFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("could not open file: %w", err)
+	}
+	defer CloseFile(file)
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() && lines < wordCountsSniffLines {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines++
+		if _, _, ok := parseWordCount(line); !ok && (lines > 1 || len(wordCountFields(line)) != 2) {
+			return false, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("could not read file: %w", err)
+	}
+	return lines > 1, nil
+}
+
+// addWordCount adds count occurrences of a word, and of its n-grams, as if the word had been
+// typed count times between spaces.
+func (c *Corpus) addWordCount(word string, count uint64) {
+	runes := []rune(word)
+	for i, r := range runes {
+		c.Unigrams[Unigram(r)] += count
+		c.TotalUnigramsCount += count
+		if i >= 1 {
+			c.Bigrams[Bigram{runes[i-1], r}] += count
+			c.TotalBigramsCount += count
+		}
+		if i >= 2 {
+			c.Trigrams[Trigram{runes[i-2], runes[i-1], r}] += count
+			c.TotalTrigramsCount += count
+			if !c.Skipgram.weighted() {
+				c.Skipgrams[Skipgram{runes[i-2], r}] += count
+				c.TotalSkipgramsCount += count
+			}
+		}
+		if c.Skipgram.weighted() {
+			c.addWeightedSkipgrams(runes[:i], r, count)
+		}
+	}
+	c.Words[word] += count
+	c.TotalWordsCount += count
+	if first, last, ok := wordEndBigrams(word); ok {
+		c.WordInitialBigrams[first] += count
+		c.WordFinalBigrams[last] += count
+	}
+}
+
+// loadFromWordCounts synthesises the n-grams of a corpus from a word-frequency list, with a
+// word and its count on every line. Words are lowercased, and the counts of words that only
+// differ in case are added up. Characters of the substitution table are replaced, and
+// excluded words are skipped. A word list has no typing order, so Stream is left empty.
+// After loading, prunes the word list to keep only the most frequent words covering the
+// specified percentage of total word occurrences.
+func (c *Corpus) loadFromWordCounts(path string, coveragePercent float64, excluded map[string]bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open file: %w", err)
+	}
+	defer CloseFile(file)
+
+	lineNum, lines, words := 0, 0, 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines++
+		word, count, ok := parseWordCount(line)
+		if !ok {
+			if lines == 1 {
+				continue // Header
+			}
+			return fmt.Errorf("line %d: expected a word and a count, got %q", lineNum, line)
+		}
+		words++
+		word = c.substitute(word, count)
+		if !excluded[word] {
+			c.addWordCount(word, count)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read file: %w", err)
+	}
+
+	fmt.Printf("Synthesised n-grams from %d word counts\n\n", words)
+	c.pruneWordsByCoverage(coveragePercent)
+
+	return nil
+}
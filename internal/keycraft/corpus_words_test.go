@@ -0,0 +1,56 @@
+package keycraft
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsWordCountsFile(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]bool{
+		"the\t100\nof 50\n":                    true,
+		"word,count\nThe,100\nof,50\n":         true,
+		"the quick brown fox\njumps over\n":    false,
+		"the 100\nsome text follows here\n":    false,
+		"the 100\n":                            false,
+		"word count here\nthe 100\nof 50\n":    false,
+		"ninety nine\nthe 100\nof 50\nand 3\n": true,
+	}
+	for content, want := range tests {
+		path := filepath.Join(dir, "corpus.txt")
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		if got := Must(isWordCountsFile(path)); got != want {
+			t.Errorf("isWordCountsFile(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
+
+// TestNewCorpusFromFile_WordCounts verifies that the n-grams synthesised from a word-frequency
+// list match those of a text with the same words.
+func TestNewCorpusFromFile_WordCounts(t *testing.T) {
+	dir := t.TempDir()
+	listPath := filepath.Join(dir, "list.txt")
+	Must0(os.WriteFile(listPath, []byte("word,count\nthe,3\nThe,1\nof,2\nlorem,5\n"), 0644))
+	textPath := filepath.Join(dir, "text.txt")
+	Must0(os.WriteFile(textPath, []byte("the the of the\nthe of\n"), 0644))
+
+	opts := CorpusBuildOptions{Rebuild: true, Coverage: 100, Excluded: map[string]bool{"lorem": true}}
+	list := Must(NewCorpusFromFileWith("list", listPath, opts))
+	text := Must(NewCorpusFromFile("text", textPath, true, 100))
+
+	if !maps.Equal(list.Words, text.Words) || list.TotalWordsCount != text.TotalWordsCount {
+		t.Errorf("words %v, want %v", list.Words, text.Words)
+	}
+	if !maps.Equal(list.Unigrams, text.Unigrams) || !maps.Equal(list.Bigrams, text.Bigrams) ||
+		!maps.Equal(list.Trigrams, text.Trigrams) || !maps.Equal(list.Skipgrams, text.Skipgrams) {
+		t.Error("synthesised n-grams differ from those of the text")
+	}
+	if !maps.Equal(list.WordInitialBigrams, text.WordInitialBigrams) {
+		t.Errorf("word-initial bigrams %v, want %v", list.WordInitialBigrams, text.WordInitialBigrams)
+	}
+	if list.Stream != "" {
+		t.Errorf("stream %q, want none", list.Stream)
+	}
+}
@@ -0,0 +1,131 @@
+package keycraft
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DedupeInput contains parameters for duplicate layout detection.
+type DedupeInput struct {
+	LayoutsDir string
+	MaxDiff    int // Maximum number of differing keys for near-duplicates (0 = exact only)
+}
+
+// LayoutCluster is a group of layouts that are identical or nearly identical.
+type LayoutCluster struct {
+	ID      string   // Identifier of the first layout in the cluster
+	Names   []string // Layout names, sorted
+	MaxDiff int      // Largest key difference between any member and the first member
+}
+
+// DedupeResult contains the duplicate clusters found in a directory.
+type DedupeResult struct {
+	NumLayouts int
+	Exact      []LayoutCluster // Identical layouts under different names
+	Near       []LayoutCluster // Layouts differing in at most MaxDiff keys (excluding exact-only groups)
+	MaxDiff    int
+}
+
+// KeyDiff returns the number of key positions at which two layouts differ.
+// Layouts of different layout types are never considered similar, and -1 is returned.
+func KeyDiff(a, b *SplitLayout) int {
+	if a.LayoutType != b.LayoutType {
+		return -1
+	}
+	diff := 0
+	for i := range a.Runes {
+		if a.Runes[i] != b.Runes[i] {
+			diff++
+		}
+	}
+	return diff
+}
+
+// FindDuplicateLayouts groups the layouts in a directory by exact configuration
+// and, when MaxDiff > 0, by small key differences. Near-duplicate clusters use
+// single linkage: two layouts end up in the same cluster if a chain of layouts
+// connects them where each step differs in at most MaxDiff keys.
+func FindDuplicateLayouts(input DedupeInput) (*DedupeResult, error) {
+	if input.MaxDiff < 0 {
+		return nil, fmt.Errorf("max diff must not be negative. Got: %d", input.MaxDiff)
+	}
+
+	layouts, err := loadLayoutsInDir(input.LayoutsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DedupeResult{NumLayouts: len(layouts), MaxDiff: input.MaxDiff}
+
+	// Exact duplicates share an identifier
+	byID := make(map[string][]int)
+	for i, layout := range layouts {
+		id := layout.ID()
+		byID[id] = append(byID[id], i)
+	}
+	for _, members := range byID {
+		if len(members) > 1 {
+			result.Exact = append(result.Exact, newLayoutCluster(layouts, members))
+		}
+	}
+
+	// Near duplicates: union-find over all pairs within MaxDiff
+	if input.MaxDiff > 0 {
+		parent := make([]int, len(layouts))
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+			return parent[i]
+		}
+
+		for i := range layouts {
+			for j := i + 1; j < len(layouts); j++ {
+				if d := KeyDiff(layouts[i], layouts[j]); d > 0 && d <= input.MaxDiff {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+
+		clusters := make(map[int][]int)
+		for i := range layouts {
+			root := find(i)
+			clusters[root] = append(clusters[root], i)
+		}
+		for _, members := range clusters {
+			if len(members) > 1 {
+				result.Near = append(result.Near, newLayoutCluster(layouts, members))
+			}
+		}
+	}
+
+	sortClusters(result.Exact)
+	sortClusters(result.Near)
+	return result, nil
+}
+
+// newLayoutCluster builds a cluster from layout indices (which are sorted by name).
+func newLayoutCluster(layouts []*SplitLayout, members []int) LayoutCluster {
+	sort.Ints(members)
+	first := layouts[members[0]]
+	cluster := LayoutCluster{ID: first.ID()}
+	for _, m := range members {
+		cluster.Names = append(cluster.Names, layouts[m].Name)
+		cluster.MaxDiff = max(cluster.MaxDiff, KeyDiff(first, layouts[m]))
+	}
+	return cluster
+}
+
+// sortClusters orders clusters by size (largest first), then by first name.
+func sortClusters(clusters []LayoutCluster) {
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Names) != len(clusters[j].Names) {
+			return len(clusters[i].Names) > len(clusters[j].Names)
+		}
+		return clusters[i].Names[0] < clusters[j].Names[0]
+	})
+}
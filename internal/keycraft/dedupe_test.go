@@ -0,0 +1,58 @@
+package keycraft
+
+import "testing"
+
+// testLayoutFarKlf differs from testLayoutKlf in many keys.
+const testLayoutFarKlf = `colstag
+ ~ q w f p g  j l u y ; \
+ ~ a r s t d  h n e i o '
+ ~ z x c v b  k m , . / ~
+ ~ ~ ~  _ ~ ~
+`
+
+func TestFindDuplicateLayouts(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"b.klf":   testLayoutKlf,
+		"c.klf":   testLayoutVariantKlf, // z and x swapped: 2 keys differ from a
+		"far.klf": testLayoutFarKlf,
+	})
+
+	result, err := FindDuplicateLayouts(DedupeInput{LayoutsDir: dir, MaxDiff: 2})
+	if err != nil {
+		t.Fatalf("FindDuplicateLayouts failed: %v", err)
+	}
+
+	if result.NumLayouts != 4 {
+		t.Errorf("expected 4 layouts, got %d", result.NumLayouts)
+	}
+	if len(result.Exact) != 1 || len(result.Exact[0].Names) != 2 {
+		t.Fatalf("expected one exact group of 2, got %v", result.Exact)
+	}
+	if len(result.Near) != 1 {
+		t.Fatalf("expected one near-duplicate cluster, got %v", result.Near)
+	}
+	near := result.Near[0]
+	if len(near.Names) != 3 || near.Names[0] != "a" || near.Names[2] != "c" {
+		t.Errorf("unexpected near cluster: %v", near.Names)
+	}
+	if near.MaxDiff != 2 {
+		t.Errorf("expected MaxDiff 2, got %d", near.MaxDiff)
+	}
+
+	exactOnly, err := FindDuplicateLayouts(DedupeInput{LayoutsDir: dir, MaxDiff: 0})
+	if err != nil {
+		t.Fatalf("FindDuplicateLayouts failed: %v", err)
+	}
+	if len(exactOnly.Near) != 0 {
+		t.Errorf("expected no near clusters with MaxDiff 0, got %v", exactOnly.Near)
+	}
+}
+
+func TestKeyDiff_DifferentTypes(t *testing.T) {
+	a := &SplitLayout{LayoutType: ROWSTAG}
+	b := &SplitLayout{LayoutType: COLSTAG}
+	if d := KeyDiff(a, b); d != -1 {
+		t.Errorf("expected -1 for different layout types, got %d", d)
+	}
+}
@@ -0,0 +1,483 @@
+package keycraft
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExperimentAlgorithms lists the optimization algorithms of experiments, and whether each
+// uses compound moves besides pairwise swaps.
+var ExperimentAlgorithms = map[string]bool{
+	"bls":          false,
+	"bls-compound": true,
+}
+
+// Experiment is a set of optimization runs of one layout: every combination of its corpora,
+// weights files, algorithms and seeds is a trial.
+type Experiment struct {
+	Name        string
+	Layout      string   // Layout to optimize
+	Corpora     []string // Corpus files
+	Weights     []string // Weights files
+	Algorithms  []string // Names of ExperimentAlgorithms
+	Seeds       []int64
+	Generations int    // Iterations of each trial
+	MaxTime     int    // Time limit of each trial, in minutes
+	PinsFile    string // Optional: pins file of the layout
+	Pins        string // Optional: characters to pin
+	Free        string // Optional: characters to free, pinning all others
+	BLSParams   string // Optional: BLS parameters, as read by ParseBLSTuning
+}
+
+// ExperimentTrial is one optimization run of an experiment.
+type ExperimentTrial struct {
+	Corpus    string `json:"corpus"`
+	Weights   string `json:"weights"`
+	Algorithm string `json:"algorithm"`
+	Seed      int64  `json:"seed"`
+}
+
+// NewExperimentFromFile loads an experiment from a file of "key = value" lines. Lists are
+// comma-separated and may be enclosed in brackets, and values may be quoted, so that the file
+// can also be written as TOML:
+//
+//	name = compound-moves
+//	layout = qwerty
+//	corpora = ["default.txt", "monkeyracer.txt"]
+//	weights = weights.txt, weights2.txt
+//	algorithms = bls, bls-compound
+//	seeds = 1, 2, 3
+//	generations = 500
+//	maxtime = 5
+//
+// Lines starting with '#' are comments. The name defaults to the file name; weights default
+// to weights.txt, algorithms to bls, seeds to 1, generations to 1000 and maxtime to 5.
+func NewExperimentFromFile(path string) (*Experiment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open experiment file: %w", err)
+	}
+	defer CloseFile(file)
+
+	exp := &Experiment{
+		Name:        strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Weights:     []string{"weights.txt"},
+		Algorithms:  []string{"bls"},
+		Seeds:       []int64{1},
+		Generations: 1000,
+		MaxTime:     5,
+	}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := exp.parseLine(line); err != nil {
+			return nil, fmt.Errorf("invalid line %d in %s: %w", lineNum, path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read experiment file: %w", err)
+	}
+
+	if exp.Layout == "" {
+		return nil, fmt.Errorf("missing layout in %s", path)
+	}
+	if len(exp.Corpora) == 0 {
+		return nil, fmt.Errorf("missing corpora in %s", path)
+	}
+	return exp, nil
+}
+
+// parseLine parses a "key = value" setting of an experiment.
+func (exp *Experiment) parseLine(line string) error {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return fmt.Errorf("expected key = value, got %q", line)
+	}
+	key = strings.TrimSpace(key)
+	value = unquote(strings.TrimSpace(value))
+
+	switch key {
+	case "name":
+		exp.Name = value
+	case "layout":
+		exp.Layout = value
+	case "corpora", "corpus":
+		exp.Corpora = parseList(value)
+	case "weights":
+		exp.Weights = parseList(value)
+	case "algorithms", "algorithm":
+		exp.Algorithms = parseList(value)
+		for _, name := range exp.Algorithms {
+			if _, ok := ExperimentAlgorithms[name]; !ok {
+				return fmt.Errorf("unknown algorithm %q (use bls or bls-compound)", name)
+			}
+		}
+	case "seeds":
+		exp.Seeds = nil
+		for _, s := range parseList(value) {
+			seed, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid seed %q: %w", s, err)
+			}
+			exp.Seeds = append(exp.Seeds, seed)
+		}
+	case "generations", "maxtime":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid %s %q: must be a whole number of at least 1", key, value)
+		}
+		if key == "generations" {
+			exp.Generations = n
+		} else {
+			exp.MaxTime = n
+		}
+	case "pins-file":
+		exp.PinsFile = value
+	case "pins":
+		exp.Pins = value
+	case "free":
+		exp.Free = value
+	case "bls-params":
+		if _, err := ParseBLSTuning(value); err != nil {
+			return err
+		}
+		exp.BLSParams = value
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	return nil
+}
+
+// unquote removes double quotes around a value.
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseList parses a comma-separated list, optionally enclosed in brackets and with quoted items.
+func parseList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Trials returns every combination of the corpora, weights, algorithms and seeds of the
+// experiment, in that order of nesting.
+func (exp *Experiment) Trials() []ExperimentTrial {
+	var trials []ExperimentTrial
+	for _, corpus := range exp.Corpora {
+		for _, weights := range exp.Weights {
+			for _, algorithm := range exp.Algorithms {
+				for _, seed := range exp.Seeds {
+					trials = append(trials, ExperimentTrial{corpus, weights, algorithm, seed})
+				}
+			}
+		}
+	}
+	return trials
+}
+
+// ExperimentRecord is the result of a trial, as stored in the JSONL results of an experiment.
+type ExperimentRecord struct {
+	Experiment string `json:"experiment"`
+	Layout     string `json:"layout"`
+	ExperimentTrial
+	Generations int                `json:"generations"`
+	MaxTime     int                `json:"maxtime"`
+	BLSParams   string             `json:"bls_params,omitempty"`
+	Started     time.Time          `json:"started"`
+	ElapsedMs   int64              `json:"elapsed_ms"`
+	InitialCost float64            `json:"initial_cost"`
+	BestCost    float64            `json:"best_cost"`
+	BestLayout  []string           `json:"best_layout"`
+	Metrics     map[string]float64 `json:"metrics"` // Metrics of the best layout
+}
+
+// Key identifies the trial and settings of a record, so that an experiment can skip the
+// trials it already has results for.
+func (r ExperimentRecord) Key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d|%d|%d|%s", r.Experiment, r.Layout, r.Corpus, r.Weights,
+		r.Algorithm, r.Seed, r.Generations, r.MaxTime, r.BLSParams)
+}
+
+// ReadExperimentRecords parses JSONL experiment results. Empty lines are ignored.
+func ReadExperimentRecords(r io.Reader) ([]ExperimentRecord, error) {
+	var records []ExperimentRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record ExperimentRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("invalid experiment result on line %d: %w", lineNum, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read experiment results: %w", err)
+	}
+	return records, nil
+}
+
+// LoadExperimentRecords loads the JSONL results of an experiment. A missing file holds no
+// results yet.
+func LoadExperimentRecords(path string) ([]ExperimentRecord, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open experiment results: %w", err)
+	}
+	defer CloseFile(file)
+
+	records, err := ReadExperimentRecords(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not load %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// WriteExperimentRecord appends a record to JSONL experiment results.
+func WriteExperimentRecord(w io.Writer, r ExperimentRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("could not encode experiment result: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("could not write experiment result: %w", err)
+	}
+	return nil
+}
+
+// ExperimentJob is a trial of an experiment with the optimization input to run it with.
+type ExperimentJob struct {
+	Record ExperimentRecord // Identifies the trial; the results are filled in by RunExperiment
+	Input  OptimizeInput
+}
+
+// RunExperiment runs the jobs of an experiment on a pool of workers, and passes the record
+// of each finished trial to save, in order of completion. Each trial runs a sequential BLS;
+// jobs with the same corpus and weights share a scorer. Use workers 0 for one worker per CPU.
+// Cancelling ctx stops the experiment: trials that are interrupted are not saved, so that
+// running the experiment again resumes it.
+func RunExperiment(ctx context.Context, jobs []ExperimentJob, workers int, save func(ExperimentRecord) error) error {
+	type scorerKey struct {
+		corpus  *Corpus
+		weights *Weights
+	}
+	scorers := map[scorerKey]*Scorer{}
+	for _, job := range jobs {
+		key := scorerKey{job.Input.Corpus, job.Input.Weights}
+		if _, ok := scorers[key]; ok {
+			continue
+		}
+		scorer, err := newOptimizeScorer(job.Input, withDefaultTargets(job.Input.Targets), job.Input.Seed)
+		if err != nil {
+			return err
+		}
+		scorers[key] = scorer
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	queue := make(chan ExperimentJob)
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		saveErr error
+	)
+	for range workers {
+		wg.Go(func() {
+			for job := range queue {
+				record, ok := runExperimentJob(ctx, job, scorers[scorerKey{job.Input.Corpus, job.Input.Weights}])
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				if saveErr == nil {
+					saveErr = save(record)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if saveErr != nil {
+		return saveErr
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("experiment stopped: %w", err)
+	}
+	return nil
+}
+
+// runExperimentJob runs the trial of a job, and returns its record unless it was interrupted.
+func runExperimentJob(ctx context.Context, job ExperimentJob, scorer *Scorer) (ExperimentRecord, bool) {
+	input := job.Input
+	input.UseParallel = false
+	numFree := 0
+	for _, isPinned := range input.Pinned {
+		if !isPinned {
+			numFree++
+		}
+	}
+
+	record := job.Record
+	record.Started = time.Now()
+	bls := NewBLS(optimizeParams(input, numFree), scorer, input.Corpus, input.Pinned)
+	if input.SwapClasses != nil {
+		bls.RestrictSwapClasses(input.Layout)
+	}
+	best := bls.Optimize(ctx, input.Layout, nil)
+	if bls.Interrupted() {
+		return record, false
+	}
+
+	record.ElapsedMs = time.Since(record.Started).Milliseconds()
+	record.InitialCost = scorer.ScoreExact(input.Layout)
+	record.BestCost = scorer.ScoreExact(best)
+	record.BestLayout = layoutToStrings(best)
+	record.Metrics = NewAnalyser(best, input.Corpus, withDefaultTargets(input.Targets)).Metrics
+	return record, true
+}
+
+// ExperimentDimensions are the fields of experiment records that reports can group by.
+var ExperimentDimensions = []string{"layout", "corpus", "weights", "algorithm", "seed", "generations"}
+
+// dimension returns the value of a field of the record by its name in ExperimentDimensions.
+func (r ExperimentRecord) dimension(name string) string {
+	switch name {
+	case "layout":
+		return r.Layout
+	case "corpus":
+		return r.Corpus
+	case "weights":
+		return r.Weights
+	case "algorithm":
+		return r.Algorithm
+	case "seed":
+		return strconv.FormatInt(r.Seed, 10)
+	case "generations":
+		return strconv.Itoa(r.Generations)
+	}
+	return ""
+}
+
+// ExperimentGroup aggregates the records of an experiment that share the values of the
+// report dimensions.
+type ExperimentGroup struct {
+	Values      []string // Values of the report dimensions
+	Runs        int
+	MeanCost    float64 // Mean best cost
+	StdDev      float64 // Standard deviation of the best costs
+	MinCost     float64 // Lowest best cost
+	MeanGain    float64 // Mean decrease of the cost from the initial layout
+	MeanElapsed time.Duration
+}
+
+// AggregateExperiment groups experiment records by the named dimensions, and returns the
+// groups sorted by their values.
+func AggregateExperiment(records []ExperimentRecord, by []string) ([]ExperimentGroup, error) {
+	for _, name := range by {
+		if !slices.Contains(ExperimentDimensions, name) {
+			return nil, fmt.Errorf("unknown dimension %q (use %s)", name, strings.Join(ExperimentDimensions, ", "))
+		}
+	}
+
+	groups := map[string]*ExperimentGroup{}
+	costs := map[string][]float64{}
+	for _, r := range records {
+		values := make([]string, len(by))
+		for i, name := range by {
+			values[i] = r.dimension(name)
+		}
+		key := strings.Join(values, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			g = &ExperimentGroup{Values: values, MinCost: math.Inf(1)}
+			groups[key] = g
+		}
+		g.Runs++
+		g.MeanCost += r.BestCost
+		g.MinCost = min(g.MinCost, r.BestCost)
+		g.MeanGain += r.InitialCost - r.BestCost
+		g.MeanElapsed += time.Duration(r.ElapsedMs) * time.Millisecond
+		costs[key] = append(costs[key], r.BestCost)
+	}
+
+	result := make([]ExperimentGroup, 0, len(groups))
+	for key, g := range groups {
+		n := float64(g.Runs)
+		g.MeanCost /= n
+		g.MeanGain /= n
+		g.MeanElapsed /= time.Duration(g.Runs)
+		for _, c := range costs[key] {
+			g.StdDev += (c - g.MeanCost) * (c - g.MeanCost) / n
+		}
+		g.StdDev = math.Sqrt(g.StdDev)
+		result = append(result, *g)
+	}
+	slices.SortFunc(result, func(a, b ExperimentGroup) int {
+		for i := range a.Values {
+			if c := compareExperimentValues(a.Values[i], b.Values[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return result, nil
+}
+
+// compareExperimentValues compares dimension values, numerically if both are numbers.
+func compareExperimentValues(a, b string) int {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(na, nb)
+	}
+	return strings.Compare(a, b)
+}
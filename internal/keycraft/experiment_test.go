@@ -0,0 +1,212 @@
+package keycraft
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestNewExperimentFromFile checks parsing experiments written as plain key = value lines and
+// as TOML, the defaults of settings that are left out, and rejecting invalid settings.
+func TestNewExperimentFromFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	exp, err := NewExperimentFromFile(write("plain.txt", `
+# plain lists
+layout = qwerty
+corpora = default.txt, other.txt
+algorithms = bls, bls-compound
+seeds = 3, 1
+`))
+	if err != nil {
+		t.Fatalf("plain: %v", err)
+	}
+	if exp.Name != "plain" || exp.Layout != "qwerty" || !slices.Equal(exp.Corpora, []string{"default.txt", "other.txt"}) ||
+		!slices.Equal(exp.Weights, []string{"weights.txt"}) || !slices.Equal(exp.Seeds, []int64{3, 1}) ||
+		exp.Generations != 1000 || exp.MaxTime != 5 {
+		t.Errorf("plain: got %+v", exp)
+	}
+
+	exp, err = NewExperimentFromFile(write("toml.toml", `
+name = "compound"
+layout = "qwerty"
+corpora = ["default.txt"]
+weights = ["weights.txt", "weights2.txt"]
+seeds = [1, 2]
+generations = 50
+bls-params = "t=20"
+`))
+	if err != nil {
+		t.Fatalf("toml: %v", err)
+	}
+	if exp.Name != "compound" || !slices.Equal(exp.Weights, []string{"weights.txt", "weights2.txt"}) ||
+		exp.Generations != 50 || exp.BLSParams != "t=20" {
+		t.Errorf("toml: got %+v", exp)
+	}
+
+	for name, content := range map[string]string{
+		"no-layout":   "corpora = default.txt\n",
+		"no-corpora":  "layout = qwerty\n",
+		"algorithm":   "layout = qwerty\ncorpora = default.txt\nalgorithms = anneal\n",
+		"seed":        "layout = qwerty\ncorpora = default.txt\nseeds = 1, x\n",
+		"generations": "layout = qwerty\ncorpora = default.txt\ngenerations = 0\n",
+		"bls-params":  "layout = qwerty\ncorpora = default.txt\nbls-params = t=0\n",
+		"unknown":     "layout = qwerty\ncorpora = default.txt\ncolour = blue\n",
+		"no-value":    "layout qwerty\n",
+	} {
+		if _, err := NewExperimentFromFile(write(name+".txt", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestExperimentTrials checks that the trials cover every combination, nesting corpora,
+// weights, algorithms and seeds in that order.
+func TestExperimentTrials(t *testing.T) {
+	exp := &Experiment{
+		Corpora:    []string{"a", "b"},
+		Weights:    []string{"w"},
+		Algorithms: []string{"bls", "bls-compound"},
+		Seeds:      []int64{1, 2},
+	}
+	trials := exp.Trials()
+	if len(trials) != 8 {
+		t.Fatalf("got %d trials, want 8", len(trials))
+	}
+	if trials[0] != (ExperimentTrial{"a", "w", "bls", 1}) || trials[1] != (ExperimentTrial{"a", "w", "bls", 2}) ||
+		trials[2] != (ExperimentTrial{"a", "w", "bls-compound", 1}) || trials[7] != (ExperimentTrial{"b", "w", "bls-compound", 2}) {
+		t.Errorf("unexpected trial order: %v", trials)
+	}
+}
+
+// TestExperimentRecords checks that records survive a round trip through JSONL, and that
+// their key distinguishes trials and settings.
+func TestExperimentRecords(t *testing.T) {
+	records := []ExperimentRecord{
+		{Experiment: "e", Layout: "qwerty", ExperimentTrial: ExperimentTrial{"a", "w", "bls", 1}, Generations: 10,
+			BestCost: 1.5, Metrics: map[string]float64{"SFB": 1}},
+		{Experiment: "e", Layout: "qwerty", ExperimentTrial: ExperimentTrial{"a", "w", "bls", 1}, Generations: 20},
+	}
+	var buf bytes.Buffer
+	for _, r := range records {
+		Must0(WriteExperimentRecord(&buf, r))
+	}
+	buf.WriteString("\n")
+
+	got, err := ReadExperimentRecords(&buf)
+	if err != nil {
+		t.Fatalf("ReadExperimentRecords: %v", err)
+	}
+	if len(got) != 2 || got[0].Key() != records[0].Key() || got[0].BestCost != 1.5 || got[0].Metrics["SFB"] != 1 {
+		t.Errorf("round trip: got %+v", got)
+	}
+	if records[0].Key() == records[1].Key() {
+		t.Error("records with different generations have the same key")
+	}
+
+	if _, err := ReadExperimentRecords(bytes.NewBufferString("{not json}\n")); err == nil {
+		t.Error("expected an error for an invalid record")
+	}
+	if got, err := LoadExperimentRecords(filepath.Join(t.TempDir(), "missing.jsonl")); err != nil || got != nil {
+		t.Errorf("missing results: got %v, %v, want no records", got, err)
+	}
+}
+
+// TestAggregateExperiment checks the statistics of groups, and sorting numeric dimensions by
+// value.
+func TestAggregateExperiment(t *testing.T) {
+	record := func(algorithm string, seed int64, initial, best float64, ms int64) ExperimentRecord {
+		return ExperimentRecord{ExperimentTrial: ExperimentTrial{"a", "w", algorithm, seed},
+			InitialCost: initial, BestCost: best, ElapsedMs: ms}
+	}
+	records := []ExperimentRecord{
+		record("bls", 10, 5, 1, 100),
+		record("bls", 2, 5, 3, 300),
+		record("bls-compound", 2, 5, 0, 200),
+	}
+
+	groups, err := AggregateExperiment(records, []string{"algorithm"})
+	if err != nil {
+		t.Fatalf("AggregateExperiment: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Values[0] != "bls" {
+		t.Fatalf("got groups %+v", groups)
+	}
+	g := groups[0]
+	if g.Runs != 2 || g.MeanCost != 2 || g.StdDev != 1 || g.MinCost != 1 || g.MeanGain != 3 ||
+		g.MeanElapsed.Milliseconds() != 200 {
+		t.Errorf("bls group: got %+v", g)
+	}
+
+	groups = Must(AggregateExperiment(records, []string{"seed"}))
+	if len(groups) != 2 || groups[0].Values[0] != "2" || groups[1].Values[0] != "10" {
+		t.Errorf("seeds not sorted numerically: %+v", groups)
+	}
+
+	if _, err := AggregateExperiment(records, []string{"colour"}); err == nil {
+		t.Error("expected an error for an unknown dimension")
+	}
+}
+
+// TestRunExperiment checks that every job is run and saved with its costs and best layout,
+// and that a cancelled experiment saves nothing.
+func TestRunExperiment(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	pinned := &PinnedKeys{}
+	for i, r := range layout.Runes {
+		pinned[i] = r == 0 || r == ' '
+	}
+	weights := NewWeights()
+
+	var jobs []ExperimentJob
+	for _, algorithm := range []string{"bls", "bls-compound"} {
+		trial := ExperimentTrial{"test", "w", algorithm, 1}
+		jobs = append(jobs, ExperimentJob{
+			Record: ExperimentRecord{Experiment: "e", Layout: "a", ExperimentTrial: trial},
+			Input: OptimizeInput{Layout: layout, LayoutsDir: dir, Corpus: corpus, Weights: weights, Pinned: pinned,
+				NumGenerations: 3, Seed: 1, CompoundMoves: ExperimentAlgorithms[algorithm]},
+		})
+	}
+
+	var saved []ExperimentRecord
+	err := RunExperiment(context.Background(), jobs, 2, func(r ExperimentRecord) error {
+		saved = append(saved, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunExperiment: %v", err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("saved %d records, want 2", len(saved))
+	}
+	for _, r := range saved {
+		if r.BestCost > r.InitialCost || len(r.BestLayout) == 0 || len(r.Metrics) == 0 || r.Started.IsZero() {
+			t.Errorf("%s: incomplete record %+v", r.Algorithm, r)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	saved = nil
+	err = RunExperiment(ctx, jobs, 1, func(r ExperimentRecord) error {
+		saved = append(saved, r)
+		return nil
+	})
+	if err == nil || len(saved) != 0 {
+		t.Errorf("cancelled experiment: got error %v and %d records, want an error and none", err, len(saved))
+	}
+}
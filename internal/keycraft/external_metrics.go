@@ -0,0 +1,119 @@
+package keycraft
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ExternalMetrics holds metric columns imported from a CSV or TSV file, such as
+// community-collected data ("learned-in-days", "subjective comfort"). Once registered,
+// the columns behave like computed metrics: they can be displayed, weighted, and are
+// normalised with the median and IQR of the reference layouts that have a value.
+type ExternalMetrics struct {
+	Columns []string                      // metric names in file order (upper case)
+	Values  map[string]map[string]float64 // layout name -> metric -> value
+}
+
+// externalMetrics is the set of registered external metric names.
+var externalMetrics = map[string]bool{}
+
+// IsExternalMetric reports whether the metric was registered from an external file.
+func IsExternalMetric(metric string) bool {
+	return externalMetrics[metric]
+}
+
+// NewExternalMetricsFromFile reads external metrics from a file with a header row. The
+// first column holds layout names (with or without .klf), the other columns hold
+// numbers. Files ending in .tsv are tab-separated, all others comma-separated. Empty
+// cells are missing values, and lines starting with '#' are ignored.
+func NewExternalMetricsFromFile(path string) (*ExternalMetrics, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open metrics file: %w", err)
+	}
+	defer CloseFile(file)
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	if strings.ToLower(filepath.Ext(path)) == ".tsv" {
+		reader.Comma = '\t'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read metrics file %s: %w", path, err)
+	}
+	if len(records) == 0 || len(records[0]) < 2 {
+		return nil, fmt.Errorf("metrics file %s needs a header with a layout column and at least 1 metric", path)
+	}
+
+	builtin := MetricsMap["all"]
+	em := &ExternalMetrics{Values: make(map[string]map[string]float64, len(records)-1)}
+	for _, col := range records[0][1:] {
+		metric := strings.ToUpper(strings.TrimSpace(col))
+		switch {
+		case metric == "":
+			return nil, fmt.Errorf("metrics file %s has an empty column name", path)
+		case strings.ContainsAny(metric, ",="):
+			return nil, fmt.Errorf("metric name %q may not contain ',' or '='", metric)
+		case slices.Contains(em.Columns, metric):
+			return nil, fmt.Errorf("metric %q appears more than once in %s", metric, path)
+		case slices.Contains(builtin, metric) && !IsExternalMetric(metric):
+			return nil, fmt.Errorf("metric %q in %s conflicts with a computed metric", metric, path)
+		}
+		em.Columns = append(em.Columns, metric)
+	}
+
+	for line, record := range records[1:] {
+		name := strings.TrimSuffix(strings.TrimSpace(record[0]), ".klf")
+		if name == "" {
+			return nil, fmt.Errorf("row %d of %s has no layout name", line+2, path)
+		}
+		values := make(map[string]float64, len(em.Columns))
+		for i, cell := range record[1:] {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q for layout %s", em.Columns[i], cell, name)
+			}
+			values[em.Columns[i]] = v
+		}
+		em.Values[name] = values
+	}
+
+	return em, nil
+}
+
+// Register adds the columns to the "all" metric set, so they are accepted by weights
+// and metric lists. It must be called before weights are parsed.
+func (em *ExternalMetrics) Register() {
+	for _, metric := range em.Columns {
+		if !externalMetrics[metric] {
+			externalMetrics[metric] = true
+			MetricsMap["all"] = append(MetricsMap["all"], metric)
+		}
+	}
+}
+
+// Apply merges the values into the metrics of the analysers of matching layouts.
+// Layouts without a value for a metric are left without it, so they don't count
+// towards its median and IQR, and contribute nothing to their score for it.
+func (em *ExternalMetrics) Apply(analysers []*Analyser) {
+	if em == nil {
+		return
+	}
+	for _, analyser := range analysers {
+		for metric, v := range em.Values[analyser.Layout.Name] {
+			analyser.Metrics[metric] = v
+		}
+	}
+}
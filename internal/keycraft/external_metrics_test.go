@@ -0,0 +1,129 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeMetricsFile writes an external metrics file to a temporary directory.
+func writeMetricsFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// registerForTest registers external metrics and restores the metric sets afterwards.
+func registerForTest(t *testing.T, em *ExternalMetrics) {
+	t.Helper()
+	all := slices.Clone(MetricsMap["all"])
+	em.Register()
+	t.Cleanup(func() {
+		MetricsMap["all"] = all
+		for _, metric := range em.Columns {
+			delete(externalMetrics, metric)
+		}
+	})
+}
+
+func TestNewExternalMetricsFromFile(t *testing.T) {
+	path := writeMetricsFile(t, "community.csv",
+		"# collected in the 2026 survey\nlayout,learned-in-days, Subjective Comfort\na.klf,14,7.5\nc,,9\n")
+	em := Must(NewExternalMetricsFromFile(path))
+
+	if want := []string{"LEARNED-IN-DAYS", "SUBJECTIVE COMFORT"}; !slices.Equal(em.Columns, want) {
+		t.Errorf("columns = %v, want %v", em.Columns, want)
+	}
+	if got := em.Values["a"]["LEARNED-IN-DAYS"]; got != 14 {
+		t.Errorf("a learned-in-days = %v, want 14", got)
+	}
+	if _, ok := em.Values["c"]["LEARNED-IN-DAYS"]; ok {
+		t.Error("empty cell should be a missing value")
+	}
+	if got := em.Values["c"]["SUBJECTIVE COMFORT"]; got != 9 {
+		t.Errorf("c comfort = %v, want 9", got)
+	}
+}
+
+func TestNewExternalMetricsFromFile_TSV(t *testing.T) {
+	path := writeMetricsFile(t, "community.tsv", "name\tcomfort\na\t1,5\n")
+	if _, err := NewExternalMetricsFromFile(path); err == nil {
+		t.Error("expected error for a comma in a tab-separated number")
+	}
+
+	path = writeMetricsFile(t, "community.tsv", "name\tcomfort\na\t1.5\n")
+	em := Must(NewExternalMetricsFromFile(path))
+	if got := em.Values["a"]["COMFORT"]; got != 1.5 {
+		t.Errorf("comfort = %v, want 1.5", got)
+	}
+}
+
+func TestNewExternalMetricsFromFile_Errors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"no metrics", "layout\na\n", "at least 1 metric"},
+		{"computed metric", "layout,sfb\na,1\n", "conflicts"},
+		{"duplicate", "layout,x,X\na,1,2\n", "more than once"},
+		{"invalid name", "layout,a=b\na,1\n", "may not contain"},
+		{"not a number", "layout,x\na,fast\n", "invalid X value"},
+		{"ragged row", "layout,x\na,1,2\n", "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExternalMetricsFromFile(writeMetricsFile(t, "m.csv", tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalMetrics_Weighted(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"c.klf":   testLayoutVariantKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	em := Must(NewExternalMetricsFromFile(writeMetricsFile(t, "m.csv", "layout,comfort\na,1\nc,2\nfar,9\n")))
+	registerForTest(t, em)
+
+	if !IsExternalMetric("COMFORT") || !slices.Contains(MetricsMap["all"], "COMFORT") {
+		t.Fatal("COMFORT was not registered")
+	}
+	em.Register() // Registering again has no effect
+	if n := strings.Count(strings.Join(MetricsMap["all"], " "), "COMFORT"); n != 1 {
+		t.Errorf("COMFORT registered %d times", n)
+	}
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+	weights := Must(NewWeightsFromString("sfb=0,comfort=1"))
+	result := Must(ComputeRankings(RankingInput{
+		LayoutsDir:  dir,
+		LayoutFiles: []string{filepath.Join(dir, "a.klf"), filepath.Join(dir, "far.klf")},
+		Corpus:      corpus,
+		Targets:     NewTargetLoads(),
+		Weights:     weights,
+		External:    em,
+	}))
+
+	if got := result.Medians["COMFORT"]; got != 2 {
+		t.Errorf("median comfort = %v, want 2", got)
+	}
+	scores := map[string]float64{}
+	for _, ls := range result.Scores {
+		scores[ls.Name] = ls.Score
+		if want := em.Values[ls.Name]["COMFORT"]; ls.Analyser.Metrics["COMFORT"] != want {
+			t.Errorf("%s comfort = %v, want %v", ls.Name, ls.Analyser.Metrics["COMFORT"], want)
+		}
+	}
+	if scores["far"] <= scores["a"] {
+		t.Errorf("far (comfort 9) should outrank a (comfort 1): %v", scores)
+	}
+}
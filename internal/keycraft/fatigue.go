@@ -0,0 +1,129 @@
+package keycraft
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultFatigueWindow is the default number of most recent keystrokes considered by FATIGUE.
+	DefaultFatigueWindow = 10
+	// DefaultFatigueLimit is the default number of uses of a finger within the window above
+	// which a keystroke counts towards FATIGUE.
+	DefaultFatigueLimit = 3
+	// maxFatigueWindow is the largest window of FATIGUE.
+	maxFatigueWindow = 1000
+)
+
+// FatigueSettings are the window and limit of FATIGUE.
+type FatigueSettings struct {
+	Window int // Number of most recent keystrokes considered
+	Limit  int // Uses of a finger within the window above which a keystroke counts
+}
+
+// DefaultFatigue returns the default FATIGUE settings: more than 3 of the last 10 keystrokes.
+func DefaultFatigue() *FatigueSettings {
+	return &FatigueSettings{Window: DefaultFatigueWindow, Limit: DefaultFatigueLimit}
+}
+
+// SetFatigue parses and sets the FATIGUE settings from 2 comma-separated values: the window
+// and the limit. The window must be at least 2 keystrokes, and the limit at least 1 and
+// below the window.
+func (tl *TargetLoads) SetFatigue(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return fmt.Errorf("fatigue must have 2 comma-separated values: window, limit (got %d)", len(parts))
+	}
+	var vals [2]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 1 {
+			return fmt.Errorf("invalid fatigue value at position %d: %q must be a positive integer", i, strings.TrimSpace(p))
+		}
+		vals[i] = v
+	}
+	if vals[0] > maxFatigueWindow {
+		return fmt.Errorf("fatigue window must be at most %d keystrokes (got %d)", maxFatigueWindow, vals[0])
+	}
+	if vals[1] >= vals[0] {
+		return fmt.Errorf("fatigue limit must be below the window (got %d of %d)", vals[1], vals[0])
+	}
+	tl.Fatigue = &FatigueSettings{Window: vals[0], Limit: vals[1]}
+	return nil
+}
+
+// FatigueSettings returns the FATIGUE settings of the targets, or the defaults if not set.
+func (an *Analyser) FatigueSettings() FatigueSettings {
+	if an.Targets != nil && an.Targets.Fatigue != nil {
+		return *an.Targets.Fatigue
+	}
+	return *DefaultFatigue()
+}
+
+// analyseFatigue computes finger repetition pressure over time:
+//   - FATIGUE: % of keystrokes whose finger has pressed more than the limit of the last
+//     window keystrokes (including the keystroke itself), by the FatigueSettings
+//   - FingerFatigue: FATIGUE split by the finger typing the keystroke
+//
+// Unlike SFB and SFS, this also catches a finger that is used every other or every third
+// keystroke for a while. The keystrokes are taken from the corpus stream. Corpora without
+// a stream use each word of the word list as a separate stream, weighted by its count.
+// Characters that are not on the layout are skipped.
+func (an *Analyser) analyseFatigue() {
+	var counts [10]uint64
+	var total uint64
+
+	settings := an.FatigueSettings()
+	window := make([]uint8, settings.Window)
+	var uses [10]int
+	var n int // number of keystrokes in the window
+	reset := func() {
+		n = 0
+		uses = [10]int{}
+	}
+	press := func(r rune, weight uint64) {
+		ki, ok := an.Layout.GetKeyInfo(r)
+		if !ok {
+			return
+		}
+		slot := n % settings.Window
+		if n >= settings.Window {
+			uses[window[slot]]--
+		}
+		window[slot] = ki.Finger
+		uses[ki.Finger]++
+		n++
+
+		total += weight
+		if uses[ki.Finger] > settings.Limit {
+			counts[ki.Finger] += weight
+		}
+	}
+
+	if an.Corpus.Stream != "" {
+		for _, r := range an.Corpus.Stream {
+			press(r, 1)
+		}
+	} else {
+		for word, cnt := range an.Corpus.Words {
+			reset()
+			for _, r := range word {
+				press(r, cnt)
+			}
+		}
+	}
+
+	an.FingerFatigue = [10]float64{}
+	an.Metrics["FATIGUE"] = 0
+	if total == 0 {
+		return
+	}
+
+	var sum uint64
+	for f, cnt := range counts {
+		an.FingerFatigue[f] = 100 * float64(cnt) / float64(total)
+		sum += cnt
+	}
+	an.Metrics["FATIGUE"] = 100 * float64(sum) / float64(total)
+}
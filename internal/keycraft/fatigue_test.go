@@ -0,0 +1,127 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFatigueTestLayout returns a layout with 'e', 'd' and 'c' on the left middle finger
+// and 'a', 'j' and 'k' on other fingers.
+func newFatigueTestLayout() *SplitLayout {
+	var runes [42]rune
+	runes[3], runes[15], runes[27], runes[13], runes[19], runes[20] = 'e', 'd', 'c', 'a', 'j', 'k'
+	return NewSplitLayout("test", ORTHO, runes)
+}
+
+func TestAnalyseFatigue_Stream(t *testing.T) {
+	corpus := NewCorpus("test")
+	// The 4th and 5th uses of the left middle finger within 10 keystrokes are the 6th and
+	// 7th keystrokes. The newline is skipped, so the window spans lines.
+	corpus.Stream = "ejdkec\ndaaaa\n"
+	an := NewAnalyser(newFatigueTestLayout(), corpus, nil)
+
+	if got, want := an.Metrics["FATIGUE"], 100*3.0/11; got != want {
+		t.Errorf("FATIGUE = %v, want %v", got, want)
+	}
+	if got, want := an.FingerFatigue[LM], 100*2.0/11; got != want {
+		t.Errorf("left middle fatigue = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyseFatigue_Words(t *testing.T) {
+	corpus := NewCorpus("test")
+	// Without a stream, each word is a separate stream: only "edced" reaches the limit
+	corpus.Words = map[string]uint64{"edced": 3, "edc": 5}
+	an := NewAnalyser(newFatigueTestLayout(), corpus, nil)
+
+	// 3 x 2 of 3 x 5 + 5 x 3 keystrokes
+	if got, want := an.Metrics["FATIGUE"], 100*6.0/30; got != want {
+		t.Errorf("FATIGUE = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyseFatigue_Settings(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.Stream = "ejdkec\ndaaaa\n"
+	targets := NewTargetLoads()
+	Must0(targets.SetFatigue("4, 1"))
+	an := NewAnalyser(newFatigueTestLayout(), corpus, targets)
+
+	// A finger used twice within 4 keystrokes: the 3rd, 5th, 6th and 7th keystrokes of the
+	// left middle finger, and the last 3 'a's
+	if got, want := an.Metrics["FATIGUE"], 100*7.0/11; got != want {
+		t.Errorf("FATIGUE = %v, want %v", got, want)
+	}
+	if got, want := an.FingerFatigue[LM], 100*4.0/11; got != want {
+		t.Errorf("left middle fatigue = %v, want %v", got, want)
+	}
+	if got := an.FatigueSettings(); got != (FatigueSettings{Window: 4, Limit: 1}) {
+		t.Errorf("settings = %+v, want a window of 4 and a limit of 1", got)
+	}
+}
+
+func TestSetFatigue(t *testing.T) {
+	tl := &TargetLoads{}
+	if err := tl.SetFatigue(" 20, 5 "); err != nil {
+		t.Fatal(err)
+	}
+	if want := (FatigueSettings{Window: 20, Limit: 5}); *tl.Fatigue != want {
+		t.Errorf("fatigue = %+v, want %+v", *tl.Fatigue, want)
+	}
+
+	for _, s := range []string{"10", "10, 3, 1", "10, x", "10, 0", "0, 3", "3, 3", "2000, 3"} {
+		if err := tl.SetFatigue(s); err == nil {
+			t.Errorf("SetFatigue(%q): expected an error", s)
+		}
+	}
+}
+
+func TestNewTargetLoadsFromFile_Fatigue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	Must0(os.WriteFile(path, []byte("fatigue = 8, 2\n"), 0644))
+	targets := Must(NewTargetLoadsFromFile(path))
+	if want := (FatigueSettings{Window: 8, Limit: 2}); *targets.Fatigue != want {
+		t.Errorf("fatigue = %+v, want %+v", *targets.Fatigue, want)
+	}
+
+	Must0(os.WriteFile(path, []byte("# no fatigue\n"), 0644))
+	if targets := Must(NewTargetLoadsFromFile(path)); *targets.Fatigue != *DefaultFatigue() {
+		t.Errorf("fatigue = %+v, want the default", *targets.Fatigue)
+	}
+
+	Must0(os.WriteFile(path, []byte("fatigue = 8\n"), 0644))
+	if _, err := NewTargetLoadsFromFile(path); err == nil || !strings.Contains(err.Error(), "fatigue") {
+		t.Errorf("error = %v, want an invalid fatigue", err)
+	}
+}
+
+func TestLoadFromFileWithWords_Stream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte("Hello World\n\nSecond line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corpus := Must(NewCorpusFromFile("test", path, true, 100))
+	if want := "hello world\nsecond line\n"; corpus.Stream != want {
+		t.Errorf("stream = %q, want %q", corpus.Stream, want)
+	}
+
+	cached := Must(LoadJSON(path + ".json"))
+	if cached.Stream != corpus.Stream || cached.Version != CorpusCacheVersion {
+		t.Errorf("cached stream = %q of version %d, want %q of version %d", cached.Stream,
+			cached.Version, corpus.Stream, CorpusCacheVersion)
+	}
+
+	// A cache from before the stream was recorded is rebuilt, even if it is newer
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	cached.Stream, cached.Version = "", 0
+	Must0(cached.SaveJSON(path + ".json"))
+	if rebuilt := Must(NewCorpusFromFile("test", path, false, 100)); rebuilt.Stream != corpus.Stream {
+		t.Errorf("stream of an old cache = %q, want %q", rebuilt.Stream, corpus.Stream)
+	}
+}
@@ -0,0 +1,112 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxFingerLoadPenalty is added to FLD for every percentage point that a finger carries above
+// its maximum load, on top of its deviation from the target load, so that a layout that keeps
+// to the maximum loads scores better than one that is merely close to the targets.
+const maxFingerLoadPenalty = 5.0
+
+// fingerLabels names the fingers F0-F9 in warnings.
+var fingerLabels = [10]string{"LP", "LR", "LM", "LI", "LT", "RT", "RI", "RM", "RR", "RP"}
+
+// SetMaxFingerLoad parses and sets the maximum finger loads from a string, such as those of an
+// injured or weaker finger. Accepts 4 values (mirrored for both hands) or 8 values (F0-F3,
+// F6-F9), in % of the keystrokes on the main rows; "-" leaves a finger without a maximum.
+// Values are NOT scaled, and must leave room for all keystrokes: a sum of at least 100%.
+func (tl *TargetLoads) SetMaxFingerLoad(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 && len(parts) != 8 {
+		return fmt.Errorf("max-finger-load must have 4 or 8 comma-separated values (got %d)", len(parts))
+	}
+	if len(parts) == 4 {
+		parts = append(parts, parts[3], parts[2], parts[1], parts[0])
+	}
+
+	caps := [10]float64{LT: 100, RT: 100}
+	var sum float64
+	for i, p := range parts {
+		finger := i
+		if i >= 4 {
+			finger += 2 // Skip the thumbs
+		}
+		p = strings.TrimSpace(p)
+		v := 100.0
+		if p != "-" {
+			var err error
+			if v, err = strconv.ParseFloat(p, 64); err != nil || v < 0 || v > 100 {
+				return fmt.Errorf("invalid max-finger-load for %s: %q must be between 0 and 100, or -", fingerLabels[finger], p)
+			}
+		}
+		caps[finger] = v
+		sum += v
+	}
+	if sum < 100 {
+		return fmt.Errorf("max-finger-load values add up to %.1f%%, leaving no room for all keystrokes", sum)
+	}
+	tl.MaxFingerLoad = &caps
+	return nil
+}
+
+// FingerLoadWarnings reports why the layout can't meet the target or maximum finger loads,
+// given the frequencies of its characters in the corpus: a finger whose keys can't reach its
+// target even with the most frequent characters, a finger whose keys exceed its maximum even
+// with the least frequent characters, and a finger that is above its maximum on this layout.
+// Targets that conflict regardless of the layout are reported by TargetLoads.Problems.
+// Returns nil if the targets are plausible.
+func (an *Analyser) FingerLoadWarnings() []string {
+	// Frequencies of the characters on the main rows, and the keys of every finger
+	var counts []uint64
+	var keys [10]int
+	var total uint64
+	for idx, r := range an.Layout.Runes[:36] {
+		if r == 0 {
+			continue
+		}
+		keys[NewKeyInfo(uint8(idx/12), uint8(idx%12), an.Layout.LayoutType).Finger]++
+		counts = append(counts, an.Corpus.Unigrams[Unigram(r)])
+		total += an.Corpus.Unigrams[Unigram(r)]
+	}
+	if total == 0 {
+		return nil
+	}
+	slices.Sort(counts)
+	pct := func(cnts []uint64) float64 {
+		var sum uint64
+		for _, c := range cnts {
+			sum += c
+		}
+		return 100 * float64(sum) / float64(total)
+	}
+
+	const tolerance = 0.05 // Percentage points
+	var warnings []string
+	caps := an.Targets.MaxFingerLoad
+	for f, label := range fingerLabels {
+		if f == int(LT) || f == int(RT) {
+			continue
+		}
+		target := an.Targets.TargetFingerLoad[f]
+		most := pct(counts[len(counts)-keys[f]:])
+		if target > most+tolerance {
+			warnings = append(warnings, fmt.Sprintf("%s has a target load of %.1f%%, but its %d keys carry at most %.1f%%",
+				label, target, keys[f], most))
+		}
+		if caps == nil || caps[f] >= 100 {
+			continue
+		}
+		if least := pct(counts[:keys[f]]); least > caps[f]+tolerance {
+			warnings = append(warnings, fmt.Sprintf("%s has a maximum load of %.1f%%, but its %d keys carry at least %.1f%%; leave some of them empty",
+				label, caps[f], keys[f], least))
+		} else if load := an.Metrics["F"+strconv.Itoa(f)]; load > caps[f]+tolerance {
+			warnings = append(warnings, fmt.Sprintf("%s carries %.1f%% on layout %s, above its maximum load of %.1f%%",
+				label, load, an.Layout.Name, caps[f]))
+		}
+	}
+	return warnings
+}
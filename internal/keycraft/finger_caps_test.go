@@ -0,0 +1,108 @@
+package keycraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetMaxFingerLoad(t *testing.T) {
+	targets := NewTargetLoads()
+	if err := targets.SetMaxFingerLoad("-, 5, -, -, -, -, -, -"); err != nil {
+		t.Fatal(err)
+	}
+	want := [10]float64{100, 5, 100, 100, 100, 100, 100, 100, 100, 100}
+	if *targets.MaxFingerLoad != want {
+		t.Errorf("MaxFingerLoad = %v, want %v", *targets.MaxFingerLoad, want)
+	}
+
+	// Four values are mirrored
+	if err := targets.SetMaxFingerLoad("8, 12, -, -"); err != nil {
+		t.Fatal(err)
+	}
+	if targets.MaxFingerLoad[LP] != 8 || targets.MaxFingerLoad[RP] != 8 || targets.MaxFingerLoad[RR] != 12 {
+		t.Errorf("MaxFingerLoad = %v, want mirrored caps", *targets.MaxFingerLoad)
+	}
+
+	for _, spec := range []string{"1,2,3", "a,-,-,-", "-,101,-,-", "-,-1,-,-", "10,10,10,10,10,10,10,10"} {
+		if err := targets.SetMaxFingerLoad(spec); err == nil {
+			t.Errorf("SetMaxFingerLoad(%q) should fail", spec)
+		}
+	}
+}
+
+func TestNewTargetLoadsFromFile_MaxFingerLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	Must0(os.WriteFile(path, []byte("max-finger-load = -, 5, -, -, -, -, -, -\n"), 0644))
+	targets := Must(NewTargetLoadsFromFile(path))
+	if targets.MaxFingerLoad == nil || targets.MaxFingerLoad[LR] != 5 {
+		t.Errorf("MaxFingerLoad = %v, want LR capped at 5", targets.MaxFingerLoad)
+	}
+
+	Must0(os.WriteFile(path, []byte("max-finger-load = 5, 5, 5, 5\n"), 0644))
+	if _, err := NewTargetLoadsFromFile(path); err == nil {
+		t.Error("caps adding up to less than 100% should fail")
+	}
+}
+
+func TestAnalyser_MaxFingerLoad(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("was this so swell; we saw sixty swans sail west")
+	layout := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+
+	plain := NewAnalyser(layout, corpus, nil)
+	if warnings := plain.FingerLoadWarnings(); len(warnings) != 0 {
+		t.Errorf("default targets should not warn, got %v", warnings)
+	}
+
+	targets := NewTargetLoads()
+	targets.TargetFingerLoad = &[10]float64{7, 4, 16, 17, 0, 0, 17, 16, 10, 7}
+	Must0(targets.SetMaxFingerLoad("-, 5, -, -, -, -, -, -"))
+	capped := NewAnalyser(layout, corpus, targets)
+
+	// FLD adds the penalised excess load of the left ring finger
+	load := capped.Metrics["F1"]
+	if load <= 5 {
+		t.Fatalf("test corpus should load LR above 5%%, got %.1f%%", load)
+	}
+	uncapped := NewAnalyser(layout, corpus, &TargetLoads{TargetFingerLoad: targets.TargetFingerLoad})
+	want := uncapped.Metrics["FLD"] + maxFingerLoadPenalty*(load-5)
+	if got := capped.Metrics["FLD"]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("FLD = %v, want %v", got, want)
+	}
+
+	warnings := capped.FingerLoadWarnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "LR carries") {
+		t.Errorf("warnings = %v, want LR above its maximum", warnings)
+	}
+
+	// A cap below the load of the least frequent characters can't be met: with only five
+	// characters on the layout, LR always types three of them
+	var runes [42]rune
+	runes[2], runes[14], runes[26], runes[3], runes[6] = 'w', 's', 'x', 'e', 't'
+	Must0(targets.SetMaxFingerLoad("-, 0.5, -, -, -, -, -, -"))
+	warnings = NewAnalyser(NewSplitLayout("five", ROWSTAG, runes), corpus, targets).FingerLoadWarnings()
+	if !containsSubstring(warnings, "LR has a maximum load of 0.5%, but its 3 keys carry at least") {
+		t.Errorf("warnings = %v, want an unreachable maximum", warnings)
+	}
+
+	// A target beyond the most frequent characters of a finger can't be met
+	targets = NewTargetLoads()
+	targets.TargetFingerLoad = &[10]float64{1, 1, 1, 1, 0, 0, 1, 1, 93, 1}
+	warnings = NewAnalyser(layout, corpus, targets).FingerLoadWarnings()
+	if !containsSubstring(warnings, "RR has a target load of 93.0%, but its 3 keys carry at most") {
+		t.Errorf("warnings = %v, want an unreachable target", warnings)
+	}
+}
+
+// containsSubstring reports whether any of the strings contains substr.
+func containsSubstring(ss []string, substr string) bool {
+	for _, s := range ss {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseFocusKey parses the character of analyse --focus-key: a single character, or "space".
+func ParseFocusKey(s string) (rune, error) {
+	if strings.EqualFold(s, "space") {
+		return ' ', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError {
+		return 0, fmt.Errorf("focus key %q must be a single character or \"space\"", s)
+	}
+	return r, nil
+}
+
+// Focused returns a copy of the details with only the n-grams that contain r, and the totals
+// of those n-grams.
+func (ma *MetricDetails) Focused(r rune) *MetricDetails {
+	focused := &MetricDetails{
+		Corpus:       ma.Corpus,
+		CorpusNGramC: ma.CorpusNGramC,
+		Metric:       ma.Metric,
+		NGramCount:   make(map[string]uint64),
+		NGramDist:    make(map[string]float64),
+		Custom:       make(map[string]map[string]any),
+	}
+	for ngram, count := range ma.NGramCount {
+		if !strings.ContainsRune(ngram, r) {
+			continue
+		}
+		focused.NGramCount[ngram] = count
+		focused.NGramDist[ngram] = ma.NGramDist[ngram]
+		if fields, ok := ma.Custom[ngram]; ok {
+			focused.Custom[ngram] = fields
+		}
+		focused.TotalNGrams += count
+		focused.TotalDist += ma.NGramDist[ngram] * float64(count)
+	}
+	if ma.Unsupported != nil {
+		focused.Unsupported = make(map[string]uint64)
+		for ngram, count := range ma.Unsupported {
+			if strings.ContainsRune(ngram, r) {
+				focused.Unsupported[ngram] = count
+			}
+		}
+	}
+	return focused
+}
+
+// KeyRole summarises the role of a key in the metrics of a layout, for analyse --focus-key.
+type KeyRole struct {
+	Key     rune            // Character of the key
+	OnKey   bool            // Whether the layout has the character
+	KeyInfo KeyInfo         // Position and finger of the key (if OnKey)
+	Load    float64         // Share of the corpus characters typed with the key, in %
+	Metrics []KeyRoleMetric // Metrics the key takes part in, in the order of AllMetricsDetails
+}
+
+// KeyRoleMetric is the part of a key in a metric.
+type KeyRoleMetric struct {
+	Metric   string              // Metric name, such as "SFB" or "2RL"
+	Count    uint64              // Number of n-grams of the metric with the key
+	Percent  float64             // N-grams of the metric with the key, in % of the corpus n-grams
+	Share    float64             // Share of the metric that has the key, in %
+	Partners []CountPair[rune]   // Other characters of the bigrams and skipgrams with the key, most frequent first
+	Dirs     []CountPair[string] // Categories of the trigrams with the key, such as "IN" and "OUT", most frequent first
+}
+
+// maxKeyRolePartners is the number of partners listed per metric.
+const maxKeyRolePartners = 5
+
+// KeyRole returns the role of the key with character r: its load, and for each metric the
+// n-grams with the key, the characters it forms bigrams and skipgrams with (such as its SFB
+// and scissor partners), and the categories of its trigrams (such as its roll directions).
+func (an *Analyser) KeyRole(r rune) *KeyRole {
+	role := &KeyRole{Key: r}
+	role.KeyInfo, role.OnKey = an.Layout.GetKeyInfo(r)
+	if an.Corpus.TotalUnigramsCount > 0 {
+		role.Load = 100 * float64(an.Corpus.Unigrams[Unigram(r)]) / float64(an.Corpus.TotalUnigramsCount)
+	}
+
+	for _, ma := range an.AllMetricsDetails() {
+		focused := ma.Focused(r)
+		if focused.TotalNGrams == 0 {
+			continue
+		}
+		metric := KeyRoleMetric{Metric: ma.Metric, Count: focused.TotalNGrams}
+		if ma.CorpusNGramC > 0 {
+			metric.Percent = 100 * float64(focused.TotalNGrams) / float64(ma.CorpusNGramC)
+		}
+		if ma.TotalNGrams > 0 {
+			metric.Share = 100 * float64(focused.TotalNGrams) / float64(ma.TotalNGrams)
+		}
+
+		partners := make(map[rune]uint64)
+		dirs := make(map[string]uint64)
+		for ngram, count := range focused.NGramCount {
+			runes := []rune(ngram)
+			if len(runes) == 2 {
+				partners[bigramPartner(runes, r)] += count
+			} else if dir, ok := focused.Custom[ngram]["Dir"]; ok {
+				dirs[fmt.Sprint(dir)] += count
+			}
+		}
+		metric.Partners = SortedMap(partners)
+		if len(metric.Partners) > maxKeyRolePartners {
+			metric.Partners = metric.Partners[:maxKeyRolePartners]
+		}
+		metric.Dirs = SortedMap(dirs)
+		role.Metrics = append(role.Metrics, metric)
+	}
+	return role
+}
+
+// bigramPartner returns the other character of a bigram with r, or r for a bigram of r twice.
+func bigramPartner(bigram []rune, r rune) rune {
+	if i := slices.Index(bigram, r); i >= 0 {
+		return bigram[1-i]
+	}
+	return r
+}
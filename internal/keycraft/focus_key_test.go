@@ -0,0 +1,59 @@
+package keycraft
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFocusKey(t *testing.T) {
+	for s, want := range map[string]rune{"e": 'e', ";": ';', "space": ' ', "é": 'é'} {
+		if got, err := ParseFocusKey(s); err != nil || got != want {
+			t.Errorf("ParseFocusKey(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "ab"} {
+		if _, err := ParseFocusKey(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestAnalyser_KeyRole(t *testing.T) {
+	sl := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("decide ceded edged")
+	an := NewAnalyser(sl, corpus, nil)
+
+	sfb := an.SFBiDetails()
+	focused := sfb.Focused('c')
+	for ngram := range focused.NGramCount {
+		if !strings.ContainsRune(ngram, 'c') {
+			t.Errorf("focused SFB %q has no c", ngram)
+		}
+	}
+	if focused.TotalNGrams == 0 || focused.TotalNGrams >= sfb.TotalNGrams {
+		t.Errorf("focused SFB total = %d, want more than 0 and less than %d", focused.TotalNGrams, sfb.TotalNGrams)
+	}
+
+	role := an.KeyRole('e')
+	if !role.OnKey || role.KeyInfo.Index != 3 {
+		t.Errorf("key e = %+v, want on key r0c3", role.KeyInfo)
+	}
+	var sfbRole *KeyRoleMetric
+	for i := range role.Metrics {
+		if role.Metrics[i].Metric == "SFB" {
+			sfbRole = &role.Metrics[i]
+		}
+	}
+	// e, d and c are all typed with the left middle finger; d is the most frequent partner
+	if sfbRole == nil || len(sfbRole.Partners) != 2 || sfbRole.Partners[0].Key != 'd' || sfbRole.Partners[1].Key != 'c' {
+		t.Fatalf("SFB role of e = %+v, want partners d and c", sfbRole)
+	}
+	if sfbRole.Share <= 0 || sfbRole.Share > 100 {
+		t.Errorf("SFB share of e = %g, want 0-100", sfbRole.Share)
+	}
+
+	if role := an.KeyRole('é'); role.OnKey || len(role.Metrics) != 0 {
+		t.Errorf("role of é = %+v, want not on the layout", role)
+	}
+}
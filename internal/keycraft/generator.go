@@ -11,11 +11,15 @@ import (
 
 // GenerateInput captures CLI inputs for generation.
 type GenerateInput struct {
-	ConfigPath      string // resolved .gen file path
-	MaxLayouts      int    // from --max-layouts flag (default 5000, 0=all)
-	Seed            uint64 // from --seed flag (0=timestamp)
-	Optimize        bool   // from --optimize flag
-	KeepUnoptimized bool   // from --keep-unoptimized flag
+	ConfigPath      string       // resolved .gen file path ("" = the generation config of the Board)
+	Board           *BoardPreset // from --board flag (nil = the layout type and keys of the .gen file)
+	MaxLayouts      int          // from --max-layouts flag (default 5000, 0=all)
+	Seed            int64        // from --seed flag
+	Optimize        bool         // from --optimize flag
+	KeepUnoptimized bool         // from --keep-unoptimized flag
+	NamePrefix      string       // from --name flag, prefixed to the generated names
+	Force           bool         // from --force flag, to overwrite existing layout files
+	DryRun          bool         // from --dry-run flag, to generate the layouts without saving them
 }
 
 // PositionType defines what kind of allocation should happen at a position.
@@ -43,6 +47,8 @@ type GenerationConfig struct {
 	Groups     map[int][]rune   // Group number -> character set
 	FilePath   string           // Original file path for error messages
 	LineNums   map[string]int   // Line numbers for error messages (key -> line number)
+	Board      string           // Board preset the layouts are generated for ("" = none), saved in the layouts
+	NumberRow  *[12]rune        // Number row of the layouts (nil = none), see SetNumberRow
 }
 
 // GenerationResult holds the results of a generation run.
@@ -86,7 +92,7 @@ func ParseConfigFile(path string) (*GenerationConfig, error) {
 
 		// First non-empty, non-comment line is layout type
 		if config.LayoutType == 0 && !inTemplate && len(templateLines) == 0 {
-			lt, ok := parseLayoutType(trimmed)
+			lt, ok := ParseLayoutType(trimmed)
 			if !ok {
 				return nil, fmt.Errorf("line %d: invalid layout type %q (must be rowstag, anglemod, ortho, or colstag)", lineNum, trimmed)
 			}
@@ -178,7 +184,7 @@ func ParseConfigString(content string) (*GenerationConfig, error) {
 
 		// First non-empty, non-comment line is layout type
 		if config.LayoutType == 0 && !inTemplate && len(templateLines) == 0 {
-			lt, ok := parseLayoutType(trimmed)
+			lt, ok := ParseLayoutType(trimmed)
 			if !ok {
 				return nil, fmt.Errorf("line %d: invalid layout type %q (must be rowstag, anglemod, ortho, or colstag)", lineNum, trimmed)
 			}
@@ -243,8 +249,8 @@ func ParseConfigString(content string) (*GenerationConfig, error) {
 	return config, nil
 }
 
-// parseLayoutType converts a string to a LayoutType.
-func parseLayoutType(s string) (LayoutType, bool) {
+// ParseLayoutType converts a layout type name, such as "colstag", to a LayoutType.
+func ParseLayoutType(s string) (LayoutType, bool) {
 	switch strings.ToLower(s) {
 	case "rowstag":
 		return ROWSTAG, true
@@ -743,7 +749,13 @@ func GenerateLayout(config *GenerationConfig, groupPerm map[int][]rune, seed uin
 	// Generate layout name with permutation index
 	name := generateLayoutNameFromRunes(runes, permIndex)
 
-	return NewSplitLayout(name, config.LayoutType, runes)
+	sl := NewSplitLayout(name, config.LayoutType, runes)
+	sl.Board = config.Board
+	if config.NumberRow != nil {
+		// Number row characters are not in the charset, so they are never on the main keys
+		_ = sl.SetNumberRow(*config.NumberRow)
+	}
+	return sl
 }
 
 // generateLayoutNameFromRunes creates a layout name from runes and permutation index.
@@ -788,15 +800,27 @@ func GenerateFromConfig(config *GenerationConfig, input GenerateInput, layoutsDi
 
 	// Generate each layout
 	for i, perm := range perms {
-		layout := GenerateLayout(config, perm, input.Seed, i)
+		layout := GenerateLayout(config, perm, uint64(input.Seed), i)
+		layout.Name = input.NamePrefix + layout.Name
 		result.Layouts = append(result.Layouts, layout)
+		result.LayoutPaths = append(result.LayoutPaths, filepath.Join(layoutsDir, layout.Name+".klf"))
+	}
 
-		// Save layout
-		layoutPath := filepath.Join(layoutsDir, layout.Name+".klf")
-		if err := layout.SaveToFile(layoutPath); err != nil {
+	// Refuse to overwrite before saving any layout, so that no run is saved halfway
+	if !input.Force {
+		for _, path := range result.LayoutPaths {
+			if _, err := os.Stat(path); err == nil {
+				return nil, fmt.Errorf("layout file %s already exists (use --force to overwrite)", path)
+			}
+		}
+	}
+	for i, layout := range result.Layouts {
+		if input.DryRun {
+			break
+		}
+		if err := layout.SaveToFile(result.LayoutPaths[i]); err != nil {
 			return nil, fmt.Errorf("failed to save layout %s: %w", layout.Name, err)
 		}
-		result.LayoutPaths = append(result.LayoutPaths, layoutPath)
 	}
 
 	result.Generated = len(result.Layouts)
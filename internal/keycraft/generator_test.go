@@ -1,6 +1,7 @@
 package keycraft
 
 import (
+	"os"
 	"strings"
 	"testing"
 )
@@ -239,12 +240,12 @@ func TestParseLayoutType(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		lt, ok := parseLayoutType(tt.input)
+		lt, ok := ParseLayoutType(tt.input)
 		if ok != tt.ok {
-			t.Errorf("parseLayoutType(%q): expected ok=%v, got ok=%v", tt.input, tt.ok, ok)
+			t.Errorf("ParseLayoutType(%q): expected ok=%v, got ok=%v", tt.input, tt.ok, ok)
 		}
 		if ok && lt != tt.expected {
-			t.Errorf("parseLayoutType(%q): expected %v, got %v", tt.input, tt.expected, lt)
+			t.Errorf("ParseLayoutType(%q): expected %v, got %v", tt.input, tt.expected, lt)
 		}
 	}
 }
@@ -862,6 +863,52 @@ func TestGenerateLayoutNameFromRunes_HexSuffix(t *testing.T) {
 	}
 }
 
+// ============================================================================
+// Generate From Config Tests
+// ============================================================================
+
+func TestGenerateFromConfig_RefusesOverwrite(t *testing.T) {
+	config, err := ParseConfigString(testConfigWithGroups)
+	if err != nil {
+		t.Fatalf("ParseConfigString failed: %v", err)
+	}
+	dir := t.TempDir()
+	input := GenerateInput{MaxLayouts: 2, Seed: 42, NamePrefix: "mine", DryRun: true}
+
+	result, err := GenerateFromConfig(config, input, dir)
+	if err != nil {
+		t.Fatalf("GenerateFromConfig with DryRun failed: %v", err)
+	}
+	if len(result.Layouts) != 2 || len(result.LayoutPaths) != 2 {
+		t.Fatalf("got %d layouts and %d paths, want 2", len(result.Layouts), len(result.LayoutPaths))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected DryRun not to save layouts, found %d files", len(entries))
+	}
+
+	input.DryRun = false
+	result, err = GenerateFromConfig(config, input, dir)
+	if err != nil {
+		t.Fatalf("GenerateFromConfig failed: %v", err)
+	}
+	for i, layout := range result.Layouts {
+		if !strings.HasPrefix(layout.Name, "mine_") {
+			t.Errorf("expected name with prefix mine_, got %s", layout.Name)
+		}
+		if _, err := os.Stat(result.LayoutPaths[i]); err != nil {
+			t.Errorf("expected layout file %s: %v", result.LayoutPaths[i], err)
+		}
+	}
+
+	if _, err := GenerateFromConfig(config, input, dir); err == nil {
+		t.Error("expected error when the generated layouts exist")
+	}
+	input.Force = true
+	if _, err := GenerateFromConfig(config, input, dir); err != nil {
+		t.Errorf("GenerateFromConfig with Force failed: %v", err)
+	}
+}
+
 // ============================================================================
 // Default Pins Tests
 // ============================================================================
@@ -0,0 +1,300 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// StandardKeyPitch is the pitch of standard 1u keys in mm. Key distances are expressed
+// in units of this pitch, so calibrated distances are comparable to the built-in presets.
+const StandardKeyPitch = 19.05
+
+// BoardGeometry is a calibration of the physical key positions of a specific board.
+// When in use (see UseBoardGeometry), key distances are computed from it instead of
+// from the built-in presets of the LayoutType. Finger assignments are not affected.
+type BoardGeometry struct {
+	PitchX        float64        // horizontal distance between key centres in mm
+	PitchY        float64        // vertical distance between key centres in mm
+	RowStagger    [3]float64     // horizontal offset of the top, home and bottom rows in mm
+	ColumnStagger [12]float64    // vertical offset of each column in mm (positive = lower)
+	Thumbs        *ThumbGeometry // thumb key positions in mm (nil = default of the layout type)
+	LSBReach      float64        // ring to inner index distance of a lateral stretch, in key units (0 = DefaultLSBReach)
+}
+
+// boardGeometry is the calibration in use, or nil for the built-in presets.
+var boardGeometry *BoardGeometry
+
+// NewBoardGeometry returns the geometry of an ortholinear board with standard key pitch.
+func NewBoardGeometry() *BoardGeometry {
+	return &BoardGeometry{PitchX: StandardKeyPitch, PitchY: StandardKeyPitch}
+}
+
+// UseBoardGeometry replaces the built-in distance presets of all layout types with
+// distances computed from the calibration. Passing nil restores the presets. It must be
+// called before layouts are loaded, as their derived caches depend on key distances.
+// Putting an equal geometry in use again has no effect.
+func UseBoardGeometry(g *BoardGeometry) {
+	if g.equal(boardGeometry) {
+		return
+	}
+	boardGeometry = g
+	for lt := range keyDistances {
+		layoutType := LayoutType(lt)
+		keyDistances[lt] = newKeyDistances(g, layoutType, VariantNone, DefaultThumbGeometry(layoutType))
+	}
+	for layoutType := range ansiKeyDistances {
+		ansiKeyDistances[layoutType] = newKeyDistances(g, layoutType, VariantANSI, DefaultThumbGeometry(layoutType))
+	}
+}
+
+// equal reports whether two geometries (either of which may be nil) are the same.
+func (g *BoardGeometry) equal(other *BoardGeometry) bool {
+	if g == nil || other == nil {
+		return g == other
+	}
+	if (g.Thumbs == nil) != (other.Thumbs == nil) ||
+		(g.Thumbs != nil && *g.Thumbs != *other.Thumbs) {
+		return false
+	}
+	return g.PitchX == other.PitchX && g.PitchY == other.PitchY &&
+		g.RowStagger == other.RowStagger && g.ColumnStagger == other.ColumnStagger &&
+		g.LSBReach == other.LSBReach
+}
+
+// x returns the horizontal position of a main-row key in mm.
+func (g *BoardGeometry) x(row, col uint8) float64 {
+	return float64(col)*g.PitchX + g.RowStagger[row]
+}
+
+// y returns the vertical position of a main-row key in mm.
+func (g *BoardGeometry) y(row, col uint8) float64 {
+	return float64(row)*g.PitchY + g.ColumnStagger[col]
+}
+
+// rowDist computes the vertical distance between two keys in standard key units.
+func (g *BoardGeometry) rowDist(row1, col1, row2, col2 uint8) float64 {
+	return math.Abs(g.y(row1, col1)-g.y(row2, col2)) / StandardKeyPitch
+}
+
+// colDist computes the horizontal distance between two keys in standard key units.
+func (g *BoardGeometry) colDist(row1, col1, row2, col2 uint8) float64 {
+	return math.Abs(g.x(row1, col1)-g.x(row2, col2)) / StandardKeyPitch
+}
+
+// thumbGeometry returns the calibrated thumb geometry in units of the board's key pitch.
+func (g *BoardGeometry) thumbGeometry() (ThumbGeometry, bool) {
+	if g.Thumbs == nil {
+		return ThumbGeometry{}, false
+	}
+	thumbs := *g.Thumbs
+	for i := range thumbs {
+		thumbs[i].Distance /= g.PitchX
+	}
+	return thumbs, true
+}
+
+// standardThumbs converts thumb geometry in units of the board's key pitch to standard
+// key units.
+func (g *BoardGeometry) standardThumbs(thumbs ThumbGeometry) ThumbGeometry {
+	for i := range thumbs {
+		thumbs[i].Distance *= g.PitchX / StandardKeyPitch
+	}
+	return thumbs
+}
+
+// NewBoardGeometryFromFile loads a board geometry from a file of key = value lines, with
+// all measurements in mm. Missing keys keep the values of NewBoardGeometry:
+//
+//	key-pitch = 19, 17                # horizontal, vertical
+//	row-stagger = 0, 4.75, 14.25      # top, home, bottom row
+//	column-stagger = 6, 4, 1, 0, 2, 3 # 6 values (mirrored) or 12 values
+//	thumbs = 19@165 0@0 21@-20 21@200 0@0 19@15
+//	lsb-reach = 3.5                   # in key units, see DefaultLSBReach
+func NewBoardGeometryFromFile(filePath string) (*BoardGeometry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %w", err)
+	}
+	defer CloseFile(file)
+
+	g := NewBoardGeometry()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value, _, _ = strings.Cut(value, "#") // Trailing comment
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "key-pitch":
+			err = g.SetKeyPitch(value)
+		case "row-stagger":
+			err = g.SetRowStagger(value)
+		case "column-stagger":
+			err = g.SetColumnStagger(value)
+		case "thumbs":
+			err = g.SetThumbs(value)
+		case "lsb-reach":
+			err = g.SetLSBReach(value)
+		default:
+			return nil, fmt.Errorf("unknown setting %q in geometry file", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in geometry file: %w", key, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading geometry file: %w", err)
+	}
+	return g, nil
+}
+
+// SaveToFile writes the board geometry in the format read by NewBoardGeometryFromFile.
+func (g *BoardGeometry) SaveToFile(filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("could not create geometry file: %w", err)
+	}
+	defer CloseFile(file)
+
+	writer := bufio.NewWriter(file)
+	defer FlushWriter(writer)
+
+	_, _ = fmt.Fprintln(writer, "# Board geometry calibration (all measurements in mm)")
+	_, _ = fmt.Fprintln(writer, "\n# Key pitch: horizontal, vertical")
+	_, _ = fmt.Fprintf(writer, "key-pitch = %s\n", formatFloats(g.PitchX, g.PitchY))
+	_, _ = fmt.Fprintln(writer, "\n# Row stagger: horizontal offset of the top, home and bottom rows")
+	_, _ = fmt.Fprintf(writer, "row-stagger = %s\n", formatFloats(g.RowStagger[:]...))
+	_, _ = fmt.Fprintln(writer, "\n# Column stagger: vertical offset of each column (positive = lower)")
+	_, _ = fmt.Fprintf(writer, "column-stagger = %s\n", formatFloats(g.ColumnStagger[:]...))
+	if g.Thumbs != nil {
+		_, _ = fmt.Fprintln(writer, "\n# Thumb keys: distance@angle from the home thumb key of each hand")
+		_, _ = fmt.Fprintf(writer, "thumbs = %s\n", g.Thumbs)
+	}
+	if g.LSBReach != 0 {
+		_, _ = fmt.Fprintln(writer, "\n# Lateral stretch: ring to inner index distance in key units, for colstag and ortho")
+		_, _ = fmt.Fprintf(writer, "lsb-reach = %s\n", formatFloats(g.LSBReach))
+	}
+	return nil
+}
+
+// SetKeyPitch parses and sets the key pitch from 1 value (square keys) or 2
+// comma-separated values (horizontal, vertical) in mm.
+func (g *BoardGeometry) SetKeyPitch(s string) error {
+	values, err := parseMeasurements(s)
+	if err != nil {
+		return err
+	}
+	switch len(values) {
+	case 1:
+		values = append(values, values[0])
+	case 2:
+	default:
+		return fmt.Errorf("expected 1 or 2 values, got %d", len(values))
+	}
+	if values[0] <= 0 || values[1] <= 0 {
+		return fmt.Errorf("key pitch must be above 0")
+	}
+	g.PitchX, g.PitchY = values[0], values[1]
+	return nil
+}
+
+// SetRowStagger parses and sets the horizontal offsets of the top, home and bottom rows
+// from 3 comma-separated values in mm. Thumb keys are positioned by SetThumbs instead.
+func (g *BoardGeometry) SetRowStagger(s string) error {
+	values, err := parseMeasurements(s)
+	if err != nil {
+		return err
+	}
+	if len(values) != 3 {
+		return fmt.Errorf("expected 3 values, got %d", len(values))
+	}
+	copy(g.RowStagger[:], values)
+	return nil
+}
+
+// SetColumnStagger parses and sets the vertical offsets of the columns from 6 values for
+// the left hand (outer to inner, mirrored to the right hand) or 12 values in mm.
+func (g *BoardGeometry) SetColumnStagger(s string) error {
+	values, err := parseMeasurements(s)
+	if err != nil {
+		return err
+	}
+	switch len(values) {
+	case 6:
+		for i, v := range values {
+			g.ColumnStagger[i] = v
+			g.ColumnStagger[11-i] = v
+		}
+	case 12:
+		copy(g.ColumnStagger[:], values)
+	default:
+		return fmt.Errorf("expected 6 or 12 values, got %d", len(values))
+	}
+	return nil
+}
+
+// SetThumbs parses and sets the thumb key positions, as for ParseThumbGeometry but with
+// distances in mm.
+func (g *BoardGeometry) SetThumbs(s string) error {
+	thumbs, err := ParseThumbGeometry(s)
+	if err != nil {
+		return err
+	}
+	g.Thumbs = &thumbs
+	return nil
+}
+
+// SetLSBReach parses and sets the distance in key units from a ring finger key to an inner
+// index column key from which the bigram is a lateral stretch on COLSTAG and ORTHO boards.
+func (g *BoardGeometry) SetLSBReach(s string) error {
+	values, err := parseMeasurements(s)
+	if err != nil {
+		return err
+	}
+	if len(values) != 1 {
+		return fmt.Errorf("expected 1 value, got %d", len(values))
+	}
+	if values[0] <= 0 {
+		return fmt.Errorf("lsb-reach must be above 0")
+	}
+	g.LSBReach = values[0]
+	return nil
+}
+
+// parseMeasurements parses comma-separated finite numbers.
+func parseMeasurements(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number %q", strings.TrimSpace(part))
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// formatFloats formats numbers as comma-separated values without trailing zeros.
+func formatFloats(values ...float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,94 @@
+package keycraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoardGeometry_Setters(t *testing.T) {
+	g := NewBoardGeometry()
+	if err := g.SetKeyPitch("18"); err != nil || g.PitchX != 18 || g.PitchY != 18 {
+		t.Errorf("SetKeyPitch(18): %v, got %v x %v", err, g.PitchX, g.PitchY)
+	}
+	if err := g.SetColumnStagger("1,2,3,4,5,6"); err != nil || g.ColumnStagger[0] != 1 || g.ColumnStagger[11] != 1 {
+		t.Errorf("SetColumnStagger should mirror 6 values: %v, got %v", err, g.ColumnStagger)
+	}
+
+	for name, set := range map[string]func() error{
+		"zero pitch":      func() error { return g.SetKeyPitch("0") },
+		"three pitches":   func() error { return g.SetKeyPitch("1,2,3") },
+		"bad number":      func() error { return g.SetRowStagger("0,x,0") },
+		"two row offsets": func() error { return g.SetRowStagger("0,1") },
+		"seven columns":   func() error { return g.SetColumnStagger("1,2,3,4,5,6,7") },
+		"bad thumbs":      func() error { return g.SetThumbs("1@0") },
+		"zero lsb reach":  func() error { return g.SetLSBReach("0") },
+	} {
+		if set() == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBoardGeometry_FileRoundTrip(t *testing.T) {
+	g := NewBoardGeometry()
+	Must0(g.SetKeyPitch("18, 17"))
+	Must0(g.SetRowStagger("0, 4.5, 13.5"))
+	Must0(g.SetColumnStagger("4, 4, 1.5, 0, 1.5, 3"))
+	Must0(g.SetThumbs("18@165 0@0 20@-20 20@200 0@0 18@15"))
+	Must0(g.SetLSBReach("3.2"))
+
+	path := filepath.Join(t.TempDir(), "board.geo")
+	if err := g.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	loaded, err := NewBoardGeometryFromFile(path)
+	if err != nil {
+		t.Fatalf("NewBoardGeometryFromFile failed: %v", err)
+	}
+	if !loaded.equal(g) {
+		t.Errorf("loaded %+v, want %+v", loaded, g)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.geo")
+	if err := os.WriteFile(bad, []byte("key-pitch = 19\nstagger = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewBoardGeometryFromFile(bad); err == nil {
+		t.Error("expected an error for an unknown setting")
+	}
+}
+
+func TestUseBoardGeometry(t *testing.T) {
+	defer UseBoardGeometry(nil)
+	preset := keyDistances[COLSTAG][KeyPair{16, 4}]
+
+	// A standard ortho calibration reproduces the ortho preset for all layout types
+	UseBoardGeometry(NewBoardGeometry())
+	if got, want := keyDistances[COLSTAG][KeyPair{16, 4}], keyDistances[ORTHO][KeyPair{16, 4}]; got != want {
+		t.Errorf("colstag distance %+v, want ortho %+v", got, want)
+	}
+
+	g := NewBoardGeometry()
+	Must0(g.SetKeyPitch("18,17"))
+	Must0(g.SetColumnStagger("0,0,0,0,0,5"))
+	Must0(g.SetThumbs("18@180 0@0 22@0 22@180 0@0 18@0"))
+	UseBoardGeometry(g)
+
+	layout := NewSplitLayout("test", COLSTAG, [42]rune{})
+	if got, want := layout.MustDistance(16, 4).RowDist, 17/StandardKeyPitch; math.Abs(got-want) > 1e-12 {
+		t.Errorf("row distance %v, want %v", got, want)
+	}
+	if got, want := layout.MustDistance(16, 17).RowDist, 5/StandardKeyPitch; math.Abs(got-want) > 1e-12 {
+		t.Errorf("column stagger distance %v, want %v", got, want)
+	}
+	if got, want := layout.MustDistance(36, 38).Distance, 40/StandardKeyPitch; math.Abs(got-want) > 1e-12 {
+		t.Errorf("thumb distance %v, want %v", got, want)
+	}
+
+	UseBoardGeometry(nil)
+	if got := keyDistances[COLSTAG][KeyPair{16, 4}]; got != preset {
+		t.Errorf("presets not restored: %+v, want %+v", got, preset)
+	}
+}
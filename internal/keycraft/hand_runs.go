@@ -0,0 +1,67 @@
+package keycraft
+
+import "math"
+
+// HandRunMetrics are the metrics computed by analyseHandRuns.
+var HandRunMetrics = []string{"HRUN-AVG", "HRUN-P95"}
+
+// analyseHandRuns computes the distribution of runs of consecutive keystrokes typed by the
+// same hand, from the words of the corpus weighted by their counts:
+//   - HandRuns: number of runs by length (index = run length, index 0 unused)
+//   - HRUN-AVG: average run length
+//   - HRUN-P95: 95th percentile of the run lengths
+//
+// A run ends at a word boundary or a character that is not on the layout. Thumb keys count
+// for the hand they are on. Unlike ALT, this exposes long one-handed bursts such as
+// "sweater" on QWERTY, which is a single left-hand run of 7.
+func (an *Analyser) analyseHandRuns() {
+	runs := make([]uint64, 2, 16)
+	var numRuns, sumLengths uint64
+
+	for word, cnt := range an.Corpus.Words {
+		hand, length := uint8(0), 0
+		flush := func() {
+			if length == 0 {
+				return
+			}
+			for len(runs) <= length {
+				runs = append(runs, 0)
+			}
+			runs[length] += cnt
+			numRuns += cnt
+			sumLengths += cnt * uint64(length)
+			length = 0
+		}
+		for _, r := range word {
+			ki, ok := an.Layout.GetKeyInfo(r)
+			switch {
+			case !ok:
+				flush()
+			case length > 0 && ki.Hand == hand:
+				length++
+			default:
+				flush()
+				hand, length = ki.Hand, 1
+			}
+		}
+		flush()
+	}
+
+	an.HandRuns = runs
+	an.Metrics["HRUN-AVG"] = 0
+	an.Metrics["HRUN-P95"] = 0
+	if numRuns == 0 {
+		return
+	}
+
+	an.Metrics["HRUN-AVG"] = float64(sumLengths) / float64(numRuns)
+	target := uint64(math.Ceil(float64(numRuns) * 0.95))
+	var cumulative uint64
+	for length, cnt := range runs {
+		cumulative += cnt
+		if cumulative >= target {
+			an.Metrics["HRUN-P95"] = float64(length)
+			break
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package keycraft
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAnalyseHandRuns(t *testing.T) {
+	var runes [42]rune
+	// Left hand: a s d f, right hand: j k, left thumb: space
+	runes[13], runes[14], runes[15], runes[16], runes[19], runes[20], runes[37] = 'a', 's', 'd', 'f', 'j', 'k', ' '
+	sl := NewSplitLayout("test", ORTHO, runes)
+
+	corpus := NewCorpus("test")
+	corpus.Words = map[string]uint64{
+		"sad":  2, // one run of 3
+		"jaks": 1, // runs of 1, 1, 1, 1
+		"fxjk": 1, // 'x' is not on the layout: runs of 1 and 2
+	}
+	an := NewAnalyser(sl, corpus, nil)
+
+	if want := []uint64{0, 5, 1, 2}; !slices.Equal(an.HandRuns, want) {
+		t.Errorf("HandRuns = %v, want %v", an.HandRuns, want)
+	}
+	if got, want := an.Metrics["HRUN-AVG"], 13.0/8; got != want {
+		t.Errorf("HRUN-AVG = %v, want %v", got, want)
+	}
+	if got := an.Metrics["HRUN-P95"]; got != 3 {
+		t.Errorf("HRUN-P95 = %v, want 3", got)
+	}
+}
+
+func TestAnalyseHandRuns_NoWords(t *testing.T) {
+	var runes [42]rune
+	runes[13] = 'a'
+	an := NewAnalyser(NewSplitLayout("test", ORTHO, runes), NewCorpus("test"), nil)
+	if an.Metrics["HRUN-AVG"] != 0 || an.Metrics["HRUN-P95"] != 0 {
+		t.Errorf("expected zero metrics without words, got %v and %v",
+			an.Metrics["HRUN-AVG"], an.Metrics["HRUN-P95"])
+	}
+}
@@ -0,0 +1,102 @@
+package keycraft
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// HistoryEntry is a single accepted new-best layout recorded during optimization.
+type HistoryEntry struct {
+	Iteration int                `json:"iteration"`
+	ElapsedMs int64              `json:"elapsed_ms"`
+	Cost      float64            `json:"cost"`
+	Layout    []string           `json:"layout"`
+	Metrics   map[string]float64 `json:"metrics"`
+}
+
+// HistoryRecorder writes an optimization history as JSONL, one HistoryEntry per
+// new-best layout. The metric snapshot of each entry is computed with a full Analyser,
+// which is affordable because new-best layouts are rare compared to Score() calls.
+type HistoryRecorder struct {
+	w       io.Writer
+	corpus  *Corpus
+	targets *TargetLoads
+}
+
+// NewHistoryRecorder creates a recorder that writes history entries to w.
+func NewHistoryRecorder(w io.Writer, corpus *Corpus, targets *TargetLoads) *HistoryRecorder {
+	return &HistoryRecorder{
+		w:       w,
+		corpus:  corpus,
+		targets: targets,
+	}
+}
+
+// Record analyses the layout and appends a history entry.
+func (h *HistoryRecorder) Record(iteration int, cost float64, layout *SplitLayout, elapsed time.Duration) error {
+	an := NewAnalyser(layout, h.corpus, h.targets)
+
+	data, err := json.Marshal(HistoryEntry{
+		Iteration: iteration,
+		ElapsedMs: elapsed.Milliseconds(),
+		Cost:      cost,
+		Layout:    layoutToStrings(layout),
+		Metrics:   an.Metrics,
+	})
+	if err != nil {
+		return fmt.Errorf("could not encode history entry: %w", err)
+	}
+
+	data = append(data, '\n')
+	if _, err := h.w.Write(data); err != nil {
+		return fmt.Errorf("could not write history entry: %w", err)
+	}
+	return nil
+}
+
+// ReadHistory parses a JSONL optimization history. Empty lines are ignored.
+func ReadHistory(r io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d: %w", lineNum, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// LoadHistoryFile reads an optimization history from a JSONL file.
+func LoadHistoryFile(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open history file %s: %w", path, err)
+	}
+	defer CloseFile(file)
+
+	entries, err := ReadHistory(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse history file %s: %w", path, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,57 @@
+package keycraft
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecorder_RoundTrip(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+
+	layout, err := NewLayoutFromFile("qwerty", "../../data/layouts/qwerty.klf")
+	if err != nil {
+		t.Skipf("Skipping test - layout not available: %v", err)
+	}
+
+	var buf bytes.Buffer
+	rec := NewHistoryRecorder(&buf, corpus, nil)
+	if err := rec.Record(0, 1.5, layout, 0); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := rec.Record(12, 0.75, layout, 2*time.Second); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	entries, err := ReadHistory(&buf)
+	if err != nil {
+		t.Fatalf("ReadHistory failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	last := entries[1]
+	if last.Iteration != 12 || last.Cost != 0.75 || last.ElapsedMs != 2000 {
+		t.Errorf("unexpected entry: iteration=%d cost=%f elapsed=%d", last.Iteration, last.Cost, last.ElapsedMs)
+	}
+	if len(last.Layout) != 4 {
+		t.Errorf("expected 4 layout rows, got %d", len(last.Layout))
+	}
+	if _, ok := last.Metrics["SFB"]; !ok {
+		t.Error("expected metric snapshot to contain SFB")
+	}
+}
+
+func TestReadHistory_InvalidLine(t *testing.T) {
+	input := "{\"iteration\": 1, \"cost\": 2}\n\nnot json\n"
+	_, err := ReadHistory(strings.NewReader(input))
+	if err == nil {
+		t.Fatal("expected error for invalid line, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected error to mention line 3, got: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package keycraft
+
+// analyserCounts holds the raw n-gram counts behind the metrics, so that ApplySwap can
+// update them without analysing the whole corpus again.
+type analyserCounts struct {
+	keys      [42]uint64                // Unigrams by key index
+	bigrams   [4]uint64                 // SFB, LSB, FSB, HSB
+	skipgrams [4]uint64                 // SFS, LSS, FSS, HSS
+	trigrams  [numTrigramClasses]uint64 // Trigrams by class (see classifyTrigram)
+	redSpan   uint64                    // Redirections weighted by their finger span (see redirectSpan)
+}
+
+// swapIndex holds the lookup tables used by ApplySwap to find the n-grams affected by a swap.
+type swapIndex struct {
+	pairs    [4][42][][2]uint8 // SFB, LSB, FSB and HSB key pairs, by key index
+	trigrams []TrigramInfo     // Trigrams with all 3 runes on the layout
+	byRune   map[rune][]int32  // Indices into trigrams, by (base) rune
+	marks    []uint32          // Last swap that visited each trigram, to visit it only once
+	swap     uint32            // Number of swaps applied
+	affected []int32           // Reused buffer of affected trigrams
+}
+
+// newSwapIndex builds the lookup tables for the analyser's layout.
+func (an *Analyser) newSwapIndex() *swapIndex {
+	idx := &swapIndex{}
+	addPair := func(pattern int, k1, k2 uint8) {
+		idx.pairs[pattern][k1] = append(idx.pairs[pattern][k1], [2]uint8{k1, k2})
+		if k2 != k1 {
+			idx.pairs[pattern][k2] = append(idx.pairs[pattern][k2], [2]uint8{k1, k2})
+		}
+	}
+	for _, p := range an.Layout.SFBs {
+		addPair(0, p.KeyIdx1, p.KeyIdx2)
+	}
+	for _, p := range an.lsbs() {
+		addPair(1, p.KeyIdx1, p.KeyIdx2)
+	}
+	for _, p := range an.Layout.FScissors {
+		addPair(2, p.keyIdx1, p.keyIdx2)
+	}
+	for _, p := range an.Layout.HScissors {
+		addPair(3, p.keyIdx1, p.keyIdx2)
+	}
+
+	idx.trigrams = an.trigrams()
+	idx.byRune = make(map[rune][]int32)
+	sl := an.Layout
+	for i, ti := range idx.trigrams {
+		r0, r1, r2 := sl.baseRune(ti.Runes[0]), sl.baseRune(ti.Runes[1]), sl.baseRune(ti.Runes[2])
+		// Index each rune of a trigram once
+		idx.byRune[r0] = append(idx.byRune[r0], int32(i))
+		if r1 != r0 {
+			idx.byRune[r1] = append(idx.byRune[r1], int32(i))
+		}
+		if r2 != r0 && r2 != r1 {
+			idx.byRune[r2] = append(idx.byRune[r2], int32(i))
+		}
+	}
+	idx.marks = make([]uint32, len(idx.trigrams))
+	return idx
+}
+
+// ApplySwap swaps the keys at indices i and j of the layout, and updates the metrics by
+// removing and re-adding only the n-gram contributions that involve the two keys. The
+// result is the same as analysing the swapped layout from scratch, at a fraction of the
+// cost. ROLLQ and the metrics computed from the word list and the corpus stream (e.g.
+// HRUN-AVG and FATIGUE) are recomputed in full.
+//
+// The analyser must have been created by NewAnalyser, and the layout must not be changed
+// by other means. Like SplitLayout.Swap, it panics if an index is out of bounds or a key
+// is unused.
+func (an *Analyser) ApplySwap(i, j uint8) {
+	sl := an.Layout
+	sl.checkSwap(i, j)
+	if i == j {
+		return
+	}
+	if an.swaps == nil {
+		an.swaps = an.newSwapIndex()
+	}
+	idx := an.swaps
+
+	// Collect the trigrams that contain either of the swapped runes
+	idx.swap++
+	idx.affected = idx.affected[:0]
+	for _, r := range [2]rune{sl.Runes[i], sl.Runes[j]} {
+		for _, t := range idx.byRune[r] {
+			if idx.marks[t] != idx.swap {
+				idx.marks[t] = idx.swap
+				idx.affected = append(idx.affected, t)
+			}
+		}
+	}
+
+	an.updateSwapped(i, j, false)
+	sl.Swap(i, j)
+	an.updateSwapped(i, j, true)
+	an.counts.keys[i], an.counts.keys[j] = an.counts.keys[j], an.counts.keys[i]
+
+	an.setHandMetrics()
+	an.setBigramMetrics()
+	an.setSkipgramMetrics()
+	an.setTrigramMetrics()
+	an.analyseRollQuality()
+	an.analyseShift()
+	an.analyseNumbers()
+	an.analyseMagic()
+	an.analyseMoves()
+	if an.stream {
+		an.analyseHandRuns()
+		an.analyseFatigue()
+	}
+}
+
+// updateSwapped removes (add = false) or adds the counts of the bigrams, skipgrams and
+// trigrams affected by swapping keys i and j, for the current layout.
+func (an *Analyser) updateSwapped(i, j uint8, add bool) {
+	idx := an.swaps
+	sl := an.Layout
+	update := func(counter *uint64, cnt uint64) {
+		if add {
+			*counter += cnt
+		} else {
+			*counter -= cnt
+		}
+	}
+
+	for pattern := range idx.pairs {
+		for _, k := range [2]uint8{i, j} {
+			for _, p := range idx.pairs[pattern][k] {
+				if k == j && (p[0] == i || p[1] == i) {
+					continue // Already visited from key i
+				}
+				r1, r2 := sl.Runes[p[0]], sl.Runes[p[1]]
+				update(&an.counts.bigrams[pattern], an.Corpus.Bigrams[Bigram{r1, r2}])
+				if pattern != 0 || !an.bridgedSFS() {
+					update(&an.counts.skipgrams[pattern], an.Corpus.Skipgrams[Skipgram{r1, r2}])
+				}
+			}
+		}
+	}
+
+	for _, t := range idx.affected {
+		ti := &idx.trigrams[t]
+		k0, _ := sl.GetKeyInfo(ti.Runes[0])
+		k1, _ := sl.GetKeyInfo(ti.Runes[1])
+		k2, _ := sl.GetKeyInfo(ti.Runes[2])
+		class := classifyTrigram(k0, k1, k2)
+		update(&an.counts.trigrams[class], ti.Count)
+		update(&an.counts.redSpan, ti.Count*redirectSpan(class, k0, k1, k2))
+		if an.bridgedSFS() && an.isBridgedSFS(k0, k1, k2) {
+			update(&an.counts.skipgrams[0], ti.Count)
+		}
+	}
+}
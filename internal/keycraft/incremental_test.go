@@ -0,0 +1,95 @@
+package keycraft
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// usedKeys returns the indices of the keys that have a rune.
+func usedKeys(sl *SplitLayout) []uint8 {
+	var keys []uint8
+	for i, r := range sl.Runes {
+		if r != 0 {
+			keys = append(keys, uint8(i))
+		}
+	}
+	return keys
+}
+
+// assertSameMetrics fails if two analysers have different metrics.
+func assertSameMetrics(t *testing.T, got, want *Analyser) {
+	t.Helper()
+	for metric, w := range want.Metrics {
+		g, ok := got.Metrics[metric]
+		if !ok || math.Abs(g-w) > 1e-9 && !(math.IsNaN(g) && math.IsNaN(w)) {
+			t.Errorf("%s = %v, want %v", metric, g, w)
+		}
+	}
+}
+
+func TestAnalyser_ApplySwap(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	corpus.addTextWithWords("sphinx of black quartz, judge my vow. how vexingly quick daft zebras jump!")
+
+	layout := Must(writeShiftedLayout(t, "\nshift: us\n"))
+	an := NewAnalyser(layout, corpus, nil)
+	keys := usedKeys(layout)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 50 {
+		i, j := keys[rng.IntN(len(keys))], keys[rng.IntN(len(keys))]
+		an.ApplySwap(i, j)
+		assertSameMetrics(t, an, NewAnalyser(layout.Clone(), corpus, nil))
+	}
+	if layout.Runes != an.Layout.Runes {
+		t.Fatal("ApplySwap should swap the keys of the analysed layout")
+	}
+
+	// Uppercase letters of a cased corpus follow their lowercase keys
+	cased := NewCorpus("cased")
+	cased.Cased = true
+	cased.addTextWithWords("The Quick Brown Fox Jumps Over The Lazy Dog; Sphinx Of Black Quartz, Judge My Vow.")
+	an = NewAnalyser(layout, cased, nil)
+	for range 50 {
+		i, j := keys[rng.IntN(len(keys))], keys[rng.IntN(len(keys))]
+		an.ApplySwap(i, j)
+		assertSameMetrics(t, an, NewAnalyser(layout.Clone(), cased, nil))
+	}
+}
+
+func TestAnalyser_ApplySwapPanics(t *testing.T) {
+	an := NewAnalyser(Must(writeShiftedLayout(t, "")), NewCorpus("test"), nil)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when swapping an unused key")
+		}
+	}()
+	an.ApplySwap(0, 13) // Key 0 is unused
+}
+
+// BenchmarkAnalyserApplySwap benchmarks updating the metrics after a swap, to compare
+// with BenchmarkAnalyser.
+//
+// Run with:
+//
+//	go test -bench=BenchmarkAnalyserApplySwap -benchmem ./internal/keycraft
+func BenchmarkAnalyserApplySwap(b *testing.B) {
+	corpus, err := NewCorpusFromFile("default", "../../data/corpus/default.txt", false, 98.0)
+	if err != nil {
+		b.Fatalf("Failed to load corpus: %v", err)
+	}
+	layout, err := NewLayoutFromFile("qwerty", "../../data/layouts/qwerty.klf")
+	if err != nil {
+		b.Fatalf("Failed to load layout: %v", err)
+	}
+
+	an := NewAnalyser(layout, corpus, nil)
+	an.stream = false // Exclude the metrics that are recomputed in full
+	keys := usedKeys(layout)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for b.Loop() {
+		an.ApplySwap(keys[rng.IntN(len(keys))], keys[rng.IntN(len(keys))])
+	}
+}
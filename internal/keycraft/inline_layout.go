@@ -0,0 +1,88 @@
+package keycraft
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// inlineSettingRE matches the "; setting=" separators of the settings of an inline layout.
+// Only known settings start a setting, so that ';' can also be a key of the layout.
+var inlineSettingRE = regexp.MustCompile(`;\s*(name|thumbs|shift|shift-finger|magic)\s*=`)
+
+// NewLayoutFromString parses a layout given inline, such as on the command line, without a
+// layout file. The format is "<type>:<keys>", optionally followed by settings as
+// "; setting=value":
+//
+//	colstag:~qwfpbjluy;~~arstgmneio'~zxcdvkh,./~~~_~~~; name=mytest
+//
+// The keys are either 42 keys separated by whitespace, with the special tokens of a .klf
+// file (see NewLayoutFromFile), or 42 characters in one string, ignoring whitespace, in
+// which "~" is an empty key and "_" is space. Keys are in layout order: the top, home and
+// bottom rows of 12 keys, then the 6 thumb keys. The settings are "name" (a generated name
+// if not given), and the optional settings of a .klf file: "thumbs", "shift",
+// "shift-finger" and "magic".
+func NewLayoutFromString(s string) (*SplitLayout, error) {
+	layoutType, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid inline layout %q: must start with a layout type and ':', such as \"colstag:\"", s)
+	}
+
+	// Split off the settings
+	var settings [][2]string
+	matches := inlineSettingRE.FindAllStringSubmatchIndex(rest, -1)
+	keys := rest
+	if len(matches) > 0 {
+		keys = rest[:matches[0][0]]
+	}
+	for i, m := range matches {
+		end := len(rest)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		settings = append(settings, [2]string{rest[m[2]:m[3]], strings.TrimSpace(rest[m[1]:end])})
+	}
+
+	tokens, err := inlineKeyTokens(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inline layout %q: %w", s, err)
+	}
+
+	var name string
+	var klf strings.Builder
+	klf.WriteString(strings.TrimSpace(layoutType) + "\n")
+	for row := range 4 {
+		klf.WriteString(strings.Join(tokens[12*row:min(12*row+12, len(tokens))], " ") + "\n")
+	}
+	for _, setting := range settings {
+		if setting[0] == "name" {
+			name = setting[1]
+			continue
+		}
+		klf.WriteString(setting[0] + ": " + setting[1] + "\n")
+	}
+
+	return newLayoutFromReader(name, "inline layout", strings.NewReader(klf.String()))
+}
+
+// inlineKeyTokens returns the 42 keys of an inline layout as .klf tokens.
+func inlineKeyTokens(keys string) ([]string, error) {
+	if fields := strings.Fields(keys); len(fields) == 42 {
+		return fields, nil
+	}
+
+	compact := strings.Join(strings.Fields(keys), "")
+	if n := utf8.RuneCountInString(compact); n != 42 {
+		return nil, fmt.Errorf("must have 42 keys, as separate keys or as one string of characters (got %d characters)", n)
+	}
+	tokens := make([]string, 0, 42)
+	for _, r := range compact {
+		if r == '#' {
+			tokens = append(tokens, "##") // A single '#' would start a comment
+			continue
+		}
+		tokens = append(tokens, string(r))
+	}
+	return tokens, nil
+}
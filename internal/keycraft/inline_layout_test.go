@@ -0,0 +1,43 @@
+package keycraft
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewLayoutFromString(t *testing.T) {
+	runes := qwertyRunes()
+	runes[0], runes[11], runes[12], runes[24], runes[35] = 0, 0, 0, 0, 0
+	want := NewSplitLayout("qwerty", ROWSTAG, runes)
+	for _, s := range []string{
+		"rowstag:~qwertyuiop~~asdfghjkl;'~zxcvbnm,./~~~~_~~; name=qwerty",
+		"rowstag: ~qwert yuiop~  ~asdfg hjkl;'  ~zxcvb nm,./~  ~~~ _~~ ;name = qwerty",
+		"rowstag: ~ q w e r t  y u i o p ~  ~ a s d f g  h j k l ; '  ~ z x c v b  n m , . / ~  ~ ~ ~  _ ~ ~; name=qwerty",
+	} {
+		sl, err := NewLayoutFromString(s)
+		if err != nil {
+			t.Fatalf("NewLayoutFromString(%q): %v", s, err)
+		}
+		if sl.Name != want.Name || sl.LayoutType != want.LayoutType || sl.Runes != want.Runes {
+			t.Errorf("NewLayoutFromString(%q) = %s %v %q, want %s %v %q", s,
+				sl.Name, sl.LayoutType, string(sl.Runes[:]), want.Name, want.LayoutType, string(want.Runes[:]))
+		}
+	}
+
+	// Without a name, a name is generated; the settings of .klf files apply
+	sl := Must(NewLayoutFromString("colstag:~qwfpbjluy;#~arstgmneio'~zxcdvkh,./~~~_~~~; shift=us"))
+	if !strings.HasPrefix(sl.Name, "_") || sl.Runes[11] != '#' || sl.Shifted == nil {
+		t.Errorf("got name %q, rune %q, shifted %v", sl.Name, sl.Runes[11], sl.Shifted)
+	}
+
+	for _, s := range []string{
+		"~qwertyuiop~~asdfghjkl;'~zxcvbnm,./~~~~_~~",
+		"qwerty:~qwertyuiop~~asdfghjkl;'~zxcvbnm,./~~~~_~~",
+		"ortho:abc",
+		"ortho:~qwertyuiop~~asdfghjkl;'~zxcvbnm,./~~~~_~q",
+	} {
+		if _, err := NewLayoutFromString(s); err == nil {
+			t.Errorf("NewLayoutFromString(%q): expected an error", s)
+		}
+	}
+}
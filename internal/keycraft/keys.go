@@ -3,6 +3,8 @@ package keycraft
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
 )
 
 // KeyPair represents an ordered pair of key indices.
@@ -16,17 +18,155 @@ type KeyPairDistance struct {
 	Distance   float64 // Euclidean distance (sqrt(RowDist^2 + ColDist^2))
 }
 
-// keyDistances contains precomputed key pair distances for each LayoutType.
-// Distance functions are selected based on keyboard geometry:
-//   - ROWSTAG: AbsRowDist, AbsColDistAdj (accounts for row stagger)
+// keyDistances contains precomputed key pair distances for each LayoutType, using the
+// default thumb geometry of the type.
+var keyDistances = []map[KeyPair]KeyPairDistance{
+	newKeyDistances(nil, ROWSTAG, VariantNone, DefaultThumbGeometry(ROWSTAG)),
+	newKeyDistances(nil, ANGLEMOD, VariantNone, DefaultThumbGeometry(ANGLEMOD)),
+	newKeyDistances(nil, ORTHO, VariantNone, DefaultThumbGeometry(ORTHO)),
+	newKeyDistances(nil, COLSTAG, VariantNone, DefaultThumbGeometry(COLSTAG)),
+}
+
+// newKeyDistances computes key pair distances for a board geometry (nil = none), LayoutType,
+// board variant and thumb geometry. Distance functions for the main rows are selected based on keyboard geometry:
+//   - ROWSTAG: AbsRowDist, AbsColDistAdj (accounts for row stagger), or AbsColDistANSI on
+//     ANSI boards
 //   - ANGLEMOD: AbsRowDist, AbsColDistAdj (similar to row-staggered)
 //   - ORTHO: AbsRowDist, AbsColDist (simple grid distances)
 //   - COLSTAG: AbsRowDistAdj, AbsColDist (accounts for column stagger)
-var keyDistances = []map[KeyPair]KeyPairDistance{
-	calcKeyDistances(AbsRowDist, AbsColDistAdj, &keyToFinger),         // ROWSTAG
-	calcKeyDistances(AbsRowDist, AbsColDistAdj, &angleModKeyToFinger), // ANGLEMOD
-	calcKeyDistances(AbsRowDist, AbsColDist, &keyToFinger),            // ORTHO
-	calcKeyDistances(AbsRowDistAdj, AbsColDist, &keyToFinger),         // COLSTAG
+//
+// A board geometry replaces these presets for all layout types and variants.
+func newKeyDistances(g *BoardGeometry, layoutType LayoutType, variant BoardVariant, thumbs ThumbGeometry) map[KeyPair]KeyPairDistance {
+	if g != nil {
+		fingers := &keyToFinger
+		if layoutType == ANGLEMOD {
+			fingers = &angleModKeyToFinger
+		}
+		standard := g.standardThumbs(thumbs)
+		return calcKeyDistances(g.rowDist, g.colDist, fingers, &standard)
+	}
+
+	colDist := AbsColDistAdj
+	if variant == VariantANSI {
+		colDist = AbsColDistANSI
+	}
+
+	switch layoutType {
+	case ANGLEMOD:
+		return calcKeyDistances(AbsRowDist, colDist, &angleModKeyToFinger, &thumbs)
+	case ORTHO:
+		return calcKeyDistances(AbsRowDist, AbsColDist, &keyToFinger, &thumbs)
+	case COLSTAG:
+		return calcKeyDistances(AbsRowDistAdj, AbsColDist, &keyToFinger, &thumbs)
+	default:
+		return calcKeyDistances(AbsRowDist, colDist, &keyToFinger, &thumbs)
+	}
+}
+
+// ThumbKey is the position of a thumb key relative to the home thumb position of its
+// hand, in polar coordinates.
+type ThumbKey struct {
+	Distance float64 // distance from the home thumb position in key units
+	Angle    float64 // direction in degrees, counter-clockwise from the right (90 = up)
+}
+
+// ThumbGeometry holds the positions of the 6 thumb keys (3 left, 3 right, in layout
+// order), with distances in key units. Thumb key distances are computed from these positions rather than from the
+// grid used for finger keys, since thumbs move in an arc around the home position.
+type ThumbGeometry [6]ThumbKey
+
+// flatThumbGeometry models thumb keys in a straight row, one key apart, with the middle
+// key of each hand as home. This matches the grid used for finger keys.
+var flatThumbGeometry = ThumbGeometry{
+	{1, 180}, {0, 0}, {1, 0}, // Left: outer, home, inner
+	{1, 180}, {0, 0}, {1, 0}, // Right: inner, home, outer
+}
+
+// colStagThumbGeometry models the thumb cluster of ergonomic boards (e.g., Corne), where
+// keys fan out along an arc: the inner key sits lower than the home key, and the outer
+// key slightly higher and closer.
+var colStagThumbGeometry = ThumbGeometry{
+	{1, 165}, {0, 0}, {1.1, -20}, // Left: outer, home, inner
+	{1.1, 200}, {0, 0}, {1, 15}, // Right: inner, home, outer
+}
+
+// DefaultThumbGeometry returns the thumb geometry used for a LayoutType, unless a layout
+// file specifies its own. A board geometry in use may replace it with calibrated positions.
+func DefaultThumbGeometry(layoutType LayoutType) ThumbGeometry {
+	if boardGeometry != nil {
+		if thumbs, ok := boardGeometry.thumbGeometry(); ok {
+			return thumbs
+		}
+	}
+	if layoutType == COLSTAG {
+		return colStagThumbGeometry
+	}
+	return flatThumbGeometry
+}
+
+// position returns the x (right) and y (down) coordinates of thumb key i (0-5),
+// relative to the home thumb position of its hand.
+func (g *ThumbGeometry) position(i uint8) (float64, float64) {
+	sin, cos := math.Sincos(g[i].Angle * math.Pi / 180)
+	// Snap rounding errors, so keys in a straight row or column are exactly aligned
+	snap := func(v float64) float64 {
+		if math.Abs(v) < 1e-12 {
+			return 0
+		}
+		return v
+	}
+	return snap(g[i].Distance * cos), snap(-g[i].Distance * sin)
+}
+
+// Mirrored returns the thumb geometry of the horizontally mirrored board.
+func (g ThumbGeometry) Mirrored() ThumbGeometry {
+	var m ThumbGeometry
+	for i, tk := range g {
+		angle := 180 - tk.Angle
+		if tk.Distance == 0 {
+			angle = 0
+		}
+		m[5-i] = ThumbKey{Distance: tk.Distance, Angle: angle}
+	}
+	return m
+}
+
+// String formats the thumb geometry as in a layout file, e.g. "1@165 0@0 1.1@-20 ...".
+func (g ThumbGeometry) String() string {
+	parts := make([]string, len(g))
+	for i, tk := range g {
+		parts[i] = strconv.FormatFloat(tk.Distance, 'f', -1, 64) + "@" +
+			strconv.FormatFloat(tk.Angle, 'f', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseThumbGeometry parses 6 whitespace-separated thumb key positions of the form
+// "distance@angle", such as "1@165 0@0 1.1@-20 1.1@200 0@0 1@15".
+func ParseThumbGeometry(s string) (ThumbGeometry, error) {
+	var g ThumbGeometry
+	fields := strings.Fields(s)
+	if len(fields) != len(g) {
+		return g, fmt.Errorf("expected %d thumb keys, got %d", len(g), len(fields))
+	}
+
+	for i, field := range fields {
+		distStr, angleStr, ok := strings.Cut(field, "@")
+		if !ok {
+			return g, fmt.Errorf("thumb key %q must be of the form distance@angle", field)
+		}
+		dist, err1 := strconv.ParseFloat(distStr, 64)
+		angle, err2 := strconv.ParseFloat(angleStr, 64)
+		if err1 != nil || err2 != nil || math.IsNaN(dist) || math.IsInf(dist, 0) ||
+			math.IsNaN(angle) || math.IsInf(angle, 0) {
+			return g, fmt.Errorf("thumb key %q must have a numeric distance and angle", field)
+		}
+		if dist < 0 {
+			return g, fmt.Errorf("thumb key %q has a negative distance", field)
+		}
+		g[i] = ThumbKey{Distance: dist, Angle: angle}
+	}
+	return g, nil
 }
 
 // rowStagOffsets defines the horizontal offset for each row in row-staggered layouts.
@@ -104,12 +244,13 @@ func (sl *SplitLayout) MustDistance(k1, k2 uint8) *KeyPairDistance {
 }
 
 // calcKeyDistances precomputes all pairwise distances between keys on the same hand.
-// Uses the provided distance functions to account for layout-specific geometry.
-// Note: thumb key distance calculations have a known minor inaccuracy.
+// Uses the provided distance functions to account for layout-specific geometry of the
+// main rows, and the thumb geometry for pairs of thumb keys.
 func calcKeyDistances(
 	rowDistFunc func(row1 uint8, col1 uint8, row2 uint8, col2 uint8) float64,
 	colDistFunc func(row1 uint8, col1 uint8, row2 uint8, col2 uint8) float64,
 	keyToFinger *[42]uint8,
+	thumbs *ThumbGeometry,
 ) map[KeyPair]KeyPairDistance {
 	keyDistances := make(map[KeyPair]KeyPairDistance, 624)
 
@@ -154,8 +295,15 @@ func calcKeyDistances(
 			}
 
 			// Compute distance metrics
-			dx := colDistFunc(row1, col1, row2, col2)
-			dy := rowDistFunc(row1, col1, row2, col2)
+			var dx, dy float64
+			if row1 == 3 {
+				x1, y1 := thumbs.position(col1)
+				x2, y2 := thumbs.position(col2)
+				dx, dy = math.Abs(x1-x2), math.Abs(y1-y2)
+			} else {
+				dx = colDistFunc(row1, col1, row2, col2)
+				dy = rowDistFunc(row1, col1, row2, col2)
+			}
 			dist := sqrt(dx*dx + dy*dy)
 			keyDistances[KeyPair{k1, k2}] = KeyPairDistance{
 				RowDist:    dy,
@@ -190,3 +338,31 @@ func AbsColDistAdj(row1, col1, row2, col2 uint8) float64 {
 	return math.Abs((float64(col1) + rowStagOffsets[row1] -
 		(float64(col2) + rowStagOffsets[row2])))
 }
+
+// KeyPairEntry is the distance between two keys of a layout type.
+type KeyPairEntry struct {
+	Key1, Key2 KeyInfo
+	KeyPairDistance
+}
+
+// KeyGeometry returns the 42 keys of a layout type with their fingers, and the distances
+// between every two keys on the same hand, each pair once with the lower key index first.
+// Distances follow the board geometry in use, if any, and the default thumb geometry of the
+// layout type. This is the physical model that the metrics rely on.
+func KeyGeometry(layoutType LayoutType) ([]KeyInfo, []KeyPairEntry) {
+	keys := make([]KeyInfo, 42)
+	for i := range uint8(42) {
+		keys[i] = NewKeyInfo(i/12, i%12, layoutType)
+	}
+
+	distances := keyDistances[layoutType]
+	pairs := make([]KeyPairEntry, 0, len(distances)/2)
+	for k1 := range uint8(42) {
+		for k2 := k1 + 1; k2 < 42; k2++ {
+			if d, ok := distances[KeyPair{k1, k2}]; ok {
+				pairs = append(pairs, KeyPairEntry{Key1: keys[k1], Key2: keys[k2], KeyPairDistance: d})
+			}
+		}
+	}
+	return keys, pairs
+}
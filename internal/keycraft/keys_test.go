@@ -0,0 +1,122 @@
+package keycraft
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseThumbGeometry(t *testing.T) {
+	g, err := ParseThumbGeometry("1@165 0@0 1.1@-20 1.1@200 0@0 1@15")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if g != colStagThumbGeometry {
+		t.Errorf("got %v, want %v", g, colStagThumbGeometry)
+	}
+	if got := g.String(); got != "1@165 0@0 1.1@-20 1.1@200 0@0 1@15" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, spec := range []string{"", "1@0 0@0 1@0", "1@0 0@0 1@0 1@0 0@0 x@0", "1 0@0 1@0 1@0 0@0 1@0", "-1@0 0@0 1@0 1@0 0@0 1@0"} {
+		if _, err := ParseThumbGeometry(spec); err == nil {
+			t.Errorf("ParseThumbGeometry(%q) should fail", spec)
+		}
+	}
+}
+
+func TestThumbDistances(t *testing.T) {
+	// Flat thumbs keep the grid distances of the main rows
+	for _, lt := range []LayoutType{ROWSTAG, ANGLEMOD, ORTHO} {
+		d := keyDistances[lt][KeyPair{36, 38}]
+		if d.Distance != 2 || d.RowDist != 0 || d.ColDist != 2 {
+			t.Errorf("%s: distance 36-38 = %+v, want 2 columns apart", LayoutTypeStrings[lt], d)
+		}
+	}
+
+	// Colstag thumbs are on an arc: the inner key is lower than the home key
+	d := keyDistances[COLSTAG][KeyPair{37, 38}]
+	if math.Abs(d.Distance-1.1) > 1e-9 || d.RowDist == 0 {
+		t.Errorf("colstag distance 37-38 = %+v, want 1.1 away and lower", d)
+	}
+	if d, ok := keyDistances[COLSTAG][KeyPair{38, 39}]; ok {
+		t.Errorf("thumb keys of different hands have a distance: %+v", d)
+	}
+
+	// Mirroring the default geometry gives the same distances on the other hand
+	left := keyDistances[COLSTAG][KeyPair{36, 38}]
+	right := keyDistances[COLSTAG][KeyPair{41, 39}]
+	if math.Abs(left.Distance-right.Distance) > 1e-9 {
+		t.Errorf("colstag thumbs are not symmetric: %v vs %v", left.Distance, right.Distance)
+	}
+	if colStagThumbGeometry.Mirrored() != colStagThumbGeometry {
+		t.Errorf("default colstag geometry should be symmetric")
+	}
+}
+
+func TestLayoutFile_ThumbGeometry(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"thumbs.klf": testLayoutKlf + "thumbs: 2@180 0@0 1@0 1@180 0@0 2@0\n",
+		"bad.klf":    testLayoutKlf + "thumbs: 1@0\n",
+	})
+
+	layout, err := NewLayoutFromFile("thumbs", filepath.Join(dir, "thumbs.klf"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if layout.Thumbs == nil {
+		t.Fatal("thumb geometry not loaded")
+	}
+	if got := layout.MustDistance(36, 38).Distance; got != 3 {
+		t.Errorf("distance 36-38 = %v, want 3", got)
+	}
+	if got := keyDistances[ROWSTAG][KeyPair{36, 38}].Distance; got != 2 {
+		t.Errorf("custom geometry changed the shared distances: %v", got)
+	}
+
+	// The geometry survives saving and mirroring
+	path := filepath.Join(dir, "saved.klf")
+	if err := layout.SaveToFile(path); err != nil {
+		t.Fatalf("could not save: %v", err)
+	}
+	saved, err := NewLayoutFromFile("saved", path)
+	if err != nil {
+		t.Fatalf("could not reload: %v", err)
+	}
+	if saved.Thumbs == nil || *saved.Thumbs != *layout.Thumbs {
+		t.Errorf("saved geometry %v, want %v", saved.Thumbs, layout.Thumbs)
+	}
+	saved.FlipHorizontal()
+	if got := saved.MustDistance(41, 39).Distance; got != 3 {
+		t.Errorf("mirrored distance 41-39 = %v, want 3", got)
+	}
+
+	if _, err := NewLayoutFromFile("bad", filepath.Join(dir, "bad.klf")); err == nil {
+		t.Error("expected an error for an invalid thumb geometry")
+	}
+
+	// A geometry equal to the default is not recorded
+	layout.SetThumbGeometry(DefaultThumbGeometry(ROWSTAG))
+	if layout.Thumbs != nil || layout.KeyPairDistances != &keyDistances[ROWSTAG] {
+		t.Error("default geometry should use the shared distances")
+	}
+}
+
+func TestKeyGeometry(t *testing.T) {
+	keys, pairs := KeyGeometry(ANGLEMOD)
+	if len(keys) != 42 || keys[25].Finger != LR || keys[37].Finger != LT {
+		t.Fatalf("angle-mod fingers of r2c1 and t1 = %v, %v, want LR and LT", keys[25].Finger, keys[37].Finger)
+	}
+	// 18 main keys and 3 thumb keys per hand
+	if want := 2 * (18*17/2 + 3); len(pairs) != want {
+		t.Errorf("got %d key pairs, want %d", len(pairs), want)
+	}
+	for _, p := range pairs {
+		if p.Key1.Index >= p.Key2.Index || p.Key1.Hand != p.Key2.Hand {
+			t.Errorf("pair %d-%d should be ordered and on one hand", p.Key1.Index, p.Key2.Index)
+		}
+		if p.KeyPairDistance != keyDistances[ANGLEMOD][KeyPair{p.Key1.Index, p.Key2.Index}] {
+			t.Errorf("pair %d-%d has distance %+v", p.Key1.Index, p.Key2.Index, p.KeyPairDistance)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package keycraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kleLayoutTypeNote is the line of the notes of a KLE file that gives the layout type, such
+// as "layout type: colstag". The other settings of the layout are notes lines as well.
+const kleLayoutTypeNote = "layout type"
+
+// kleSpace is the legend of the space key in KLE files.
+const kleSpace = "␣"
+
+// WriteKLE writes the layout as a keyboard-layout-editor.com (KLE) JSON file, which can be
+// uploaded there to draw the layout: the 3 rows of 12 keys, split between the hands, and
+// the 6 thumb keys. Keys with a shifted character have it as their top legend. The layout
+// type and the settings of the layout that keys can't show, such as a magic key, are
+// written to the notes of the file, for NewLayoutFromKLE.
+func (sl *SplitLayout) WriteKLE(w io.Writer) error {
+	notes := []string{kleLayoutTypeNote + ": " + LayoutTypeStrings[sl.LayoutType]}
+	if sl.Thumbs != nil {
+		notes = append(notes, fmt.Sprintf("thumbs: %s", sl.Thumbs))
+	}
+	if sl.ShiftHolder != HoldOppositePinky {
+		notes = append(notes, "shift-finger: "+sl.ShiftHolder.String())
+	}
+	if sl.Magic != nil {
+		notes = append(notes, "magic: "+sl.Magic.String())
+	}
+
+	shiftOf := make(map[rune]rune, len(sl.Shifted))
+	for shift, base := range sl.Shifted {
+		shiftOf[base] = shift
+	}
+	legend := func(r rune) string {
+		var base string
+		switch {
+		case r == 0:
+			return ""
+		case r == ' ':
+			base = kleSpace
+		case unicode.IsLower(r):
+			base = string(unicode.ToUpper(r))
+		default:
+			base = string(r)
+		}
+		if shift, ok := shiftOf[r]; ok {
+			return string(shift) + "\n" + base
+		}
+		return base
+	}
+
+	rows := []any{map[string]string{"name": sl.Name, "notes": strings.Join(notes, "\n")}}
+	for row := range 3 {
+		var keys []any
+		for col := range 12 {
+			if col == 6 {
+				keys = append(keys, map[string]float64{"x": 1}) // Gap between the hands
+			}
+			keys = append(keys, legend(sl.Runes[12*row+col]))
+		}
+		rows = append(rows, keys)
+	}
+	thumbs := []any{map[string]float64{"x": 3}}
+	for col := range 6 {
+		if col == 3 {
+			thumbs = append(thumbs, map[string]float64{"x": 1})
+		}
+		thumbs = append(thumbs, legend(sl.Runes[36+col]))
+	}
+	rows = append(rows, thumbs)
+
+	// One row per line, as in the raw data of keyboard-layout-editor.com
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("could not encode layout: %w", err)
+		}
+		lines[i] = string(data)
+	}
+	_, err := fmt.Fprintf(w, "[\n%s\n]\n", strings.Join(lines, ",\n"))
+	return err
+}
+
+// SaveToKLE saves the layout to a KLE JSON file (see WriteKLE).
+func (sl *SplitLayout) SaveToKLE(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create layout file: %w", err)
+	}
+	defer CloseFile(file)
+
+	return sl.WriteKLE(file)
+}
+
+// NewLayoutFromKLE loads a layout from a keyboard-layout-editor.com (KLE) JSON file, as
+// downloaded from the site. The keys must be in 4 rows: 3 rows of 12 keys, then 6 thumb
+// keys; the positions and sizes of the keys are ignored. The legend of a key is its
+// character, in lowercase for letters, and a key with two legends types the top one with
+// Shift. The layout type is the "layout type:" line of the notes (see WriteKLE), or the
+// given layout type if the notes have none.
+func NewLayoutFromKLE(name, path string, layoutType LayoutType) (*SplitLayout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %w", err)
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid KLE file %s: %w", path, err)
+	}
+
+	typeName := LayoutTypeStrings[layoutType]
+	var settings []string
+	var rows [][]string
+	for _, item := range items {
+		var keys []json.RawMessage
+		if err := json.Unmarshal(item, &keys); err != nil {
+			// Not a row, but the metadata of the keyboard
+			var meta struct {
+				Notes string `json:"notes"`
+			}
+			if err := json.Unmarshal(item, &meta); err != nil {
+				return nil, fmt.Errorf("invalid KLE file %s: %w", path, err)
+			}
+			for line := range strings.Lines(meta.Notes) {
+				key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+				if !ok {
+					continue
+				}
+				if strings.EqualFold(strings.TrimSpace(key), kleLayoutTypeNote) {
+					typeName = strings.TrimSpace(value)
+					continue
+				}
+				settings = append(settings, strings.TrimSpace(line))
+			}
+			continue
+		}
+
+		var legends []string
+		for _, key := range keys {
+			var legend string
+			if json.Unmarshal(key, &legend) == nil {
+				legends = append(legends, legend)
+			}
+		}
+		rows = append(rows, legends)
+	}
+
+	expectedKeys := []int{12, 12, 12, 6}
+	if len(rows) != len(expectedKeys) {
+		return nil, fmt.Errorf("invalid KLE file %s: has %d rows of keys, expected 4 (3 rows of 12 keys and 6 thumb keys)", path, len(rows))
+	}
+	var klf strings.Builder
+	var shifted []string
+	klf.WriteString(typeName + "\n")
+	for row, legends := range rows {
+		if len(legends) != expectedKeys[row] {
+			return nil, fmt.Errorf("invalid KLE file %s: row %d has %d keys, expected %d",
+				path, row+1, len(legends), expectedKeys[row])
+		}
+		tokens := make([]string, len(legends))
+		for col, legend := range legends {
+			base, shift := kleLegendRunes(legend)
+			tokens[col] = klfToken(base)
+			if shift != 0 {
+				shifted = append(shifted, string([]rune{base, shift}))
+			}
+		}
+		klf.WriteString(strings.Join(tokens, " ") + "\n")
+	}
+	if len(shifted) > 0 {
+		settings = append(settings, "shift: "+strings.Join(shifted, " "))
+	}
+	for _, setting := range settings {
+		klf.WriteString(setting + "\n")
+	}
+
+	return newLayoutFromReader(name, path, strings.NewReader(klf.String()))
+}
+
+// kleLegendRunes returns the character of a key with the given KLE legend, and the
+// character typed on it with Shift, if any. The legend lines are the top legend and the
+// bottom legend; a single legend, such as a letter, is the character of the key.
+func kleLegendRunes(legend string) (base, shift rune) {
+	var lines []string
+	for line := range strings.SplitSeq(legend, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	toRune := func(s string) rune {
+		if s == kleSpace || strings.EqualFold(s, "space") {
+			return ' '
+		}
+		r := []rune(s)
+		if len(r) != 1 {
+			return utf8.RuneError
+		}
+		return r[0]
+	}
+	switch len(lines) {
+	case 0:
+		return 0, 0
+	case 1:
+		return unicode.ToLower(toRune(lines[0])), 0
+	default:
+		base = toRune(lines[len(lines)-1])
+		return unicode.ToLower(base), toRune(lines[0])
+	}
+}
+
+// klfToken returns the token of a character in a .klf file.
+func klfToken(r rune) string {
+	switch r {
+	case 0:
+		return "~"
+	case ' ':
+		return "_"
+	case '~':
+		return "~~"
+	case '_':
+		return "__"
+	case '#':
+		return "##"
+	}
+	return string(r)
+}
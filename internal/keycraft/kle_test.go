@@ -0,0 +1,55 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKLE_RoundTrip(t *testing.T) {
+	sl := Must(writeShiftedLayout(t, "\nshift: us\nshift-finger: left-thumb\nmagic: / repeat ui\n"))
+	path := filepath.Join(t.TempDir(), "a.json")
+	Must0(sl.SaveToKLE(path))
+
+	got := Must(NewLayoutFromKLE("a", path, ROWSTAG))
+	if got.LayoutType != sl.LayoutType || got.Runes != sl.Runes {
+		t.Errorf("layout = %v %q, want %v %q", got.LayoutType, got.Runes, sl.LayoutType, sl.Runes)
+	}
+	if got.ShiftedString() != sl.ShiftedString() {
+		t.Errorf("shifted = %q, want %q", got.ShiftedString(), sl.ShiftedString())
+	}
+	if got.ShiftHolder != sl.ShiftHolder || got.Magic.String() != sl.Magic.String() {
+		t.Errorf("settings = %v %v, want %v %v", got.ShiftHolder, got.Magic, sl.ShiftHolder, sl.Magic)
+	}
+}
+
+func TestNewLayoutFromKLE(t *testing.T) {
+	row := func(legends string) string {
+		return `["` + strings.Join(strings.Split(legends, ""), `","`) + `"]`
+	}
+	// A board as drawn on keyboard-layout-editor.com: key properties, a two-legend key and
+	// no notes, so the layout type is the given one
+	kle := `[{"name":"test"},` +
+		`[{"a":4},"Q","W","E","R","T","Y",{"x":1},"U","I","O","P","[","!\n1"],` +
+		row("ASDFGHJKL;'~") + "," + row("ZXCVBNM,./-=") + "," +
+		`[{"x":3},"","␣","",{"x":1},"","",""]]`
+	path := filepath.Join(t.TempDir(), "a.json")
+	Must0(os.WriteFile(path, []byte(kle), 0644))
+
+	sl := Must(NewLayoutFromKLE("a", path, COLSTAG))
+	if sl.LayoutType != COLSTAG {
+		t.Errorf("layout type = %v, want colstag", sl.LayoutType)
+	}
+	if sl.Runes[0] != 'q' || sl.Runes[11] != '1' || sl.Runes[23] != '~' || sl.Runes[37] != ' ' || sl.Runes[36] != 0 {
+		t.Errorf("runes = %q", sl.Runes)
+	}
+	if sl.Shifted['!'] != '1' {
+		t.Errorf("shifted = %q, want ! on 1", sl.ShiftedString())
+	}
+
+	Must0(os.WriteFile(path, []byte(`[["Q","W"]]`), 0644))
+	if _, err := NewLayoutFromKLE("a", path, COLSTAG); err == nil || !strings.Contains(err.Error(), "rows") {
+		t.Errorf("error = %v, want an invalid number of rows", err)
+	}
+}
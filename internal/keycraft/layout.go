@@ -9,6 +9,7 @@ package keycraft
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"maps"
 	"math"
 	"os"
@@ -82,6 +83,17 @@ type SplitLayout struct {
 	KeyInfos         [95]KeyInfo                  // fast lookup for ASCII runes (32-126, indexed by rune-32)
 	KeyInfoValid     [95]bool                     // validity bitmap for KeyInfos array
 	KeyPairDistances *map[KeyPair]KeyPairDistance // cache of distances between key index pairs
+	Thumbs           *ThumbGeometry               // thumb geometry from the layout file (nil = default for LayoutType)
+	Shifted          map[rune]rune                // shifted rune -> base rune typed with Shift (nil = none)
+	ShiftHolder      ModifierHolder               // finger that holds Shift for shifted runes
+	shiftKey         string                       // Shifted in a canonical form, for cache keys
+	Magic            *MagicKey                    // magic key and its rules (nil = none)
+	Provenance       *Provenance                  // how optimize produced the layout (nil = unknown)
+	Author           string                       // author of the layout, from the author setting ("" = unknown)
+	Tags             []string                     // tags of the layout, from the tags setting; the first names its family
+	Board            string                       // board preset the layout was made for, from the board setting ("" = unknown)
+	Variant          BoardVariant                 // ANSI or ISO variant of a row-staggered board, from the variant setting
+	NumberRow        *[12]rune                    // runes of the number row above the top row, from the numbers setting (nil = none)
 	SFBs             []SFBInfo                    // cache of notable same-finger bigram key-pairs
 	LSBs             []LSBInfo                    // cache of notable lateral-stretch bigram key-pairs
 	FScissors        []ScissorInfo                // cache of notable full scissor key-pairs
@@ -111,6 +123,7 @@ func NewSplitLayout(name string, layoutType LayoutType, runes [42]rune) *SplitLa
 		Runes:            runes,
 		RuneInfo:         runeInfo,
 		KeyPairDistances: &keyDistances[layoutType],
+		ShiftHolder:      HoldOppositePinky,
 	}
 
 	if name == "" {
@@ -127,12 +140,30 @@ func NewSplitLayout(name string, layoutType LayoutType, runes [42]rune) *SplitLa
 		}
 	}
 
-	// pre-calculate caches
+	sl.initKeyCaches()
+	return sl
+}
+
+// initKeyCaches pre-calculates the caches of the key pairs of the used keys of the layout.
+func (sl *SplitLayout) initKeyCaches() {
 	sl.initSFBs()
 	sl.initLSBs()
 	sl.initFScissors()
 	sl.initHScissors()
-	return sl
+}
+
+// SetThumbGeometry replaces the default thumb geometry of the layout type, recomputing
+// key distances and the derived caches.
+func (sl *SplitLayout) SetThumbGeometry(g ThumbGeometry) {
+	if g == DefaultThumbGeometry(sl.LayoutType) {
+		sl.Thumbs = nil
+		sl.KeyPairDistances = sl.presetDistances()
+	} else {
+		sl.Thumbs = &g
+		distances := newKeyDistances(sl.geometry(), sl.LayoutType, sl.Variant, g)
+		sl.KeyPairDistances = &distances
+	}
+	sl.initKeyCaches()
 }
 
 // Clone creates a deep copy of the SplitLayout.
@@ -154,6 +185,17 @@ func (sl *SplitLayout) Clone() *SplitLayout {
 		KeyInfos:         sl.KeyInfos,         // Array is copied by value
 		KeyInfoValid:     sl.KeyInfoValid,     // Array is copied by value
 		KeyPairDistances: sl.KeyPairDistances, // Shared reference to immutable data
+		Thumbs:           sl.Thumbs,           // Shared reference to immutable data
+		Shifted:          sl.Shifted,          // Shared reference to immutable data
+		ShiftHolder:      sl.ShiftHolder,      // Value copy
+		shiftKey:         sl.shiftKey,         // Derived from Shifted
+		Magic:            sl.Magic,            // Shared reference to immutable data
+		Provenance:       sl.Provenance,       // Shared reference to immutable data
+		Author:           sl.Author,           // Value copy
+		Tags:             sl.Tags,             // Shared - not modified after loading
+		Board:            sl.Board,            // Value copy
+		Variant:          sl.Variant,          // Value copy
+		NumberRow:        sl.NumberRow,        // Shared reference to immutable data
 		SFBs:             sl.SFBs,             // Shared - derived data, not modified
 		LSBs:             sl.LSBs,             // Shared - derived data, not modified
 		FScissors:        sl.FScissors,        // Shared - derived data, not modified
@@ -166,33 +208,40 @@ func (sl *SplitLayout) Clone() *SplitLayout {
 // GetKeyInfo returns the KeyInfo for a given rune and a boolean indicating whether the rune exists in the layout.
 // For ASCII printable runes (32-126), it uses direct array indexing with validity bitmap for O(1) lookup.
 // For non-ASCII runes or control characters, it falls back to the RuneInfo map.
+// A shifted rune, or an uppercase letter without a key of its own, returns the KeyInfo of
+// its base key.
 func (sl *SplitLayout) GetKeyInfo(r rune) (KeyInfo, bool) {
 	if r >= 32 && r < 127 {
 		idx := r - 32
 		if sl.KeyInfoValid[idx] {
 			return sl.KeyInfos[idx], true
 		}
-		return KeyInfo{}, false
+		return sl.shiftedKeyInfo(r)
+	}
+	if ki, ok := sl.RuneInfo[r]; ok {
+		return ki, true
 	}
-	ki, ok := sl.RuneInfo[r]
-	return ki, ok
+	return sl.shiftedKeyInfo(r)
+}
+
+// shiftedKeyInfo returns the KeyInfo of the base key of a shifted rune or uppercase letter.
+func (sl *SplitLayout) shiftedKeyInfo(r rune) (KeyInfo, bool) {
+	if base := sl.baseRune(r); base != r {
+		return sl.GetKeyInfo(base)
+	}
+	return KeyInfo{}, false
 }
 
 // Swap exchanges the runes at two key positions and updates the RuneInfo map and KeyInfos array accordingly.
 // This is the fundamental operation for layout optimization algorithms.
 func (sl *SplitLayout) Swap(idx1, idx2 uint8) {
-	if idx1 >= 42 || idx2 >= 42 {
-		panic(fmt.Sprintf("swap indices out of bounds: %d, %d", idx1, idx2))
-	}
+	sl.checkSwap(idx1, idx2)
 	if idx1 == idx2 {
 		return
 	}
 
 	// Swap runes in the array
 	r1, r2 := sl.Runes[idx1], sl.Runes[idx2]
-	if r1 == 0 || r2 == 0 {
-		panic(fmt.Sprintf("can't swap unused key at index %d or %d", idx1, idx2))
-	}
 
 	// Update Runes slice
 	sl.Runes[idx1], sl.Runes[idx2] = r2, r1
@@ -216,14 +265,24 @@ func (sl *SplitLayout) Swap(idx1, idx2 uint8) {
 	// still unused, and used keys are still used.
 }
 
+// checkSwap panics if the keys at two positions can't be swapped.
+func (sl *SplitLayout) checkSwap(idx1, idx2 uint8) {
+	if idx1 >= 42 || idx2 >= 42 {
+		panic(fmt.Sprintf("swap indices out of bounds: %d, %d", idx1, idx2))
+	}
+	if idx1 != idx2 && (sl.Runes[idx1] == 0 || sl.Runes[idx2] == 0) {
+		panic(fmt.Sprintf("can't swap unused key at index %d or %d", idx1, idx2))
+	}
+}
+
 func (sl *SplitLayout) String() string {
 	var sb strings.Builder
 
 	writeRune := func(r rune) {
-		switch r {
-		case 0:
+		switch {
+		case r == 0 || isSpillPlaceholder(r):
 			sb.WriteRune(' ')
-		case ' ':
+		case r == ' ':
 			sb.WriteRune('_')
 		default:
 			sb.WriteRune(r)
@@ -266,7 +325,20 @@ func (sl *SplitLayout) String() string {
 // File format:
 //   - First non-comment line: layout type ("rowstag", "anglemod", "ortho", or "colstag")
 //   - Next 3 lines: 12 keys each (6 left, 6 right) for main rows
-//   - Last line: 6 thumb keys (3 left, 3 right)
+//   - Next line: 6 thumb keys (3 left, 3 right)
+//   - Optional line: "thumbs:" followed by the 6 thumb key positions as distance@angle
+//     (see ParseThumbGeometry), replacing the default thumb geometry of the layout type
+//   - Optional line: "shift:" followed by the characters typed with Shift, as pairs of a
+//     base key and its shifted character or "us" (see SetShiftedFromString)
+//   - Optional line: "shift-finger:" followed by the finger holding Shift, such as
+//     "opposite-pinky" (the default) or "left-thumb"
+//   - Optional line: "magic:" followed by the character of a magic key and its rules, such
+//     as "* repeat" for a repeat key (see SetMagicFromString)
+//   - Optional line: "board:" followed by the name of the board preset the layout was made
+//     for, such as "ferris"; the layout must fit the keys of the board, and is analysed with
+//     its geometry (see SetBoard)
+//   - Optional line: "numbers:" followed by the 12 keys of a number row above the top row,
+//     such as "~ 1 2 3 4 5 6 7 8 9 0 ~" (see SetNumberRow)
 //   - Lines starting with '#' are comments
 //   - Empty lines are ignored
 //
@@ -280,6 +352,18 @@ func (sl *SplitLayout) String() string {
 // Each character can appear only once in the layout.
 // Returns an error if the file format is invalid or contains duplicate characters.
 func NewLayoutFromFile(name, path string) (*SplitLayout, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %w", err)
+	}
+	defer CloseFile(file)
+
+	return newLayoutFromReader(name, path, file)
+}
+
+// newLayoutFromReader reads a layout in the .klf format of NewLayoutFromFile. The path is
+// the source of the layout in error messages.
+func newLayoutFromReader(name, path string, reader io.Reader) (*SplitLayout, error) {
 	keyMap := map[string]rune{
 		"~":  rune(0),
 		"_":  rune(' '),
@@ -288,13 +372,7 @@ func NewLayoutFromFile(name, path string) (*SplitLayout, error) {
 		"##": rune('#'),
 	}
 
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("could not open file: %w", err)
-	}
-	defer CloseFile(file)
-
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(reader)
 
 	// Parse layout type from first line
 	layoutTypeStr, err := readLine(scanner)
@@ -358,11 +436,119 @@ func NewLayoutFromFile(name, path string) (*SplitLayout, error) {
 		}
 	}
 
+	// Optional settings; other lines after the thumb row are ignored as before
+	var thumbs *ThumbGeometry
+	var shifted, shiftFinger, magic, author, board, variant string
+	var tags []string
+	var numberRow *[12]rune
+	var provenance Provenance
+	hasProvenance := false
+	for {
+		line, err := readLine(scanner)
+		if err != nil {
+			break
+		}
+		key, value, _ := strings.Cut(line, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		found, err := provenance.setSetting(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid provenance in %s: %w", path, err)
+		}
+		hasProvenance = hasProvenance || found
+		switch key {
+		case "thumbs":
+			g, err := ParseThumbGeometry(strings.ToLower(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid thumb geometry in %s: %w", path, err)
+			}
+			thumbs = &g
+		case "shift":
+			shifted = strings.TrimSpace(shifted + " " + value)
+		case "shift-finger":
+			shiftFinger = strings.ToLower(strings.TrimSpace(value))
+		case "magic":
+			magic = strings.TrimSpace(value)
+		case "author":
+			author = strings.TrimSpace(value)
+		case "tags":
+			tags = ParseTags(value)
+		case "board":
+			board = strings.ToLower(strings.TrimSpace(value))
+		case "variant":
+			variant = value
+		case "numbers":
+			keys := strings.Fields(value)
+			if len(keys) != 12 {
+				return nil, fmt.Errorf("invalid number row in %s: has %d keys, expected 12", path, len(keys))
+			}
+			numberRow = &[12]rune{}
+			for col, key := range keys {
+				r, ok := keyMap[strings.ToLower(key)]
+				if !ok {
+					if len([]rune(key)) != 1 {
+						return nil, fmt.Errorf("invalid number row in %s: key '%s' must have 1 character", path, key)
+					}
+					r = []rune(key)[0]
+				}
+				numberRow[col] = r
+			}
+		}
+	}
+
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("could not read file: %w", err)
 	}
 
-	return NewSplitLayout(name, layoutType, runeArray), nil
+	sl := NewSplitLayout(name, layoutType, runeArray)
+	if thumbs != nil {
+		sl.SetThumbGeometry(*thumbs)
+	}
+	if variant != "" {
+		v, err := ParseBoardVariant(variant)
+		if err == nil {
+			err = sl.SetVariant(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid variant in %s: %w", path, err)
+		}
+	}
+	if shifted != "" {
+		if err := sl.SetShiftedFromString(shifted); err != nil {
+			return nil, fmt.Errorf("invalid shifted characters in %s: %w", path, err)
+		}
+	}
+	if shiftFinger != "" {
+		holder := slices.Index(modifierHolderNames, shiftFinger)
+		if holder < 0 {
+			return nil, fmt.Errorf("invalid shift finger %q in %s; must be one of: %s",
+				shiftFinger, path, strings.Join(modifierHolderNames, ", "))
+		}
+		sl.ShiftHolder = ModifierHolder(holder)
+	}
+	if magic != "" {
+		if err := sl.SetMagicFromString(magic); err != nil {
+			return nil, fmt.Errorf("invalid magic key in %s: %w", path, err)
+		}
+	}
+	if hasProvenance {
+		sl.Provenance = &provenance
+	}
+	sl.Author, sl.Tags = author, tags
+	if numberRow != nil {
+		if err := sl.SetNumberRow(*numberRow); err != nil {
+			return nil, fmt.Errorf("invalid number row in %s: %w", path, err)
+		}
+	}
+	if board != "" {
+		preset, err := ParseBoardPreset(board)
+		if err == nil {
+			err = sl.SetBoard(preset)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid board in %s: %w", path, err)
+		}
+	}
+	return sl, nil
 }
 
 // generateLayoutName creates an auto-generated name: _<chars>-<random>
@@ -395,6 +581,18 @@ func (sl *SplitLayout) generateLayoutName() string {
 
 // SaveToFile saves the layout to a .klf file in the standard format.
 func (sl *SplitLayout) SaveToFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create layout file: %w", err)
+	}
+	defer CloseFile(file)
+
+	return sl.WriteKlf(file)
+}
+
+// WriteKlf writes the layout in the .klf format of SaveToFile, such as to show what would be
+// saved.
+func (sl *SplitLayout) WriteKlf(w io.Writer) error {
 	inverseKeyMap := map[rune]string{
 		rune(0): "~",
 		' ':     "_",
@@ -403,13 +601,7 @@ func (sl *SplitLayout) SaveToFile(path string) error {
 		'#':     "##",
 	}
 
-	file, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("could not create layout file: %w", err)
-	}
-	defer CloseFile(file)
-
-	writer := bufio.NewWriter(file)
+	writer := bufio.NewWriter(w)
 	defer FlushWriter(writer)
 
 	writeRune := func(r rune) {
@@ -455,9 +647,69 @@ func (sl *SplitLayout) SaveToFile(path string) error {
 		}
 	}
 
+	// Write optional settings
+	var settings []string
+	if sl.Thumbs != nil {
+		settings = append(settings, fmt.Sprintf("thumbs: %s", sl.Thumbs))
+	}
+	if sl.Shifted != nil {
+		settings = append(settings, "shift: "+sl.ShiftedString())
+	}
+	if sl.ShiftHolder != HoldOppositePinky {
+		settings = append(settings, "shift-finger: "+sl.ShiftHolder.String())
+	}
+	if sl.Magic != nil {
+		settings = append(settings, "magic: "+sl.Magic.String())
+	}
+	if sl.Author != "" {
+		settings = append(settings, "author: "+sl.Author)
+	}
+	if len(sl.Tags) > 0 {
+		settings = append(settings, "tags: "+strings.Join(sl.Tags, ", "))
+	}
+	if sl.Board != "" {
+		settings = append(settings, "board: "+sl.Board)
+	}
+	if sl.Variant != VariantNone {
+		settings = append(settings, "variant: "+string(sl.Variant))
+	}
+	if sl.NumberRow != nil {
+		keys := make([]string, len(sl.NumberRow))
+		for col, r := range sl.NumberRow {
+			if str, ok := inverseKeyMap[r]; ok {
+				keys[col] = str
+			} else {
+				keys[col] = string(r)
+			}
+		}
+		settings = append(settings, "numbers: "+strings.Join(keys, " "))
+	}
+	if sl.Provenance != nil {
+		settings = append(settings, sl.Provenance.settings()...)
+	}
+	for _, setting := range settings {
+		_, _ = fmt.Fprintf(writer, "\n%s", setting)
+	}
+	if len(settings) > 0 {
+		_, _ = fmt.Fprintln(writer)
+	}
+
 	return nil
 }
 
+// ParseTags parses the comma-separated tags of a tags setting, such as "colemak, mod", into
+// lowercase tags without duplicates.
+func ParseTags(value string) []string {
+	var tags []string
+	for part := range strings.SplitSeq(value, ",") {
+		tag := strings.ToLower(strings.TrimSpace(part))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // readLine reads the next non-empty, non-comment line from the scanner.
 // Returns an error if EOF is reached without finding a valid line.
 func readLine(scanner *bufio.Scanner) (string, error) {
@@ -525,6 +777,26 @@ type LSBInfo struct {
 	KeyIdx1     uint8
 	KeyIdx2     uint8
 	ColDistance float64
+	Reach       bool // Only a lateral stretch by the ring to inner index reach (metric version 3)
+}
+
+// DefaultLSBReach is the distance in key units, column stagger included, from a ring finger key
+// to an inner index column key from which the bigram is a lateral stretch on COLSTAG and ORTHO
+// boards. Without row stagger, these keys are never more than 3 columns apart, short of the 3.5
+// of the ring-index stretch, yet reaching the inner column from another row stretches the hand
+// as much. A geometry file can set it with lsb-reach.
+const DefaultLSBReach = 3.5
+
+// lsbReach returns the ring to inner index distance of a lateral stretch for the layout type
+// and board geometry (nil = none), or 0 if the layout type has no such edge case.
+func lsbReach(layoutType LayoutType, g *BoardGeometry) float64 {
+	if layoutType != COLSTAG && layoutType != ORTHO {
+		return 0
+	}
+	if g != nil && g.LSBReach > 0 {
+		return g.LSBReach
+	}
+	return DefaultLSBReach
 }
 
 // initLSBs identifies all lateral-stretch bigram key pairs in the layout.
@@ -541,6 +813,9 @@ func (sl *SplitLayout) initLSBs() {
 		{RP, RR}: 2.0, {RR, RP}: 2.0,
 	}
 
+	ringIndex := makePairs([][2]uint8{{LR, LI}, {LI, LR}, {RR, RI}, {RI, RR}})
+	reach := lsbReach(sl.LayoutType, sl.geometry())
+
 	sl.LSBs = make([]LSBInfo, 0, 72)
 
 	for key1, rune1 := range sl.Runes {
@@ -568,23 +843,34 @@ func (sl *SplitLayout) initLSBs() {
 				continue
 			}
 
-			// Check if distance exceeds threshold
-			dx := sl.MustDistance(uint8(key1), uint8(key2)).ColDist
-			if dx >= minHorDistance {
-				sl.LSBs = append(sl.LSBs, LSBInfo{uint8(key1), uint8(key2), dx})
+			// Check if distance exceeds threshold, or, on boards without row stagger, if a ring
+			// finger reaches far enough to the inner index column
+			kp := sl.MustDistance(uint8(key1), uint8(key2))
+			inner := ri1.Column == 5 || ri1.Column == 6 || ri2.Column == 5 || ri2.Column == 6
+			if kp.ColDist >= minHorDistance {
+				sl.LSBs = append(sl.LSBs, LSBInfo{uint8(key1), uint8(key2), kp.ColDist, false})
+			} else if reach > 0 && ringIndex[fingerPair] && inner && kp.Distance >= reach {
+				sl.LSBs = append(sl.LSBs, LSBInfo{uint8(key1), uint8(key2), kp.ColDist, true})
 			}
 		}
 	}
 
-	// Add geometry-specific edge cases for row-staggered layouts
+	// Add geometry-specific edge cases for row-staggered layouts; those of boards without row
+	// stagger follow from the column offsets (see DefaultLSBReach)
 	switch sl.LayoutType {
 	case ROWSTAG:
-		sl.LSBs = append(sl.LSBs, LSBInfo{1, 26, 1.75})
-		sl.LSBs = append(sl.LSBs, LSBInfo{2, 27, 1.75})
-		sl.LSBs = append(sl.LSBs, LSBInfo{3, 28, 1.75})
+		sl.LSBs = append(sl.LSBs, LSBInfo{1, 26, 1.75, false})
+		sl.LSBs = append(sl.LSBs, LSBInfo{2, 27, 1.75, false})
+		sl.LSBs = append(sl.LSBs, LSBInfo{3, 28, 1.75, false})
 	case ANGLEMOD:
 		// Angle-mod only stretches middle-index in this configuration
-		sl.LSBs = append(sl.LSBs, LSBInfo{3, 28, 1.75})
+		sl.LSBs = append(sl.LSBs, LSBInfo{3, 28, 1.75, false})
+	}
+
+	// The wide Left Shift of ANSI boards moves the bottom-left key away from the ring finger
+	// on the top row, short of the ring-pinky threshold as with the edge cases above
+	if sl.Variant == VariantANSI && sl.geometry() == nil {
+		sl.LSBs = append(sl.LSBs, LSBInfo{2, 24, 1.875, false})
 	}
 }
 
@@ -744,6 +1030,13 @@ func (sl *SplitLayout) FlipHorizontal() {
 		sl.Runes[leftIdx], sl.Runes[rightIdx] = sl.Runes[rightIdx], sl.Runes[leftIdx]
 	}
 
+	// Mirror the number row, which may be shared with clones
+	if sl.NumberRow != nil {
+		mirrored := *sl.NumberRow
+		slices.Reverse(mirrored[:])
+		sl.NumberRow = &mirrored
+	}
+
 	// Rebuild RuneInfo map with updated key positions
 	sl.RuneInfo = make(map[rune]KeyInfo, len(sl.RuneInfo))
 	for idx, r := range sl.Runes {
@@ -770,6 +1063,12 @@ func (sl *SplitLayout) FlipHorizontal() {
 		}
 	}
 
+	// Mirror a custom thumb geometry along with the thumb keys
+	if sl.Thumbs != nil {
+		sl.SetThumbGeometry(sl.Thumbs.Mirrored())
+		return
+	}
+
 	// Reinitialize derived data structures
 	sl.initSFBs()
 	sl.initLSBs()
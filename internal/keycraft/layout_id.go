@@ -0,0 +1,137 @@
+package keycraft
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LayoutIDLength is the number of hex characters in a short layout identifier.
+const LayoutIDLength = 8
+
+// minLayoutIDPrefix is the shortest identifier prefix accepted when resolving layouts.
+const minLayoutIDPrefix = 4
+
+// ID returns a short, stable identifier of the layout configuration.
+// The identifier is derived from the layout type and runes only, so the same
+// layout saved under different names has the same ID, and settings such as the
+// thumbs or the number row leave it unchanged.
+func (sl *SplitLayout) ID() string {
+	key := append([]byte{byte(sl.LayoutType)}, string(sl.Runes[:])...)
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])[:LayoutIDLength]
+}
+
+// IsLayoutIDPrefix reports whether s could be a (prefix of a) layout identifier,
+// i.e. a lowercase hex string of at least 4 and at most LayoutIDLength characters.
+func IsLayoutIDPrefix(s string) bool {
+	if len(s) < minLayoutIDPrefix || len(s) > LayoutIDLength {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// LayoutIDs loads all .klf layouts in dir and returns the identifier of each,
+// keyed by layout name. Files that cannot be parsed are skipped.
+func LayoutIDs(dir string) (map[string]string, error) {
+	layouts, err := loadLayoutsInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(layouts))
+	for _, layout := range layouts {
+		ids[layout.Name] = layout.ID()
+	}
+	return ids, nil
+}
+
+// loadLayoutsInDir loads all .klf layouts in dir, sorted by name.
+// Files that cannot be parsed are skipped.
+func loadLayoutsInDir(dir string) ([]*SplitLayout, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read layout directory %s: %w", dir, err)
+	}
+
+	layouts := make([]*SplitLayout, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".klf") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		layout, err := NewLayoutFromFile(name, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		layouts = append(layouts, layout)
+	}
+	sort.Slice(layouts, func(i, j int) bool { return layouts[i].Name < layouts[j].Name })
+	return layouts, nil
+}
+
+// FindLayoutByID returns the name of the layout in dir whose identifier starts
+// with the given prefix. It returns an error if no layout or more than one
+// distinct layout matches. Identical layouts under different names count as
+// one match; the alphabetically first name is returned.
+func FindLayoutByID(dir, prefix string) (string, error) {
+	if !IsLayoutIDPrefix(prefix) {
+		return "", fmt.Errorf("invalid layout id %q: expected %d-%d hex characters",
+			prefix, minLayoutIDPrefix, LayoutIDLength)
+	}
+
+	ids, err := LayoutIDs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	matches := make(map[string][]string)
+	for name, id := range ids {
+		if strings.HasPrefix(id, prefix) {
+			matches[id] = append(matches[id], name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no layout with id %s in %s", prefix, dir)
+	case 1:
+		for _, names := range matches {
+			sort.Strings(names)
+			return names[0], nil
+		}
+	}
+	return "", fmt.Errorf("layout id %s is ambiguous: matches %d layouts", prefix, len(matches))
+}
+
+// DuplicateLayouts groups the layouts in dir by identifier and returns only the
+// groups with more than one name. Names within a group are sorted, and groups
+// are keyed by identifier.
+func DuplicateLayouts(dir string) (map[string][]string, error) {
+	ids, err := LayoutIDs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for name, id := range ids {
+		groups[id] = append(groups[id], name)
+	}
+	for id, names := range groups {
+		if len(names) < 2 {
+			delete(groups, id)
+			continue
+		}
+		sort.Strings(names)
+	}
+	return groups, nil
+}
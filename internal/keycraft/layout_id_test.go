@@ -0,0 +1,144 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLayoutKlf = `rowstag
+ ~ q w e r t  y u i o p \
+ ~ a s d f g  h j k l ; '
+ ~ z x c v b  n m , . / ~
+ ~ ~ ~  _ ~ ~
+`
+
+const testLayoutVariantKlf = `rowstag
+ ~ q w e r t  y u i o p \
+ ~ a s d f g  h j k l ; '
+ ~ x z c v b  n m , . / ~
+ ~ ~ ~  _ ~ ~
+`
+
+func writeTestLayouts(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLayoutID_IgnoresName(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"b.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+
+	a := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	b := Must(NewLayoutFromFile("b", filepath.Join(dir, "b.klf")))
+	c := Must(NewLayoutFromFile("c", filepath.Join(dir, "c.klf")))
+
+	if a.ID() != b.ID() {
+		t.Errorf("identical layouts have different ids: %s vs %s", a.ID(), b.ID())
+	}
+	if a.ID() == c.ID() {
+		t.Errorf("different layouts have the same id: %s", a.ID())
+	}
+	if len(a.ID()) != LayoutIDLength || !IsLayoutIDPrefix(a.ID()) {
+		t.Errorf("unexpected id format: %q", a.ID())
+	}
+
+	flipped := a.Clone()
+	flipped.LayoutType = ORTHO
+	if flipped.ID() == a.ID() {
+		t.Error("layout type should be part of the id")
+	}
+
+	numbered := a.Clone()
+	numbered.NumberRow = &[12]rune{'1', '2', '3'}
+	if numbered.ID() != a.ID() {
+		t.Error("the number row should not be part of the id")
+	}
+}
+
+// TestLayoutID_Stable pins the id of qwerty, so that ids of saved layouts do not change
+// between versions.
+func TestLayoutID_Stable(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"qwerty.klf": testLayoutKlf})
+	if got, want := Must(NewLayoutFromFile("qwerty", filepath.Join(dir, "qwerty.klf"))).ID(), "dd41c1f3"; got != want {
+		t.Errorf("id of qwerty = %s, want %s", got, want)
+	}
+}
+
+func TestIsLayoutIDPrefix(t *testing.T) {
+	tests := map[string]bool{
+		"dd41":      true,
+		"dd41c1f3":  true,
+		"dd4":       false, // too short
+		"dd41c1f30": false, // too long
+		"qwer":      false, // not hex
+		"DD41":      false, // uppercase
+	}
+	for s, want := range tests {
+		if got := IsLayoutIDPrefix(s); got != want {
+			t.Errorf("IsLayoutIDPrefix(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestFindLayoutByID(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"b.klf": testLayoutKlf,
+		"a.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+	id := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf"))).ID()
+
+	name, err := FindLayoutByID(dir, id[:4])
+	if err != nil {
+		t.Fatalf("FindLayoutByID failed: %v", err)
+	}
+	if name != "a" {
+		t.Errorf("expected alphabetically first duplicate %q, got %q", "a", name)
+	}
+
+	variantID := Must(NewLayoutFromFile("c", filepath.Join(dir, "c.klf"))).ID()
+	for _, unknown := range []string{"0000", "1111", "2222"} {
+		if strings.HasPrefix(id, unknown) || strings.HasPrefix(variantID, unknown) {
+			continue
+		}
+		if _, err := FindLayoutByID(dir, unknown); err == nil {
+			t.Errorf("expected error for unknown id %s", unknown)
+		}
+		break
+	}
+	if _, err := FindLayoutByID(dir, "xyz"); err == nil {
+		t.Error("expected error for invalid id")
+	}
+}
+
+func TestDuplicateLayouts(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"b.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+
+	groups, err := DuplicateLayouts(dir)
+	if err != nil {
+		t.Fatalf("DuplicateLayouts failed: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 duplicate group, got %d: %v", len(groups), groups)
+	}
+	for _, names := range groups {
+		if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+			t.Errorf("unexpected group: %v", names)
+		}
+	}
+}
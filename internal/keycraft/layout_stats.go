@@ -0,0 +1,78 @@
+package keycraft
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LayoutStatsInput captures the inputs for summarising the metrics of the layouts in a
+// directory.
+type LayoutStatsInput struct {
+	LayoutsDir string       // Directory of the layouts to summarise
+	Corpus     *Corpus      // The corpus the layouts are analysed on
+	Targets    *TargetLoads // Load targets (row, finger, pinky penalties)
+	Metrics    []string     // Metrics to summarise, in display order
+	Bins       int          // Number of bins of the histograms
+}
+
+// LayoutStats is the distribution of each metric across the layouts of a directory.
+type LayoutStats struct {
+	Layouts int           // Number of layouts
+	Metrics []MetricStats // Distribution of each metric, in the order of the input
+}
+
+// MetricStats summarises the values of a metric across layouts.
+type MetricStats struct {
+	Metric               string
+	Min, Q1, Median      float64
+	Q3, Max              float64
+	MinLayout, MaxLayout string // Layouts with the minimum and maximum value
+	Bins                 []int  // Number of layouts per bin of equal width from Min to Max
+}
+
+// ComputeLayoutStats analyses all layouts in a directory, and summarises each metric by its
+// minimum, quartiles and maximum, and a histogram of its values.
+func ComputeLayoutStats(input LayoutStatsInput) (*LayoutStats, error) {
+	if input.Bins < 1 {
+		return nil, fmt.Errorf("bins must be at least 1, got %d", input.Bins)
+	}
+	analysers, err := LoadAnalysers(input.LayoutsDir, input.Corpus, input.Targets, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not load analysers: %w", err)
+	}
+	if len(analysers) == 0 {
+		return nil, fmt.Errorf("no layouts found in %s", input.LayoutsDir)
+	}
+	// Consistent order of layouts with equal values, as they are loaded in parallel
+	sort.Slice(analysers, func(i, j int) bool { return analysers[i].Layout.Name < analysers[j].Layout.Name })
+
+	stats := &LayoutStats{Layouts: len(analysers)}
+	for _, metric := range input.Metrics {
+		values := make([]float64, len(analysers))
+		ms := MetricStats{Metric: metric, Bins: make([]int, input.Bins)}
+		for i, an := range analysers {
+			values[i] = an.Metrics[metric]
+			if i == 0 || values[i] < ms.Min {
+				ms.Min, ms.MinLayout = values[i], an.Layout.Name
+			}
+			if i == 0 || values[i] > ms.Max {
+				ms.Max, ms.MaxLayout = values[i], an.Layout.Name
+			}
+		}
+		for _, v := range values {
+			bin := 0
+			if ms.Max > ms.Min {
+				bin = min(int((v-ms.Min)/(ms.Max-ms.Min)*float64(input.Bins)), input.Bins-1)
+			}
+			ms.Bins[bin]++
+		}
+		sort.Float64s(values)
+		ms.Median = Median(values)
+		ms.Q1, ms.Q3 = ms.Median, ms.Median
+		if len(values) > 1 {
+			ms.Q1, ms.Q3 = Quartiles(values)
+		}
+		stats.Metrics = append(stats.Metrics, ms)
+	}
+	return stats, nil
+}
@@ -0,0 +1,40 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestComputeLayoutStats checks the quartiles, extremes and histogram of a metric across the
+// layouts of a directory.
+func TestComputeLayoutStats(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"b.klf": testLayoutVariantKlf,
+		"c.klf": testLayoutKlf,
+	})
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	stats := Must(ComputeLayoutStats(LayoutStatsInput{LayoutsDir: dir, Corpus: corpus, Metrics: []string{"SFB", "ALT"}, Bins: 4}))
+
+	if stats.Layouts != 3 || len(stats.Metrics) != 2 || stats.Metrics[0].Metric != "SFB" {
+		t.Fatalf("got %d layouts and %d metrics, want 3 layouts and SFB, ALT", stats.Layouts, len(stats.Metrics))
+	}
+	a := NewAnalyser(Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf"))), corpus, nil).Metrics["SFB"]
+	b := NewAnalyser(Must(NewLayoutFromFile("b", filepath.Join(dir, "b.klf"))), corpus, nil).Metrics["SFB"]
+	sfb := stats.Metrics[0]
+	if sfb.Min != min(a, b) || sfb.Max != max(a, b) || sfb.Median != a {
+		t.Errorf("SFB min/median/max = %g/%g/%g, want %g/%g/%g", sfb.Min, sfb.Median, sfb.Max, min(a, b), a, max(a, b))
+	}
+	total := 0
+	for _, n := range sfb.Bins {
+		total += n
+	}
+	if len(sfb.Bins) != 4 || total != 3 {
+		t.Errorf("SFB histogram %v, want 4 bins with 3 layouts", sfb.Bins)
+	}
+
+	if _, err := ComputeLayoutStats(LayoutStatsInput{LayoutsDir: t.TempDir(), Corpus: corpus, Bins: 4}); err == nil {
+		t.Error("expected an error for a directory without layouts")
+	}
+}
@@ -0,0 +1,78 @@
+package keycraft
+
+import "testing"
+
+// qwertyRunes returns the runes of qwerty on the 42 key positions.
+func qwertyRunes() [42]rune {
+	var runes [42]rune
+	for i, r := range "1qwertyuiop[" + "2asdfghjkl;'" + "3zxcvbnm,./4" {
+		runes[i] = r
+	}
+	runes[39] = ' '
+	return runes
+}
+
+// hasLSB reports whether the key pair is a lateral stretch of the layout.
+func hasLSB(sl *SplitLayout, k1, k2 uint8) bool {
+	for _, lsb := range sl.LSBs {
+		if lsb.KeyIdx1 == k1 && lsb.KeyIdx2 == k2 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInitLSBs_NoRowStagger(t *testing.T) {
+	// Key indices: w=2, e=3, t=5, s=14, g=17, x=26, v=28, b=29
+	for _, lt := range []LayoutType{COLSTAG, ORTHO} {
+		sl := NewSplitLayout("test", lt, qwertyRunes())
+
+		// Ring to inner index across two rows reaches past DefaultLSBReach
+		for _, pair := range [][2]uint8{{2, 29}, {29, 2}, {26, 5}, {5, 26}} {
+			if !hasLSB(sl, pair[0], pair[1]) {
+				t.Errorf("%v: %c%c should be a lateral stretch", lt, sl.Runes[pair[0]], sl.Runes[pair[1]])
+			}
+		}
+		// Within a row or one row apart it does not, nor do other finger pairs
+		for _, pair := range [][2]uint8{{14, 17}, {2, 17}, {14, 29}, {3, 28}, {28, 3}} {
+			if hasLSB(sl, pair[0], pair[1]) {
+				t.Errorf("%v: %c%c should not be a lateral stretch", lt, sl.Runes[pair[0]], sl.Runes[pair[1]])
+			}
+		}
+	}
+
+	// Row-staggered boards keep their own edge cases
+	rowstag := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	if !hasLSB(rowstag, 3, 28) || hasLSB(rowstag, 28, 3) {
+		t.Error("rowstag: ev should be a lateral stretch in one direction only")
+	}
+}
+
+func TestInitLSBs_CalibratedReach(t *testing.T) {
+	defer UseBoardGeometry(nil)
+
+	// A shorter reach adds the ring to inner index stretches one row apart
+	g := NewBoardGeometry()
+	Must0(g.SetLSBReach("3.1"))
+	UseBoardGeometry(g)
+	sl := NewSplitLayout("test", ORTHO, qwertyRunes())
+	if !hasLSB(sl, 2, 17) || !hasLSB(sl, 17, 2) {
+		t.Error("wg should be a lateral stretch with lsb-reach 3.1")
+	}
+	if hasLSB(sl, 15, 18) {
+		t.Error("dh is on two hands and should not be a lateral stretch")
+	}
+
+	// Raising the ring column moves its top key away from the inner index column, and its
+	// bottom key closer
+	g = NewBoardGeometry()
+	Must0(g.SetColumnStagger("0, 0, -6, 0, 0, 0"))
+	UseBoardGeometry(g)
+	sl = NewSplitLayout("test", COLSTAG, qwertyRunes())
+	if !hasLSB(sl, 2, 29) {
+		t.Error("wb should be a lateral stretch with the ring column raised")
+	}
+	if hasLSB(sl, 26, 5) {
+		t.Error("xt should not be a lateral stretch with the ring column raised")
+	}
+}
@@ -0,0 +1,43 @@
+package keycraft
+
+// analyseMoves computes the learning cost of the layout relative to the baseline layout of
+// the targets:
+//   - MOVE: number of characters of the baseline that are on a different key of the layout,
+//     or missing from it
+//   - MOVE-FREQ: % of the characters of the corpus that are typed on a different key than on
+//     the baseline
+//
+// Both are 0 without a baseline. Characters that the baseline lacks do not count as moved.
+func (an *Analyser) analyseMoves() {
+	an.Metrics["MOVE"] = 0
+	an.Metrics["MOVE-FREQ"] = 0
+	if an.Targets == nil || an.Targets.Baseline == nil {
+		return
+	}
+	baseline := an.Targets.Baseline
+
+	moved := 0
+	for i, r := range baseline.Runes {
+		if r == 0 {
+			continue
+		}
+		if key, ok := an.Layout.RuneInfo[r]; !ok || key.Index != uint8(i) {
+			moved++
+		}
+	}
+	an.Metrics["MOVE"] = float64(moved)
+
+	var movedCount uint64
+	for r, cnt := range an.Corpus.Unigrams {
+		from, ok := baseline.GetKeyInfo(rune(r))
+		if !ok {
+			continue
+		}
+		if to, ok := an.Layout.GetKeyInfo(rune(r)); !ok || to.Index != from.Index {
+			movedCount += cnt
+		}
+	}
+	if an.Corpus.TotalUnigramsCount > 0 {
+		an.Metrics["MOVE-FREQ"] = 100 * float64(movedCount) / float64(an.Corpus.TotalUnigramsCount)
+	}
+}
@@ -0,0 +1,43 @@
+package keycraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyseMoves(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	layout := Must(writeShiftedLayout(t, ""))
+
+	an := NewAnalyser(layout, corpus, nil)
+	if an.Metrics["MOVE"] != 0 || an.Metrics["MOVE-FREQ"] != 0 {
+		t.Fatalf("MOVE = %v, MOVE-FREQ = %v without a baseline, want 0", an.Metrics["MOVE"], an.Metrics["MOVE-FREQ"])
+	}
+
+	targets := NewTargetLoads()
+	targets.Baseline = layout.Clone()
+	an = NewAnalyser(layout.Clone(), corpus, targets)
+	if an.Metrics["MOVE"] != 0 || an.Metrics["MOVE-FREQ"] != 0 {
+		t.Fatalf("MOVE = %v, MOVE-FREQ = %v for the baseline itself, want 0", an.Metrics["MOVE"], an.Metrics["MOVE-FREQ"])
+	}
+
+	// Swapping e and t moves two keys, and every e and t of the corpus
+	e, te := layout.RuneInfo['e'], layout.RuneInfo['t']
+	an.ApplySwap(e.Index, te.Index)
+	if an.Metrics["MOVE"] != 2 {
+		t.Errorf("MOVE = %v after one swap, want 2", an.Metrics["MOVE"])
+	}
+	moved := corpus.Unigrams[Unigram('e')] + corpus.Unigrams[Unigram('t')]
+	want := 100 * float64(moved) / float64(corpus.TotalUnigramsCount)
+	if math.Abs(an.Metrics["MOVE-FREQ"]-want) > 1e-9 {
+		t.Errorf("MOVE-FREQ = %v after one swap, want %v", an.Metrics["MOVE-FREQ"], want)
+	}
+	assertSameMetrics(t, an, NewAnalyser(an.Layout.Clone(), corpus, targets))
+
+	// Swapping them back restores the baseline
+	an.ApplySwap(e.Index, te.Index)
+	if an.Metrics["MOVE"] != 0 || an.Metrics["MOVE-FREQ"] != 0 {
+		t.Errorf("MOVE = %v, MOVE-FREQ = %v after swapping back, want 0", an.Metrics["MOVE"], an.Metrics["MOVE-FREQ"])
+	}
+}
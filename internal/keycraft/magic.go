@@ -0,0 +1,377 @@
+package keycraft
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MagicKey is a key whose character depends on the character typed before it, such as a
+// repeat key, which types the previous character again. Layouts use it to type the second
+// character of common same-finger bigrams with another finger.
+type MagicKey struct {
+	Rune   rune          // Character of the magic key on the layout; the corpus cannot type it
+	Repeat bool          // Whether the key repeats a previous character without a rule of its own
+	Rules  map[rune]rune // Previous character -> character typed by the magic key
+	spec   string        // The key in the format of SetMagicFromString, for cache keys
+}
+
+// SetMagicFromString defines the magic key of the layout from a space-separated list:
+// the character of the magic key on the layout, followed by rules. A rule is "repeat",
+// which makes the key type the previous character again, or a pair of a previous
+// character and the character the key types after it (e.g. "* repeat ui" repeats every
+// character except after u, where it types i). Both characters of a pair must be on the
+// layout.
+func (sl *SplitLayout) SetMagicFromString(s string) error {
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 {
+		return fmt.Errorf("magic key is empty")
+	}
+	runes := []rune(tokens[0])
+	if len(runes) != 1 {
+		return fmt.Errorf("magic key %q must be a single character", tokens[0])
+	}
+	mk := &MagicKey{Rune: runes[0], Rules: make(map[rune]rune)}
+	if _, ok := sl.RuneInfo[mk.Rune]; !ok || unicode.IsSpace(mk.Rune) {
+		return fmt.Errorf("magic key %q is not on the layout", mk.Rune)
+	}
+
+	for _, token := range tokens[1:] {
+		if strings.ToLower(token) == "repeat" {
+			mk.Repeat = true
+			continue
+		}
+		pair := []rune(token)
+		if len(pair) != 2 {
+			return fmt.Errorf("magic rule %q must be \"repeat\" or a previous character followed by the character to type", token)
+		}
+		prev, typed := pair[0], pair[1]
+		for _, r := range pair {
+			if _, ok := sl.RuneInfo[r]; !ok || r == mk.Rune {
+				return fmt.Errorf("character %q of magic rule %q is not on the layout, or is the magic key", r, token)
+			}
+		}
+		if _, seen := mk.Rules[prev]; seen {
+			return fmt.Errorf("magic rule for %q is defined more than once", prev)
+		}
+		mk.Rules[prev] = typed
+	}
+	if !mk.Repeat && len(mk.Rules) == 0 {
+		return fmt.Errorf("magic key %q has no rules", mk.Rune)
+	}
+
+	mk.spec = mk.String()
+	sl.Magic = mk
+	return nil
+}
+
+// String returns the magic key in the format of SetMagicFromString, with the rules ordered
+// by previous character.
+func (mk *MagicKey) String() string {
+	parts := []string{string(mk.Rune)}
+	if mk.Repeat {
+		parts = append(parts, "repeat")
+	}
+	for _, prev := range slices.Sorted(maps.Keys(mk.Rules)) {
+		parts = append(parts, string([]rune{prev, mk.Rules[prev]}))
+	}
+	return strings.Join(parts, " ")
+}
+
+// output returns the character the magic key types after prev, or 0 if it types none.
+func (mk *MagicKey) output(prev rune) rune {
+	if typed, ok := mk.Rules[prev]; ok {
+		return typed
+	}
+	if mk.Repeat && prev != 0 && prev != mk.Rune && !unicode.IsSpace(prev) {
+		return prev
+	}
+	return 0
+}
+
+// typed returns the character pressed for r after prev: the magic key if it types r after
+// prev, else r itself. The character of the magic key can only be typed by its rules, so it
+// becomes utf8.RuneError, which is on no layout.
+func (mk *MagicKey) typed(prev, r rune) rune {
+	if prev != 0 && mk.output(prev) == r {
+		return mk.Rune
+	}
+	return mk.plain(r)
+}
+
+// plain returns r as pressed without the magic key.
+func (mk *MagicKey) plain(r rune) rune {
+	return IfThen(r == mk.Rune, utf8.RuneError, r)
+}
+
+// magicFold is a corpus with the magic key of a layout applied, together with the n-grams
+// it changed, needed for the magic metrics.
+type magicFold struct {
+	magic     *MagicKey
+	corpus    *Corpus            // corpus as typed with the magic key, used for all other metrics
+	firstRate map[Bigram]float64 // share of each bigram whose first character was typed with the magic key
+	bigrams   []magicChange      // original bigrams that the magic key types differently
+	skipgrams []magicChange      // original skipgrams that the magic key types differently
+	tables    sync.Map           // *trigramTable -> *trigramTable with the magic key applied
+}
+
+// magicChange is an n-gram of the original corpus that is typed differently with the magic
+// key, with the pressed characters and the number of such occurrences.
+type magicChange struct {
+	from, to [2]rune
+	count    uint64
+}
+
+// magicFoldKey identifies a magic fold.
+type magicFoldKey struct {
+	corpus *Corpus
+	magic  string
+}
+
+// magicFolds caches magic folds, as all layouts with the same magic key share one,
+// including all layouts scored during an optimisation.
+var magicFolds sync.Map // magicFoldKey -> *magicFold
+
+// splitCount splits count into the part typed with the magic key first, by rate, and the rest.
+func splitCount(count uint64, rate float64) (magic, plain uint64) {
+	magic = min(count, uint64(math.Round(float64(count)*rate)))
+	return magic, count - magic
+}
+
+// foldMagic returns the corpus as typed with the layout's magic key. Whether the magic key
+// types a character depends on the character before it, which is known within an n-gram
+// except for its first character. For that one, the share of occurrences after a character
+// that triggers the magic key is taken from the trigrams, which is exact for bigrams and
+// unigrams, and an estimate for trigrams and skipgrams. N-gram totals are unchanged.
+func (c *Corpus) foldMagic(sl *SplitLayout) *magicFold {
+	mk := sl.Magic
+	key := magicFoldKey{c, mk.spec}
+	if fold, ok := magicFolds.Load(key); ok {
+		return fold.(*magicFold)
+	}
+
+	// Count how often the first character of each bigram follows a character that triggers it
+	fold := &magicFold{magic: mk, firstRate: make(map[Bigram]float64)}
+	triggered := make(map[Bigram]uint64)
+	for tri, cnt := range c.Trigrams {
+		if mk.output(tri[0]) == tri[1] {
+			triggered[Bigram{tri[1], tri[2]}] += cnt
+		}
+	}
+	for bi, cnt := range triggered {
+		if total := c.Bigrams[bi]; total > 0 {
+			fold.firstRate[bi] = min(1, float64(cnt)/float64(total))
+		}
+	}
+
+	folded := &Corpus{
+		Name:                c.Name,
+		Unigrams:            make(map[Unigram]uint64, len(c.Unigrams)+1),
+		TotalUnigramsCount:  c.TotalUnigramsCount,
+		Bigrams:             make(map[Bigram]uint64, len(c.Bigrams)),
+		TotalBigramsCount:   c.TotalBigramsCount,
+		Trigrams:            make(map[Trigram]uint64, len(c.Trigrams)),
+		TotalTrigramsCount:  c.TotalTrigramsCount,
+		Skipgrams:           make(map[Skipgram]uint64, len(c.Skipgrams)),
+		TotalSkipgramsCount: c.TotalSkipgramsCount,
+		Words:               make(map[string]uint64, len(c.Words)),
+		TotalWordsCount:     c.TotalWordsCount,
+		Stream:              mk.typeText(c.Stream),
+	}
+
+	// Unigrams typed with the magic key are the second characters of triggering bigrams
+	magicUnigrams := make(map[Unigram]uint64)
+	for bi, cnt := range c.Bigrams {
+		if mk.output(bi[0]) == bi[1] {
+			magicUnigrams[Unigram(bi[1])] += cnt
+		}
+	}
+	for uni, cnt := range c.Unigrams {
+		magic := min(cnt, magicUnigrams[uni])
+		folded.Unigrams[Unigram(mk.plain(rune(uni)))] += cnt - magic
+		if magic > 0 {
+			folded.Unigrams[Unigram(mk.Rune)] += magic
+		}
+	}
+
+	for bi, cnt := range c.Bigrams {
+		second := mk.typed(bi[0], bi[1])
+		magic, plain := splitCount(cnt, fold.firstRate[bi])
+		for _, part := range [2]struct {
+			first rune
+			count uint64
+		}{{mk.Rune, magic}, {mk.plain(bi[0]), plain}} {
+			if part.count == 0 {
+				continue
+			}
+			to := Bigram{part.first, second}
+			folded.Bigrams[to] += part.count
+			if to != bi {
+				fold.bigrams = append(fold.bigrams, magicChange{bi, to, part.count})
+			}
+		}
+	}
+
+	// Skipgrams are the outer characters of the trigrams, so they change like them
+	for skp, cnt := range c.Skipgrams {
+		folded.Skipgrams[Skipgram{mk.plain(skp[0]), mk.plain(skp[1])}] += cnt
+	}
+	for tri, cnt := range c.Trigrams {
+		for _, ti := range fold.typeTrigram(TrigramInfo{Runes: [3]rune(tri[:]), Count: cnt}) {
+			folded.Trigrams[Trigram(ti.Runes)] += ti.Count
+
+			from := Skipgram{mk.plain(tri[0]), mk.plain(tri[2])}
+			to := Skipgram{ti.Runes[0], ti.Runes[2]}
+			if to == from {
+				continue
+			}
+			moved := min(ti.Count, folded.Skipgrams[from])
+			folded.Skipgrams[from] -= moved
+			folded.Skipgrams[to] += moved
+			fold.skipgrams = append(fold.skipgrams, magicChange{Skipgram{tri[0], tri[2]}, to, moved})
+		}
+	}
+
+	for word, cnt := range c.Words {
+		folded.Words[mk.typeText(word)] += cnt
+	}
+	if c.WordInitialBigrams != nil {
+		folded.WordInitialBigrams = make(map[Bigram]uint64, len(c.WordInitialBigrams))
+		for bi, cnt := range c.WordInitialBigrams {
+			folded.WordInitialBigrams[Bigram{mk.plain(bi[0]), mk.typed(bi[0], bi[1])}] += cnt
+		}
+	}
+	if c.WordFinalBigrams != nil {
+		folded.WordFinalBigrams = make(map[Bigram]uint64, len(c.WordFinalBigrams))
+		for bi, cnt := range c.WordFinalBigrams {
+			second := mk.typed(bi[0], bi[1])
+			magic, plain := splitCount(cnt, fold.firstRate[bi])
+			folded.WordFinalBigrams[Bigram{mk.Rune, second}] += magic
+			folded.WordFinalBigrams[Bigram{mk.plain(bi[0]), second}] += plain
+		}
+	}
+
+	fold.corpus = folded
+	actual, _ := magicFolds.LoadOrStore(key, fold)
+	return actual.(*magicFold)
+}
+
+// typeText returns text as typed with the magic key.
+func (mk *MagicKey) typeText(text string) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	var prev rune
+	for _, r := range text {
+		sb.WriteRune(mk.typed(prev, r))
+		prev = r
+	}
+	return sb.String()
+}
+
+// typeTrigram returns the trigram as typed with the magic key: one trigram, or two if its
+// first character is typed with the magic key in some of its occurrences.
+func (fold *magicFold) typeTrigram(ti TrigramInfo) []TrigramInfo {
+	mk, r := fold.magic, ti.Runes
+	second, third := mk.typed(r[0], r[1]), mk.typed(r[1], r[2])
+	magic, plain := splitCount(ti.Count, fold.firstRate[Bigram{r[0], r[1]}])
+	typed := make([]TrigramInfo, 0, 2)
+	if magic > 0 {
+		typed = append(typed, TrigramInfo{Runes: [3]rune{mk.Rune, second, third}, Count: magic})
+	}
+	if plain > 0 {
+		typed = append(typed, TrigramInfo{Runes: [3]rune{mk.plain(r[0]), second, third}, Count: plain})
+	}
+	return typed
+}
+
+// trigramTable returns the pre-filtered trigrams of a scorer as typed with the magic key.
+func (fold *magicFold) trigramTable(t *trigramTable) *trigramTable {
+	if typed, ok := fold.tables.Load(t); ok {
+		return typed.(*trigramTable)
+	}
+	list := make([]TrigramInfo, 0, len(t.list))
+	for _, ti := range t.list {
+		for _, typed := range fold.typeTrigram(ti) {
+			if !slices.Contains(typed.Runes[:], utf8.RuneError) { // Keep all runes on the layout
+				list = append(list, typed)
+			}
+		}
+	}
+	actual, _ := fold.tables.LoadOrStore(t, newTrigramTable(list))
+	return actual.(*trigramTable)
+}
+
+// forgetMagicFolds drops the magic folds of a corpus that is no longer used, such as a
+// discarded corpus sample.
+func forgetMagicFolds(c *Corpus) {
+	magicFolds.Range(func(key, _ any) bool {
+		if key.(magicFoldKey).corpus == c {
+			magicFolds.Delete(key)
+		}
+		return true
+	})
+}
+
+// useMagicCorpus switches the analyser to the corpus as typed with the layout's magic key,
+// keeping the fold for the magic metrics. It must be called after useShiftedCorpus and
+// before the metrics are computed.
+func (an *Analyser) useMagicCorpus() {
+	if an.Layout.Magic == nil {
+		return
+	}
+	an.magic = an.Corpus.foldMagic(an.Layout)
+	an.Corpus = an.magic.corpus
+	if an.trigramTable != nil {
+		an.trigramTable = an.magic.trigramTable(an.trigramTable)
+		an.relevantTrigrams = an.trigramTable.infos()
+	}
+}
+
+// analyseMagic computes the magic key metrics:
+//   - MAGIC: % of characters typed with the magic key
+//   - MAGIC-SFB: % of bigrams that are same-finger bigrams without the magic key, but not
+//     with it, less those that the magic key turns into same-finger bigrams
+//   - MAGIC-SFS: the same for skipgrams
+//
+// The MAGIC-SFB and MAGIC-SFS are the SFB and SFS that the magic key absorbs. All three are
+// 0 without a magic key.
+func (an *Analyser) analyseMagic() {
+	fold := an.magic
+	an.Metrics["MAGIC"] = 0
+	an.Metrics["MAGIC-SFB"] = 0
+	an.Metrics["MAGIC-SFS"] = 0
+	if fold == nil {
+		return
+	}
+
+	absorbed := func(changes []magicChange) float64 {
+		var net int64
+		for _, ch := range changes {
+			net += int64(ch.count) * int64(b2i(an.sameFinger(ch.from[0], ch.from[1]))-b2i(an.sameFinger(ch.to[0], ch.to[1])))
+		}
+		return float64(net)
+	}
+
+	if an.Corpus.TotalUnigramsCount > 0 {
+		an.Metrics["MAGIC"] = 100 * float64(an.Corpus.Unigrams[Unigram(fold.magic.Rune)]) / float64(an.Corpus.TotalUnigramsCount)
+	}
+	if an.Corpus.TotalBigramsCount > 0 {
+		an.Metrics["MAGIC-SFB"] = 100 * absorbed(fold.bigrams) / float64(an.Corpus.TotalBigramsCount)
+	}
+	if an.Corpus.TotalSkipgramsCount > 0 {
+		an.Metrics["MAGIC-SFS"] = 100 * absorbed(fold.skipgrams) / float64(an.Corpus.TotalSkipgramsCount)
+	}
+}
+
+// sameFinger reports whether r0 then r1 is a same-finger bigram or skipgram on the layout,
+// as counted by SFB and SFS.
+func (an *Analyser) sameFinger(r0, r1 rune) bool {
+	k0, ok0 := an.Layout.GetKeyInfo(r0)
+	k1, ok1 := an.Layout.GetKeyInfo(r1)
+	return ok0 && ok1 && k0.Finger == k1.Finger && (k0.Index != k1.Index || an.countsRepeats())
+}
@@ -0,0 +1,152 @@
+package keycraft
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// MagicRulesInput holds the parameters of a search for the rules of a magic key.
+type MagicRulesInput struct {
+	Layout   *SplitLayout
+	Corpus   *Corpus
+	Targets  *TargetLoads
+	MagicKey rune // Character of the magic key on the layout; ignored if the layout has one
+	MaxRules int  // Maximum number of rules to suggest
+}
+
+// MagicRule is a suggested rule of a magic key, with its estimated effect on its own.
+type MagicRule struct {
+	Prev, Typed rune    // After Prev, the magic key types Typed
+	Uses        float64 // % of characters the rule types with the magic key
+	SFB, SFS    float64 // SFB and SFS the rule absorbs, in % of bigrams and skipgrams
+}
+
+// MagicRulesResult holds the suggested rules of a magic key, and the analysis of the layout
+// without a magic key and with the suggested rules.
+type MagicRulesResult struct {
+	Rules  []MagicRule
+	Layout *SplitLayout // Layout with a magic key that has the suggested rules
+	Before *Analyser    // Analysis without a magic key
+	After  *Analyser    // Analysis with the suggested rules
+}
+
+// SuggestMagicRules finds the rules for a magic key that absorb the most SFB and SFS. Every
+// bigram of characters on the layout is a candidate rule, whose effect on its own is taken
+// from the n-grams it changes: the bigram itself, the bigrams and skipgrams that start or end
+// with its second character, and, estimated from the trigrams, the skipgrams that start with
+// it. Candidates are taken by descending SFB plus SFS, at most one per previous character,
+// since the magic key can only type one character after each. The combined effect of the
+// rules is then analysed exactly. The rules of a magic key that is already on the layout are
+// replaced.
+func SuggestMagicRules(input MagicRulesInput) (*MagicRulesResult, error) {
+	if input.MaxRules < 1 {
+		return nil, fmt.Errorf("number of rules must be at least 1")
+	}
+	magic := input.MagicKey
+	if input.Layout.Magic != nil {
+		magic = input.Layout.Magic.Rune
+	}
+	if _, ok := input.Layout.RuneInfo[magic]; !ok {
+		return nil, fmt.Errorf("magic key %q is not on layout %s", magic, input.Layout.Name)
+	}
+
+	plain := input.Layout.Clone()
+	plain.Magic = nil
+	before := NewAnalyser(plain, input.Corpus, input.Targets)
+	corpus := before.Corpus
+	if corpus.TotalBigramsCount == 0 || corpus.TotalSkipgramsCount == 0 {
+		return nil, fmt.Errorf("corpus %s has no bigrams or skipgrams", corpus.Name)
+	}
+
+	// Net change of the same-finger n-grams when the magic key types r1 instead of r0
+	sf := func(r0, r1 rune) int64 { return int64(b2i(before.sameFinger(r0, r1))) }
+	absorbs := func(first, second rune, magicFirst bool) int64 {
+		if magicFirst {
+			return sf(first, second) - sf(magic, second)
+		}
+		return sf(first, second) - sf(first, magic)
+	}
+
+	type candidate struct {
+		uses     uint64
+		sfb, sfs float64
+	}
+	candidates := make(map[Bigram]*candidate)
+	for bi, cnt := range corpus.Bigrams {
+		r0, r1 := bi[0], bi[1]
+		_, ok0 := plain.RuneInfo[r0]
+		_, ok1 := plain.RuneInfo[r1]
+		if ok0 && ok1 && r0 != magic && r1 != magic && !unicode.IsSpace(r0) && !unicode.IsSpace(r1) {
+			// The bigram itself is typed r0 then the magic key
+			candidates[bi] = &candidate{uses: cnt, sfb: float64(cnt) * float64(absorbs(r0, r1, false))}
+		}
+	}
+
+	trigramsByPrefix := make(map[Bigram][]TrigramInfo)
+	for tri, cnt := range corpus.Trigrams {
+		prefix := Bigram{tri[0], tri[1]}
+		trigramsByPrefix[prefix] = append(trigramsByPrefix[prefix], TrigramInfo{Runes: [3]rune(tri), Count: cnt})
+	}
+	for tri, cnt := range corpus.Trigrams {
+		w, a, b := tri[0], tri[1], tri[2]
+		// Rule w->a: the bigram a-b starts with the magic key
+		if cand, ok := candidates[Bigram{w, a}]; ok {
+			cand.sfb += float64(cnt) * float64(absorbs(a, b, true))
+
+			// The skipgrams starting with a are estimated from the share of a-b after w
+			if total := corpus.Bigrams[Bigram{a, b}]; total > 0 {
+				share := float64(cnt) / float64(total)
+				for _, next := range trigramsByPrefix[Bigram{a, b}] {
+					cand.sfs += share * float64(next.Count) * float64(absorbs(a, next.Runes[2], true))
+				}
+			}
+		}
+		// Rule a->b: the skipgram w-b ends with the magic key
+		if cand, ok := candidates[Bigram{a, b}]; ok {
+			cand.sfs += float64(cnt) * float64(absorbs(w, b, false))
+		}
+	}
+
+	rules := make([]MagicRule, 0, len(candidates))
+	for bi, cand := range candidates {
+		rules = append(rules, MagicRule{
+			Prev:  bi[0],
+			Typed: bi[1],
+			Uses:  100 * float64(cand.uses) / float64(corpus.TotalUnigramsCount),
+			SFB:   100 * cand.sfb / float64(corpus.TotalBigramsCount),
+			SFS:   100 * cand.sfs / float64(corpus.TotalSkipgramsCount),
+		})
+	}
+	slices.SortFunc(rules, func(x, y MagicRule) int {
+		return cmp.Or(cmp.Compare(y.SFB+y.SFS, x.SFB+x.SFS), cmp.Compare(x.Prev, y.Prev), cmp.Compare(x.Typed, y.Typed))
+	})
+
+	result := &MagicRulesResult{Before: before}
+	used := make(map[rune]bool)
+	for _, rule := range rules {
+		if len(result.Rules) == input.MaxRules || rule.SFB+rule.SFS <= 0 {
+			break
+		}
+		if !used[rule.Prev] {
+			used[rule.Prev] = true
+			result.Rules = append(result.Rules, rule)
+		}
+	}
+	if len(result.Rules) == 0 {
+		return nil, fmt.Errorf("no magic key rule absorbs any SFB or SFS on layout %s", input.Layout.Name)
+	}
+
+	spec := []string{string(magic)}
+	for _, rule := range result.Rules {
+		spec = append(spec, string([]rune{rule.Prev, rule.Typed}))
+	}
+	result.Layout = input.Layout.Clone()
+	if err := result.Layout.SetMagicFromString(strings.Join(spec, " ")); err != nil {
+		return nil, fmt.Errorf("could not set magic key rules: %w", err)
+	}
+	result.After = NewAnalyser(result.Layout, input.Corpus, input.Targets)
+	return result, nil
+}
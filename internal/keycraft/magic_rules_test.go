@@ -0,0 +1,54 @@
+package keycraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSuggestMagicRules(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	corpus.addTextWithWords("sphinx of black quartz, judge my vow. how vexingly quick daft zebras jump! deed ill feed")
+	layout := Must(writeShiftedLayout(t, ""))
+
+	result := Must(SuggestMagicRules(MagicRulesInput{Layout: layout, Corpus: corpus, MagicKey: '/', MaxRules: 5}))
+	if len(result.Rules) == 0 || len(result.Rules) > 5 {
+		t.Fatalf("got %d rules, want 1 to 5", len(result.Rules))
+	}
+	prevs := map[rune]bool{}
+	for _, rule := range result.Rules {
+		if prevs[rule.Prev] {
+			t.Errorf("more than one rule after %q", rule.Prev)
+		}
+		prevs[rule.Prev] = true
+		if rule.SFB+rule.SFS <= 0 {
+			t.Errorf("rule %c%c absorbs no SFB or SFS", rule.Prev, rule.Typed)
+		}
+	}
+	if result.Layout.Magic == nil || len(result.Layout.Magic.Rules) != len(result.Rules) {
+		t.Fatalf("magic key = %v, want %d rules", result.Layout.Magic, len(result.Rules))
+	}
+	if layout.Magic != nil {
+		t.Error("the input layout should not be changed")
+	}
+	if after, before := result.After.Metrics["SFB"]+result.After.Metrics["SFS"],
+		result.Before.Metrics["SFB"]+result.Before.Metrics["SFS"]; after >= before {
+		t.Errorf("SFB+SFS = %v with the rules, want below %v", after, before)
+	}
+
+	// The SFB of a rule on its own is exact, as bigrams are folded exactly
+	best := Must(SuggestMagicRules(MagicRulesInput{Layout: layout, Corpus: corpus, MagicKey: '/', MaxRules: 1}))
+	rule := best.Rules[0]
+	if got, want := best.After.Metrics["SFB"], best.Before.Metrics["SFB"]-rule.SFB; math.Abs(got-want) > 1e-9 {
+		t.Errorf("SFB with rule %c%c = %v, want %v", rule.Prev, rule.Typed, got, want)
+	}
+
+	for _, input := range []MagicRulesInput{
+		{Layout: layout, Corpus: corpus, MagicKey: '/', MaxRules: 0},
+		{Layout: layout, Corpus: corpus, MagicKey: '*', MaxRules: 5},
+	} {
+		if _, err := SuggestMagicRules(input); err == nil {
+			t.Errorf("%q with %d rules: expected error", input.MagicKey, input.MaxRules)
+		}
+	}
+}
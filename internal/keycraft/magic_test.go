@@ -0,0 +1,97 @@
+package keycraft
+
+import (
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetMagicFromString(t *testing.T) {
+	sl := Must(writeShiftedLayout(t, "\nmagic: / repeat ui eo\n"))
+	if sl.Magic == nil || sl.Magic.Rune != '/' || !sl.Magic.Repeat {
+		t.Fatalf("Magic = %+v, want a repeat key on /", sl.Magic)
+	}
+	for prev, want := range map[rune]rune{'u': 'i', 'e': 'o', 'l': 'l', ' ': 0, '/': 0} {
+		if got := sl.Magic.output(prev); got != want {
+			t.Errorf("output(%q) = %q, want %q", prev, got, want)
+		}
+	}
+	if got := sl.Magic.String(); got != "/ repeat eo ui" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// The magic key is saved with the layout
+	path := filepath.Join(t.TempDir(), "saved.klf")
+	Must0(sl.SaveToFile(path))
+	saved := Must(NewLayoutFromFile("saved", path))
+	if saved.Magic == nil || saved.Magic.String() != sl.Magic.String() {
+		t.Errorf("saved magic key = %v, want %v", saved.Magic, sl.Magic)
+	}
+
+	for _, bad := range []string{"", "* repeat", "// repeat", "/", "/ u", "/ u/", "/ ui ue", "/ uä"} {
+		if err := sl.Clone().SetMagicFromString(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestFoldMagic(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("hello all a/b")
+	sl := Must(writeShiftedLayout(t, "\nmagic: / repeat\n"))
+	folded := corpus.foldMagic(sl).corpus
+
+	// "ll" is typed l then the repeat key, and the o after it follows the repeat key
+	wantBigrams := map[Bigram]uint64{
+		{'h', 'e'}: 1, {'e', 'l'}: 1, {'l', '/'}: 2, {'/', 'o'}: 1, {'a', 'l'}: 1,
+		{'a', '�'}: 1, {'�', 'b'}: 1,
+	}
+	for bi, want := range wantBigrams {
+		if got := folded.Bigrams[bi]; got != want {
+			t.Errorf("bigram %q = %d, want %d", bi, got, want)
+		}
+	}
+	if got := folded.Bigrams[Bigram{'l', 'l'}] + folded.Bigrams[Bigram{'l', 'o'}]; got != 0 {
+		t.Errorf("ll and lo should be typed with the magic key, %d left", got)
+	}
+	if folded.Unigrams['/'] != 2 || folded.Unigrams['l'] != 2 {
+		t.Errorf("unigrams / = %d, l = %d, want 2 and 2", folded.Unigrams['/'], folded.Unigrams['l'])
+	}
+	if folded.Trigrams[Trigram{'l', '/', 'o'}] != 1 || folded.Trigrams[Trigram{'e', 'l', '/'}] != 1 {
+		t.Errorf("trigrams ell and llo should be typed with the magic key: %v", folded.Trigrams)
+	}
+	if folded.TotalBigramsCount != corpus.TotalBigramsCount || folded.TotalSkipgramsCount != corpus.TotalSkipgramsCount {
+		t.Error("folding should keep the n-gram totals")
+	}
+	if folded.Words["hel/o"] != 1 || folded.Words["al/"] != 1 {
+		t.Errorf("words = %v, want hel/o and al/", folded.Words)
+	}
+}
+
+func TestAnalyseMagic(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	corpus.addTextWithWords("sphinx of black quartz, judge my vow. how vexingly quick daft zebras jump! deed ill feed")
+	plain := NewAnalyser(Must(writeShiftedLayout(t, "")), corpus, nil)
+	layout := Must(writeShiftedLayout(t, "\nmagic: / repeat ed\n"))
+	an := NewAnalyser(layout, corpus, nil)
+
+	if plain.Metrics["MAGIC"] != 0 || plain.Metrics["MAGIC-SFB"] != 0 {
+		t.Errorf("MAGIC = %v, MAGIC-SFB = %v without a magic key, want 0", plain.Metrics["MAGIC"], plain.Metrics["MAGIC-SFB"])
+	}
+	if an.Metrics["MAGIC"] <= 0 || an.Metrics["MAGIC-SFB"] <= 0 {
+		t.Errorf("MAGIC = %v, MAGIC-SFB = %v, want both above 0", an.Metrics["MAGIC"], an.Metrics["MAGIC-SFB"])
+	}
+	// Bigrams are folded exactly, so MAGIC-SFB is the SFB that the magic key absorbs
+	if got, want := an.Metrics["SFB"], plain.Metrics["SFB"]-an.Metrics["MAGIC-SFB"]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("SFB = %v, want %v", got, want)
+	}
+
+	keys := usedKeys(layout)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 30 {
+		an.ApplySwap(keys[rng.IntN(len(keys))], keys[rng.IntN(len(keys))])
+		assertSameMetrics(t, an, NewAnalyser(layout.Clone(), corpus, nil))
+	}
+}
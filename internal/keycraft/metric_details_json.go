@@ -0,0 +1,126 @@
+package keycraft
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MetricDetailsSchemaVersion is the version of the JSON form of MetricDetails. It is raised
+// when a field is renamed or removed, or changes meaning; new fields may be added without it.
+const MetricDetailsSchemaVersion = 1
+
+// MetricDetailsJSON is the JSON form of MetricDetails. Unlike MetricDetails, whose Custom
+// attributes depend on the metric and may change, its fields are fixed: each attribute of
+// an n-gram is a typed field of its row, which is left out for metrics that lack it.
+type MetricDetailsJSON struct {
+	Schema       int              `json:"schema"`                   // MetricDetailsSchemaVersion
+	Metric       string           `json:"metric"`                   // Metric name, such as "SFB" or "2RL"
+	Corpus       string           `json:"corpus"`                   // Name of the corpus
+	CorpusNGrams uint64           `json:"corpus_ngrams"`            // Total n-grams of this type in the corpus
+	TotalNGrams  uint64           `json:"total_ngrams"`             // Total count of the n-grams of the metric
+	TotalDist    float64          `json:"total_dist"`               // Sum of the weighted distances
+	Skipped      uint64           `json:"skipped_ngrams,omitempty"` // Corpus n-grams skipped because a character is not on the layout
+	Rows         []MetricNGramRow `json:"rows"`                     // N-grams of the metric, by count, descending
+}
+
+// MetricNGramRow is an n-gram of a metric in the JSON form of MetricDetails.
+type MetricNGramRow struct {
+	NGram   string   `json:"ngram"`
+	Count   uint64   `json:"count"`
+	Percent float64  `json:"percent"`            // Count as a % of the corpus n-grams
+	Dist    float64  `json:"dist"`               // Distance of the n-gram
+	Hand    *int     `json:"hand,omitempty"`     // Hand of the first key: 1 (left) or 2 (right)
+	Finger  *int     `json:"finger,omitempty"`   // Finger of the first key: 1-10, left pinky to right pinky
+	RowDist *float64 `json:"row_dist,omitempty"` // Vertical distance between the keys, in layout units
+	ColDist *float64 `json:"col_dist,omitempty"` // Horizontal distance between the keys, in layout units
+	Angle   *float64 `json:"angle,omitempty"`    // Angle of a scissor, in degrees
+	Type    string   `json:"type,omitempty"`     // Bigram type of the corpus bigrams table, such as "SFB"
+	Dir     string   `json:"dir,omitempty"`      // Category of a trigram, such as "IN" or "NML"
+	Sev     *float64 `json:"sev,omitempty"`      // Severity of a redirection, by its finger span (see RED-SEV)
+}
+
+// JSON returns the JSON form of the metric details.
+func (ma *MetricDetails) JSON() MetricDetailsJSON {
+	out := MetricDetailsJSON{
+		Schema:       MetricDetailsSchemaVersion,
+		Metric:       ma.Metric,
+		CorpusNGrams: ma.CorpusNGramC,
+		TotalNGrams:  ma.TotalNGrams,
+		TotalDist:    ma.TotalDist,
+		Rows:         make([]MetricNGramRow, 0, len(ma.NGramCount)),
+	}
+	if ma.Corpus != nil {
+		out.Corpus = ma.Corpus.Name
+	}
+	for _, cnt := range ma.Unsupported {
+		out.Skipped += cnt
+	}
+
+	ngrams := slices.SortedFunc(maps.Keys(ma.NGramCount), func(a, b string) int {
+		return cmp.Or(cmp.Compare(ma.NGramCount[b], ma.NGramCount[a]), strings.Compare(a, b))
+	})
+	for _, ngram := range ngrams {
+		row := MetricNGramRow{
+			NGram: ngram,
+			Count: ma.NGramCount[ngram],
+			Dist:  ma.NGramDist[ngram],
+		}
+		if ma.CorpusNGramC > 0 {
+			row.Percent = 100 * float64(row.Count) / float64(ma.CorpusNGramC)
+		}
+		for key, value := range ma.Custom[ngram] {
+			row.setAttribute(key, value)
+		}
+		out.Rows = append(out.Rows, row)
+	}
+	return out
+}
+
+// setAttribute sets the field of the row for a Custom attribute of MetricDetails. This is
+// the only place that maps the Custom keys to the JSON form; unknown keys are ignored.
+func (row *MetricNGramRow) setAttribute(key string, value any) {
+	toInt := func() *int {
+		if v, ok := value.(uint8); ok {
+			n := int(v)
+			return &n
+		}
+		return nil
+	}
+	toFloat := func() *float64 {
+		if v, ok := value.(float64); ok {
+			return &v
+		}
+		return nil
+	}
+	toString := func() string {
+		s, _ := value.(string)
+		return s
+	}
+
+	switch key {
+	case "Hd":
+		row.Hand = toInt()
+	case "Fgr":
+		row.Finger = toInt()
+	case "Δrow":
+		row.RowDist = toFloat()
+	case "Δcol":
+		row.ColDist = toFloat()
+	case "Angle":
+		row.Angle = toFloat()
+	case "Type":
+		row.Type = toString()
+	case "Dir":
+		row.Dir = toString()
+	case "Sev":
+		row.Sev = toFloat()
+	}
+}
+
+// MarshalJSON encodes the metric details in their JSON form (see MetricDetailsJSON).
+func (ma *MetricDetails) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ma.JSON())
+}
@@ -0,0 +1,81 @@
+package keycraft
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMetricDetails_JSON(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("ded fr fr fr ju")
+	an := NewAnalyser(NewSplitLayout("test", ROWSTAG, qwertyRunes()), corpus, nil)
+
+	data := Must(json.Marshal(an.SFBiDetails()))
+	var got MetricDetailsJSON
+	Must0(json.Unmarshal(data, &got))
+
+	if got.Schema != MetricDetailsSchemaVersion || got.Metric != "SFB" || got.Corpus != "test" {
+		t.Errorf("header = %d %q %q", got.Schema, got.Metric, got.Corpus)
+	}
+	if got.CorpusNGrams != corpus.TotalBigramsCount || got.TotalNGrams != 6 {
+		t.Errorf("corpus n-grams = %d, total n-grams = %d, want %d and 6",
+			got.CorpusNGrams, got.TotalNGrams, corpus.TotalBigramsCount)
+	}
+	if len(got.Rows) != 4 {
+		t.Fatalf("rows = %+v, want 4", got.Rows)
+	}
+
+	// Rows are ordered by count and then by n-gram, with typed attributes
+	first := got.Rows[0]
+	if first.NGram != "fr" || first.Count != 3 || first.Hand == nil || *first.Hand != 1 ||
+		first.Finger == nil || *first.Finger != 4 || first.RowDist == nil || *first.RowDist != 1 {
+		t.Errorf("first row = %+v", first)
+	}
+	if first.ColDist != nil || first.Angle != nil || first.Dir != "" {
+		t.Errorf("first row has attributes that SFB lacks: %+v", first)
+	}
+	if want := 300 / float64(corpus.TotalBigramsCount); first.Percent != want {
+		t.Errorf("percent = %v, want %v", first.Percent, want)
+	}
+	if got.Rows[1].NGram != "de" || got.Rows[2].NGram != "ed" || got.Rows[3].NGram != "ju" {
+		t.Errorf("rows = %+v", got.Rows)
+	}
+
+	// The keys of the Custom attributes are not part of the JSON form
+	for _, key := range []string{"Custom", "Fgr", "Hd", "Δrow"} {
+		if strings.Contains(string(data), key) {
+			t.Errorf("JSON contains %q: %s", key, data)
+		}
+	}
+}
+
+func TestMetricDetails_JSONTrigrams(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("sdf")
+	an := NewAnalyser(NewSplitLayout("test", ROWSTAG, qwertyRunes()), corpus, nil)
+
+	_, _, rl3, _ := an.TrigramDetails()
+	got := rl3.JSON()
+	if len(got.Rows) != 1 || got.Rows[0].NGram != "sdf" || got.Rows[0].Dir != "IN" || got.Rows[0].Hand != nil {
+		t.Errorf("3RL rows = %+v", got.Rows)
+	}
+	if got.Rows[0].Sev != nil {
+		t.Errorf("3RL row has a severity: %+v", got.Rows[0])
+	}
+
+	// Redirections have the severity of RED-SEV
+	corpus = NewCorpus("test")
+	corpus.addTextWithWords("sfd")
+	an = NewAnalyser(NewSplitLayout("test", ROWSTAG, qwertyRunes()), corpus, nil)
+	_, _, _, red := an.TrigramDetails()
+	got = red.JSON()
+	key := func(r rune) KeyInfo {
+		ki, _ := an.Layout.GetKeyInfo(r)
+		return ki
+	}
+	want := float64(redirectSpan(triREDNml, key('s'), key('f'), key('d'))) / 2
+	if len(got.Rows) != 1 || got.Rows[0].NGram != "sfd" || got.Rows[0].Sev == nil || *got.Rows[0].Sev != want {
+		t.Errorf("RED rows = %+v, want sfd with severity %v", got.Rows, want)
+	}
+}
@@ -0,0 +1,53 @@
+package keycraft
+
+import "fmt"
+
+// CurrentMetricVersion is the version of the metric definitions computed by default. It is
+// bumped when a definition changes such that values are no longer comparable with results
+// published before, and the older definition remains available via TargetLoads.MetricVersion.
+const CurrentMetricVersion = 3
+
+// MetricVersionChanges describes each version of the metric definitions (index = version - 1).
+var MetricVersionChanges = []string{
+	"SFB and SFS include same-key repeats (e.g. \"ll\"), as reported by some other analysers.",
+	"SFB and SFS exclude same-key repeats, which are typed without moving the finger.",
+	"LSB includes ring finger to inner index column bigrams of colstag and ortho boards whose keys are " +
+		"far apart once the column stagger is taken into account (see DefaultLSBReach).",
+}
+
+// SetMetricVersion sets the version of the metric definitions to compute.
+func (tl *TargetLoads) SetMetricVersion(version int) error {
+	if version < 1 || version > CurrentMetricVersion {
+		return fmt.Errorf("invalid metric version %d: must be between 1 and %d", version, CurrentMetricVersion)
+	}
+	tl.MetricVersion = version
+	return nil
+}
+
+// MetricVersion returns the version of the metric definitions computed by the analyser.
+func (an *Analyser) MetricVersion() int {
+	if an.Targets == nil || an.Targets.MetricVersion == 0 {
+		return CurrentMetricVersion
+	}
+	return an.Targets.MetricVersion
+}
+
+// countsRepeats reports whether SFB and SFS include same-key repeats (metric version 1).
+func (an *Analyser) countsRepeats() bool {
+	return an.MetricVersion() < 2
+}
+
+// lsbs returns the lateral stretch bigrams of the layout. Before metric version 3, the ring
+// finger stretches to the inner index column of boards without row stagger are left out.
+func (an *Analyser) lsbs() []LSBInfo {
+	if an.MetricVersion() >= 3 {
+		return an.Layout.LSBs
+	}
+	lsbs := make([]LSBInfo, 0, len(an.Layout.LSBs))
+	for _, lsb := range an.Layout.LSBs {
+		if !lsb.Reach {
+			lsbs = append(lsbs, lsb)
+		}
+	}
+	return lsbs
+}
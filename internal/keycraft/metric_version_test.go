@@ -0,0 +1,111 @@
+package keycraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnalyser_MetricVersion(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("all good books need little letters; did mom see the eye")
+	layout := Must(writeShiftedLayout(t, ""))
+
+	current := NewAnalyser(layout, corpus, nil)
+	if v := current.MetricVersion(); v != CurrentMetricVersion {
+		t.Fatalf("default metric version = %d, want %d", v, CurrentMetricVersion)
+	}
+
+	targets := NewTargetLoads()
+	if err := targets.SetMetricVersion(1); err != nil {
+		t.Fatal(err)
+	}
+	v1 := NewAnalyser(layout.Clone(), corpus, targets)
+
+	// Version 1 adds the same-key repeats to SFB and SFS
+	var biRepeats, skpRepeats uint64
+	for _, r := range layout.Runes {
+		if r != 0 {
+			biRepeats += corpus.Bigrams[Bigram{r, r}]
+			skpRepeats += corpus.Skipgrams[Skipgram{r, r}]
+		}
+	}
+	if biRepeats == 0 || skpRepeats == 0 {
+		t.Fatal("test corpus should contain repeats")
+	}
+	wantSFB := current.Metrics["SFB"] + 100*float64(biRepeats)/float64(corpus.TotalBigramsCount)
+	if got := v1.Metrics["SFB"]; math.Abs(got-wantSFB) > 1e-9 {
+		t.Errorf("v1 SFB = %v, want %v", got, wantSFB)
+	}
+	wantSFS := current.Metrics["SFS"] + 100*float64(skpRepeats)/float64(corpus.TotalSkipgramsCount)
+	if got := v1.Metrics["SFS"]; math.Abs(got-wantSFS) > 1e-9 {
+		t.Errorf("v1 SFS = %v, want %v", got, wantSFS)
+	}
+	if v1.Metrics["LSB"] != current.Metrics["LSB"] {
+		t.Errorf("v1 LSB = %v, want %v", v1.Metrics["LSB"], current.Metrics["LSB"])
+	}
+
+	// Details and per-key attribution agree with the metric
+	details := v1.SFBiDetails()
+	if got := 100 * float64(details.TotalNGrams) / float64(corpus.TotalBigramsCount); math.Abs(got-wantSFB) > 1e-9 {
+		t.Errorf("v1 SFB details total = %v, want %v", got, wantSFB)
+	}
+	var perKey float64
+	for _, v := range v1.PerKeyMetrics().SFB {
+		perKey += v
+	}
+	if math.Abs(perKey-wantSFB) > 1e-9 {
+		t.Errorf("v1 per-key SFB sum = %v, want %v", perKey, wantSFB)
+	}
+
+	// Swapping keys keeps the repeats
+	keys := usedKeys(v1.Layout)
+	v1.ApplySwap(keys[0], keys[5])
+	assertSameMetrics(t, v1, NewAnalyser(v1.Layout.Clone(), corpus, targets))
+}
+
+func TestTargetLoads_SetMetricVersion(t *testing.T) {
+	targets := NewTargetLoads()
+	for _, v := range []int{0, CurrentMetricVersion + 1} {
+		if err := targets.SetMetricVersion(v); err == nil {
+			t.Errorf("expected error for metric version %d, got nil", v)
+		}
+	}
+	if len(MetricVersionChanges) != CurrentMetricVersion {
+		t.Errorf("%d metric version changes, want %d", len(MetricVersionChanges), CurrentMetricVersion)
+	}
+}
+
+func TestAnalyser_MetricVersionLSBReach(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("web wave brew vex text bent tax")
+	layout := NewSplitLayout("test", ORTHO, qwertyRunes())
+
+	targets := NewTargetLoads()
+	if err := targets.SetMetricVersion(2); err != nil {
+		t.Fatal(err)
+	}
+	current := NewAnalyser(layout, corpus, nil)
+	v2 := NewAnalyser(layout.Clone(), corpus, targets)
+
+	// Version 2 leaves out the ring to inner index stretches such as "wb" and "xt"
+	if v2.Metrics["LSB"] >= current.Metrics["LSB"] {
+		t.Errorf("v2 LSB = %v, want less than current %v", v2.Metrics["LSB"], current.Metrics["LSB"])
+	}
+	for _, lsb := range v2.lsbs() {
+		if lsb.Reach {
+			t.Errorf("v2 counts reach stretch %c%c", layout.Runes[lsb.KeyIdx1], layout.Runes[lsb.KeyIdx2])
+		}
+	}
+	var perKey float64
+	for _, v := range v2.PerKeyMetrics().LSB {
+		perKey += v
+	}
+	if math.Abs(perKey-v2.Metrics["LSB"]) > 1e-9 {
+		t.Errorf("v2 per-key LSB sum = %v, want %v", perKey, v2.Metrics["LSB"])
+	}
+
+	// Swapping keys keeps the old rule
+	keys := usedKeys(v2.Layout)
+	v2.ApplySwap(keys[0], keys[5])
+	assertSameMetrics(t, v2, NewAnalyser(v2.Layout.Clone(), corpus, targets))
+}
@@ -0,0 +1,246 @@
+package keycraft
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// metricsCacheVersion is the version of the metrics cache file. It must be bumped when the
+// analysis of a layout changes, so that metrics of an older version are not reused.
+const metricsCacheVersion = 3
+
+// maxMetricsCacheEntries is the number of layouts kept in the cache; the entries used least
+// recently are dropped when the cache is saved.
+const maxMetricsCacheEntries = 5000
+
+// MetricsCache is an on-disk store of the metrics of analysed layouts, keyed by the layout,
+// the corpus and the targets, so that ranking a directory of layouts again only analyses the
+// layouts that changed. Safe for concurrent use.
+type MetricsCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*metricsCacheEntry
+	dirty   bool
+	hits    int
+	misses  int
+
+	// Fingerprints of the last corpus and targets, which are the same for all layouts of a ranking
+	corpus     *Corpus
+	corpusKey  string
+	targets    *TargetLoads
+	targetsKey string
+}
+
+// metricsCacheEntry is the metrics of one layout, analysed with one corpus and targets.
+type metricsCacheEntry struct {
+	Metrics map[string]float64 `json:"metrics"`
+	Used    int64              `json:"used"` // Unix time the entry was last used
+}
+
+// metricsCacheFile is the content of a metrics cache file.
+type metricsCacheFile struct {
+	Version int                           `json:"version"`
+	Entries map[string]*metricsCacheEntry `json:"entries"`
+}
+
+// OpenMetricsCache loads the metrics cache from path. A missing file, or a file of another
+// version, gives an empty cache.
+func OpenMetricsCache(path string) (*MetricsCache, error) {
+	mc := &MetricsCache{path: path, entries: make(map[string]*metricsCacheEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return mc, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read metrics cache: %w", err)
+	}
+	var file metricsCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("could not decode metrics cache %s: %w", path, err)
+	}
+	if file.Version == metricsCacheVersion && file.Entries != nil {
+		mc.entries = file.Entries
+	}
+	return mc, nil
+}
+
+// Save writes the cache to its file if layouts were added since it was opened, keeping the
+// most recently used entries. A nil cache is not saved.
+func (mc *MetricsCache) Save() error {
+	if mc == nil {
+		return nil
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if !mc.dirty {
+		return nil
+	}
+	if len(mc.entries) > maxMetricsCacheEntries {
+		keys := slices.Collect(maps.Keys(mc.entries))
+		slices.SortFunc(keys, func(a, b string) int {
+			return int(mc.entries[b].Used - mc.entries[a].Used)
+		})
+		for _, key := range keys[maxMetricsCacheEntries:] {
+			delete(mc.entries, key)
+		}
+	}
+
+	data, err := json.Marshal(metricsCacheFile{Version: metricsCacheVersion, Entries: mc.entries})
+	if err != nil {
+		return fmt.Errorf("could not encode metrics cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(mc.path), 0o755); err != nil {
+		return fmt.Errorf("could not create metrics cache directory: %w", err)
+	}
+	// Write to a temporary file first, so that an interrupted save leaves the old cache intact
+	tmp := mc.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("could not write metrics cache: %w", err)
+	}
+	if err := os.Rename(tmp, mc.path); err != nil {
+		return fmt.Errorf("could not write metrics cache: %w", err)
+	}
+	mc.dirty = false
+	return nil
+}
+
+// Stats returns the number of layouts whose metrics were found in the cache, and the number
+// that were analysed.
+func (mc *MetricsCache) Stats() (hits, misses int) {
+	if mc == nil {
+		return 0, 0
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.hits, mc.misses
+}
+
+// NewAnalyser returns an analyser of the layout with the metrics from the cache, or analyses
+// the layout and adds its metrics to the cache. An analyser from the cache only has the
+// Layout, Corpus, Targets and Metrics, which is all that ranking uses. A nil cache analyses
+// every layout.
+func (mc *MetricsCache) NewAnalyser(layout *SplitLayout, corpus *Corpus, targets *TargetLoads) *Analyser {
+	if mc == nil {
+		return NewAnalyser(layout, corpus, targets)
+	}
+	targets = withDefaultTargets(targets)
+	key := mc.key(layout, corpus, targets)
+
+	mc.mu.Lock()
+	entry, ok := mc.entries[key]
+	if ok {
+		entry.Used = time.Now().Unix()
+		mc.hits++
+		mc.dirty = true
+		metrics := maps.Clone(entry.Metrics)
+		mc.mu.Unlock()
+		return &Analyser{Layout: layout, Corpus: corpus, Targets: targets, Metrics: metrics}
+	}
+	mc.misses++
+	mc.mu.Unlock()
+
+	an := NewAnalyser(layout, corpus, targets)
+	// JSON cannot hold NaN or infinite values, so such metrics are computed every time
+	if slices.ContainsFunc(slices.Collect(maps.Values(an.Metrics)), func(v float64) bool {
+		return math.IsNaN(v) || math.IsInf(v, 0)
+	}) {
+		return an
+	}
+	mc.mu.Lock()
+	mc.entries[key] = &metricsCacheEntry{Metrics: maps.Clone(an.Metrics), Used: time.Now().Unix()}
+	mc.dirty = true
+	mc.mu.Unlock()
+	return an
+}
+
+// key returns the cache key of a layout analysed with a corpus and targets. The key covers
+// everything the metrics depend on: the keys and settings of the layout (but not its name),
+// the n-gram tables of the corpus, the targets, and the board geometry in use.
+func (mc *MetricsCache) key(layout *SplitLayout, corpus *Corpus, targets *TargetLoads) string {
+	mc.mu.Lock()
+	if corpus != mc.corpus {
+		mc.corpus, mc.corpusKey = corpus, corpusFingerprint(corpus)
+	}
+	if targets != mc.targets {
+		mc.targets, mc.targetsKey = targets, targetsFingerprint(targets)
+	}
+	corpusKey, targetsKey := mc.corpusKey, mc.targetsKey
+	mc.mu.Unlock()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\x00%s\x00%s\x00%s", layoutCacheKey(layout), corpusKey, targetsKey, geometryFingerprint())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// corpusFingerprint returns a hash of the counts and settings of a corpus. The counts are
+// hashed in an order-independent way, so that the tables need not be sorted.
+func corpusFingerprint(c *Corpus) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d %d %d %d %t %t %+v\x00", c.TotalUnigramsCount, c.TotalBigramsCount,
+		c.TotalTrigramsCount, c.TotalSkipgramsCount, c.TotalWordsCount, c.Cased, c.Spaced, c.Skipgram)
+	for _, sum := range []uint64{
+		countsFingerprint(c.Unigrams), countsFingerprint(c.Bigrams), countsFingerprint(c.Trigrams),
+		countsFingerprint(c.Skipgrams), countsFingerprint(c.Words),
+		countsFingerprint(c.WordInitialBigrams), countsFingerprint(c.WordFinalBigrams),
+	} {
+		_ = binary.Write(h, binary.LittleEndian, sum)
+	}
+	h.Write([]byte(c.Stream))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// countsFingerprint returns the sum of the hashes of the entries of an n-gram table.
+func countsFingerprint[K comparable](counts map[K]uint64) uint64 {
+	var sum uint64
+	for key, cnt := range counts {
+		h := fnv.New64a()
+		fmt.Fprint(h, key, cnt)
+		sum += h.Sum64()
+	}
+	return sum
+}
+
+// targetsFingerprint returns a description of the targets with default values filled in.
+func targetsFingerprint(t *TargetLoads) string {
+	version := t.MetricVersion
+	if version == 0 {
+		version = CurrentMetricVersion
+	}
+	var baseline string
+	if t.Baseline != nil {
+		baseline = layoutCacheKey(t.Baseline)
+	}
+	fp := fmt.Sprintf("%v %v %v %v %v %v %v %v %v %v %v %d %q", t.TargetHandLoad, t.TargetFingerLoad, t.TargetRowLoad,
+		t.PinkyPenalties, t.RingPenalties, t.MiddlePenalties, t.IndexPenalties, t.MaxFingerLoad, t.RollQuality, t.Fatigue, t.ComfortZones,
+		version, baseline)
+	if t.SFSDefinition != "" && t.SFSDefinition != SFSAll {
+		fp += " sfs=" + string(t.SFSDefinition)
+	}
+	return fp
+}
+
+// geometryFingerprint returns a description of the board geometry in use.
+func geometryFingerprint() string {
+	if boardGeometry == nil {
+		return ""
+	}
+	g := *boardGeometry
+	var thumbs string
+	if g.Thumbs != nil {
+		thumbs = g.Thumbs.String()
+	}
+	g.Thumbs = nil
+	return fmt.Sprintf("%+v %s", g, thumbs)
+}
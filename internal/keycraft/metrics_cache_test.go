@@ -0,0 +1,89 @@
+package keycraft
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMetricsCache checks that the metrics of a layout are reused after the cache is saved
+// and opened again, also under another name, and that other targets or corpora miss.
+func TestMetricsCache(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	path := filepath.Join(t.TempDir(), "cache", "metrics.json")
+
+	cache := Must(OpenMetricsCache(path))
+	want := cache.NewAnalyser(layout, corpus, nil).Metrics
+	if hits, misses := cache.Stats(); hits != 0 || misses != 1 {
+		t.Errorf("first analysis: got %d hits and %d misses, want 0 and 1", hits, misses)
+	}
+	Must0(cache.Save())
+
+	cache = Must(OpenMetricsCache(path))
+	renamed := Must(NewLayoutFromFile("renamed", filepath.Join(dir, "a.klf")))
+	got := cache.NewAnalyser(renamed, corpus, nil)
+	if !maps.Equal(got.Metrics, want) {
+		t.Errorf("cached metrics differ from the analysed metrics")
+	}
+	if got.Layout != renamed {
+		t.Errorf("cached analyser has layout %s, want renamed", got.Layout.Name)
+	}
+
+	targets := &TargetLoads{TargetHandLoad: &[2]float64{45, 55}}
+	cache.NewAnalyser(layout, corpus, targets)
+	cache.NewAnalyser(layout, corpus, &TargetLoads{Fatigue: &FatigueSettings{Window: 5, Limit: 2}})
+	other := NewCorpus("other")
+	other.addTextWithWords("pack my box with five dozen liquor jugs")
+	cache.NewAnalyser(layout, other, nil)
+	if hits, misses := cache.Stats(); hits != 1 || misses != 3 {
+		t.Errorf("got %d hits and %d misses, want 1 and 3", hits, misses)
+	}
+
+	// A cache of another version is ignored
+	Must0(os.WriteFile(path, []byte(`{"version":0,"entries":{}}`), 0o644))
+	cache = Must(OpenMetricsCache(path))
+	cache.NewAnalyser(layout, corpus, nil)
+	if hits, _ := cache.Stats(); hits != 0 {
+		t.Errorf("got %d hits from a cache of another version, want 0", hits)
+	}
+
+	var none *MetricsCache
+	if an := none.NewAnalyser(layout, corpus, nil); !maps.Equal(an.Metrics, want) {
+		t.Error("a nil cache should analyse the layout")
+	}
+	Must0(none.Save())
+}
+
+// TestComputeRankings_Cache checks that rankings with a warm cache equal those without.
+func TestComputeRankings_Cache(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"b.klf": testLayoutVariantKlf,
+	})
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	input := RankingInput{
+		LayoutsDir:  dir,
+		LayoutFiles: []string{filepath.Join(dir, "a.klf"), filepath.Join(dir, "b.klf")},
+		Corpus:      corpus,
+		Weights:     Must(NewWeightsFromString("SFB=-10")),
+	}
+	want := Must(ComputeRankings(input))
+
+	input.Cache = Must(OpenMetricsCache(filepath.Join(t.TempDir(), "metrics.json")))
+	Must(ComputeRankings(input))
+	got := Must(ComputeRankings(input))
+	if hits, misses := input.Cache.Stats(); hits != 2 || misses != 2 {
+		t.Errorf("got %d hits and %d misses, want 2 and 2", hits, misses)
+	}
+	for i := range want.Scores {
+		if got.Scores[i].Name != want.Scores[i].Name || got.Scores[i].Score != want.Scores[i].Score {
+			t.Errorf("score %d: got %s %.4f, want %s %.4f", i, got.Scores[i].Name, got.Scores[i].Score,
+				want.Scores[i].Name, want.Scores[i].Score)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package keycraft
+
+import "fmt"
+
+// changeLimitPenalty is the cost of every key position changed beyond the limit of a change
+// penalty. It outweighs any metric, so that the optimizer never keeps such a layout as best.
+const changeLimitPenalty = 1000.0
+
+// ChangesMetric is the name of the cost of changed key positions in MetricCosts.
+const ChangesMetric = "CHANGES"
+
+// changePenalty makes a scorer add a cost for the key positions at which a layout differs
+// from the layout an optimization starts from, so that migrating to the result is easier.
+type changePenalty struct {
+	origin     *SplitLayout
+	maxChanges int     // Changed positions allowed (0 = unlimited)
+	weight     float64 // Cost of each changed position
+}
+
+// cost returns the change penalty of a layout.
+func (p *changePenalty) cost(layout *SplitLayout) float64 {
+	if p == nil {
+		return 0
+	}
+	changes := KeyDiff(p.origin, layout)
+	cost := p.weight * float64(changes)
+	if p.maxChanges > 0 && changes > p.maxChanges {
+		cost += changeLimitPenalty * float64(changes-p.maxChanges)
+	}
+	return cost
+}
+
+// SetChangePenalty makes the scorer penalise layouts that differ from origin: by weight for
+// every changed key position, and prohibitively for every position beyond maxChanges
+// (0 = no limit). Scores then only make sense for layouts of the type of origin.
+//
+// SetChangePenalty must not be called concurrently with other methods of the scorer.
+func (sc *Scorer) SetChangePenalty(origin *SplitLayout, maxChanges int, weight float64) error {
+	if maxChanges < 0 {
+		return fmt.Errorf("invalid maximum number of changes %d: must not be negative", maxChanges)
+	}
+	if weight < 0 {
+		return fmt.Errorf("invalid change weight %g: must not be negative", weight)
+	}
+	sc.changes = nil
+	if maxChanges > 0 || weight > 0 {
+		sc.changes = &changePenalty{origin: origin.Clone(), maxChanges: maxChanges, weight: weight}
+	}
+	sc.scoreCache.clear()
+	return nil
+}
@@ -0,0 +1,84 @@
+package keycraft
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// TestChangePenalty checks that the change penalty costs the weight of every changed key
+// position, plus the limit penalty for every position beyond the limit, in all scores.
+func TestChangePenalty(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	sc := bls.scorer
+	keys := usedKeys(layout)
+	swapped := layout.Clone()
+	swapped.Swap(keys[1], keys[2])
+	swapped.Swap(keys[3], keys[4])
+	costs := sc.MetricCosts(swapped)
+	base, unchanged := sc.ScoreExact(swapped), sc.ScoreExact(layout)
+	if _, ok := costs[ChangesMetric]; ok {
+		t.Fatalf("metric costs include %s without a change penalty", ChangesMetric)
+	}
+
+	for _, tc := range []struct {
+		maxChanges int
+		weight     float64
+		want       float64
+	}{
+		{0, 0.5, 2},
+		{4, 0, 0},
+		{3, 0, changeLimitPenalty},
+		{2, 1, 4 + 2*changeLimitPenalty},
+	} {
+		Must0(sc.SetChangePenalty(layout, tc.maxChanges, tc.weight))
+		if got := sc.Score(swapped) - base; math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("max %d, weight %g: Score penalty %g, want %g", tc.maxChanges, tc.weight, got, tc.want)
+		}
+		if got := sc.ScoreExact(layout); got != unchanged {
+			t.Errorf("max %d, weight %g: unchanged layout penalised", tc.maxChanges, tc.weight)
+		}
+
+		total := 0.0
+		for _, cost := range sc.MetricCosts(swapped) {
+			total += cost
+		}
+		if math.Abs(total-sc.ScoreExact(swapped)) > 1e-9 {
+			t.Errorf("max %d, weight %g: metric costs add up to %g, want %g",
+				tc.maxChanges, tc.weight, total, sc.ScoreExact(swapped))
+		}
+
+		bounds := sc.NewSwapBounds(layout)
+		swapped.Swap(keys[3], keys[4])
+		sc.scoreCache.clear()
+		got, exact := sc.ScoreSwap(swapped, bounds, keys[1], keys[2], math.Inf(1))
+		sc.scoreCache.clear()
+		want := sc.Score(swapped)
+		swapped.Swap(keys[3], keys[4])
+		if !exact || math.Abs(got-want) > 1e-9 {
+			t.Errorf("max %d, weight %g: ScoreSwap %g (exact %v), want %g", tc.maxChanges, tc.weight, got, exact, want)
+		}
+	}
+
+	if err := sc.SetChangePenalty(layout, -1, 0); err == nil {
+		t.Error("expected an error for a negative maximum number of changes")
+	}
+	if err := sc.SetChangePenalty(layout, 0, -1); err == nil {
+		t.Error("expected an error for a negative change weight")
+	}
+}
+
+// TestOptimize_MaxChanges checks that the best layout of an optimization with a change limit
+// differs from the input layout by at most the limit.
+func TestOptimize_MaxChanges(t *testing.T) {
+	for _, maxChanges := range []int{2, 5} {
+		bls, layout := newTestMovesBLS(t)
+		bls.params.MaxIterations = 10
+		Must0(bls.scorer.SetChangePenalty(layout, maxChanges, 0))
+
+		best := bls.Optimize(context.Background(), layout, nil)
+		if diff := KeyDiff(layout, best); diff > maxChanges || diff == 0 {
+			t.Errorf("max %d changes: best layout differs at %d key positions", maxChanges, diff)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package keycraft
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WeightedCorpus is a corpus with its relative importance in a multi-corpus objective.
+type WeightedCorpus struct {
+	Corpus *Corpus
+	Weight float64
+}
+
+// ParseCorpusSpec splits a corpus specification of the form "file[:weight]" into the
+// corpus file and its weight. The weight defaults to 1 and must be above 0. A suffix after
+// the last ":" is only a weight if it is a number, so that files with a ":" in their path,
+// such as "C:\corpora\en.txt" on Windows, need no weight.
+func ParseCorpusSpec(spec string) (string, float64, error) {
+	spec = strings.TrimSpace(spec)
+	file, weightStr, found := cutLast(spec, ":")
+	if !found {
+		return spec, 1, nil
+	}
+
+	weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+	if err != nil {
+		return spec, 1, nil
+	}
+	if weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return "", 0, fmt.Errorf("invalid weight %q for corpus %s: must be a number above 0", weightStr, file)
+	}
+	file = strings.TrimSpace(file)
+	if file == "" {
+		return "", 0, fmt.Errorf("missing corpus file in %q", spec)
+	}
+	return file, weight, nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// BlendCorpora merges weighted corpora into a single corpus in which each corpus
+// contributes to every n-gram table in proportion to its weight, regardless of its size.
+// It is used where a single corpus is needed, such as for guiding BLS perturbations.
+func BlendCorpora(corpora []WeightedCorpus) *Corpus {
+	if len(corpora) == 1 {
+		return corpora[0].Corpus
+	}
+
+	names := make([]string, len(corpora))
+	for i, wc := range corpora {
+		names[i] = wc.Corpus.Name
+	}
+	blend := NewCorpus(strings.Join(names, " + "))
+	blend.WordInitialBigrams, blend.WordFinalBigrams = nil, nil
+
+	var totalWeight float64
+	for _, wc := range corpora {
+		totalWeight += wc.Weight
+	}
+
+	uni := make([]blendPart[Unigram], len(corpora))
+	bi := make([]blendPart[Bigram], len(corpora))
+	tri := make([]blendPart[Trigram], len(corpora))
+	skp := make([]blendPart[Skipgram], len(corpora))
+	words := make([]blendPart[string], len(corpora))
+	mixedSkipgrams := false
+	for i, wc := range corpora {
+		w := wc.Weight / totalWeight
+		c := wc.Corpus
+		uni[i] = blendPart[Unigram]{c.Unigrams, c.TotalUnigramsCount, w}
+		bi[i] = blendPart[Bigram]{c.Bigrams, c.TotalBigramsCount, w}
+		tri[i] = blendPart[Trigram]{c.Trigrams, c.TotalTrigramsCount, w}
+		skp[i] = blendPart[Skipgram]{c.Skipgrams, c.TotalSkipgramsCount, w}
+		words[i] = blendPart[string]{c.Words, c.TotalWordsCount, w}
+		blend.Cased = blend.Cased || c.Cased
+		blend.Spaced = blend.Spaced || c.Spaced
+		if c.Skipgram != corpora[0].Corpus.Skipgram {
+			mixedSkipgrams = true
+		}
+	}
+
+	blend.Unigrams, blend.TotalUnigramsCount = blendCounts(uni)
+	blend.Bigrams, blend.TotalBigramsCount = blendCounts(bi)
+	blend.Trigrams, blend.TotalTrigramsCount = blendCounts(tri)
+	blend.Skipgrams, blend.TotalSkipgramsCount = blendCounts(skp)
+	blend.Words, blend.TotalWordsCount = blendCounts(words)
+	if !mixedSkipgrams {
+		blend.Skipgram = corpora[0].Corpus.Skipgram
+	}
+
+	return blend
+}
+
+// blendPart is one n-gram table of a corpus being blended.
+type blendPart[K comparable] struct {
+	counts map[K]uint64
+	total  uint64
+	weight float64 // Normalised weight of the corpus
+}
+
+// blendCounts merges n-gram tables by relative frequency. The blended counts are scaled
+// to the combined size of all tables to retain the precision of integer counts.
+func blendCounts[K comparable](parts []blendPart[K]) (map[K]uint64, uint64) {
+	var size uint64
+	for _, p := range parts {
+		size += p.total
+	}
+
+	freqs := make(map[K]float64)
+	for _, p := range parts {
+		if p.total == 0 {
+			continue
+		}
+		for k, cnt := range p.counts {
+			freqs[k] += p.weight * float64(cnt) / float64(p.total)
+		}
+	}
+
+	counts := make(map[K]uint64, len(freqs))
+	var total uint64
+	for k, f := range freqs {
+		cnt := uint64(math.Round(f * float64(size)))
+		if cnt == 0 {
+			continue
+		}
+		counts[k] = cnt
+		total += cnt
+	}
+	return counts, total
+}
+
+// CorpusScores holds the scores of a layout ranked against several corpora.
+type CorpusScores struct {
+	Name      string
+	Scores    []float64   // Score against each corpus, in the order of the corpora
+	Combined  float64     // Mean of Scores, weighted by the corpus weights
+	Worst     float64     // Lowest of Scores
+	Analysers []*Analyser // Analysis against each corpus, in the order of the corpora
+}
+
+// ComputeCorpusRankings ranks layouts against each of several corpora separately, and
+// combines the scores using the corpus weights, normalised to sum to 1. Each corpus gets its
+// own reference statistics, as with NewMultiCorpusScorer. The corpus of input is ignored.
+// Returns the layouts by descending combined score.
+func ComputeCorpusRankings(input RankingInput, corpora []WeightedCorpus) ([]CorpusScores, error) {
+	if len(corpora) == 0 {
+		return nil, fmt.Errorf("no corpora given")
+	}
+	var total float64
+	for _, wc := range corpora {
+		if wc.Weight <= 0 {
+			return nil, fmt.Errorf("corpus %s has weight %g; weights must be above 0", wc.Corpus.Name, wc.Weight)
+		}
+		total += wc.Weight
+	}
+
+	var rows []CorpusScores
+	index := make(map[string]int)
+	for i, wc := range corpora {
+		input.Corpus = wc.Corpus
+		result, err := ComputeRankings(input)
+		if err != nil {
+			return nil, fmt.Errorf("could not rank layouts for corpus %s: %w", wc.Corpus.Name, err)
+		}
+		for _, ls := range result.Scores {
+			j, ok := index[ls.Name]
+			if !ok {
+				j = len(rows)
+				index[ls.Name] = j
+				rows = append(rows, CorpusScores{
+					Name:      ls.Name,
+					Scores:    make([]float64, len(corpora)),
+					Worst:     math.Inf(1),
+					Analysers: make([]*Analyser, len(corpora)),
+				})
+			}
+			row := &rows[j]
+			row.Scores[i], row.Analysers[i] = ls.Score, ls.Analyser
+			row.Combined += ls.Score * wc.Weight / total
+			row.Worst = min(row.Worst, ls.Score)
+		}
+	}
+
+	slices.SortStableFunc(rows, func(a, b CorpusScores) int {
+		return cmp.Or(cmp.Compare(b.Combined, a.Combined), cmp.Compare(a.Name, b.Name))
+	})
+	return rows, nil
+}
@@ -0,0 +1,157 @@
+package keycraft
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCorpusSpec(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantFile   string
+		wantWeight float64
+		wantErr    bool
+	}{
+		{"en.txt", "en.txt", 1, false},
+		{"en.txt:0.6", "en.txt", 0.6, false},
+		{" nl.txt : 2 ", "nl.txt", 2, false},
+		{"en.txt:0", "", 0, true},
+		{"en.txt:-1", "", 0, true},
+		{"en.txt:abc", "en.txt:abc", 1, false},
+		{":0.5", "", 0, true},
+		{`C:\corpora\en.txt`, `C:\corpora\en.txt`, 1, false},
+		{`C:\corpora\en.txt:2`, `C:\corpora\en.txt`, 2, false},
+		{`C:\corpora\en.txt:0`, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		file, weight, err := ParseCorpusSpec(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error state: %v", tt.spec, err)
+			continue
+		}
+		if !tt.wantErr && (file != tt.wantFile || weight != tt.wantWeight) {
+			t.Errorf("%q: got (%q, %g), want (%q, %g)", tt.spec, file, weight, tt.wantFile, tt.wantWeight)
+		}
+	}
+}
+
+func TestBlendCorpora(t *testing.T) {
+	a := NewCorpus("a")
+	a.addTextWithWords("ab ab ab ab") // 4 bigrams
+	b := NewCorpus("b")
+	b.addTextWithWords("cd") // 1 bigram
+
+	// Equal weights: each corpus contributes half, regardless of its size
+	blend := BlendCorpora([]WeightedCorpus{{a, 1}, {b, 1}})
+	if blend.Name != "a + b" {
+		t.Errorf("unexpected name %q", blend.Name)
+	}
+	ab := float64(blend.Bigrams[Bigram{'a', 'b'}]) / float64(blend.TotalBigramsCount)
+	cd := float64(blend.Bigrams[Bigram{'c', 'd'}]) / float64(blend.TotalBigramsCount)
+	if math.Abs(ab-0.5) > 1e-9 || math.Abs(cd-0.5) > 1e-9 {
+		t.Errorf("expected equal shares, got ab=%.3f cd=%.3f", ab, cd)
+	}
+
+	// A single corpus is returned as is
+	if BlendCorpora([]WeightedCorpus{{a, 0.3}}) != a {
+		t.Error("expected a single corpus to be returned unchanged")
+	}
+}
+
+func TestNewMultiCorpusScorer(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+
+	en := NewCorpus("en")
+	en.addTextWithWords("the quick brown fox jumps over the lazy dog")
+	nl := NewCorpus("nl")
+	nl.addTextWithWords("de snelle bruine vos springt over de luie hond")
+
+	weights := NewWeights()
+	targets := NewTargetLoads()
+	multi, err := NewMultiCorpusScorer(dir, []WeightedCorpus{{en, 3}, {nl, 1}}, targets, weights, nil)
+	if err != nil {
+		t.Fatalf("NewMultiCorpusScorer failed: %v", err)
+	}
+
+	enScore := Must(NewScorer(dir, en, targets, weights, nil)).Score(layout)
+	nlScore := Must(NewScorer(dir, nl, targets, weights, nil)).Score(layout)
+	want := 0.75*enScore + 0.25*nlScore
+	if got := multi.Score(layout); math.Abs(got-want) > 1e-9 {
+		t.Errorf("combined score %.6f, want %.6f", got, want)
+	}
+
+	if _, err := NewMultiCorpusScorer(dir, []WeightedCorpus{{en, 0}}, targets, weights, nil); err == nil {
+		t.Error("expected error for zero weight")
+	}
+}
+
+func TestComputeCorpusRankings(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	en := NewCorpus("en")
+	en.addTextWithWords("the quick brown fox jumps over the lazy dog")
+	nl := NewCorpus("nl")
+	nl.addTextWithWords("de snelle bruine vos springt over de luie hond")
+
+	input := RankingInput{
+		LayoutsDir:  dir,
+		LayoutFiles: []string{filepath.Join(dir, "a.klf"), filepath.Join(dir, "far.klf")},
+		Targets:     NewTargetLoads(),
+		Weights:     NewWeights(),
+	}
+	rows, err := ComputeCorpusRankings(input, []WeightedCorpus{{en, 3}, {nl, 1}})
+	if err != nil {
+		t.Fatalf("ComputeCorpusRankings failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d layouts, want 2", len(rows))
+	}
+
+	for _, row := range rows {
+		input.Corpus = en
+		enScores := Must(ComputeRankings(input)).Scores
+		input.Corpus = nl
+		nlScores := Must(ComputeRankings(input)).Scores
+		score := func(scores []LayoutScore) float64 {
+			for _, ls := range scores {
+				if ls.Name == row.Name {
+					return ls.Score
+				}
+			}
+			t.Fatalf("layout %s not ranked", row.Name)
+			return 0
+		}
+		enScore, nlScore := score(enScores), score(nlScores)
+
+		if row.Scores[0] != enScore || row.Scores[1] != nlScore {
+			t.Errorf("%s: scores %v, want [%v %v]", row.Name, row.Scores, enScore, nlScore)
+		}
+		if want := 0.75*enScore + 0.25*nlScore; math.Abs(row.Combined-want) > 1e-9 {
+			t.Errorf("%s: combined score %.6f, want %.6f", row.Name, row.Combined, want)
+		}
+		if want := min(enScore, nlScore); row.Worst != want {
+			t.Errorf("%s: worst score %.6f, want %.6f", row.Name, row.Worst, want)
+		}
+		if row.Analysers[0].Corpus != en || row.Analysers[1].Corpus != nl {
+			t.Errorf("%s: analysers not in the order of the corpora", row.Name)
+		}
+	}
+	if rows[0].Combined < rows[1].Combined {
+		t.Errorf("layouts not sorted by combined score: %.6f < %.6f", rows[0].Combined, rows[1].Combined)
+	}
+
+	if _, err := ComputeCorpusRankings(input, nil); err == nil {
+		t.Error("expected error for no corpora")
+	}
+	if _, err := ComputeCorpusRankings(input, []WeightedCorpus{{en, 0}}); err == nil {
+		t.Error("expected error for zero weight")
+	}
+}
@@ -0,0 +1,155 @@
+package keycraft
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+)
+
+// SetNumberRow places the runes of a number row above the top row, one per column as in the
+// main rows, with 0 for a column without a key. The runes must not be on the main keys. The
+// keys of the number row are typed by the finger of the top-row key below them, and are not
+// part of the layout that is optimised.
+func (sl *SplitLayout) SetNumberRow(row [12]rune) error {
+	seen := make(map[rune]bool, len(row))
+	for col, r := range row {
+		if r == 0 {
+			continue
+		}
+		if _, ok := sl.RuneInfo[r]; ok {
+			return fmt.Errorf("number row character %q in column %d already has a key on the main rows", r, col+1)
+		}
+		if seen[r] {
+			return fmt.Errorf("duplicate number row character %q in column %d", r, col+1)
+		}
+		seen[r] = true
+	}
+	if len(seen) == 0 {
+		sl.NumberRow = nil
+		return nil
+	}
+	sl.NumberRow = &row
+	return nil
+}
+
+// numberKey returns the column of r on the number row of the layout, if it is on it.
+func (sl *SplitLayout) numberKey(r rune) (uint8, bool) {
+	if sl.NumberRow == nil || r == 0 {
+		return 0, false
+	}
+	for col, nr := range sl.NumberRow {
+		if nr == r {
+			return uint8(col), true
+		}
+	}
+	return 0, false
+}
+
+// numberKeyFinger returns the key index and finger of r on the main keys, or on the number
+// row with an index of 42 and up, if it has a key.
+func (sl *SplitLayout) numberKeyFinger(r rune) (uint8, uint8, bool) {
+	if ki, ok := sl.GetKeyInfo(r); ok {
+		return ki.Index, ki.Finger, true
+	}
+	if col, ok := sl.numberKey(r); ok {
+		return 42 + col, keyToFinger[col], true
+	}
+	return 0, 0, false
+}
+
+// numberFold is the n-grams of a corpus with a digit or a character of a number row, which
+// are all the number metrics look at.
+type numberFold struct {
+	unigrams map[rune]uint64
+	bigrams  []shiftBigram
+}
+
+// numberFoldKey identifies a number fold.
+type numberFoldKey struct {
+	corpus    *Corpus
+	numberRow [12]rune
+}
+
+// numberFolds caches number folds, as all layouts with the same number row share one,
+// including all layouts scored during an optimisation.
+var numberFolds sync.Map // numberFoldKey -> *numberFold
+
+// foldNumbers returns the n-grams of the corpus with a digit or a character of the number
+// row of the layout.
+func (c *Corpus) foldNumbers(sl *SplitLayout) *numberFold {
+	key := numberFoldKey{corpus: c}
+	if sl.NumberRow != nil {
+		key.numberRow = *sl.NumberRow
+	}
+	if fold, ok := numberFolds.Load(key); ok {
+		return fold.(*numberFold)
+	}
+
+	relevant := func(r rune) bool {
+		_, onRow := sl.numberKey(r)
+		return onRow || unicode.IsDigit(r)
+	}
+	fold := &numberFold{unigrams: make(map[rune]uint64)}
+	for uni, cnt := range c.Unigrams {
+		if relevant(rune(uni)) {
+			fold.unigrams[rune(uni)] += cnt
+		}
+	}
+	for bi, cnt := range c.Bigrams {
+		if relevant(bi[0]) || relevant(bi[1]) {
+			fold.bigrams = append(fold.bigrams, shiftBigram{bi, cnt})
+		}
+	}
+
+	actual, _ := numberFolds.LoadOrStore(key, fold)
+	return actual.(*numberFold)
+}
+
+// forgetNumberFolds drops the number folds of a corpus that is no longer used, such as a
+// discarded corpus sample.
+func forgetNumberFolds(c *Corpus) {
+	numberFolds.Range(func(key, _ any) bool {
+		if key.(numberFoldKey).corpus == c {
+			numberFolds.Delete(key)
+		}
+		return true
+	})
+}
+
+// analyseNumbers computes the metrics of digits and the number row:
+//   - NUM: % of characters typed on the number row
+//   - NUM-SFB: % of bigrams with a digit or a number row character that are typed by one
+//     finger on two different keys, such as "1q" with a number row, or "2," with digits on the
+//     main keys
+//   - NUM-MISS: % of characters that are digits without a key, neither on the main keys nor
+//     on the number row
+//
+// Digits on the main keys or thumbs count in NUM-SFB alike, so that layouts that move the
+// digits off the number row can be compared with those that keep them.
+func (an *Analyser) analyseNumbers() {
+	fold := an.Corpus.foldNumbers(an.Layout)
+	var onRow, missing, sfbs uint64
+	for r, cnt := range fold.unigrams {
+		if _, ok := an.Layout.numberKey(r); ok {
+			onRow += cnt
+		} else if _, _, ok := an.Layout.numberKeyFinger(r); !ok {
+			missing += cnt
+		}
+	}
+	for _, nb := range fold.bigrams {
+		k0, f0, ok0 := an.Layout.numberKeyFinger(nb.runes[0])
+		k1, f1, ok1 := an.Layout.numberKeyFinger(nb.runes[1])
+		if ok0 && ok1 && k0 != k1 && f0 == f1 {
+			sfbs += nb.count
+		}
+	}
+
+	an.Metrics["NUM"], an.Metrics["NUM-SFB"], an.Metrics["NUM-MISS"] = 0, 0, 0
+	if an.Corpus.TotalUnigramsCount > 0 {
+		an.Metrics["NUM"] = 100 * float64(onRow) / float64(an.Corpus.TotalUnigramsCount)
+		an.Metrics["NUM-MISS"] = 100 * float64(missing) / float64(an.Corpus.TotalUnigramsCount)
+	}
+	if an.Corpus.TotalBigramsCount > 0 {
+		an.Metrics["NUM-SFB"] = 100 * float64(sfbs) / float64(an.Corpus.TotalBigramsCount)
+	}
+}
@@ -0,0 +1,91 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLayoutFromFile_NumberRow(t *testing.T) {
+	sl := Must(writeShiftedLayout(t, "\nnumbers: ~ 1 2 3 4 5  6 7 8 9 0 -\n"))
+	if sl.NumberRow == nil || sl.NumberRow[1] != '1' || sl.NumberRow[10] != '0' || sl.NumberRow[0] != 0 {
+		t.Fatalf("number row = %v, want ~ 1 ... 0 -", sl.NumberRow)
+	}
+	if _, ok := sl.GetKeyInfo('1'); ok {
+		t.Error("a number row key should not be one of the 42 keys")
+	}
+
+	// Saving and loading keeps the number row, and mirroring reverses it
+	path := filepath.Join(t.TempDir(), "saved.klf")
+	Must0(sl.SaveToFile(path))
+	loaded := Must(NewLayoutFromFile("saved", path))
+	if loaded.NumberRow == nil || *loaded.NumberRow != *sl.NumberRow {
+		t.Errorf("reloaded number row %v, want %v", loaded.NumberRow, sl.NumberRow)
+	}
+	if layoutCacheKey(loaded) == layoutCacheKey(Must(writeShiftedLayout(t, ""))) {
+		t.Error("cache key should depend on the number row")
+	}
+	clone := sl.Clone()
+	clone.FlipHorizontal()
+	if clone.NumberRow[11] != 0 || clone.NumberRow[1] != '0' || sl.NumberRow[1] != '1' {
+		t.Errorf("mirrored number row %v, original %v", clone.NumberRow, sl.NumberRow)
+	}
+
+	tests := []struct {
+		name, settings, want string
+	}{
+		{"too few keys", "\nnumbers: 1 2 3\n", "expected 12"},
+		{"long key", "\nnumbers: ~ 12 2 3 4 5 6 7 8 9 0 ~\n", "must have 1 character"},
+		{"on main rows", "\nnumbers: ~ 1 2 3 4 5 6 7 8 9 0 q\n", "already has a key"},
+		{"duplicate", "\nnumbers: ~ 1 1 3 4 5 6 7 8 9 0 ~\n", "duplicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := writeShiftedLayout(t, tt.settings)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyser_Numbers(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("1q 15 9x")
+	uni, bi := float64(corpus.TotalUnigramsCount), float64(corpus.TotalBigramsCount)
+
+	// '1' and 'q' are both typed by the left pinky
+	sl := Must(writeShiftedLayout(t, "\nnumbers: ~ 1 2 3 4 5 6 7 8 9 0 ~\n"))
+	an := NewAnalyser(sl, corpus, nil)
+	if got, want := an.Metrics["NUM"], 400/uni; got != want {
+		t.Errorf("NUM = %v, want %v", got, want)
+	}
+	if got, want := an.Metrics["NUM-SFB"], 100/bi; got != want {
+		t.Errorf("NUM-SFB = %v, want %v", got, want)
+	}
+	if got := an.Metrics["NUM-MISS"]; got != 0 {
+		t.Errorf("NUM-MISS = %v, want 0", got)
+	}
+
+	// Without a number row, the digits have no key
+	plain := NewAnalyser(Must(writeShiftedLayout(t, "")), corpus, nil)
+	if plain.Metrics["NUM"] != 0 || plain.Metrics["NUM-SFB"] != 0 {
+		t.Errorf("layout without a number row has NUM %v and NUM-SFB %v",
+			plain.Metrics["NUM"], plain.Metrics["NUM-SFB"])
+	}
+	if got, want := plain.Metrics["NUM-MISS"], 400/uni; got != want {
+		t.Errorf("NUM-MISS = %v, want %v", got, want)
+	}
+
+	// Digits on the main keys count alike: '1' and '5' replace 'a' and 'z', so that "1q" and
+	// "15" are typed by the left pinky, and '9' has no key
+	runes := sl.Runes
+	runes[13], runes[25] = '1', '5'
+	main := NewAnalyser(NewSplitLayout("main", ROWSTAG, runes), corpus, nil)
+	if got, want := main.Metrics["NUM-SFB"], 200/bi; got != want {
+		t.Errorf("NUM-SFB with digits on the main keys = %v, want %v", got, want)
+	}
+	if got, want := main.Metrics["NUM-MISS"], 100/uni; got != want {
+		t.Errorf("NUM-MISS with digits on the main keys = %v, want %v", got, want)
+	}
+}
@@ -1,8 +1,10 @@
 package keycraft
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"time"
 )
 
 // OptimizeInput encapsulates parameters for BLS optimization.
@@ -10,35 +12,75 @@ type OptimizeInput struct {
 	Layout          *SplitLayout
 	LayoutsDir      string
 	Corpus          *Corpus
+	Corpora         []WeightedCorpus // Optional: score against each corpus, combining costs by weight
 	Targets         *TargetLoads
 	Weights         *Weights
+	Reference       *ReferenceSet // Layouts used for normalisation stats (nil = default naming rule)
 	Pinned          *PinnedKeys
+	SwapClasses     *SwapClasses // Optional: swap keys only with keys of the same rune class (nil = any keys)
 	NumGenerations  int
 	MaxTime         int // minutes
 	Seed            int64
-	LogFile         io.Writer
-	Medians         map[string]float64 // Optional: pre-computed filtered medians (skip LoadAnalysers)
-	IQRs            map[string]float64 // Optional: pre-computed filtered IQRs (skip LoadAnalysers)
-	FilteredWeights map[string]float64 // Optional: pre-computed filtered weights (used with Medians/IQRs)
-	UseParallel     bool               // Enable parallel evaluation in BLS steepest descent
+	HistoryFile     io.Writer              // Optional: JSONL history of every new-best layout
+	Medians         map[string]float64     // Optional: pre-computed filtered medians (skip LoadAnalysers)
+	IQRs            map[string]float64     // Optional: pre-computed filtered IQRs (skip LoadAnalysers)
+	FilteredWeights map[string]float64     // Optional: pre-computed filtered weights (used with Medians/IQRs)
+	UseParallel     bool                   // Enable parallel evaluation in BLS steepest descent
+	CompoundMoves   bool                   // Enable 3-cycles and rotations besides pairwise swaps
+	ScoreCacheSize  int                    // Maximum number of cached layout scores (0 = unbounded)
+	Subsample       float64                // Fraction of the corpus to score candidates against (0 = full corpus)
+	Progress        func(BLSProgress)      // Optional: called with a snapshot of the search after every iteration
+	Tuning          *BLSTuning             // Optional: BLS parameters to use instead of the defaults
+	MaxChanges      int                    // Key positions the best layout may differ from Layout by (0 = unlimited)
+	ChangeWeight    float64                // Cost of each key position that differs from Layout
+	PlaceRemaining  *PinnedKeys            // Optional: afterwards, place the corpus punctuation on the punctuation keys not pinned here
+	Perturbations   []WeightedPerturbation // Optional: custom perturbation strategies besides the built-in ones
+	Taboo           *TabooPatterns         // Optional: bigrams and trigrams the best layout must not type as SFBs or scissors
+	SpillKeys       *Region                // Optional: empty keys that characters may be moved to (see SplitLayout.SpillKeys)
 }
 
 // OptimizeResult contains optimization results.
 type OptimizeResult struct {
 	OriginalLayout *SplitLayout
 	BestLayout     *SplitLayout
+	Interrupted    bool              // Whether the search was cancelled, leaving the best layout found so far
+	Trajectory     []TrajectoryPoint // Cost of every new best layout, starting with OriginalLayout
+	Placed         []rune            // Characters that the placement of remaining characters added to BestLayout
+	Dropped        []rune            // Characters that the placement of remaining characters left out of BestLayout
+	Taboo          []Bigram          // Taboo bigrams that BestLayout still types as SFBs or scissors
+}
+
+// TrajectoryPoint is the cost of a new best layout found during optimization.
+type TrajectoryPoint struct {
+	Iteration int
+	Elapsed   time.Duration
+	Cost      float64 // Cost against the full corpus (lower is better)
 }
 
 // OptimizeLayout performs BLS optimization.
 // This is the pure computation function that doesn't handle I/O or rendering.
-func OptimizeLayout(input OptimizeInput, consoleWriter io.Writer) (*OptimizeResult, error) {
-	best, err := OptimizeLayoutBLS(input, consoleWriter)
+// Cancelling ctx stops the search early with the best layout found so far.
+func OptimizeLayout(ctx context.Context, input OptimizeInput, consoleWriter io.Writer) (*OptimizeResult, error) {
+	original := input.Layout
+	if input.SpillKeys != nil {
+		input.Layout = input.Layout.withSpillPlaceholders(input.SpillKeys)
+	}
+	bls, best, err := runBLS(ctx, input, consoleWriter)
 	if err != nil {
 		return nil, fmt.Errorf("could not optimize layout: %w", err)
 	}
 
-	return &OptimizeResult{
-		OriginalLayout: input.Layout,
+	result := &OptimizeResult{
+		OriginalLayout: original,
 		BestLayout:     best,
-	}, nil
+		Interrupted:    bls.Interrupted(),
+		Trajectory:     bls.Trajectory(),
+	}
+	if input.PlaceRemaining != nil && !result.Interrupted {
+		result.BestLayout, result.Placed, result.Dropped =
+			placeRemaining(ctx, best, input.Corpus, bls.scorer, input.PlaceRemaining)
+	}
+	result.BestLayout = result.BestLayout.withoutSpillPlaceholders()
+	result.Taboo = bls.scorer.TabooViolations(result.BestLayout)
+	return result, nil
 }
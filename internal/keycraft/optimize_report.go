@@ -0,0 +1,59 @@
+package keycraft
+
+import "sort"
+
+// KeyMove is a character that is on another key of the optimized layout than of the input
+// layout.
+type KeyMove struct {
+	Rune     rune
+	From, To uint8 // Key indices in the input and the optimized layout
+}
+
+// OptimizeReport describes what changed from the input layout to the optimized layout: the
+// keys that moved, the metrics before and after, and the costs of the new best layouts found
+// on the way.
+type OptimizeReport struct {
+	Original, Best *SplitLayout
+	Moves          []KeyMove          // Moved characters, in order of their key in the input layout
+	Placed         []rune             // Characters that the placement of remaining characters added
+	Dropped        []rune             // Characters that the placement of remaining characters left out
+	Metrics        []string           // Metrics to compare, in display order
+	Before, After  map[string]float64 // Metrics of the input and the optimized layout
+	Trajectory     []TrajectoryPoint
+	Interrupted    bool
+}
+
+// NewOptimizeReport analyses the input and the optimized layout of an optimization, and
+// lists the characters that moved. Metrics are the metrics to compare; the corpus and targets
+// should be those the layout was optimized with.
+func NewOptimizeReport(result *OptimizeResult, corpus *Corpus, targets *TargetLoads, metrics []string) *OptimizeReport {
+	report := &OptimizeReport{
+		Original:    result.OriginalLayout,
+		Best:        result.BestLayout,
+		Metrics:     metrics,
+		Before:      NewAnalyser(result.OriginalLayout, corpus, targets).Metrics,
+		After:       NewAnalyser(result.BestLayout, corpus, targets).Metrics,
+		Trajectory:  result.Trajectory,
+		Interrupted: result.Interrupted,
+		Placed:      result.Placed,
+		Dropped:     result.Dropped,
+	}
+	report.Moves = KeyMoves(result.OriginalLayout, result.BestLayout)
+	return report
+}
+
+// KeyMoves returns the characters of layout a that are on another key of layout b, in order
+// of their key in a. Characters that b lacks are left out.
+func KeyMoves(a, b *SplitLayout) []KeyMove {
+	var moves []KeyMove
+	for i, r := range a.Runes {
+		if r == 0 {
+			continue
+		}
+		if ki, ok := b.RuneInfo[r]; ok && ki.Index != uint8(i) {
+			moves = append(moves, KeyMove{Rune: r, From: uint8(i), To: ki.Index})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].From < moves[j].From })
+	return moves
+}
@@ -0,0 +1,48 @@
+package keycraft
+
+import (
+	"context"
+	"testing"
+)
+
+// TestNewOptimizeReport checks that the report lists the moved keys and the metrics of both
+// layouts, and that the trajectory of a search improves from the input layout to the best.
+func TestNewOptimizeReport(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 20
+	best := bls.Optimize(context.Background(), layout, nil)
+
+	trajectory := bls.Trajectory()
+	if len(trajectory) == 0 || trajectory[0].Iteration != 0 {
+		t.Fatalf("trajectory %v should start with the input layout", trajectory)
+	}
+	if first, last := trajectory[0], trajectory[len(trajectory)-1]; first.Cost != bls.scorer.ScoreExact(layout) ||
+		last.Cost != bls.state.bestCost {
+		t.Errorf("trajectory from %.4f to %.4f, want from the input cost to the best cost %.4f",
+			first.Cost, last.Cost, bls.state.bestCost)
+	}
+	for i := 1; i < len(trajectory); i++ {
+		if trajectory[i].Cost >= trajectory[i-1].Cost {
+			t.Errorf("cost %d of the trajectory did not improve: %v", i, trajectory)
+		}
+	}
+
+	result := &OptimizeResult{OriginalLayout: layout, BestLayout: best, Trajectory: trajectory}
+	report := NewOptimizeReport(result, bls.corpus, nil, []string{"SFB"})
+	if len(report.Moves) != KeyDiff(layout, best) {
+		t.Errorf("got %d moves, want %d", len(report.Moves), KeyDiff(layout, best))
+	}
+	for _, m := range report.Moves {
+		if layout.Runes[m.From] != m.Rune || best.Runes[m.To] != m.Rune || m.From == m.To {
+			t.Errorf("move %+v does not match the layouts", m)
+		}
+	}
+	if report.Before["SFB"] != NewAnalyser(layout, bls.corpus, nil).Metrics["SFB"] ||
+		report.After["SFB"] != NewAnalyser(best, bls.corpus, nil).Metrics["SFB"] {
+		t.Errorf("SFB before %v and after %v do not match the layouts", report.Before["SFB"], report.After["SFB"])
+	}
+
+	if moves := KeyMoves(layout, layout); len(moves) != 0 {
+		t.Errorf("got %d moves from a layout to itself, want 0", len(moves))
+	}
+}
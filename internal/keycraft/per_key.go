@@ -0,0 +1,63 @@
+package keycraft
+
+// KeyAttribution holds per-key shares of the layout's load and bigram metrics,
+// indexed by key position (0-41). All values are percentages of the corpus.
+//
+// The count of a bigram is split evenly between its two keys, so summing a metric
+// over all keys gives the layout-level metric (e.g. the SFB values add up to SFB).
+type KeyAttribution struct {
+	Load     [42]float64 // Share of all corpus unigrams typed on the key
+	SFB      [42]float64 // Share of same-finger bigrams
+	LSB      [42]float64 // Share of lateral stretch bigrams
+	Scissors [42]float64 // Share of full and half scissor bigrams (FSB + HSB)
+}
+
+// PerKeyMetrics aggregates load and the SFB, LSB and scissor pattern caches of the
+// layout per physical key position.
+func (an *Analyser) PerKeyMetrics() *KeyAttribution {
+	ka := &KeyAttribution{}
+
+	if an.Corpus.TotalUnigramsCount > 0 {
+		factor := 100 / float64(an.Corpus.TotalUnigramsCount)
+		for uniGr, uniCnt := range an.Corpus.Unigrams {
+			if key, ok := an.Layout.GetKeyInfo(rune(uniGr)); ok {
+				ka.Load[key.Index] += float64(uniCnt) * factor
+			}
+		}
+	}
+
+	if an.Corpus.TotalBigramsCount == 0 {
+		return ka
+	}
+	half := 50 / float64(an.Corpus.TotalBigramsCount)
+	attribute := func(dst *[42]float64, idx1, idx2 uint8) {
+		bi := Bigram{an.Layout.Runes[idx1], an.Layout.Runes[idx2]}
+		if cnt, ok := an.Corpus.Bigrams[bi]; ok {
+			share := float64(cnt) * half
+			dst[idx1] += share
+			dst[idx2] += share
+		}
+	}
+
+	for _, sfb := range an.Layout.SFBs {
+		attribute(&ka.SFB, sfb.KeyIdx1, sfb.KeyIdx2)
+	}
+	if an.countsRepeats() {
+		for idx, r := range an.Layout.Runes {
+			if r != 0 {
+				attribute(&ka.SFB, uint8(idx), uint8(idx))
+			}
+		}
+	}
+	for _, lsb := range an.lsbs() {
+		attribute(&ka.LSB, lsb.KeyIdx1, lsb.KeyIdx2)
+	}
+	for _, sci := range an.Layout.FScissors {
+		attribute(&ka.Scissors, sci.keyIdx1, sci.keyIdx2)
+	}
+	for _, sci := range an.Layout.HScissors {
+		attribute(&ka.Scissors, sci.keyIdx1, sci.keyIdx2)
+	}
+
+	return ka
+}
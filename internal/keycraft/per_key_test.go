@@ -0,0 +1,63 @@
+package keycraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPerKeyMetrics_SumsMatchMetrics(t *testing.T) {
+	layout, err := NewLayoutFromFile("qwerty", "../../data/layouts/qwerty.klf")
+	if err != nil {
+		t.Skipf("Skipping test - layout not available: %v", err)
+	}
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; my deck was cedar")
+
+	an := NewAnalyser(layout, corpus, nil)
+	ka := an.PerKeyMetrics()
+
+	sum := func(v [42]float64) float64 {
+		var s float64
+		for _, x := range v {
+			s += x
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"SFB", sum(ka.SFB), an.Metrics["SFB"]},
+		{"LSB", sum(ka.LSB), an.Metrics["LSB"]},
+		{"Scissors", sum(ka.Scissors), an.Metrics["FSB"] + an.Metrics["HSB"]},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s: per-key sum %.6f, want %.6f", tt.name, tt.got, tt.want)
+		}
+	}
+	if an.Metrics["SFB"] == 0 {
+		t.Error("expected test corpus to contain SFBs on qwerty")
+	}
+
+	// Load covers every corpus character on the layout
+	var onLayout uint64
+	for uni, cnt := range corpus.Unigrams {
+		if _, ok := layout.GetKeyInfo(rune(uni)); ok {
+			onLayout += cnt
+		}
+	}
+	wantLoad := 100 * float64(onLayout) / float64(corpus.TotalUnigramsCount)
+	if got := sum(ka.Load); math.Abs(got-wantLoad) > 1e-9 {
+		t.Errorf("Load: per-key sum %.6f, want %.6f", got, wantLoad)
+	}
+
+	// The 'e' key carries the ed/de/ce/ec SFBs on qwerty
+	e, _ := layout.GetKeyInfo('e')
+	if ka.SFB[e.Index] == 0 {
+		t.Error("expected SFB share on the e key")
+	}
+}
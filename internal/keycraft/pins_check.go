@@ -0,0 +1,63 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PinsCheck is the result of checking a pins file against a layout (see CheckPins).
+type PinsCheck struct {
+	Free         int      // Number of free keys, as the optimiser sees them
+	FreeInCorpus int      // Number of free keys with characters that occur in the corpus
+	Warnings     []string // Pins that are probably not what was meant
+}
+
+// CheckPins checks pins against a layout and corpus. Pinned empty keys suggest that the
+// pins were written for another layout. Free keys whose characters don't occur in the corpus
+// don't change the score wherever they go, so the optimiser can't improve the layout by
+// moving only them.
+func CheckPins(pinned *PinnedKeys, sl *SplitLayout, corpus *Corpus) *PinsCheck {
+	check := &PinsCheck{}
+	var pinnedEmpty, freeAbsent []string
+	for i, r := range sl.Runes {
+		switch {
+		case pinned[i] && r == 0:
+			pinnedEmpty = append(pinnedEmpty, keyPosName(uint8(i)))
+		case pinned[i]:
+		case r == 0:
+			check.Free++
+		case corpus.Unigrams[Unigram(r)] == 0:
+			check.Free++
+			freeAbsent = append(freeAbsent, fmt.Sprintf("%s %q", keyPosName(uint8(i)), r))
+		default:
+			check.Free++
+			check.FreeInCorpus++
+		}
+	}
+
+	if len(pinnedEmpty) > 0 {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("%d pinned keys are empty on %s, so the pins may be meant for another layout: %s",
+			len(pinnedEmpty), sl.Name, strings.Join(pinnedEmpty, ", ")))
+	}
+	if len(freeAbsent) > 0 {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("%d free characters don't occur in corpus %s, so moving them doesn't change the score: %s",
+			len(freeAbsent), corpus.Name, strings.Join(freeAbsent, ", ")))
+	}
+	switch {
+	case check.Free < 2:
+		check.Warnings = append(check.Warnings, "fewer than 2 keys are free, so the optimiser can't swap any keys")
+	case check.FreeInCorpus == 0:
+		check.Warnings = append(check.Warnings, fmt.Sprintf("none of the free keys have characters of corpus %s, so the optimiser can't improve the layout",
+			corpus.Name))
+	}
+	return check
+}
+
+// keyPosName names a key position: "r1c5" for rows r0-r2 (top, home, bottom) and columns
+// c0-c11, or "t2" for thumb keys t0-t5.
+func keyPosName(idx uint8) string {
+	if idx >= 36 {
+		return fmt.Sprintf("t%d", idx-36)
+	}
+	return fmt.Sprintf("r%dc%d", idx/12, idx%12)
+}
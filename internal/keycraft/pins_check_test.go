@@ -0,0 +1,43 @@
+package keycraft
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPins(t *testing.T) {
+	sl := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+
+	// The thumb keys are pinned, of which all but space are empty; the free digits 1-4 don't
+	// occur in the corpus
+	check := CheckPins(Must(ParsePinPolicy("pin-punct,pin-thumbs")).Pins(sl), sl, corpus)
+	if check.Free != 30 || check.FreeInCorpus != 26 {
+		t.Errorf("free keys = %d (%d in corpus), want 30 (26 in corpus)", check.Free, check.FreeInCorpus)
+	}
+	if len(check.Warnings) != 2 ||
+		!strings.HasPrefix(check.Warnings[0], "5 pinned keys are empty") ||
+		!strings.HasPrefix(check.Warnings[1], "4 free characters don't occur") {
+		t.Errorf("warnings = %q, want pinned empty keys and free characters absent from the corpus", check.Warnings)
+	}
+
+	// Freeing only digits leaves nothing for the optimiser to improve
+	pinned := &PinnedKeys{}
+	for i := range pinned {
+		pinned[i] = i != 0 && i != 12
+	}
+	check = CheckPins(pinned, sl, corpus)
+	if check.Free != 2 || check.FreeInCorpus != 0 {
+		t.Errorf("free keys = %d (%d in corpus), want 2 (0 in corpus)", check.Free, check.FreeInCorpus)
+	}
+	if last := check.Warnings[len(check.Warnings)-1]; !strings.HasPrefix(last, "none of the free keys") {
+		t.Errorf("last warning = %q, want that no free keys have characters of the corpus", last)
+	}
+
+	pinned[0] = false
+	pinned[12] = true
+	if check = CheckPins(pinned, sl, corpus); !strings.HasPrefix(check.Warnings[len(check.Warnings)-1], "fewer than 2 keys are free") {
+		t.Errorf("warnings = %q, want that too few keys are free", check.Warnings)
+	}
+}
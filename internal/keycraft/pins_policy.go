@@ -0,0 +1,115 @@
+package keycraft
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// PinRule is a rule of a pins policy, as given to pins generate. It pins the keys of a
+// layout that it matches.
+type PinRule struct {
+	Name        string                        // Name of the rule, such as "pin-punct"
+	Description string                        // What the rule pins, for the help
+	pins        func(ki KeyInfo, r rune) bool // Whether the rule pins the key ki with character r
+}
+
+// PinRules are the rules of pins policies, in the order of the help.
+var PinRules = []PinRule{
+	{"pin-letters", "keys with letters", func(_ KeyInfo, r rune) bool { return RuneClassOf(r) == LetterClass }},
+	{"pin-punct", "keys with punctuation and symbols", func(_ KeyInfo, r rune) bool { return RuneClassOf(r) == PunctClass }},
+	{"pin-digits", "keys with digits", func(_ KeyInfo, r rune) bool { return unicode.IsDigit(r) }},
+	{"pin-empty", "empty keys and space, as optimize pins by default", func(_ KeyInfo, r rune) bool { return r == 0 || unicode.IsSpace(r) }},
+	{"pin-thumbs", "all thumb keys", func(ki KeyInfo, _ rune) bool { return ki.Row == 3 }},
+	{"pin-top", "all keys of the top row", func(ki KeyInfo, _ rune) bool { return ki.Row == 0 }},
+	{"pin-home", "all keys of the home row", func(ki KeyInfo, _ rune) bool { return ki.Row == 1 }},
+	{"pin-bottom", "all keys of the bottom row", func(ki KeyInfo, _ rune) bool { return ki.Row == 2 }},
+	{"pin-top-rownums", "keys of the top row with digits, as on layouts with the numbers on the top row",
+		func(ki KeyInfo, r rune) bool { return ki.Row == 0 && unicode.IsDigit(r) }},
+}
+
+// PinPolicy is a list of pin rules. A key is pinned if any of the rules pins it.
+type PinPolicy []PinRule
+
+// ParsePinPolicy parses a comma-separated list of the names of PinRules, such as
+// "pin-punct,pin-thumbs".
+func ParsePinPolicy(spec string) (PinPolicy, error) {
+	var policy PinPolicy
+	for part := range strings.SplitSeq(spec, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(PinRules, func(rule PinRule) bool { return rule.Name == name })
+		if i < 0 {
+			names := make([]string, len(PinRules))
+			for j, rule := range PinRules {
+				names[j] = rule.Name
+			}
+			return nil, fmt.Errorf("invalid pins policy rule %q; must be one of: %s", name, strings.Join(names, ", "))
+		}
+		policy = append(policy, PinRules[i])
+	}
+	if len(policy) == 0 {
+		return nil, fmt.Errorf("pins policy is empty")
+	}
+	return policy, nil
+}
+
+// String returns the policy as a comma-separated list of its rules.
+func (p PinPolicy) String() string {
+	names := make([]string, len(p))
+	for i, rule := range p {
+		names[i] = rule.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// Pins returns the keys of a layout that the policy pins.
+func (p PinPolicy) Pins(sl *SplitLayout) *PinnedKeys {
+	pinned := &PinnedKeys{}
+	for i, r := range sl.Runes {
+		ki := NewKeyInfo(uint8(i/12), uint8(i%12), sl.LayoutType)
+		for _, rule := range p {
+			if rule.pins(ki, r) {
+				pinned[i] = true
+			}
+		}
+	}
+	return pinned
+}
+
+// WritePins writes pins in the format of LoadPins, each row preceded by a comment with the
+// characters of the layout on its keys ('~' for empty keys and '_' for space), so that the
+// file can be edited without looking up the layout.
+func WritePins(w io.Writer, pinned *PinnedKeys, sl *SplitLayout, comment string) error {
+	var b strings.Builder
+	if comment != "" {
+		fmt.Fprintf(&b, "# %s\n", comment)
+	}
+	b.WriteString("# Pinned keys are *, free keys are .\n")
+	for row, n := range []int{12, 12, 12, 6} {
+		keys := make([]string, n)
+		pins := make([]string, n)
+		for col := range n {
+			i := 12*row + col
+			switch r := sl.Runes[i]; {
+			case r == 0:
+				keys[col] = "~"
+			case r == ' ':
+				keys[col] = "_"
+			default:
+				keys[col] = string(r)
+			}
+			pins[col] = "."
+			if pinned[i] {
+				pins[col] = "*"
+			}
+		}
+		fmt.Fprintf(&b, "\n# %s\n%s\n", strings.Join(keys, " "), strings.Join(pins, " "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
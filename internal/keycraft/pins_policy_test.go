@@ -0,0 +1,48 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPinPolicy_Pins(t *testing.T) {
+	sl := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	for spec, want := range map[string][]int{
+		"pin-top-rownums":     {0},
+		"pin-digits":          {0, 12, 24, 35},
+		"pin-punct":           {11, 22, 23, 32, 33, 34},
+		" PIN-Thumbs , ":      {36, 37, 38, 39, 40, 41},
+		"pin-empty,pin-punct": {11, 22, 23, 32, 33, 34, 36, 37, 38, 39, 40, 41},
+	} {
+		pinned := Must(ParsePinPolicy(spec)).Pins(sl)
+		wantPins := PinnedKeys{}
+		for _, i := range want {
+			wantPins[i] = true
+		}
+		if *pinned != wantPins {
+			t.Errorf("pins of %q = %v, want %v", spec, pinned, wantPins.String())
+		}
+	}
+
+	for _, bad := range []string{"", ",", "pin-punct,pin-everything"} {
+		if _, err := ParsePinPolicy(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestWritePins(t *testing.T) {
+	sl := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	pinned := Must(ParsePinPolicy("pin-punct,pin-thumbs")).Pins(sl)
+
+	path := filepath.Join(t.TempDir(), "test.pin")
+	file := Must(os.Create(path))
+	Must0(WritePins(file, pinned, sl, "Pins of test"))
+	Must0(file.Close())
+
+	// The comments with the characters of the layout are skipped when loading
+	if loaded := Must(LoadPins(path)); *loaded != *pinned {
+		t.Errorf("loaded pins = %v, want %v", loaded, pinned)
+	}
+}
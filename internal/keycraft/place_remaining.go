@@ -0,0 +1,156 @@
+package keycraft
+
+import (
+	"context"
+	"sort"
+)
+
+// placeRemaining places the punctuation and symbols of the corpus on the punctuation keys of
+// a layout after the search. The most frequent of the characters on those keys and of the
+// characters that the layout can't type get a key; then keys are swapped among those
+// positions while that improves the cost. Keys that are pinned, the magic key and the base
+// keys of shifted characters stay as they are. It returns the new layout, and the
+// characters that were added to and removed from it.
+//
+// Which characters get a key goes by frequency rather than by cost, since the metrics
+// ignore characters without a key: leaving out a character that is hard to place would
+// otherwise look like an improvement. Cancelling ctx stops the swaps early.
+func placeRemaining(ctx context.Context, sl *SplitLayout, corpus *Corpus, scorer *Scorer,
+	pinned *PinnedKeys) (*SplitLayout, []rune, []rune) {
+	slots := remainingSlots(sl, pinned)
+	if len(slots) == 0 {
+		return sl, nil, nil
+	}
+
+	// Choose the characters to place: the most frequent of those on the slots and those
+	// without a key, preferring characters already on the layout on ties
+	onSlots := make(map[rune]bool, len(slots))
+	chars := make([]rune, 0, len(slots))
+	for _, idx := range slots {
+		onSlots[sl.Runes[idx]] = true
+		chars = append(chars, sl.Runes[idx])
+	}
+	chars = append(chars, missingPunct(sl, corpus)...)
+	sort.SliceStable(chars, func(i, j int) bool {
+		ci, cj := corpus.Unigrams[Unigram(chars[i])], corpus.Unigrams[Unigram(chars[j])]
+		if ci != cj {
+			return ci > cj
+		}
+		if onSlots[chars[i]] != onSlots[chars[j]] {
+			return onSlots[chars[i]]
+		}
+		return chars[i] < chars[j]
+	})
+	chosen := make(map[rune]bool, len(slots))
+	for _, r := range chars[:len(slots)] {
+		chosen[r] = true
+	}
+
+	// Put the new characters on the slots of the characters that are left out
+	best := sl.Clone()
+	var placed, dropped []rune
+	for _, r := range chars[:len(slots)] {
+		if !onSlots[r] {
+			placed = append(placed, r)
+		}
+	}
+	i := 0
+	for _, idx := range slots {
+		if old := best.Runes[idx]; !chosen[old] {
+			dropped = append(dropped, old)
+			best.replaceRune(idx, placed[i])
+			i++
+		}
+	}
+
+	// Swap characters among the slots while that improves the cost
+	cost := scorer.scoreFull(best)
+	for ctx.Err() == nil {
+		bestI, bestJ, bestCost := -1, -1, cost
+		for a := range slots {
+			for b := a + 1; b < len(slots); b++ {
+				best.Swap(slots[a], slots[b])
+				if c := scorer.scoreFull(best); c < bestCost {
+					bestI, bestJ, bestCost = a, b, c
+				}
+				best.Swap(slots[a], slots[b])
+			}
+		}
+		if bestI < 0 {
+			break
+		}
+		best.Swap(slots[bestI], slots[bestJ])
+		cost = bestCost
+	}
+	return best, placed, dropped
+}
+
+// remainingSlots returns the positions of the punctuation keys of the layout that
+// placeRemaining may change.
+func remainingSlots(sl *SplitLayout, pinned *PinnedKeys) []uint8 {
+	bases := make(map[rune]bool, len(sl.Shifted))
+	for _, base := range sl.Shifted {
+		bases[base] = true
+	}
+	var slots []uint8
+	for idx, r := range sl.Runes {
+		if r == 0 || RuneClassOf(r) != PunctClass || (pinned != nil && pinned[idx]) || bases[r] ||
+			(sl.Magic != nil && sl.Magic.Rune == r) {
+			continue
+		}
+		slots = append(slots, uint8(idx))
+	}
+	return slots
+}
+
+// missingPunct returns the punctuation and symbols of the corpus that the layout can't
+// type: not on a key, not shifted, and not on the number row.
+func missingPunct(sl *SplitLayout, corpus *Corpus) []rune {
+	var missing []rune
+	for uni, cnt := range corpus.Unigrams {
+		r := rune(uni)
+		if cnt == 0 || RuneClassOf(r) != PunctClass {
+			continue
+		}
+		if _, ok := sl.GetKeyInfo(r); ok {
+			continue
+		}
+		if _, ok := sl.numberKey(r); ok {
+			continue
+		}
+		missing = append(missing, r)
+	}
+	return missing
+}
+
+// replaceRune puts another character on a key that has one. Since the key stays in use,
+// the derived caches of the layout remain valid.
+func (sl *SplitLayout) replaceRune(idx uint8, r rune) {
+	old := sl.Runes[idx]
+	ki := sl.RuneInfo[old]
+	delete(sl.RuneInfo, old)
+	if old >= 32 && old < 127 {
+		sl.KeyInfoValid[old-32] = false
+	}
+
+	sl.Runes[idx] = r
+	sl.RuneInfo[r] = ki
+	if r >= 32 && r < 127 {
+		sl.KeyInfos[r-32] = ki
+		sl.KeyInfoValid[r-32] = true
+	}
+}
+
+// scoreFull returns the cost of a layout against the full corpus, with all trigrams of the
+// layout rather than the pre-filtered trigrams of the first layout scored, which lack the
+// characters that placeRemaining adds.
+func (sc *Scorer) scoreFull(layout *SplitLayout) float64 {
+	if len(sc.parts) > 0 {
+		var score float64
+		for _, part := range sc.parts {
+			score += part.weight * part.scorer.scoreFull(layout)
+		}
+		return score + sc.penalty(layout)
+	}
+	return sc.scoreAgainst(layout, sc.corpus, nil) + sc.penalty(layout)
+}
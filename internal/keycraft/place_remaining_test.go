@@ -0,0 +1,62 @@
+package keycraft
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPlaceRemaining(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf, "c.klf": testLayoutVariantKlf})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("hello, world! what? yes! no! ok; fine")
+	scorer := Must(NewScorer(dir, corpus, NewTargetLoads(), NewWeights(), nil))
+
+	// '!' and '?' have no key, and are more frequent than '/' and '\', which have none in the
+	// corpus; ';' is pinned
+	semicolon := layout.RuneInfo[';'].Index
+	pinned := &PinnedKeys{}
+	pinned[semicolon] = true
+	best, placed, dropped := placeRemaining(context.Background(), layout, corpus, scorer, pinned)
+
+	slices.Sort(placed)
+	slices.Sort(dropped)
+	if string(placed) != "!?" || string(dropped) != "/\\" {
+		t.Fatalf("placed %q and dropped %q, want \"!?\" and \"/\\\\\"", string(placed), string(dropped))
+	}
+	for _, r := range "!?,.'" {
+		if ki, ok := best.GetKeyInfo(r); !ok || RuneClassOf(layout.Runes[ki.Index]) != PunctClass {
+			t.Errorf("%q should be on a punctuation key of the input layout", r)
+		}
+	}
+	if best.Runes[semicolon] != ';' {
+		t.Error("the pinned ';' should stay on its key")
+	}
+	if _, ok := best.GetKeyInfo('/'); ok {
+		t.Error("'/' should be left out")
+	}
+	if _, ok := layout.GetKeyInfo('!'); ok || layout.Runes[semicolon] != ';' {
+		t.Error("the input layout should not change")
+	}
+	for i, r := range layout.Runes {
+		if RuneClassOf(r) != PunctClass && best.Runes[i] != r {
+			t.Errorf("key %d changed from %q to %q, want only punctuation keys to change", i, r, best.Runes[i])
+		}
+	}
+
+	// No swap of the placed keys improves the cost any further
+	slots := remainingSlots(best, pinned)
+	cost := scorer.scoreFull(best)
+	for a := range slots {
+		for b := a + 1; b < len(slots); b++ {
+			swapped := best.Clone()
+			swapped.Swap(slots[a], slots[b])
+			if c := scorer.scoreFull(swapped); c < cost {
+				t.Errorf("swapping %q and %q improves the cost from %v to %v",
+					best.Runes[slots[a]], best.Runes[slots[b]], cost, c)
+			}
+		}
+	}
+}
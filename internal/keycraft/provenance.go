@@ -0,0 +1,108 @@
+package keycraft
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Provenance records how optimize produced a layout, so that a shared layout file says
+// what it was optimized from and for. It is saved as settings of the layout file.
+type Provenance struct {
+	Source  string      // Name of the layout that was optimized
+	Seed    int64       // Seed of the search
+	Corpus  string      // Name of the corpus the layout was optimized for
+	Weights string      // Weights of the search, as given: the weights file and --weights
+	Pins    *PinnedKeys // Keys that were pinned during the search (nil = unknown)
+}
+
+// settings returns the provenance as settings lines of a layout file.
+func (p *Provenance) settings() []string {
+	settings := []string{
+		"optimized-from: " + p.Source,
+		"seed: " + strconv.FormatInt(p.Seed, 10),
+	}
+	if p.Corpus != "" {
+		settings = append(settings, "corpus: "+p.Corpus)
+	}
+	if p.Weights != "" {
+		settings = append(settings, "weights: "+p.Weights)
+	}
+	if p.Pins != nil {
+		settings = append(settings, "pins: "+p.Pins.String())
+	}
+	return settings
+}
+
+// setSetting sets a field of the provenance from a setting of a layout file. It reports
+// whether the key is a provenance setting.
+func (p *Provenance) setSetting(key, value string) (bool, error) {
+	value = strings.TrimSpace(value)
+	switch key {
+	case "optimized-from":
+		p.Source = value
+	case "seed":
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("invalid seed %q: %w", value, err)
+		}
+		p.Seed = seed
+	case "corpus":
+		p.Corpus = value
+	case "weights":
+		p.Weights = value
+	case "pins":
+		pins, err := ParsePinnedKeys(value)
+		if err != nil {
+			return true, err
+		}
+		p.Pins = pins
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// String returns the pinned keys in the format of ParsePinnedKeys: '*' for a pinned key and
+// '.' for a free key, with the rows separated by spaces.
+func (p *PinnedKeys) String() string {
+	var b strings.Builder
+	for i, pinned := range p {
+		if i > 0 && i%12 == 0 {
+			b.WriteByte(' ')
+		}
+		if pinned {
+			b.WriteByte('*')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+// ParsePinnedKeys parses 42 pins in layout order, using the characters of a pins file
+// (see LoadPins). Spaces between the pins are ignored.
+func ParsePinnedKeys(s string) (*PinnedKeys, error) {
+	pinned := &PinnedKeys{}
+	index := 0
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			continue
+		}
+		if index == 42 {
+			return nil, fmt.Errorf("pins %q have more than 42 keys", s)
+		}
+		switch r {
+		case '.', '_', '-':
+		case '*', 'x', 'X':
+			pinned[index] = true
+		default:
+			return nil, fmt.Errorf("invalid pin %q in %q (use . _ - for unpinned, * x X for pinned)", r, s)
+		}
+		index++
+	}
+	if index != 42 {
+		return nil, fmt.Errorf("pins %q have %d keys, expected 42", s, index)
+	}
+	return pinned, nil
+}
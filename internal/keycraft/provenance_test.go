@@ -0,0 +1,87 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProvenance_SavedWithLayout(t *testing.T) {
+	sl := NewSplitLayout("test-opt", ROWSTAG, qwertyRunes())
+	pins := &PinnedKeys{}
+	pins[0], pins[41] = true, true
+	sl.Provenance = &Provenance{Source: "test", Seed: 42, Corpus: "shai", Weights: "weights.txt; SFB=-10", Pins: pins}
+
+	path := filepath.Join(t.TempDir(), "test-opt.klf")
+	Must0(sl.SaveToFile(path))
+	saved := Must(NewLayoutFromFile("test-opt", path))
+	if saved.Provenance == nil {
+		t.Fatal("saved layout has no provenance")
+	}
+	got := *saved.Provenance
+	if got.Source != "test" || got.Seed != 42 || got.Corpus != "shai" || got.Weights != "weights.txt; SFB=-10" {
+		t.Errorf("provenance = %+v", got)
+	}
+	if got.Pins == nil || *got.Pins != *pins {
+		t.Errorf("pins = %v, want %v", got.Pins, pins)
+	}
+
+	// Layouts without provenance are saved without it
+	sl.Provenance = nil
+	Must0(sl.SaveToFile(path))
+	if saved := Must(NewLayoutFromFile("test-opt", path)); saved.Provenance != nil {
+		t.Errorf("provenance = %+v, want nil", saved.Provenance)
+	}
+}
+
+func TestParsePinnedKeys(t *testing.T) {
+	pins := &PinnedKeys{}
+	pins[3], pins[12], pins[36] = true, true, true
+	s := pins.String()
+	if s != "...*........ *........... ............ *....." {
+		t.Errorf("String() = %q", s)
+	}
+	if got := Must(ParsePinnedKeys(s)); *got != *pins {
+		t.Errorf("ParsePinnedKeys(%q) = %v", s, got)
+	}
+
+	for _, bad := range []string{"", "...", s + ".", s[:len(s)-1] + "?"} {
+		if _, err := ParsePinnedKeys(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestViewLayouts_Pins(t *testing.T) {
+	dir := t.TempDir()
+	withPins := NewSplitLayout("pinned", ROWSTAG, qwertyRunes())
+	optimized := NewSplitLayout("optimized", ROWSTAG, qwertyRunes())
+	optimized.Provenance = &Provenance{Source: "pinned", Pins: &PinnedKeys{1: true}}
+	plain := NewSplitLayout("plain", ROWSTAG, qwertyRunes())
+
+	pinsFile := "* . . . . . . . . . . .\n. . . . . . . . . . . .\n. . . . . . . . . . . .\n. . . . . .\n"
+	Must0(os.WriteFile(filepath.Join(dir, "pinned.pin"), []byte(pinsFile), 0644))
+
+	result := Must(ViewLayouts(ViewInput{
+		Layouts: []*SplitLayout{withPins, optimized, plain},
+		Corpus:  NewCorpus("test"),
+		PinsDir: dir,
+	}))
+	if len(result.Pins) != 3 {
+		t.Fatalf("pins = %v, want 3", result.Pins)
+	}
+	if result.Pins[0] == nil || !result.Pins[0][0] || result.Pins[0][1] {
+		t.Errorf("pins of the pins file = %v", result.Pins[0])
+	}
+	if result.Pins[1] == nil || result.Pins[1][0] || !result.Pins[1][1] {
+		t.Errorf("pins of the provenance = %v", result.Pins[1])
+	}
+	if result.Pins[2] != nil {
+		t.Errorf("pins of a layout without pins = %v", result.Pins[2])
+	}
+
+	Must0(os.WriteFile(filepath.Join(dir, "plain.pin"), []byte("* ?\n"), 0644))
+	if _, err := ViewLayouts(ViewInput{Layouts: []*SplitLayout{plain}, Corpus: NewCorpus("test"), PinsDir: dir}); err == nil {
+		t.Error("expected error for an invalid pins file")
+	}
+}
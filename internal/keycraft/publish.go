@@ -0,0 +1,100 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultPublishBaselines are the popular layouts that a new layout is compared with in its
+// publish report, unless others are given.
+var DefaultPublishBaselines = []string{"qwerty", "colemak", "colemak-dh", "graphite", "sturdy"}
+
+// PublishInput captures the inputs of the report that is published with a new layout.
+type PublishInput struct {
+	LayoutsDir string         // Layouts used for the medians/IQRs that normalise the scores
+	Reference  *ReferenceSet  // Layouts of LayoutsDir used for medians/IQRs (nil = default naming rule)
+	Layout     *SplitLayout   // Layout to publish
+	Baselines  []*SplitLayout // Layouts to compare it with
+	Corpus     *Corpus        // The corpus that the metrics are based on
+	Targets    *TargetLoads   // Load targets (row, finger, pinky penalties)
+	Weights    *Weights       // Metric weights for weighted scoring
+	TopN       int            // Number of SFBs and LSBs to list
+	Commands   []string       // Command lines that reproduce the numbers of the report
+}
+
+// PublishReport is the standard package of a new layout: the board, its metrics against
+// popular baselines, its most frequent SFBs and LSBs, the corpus the numbers are based on,
+// and the commands that reproduce them.
+type PublishReport struct {
+	Layout   *SplitLayout
+	Scores   []LayoutScore // The layout, then the baselines in the order given
+	Rank     int           // Rank of the layout by score among Scores (1 = best)
+	Metrics  []string      // Metrics to compare, in display order
+	SFBs     []NGramShare  // Most frequent SFBs of the layout
+	LSBs     []NGramShare  // Most frequent LSBs of the layout
+	Corpus   *Corpus
+	Commands []string
+}
+
+// NGramShare is an n-gram with its count in the corpus, and its share of the n-grams of its
+// kind in percent.
+type NGramShare struct {
+	NGram string
+	Count uint64
+	Pct   float64
+}
+
+// BuildPublishReport analyses the layout and the baselines, scores them like rank does, and
+// lists the most frequent SFBs and LSBs of the layout. Baselines with the name of the layout
+// are left out.
+func BuildPublishReport(input PublishInput) (*PublishReport, error) {
+	if input.Layout == nil {
+		return nil, fmt.Errorf("layout is required")
+	}
+	targets := withDefaultTargets(input.Targets)
+	reference, err := loadReferenceAnalysers(input.LayoutsDir, input.Corpus, targets, input.Reference)
+	if err != nil {
+		return nil, err
+	}
+	medians, iqrs := computeMediansAndIQR(reference, nil)
+
+	analysers := []*Analyser{NewAnalyser(input.Layout, input.Corpus, targets)}
+	for _, baseline := range input.Baselines {
+		if baseline.Name != input.Layout.Name {
+			analysers = append(analysers, NewAnalyser(baseline, input.Corpus, targets))
+		}
+	}
+	report := &PublishReport{
+		Layout:   input.Layout,
+		Scores:   computeScores(analysers, medians, iqrs, input.Weights),
+		Rank:     1,
+		Metrics:  MetricsMap["basic"],
+		Corpus:   input.Corpus,
+		Commands: input.Commands,
+	}
+	for _, score := range report.Scores[1:] {
+		if score.Score > report.Scores[0].Score {
+			report.Rank++
+		}
+	}
+	report.SFBs = topNGrams(analysers[0].SFBiDetails(), input.TopN)
+	report.LSBs = topNGrams(analysers[0].LSBiDetails(), input.TopN)
+	return report, nil
+}
+
+// topNGrams returns the n most frequent n-grams of a metric, most frequent first.
+func topNGrams(details *MetricDetails, n int) []NGramShare {
+	shares := make([]NGramShare, 0, len(details.NGramCount))
+	for ngram, count := range details.NGramCount {
+		shares = append(shares, NGramShare{NGram: ngram, Count: count,
+			Pct: 100 * float64(count) / float64(max(details.CorpusNGramC, 1))})
+	}
+	slices.SortFunc(shares, func(a, b NGramShare) int {
+		if a.Count != b.Count {
+			return IfThen(a.Count > b.Count, -1, 1)
+		}
+		return strings.Compare(a.NGram, b.NGram)
+	})
+	return shares[:min(n, len(shares))]
+}
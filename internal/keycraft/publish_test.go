@@ -0,0 +1,53 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestBuildPublishReport checks that the layout comes first and is ranked among the
+// baselines, that a baseline with its name is left out, and that the SFBs are listed most
+// frequent first.
+func TestBuildPublishReport(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf,
+	})
+	a := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	c := Must(NewLayoutFromFile("c", filepath.Join(dir, "c.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs\n")
+
+	report := Must(BuildPublishReport(PublishInput{
+		LayoutsDir: dir,
+		Layout:     a,
+		Baselines:  []*SplitLayout{a, c},
+		Corpus:     corpus,
+		Weights:    NewWeights(),
+		TopN:       3,
+	}))
+	if len(report.Scores) != 2 || report.Scores[0].Name != "a" || report.Scores[1].Name != "c" {
+		t.Fatalf("scores of %v, want a then c", report.Scores)
+	}
+	wantRank := IfThen(report.Scores[0].Score >= report.Scores[1].Score, 1, 2)
+	if report.Rank != wantRank {
+		t.Errorf("rank %d with scores %.3f and %.3f, want %d",
+			report.Rank, report.Scores[0].Score, report.Scores[1].Score, wantRank)
+	}
+
+	if len(report.SFBs) == 0 || len(report.SFBs) > 3 {
+		t.Fatalf("%d SFBs listed, want 1 to 3", len(report.SFBs))
+	}
+	for i, s := range report.SFBs {
+		if i > 0 && s.Count > report.SFBs[i-1].Count {
+			t.Errorf("SFB %s (%d) listed after %s (%d)", s.NGram, s.Count, report.SFBs[i-1].NGram, report.SFBs[i-1].Count)
+		}
+		if want := 100 * float64(s.Count) / float64(corpus.TotalBigramsCount); s.Pct != want {
+			t.Errorf("SFB %s share %.3f%%, want %.3f%%", s.NGram, s.Pct, want)
+		}
+	}
+
+	if _, err := BuildPublishReport(PublishInput{LayoutsDir: dir, Corpus: corpus, Weights: NewWeights()}); err == nil {
+		t.Error("expected an error without a layout")
+	}
+}
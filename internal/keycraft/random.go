@@ -1,11 +1,47 @@
 package keycraft
 
 import (
+	"fmt"
 	"math/rand/v2"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// NewSeed returns a new random seed, for a run without a given seed. It is always positive,
+// as 0 means "a new seed" where a seed is optional.
+func NewSeed() int64 {
+	return DeriveSeed(time.Now().UnixNano(), 0)
+}
+
+// ParseSeed parses the value of a --seed flag: a positive number, or "random" (or "" or 0)
+// for a NewSeed.
+func ParseSeed(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "random") || s == "0" {
+		return NewSeed(), nil
+	}
+	seed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || seed < 0 {
+		return 0, fmt.Errorf("invalid seed %q: must be a positive number or \"random\"", s)
+	}
+	return seed, nil
+}
+
+// DeriveSeed returns the seed of the i-th of several random streams of a run, such as the
+// layouts that generate optimizes in parallel, so that the whole run is reproducible from
+// one seed whatever the order in which the streams are used. The seeds are mixed with
+// SplitMix64, so that nearby seeds and indices give unrelated streams. The derived seed is
+// always positive.
+func DeriveSeed(seed int64, i int) int64 {
+	z := uint64(seed) + uint64(i+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return max(int64(z>>1), 1)
+}
+
 // LockedSource wraps a PCG source with a mutex to make it thread-safe.
 // This is essential for Go 1.22+ when sharing a specific seeded generator
 // across multiple goroutines.
@@ -54,6 +90,15 @@ func (ls *LockedSource) Float64() float64 {
 	return ls.rng.Float64()
 }
 
+// NormFloat64 returns a normally distributed number with mean 0 and standard deviation 1.
+// Common Use Case: Approximating the noise of a count, such as the binomial counts of a
+// corpus sample.
+func (ls *LockedSource) NormFloat64() float64 {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.rng.NormFloat64()
+}
+
 // Perm returns a slice of n integers containing a random permutation
 // of the integers [0, n).
 // Common Use Case: Determining a unique, non-repeating order for a
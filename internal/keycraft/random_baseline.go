@@ -0,0 +1,201 @@
+package keycraft
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// randomBaselineVersion is raised when the scores of a cached random baseline are computed
+// differently, so that older caches are recomputed.
+const randomBaselineVersion = 1
+
+// RandomBaselineInput captures the inputs for comparing layouts with random layouts.
+type RandomBaselineInput struct {
+	LayoutsDir string        // Layouts used for the medians/IQRs that normalise the scores
+	Reference  *ReferenceSet // Layouts of LayoutsDir used for medians/IQRs (nil = default naming rule)
+	Corpus     *Corpus       // The corpus that the layouts are analysed with
+	Targets    *TargetLoads  // Load targets (row, finger, pinky penalties)
+	Weights    *Weights      // Metric weights for weighted scoring
+	Count      int           // Number of random layouts
+	Seed       int64         // Seed of the random layouts
+	CacheDir   string        // Directory to cache the scores of the random layouts in ("" = no caching)
+}
+
+// RandomComparison is the weighted score of a layout and the scores of random layouts with
+// the same keys and characters.
+type RandomComparison struct {
+	Score    float64         // Weighted score of the layout
+	Baseline *RandomBaseline // Scores of the random layouts
+	Cached   bool            // Whether the baseline was loaded from the cache
+}
+
+// PercentBeaten returns the percentage of the random layouts that the layout beats.
+func (rc *RandomComparison) PercentBeaten() float64 {
+	return rc.Baseline.PercentBeaten(rc.Score)
+}
+
+// CompareWithRandom scores each analysed layout, and the random layouts that shuffle its
+// characters over its keys (see ShuffleConfig), with the same normalisation as rank. Layouts
+// with the same keys and characters share their random layouts. The scores of the random
+// layouts are cached per layout type, keys, characters, corpus, targets, weights and
+// normalisation, as they take some time to compute.
+func CompareWithRandom(analysers []*Analyser, input RandomBaselineInput) ([]*RandomComparison, error) {
+	if input.Count < 1 {
+		return nil, fmt.Errorf("count must be at least 1, got %d", input.Count)
+	}
+	targets := withDefaultTargets(input.Targets)
+	reference, err := loadReferenceAnalysers(input.LayoutsDir, input.Corpus, targets, input.Reference)
+	if err != nil {
+		return nil, err
+	}
+	medians, iqrs := computeMediansAndIQR(reference, nil)
+
+	baselines := make(map[string]*RandomComparison)
+	comparisons := make([]*RandomComparison, len(analysers))
+	for i, an := range analysers {
+		config := ShuffleConfig(an.Layout)
+		key := randomBaselineKey(config, input, targets, medians, iqrs)
+		shared, ok := baselines[key]
+		if !ok {
+			shared, err = loadOrComputeBaseline(config, key, input, targets, medians, iqrs)
+			if err != nil {
+				return nil, fmt.Errorf("could not compute random baseline of %s: %w", an.Layout.Name, err)
+			}
+			baselines[key] = shared
+		}
+		score := computeScores([]*Analyser{an}, medians, iqrs, input.Weights)[0].Score
+		comparisons[i] = &RandomComparison{Score: score, Baseline: shared.Baseline, Cached: shared.Cached}
+	}
+	return comparisons, nil
+}
+
+// ShuffleConfig returns a generation config that shuffles the characters of a layout over
+// its keys, with space kept on its key: random layouts of the config use the same keys and
+// characters as the layout.
+func ShuffleConfig(sl *SplitLayout) *GenerationConfig {
+	config := &GenerationConfig{
+		LayoutType: sl.LayoutType,
+		Groups:     make(map[int][]rune),
+		FilePath:   "layout " + sl.Name,
+		LineNums:   make(map[string]int),
+	}
+	for i, r := range sl.Runes {
+		switch r {
+		case 0:
+			config.Template[i] = PositionSpec{Type: PositionUnused}
+		case ' ':
+			config.Template[i] = PositionSpec{Type: PositionFixed, FixedChar: r}
+			config.Charset = append(config.Charset, r)
+		default:
+			config.Template[i] = PositionSpec{Type: PositionRandom}
+			config.Charset = append(config.Charset, r)
+		}
+	}
+	return config
+}
+
+// randomBaselineFile is the JSON form of a cached random baseline.
+type randomBaselineFile struct {
+	Key    string    `json:"key"`    // Everything the scores depend on (see randomBaselineKey)
+	Scores []float64 `json:"scores"` // Scores of the random layouts, in ascending order
+}
+
+// loadOrComputeBaseline loads the random baseline with the given key from the cache, or
+// computes and caches it.
+func loadOrComputeBaseline(config *GenerationConfig, key string, input RandomBaselineInput,
+	targets *TargetLoads, medians, iqrs map[string]float64) (*RandomComparison, error) {
+	var path string
+	if input.CacheDir != "" {
+		sum := sha256.Sum256([]byte(key))
+		path = filepath.Join(input.CacheDir, "random-"+hex.EncodeToString(sum[:8])+".json")
+		if data, err := os.ReadFile(path); err == nil {
+			var cached randomBaselineFile
+			if json.Unmarshal(data, &cached) == nil && cached.Key == key && len(cached.Scores) == input.Count {
+				return &RandomComparison{Baseline: &RandomBaseline{Scores: cached.Scores}, Cached: true}, nil
+			}
+		}
+	}
+
+	layouts, err := RandomLayouts(RandomLayoutsInput{Config: config, Count: input.Count, Seed: input.Seed})
+	if err != nil {
+		return nil, err
+	}
+	analysers := analyseLayouts(layouts, input.Corpus, targets)
+	scores := make([]float64, len(analysers))
+	for i, ls := range computeScores(analysers, medians, iqrs, input.Weights) {
+		scores[i] = ls.Score
+	}
+	baseline := NewRandomBaseline(scores)
+
+	if path != "" {
+		data, err := json.Marshal(randomBaselineFile{Key: key, Scores: baseline.Scores})
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(input.CacheDir, 0755); err != nil {
+			return nil, fmt.Errorf("could not create cache directory: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, fmt.Errorf("could not save random baseline: %w", err)
+		}
+	}
+	return &RandomComparison{Baseline: baseline}, nil
+}
+
+// analyseLayouts analyses layouts concurrently, with at most GOMAXPROCS layouts at a time.
+func analyseLayouts(layouts []*SplitLayout, corpus *Corpus, targets *TargetLoads) []*Analyser {
+	var (
+		analysers = make([]*Analyser, len(layouts))
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, runtime.GOMAXPROCS(0))
+	)
+	for i, layout := range layouts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			analysers[i] = NewAnalyser(layout, corpus, targets)
+		}()
+	}
+	wg.Wait()
+	return analysers
+}
+
+// randomBaselineKey returns a description of everything that the scores of the random
+// layouts of a config depend on, as the key of their cache.
+func randomBaselineKey(config *GenerationConfig, input RandomBaselineInput, targets *TargetLoads,
+	medians, iqrs map[string]float64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "v%d count=%d seed=%d type=%d\n", randomBaselineVersion, input.Count, input.Seed, config.LayoutType)
+	for _, spec := range config.Template {
+		fmt.Fprintf(&b, "%d:%q ", spec.Type, spec.FixedChar)
+	}
+	chars := slices.Clone(config.Charset)
+	slices.Sort(chars)
+	fmt.Fprintf(&b, "\ncharset=%q board=%q", string(chars), config.Board)
+	if config.NumberRow != nil {
+		fmt.Fprintf(&b, " numbers=%q", string(config.NumberRow[:]))
+	}
+	b.WriteByte('\n')
+
+	// The corpus, targets and board geometry are described as for the metrics cache
+	fmt.Fprintf(&b, "corpus=%s\ntargets=%q\ngeometry=%q\n", corpusFingerprint(input.Corpus),
+		targetsFingerprint(targets), geometryFingerprint())
+
+	for _, metric := range slices.Sorted(maps.Keys(medians)) {
+		if w := input.Weights.Get(metric); w != 0 {
+			fmt.Fprintf(&b, "%s=%g/%g/%g ", metric, w, medians[metric], iqrs[metric])
+		}
+	}
+	return b.String()
+}
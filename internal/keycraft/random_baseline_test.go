@@ -0,0 +1,78 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestShuffleConfig(t *testing.T) {
+	sl := NewSplitLayout("qwerty", ROWSTAG, qwertyRunes())
+	config := ShuffleConfig(sl)
+	Must0(ValidateConfig(config))
+	if spec := config.Template[39]; spec.Type != PositionFixed || spec.FixedChar != ' ' {
+		t.Errorf("space key = %+v, want fixed space", spec)
+	}
+	if spec := config.Template[36]; spec.Type != PositionUnused {
+		t.Errorf("empty key = %+v, want unused", spec)
+	}
+	if len(config.Charset) != 37 {
+		t.Errorf("charset = %q, want the 37 characters of the layout", string(config.Charset))
+	}
+}
+
+func TestCompareWithRandom(t *testing.T) {
+	dir := t.TempDir()
+	qwerty := NewSplitLayout("qwerty", ROWSTAG, qwertyRunes())
+	dvorakish := qwertyRunes()
+	dvorakish[13], dvorakish[22] = dvorakish[22], dvorakish[13]
+	Must0(qwerty.SaveToFile(filepath.Join(dir, "qwerty.klf")))
+	Must0(NewSplitLayout("other", ROWSTAG, dvorakish).SaveToFile(filepath.Join(dir, "other.klf")))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog and the lazy cat")
+
+	input := RandomBaselineInput{
+		LayoutsDir: dir,
+		Corpus:     corpus,
+		Weights:    Must(NewWeightsFromString("SFB=-1,LSB=-1")),
+		Count:      20,
+		Seed:       1,
+		CacheDir:   filepath.Join(dir, "baselines"),
+	}
+	an := NewAnalyser(qwerty, corpus, nil)
+	got := Must(CompareWithRandom([]*Analyser{an, an}, input))
+	if len(got) != 2 || got[0].Cached || len(got[0].Baseline.Scores) != 20 {
+		t.Fatalf("comparisons = %+v", got)
+	}
+	if got[0].Baseline != got[1].Baseline {
+		t.Error("layouts with the same keys and characters do not share their random layouts")
+	}
+	if p := got[0].PercentBeaten(); p < 0 || p > 100 {
+		t.Errorf("PercentBeaten() = %v", p)
+	}
+
+	// The scores are loaded from the cache the next time, and recomputed for other weights
+	cached := Must(CompareWithRandom([]*Analyser{an}, input))
+	if !cached[0].Cached || !slices.Equal(cached[0].Baseline.Scores, got[0].Baseline.Scores) {
+		t.Errorf("cached comparison = %+v, want the scores of %+v", cached[0], got[0])
+	}
+	input.Weights = Must(NewWeightsFromString("SFB=-2"))
+	if other := Must(CompareWithRandom([]*Analyser{an}, input)); other[0].Cached {
+		t.Error("baseline of other weights was loaded from the cache")
+	}
+
+	// A corpus with the same totals but other n-grams, or other targets, is another baseline
+	input.Weights = Must(NewWeightsFromString("SFB=-1,LSB=-1"))
+	swapped := NewCorpus("test")
+	swapped.addTextWithWords("the quick brown fox jumps over the lazy dog and the lazy hat")
+	input.Corpus = swapped
+	if other := Must(CompareWithRandom([]*Analyser{NewAnalyser(qwerty, swapped, nil)}, input)); other[0].Cached {
+		t.Error("baseline of another corpus was loaded from the cache")
+	}
+	input.Corpus = corpus
+	input.Targets = NewTargetLoads()
+	Must0(input.Targets.SetMetricVersion(1))
+	if other := Must(CompareWithRandom([]*Analyser{an}, input)); other[0].Cached {
+		t.Error("baseline of other targets was loaded from the cache")
+	}
+}
@@ -0,0 +1,134 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RandomLayoutsInput captures the inputs for sampling random layouts.
+type RandomLayoutsInput struct {
+	Config *GenerationConfig // Constraints of the layouts: fixed keys, sets and free keys
+	Count  int               // Number of layouts to sample
+	Seed   int64             // Seed of the sampling; layout i uses DeriveSeed(Seed, i)
+}
+
+// RandomLayouts samples random layouts that satisfy the constraints of a generation config.
+// Unlike GenerateFromConfig, which enumerates the permutations of the sets in order, each
+// layout draws its own permutation of the sets, so that the layouts are spread over the
+// whole space of the config. Sets are filled in ascending order from the characters that
+// are not fixed or used by an earlier set, and the remaining characters are shuffled over
+// the random positions. The layouts are named like generated layouts, so they are not
+// part of the default reference set.
+func RandomLayouts(input RandomLayoutsInput) ([]*SplitLayout, error) {
+	if input.Count < 1 {
+		return nil, fmt.Errorf("count must be at least 1, got %d", input.Count)
+	}
+	config := input.Config
+	if err := ValidateConfig(config); err != nil {
+		return nil, err
+	}
+
+	// Count the positions of each set, and the characters that are fixed
+	groupPositions := make(map[int]int)
+	fixed := make(map[rune]bool)
+	for _, spec := range config.Template {
+		switch spec.Type {
+		case PositionGroup:
+			groupPositions[spec.GroupNum]++
+		case PositionFixed:
+			fixed[spec.FixedChar] = true
+		}
+	}
+	groupNums := make([]int, 0, len(groupPositions))
+	for g := range groupPositions {
+		groupNums = append(groupNums, g)
+	}
+	slices.Sort(groupNums)
+
+	layouts := make([]*SplitLayout, 0, input.Count)
+	for i := range input.Count {
+		seed := uint64(DeriveSeed(input.Seed, i))
+		rng := NewLockedRNG(seed, 1)
+
+		// Sets that share characters may leave too few for a later set; draw again then
+		var groupPerm map[int][]rune
+		for try := 0; groupPerm == nil; try++ {
+			if try == maxSampleTries {
+				return nil, fmt.Errorf("could not sample the sets in %d tries; check that the sets can be filled together",
+					maxSampleTries)
+			}
+			groupPerm = sampleGroups(config, groupNums, groupPositions, fixed, rng)
+		}
+
+		layouts = append(layouts, GenerateLayout(config, groupPerm, seed, i))
+	}
+	return layouts, nil
+}
+
+// maxSampleTries is the number of draws of the sets of a random layout before giving up.
+const maxSampleTries = 1000
+
+// sampleGroups draws a random permutation of each set, in the order of groupNums, from the
+// characters that are not fixed or drawn for an earlier set. It returns nil if a set has
+// too few characters left.
+func sampleGroups(config *GenerationConfig, groupNums []int, groupPositions map[int]int,
+	fixed map[rune]bool, rng *LockedSource) map[int][]rune {
+	used := make(map[rune]bool, len(config.Charset))
+	for r := range fixed {
+		used[r] = true
+	}
+	groupPerm := make(map[int][]rune, len(groupNums))
+	for _, g := range groupNums {
+		available := make([]rune, 0, len(config.Groups[g]))
+		for _, r := range config.Groups[g] {
+			if !used[r] {
+				available = append(available, r)
+			}
+		}
+		k := groupPositions[g]
+		if len(available) < k {
+			return nil
+		}
+		ShuffleSlice(rng, available)
+		groupPerm[g] = available[:k]
+		for _, r := range groupPerm[g] {
+			used[r] = true
+		}
+	}
+	return groupPerm
+}
+
+// RandomBaseline is the distribution of the scores of random layouts, for comparing a
+// layout with random layouts ("how good is my layout vs random?").
+type RandomBaseline struct {
+	Scores []float64 // Scores of the random layouts, in ascending order
+}
+
+// NewRandomBaseline returns the baseline of the given scores of random layouts.
+func NewRandomBaseline(scores []float64) *RandomBaseline {
+	sorted := slices.Clone(scores)
+	slices.Sort(sorted)
+	return &RandomBaseline{Scores: sorted}
+}
+
+// PercentBeaten returns the percentage of the random layouts that score lower than the
+// given score. Ties count for half, so a layout as good as all random layouts gets 50%.
+func (b *RandomBaseline) PercentBeaten(score float64) float64 {
+	if len(b.Scores) == 0 {
+		return 0
+	}
+	lower, _ := slices.BinarySearch(b.Scores, score)
+	upper := lower
+	for upper < len(b.Scores) && b.Scores[upper] == score {
+		upper++
+	}
+	return 100 * (float64(lower) + float64(upper-lower)/2) / float64(len(b.Scores))
+}
+
+// Median returns the median score of the random layouts.
+func (b *RandomBaseline) Median() float64 {
+	if len(b.Scores) == 0 {
+		return 0
+	}
+	return Median(b.Scores)
+}
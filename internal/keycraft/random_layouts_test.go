@@ -0,0 +1,109 @@
+package keycraft
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRandomLayouts(t *testing.T) {
+	config := Must(ParseConfigString(testConfigOverlapping))
+	layouts := Must(RandomLayouts(RandomLayoutsInput{Config: config, Count: 50, Seed: 7}))
+	if len(layouts) != 50 {
+		t.Fatalf("got %d layouts, want 50", len(layouts))
+	}
+
+	distinct := make(map[[42]rune]bool)
+	for _, sl := range layouts {
+		distinct[sl.Runes] = true
+		if sl.Name[0] != '_' {
+			t.Errorf("name %q does not start with _", sl.Name)
+		}
+
+		// Every character of the charset is placed once
+		var placed []rune
+		for _, r := range sl.Runes {
+			if r != 0 {
+				placed = append(placed, r)
+			}
+		}
+		want := slices.Clone(config.Charset)
+		slices.Sort(placed)
+		slices.Sort(want)
+		if !slices.Equal(placed, want) {
+			t.Fatalf("%s places %q, want %q", sl.Name, string(placed), string(want))
+		}
+
+		// The constraints hold: fixed keys, sets and unused keys
+		for i, spec := range config.Template {
+			r := sl.Runes[i]
+			switch spec.Type {
+			case PositionFixed:
+				if r != spec.FixedChar {
+					t.Errorf("%s: key %d = %q, want fixed %q", sl.Name, i, r, spec.FixedChar)
+				}
+			case PositionGroup:
+				if !slices.Contains(config.Groups[spec.GroupNum], r) {
+					t.Errorf("%s: key %d = %q, not in set%d", sl.Name, i, r, spec.GroupNum)
+				}
+			case PositionUnused:
+				if r != 0 {
+					t.Errorf("%s: unused key %d = %q", sl.Name, i, r)
+				}
+			}
+		}
+	}
+	if len(distinct) < 45 {
+		t.Errorf("only %d distinct layouts of 50", len(distinct))
+	}
+
+	// The same seed gives the same layouts
+	again := Must(RandomLayouts(RandomLayoutsInput{Config: config, Count: 50, Seed: 7}))
+	for i := range layouts {
+		if again[i].Runes != layouts[i].Runes {
+			t.Fatalf("layout %d differs with the same seed", i)
+		}
+	}
+
+	if _, err := RandomLayouts(RandomLayoutsInput{Config: config, Count: 0}); err == nil {
+		t.Error("expected error for count 0")
+	}
+}
+
+func TestRandomBaseline_PercentBeaten(t *testing.T) {
+	b := NewRandomBaseline([]float64{3, 1, 2, 2, 4})
+	tests := []struct {
+		score float64
+		want  float64
+	}{
+		{0, 0},
+		{1, 10},
+		{2, 40},
+		{2.5, 60},
+		{5, 100},
+	}
+	for _, tt := range tests {
+		if got := b.PercentBeaten(tt.score); got != tt.want {
+			t.Errorf("PercentBeaten(%v) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+	if got := b.Median(); got != 2 {
+		t.Errorf("Median() = %v, want 2", got)
+	}
+}
+
+func TestRandomLayouts_Unsatisfiable(t *testing.T) {
+	// Both sets need t, so no layout satisfies the constraints
+	config := Must(ParseConfigString(`colstag
+~ 0 0 0 0 0  0 0 0 0 0 ~
+~ 2 0 0 0 0  0 e a i o ~
+~ 0 0 0 0 0  0 0 0 0 0 ~
+      ~ ~ 1  _ ~ ~
+
+charset=etaoinshrdlcumwfgypbvkjxqz ,./;'
+set1=t
+set2=t
+`))
+	if _, err := RandomLayouts(RandomLayoutsInput{Config: config, Count: 1}); err == nil {
+		t.Error("expected error for sets that cannot be filled together")
+	}
+}
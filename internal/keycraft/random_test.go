@@ -102,3 +102,35 @@ func TestConcurrencySafety(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestParseSeed(t *testing.T) {
+	if seed := Must(ParseSeed("42")); seed != 42 {
+		t.Errorf("ParseSeed(42) = %d", seed)
+	}
+	for _, s := range []string{"random", "RANDOM", "", "0"} {
+		if seed := Must(ParseSeed(s)); seed <= 0 {
+			t.Errorf("ParseSeed(%q) = %d, want a new positive seed", s, seed)
+		}
+	}
+	for _, s := range []string{"-1", "abc", "1.5"} {
+		if _, err := ParseSeed(s); err == nil {
+			t.Errorf("ParseSeed(%q): expected an error", s)
+		}
+	}
+}
+
+func TestDeriveSeed(t *testing.T) {
+	seen := make(map[int64]bool)
+	for _, seed := range []int64{0, 1, 2} {
+		for i := range 3 {
+			derived := DeriveSeed(seed, i)
+			if derived <= 0 || seen[derived] {
+				t.Errorf("DeriveSeed(%d, %d) = %d, want a new positive seed", seed, i, derived)
+			}
+			seen[derived] = true
+			if DeriveSeed(seed, i) != derived {
+				t.Errorf("DeriveSeed(%d, %d) is not deterministic", seed, i)
+			}
+		}
+	}
+}
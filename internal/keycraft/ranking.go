@@ -3,17 +3,22 @@ package keycraft
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // RankingInput encapsulates all configuration for layout ranking computation.
 // All layouts in LayoutsDir are analyzed for normalization, then filtered to LayoutFiles.
 type RankingInput struct {
-	LayoutsDir  string       // Used to load all layouts for calculating medians/IQRs for normalization
-	LayoutFiles []string     // Full filepaths for specific layouts to rank.
-	Corpus      *Corpus      // The corpus that ranking is based on
-	Targets     *TargetLoads // Load targets (row, finger, pinky penalties)
-	Weights     *Weights     // Metric weights for weighted scoring
+	LayoutsDir  string           // Used to load all layouts for calculating medians/IQRs for normalization
+	LayoutFiles []string         // Full filepaths for specific layouts to rank.
+	Layouts     []*SplitLayout   // Layouts to rank after the files, that need not be saved (e.g. with --dry-run)
+	Corpus      *Corpus          // The corpus that ranking is based on
+	Targets     *TargetLoads     // Load targets (row, finger, pinky penalties)
+	Weights     *Weights         // Metric weights for weighted scoring
+	Reference   *ReferenceSet    // Layouts used for medians/IQRs (nil = default naming rule)
+	External    *ExternalMetrics // Imported metric columns merged into the analysers (optional)
+	Cache       *MetricsCache    // Metrics of layouts analysed before (optional)
 }
 
 // RankingResult provides ranked layouts with normalization statistics.
@@ -28,11 +33,19 @@ type RankingResult struct {
 // It loads layouts, computes statistics, filters, scores, and returns results.
 func ComputeRankings(input RankingInput) (*RankingResult, error) {
 	// Load and analyze all layouts (needed for normalization even if we filter later)
-	analysers, err := LoadAnalysers(input.LayoutsDir, input.Corpus, input.Targets, false)
+	analysers, err := loadAnalysers(input.LayoutsDir, input.Corpus, input.Targets, nil, input.Cache)
 	if err != nil {
 		return nil, fmt.Errorf("could not load analysers: %w", err)
 	}
-	medians, iqrs := computeMediansAndIQR(analysers, true)
+	input.External.Apply(analysers)
+	reference := input.Reference
+	if reference == nil {
+		reference = DefaultReferenceSet()
+	}
+	if !slices.ContainsFunc(analysers, func(a *Analyser) bool { return reference.Contains(a.Layout.Name) }) {
+		return nil, fmt.Errorf("reference set %s matches no layouts in %s", reference, input.LayoutsDir)
+	}
+	medians, iqrs := computeMediansAndIQR(analysers, reference)
 
 	// Build lookup map for filtering
 	analyserMap := make(map[string]*Analyser, len(analysers))
@@ -45,19 +58,30 @@ func ComputeRankings(input RankingInput) (*RankingResult, error) {
 	for _, fname := range input.LayoutFiles {
 		// Extract layout name from full filepath (basename without extension)
 		layoutName := strings.TrimSuffix(filepath.Base(fname), filepath.Ext(fname))
+		if filepath.Clean(filepath.Dir(fname)) != filepath.Clean(input.LayoutsDir) {
+			// Layout outside the layouts directory (e.g. saved with --out), load it explicitly
+			layout, err := NewLayoutFromFile(layoutName, fname)
+			if err != nil {
+				return nil, fmt.Errorf("could not load layout %s: %w", fname, err)
+			}
+			analyser := input.Cache.NewAnalyser(layout, input.Corpus, input.Targets)
+			input.External.Apply([]*Analyser{analyser})
+			filteredAnalysers = append(filteredAnalysers, analyser)
+			continue
+		}
 		analyser, ok := analyserMap[layoutName]
 		if !ok {
-			// // Layout not in the reference set, load it explicitly
-			// layout, err := NewLayoutFromFile(layoutName, fname)
-			// if err != nil {
-			// 	return nil, fmt.Errorf("could not load layout %s: %v", fname, err)
-			// }
-			// analyser = NewAnalyser(layout, input.Corpus, input.Targets)
 			return nil, fmt.Errorf("layout file %s was not found", fname)
 		}
 		filteredAnalysers = append(filteredAnalysers, analyser)
 	}
 
+	for _, layout := range input.Layouts {
+		analyser := NewAnalyser(layout, input.Corpus, input.Targets)
+		input.External.Apply([]*Analyser{analyser})
+		filteredAnalysers = append(filteredAnalysers, analyser)
+	}
+
 	// Compute scores using normalized metrics
 	layoutScores := computeScores(filteredAnalysers, medians, iqrs, input.Weights)
 
@@ -68,6 +92,16 @@ func ComputeRankings(input RankingInput) (*RankingResult, error) {
 	}, nil
 }
 
+// Rescore scores the ranked layouts again with other weights. The layouts are not analysed
+// again, and the medians and IQRs stay the same, as they don't depend on the weights.
+func (r *RankingResult) Rescore(weights *Weights) {
+	analysers := make([]*Analyser, len(r.Scores))
+	for i, ls := range r.Scores {
+		analysers[i] = ls.Analyser
+	}
+	r.Scores = computeScores(analysers, r.Medians, r.IQRs, weights)
+}
+
 // ComputeMedianScore creates a synthetic LayoutScore from median values.
 // The score is always 0.0 because normalized median values are (median - median) / IQR = 0.
 func ComputeMedianScore(medians map[string]float64, weights *Weights) LayoutScore {
@@ -0,0 +1,121 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GroupBy is what rank --group-by groups the ranked layouts by.
+type GroupBy int
+
+const (
+	GroupNone     GroupBy = iota // No groups
+	GroupByTag                   // The first tag of the layout, which names its family
+	GroupByAuthor                // The author of the layout
+	GroupByBoard                 // The board setting of the layout, or else its layout type
+)
+
+// GroupByNames are the names of the GroupBy values, as given to --group-by.
+var GroupByNames = []string{"none", "tag", "author", "board"}
+
+// ParseGroupBy parses the name of a GroupBy (see GroupByNames).
+func ParseGroupBy(s string) (GroupBy, error) {
+	i := slices.Index(GroupByNames, strings.ToLower(strings.TrimSpace(s)))
+	if i < 0 {
+		return GroupNone, fmt.Errorf("invalid group-by %q; must be one of: %s", s, strings.Join(GroupByNames, ", "))
+	}
+	return GroupBy(i), nil
+}
+
+// String returns the name of the GroupBy.
+func (g GroupBy) String() string {
+	return GroupByNames[g]
+}
+
+// Key returns the name of the group of a layout, or "(none)" for layouts without the tag or
+// author that the layouts are grouped by.
+func (g GroupBy) Key(sl *SplitLayout) string {
+	var key string
+	switch g {
+	case GroupByTag:
+		if len(sl.Tags) > 0 {
+			key = sl.Tags[0]
+		}
+	case GroupByAuthor:
+		key = sl.Author
+	case GroupByBoard:
+		key = IfThen(sl.Board != "", sl.Board, LayoutTypeStrings[sl.LayoutType])
+	}
+	if key == "" {
+		return "(none)"
+	}
+	return key
+}
+
+// RankingGroup is a group of ranked layouts, with the best and median value of each metric
+// over the layouts of the group.
+type RankingGroup struct {
+	Name   string        // Name of the group, as returned by GroupBy.Key
+	Scores []LayoutScore // Layouts of the group, best score first
+	Best   LayoutScore   // Best score and metric values of the group, by the polarity of the weights
+	Median LayoutScore   // Median score and metric values of the group
+}
+
+// GroupScores groups ranked layouts, and orders the groups by their best score. The best
+// value of a metric is the highest for metrics with a positive weight, and the lowest
+// otherwise.
+func GroupScores(scores []LayoutScore, by GroupBy, weights *Weights) []RankingGroup {
+	byKey := make(map[string][]LayoutScore)
+	for _, score := range scores {
+		key := by.Key(score.Analyser.Layout)
+		byKey[key] = append(byKey[key], score)
+	}
+
+	groups := make([]RankingGroup, 0, len(byKey))
+	for key, scores := range byKey {
+		sort.Slice(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+
+		values := map[string][]float64{"": make([]float64, len(scores))}
+		for i, score := range scores {
+			values[""][i] = score.Score
+			for metric, value := range score.Analyser.Metrics {
+				values[metric] = append(values[metric], value)
+			}
+		}
+		best, median := make(map[string]float64), make(map[string]float64)
+		for metric, vals := range values {
+			sort.Float64s(vals)
+			median[metric] = Median(vals)
+			best[metric] = IfThen(metric == "" || weights.Get(metric) > 0, vals[len(vals)-1], vals[0])
+		}
+		bestScore, medianScore := best[""], median[""]
+		delete(best, "")
+		delete(median, "")
+
+		groups = append(groups, RankingGroup{
+			Name:   key,
+			Scores: scores,
+			Best:   groupRow("best", bestScore, best),
+			Median: groupRow("median", medianScore, median),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Best.Score != groups[j].Best.Score {
+			return groups[i].Best.Score > groups[j].Best.Score
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// groupRow creates a synthetic LayoutScore for the best or median row of a group, as
+// ComputeMedianScore does for the median of the ranking.
+func groupRow(name string, score float64, metrics map[string]float64) LayoutScore {
+	return LayoutScore{
+		Name:     name,
+		Score:    score,
+		Analyser: &Analyser{Layout: &SplitLayout{Name: name}, Metrics: metrics},
+	}
+}
@@ -0,0 +1,68 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLayoutMetadata_SavedWithLayout(t *testing.T) {
+	sl := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	sl.Author = "Jane Doe"
+	sl.Tags = ParseTags(" Colemak, mod,, colemak ")
+	if !slices.Equal(sl.Tags, []string{"colemak", "mod"}) {
+		t.Errorf("ParseTags = %q, want [colemak mod]", sl.Tags)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.klf")
+	Must0(sl.SaveToFile(path))
+	saved := Must(NewLayoutFromFile("test", path))
+	if saved.Author != "Jane Doe" || !slices.Equal(saved.Tags, sl.Tags) {
+		t.Errorf("author = %q, tags = %q, want %q and %q", saved.Author, saved.Tags, sl.Author, sl.Tags)
+	}
+}
+
+func TestGroupScores(t *testing.T) {
+	score := func(name, tag string, value, sfb float64) LayoutScore {
+		sl := NewSplitLayout(name, ROWSTAG, qwertyRunes())
+		sl.Tags = ParseTags(tag)
+		return LayoutScore{Name: name, Score: value, Analyser: &Analyser{Layout: sl, Metrics: map[string]float64{"SFB": sfb}}}
+	}
+	scores := []LayoutScore{
+		score("a", "dvorak", -1, 2),
+		score("b", "colemak", 1, 1),
+		score("c", "", 0, 3),
+		score("d", "colemak", -2, 0.5),
+		score("e", "colemak", 0.5, 4),
+	}
+	weights := Must(NewWeightsFromString("SFB=-10"))
+
+	groups := GroupScores(scores, GroupByTag, weights)
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	if !slices.Equal(names, []string{"colemak", "(none)", "dvorak"}) {
+		t.Fatalf("groups = %q, want colemak, (none), dvorak", names)
+	}
+
+	colemak := groups[0]
+	if colemak.Scores[0].Name != "b" || colemak.Scores[2].Name != "d" {
+		t.Errorf("colemak layouts are not ordered best first: %s, %s, %s",
+			colemak.Scores[0].Name, colemak.Scores[1].Name, colemak.Scores[2].Name)
+	}
+	if colemak.Best.Score != 1 || colemak.Median.Score != 0.5 {
+		t.Errorf("best, median score = %g, %g, want 1, 0.5", colemak.Best.Score, colemak.Median.Score)
+	}
+	// SFB has a negative weight, so the lowest is best
+	if best, median := colemak.Best.Analyser.Metrics["SFB"], colemak.Median.Analyser.Metrics["SFB"]; best != 0.5 || median != 1 {
+		t.Errorf("best, median SFB = %g, %g, want 0.5, 1", best, median)
+	}
+
+	if groups := GroupScores(scores, GroupByBoard, weights); len(groups) != 1 || groups[0].Name != "rowstag" {
+		t.Errorf("board groups = %+v, want one rowstag group", groups)
+	}
+	if _, err := ParseGroupBy("family"); err == nil {
+		t.Error("expected error for an invalid group-by")
+	}
+}
@@ -36,7 +36,7 @@ func Benchmark_Rankings(b *testing.B) {
 		PinkyPenalties:   DefaultPinkyPenalties(),
 	}
 	for b.Loop() {
-		analysers, err := LoadAnalysers(layoutDir, corpus, targets, false)
+		analysers, err := LoadAnalysers(layoutDir, corpus, targets, nil)
 		if err != nil {
 			panic(err)
 		}
@@ -56,3 +56,83 @@ func Benchmark_Rankings(b *testing.B) {
 		// })
 	}
 }
+
+func TestComputeRankings_OutsideLayoutsDir(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	// A layout by the name of one in the layouts directory, but with other keys
+	outDir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutVariantKlf})
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+	input := RankingInput{
+		LayoutsDir:  dir,
+		LayoutFiles: []string{filepath.Join(dir, "a.klf"), filepath.Join(outDir, "a.klf")},
+		Corpus:      corpus,
+		Targets:     NewTargetLoads(),
+		Weights:     NewWeights(),
+	}
+	result, err := ComputeRankings(input)
+	if err != nil {
+		t.Fatalf("ComputeRankings failed: %v", err)
+	}
+	if len(result.Scores) != 2 {
+		t.Fatalf("got %d scores, want 2", len(result.Scores))
+	}
+	inDir, outside := result.Scores[0].Analyser.Layout, result.Scores[1].Analyser.Layout
+	if inDir == outside || KeyDiff(inDir, outside) == 0 {
+		t.Error("expected the layout outside the layouts directory to be loaded from its own file")
+	}
+
+	// Layouts that are not saved are ranked after the files
+	unsaved := Must(NewLayoutFromFile("unsaved", filepath.Join(outDir, "a.klf")))
+	input.LayoutFiles, input.Layouts = input.LayoutFiles[:1], []*SplitLayout{unsaved}
+	result = Must(ComputeRankings(input))
+	if len(result.Scores) != 2 || result.Scores[1].Analyser.Layout != unsaved {
+		t.Error("expected the unsaved layout to be ranked after the layout file")
+	}
+	input.Layouts = nil
+
+	input.LayoutFiles = []string{filepath.Join(outDir, "missing.klf")}
+	if _, err := ComputeRankings(input); err == nil {
+		t.Error("expected error for a missing layout file")
+	}
+}
+
+func TestRankingResult_Rescore(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+	input := RankingInput{
+		LayoutsDir:  dir,
+		LayoutFiles: []string{filepath.Join(dir, "a.klf"), filepath.Join(dir, "far.klf")},
+		Corpus:      corpus,
+		Targets:     NewTargetLoads(),
+		Weights:     Must(NewWeightsFromString("SFB=-1")),
+	}
+	result := Must(ComputeRankings(input))
+
+	// Rescoring gives the scores of ranking again with the new weights
+	weights := input.Weights.Clone()
+	Must0(weights.AddWeightsFromString("SFB=0,LSB=-2,ALT=1"))
+	if input.Weights.Get("LSB") != 0 || input.Weights.Get("SFB") != -1 {
+		t.Error("changing a clone of the weights changed the weights")
+	}
+	analysers := []*Analyser{result.Scores[0].Analyser, result.Scores[1].Analyser}
+	result.Rescore(weights)
+	input.Weights = weights
+	want := Must(ComputeRankings(input))
+	for i, ls := range result.Scores {
+		if ls.Analyser != analysers[i] {
+			t.Errorf("layout %s was analysed again", ls.Name)
+		}
+		if ls.Score != want.Scores[i].Score {
+			t.Errorf("rescored %s = %v, want %v", ls.Name, ls.Score, want.Scores[i].Score)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ReferenceSet selects the layouts that form the normalisation population, i.e. the
+// layouts whose metric medians and IQRs are used to scale metrics in Scorer and ranking.
+//
+// A layout is in the set if its name matches any of the glob patterns or is listed
+// explicitly. A set without patterns or names falls back to the default rule, which
+// excludes layouts whose name starts with "_" or contains "-flipped", "-best" or "-opt".
+type ReferenceSet struct {
+	globs []string
+	names map[string]bool
+}
+
+// DefaultReferenceSet returns the reference set using the default naming rule.
+func DefaultReferenceSet() *ReferenceSet {
+	return &ReferenceSet{}
+}
+
+// NewReferenceSet creates a reference set from glob patterns and explicit layout names.
+// Patterns use filepath.Match syntax and are matched against layout names without the
+// .klf extension. Returns an error for malformed patterns.
+func NewReferenceSet(globs, names []string) (*ReferenceSet, error) {
+	rs := &ReferenceSet{names: make(map[string]bool, len(names))}
+	for _, g := range globs {
+		g = strings.TrimSpace(g)
+		if g == "" {
+			continue
+		}
+		g = strings.TrimSuffix(g, ".klf")
+		if _, err := filepath.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid reference glob %q: %w", g, err)
+		}
+		rs.globs = append(rs.globs, g)
+	}
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		rs.names[strings.TrimSuffix(n, ".klf")] = true
+	}
+	return rs, nil
+}
+
+// NewReferenceSetFromParams builds a reference set from a comma-separated list of glob
+// patterns and an optional list file (see LoadReferenceList). If both are empty, the
+// default reference set is returned.
+func NewReferenceSetFromParams(globs, listPath string) (*ReferenceSet, error) {
+	var names []string
+	if listPath != "" {
+		var err error
+		names, err = LoadReferenceList(listPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("reference list %s contains no layouts", listPath)
+		}
+	}
+
+	var patterns []string
+	if globs != "" {
+		patterns = strings.Split(globs, ",")
+	}
+
+	return NewReferenceSet(patterns, names)
+}
+
+// LoadReferenceList reads layout names from a file, one per line.
+// Empty lines and lines starting with '#' are ignored.
+func LoadReferenceList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open reference list %s: %w", path, err)
+	}
+	defer CloseFile(file)
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read reference list %s: %w", path, err)
+	}
+	return names, nil
+}
+
+// IsDefault returns true if the set uses the default naming rule.
+func (rs *ReferenceSet) IsDefault() bool {
+	return rs == nil || (len(rs.globs) == 0 && len(rs.names) == 0)
+}
+
+// Contains returns true if the named layout belongs to the reference set.
+// A nil set uses the default naming rule.
+func (rs *ReferenceSet) Contains(name string) bool {
+	if rs.IsDefault() {
+		return isReferenceLayout(name)
+	}
+	if rs.names[name] {
+		return true
+	}
+	for _, g := range rs.globs {
+		if ok, _ := filepath.Match(g, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckNames returns an error if a listed layout has no layout file in dir, so that a typo
+// in a reference list does not silently leave a layout out of the reference set.
+func (rs *ReferenceSet) CheckNames(dir string) error {
+	if rs == nil || len(rs.names) == 0 {
+		return nil
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("error reading layout files from %v: %w", dir, err)
+	}
+	found := make(map[string]bool, len(files))
+	for _, file := range files {
+		if strings.HasSuffix(strings.ToLower(file.Name()), ".klf") {
+			found[strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))] = true
+		}
+	}
+	var missing []string
+	for name := range rs.names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("reference layouts not found in %s: %s", dir, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// String describes the reference set for display and logging.
+func (rs *ReferenceSet) String() string {
+	if rs.IsDefault() {
+		return "default (excluding _*, *-flipped*, *-best*, *-opt*)"
+	}
+	var parts []string
+	if len(rs.globs) > 0 {
+		parts = append(parts, "globs "+strings.Join(rs.globs, ","))
+	}
+	if len(rs.names) > 0 {
+		parts = append(parts, fmt.Sprintf("%d listed layouts", len(rs.names)))
+	}
+	return strings.Join(parts, " + ")
+}
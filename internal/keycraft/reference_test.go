@@ -0,0 +1,147 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReferenceSet_Default(t *testing.T) {
+	for _, rs := range []*ReferenceSet{nil, DefaultReferenceSet()} {
+		if !rs.IsDefault() {
+			t.Errorf("expected default reference set")
+		}
+		if !rs.Contains("qwerty") {
+			t.Errorf("expected qwerty in default set")
+		}
+		for _, name := range []string{"_gen1", "qwerty-flipped", "qwerty-best", "qwerty-opt"} {
+			if rs.Contains(name) {
+				t.Errorf("expected %s not in default set", name)
+			}
+		}
+	}
+}
+
+func TestReferenceSet_GlobsAndNames(t *testing.T) {
+	rs, err := NewReferenceSet([]string{"*mak*", " hd-*.klf "}, []string{"qwerty.klf", "_gen1"})
+	if err != nil {
+		t.Fatalf("NewReferenceSet failed: %v", err)
+	}
+	if rs.IsDefault() {
+		t.Fatal("expected non-default reference set")
+	}
+
+	for _, name := range []string{"semimak", "semimak-opt", "hd-silver", "qwerty", "_gen1"} {
+		if !rs.Contains(name) {
+			t.Errorf("expected %s in reference set", name)
+		}
+	}
+	for _, name := range []string{"dvorak", "qwerty-flipped", "hd"} {
+		if rs.Contains(name) {
+			t.Errorf("expected %s not in reference set", name)
+		}
+	}
+}
+
+func TestReferenceSet_InvalidGlob(t *testing.T) {
+	if _, err := NewReferenceSet([]string{"[abc"}, nil); err == nil {
+		t.Error("expected error for malformed glob")
+	}
+}
+
+func TestLoadReferenceList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refs.txt")
+	content := "# reference layouts\nqwerty\n\n  colemak.klf  \n# dvorak\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write list: %v", err)
+	}
+
+	names, err := LoadReferenceList(path)
+	if err != nil {
+		t.Fatalf("LoadReferenceList failed: %v", err)
+	}
+	if strings.Join(names, ",") != "qwerty,colemak.klf" {
+		t.Errorf("unexpected names: %v", names)
+	}
+
+	rs, err := NewReferenceSetFromParams("dv*", path)
+	if err != nil {
+		t.Fatalf("NewReferenceSetFromParams failed: %v", err)
+	}
+	for _, name := range []string{"qwerty", "colemak", "dvorak"} {
+		if !rs.Contains(name) {
+			t.Errorf("expected %s in reference set", name)
+		}
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing\n"), 0o644); err != nil {
+		t.Fatalf("could not write list: %v", err)
+	}
+	if _, err := NewReferenceSetFromParams("", empty); err == nil {
+		t.Error("expected error for empty reference list")
+	}
+}
+
+func TestReferenceSet_CheckNames(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf, "b.klf": testLayoutVariantKlf})
+	rs, err := NewReferenceSet([]string{"x*"}, []string{"a", "b.klf"})
+	if err != nil {
+		t.Fatalf("NewReferenceSet failed: %v", err)
+	}
+	if err := rs.CheckNames(dir); err != nil {
+		t.Errorf("CheckNames failed: %v", err)
+	}
+	if err := DefaultReferenceSet().CheckNames(dir); err != nil {
+		t.Errorf("CheckNames of the default set failed: %v", err)
+	}
+
+	rs, err = NewReferenceSet(nil, []string{"a", "qwerty", "colemak"})
+	if err != nil {
+		t.Fatalf("NewReferenceSet failed: %v", err)
+	}
+	if err := rs.CheckNames(dir); err == nil || !strings.HasSuffix(err.Error(), ": colemak, qwerty") {
+		t.Errorf("expected error naming colemak and qwerty, got %v", err)
+	}
+}
+
+func TestLoadAnalysers_ReferenceSet(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":     testLayoutKlf,
+		"b-opt.klf": testLayoutVariantKlf,
+		"far.klf":   testLayoutFarKlf,
+	})
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+
+	all, err := LoadAnalysers(dir, corpus, nil, nil)
+	if err != nil {
+		t.Fatalf("LoadAnalysers failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 analysers without reference set, got %d", len(all))
+	}
+
+	def, err := LoadAnalysers(dir, corpus, nil, DefaultReferenceSet())
+	if err != nil {
+		t.Fatalf("LoadAnalysers failed: %v", err)
+	}
+	if len(def) != 2 {
+		t.Errorf("expected 2 analysers with default reference set, got %d", len(def))
+	}
+
+	rs := Must(NewReferenceSet([]string{"b-*"}, nil))
+	selected, err := LoadAnalysers(dir, corpus, nil, rs)
+	if err != nil {
+		t.Fatalf("LoadAnalysers failed: %v", err)
+	}
+	if len(selected) != 1 || selected[0].Layout.Name != "b-opt" {
+		t.Errorf("expected only b-opt, got %d analysers", len(selected))
+	}
+
+	none := Must(NewReferenceSet([]string{"missing*"}, nil))
+	if _, err := NewScorer(dir, corpus, nil, NewWeights(), none); err == nil {
+		t.Error("expected error when reference set matches no layouts")
+	}
+}
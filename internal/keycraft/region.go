@@ -0,0 +1,103 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Region is a set of key positions on the board, indexed like SplitLayout.Runes.
+// A value of true means the key belongs to the region.
+type Region [42]bool
+
+// RegionNames lists the named regions accepted by ParseRegion, besides "cols:N-M".
+var RegionNames = []string{"left-hand", "right-hand", "top-row", "home-row", "bottom-row", "thumbs"}
+
+// ParseRegion parses a region specification: one or more comma-separated parts, each
+// a named region (see RegionNames) or a column range "cols:N-M" (or "cols:N") covering
+// columns 0-11 of the top, home and bottom rows. The region is the union of its parts.
+// For example, "cols:10-11" selects both right pinky columns, and "top-row,thumbs"
+// selects the top row and all thumb keys.
+func ParseRegion(spec string) (*Region, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return nil, fmt.Errorf("region is empty")
+	}
+
+	region := &Region{}
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if cols, ok := strings.CutPrefix(part, "cols:"); ok {
+			first, last, err := parseColumnRange(cols)
+			if err != nil {
+				return nil, fmt.Errorf("invalid region %q: %w", part, err)
+			}
+			for row := uint8(0); row < 3; row++ {
+				for col := first; col <= last; col++ {
+					region[12*row+col] = true
+				}
+			}
+			continue
+		}
+
+		if !slices.Contains(RegionNames, part) {
+			return nil, fmt.Errorf("invalid region %q: must be one of %s, or cols:N-M",
+				part, strings.Join(RegionNames, ", "))
+		}
+		for i := range uint8(42) {
+			if inNamedRegion(part, NewKeyInfo(i/12, i%12, ROWSTAG)) {
+				region[i] = true
+			}
+		}
+	}
+
+	return region, nil
+}
+
+// inNamedRegion reports whether a key belongs to the named region.
+func inNamedRegion(name string, key KeyInfo) bool {
+	switch name {
+	case "left-hand":
+		return key.Hand == LEFT
+	case "right-hand":
+		return key.Hand == RIGHT
+	case "top-row":
+		return key.Row == 0
+	case "home-row":
+		return key.Row == 1
+	case "bottom-row":
+		return key.Row == 2
+	case "thumbs":
+		return key.Row == 3
+	}
+	return false
+}
+
+// parseColumnRange parses "N-M" or "N" into an inclusive range of columns 0-11.
+func parseColumnRange(s string) (uint8, uint8, error) {
+	firstStr, lastStr, isRange := strings.Cut(s, "-")
+	if !isRange {
+		lastStr = firstStr
+	}
+
+	first, err1 := strconv.ParseUint(strings.TrimSpace(firstStr), 10, 8)
+	last, err2 := strconv.ParseUint(strings.TrimSpace(lastStr), 10, 8)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("columns must be numbers, e.g. cols:0-2")
+	}
+	if first > last || last > 11 {
+		return 0, 0, fmt.Errorf("columns must be an ascending range within 0-11")
+	}
+	return uint8(first), uint8(last), nil
+}
+
+// PinOutside pins every key outside the region, so only keys inside it can be swapped.
+// Keys inside the region keep their pinned state.
+func (p *PinnedKeys) PinOutside(r *Region) {
+	for i, in := range r {
+		if !in {
+			p[i] = true
+		}
+	}
+}
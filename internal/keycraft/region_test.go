@@ -0,0 +1,66 @@
+package keycraft
+
+import "testing"
+
+func TestParseRegion(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []uint8 // a sample of key indices that must be in the region
+		size int
+	}{
+		{"left-hand", []uint8{0, 5, 12, 24, 36, 38}, 21},
+		{"right-hand", []uint8{6, 11, 35, 39, 41}, 21},
+		{"top-row", []uint8{0, 11}, 12},
+		{"home-row", []uint8{12, 23}, 12},
+		{"bottom-row", []uint8{24, 35}, 12},
+		{"thumbs", []uint8{36, 41}, 6},
+		{"cols:10-11", []uint8{10, 11, 22, 23, 34, 35}, 6},
+		{"cols:3", []uint8{3, 15, 27}, 3},
+		{"Top-Row, thumbs", []uint8{0, 36}, 18},
+		{"cols:0-5,left-hand", []uint8{0, 36}, 21},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRegion(tt.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.spec, err)
+			continue
+		}
+		size := 0
+		for _, in := range r {
+			if in {
+				size++
+			}
+		}
+		if size != tt.size {
+			t.Errorf("%q: expected %d keys, got %d", tt.spec, tt.size, size)
+		}
+		for _, idx := range tt.want {
+			if !r[idx] {
+				t.Errorf("%q: expected key %d in region", tt.spec, idx)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "pinky", "cols:", "cols:a-b", "cols:5-2", "cols:0-12"} {
+		if _, err := ParseRegion(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestPinnedKeys_PinOutside(t *testing.T) {
+	pinned := &PinnedKeys{}
+	pinned[10] = true // pinned inside the region stays pinned
+
+	pinned.PinOutside(Must(ParseRegion("cols:10-11")))
+	for i, p := range pinned {
+		inRegion := i%12 >= 10 && i < 36
+		switch {
+		case i == 10 && !p:
+			t.Error("key 10 should stay pinned")
+		case i != 10 && p == inRegion:
+			t.Errorf("key %d: pinned=%v, in region=%v", i, p, inRegion)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package keycraft
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RollQualityWeights are the weights of the components of ROLLQ. Each component scores a roll
+// from 0 (worst) to 1 (best), and the quality of a roll is the weighted average of its scores.
+type RollQualityWeights struct {
+	Direction float64 // Inward rolls score 1, outward rolls 0
+	Length    float64 // 3-key rolls score 1, 2-key rolls 0
+	Row       float64 // Rolls on the home row score 1, on another single row 0.5, across rows 0
+	Finger    float64 // Average strength of the fingers of the roll (see rollFingerStrength)
+}
+
+// DefaultRollQuality returns the default roll quality weights: direction, row and finger
+// strength count the same, and the length of a roll half as much.
+func DefaultRollQuality() *RollQualityWeights {
+	return &RollQualityWeights{Direction: 1, Length: 0.5, Row: 1, Finger: 1}
+}
+
+// rollFingerStrength is the strength of fingers F0-F9 for the finger component of ROLLQ.
+var rollFingerStrength = [10]float64{0.25, 0.5, 1, 1, 0.75, 0.75, 1, 1, 0.5, 0.25}
+
+// SetRollQuality parses and sets the roll quality weights from 4 comma-separated values:
+// direction, length, row and finger. Values must not be negative, and not all be zero.
+func (tl *TargetLoads) SetRollQuality(s string) error {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return fmt.Errorf("roll-quality must have 4 comma-separated values: direction, length, row, finger (got %d)", len(parts))
+	}
+	var vals [4]float64
+	var sum float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid roll-quality value at position %d: %q must be a non-negative number", i, strings.TrimSpace(p))
+		}
+		vals[i] = v
+		sum += v
+	}
+	if sum == 0 {
+		return fmt.Errorf("roll-quality values must not all be zero")
+	}
+	tl.RollQuality = &RollQualityWeights{Direction: vals[0], Length: vals[1], Row: vals[2], Finger: vals[3]}
+	return nil
+}
+
+// quality returns the quality of a roll from 0 to 1, given the keys of the roll.
+func (w *RollQualityWeights) quality(inward bool, keys ...KeyInfo) float64 {
+	var dir, length, row, finger float64
+	if inward {
+		dir = 1
+	}
+	if len(keys) == 3 {
+		length = 1
+	}
+	row = 1
+	for _, k := range keys {
+		finger += rollFingerStrength[k.Finger]
+		if k.Row != keys[0].Row {
+			row = 0
+		}
+	}
+	if row == 1 && keys[0].Row != 1 {
+		row = 0.5
+	}
+	finger /= float64(len(keys))
+
+	sum := w.Direction + w.Length + w.Row + w.Finger
+	return (w.Direction*dir + w.Length*length + w.Row*row + w.Finger*finger) / sum
+}
+
+// rollQuality returns the quality of a trigram of the given class, typed with the given
+// keys, or 0 if it is not a roll without a same-finger bigram.
+func (w *RollQualityWeights) rollQuality(class int, k0, k1, k2 KeyInfo) float64 {
+	switch class {
+	case tri3RLIn, tri3RLOut:
+		return w.quality(class == tri3RLIn, k0, k1, k2)
+	case tri2RLIn, tri2RLOut:
+		if k0.Hand == k1.Hand {
+			return w.quality(class == tri2RLIn, k0, k1)
+		}
+		return w.quality(class == tri2RLIn, k1, k2)
+	}
+	return 0
+}
+
+// analyseRollQuality computes ROLLQ, the roll trigrams (2RL-IN, 2RL-OUT, 3RL-IN and 3RL-OUT)
+// as a % of all trigrams, each weighted by its quality from 0 to 1. Inward rolls on the home
+// row with strong fingers score highest, and outward rolls across rows on the pinky and
+// ring finger lowest. ROLLQ is at most the sum of the roll metrics.
+func (an *Analyser) analyseRollQuality() {
+	an.Metrics["ROLLQ"] = 0
+	if an.Corpus.TotalTrigramsCount == 0 {
+		return
+	}
+	w := DefaultRollQuality()
+	if an.Targets != nil && an.Targets.RollQuality != nil {
+		w = an.Targets.RollQuality
+	}
+	// Count the trigrams per quality, and sum them in a fixed order, so that the metric
+	// doesn't depend on the order of the trigrams
+	counts := make(map[float64]uint64)
+	for _, ti := range an.trigrams() {
+		k0, _ := an.Layout.GetKeyInfo(ti.Runes[0])
+		k1, _ := an.Layout.GetKeyInfo(ti.Runes[1])
+		k2, _ := an.Layout.GetKeyInfo(ti.Runes[2])
+		if q := w.rollQuality(classifyTrigram(k0, k1, k2), k0, k1, k2); q > 0 {
+			counts[q] += ti.Count
+		}
+	}
+	var sum float64
+	for _, q := range slices.Sorted(maps.Keys(counts)) {
+		sum += q * float64(counts[q])
+	}
+	an.Metrics["ROLLQ"] = 100 * sum / float64(an.Corpus.TotalTrigramsCount)
+}
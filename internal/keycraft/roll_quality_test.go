@@ -0,0 +1,70 @@
+package keycraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetRollQuality(t *testing.T) {
+	tl := &TargetLoads{}
+	if err := tl.SetRollQuality("2, 0, 1, 0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (RollQualityWeights{Direction: 2, Row: 1, Finger: 0.5}); *tl.RollQuality != want {
+		t.Errorf("roll quality = %+v, want %+v", *tl.RollQuality, want)
+	}
+
+	for _, s := range []string{"1, 1, 1", "1, 1, 1, x", "1, -1, 1, 1", "0, 0, 0, 0"} {
+		if err := tl.SetRollQuality(s); err == nil {
+			t.Errorf("SetRollQuality(%q): expected an error", s)
+		}
+	}
+}
+
+func TestAnalyser_RollQuality(t *testing.T) {
+	var runes [42]rune
+	runes[2], runes[13], runes[15], runes[16], runes[19], runes[37] = 'w', 'a', 'd', 'f', 'j', ' '
+	sl := NewSplitLayout("test", ORTHO, runes)
+
+	// "dfj" is an inward roll on the home row with the middle and index finger, "waj" an
+	// outward roll across rows with the ring finger and pinky
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("dfj waj")
+	w := DefaultRollQuality()
+	good := (w.Direction + w.Row + w.Finger) / 3.5
+	bad := w.Finger * (0.5 + 0.25) / 2 / 3.5
+
+	an := NewAnalyser(sl, corpus, nil)
+	if got, want := an.Metrics["ROLLQ"], 100*(good+bad)/2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("ROLLQ = %v, want %v", got, want)
+	}
+	if rolls := an.Metrics["2RL-IN"] + an.Metrics["2RL-OUT"]; an.Metrics["ROLLQ"] > rolls {
+		t.Errorf("ROLLQ = %v exceeds the rolls %v", an.Metrics["ROLLQ"], rolls)
+	}
+
+	// Only the direction counts
+	targets := NewTargetLoads()
+	if err := targets.SetRollQuality("1, 0, 0, 0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := NewAnalyser(sl, corpus, targets).Metrics["ROLLQ"]; got != 50 {
+		t.Errorf("ROLLQ by direction = %v, want 50", got)
+	}
+}
+
+func TestNewTargetLoadsFromFile_RollQuality(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.txt")
+	Must0(os.WriteFile(path, []byte("roll-quality = 0, 1, 0, 0\n"), 0644))
+	targets := Must(NewTargetLoadsFromFile(path))
+	if want := (RollQualityWeights{Length: 1}); *targets.RollQuality != want {
+		t.Errorf("roll quality = %+v, want %+v", *targets.RollQuality, want)
+	}
+
+	Must0(os.WriteFile(path, []byte("roll-quality = 1, 2\n"), 0644))
+	if _, err := NewTargetLoadsFromFile(path); err == nil || !strings.Contains(err.Error(), "roll-quality") {
+		t.Errorf("error = %v, want an invalid roll-quality", err)
+	}
+}
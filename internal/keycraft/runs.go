@@ -0,0 +1,139 @@
+package keycraft
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Files of a run directory, as written by `optimize --run-dir`.
+const (
+	RunManifestFile = "manifest.json" // RunManifest of the run
+	RunHistoryFile  = "history.jsonl" // History of every new-best layout, see HistoryRecorder
+	RunLayoutFile   = "best.klf"      // Best layout of the run
+)
+
+// RunManifest describes an optimization run recorded in a run directory.
+type RunManifest struct {
+	Layout      string            `json:"layout"`      // Name of the optimized layout
+	Started     time.Time         `json:"started"`     // Start time of the run
+	ElapsedMs   int64             `json:"elapsed_ms"`  // Duration of the run
+	Interrupted bool              `json:"interrupted"` // Whether the run was stopped early
+	Params      map[string]string `json:"params"`      // Parameters of the run, by name
+}
+
+// Run is an optimization run loaded from a run directory.
+type Run struct {
+	Dir      string
+	Manifest RunManifest
+	History  []HistoryEntry
+	Best     *SplitLayout // Best layout, named after the run directory
+}
+
+// WriteRunManifest writes the manifest of a run to its run directory.
+func WriteRunManifest(dir string, m RunManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode run manifest: %w", err)
+	}
+	path := filepath.Join(dir, RunManifestFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("could not write run manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadRun loads the manifest, history and best layout of a run directory.
+func LoadRun(dir string) (*Run, error) {
+	path := filepath.Join(dir, RunManifestFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read run manifest %s: %w", path, err)
+	}
+	run := &Run{Dir: dir}
+	if err := json.Unmarshal(data, &run.Manifest); err != nil {
+		return nil, fmt.Errorf("could not parse run manifest %s: %w", path, err)
+	}
+
+	run.History, err = LoadHistoryFile(filepath.Join(dir, RunHistoryFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(run.History) == 0 {
+		return nil, fmt.Errorf("history of run %s is empty", dir)
+	}
+
+	name := filepath.Base(filepath.Clean(dir))
+	run.Best, err = NewLayoutFromFile(name, filepath.Join(dir, RunLayoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not load best layout of run %s: %w", dir, err)
+	}
+	return run, nil
+}
+
+// InitialCost returns the cost of the layout the run started from.
+func (r *Run) InitialCost() float64 {
+	return r.History[0].Cost
+}
+
+// BestCost returns the cost of the best layout of the run.
+func (r *Run) BestCost() float64 {
+	return r.History[len(r.History)-1].Cost
+}
+
+// BestMetrics returns the metrics of the best layout of the run.
+func (r *Run) BestMetrics() map[string]float64 {
+	return r.History[len(r.History)-1].Metrics
+}
+
+// ParamDiff is a parameter that differs between two runs. A parameter that a run lacks
+// has an empty value.
+type ParamDiff struct {
+	Name string
+	A, B string
+}
+
+// RunComparison compares two optimization runs.
+type RunComparison struct {
+	A, B         *Run
+	ParamDiffs   []ParamDiff // Parameters that differ, by name
+	SameParams   int         // Number of parameters that are the same
+	Metrics      []string    // Metrics of both best layouts, sorted
+	ChangedCount int         // Number of metrics that differ between the best layouts
+}
+
+// CompareRuns compares the parameters and best layouts of two runs.
+func CompareRuns(a, b *Run) *RunComparison {
+	cmp := &RunComparison{A: a, B: b}
+
+	names := slices.Sorted(maps.Keys(a.Manifest.Params))
+	for name := range b.Manifest.Params {
+		if _, ok := a.Manifest.Params[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		va, vb := a.Manifest.Params[name], b.Manifest.Params[name]
+		if va == vb {
+			cmp.SameParams++
+		} else {
+			cmp.ParamDiffs = append(cmp.ParamDiffs, ParamDiff{Name: name, A: va, B: vb})
+		}
+	}
+
+	metricsA, metricsB := a.BestMetrics(), b.BestMetrics()
+	for _, metric := range slices.Sorted(maps.Keys(metricsA)) {
+		if vb, ok := metricsB[metric]; ok {
+			cmp.Metrics = append(cmp.Metrics, metric)
+			if vb != metricsA[metric] {
+				cmp.ChangedCount++
+			}
+		}
+	}
+	return cmp
+}
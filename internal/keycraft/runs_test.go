@@ -0,0 +1,74 @@
+package keycraft
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestRun writes a run directory with a two-entry history for the test layout.
+func writeTestRun(t *testing.T, params map[string]string, cost float64) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "run")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	layout := Must(writeShiftedLayout(t, ""))
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+
+	var history bytes.Buffer
+	rec := NewHistoryRecorder(&history, corpus, nil)
+	Must0(rec.Record(0, 10, layout, 0))
+	Must0(rec.Record(25, cost, layout, time.Second))
+	Must0(os.WriteFile(filepath.Join(dir, RunHistoryFile), history.Bytes(), 0644))
+	Must0(layout.SaveToFile(filepath.Join(dir, RunLayoutFile)))
+	Must0(WriteRunManifest(dir, RunManifest{Layout: "a", ElapsedMs: 1500, Params: params}))
+	return dir
+}
+
+func TestLoadRun_RoundTrip(t *testing.T) {
+	dir := writeTestRun(t, map[string]string{"seed": "1"}, 4)
+	run := Must(LoadRun(dir))
+
+	if run.Manifest.Layout != "a" || run.Manifest.ElapsedMs != 1500 || run.Manifest.Params["seed"] != "1" {
+		t.Errorf("unexpected manifest: %+v", run.Manifest)
+	}
+	if run.InitialCost() != 10 || run.BestCost() != 4 {
+		t.Errorf("costs %v -> %v, want 10 -> 4", run.InitialCost(), run.BestCost())
+	}
+	if run.Best.Name != "run" {
+		t.Errorf("best layout is named %q, want the run directory", run.Best.Name)
+	}
+	if _, ok := run.BestMetrics()["SFB"]; !ok {
+		t.Error("expected metrics of the best layout")
+	}
+
+	if _, err := LoadRun(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without a manifest")
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	a := Must(LoadRun(writeTestRun(t, map[string]string{"seed": "1", "generations": "100", "pins": "a"}, 4)))
+	b := Must(LoadRun(writeTestRun(t, map[string]string{"seed": "2", "generations": "100", "free": "x"}, 3)))
+
+	cmp := CompareRuns(a, b)
+	want := []ParamDiff{{"free", "", "x"}, {"pins", "a", ""}, {"seed", "1", "2"}}
+	if len(cmp.ParamDiffs) != len(want) {
+		t.Fatalf("parameter differences %v, want %v", cmp.ParamDiffs, want)
+	}
+	for i, d := range cmp.ParamDiffs {
+		if d != want[i] {
+			t.Errorf("difference %d is %v, want %v", i, d, want[i])
+		}
+	}
+	if cmp.SameParams != 1 {
+		t.Errorf("%d parameters are the same, want 1", cmp.SameParams)
+	}
+	if len(cmp.Metrics) == 0 || cmp.ChangedCount != 0 {
+		t.Errorf("%d of %d metrics differ, want 0 of some for the same layout", cmp.ChangedCount, len(cmp.Metrics))
+	}
+}
@@ -0,0 +1,100 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnalysisScope is the subset of characters that layouts are analysed on. Layouts that only
+// place letters can then be compared with layouts that also place punctuation, without the
+// punctuation of the corpus diluting the percentages of the letters.
+type AnalysisScope uint8
+
+const (
+	ScopeFull        AnalysisScope = iota // All characters of the corpus
+	ScopeAlphas                           // Letters only
+	ScopeAlphasPunct                      // Letters, punctuation and symbols
+)
+
+// AnalysisScopeNames are the names of the analysis scopes, as given to --scope.
+var AnalysisScopeNames = map[AnalysisScope]string{
+	ScopeFull:        "full",
+	ScopeAlphas:      "alphas",
+	ScopeAlphasPunct: "alphas+punct",
+}
+
+// ParseAnalysisScope parses a --scope specification: "full" (or ""), "alphas" or
+// "alphas+punct".
+func ParseAnalysisScope(spec string) (AnalysisScope, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" {
+		return ScopeFull, nil
+	}
+	for scope, name := range AnalysisScopeNames {
+		if spec == name {
+			return scope, nil
+		}
+	}
+	return ScopeFull, fmt.Errorf("invalid scope %q; must be alphas, alphas+punct or full", spec)
+}
+
+// String returns the name of the scope.
+func (s AnalysisScope) String() string {
+	return AnalysisScopeNames[s]
+}
+
+// Includes reports whether a character is in the scope.
+func (s AnalysisScope) Includes(r rune) bool {
+	switch s {
+	case ScopeAlphas:
+		return RuneClassOf(r) == LetterClass
+	case ScopeAlphasPunct:
+		return RuneClassOf(r) != OtherClass
+	}
+	return true
+}
+
+// Scoped returns a copy of the corpus with only the n-grams whose characters are all in the
+// scope, and totals of only those n-grams, so that percentages are of the characters in the
+// scope. The characters that are not in the scope are left out of the stream. The word list
+// is shared with the receiver. The name of the copy mentions the scope.
+// The full scope returns the receiver.
+func (c *Corpus) Scoped(s AnalysisScope) *Corpus {
+	if s == ScopeFull {
+		return c
+	}
+	scoped := *c
+	scoped.Name = fmt.Sprintf("%s (%s)", c.Name, s)
+	scoped.Unigrams, scoped.TotalUnigramsCount = scopeCounts(c.Unigrams, func(u Unigram) bool {
+		return s.Includes(rune(u))
+	})
+	scoped.Bigrams, scoped.TotalBigramsCount = scopeCounts(c.Bigrams, func(b Bigram) bool {
+		return s.Includes(b[0]) && s.Includes(b[1])
+	})
+	scoped.Trigrams, scoped.TotalTrigramsCount = scopeCounts(c.Trigrams, func(t Trigram) bool {
+		return s.Includes(t[0]) && s.Includes(t[1]) && s.Includes(t[2])
+	})
+	scoped.Skipgrams, scoped.TotalSkipgramsCount = scopeCounts(c.Skipgrams, func(sk Skipgram) bool {
+		return s.Includes(sk[0]) && s.Includes(sk[1])
+	})
+	scoped.Stream = strings.Map(func(r rune) rune {
+		if r == '\n' || s.Includes(r) {
+			return r
+		}
+		return -1
+	}, c.Stream)
+	return &scoped
+}
+
+// scopeCounts returns the counts of an n-gram table that are kept, and their total.
+func scopeCounts[K comparable](counts map[K]uint64, keep func(K) bool) (map[K]uint64, uint64) {
+	kept := make(map[K]uint64, len(counts))
+	var total uint64
+	for k, cnt := range counts {
+		if keep(k) {
+			kept[k] = cnt
+			total += cnt
+		}
+	}
+	return kept, total
+}
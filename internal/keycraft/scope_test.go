@@ -0,0 +1,46 @@
+package keycraft
+
+import "testing"
+
+func TestParseAnalysisScope(t *testing.T) {
+	for spec, want := range map[string]AnalysisScope{
+		"": ScopeFull, "full": ScopeFull, "alphas": ScopeAlphas, " Alphas+Punct ": ScopeAlphasPunct,
+	} {
+		if got := Must(ParseAnalysisScope(spec)); got != want {
+			t.Errorf("ParseAnalysisScope(%q) = %v, want %v", spec, got, want)
+		}
+	}
+	if _, err := ParseAnalysisScope("letters"); err == nil {
+		t.Error("expected error for an invalid scope")
+	}
+}
+
+func TestCorpus_Scoped(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("ab, cd. ab")
+	if corpus.Scoped(ScopeFull) != corpus {
+		t.Error("full scope should return the corpus")
+	}
+
+	alphas := corpus.Scoped(ScopeAlphas)
+	if alphas.Name != "test (alphas)" {
+		t.Errorf("name = %q", alphas.Name)
+	}
+	if alphas.Unigrams[','] != 0 || alphas.TotalUnigramsCount != 6 || alphas.Unigrams['a'] != 2 {
+		t.Errorf("unigrams = %v, total %d", alphas.Unigrams, alphas.TotalUnigramsCount)
+	}
+	if alphas.Bigrams[Bigram{'b', ','}] != 0 || alphas.TotalBigramsCount != 3 {
+		t.Errorf("bigrams = %v, total %d", alphas.Bigrams, alphas.TotalBigramsCount)
+	}
+	if alphas.TotalTrigramsCount != 0 || alphas.TotalSkipgramsCount != 0 {
+		t.Errorf("trigrams %d, skipgrams %d, want none", alphas.TotalTrigramsCount, alphas.TotalSkipgramsCount)
+	}
+	if corpus.Unigrams[','] != 1 {
+		t.Error("scoping changed the corpus")
+	}
+
+	punct := corpus.Scoped(ScopeAlphasPunct)
+	if punct.TotalUnigramsCount != 8 || punct.Trigrams[Trigram{'a', 'b', ','}] != 1 {
+		t.Errorf("unigrams total %d, trigrams %v", punct.TotalUnigramsCount, punct.Trigrams)
+	}
+}
@@ -0,0 +1,100 @@
+package keycraft
+
+import (
+	"container/list"
+	"sync"
+)
+
+// scoreCacheEntry is a single cached layout score.
+type scoreCacheEntry struct {
+	key   string
+	score float64
+}
+
+// scoreCache is a thread-safe LRU cache of layout scores keyed by layoutCacheKey.
+// A capacity of 0 means the cache is unbounded. When the cache is full, the least
+// recently used entry is evicted to make room for a new one.
+type scoreCache struct {
+	mu        sync.Mutex
+	capacity  int
+	items     map[string]*list.Element
+	order     *list.List // Front is most recently used
+	evictions int64
+}
+
+// newScoreCache creates a score cache holding at most capacity entries (0 = unbounded).
+func newScoreCache(capacity int) *scoreCache {
+	return &scoreCache{
+		capacity: max(capacity, 0),
+		items:    make(map[string]*list.Element, 1000),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached score for key and marks it as recently used.
+func (c *scoreCache) get(key string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return 0, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*scoreCacheEntry).score, true
+}
+
+// put stores the score for key, evicting the least recently used entries if needed.
+func (c *scoreCache) put(key string, score float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*scoreCacheEntry).score = score
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&scoreCacheEntry{key: key, score: score})
+	c.evictOverflow()
+}
+
+// setCapacity changes the maximum number of entries (0 = unbounded), evicting the
+// least recently used entries if the cache currently holds more than that.
+func (c *scoreCache) setCapacity(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = max(capacity, 0)
+	c.evictOverflow()
+}
+
+// evictOverflow removes least recently used entries until the capacity is respected.
+// The caller must hold c.mu.
+func (c *scoreCache) evictOverflow() {
+	if c.capacity == 0 {
+		return
+	}
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*scoreCacheEntry).key)
+		c.evictions++
+	}
+}
+
+// clear removes all entries. The eviction count is preserved.
+func (c *scoreCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.items)
+	c.order.Init()
+}
+
+// stats returns the number of cached entries, the capacity, and the number of evictions.
+func (c *scoreCache) stats() (size, capacity int, evictions int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), c.capacity, c.evictions
+}
@@ -13,13 +13,39 @@ import (
 	"sync/atomic"
 )
 
-// layoutCacheKey generates a unique cache key based on LayoutType and Runes.
+// layoutCacheKey generates a unique cache key based on LayoutType, Runes and any custom
+// thumb geometry.
 // This ensures cache hits for layouts with the same configuration regardless of their name.
 func layoutCacheKey(layout *SplitLayout) string {
 	var b strings.Builder
 	b.Grow(1 + 42) // layoutType byte + 42 runes
 	b.WriteByte(byte(layout.LayoutType))
 	b.WriteString(string(layout.Runes[:]))
+	if layout.Thumbs != nil {
+		// Custom thumb geometry changes key distances
+		b.WriteString(layout.Thumbs.String())
+	}
+	if layout.shiftKey != "" {
+		// Shifted characters and the shift finger change the folded corpus and shift metrics
+		b.WriteString(layout.shiftKey)
+		b.WriteByte(byte(layout.ShiftHolder))
+	}
+	if layout.Magic != nil {
+		// The magic key changes the corpus as typed
+		b.WriteString(layout.Magic.spec)
+	}
+	if layout.NumberRow != nil {
+		// The number row changes the number metrics
+		b.WriteString(string(layout.NumberRow[:]))
+	}
+	if layout.Board != "" {
+		// The geometry of the board changes key distances
+		b.WriteString("board " + layout.Board)
+	}
+	if layout.Variant != VariantNone {
+		// The board variant changes key distances
+		b.WriteString(string(layout.Variant))
+	}
 	return b.String()
 }
 
@@ -34,43 +60,69 @@ type Scorer struct {
 	medians           map[string]float64 // Median values for each metric (filtered)
 	iqrs              map[string]float64 // Interquartile ranges for each metric (filtered)
 	weights           map[string]float64 // Importance weights for each metric (filtered)
-	scoreCache        map[string]float64 // Cache of computed scores by layout identifier
-	cacheMu           sync.RWMutex       // Protects scoreCache for concurrent access
+	scoreCache        *scoreCache        // LRU cache of computed scores by layout identifier
 	DisableScoreCache bool               // If true, skip score cache lookup/storage
 
 	// Pre-filtered n-gram caches (computed lazily on first Score() call)
 	trigramCache      []TrigramInfo // Pre-filtered trigrams with KeyInfo lookups
+	trigramTable      *trigramTable // trigramCache translated to rune indices
 	trigramCacheOnce  sync.Once     // Ensures trigram cache is initialized exactly once
 	DisableNGramCache bool          // If true, don't inject n-gram caches into Analyser
 
+	// Multi-corpus scoring: when non-empty, the score is the weighted sum of the
+	// scores of these per-corpus scorers, and the fields above are unused
+	parts []weightedScorer
+
+	// Cost of the key positions changed from the layout being optimized (see SetChangePenalty)
+	changes *changePenalty
+
+	// Taboo bigrams that must not be typed as SFBs or scissors (see SetTabooPatterns)
+	taboo *tabooCheck
+
+	// Corpus subsampling (see SetSubsample): when enabled, Score uses a random sample of
+	// the corpus and its trigram cache, redrawn by Resample
+	subsample      float64       // Fraction of the corpus in a sample (0 = disabled)
+	sampleRng      *LockedSource // Source of the samples
+	sample         *Corpus       // Current corpus sample
+	sampleTrigrams *trigramTable // Current sample of the trigram cache
+
 	// Statistics tracking (atomic for thread safety)
 	cacheHits   atomic.Int64 // Number of cache hits
 	cacheMisses atomic.Int64 // Number of cache misses
+	cutShort    atomic.Int64 // Number of ScoreSwap calls that skipped the costly metrics
+}
+
+// weightedScorer is one corpus of a multi-corpus Scorer.
+type weightedScorer struct {
+	scorer *Scorer
+	weight float64 // Normalised so that the weights of all parts sum to 1
 }
 
 // NewScorer creates a new Scorer by analyzing reference layouts from the given directory.
 // It computes median and IQR statistics from the reference layouts and filters out metrics
-// with insignificant variance or weight to ensure robust scoring.
-func NewScorer(layoutsDir string, corpus *Corpus, targets *TargetLoads, weights *Weights) (*Scorer, error) {
-	analysers, err := LoadAnalysers(layoutsDir, corpus, targets, true)
+// with insignificant variance or weight to ensure robust scoring. A nil reference set
+// selects the reference layouts using the default naming rule.
+func NewScorer(layoutsDir string, corpus *Corpus, targets *TargetLoads, weights *Weights,
+	reference *ReferenceSet) (*Scorer, error) {
+	analysers, err := loadReferenceAnalysers(layoutsDir, corpus, targets, reference)
 	if err != nil {
-		return nil, fmt.Errorf("could not load analysers: %w", err)
+		return nil, err
 	}
-	medians, iqrs := computeMediansAndIQR(analysers, false)
+	medians, iqrs := computeMediansAndIQR(analysers, nil)
 
 	// Filter out metrics with insignificant IQR values or weights
 	numMetrics := len(medians)
 	filteredMedians := make(map[string]float64, numMetrics)
 	filteredIQRs := make(map[string]float64, numMetrics)
 	filteredWeights := make(map[string]float64, numMetrics)
+	cutoff := weights.minScored()
 	for metric, median := range medians {
-		const epsilon = 1e-9
 		iqr, iqrExists := iqrs[metric]
-		if !iqrExists || iqr <= epsilon {
+		if !iqrExists || iqr <= minScoredIQR {
 			continue
 		}
 		weight := weights.Get(metric)
-		if math.Abs(weight) <= 0.01 {
+		if math.Abs(weight) <= cutoff {
 			// Often, tiny weights are assigned to have them in the Weights struct, but not
 			// to actually count towards anything. So, ignore tiny weights.
 			continue
@@ -86,7 +138,7 @@ func NewScorer(layoutsDir string, corpus *Corpus, targets *TargetLoads, weights
 		medians:    filteredMedians,
 		iqrs:       filteredIQRs,
 		weights:    filteredWeights,
-		scoreCache: make(map[string]float64, 1000),
+		scoreCache: newScoreCache(0),
 	}
 
 	return sc, nil
@@ -102,34 +154,66 @@ func NewScorerWithStats(corpus *Corpus, targets *TargetLoads, medians, iqrs, fil
 		medians:    medians,
 		iqrs:       iqrs,
 		weights:    filteredWeights,
-		scoreCache: make(map[string]float64, 1000),
+		scoreCache: newScoreCache(0),
+	}
+}
+
+// NewMultiCorpusScorer creates a Scorer that scores layouts against several corpora
+// separately and combines the costs using the corpus weights, normalised to sum to 1.
+// Each corpus gets its own reference statistics, so every part is normalised on the
+// same scale before weighting.
+func NewMultiCorpusScorer(layoutsDir string, corpora []WeightedCorpus, targets *TargetLoads,
+	weights *Weights, reference *ReferenceSet) (*Scorer, error) {
+	if len(corpora) == 0 {
+		return nil, fmt.Errorf("no corpora given")
+	}
+
+	var total float64
+	for _, wc := range corpora {
+		if wc.Weight <= 0 {
+			return nil, fmt.Errorf("corpus %s has weight %g; weights must be above 0", wc.Corpus.Name, wc.Weight)
+		}
+		total += wc.Weight
+	}
+
+	sc := &Scorer{scoreCache: newScoreCache(0)}
+	for _, wc := range corpora {
+		part, err := NewScorer(layoutsDir, wc.Corpus, targets, weights, reference)
+		if err != nil {
+			return nil, fmt.Errorf("could not create scorer for corpus %s: %w", wc.Corpus.Name, err)
+		}
+		part.DisableScoreCache = true // combined scores are cached by the parent
+		sc.parts = append(sc.parts, weightedScorer{scorer: part, weight: wc.Weight / total})
 	}
+
+	return sc, nil
 }
 
 // ComputeReferenceStats loads reference layouts, computes medians/IQRs, and filters
 // metrics by IQR significance and weight magnitude. Returns the filtered maps suitable
-// for passing to NewScorerWithStats.
-func ComputeReferenceStats(layoutsDir string, corpus *Corpus, targets *TargetLoads, weights *Weights) (
-	medians, iqrs, filteredWeights map[string]float64, err error) {
-	analysers, err := LoadAnalysers(layoutsDir, corpus, targets, true)
+// for passing to NewScorerWithStats. A nil reference set selects the reference layouts
+// using the default naming rule.
+func ComputeReferenceStats(layoutsDir string, corpus *Corpus, targets *TargetLoads, weights *Weights,
+	reference *ReferenceSet) (medians, iqrs, filteredWeights map[string]float64, err error) {
+	analysers, err := loadReferenceAnalysers(layoutsDir, corpus, targets, reference)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("could not load analysers: %w", err)
+		return nil, nil, nil, err
 	}
-	rawMedians, rawIQRs := computeMediansAndIQR(analysers, false)
+	rawMedians, rawIQRs := computeMediansAndIQR(analysers, nil)
 
 	// Filter out metrics with insignificant IQR values or weights
 	numMetrics := len(rawMedians)
 	medians = make(map[string]float64, numMetrics)
 	iqrs = make(map[string]float64, numMetrics)
 	filteredWeights = make(map[string]float64, numMetrics)
+	cutoff := weights.minScored()
 	for metric, median := range rawMedians {
-		const epsilon = 1e-9
 		iqr, iqrExists := rawIQRs[metric]
-		if !iqrExists || iqr <= epsilon {
+		if !iqrExists || iqr <= minScoredIQR {
 			continue
 		}
 		weight := weights.Get(metric)
-		if math.Abs(weight) <= 0.01 {
+		if math.Abs(weight) <= cutoff {
 			continue
 		}
 		medians[metric] = median
@@ -185,33 +269,25 @@ func (sc *Scorer) prepareTrigramCache(templateLayout *SplitLayout) {
 
 	// Step 4: Keep only trigrams that meet 98% coverage threshold
 	sc.trigramCache = layoutFiltered[:cutoffIndex]
+	sc.trigramTable = newTrigramTable(sc.trigramCache)
 }
 
 // Score evaluates a layout by computing a weighted sum of normalized metrics.
 // Each metric is normalized using robust scaling: (value - median) / IQR.
 // Only metrics with non-zero weights and sufficient variance are scored.
 // The weighted sum is subtracted to produce a cost score where lower is better.
+// A multi-corpus scorer returns the weighted sum of the costs against each corpus.
 // Results are cached by layout configuration to avoid redundant calculations (unless DisableScoreCache is true).
 // Thread-safe for concurrent access.
 func (sc *Scorer) Score(layout *SplitLayout) float64 {
-	// Initialize n-gram caches lazily on first call (unless disabled)
-	if !sc.DisableNGramCache {
-		sc.trigramCacheOnce.Do(func() {
-			sc.prepareTrigramCache(layout)
-		})
-	}
+	sc.prepareNGramCaches(layout)
 
 	// Check score cache first (unless disabled)
 	var cacheKey string
 	if !sc.DisableScoreCache {
 		cacheKey = layoutCacheKey(layout)
 
-		// Read lock for cache check
-		sc.cacheMu.RLock()
-		cachedScore, exists := sc.scoreCache[cacheKey]
-		sc.cacheMu.RUnlock()
-
-		if exists {
+		if cachedScore, exists := sc.scoreCache.get(cacheKey); exists {
 			sc.cacheHits.Add(1)
 			return cachedScore
 		}
@@ -219,35 +295,165 @@ func (sc *Scorer) Score(layout *SplitLayout) float64 {
 	}
 
 	// Calculate score
+	var score float64
+	if len(sc.parts) > 0 {
+		for _, part := range sc.parts {
+			score += part.weight * part.scorer.Score(layout)
+		}
+	} else {
+		score = sc.scoreSingle(layout)
+	}
+	score += sc.penalty(layout)
+
+	// Update cache (unless disabled)
+	if !sc.DisableScoreCache {
+		sc.scoreCache.put(cacheKey, score)
+	}
+
+	return score
+}
+
+// prepareNGramCaches initializes the n-gram caches lazily on first use (unless disabled).
+func (sc *Scorer) prepareNGramCaches(layout *SplitLayout) {
+	if !sc.DisableNGramCache && len(sc.parts) == 0 {
+		sc.trigramCacheOnce.Do(func() {
+			sc.prepareTrigramCache(layout)
+		})
+	}
+}
+
+// scoringCorpus returns the corpus and pre-filtered trigrams that Score uses: the scorer's own
+// corpus, or its current sample when subsampling.
+func (sc *Scorer) scoringCorpus() (*Corpus, *trigramTable) {
+	if sc.sample != nil {
+		return sc.sample, sc.sampleTrigrams
+	}
+	return sc.corpus, sc.trigramTable
+}
+
+// scoreSingle computes the cost of a layout against the scorer's own corpus, or its current
+// sample when subsampling.
+func (sc *Scorer) scoreSingle(layout *SplitLayout) float64 {
+	corpus, trigrams := sc.scoringCorpus()
+	return sc.scoreAgainst(layout, corpus, trigrams)
+}
+
+// scoreAgainst computes the cost of a layout against a corpus and its pre-filtered trigrams.
+func (sc *Scorer) scoreAgainst(layout *SplitLayout, corpus *Corpus, trigrams *trigramTable) float64 {
+	an := sc.analyseCheap(layout, corpus, trigrams)
+	sc.analyseCostly(an)
+	return sc.cost(an.Metrics)
+}
+
+// analyseCheap creates an analyser of the layout and computes the metrics that are quick to
+// compute: the hand, bigram, skipgram, shift and learning cost metrics.
+func (sc *Scorer) analyseCheap(layout *SplitLayout, corpus *Corpus, trigrams *trigramTable) *Analyser {
 	an := &Analyser{
 		Layout:           layout,
-		Corpus:           sc.corpus,
+		Corpus:           corpus,
 		Targets:          sc.targets,
 		Metrics:          make(map[string]float64, 60),
-		relevantTrigrams: sc.trigramCache, // Inject pre-filtered trigrams for performance optimization
+		relevantTrigrams: trigrams.infos(), // Inject pre-filtered trigrams for performance optimization
+		trigramTable:     trigrams,
 	}
 
+	an.useShiftedCorpus()
+	an.useMagicCorpus()
 	an.analyseHand()
 	an.analyseBigrams()
 	an.analyseSkipgrams()
+	an.analyseShift()
+	an.analyseNumbers()
+	an.analyseMoves()
+	return an
+}
+
+// analyseCostly computes the remaining metrics after analyseCheap: the trigram metrics, and
+// ROLLQ and the metrics that scan the word list or stream if they are weighted.
+func (sc *Scorer) analyseCostly(an *Analyser) {
 	an.analyseTrigrams()
+	if sc.weighs("ROLLQ") {
+		an.analyseRollQuality()
+	}
+	if sc.weighs(HandRunMetrics...) {
+		an.analyseHandRuns()
+	}
+	if sc.weighs("FATIGUE") {
+		an.analyseFatigue()
+	}
+	if sc.weighs("MAGIC", "MAGIC-SFB", "MAGIC-SFS") {
+		an.analyseMagic()
+	}
+}
 
+// cost returns the negated weighted sum of the normalised metrics, so that lower is better.
+// Metrics that were not computed are left out.
+func (sc *Scorer) cost(metrics map[string]float64) float64 {
 	score := 0.0
-	for metric, iqr := range sc.iqrs {
+	for metric := range sc.iqrs {
+		if value, exists := metrics[metric]; exists {
+			score += sc.metricCost(metric, value)
+		}
+	}
+	return score
+}
+
+// metricCost returns the share of a scored metric in the cost of a layout.
+func (sc *Scorer) metricCost(metric string, value float64) float64 {
+	return -sc.weights[metric] * (value - sc.medians[metric]) / sc.iqrs[metric]
+}
+
+// MetricCosts returns the share of every scored metric in the cost of a layout against the
+// full corpus, also when subsampling. The shares add up to the result of ScoreExact.
+func (sc *Scorer) MetricCosts(layout *SplitLayout) map[string]float64 {
+	costs := make(map[string]float64, len(sc.iqrs))
+	if len(sc.parts) > 0 {
+		for _, part := range sc.parts {
+			for metric, cost := range part.scorer.MetricCosts(layout) {
+				costs[metric] += part.weight * cost
+			}
+		}
+		sc.addPenalties(costs, layout)
+		return costs
+	}
+
+	sc.prepareNGramCaches(layout)
+	an := sc.analyseCheap(layout, sc.corpus, sc.trigramTable)
+	sc.analyseCostly(an)
+	for metric := range sc.iqrs {
 		if value, exists := an.Metrics[metric]; exists {
-			scaledValue := (value - sc.medians[metric]) / iqr
-			score -= sc.weights[metric] * scaledValue
+			costs[metric] = sc.metricCost(metric, value)
 		}
 	}
+	sc.addPenalties(costs, layout)
+	return costs
+}
 
-	// Update cache (unless disabled)
-	if !sc.DisableScoreCache {
-		sc.cacheMu.Lock()
-		sc.scoreCache[cacheKey] = score
-		sc.cacheMu.Unlock()
+// addPenalties adds the change and taboo penalties of the layout to its metric costs, if any.
+func (sc *Scorer) addPenalties(costs map[string]float64, layout *SplitLayout) {
+	if cost := sc.changes.cost(layout); cost != 0 {
+		costs[ChangesMetric] = cost
+	}
+	if cost := sc.taboo.cost(layout); cost != 0 {
+		costs[TabooMetric] = cost
+	}
+}
+
+// weighs reports whether the scorer uses any of the given metrics.
+func (sc *Scorer) weighs(metrics ...string) bool {
+	for _, metric := range metrics {
+		if _, ok := sc.iqrs[metric]; ok {
+			return true
+		}
 	}
+	return false
+}
 
-	return score
+// SetCacheSize limits the score cache to at most n layouts (0 = unbounded).
+// When the cache is full, the least recently used score is evicted.
+// Can be called at any time; excess entries are evicted immediately.
+func (sc *Scorer) SetCacheSize(n int) {
+	sc.scoreCache.setCapacity(n)
 }
 
 // ScorerStats holds statistics about Scorer performance.
@@ -257,7 +463,10 @@ type ScorerStats struct {
 	CacheMisses    int64   // Number of cache misses
 	HitRate        float64 // Cache hit rate as percentage (0-100)
 	UniqueLayouts  int     // Number of unique layouts cached
+	CacheCapacity  int     // Maximum number of cached layouts (0 = unbounded)
+	CacheEvictions int64   // Number of scores evicted from the cache
 	CacheSizeBytes int     // Estimated cache memory usage in bytes
+	CutShort       int64   // Number of swaps ruled out by ScoreSwap before the costly metrics
 }
 
 // GetStats returns current statistics about the Scorer's performance.
@@ -272,15 +481,12 @@ func (sc *Scorer) GetStats() ScorerStats {
 		hitRate = (float64(hits) / float64(total)) * 100.0
 	}
 
-	// Read lock to get cache size
-	sc.cacheMu.RLock()
-	cacheLen := len(sc.scoreCache)
-	sc.cacheMu.RUnlock()
+	cacheLen, capacity, evictions := sc.scoreCache.stats()
 
 	// Estimate cache memory usage
 	// Each entry: ~44 bytes for key (layout type + 42 runes) + 8 bytes for float64 value
-	// Plus map overhead (~48 bytes per entry in Go)
-	avgEntrySize := 100 // Conservative estimate
+	// Plus map and LRU list overhead (~48 + ~48 bytes per entry in Go)
+	avgEntrySize := 150 // Conservative estimate
 	cacheSize := cacheLen * avgEntrySize
 
 	return ScorerStats{
@@ -289,7 +495,10 @@ func (sc *Scorer) GetStats() ScorerStats {
 		CacheMisses:    misses,
 		HitRate:        hitRate,
 		UniqueLayouts:  cacheLen,
+		CacheCapacity:  capacity,
+		CacheEvictions: evictions,
 		CacheSizeBytes: cacheSize,
+		CutShort:       sc.cutShort.Load(),
 	}
 }
 
@@ -305,7 +514,14 @@ func (sc *Scorer) LogStats(w io.Writer) {
 	MustFprintf(w, "Cache hits:              %s (%.1f%%)\n", formatInt(stats.CacheHits), stats.HitRate)
 	MustFprintf(w, "Cache misses:            %s (%.1f%%)\n", formatInt(stats.CacheMisses), 100.0-stats.HitRate)
 	MustFprintf(w, "Unique layouts cached:   %s\n", formatInt(int64(stats.UniqueLayouts)))
+	if stats.CacheCapacity > 0 {
+		MustFprintf(w, "Cache capacity:          %s\n", formatInt(int64(stats.CacheCapacity)))
+		MustFprintf(w, "Cache evictions:         %s\n", formatInt(stats.CacheEvictions))
+	}
 	MustFprintf(w, "Cache memory usage:      ~%s\n", formatBytes(stats.CacheSizeBytes))
+	if stats.CutShort > 0 {
+		MustFprintf(w, "Swaps cut short:         %s\n", formatInt(stats.CutShort))
+	}
 	MustFprintf(w, "\n")
 }
 
@@ -349,10 +565,35 @@ func isReferenceLayout(name string) bool {
 		!strings.Contains(name, "-opt")
 }
 
+// loadReferenceAnalysers loads the analysers of the layouts in the reference set,
+// using the default reference set if reference is nil. Returns an error if the
+// set matches no layouts, since no normalisation statistics can be computed.
+func loadReferenceAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads,
+	reference *ReferenceSet) ([]*Analyser, error) {
+	if reference == nil {
+		reference = DefaultReferenceSet()
+	}
+	analysers, err := LoadAnalysers(layoutsDir, corpus, targets, reference)
+	if err != nil {
+		return nil, fmt.Errorf("could not load analysers: %w", err)
+	}
+	if len(analysers) == 0 {
+		return nil, fmt.Errorf("reference set %s matches no layouts in %s", reference, layoutsDir)
+	}
+	return analysers, nil
+}
+
 // LoadAnalysers loads and analyses .klf layout files from a directory in parallel.
-// When referenceOnly is true, excludes files that start with "_" or contain "-flipped", "-best", or "-opt".
+// When reference is non-nil, only layouts in the reference set are loaded.
 // Uses bounded concurrency based on GOMAXPROCS to avoid overloading the system.
-func LoadAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads, referenceOnly bool) ([]*Analyser, error) {
+func LoadAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads, reference *ReferenceSet) ([]*Analyser, error) {
+	return loadAnalysers(layoutsDir, corpus, targets, reference, nil)
+}
+
+// loadAnalysers is LoadAnalysers with the metrics of the layouts taken from, and added to, a
+// metrics cache (nil = no cache).
+func loadAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads, reference *ReferenceSet,
+	cache *MetricsCache) ([]*Analyser, error) {
 	layoutFiles, err := os.ReadDir(layoutsDir)
 	if err != nil {
 		return nil, fmt.Errorf("error reading layout files from %v: %w", layoutsDir, err)
@@ -374,7 +615,7 @@ func LoadAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads, refe
 		layoutName := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
 
 		// Skip non-reference layouts when filtering
-		if referenceOnly && !isReferenceLayout(layoutName) {
+		if reference != nil && !reference.Contains(layoutName) {
 			continue
 		}
 
@@ -390,7 +631,7 @@ func LoadAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads, refe
 				errs <- fmt.Errorf("could not load layout from file %s: %w", layoutPath, err)
 				return
 			}
-			analyser := NewAnalyser(layout, corpus, targets)
+			analyser := cache.NewAnalyser(layout, corpus, targets)
 
 			mu.Lock()
 			analysers = append(analysers, analyser)
@@ -415,13 +656,12 @@ func LoadAnalysers(layoutsDir string, corpus *Corpus, targets *TargetLoads, refe
 
 // computeMediansAndIQR computes median and interquartile range (IQR) for each metric
 // across all analysers. These values are used for robust normalization of layout scores.
-// When referenceOnly is true, only uses reference layouts for normalization, excluding
-// layouts that start with "_" or contain "-flipped", "-best", or "-opt" in their name.
-func computeMediansAndIQR(analysers []*Analyser, referenceOnly bool) (map[string]float64, map[string]float64) {
+// When reference is non-nil, only layouts in the reference set are used for normalization.
+func computeMediansAndIQR(analysers []*Analyser, reference *ReferenceSet) (map[string]float64, map[string]float64) {
 	metrics := make(map[string][]float64)
 	for _, analyser := range analysers {
 		// Skip non-reference layouts for normalization statistics
-		if referenceOnly && !isReferenceLayout(analyser.Layout.Name) {
+		if reference != nil && !reference.Contains(analyser.Layout.Name) {
 			continue
 		}
 
@@ -264,7 +264,7 @@ func createTestScorer() *Scorer {
 			"SFB": -1.0,
 			"LSB": -0.5,
 		},
-		scoreCache: make(map[string]float64, 100),
+		scoreCache: newScoreCache(0),
 	}
 }
 
@@ -283,7 +283,7 @@ func TestScoreCache(t *testing.T) {
 
 	// Verify it was cached
 	cacheKey := layoutCacheKey(layout)
-	cachedScore, exists := scorer.scoreCache[cacheKey]
+	cachedScore, exists := scorer.scoreCache.get(cacheKey)
 	if !exists {
 		t.Error("Score was not cached after first call")
 	}
@@ -331,8 +331,8 @@ func TestScoreCacheUniqueness(t *testing.T) {
 	score3 := scorer.Score(layout3)
 
 	// Verify all three are cached
-	if len(scorer.scoreCache) != 3 {
-		t.Errorf("Expected 3 cache entries, got %d", len(scorer.scoreCache))
+	if n, _, _ := scorer.scoreCache.stats(); n != 3 {
+		t.Errorf("Expected 3 cache entries, got %d", n)
 	}
 
 	// Verify they produce different scores (since they have different configurations)
@@ -341,13 +341,13 @@ func TestScoreCacheUniqueness(t *testing.T) {
 	key2 := layoutCacheKey(layout2)
 	key3 := layoutCacheKey(layout3)
 
-	if scorer.scoreCache[key1] != score1 {
+	if cached, _ := scorer.scoreCache.get(key1); cached != score1 {
 		t.Error("Layout 1 score not properly cached")
 	}
-	if scorer.scoreCache[key2] != score2 {
+	if cached, _ := scorer.scoreCache.get(key2); cached != score2 {
 		t.Error("Layout 2 score not properly cached")
 	}
-	if scorer.scoreCache[key3] != score3 {
+	if cached, _ := scorer.scoreCache.get(key3); cached != score3 {
 		t.Error("Layout 3 score not properly cached")
 	}
 }
@@ -377,8 +377,70 @@ func TestScoreCacheIgnoresName(t *testing.T) {
 	}
 
 	// Should only have one cache entry
-	if len(scorer.scoreCache) != 1 {
-		t.Errorf("Expected 1 cache entry for identical configurations, got %d", len(scorer.scoreCache))
+	if n, _, _ := scorer.scoreCache.stats(); n != 1 {
+		t.Errorf("Expected 1 cache entry for identical configurations, got %d", n)
+	}
+}
+
+// TestScoreCacheEviction verifies the cache respects its size cap and evicts the
+// least recently used layout first.
+func TestScoreCacheEviction(t *testing.T) {
+	c := newScoreCache(2)
+	c.put("a", 1)
+	c.put("b", 2)
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.put("c", 3)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if v, ok := c.get("c"); !ok || v != 3 {
+		t.Errorf("get(c) = %v, %v; want 3, true", v, ok)
+	}
+
+	size, capacity, evictions := c.stats()
+	if size != 2 || capacity != 2 || evictions != 1 {
+		t.Errorf("stats() = %d, %d, %d; want 2, 2, 1", size, capacity, evictions)
+	}
+
+	// Shrinking the cache evicts immediately
+	c.setCapacity(1)
+	if size, _, evictions := c.stats(); size != 1 || evictions != 2 {
+		t.Errorf("after setCapacity(1): size=%d evictions=%d; want 1, 2", size, evictions)
+	}
+}
+
+// TestScorerStatsEvictions verifies evictions are reported through GetStats.
+func TestScorerStatsEvictions(t *testing.T) {
+	scorer := createTestScorer()
+	scorer.SetCacheSize(1)
+
+	layout := &SplitLayout{
+		Name:       "test",
+		LayoutType: ROWSTAG,
+		Runes:      [42]rune{'q', 'w', 'e', 'r', 't', 'y', 'u', 'i', 'o', 'p', 'a', 's', 'd', 'f', 'g', 'h', 'j', 'k', 'l', ';', 'z', 'x', 'c', 'v', 'b', 'n', 'm', ',', '.', '/', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '},
+	}
+	other := layout.Clone()
+	other.LayoutType = ORTHO
+
+	scorer.Score(layout)
+	scorer.Score(other)
+	scorer.Score(layout) // evicted by other, so this is a miss
+
+	stats := scorer.GetStats()
+	if stats.UniqueLayouts != 1 || stats.CacheCapacity != 1 || stats.CacheEvictions != 2 {
+		t.Errorf("got UniqueLayouts=%d CacheCapacity=%d CacheEvictions=%d; want 1, 1, 2",
+			stats.UniqueLayouts, stats.CacheCapacity, stats.CacheEvictions)
+	}
+	if stats.CacheMisses != 3 {
+		t.Errorf("expected 3 cache misses, got %d", stats.CacheMisses)
 	}
 }
 
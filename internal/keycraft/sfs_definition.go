@@ -0,0 +1,107 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SFSDefinition selects which same-finger skipgrams SFS counts. Analysers differ in
+// whether the key in between matters, which changes SFS considerably, so comparing SFS
+// with another analyser requires the same definition.
+type SFSDefinition string
+
+const (
+	SFSAll         SFSDefinition = "all"         // Every skipgram, whatever is typed in between (default)
+	SFSAlternating SFSDefinition = "alternating" // Only skipgrams with a key of the other hand in between
+	SFSSameHand    SFSDefinition = "same-hand"   // Only skipgrams with a key of the same hand in between
+)
+
+// SFSDefinitions lists the definitions of SFS.
+var SFSDefinitions = []SFSDefinition{SFSAll, SFSAlternating, SFSSameHand}
+
+// ParseSFSDefinition parses the name of an SFS definition, or "alt" for SFSAlternating.
+func ParseSFSDefinition(name string) (SFSDefinition, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "alt" {
+		return SFSAlternating, nil
+	}
+	for _, def := range SFSDefinitions {
+		if name == string(def) {
+			return def, nil
+		}
+	}
+	return "", fmt.Errorf("invalid SFS definition %q: must be one of: all, alternating, same-hand", name)
+}
+
+// sfsDefinition returns the definition of SFS computed by the analyser.
+func (an *Analyser) sfsDefinition() SFSDefinition {
+	if an.Targets == nil || an.Targets.SFSDefinition == "" {
+		return SFSAll
+	}
+	return an.Targets.SFSDefinition
+}
+
+// bridgedSFS reports whether SFS is counted from the trigrams, as its definition depends on
+// the key in between. The skipgrams of the corpus don't keep the character in between.
+func (an *Analyser) bridgedSFS() bool {
+	return an.sfsDefinition() != SFSAll
+}
+
+// isBridgedSFS reports whether a trigram typed with the given keys is a same-finger
+// skipgram that counts in SFS: the first and last key are typed by one finger, and the key
+// in between by the hand that the definition requires.
+func (an *Analyser) isBridgedSFS(k0, k1, k2 KeyInfo) bool {
+	if k0.Finger != k2.Finger || (k0.Index == k2.Index && !an.countsRepeats()) {
+		return false
+	}
+	if an.sfsDefinition() == SFSAlternating {
+		return k1.Hand != k0.Hand
+	}
+	return k1.Hand == k0.Hand
+}
+
+// countBridgedSFS counts the trigrams that are same-finger skipgrams of the SFS definition.
+func (an *Analyser) countBridgedSFS() uint64 {
+	var count uint64
+	for _, ti := range an.trigrams() {
+		k0, _ := an.Layout.GetKeyInfo(ti.Runes[0])
+		k1, _ := an.Layout.GetKeyInfo(ti.Runes[1])
+		k2, _ := an.Layout.GetKeyInfo(ti.Runes[2])
+		if an.isBridgedSFS(k0, k1, k2) {
+			count += ti.Count
+		}
+	}
+	return count
+}
+
+// bridgedSFSDetails adds the same-finger skipgrams of the SFS definition to the details of
+// SFS, counted from the trigrams of the corpus.
+func (an *Analyser) bridgedSFSDetails(ma *MetricDetails) {
+	ma.CorpusNGramC = an.Corpus.TotalTrigramsCount
+	for tri, cnt := range an.Corpus.Trigrams {
+		k0, ok0 := an.Layout.GetKeyInfo(tri[0])
+		k1, ok1 := an.Layout.GetKeyInfo(tri[1])
+		k2, ok2 := an.Layout.GetKeyInfo(tri[2])
+		skpStr := Skipgram{tri[0], tri[2]}.String()
+		if !ok0 || !ok1 || !ok2 {
+			ma.Unsupported[skpStr] += cnt
+			continue
+		}
+		if !an.isBridgedSFS(k0, k1, k2) {
+			continue
+		}
+
+		ma.NGramCount[skpStr] += cnt
+		ma.TotalNGrams += cnt
+		kpDist := an.sameFingerDistance(k0.Index, k2.Index)
+		ma.NGramDist[skpStr] = kpDist.Distance
+		ma.TotalDist += kpDist.Distance * float64(cnt)
+
+		if _, ok := ma.Custom[skpStr]; !ok {
+			ma.Custom[skpStr] = make(map[string]any)
+		}
+		ma.Custom[skpStr]["Hd"] = k0.Hand + 1
+		ma.Custom[skpStr]["Fgr"] = k0.Finger + 1
+		ma.Custom[skpStr]["Δrow"] = kpDist.RowDist
+	}
+}
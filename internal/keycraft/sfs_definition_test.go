@@ -0,0 +1,72 @@
+package keycraft
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestAnalyser_SFSDefinition(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	layout := Must(writeShiftedLayout(t, ""))
+	all := NewAnalyser(layout, corpus, nil)
+
+	analyse := func(definition SFSDefinition) *Analyser {
+		targets := NewTargetLoads()
+		targets.SFSDefinition = definition
+		return NewAnalyser(layout.Clone(), corpus, targets)
+	}
+	alternating, sameHand := analyse(SFSAlternating), analyse(SFSSameHand)
+	if alternating.Metrics["SFS"] == 0 || sameHand.Metrics["SFS"] == 0 {
+		t.Fatalf("test corpus should have both kinds of SFS, got %v and %v",
+			alternating.Metrics["SFS"], sameHand.Metrics["SFS"])
+	}
+	// Every trigram has a skipgram, so both kinds add up to all same-finger skipgrams of
+	// the trigrams with all characters on the layout
+	if got, max := alternating.Metrics["SFS"]+sameHand.Metrics["SFS"], all.Metrics["SFS"]; got > max+1e-9 {
+		t.Errorf("alternating and same-hand SFS add up to %v, more than SFS %v", got, max)
+	}
+	if alternating.Metrics["SFB"] != all.Metrics["SFB"] || alternating.Metrics["LSS"] != all.Metrics["LSS"] {
+		t.Error("the SFS definition should only change SFS")
+	}
+
+	// 'o' and 'l' are typed by the right ring finger, with 'v' of the other hand or 'k' of
+	// the same hand in between
+	key := func(r rune) KeyInfo {
+		ki, _ := layout.GetKeyInfo(r)
+		return ki
+	}
+	trigram := func(r0, r1, r2 rune) (KeyInfo, KeyInfo, KeyInfo) {
+		return key(r0), key(r1), key(r2)
+	}
+	if !alternating.isBridgedSFS(trigram('o', 'v', 'l')) || alternating.isBridgedSFS(trigram('o', 'k', 'l')) {
+		t.Error("alternating SFS should only count a key of the other hand in between")
+	}
+	if !sameHand.isBridgedSFS(trigram('o', 'k', 'l')) || sameHand.isBridgedSFS(trigram('o', 'v', 'l')) {
+		t.Error("same-hand SFS should only count a key of the same hand in between")
+	}
+
+	// The details agree with the metric
+	details := alternating.SFSkpDetails()
+	if got := 100 * float64(details.TotalNGrams) / float64(details.CorpusNGramC); math.Abs(got-alternating.Metrics["SFS"]) > 1e-9 {
+		t.Errorf("alternating SFS details total = %v, want %v", got, alternating.Metrics["SFS"])
+	}
+
+	// Swapping keys keeps the definition
+	keys := usedKeys(alternating.Layout)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 30 {
+		alternating.ApplySwap(keys[rng.IntN(len(keys))], keys[rng.IntN(len(keys))])
+		assertSameMetrics(t, alternating, NewAnalyser(alternating.Layout.Clone(), corpus, alternating.Targets))
+	}
+
+	for _, name := range []string{"all", "Alternating", "alt", "same-hand"} {
+		if _, err := ParseSFSDefinition(name); err != nil {
+			t.Errorf("ParseSFSDefinition(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := ParseSFSDefinition("oxey"); err == nil {
+		t.Error("expected error for an unknown SFS definition")
+	}
+}
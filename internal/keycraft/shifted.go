@@ -0,0 +1,255 @@
+package keycraft
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// USShiftPairs lists the shifted symbols of a US keymap, each as a base key followed by
+// the character typed with Shift on that key.
+var USShiftPairs = []string{
+	"`~", "1!", "2@", "3#", "4$", "5%", "6^", "7&", "8*", "9(", "0)", "-_", "=+",
+	"[{", "]}", "\\|", ";:", "'\"", ",<", ".>", "/?",
+}
+
+// SetShiftedFromString defines the characters typed with Shift from a space-separated
+// list of pairs, each a base key followed by its shifted character (e.g. ";: '\" /?").
+// The word "us" adds the pairs of USShiftPairs whose base key is on the layout and
+// whose shifted character is not. A shifted character takes no key of its own: it is
+// analysed as its base key, while Shift is held by the layout's ShiftHolder.
+func (sl *SplitLayout) SetShiftedFromString(s string) error {
+	shifted := make(map[rune]rune)
+	add := func(pair string, strict bool) error {
+		runes := []rune(pair)
+		if len(runes) != 2 {
+			return fmt.Errorf("shifted pair %q must be a base key followed by its shifted character", pair)
+		}
+		base, shift := runes[0], runes[1]
+		_, baseOK := sl.RuneInfo[base]
+		_, shiftOnLayout := sl.RuneInfo[shift]
+		_, seen := shifted[shift]
+		switch {
+		case !strict && (!baseOK || shiftOnLayout || seen):
+			return nil
+		case !baseOK:
+			return fmt.Errorf("base key %q of shifted pair %q is not on the layout", base, pair)
+		case shiftOnLayout:
+			return fmt.Errorf("shifted character %q of pair %q already has its own key", shift, pair)
+		case seen:
+			return fmt.Errorf("shifted character %q is defined more than once", shift)
+		}
+		shifted[shift] = base
+		return nil
+	}
+
+	for token := range strings.FieldsSeq(s) {
+		if strings.ToLower(token) == "us" {
+			for _, pair := range USShiftPairs {
+				_ = add(pair, false)
+			}
+			continue
+		}
+		if err := add(token, true); err != nil {
+			return err
+		}
+	}
+
+	sl.setShifted(shifted)
+	return nil
+}
+
+// setShifted sets the shifted characters (shifted rune -> base rune), or clears them if
+// the map is empty.
+func (sl *SplitLayout) setShifted(shifted map[rune]rune) {
+	if len(shifted) == 0 {
+		sl.Shifted, sl.shiftKey = nil, ""
+		return
+	}
+	sl.Shifted = shifted
+	sl.shiftKey = sl.ShiftedString()
+}
+
+// ShiftedString returns the shifted characters in the format of SetShiftedFromString,
+// ordered by base key.
+func (sl *SplitLayout) ShiftedString() string {
+	pairs := make([]string, 0, len(sl.Shifted))
+	for shift, base := range sl.Shifted {
+		pairs = append(pairs, string([]rune{base, shift}))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// baseRune returns the rune of the key that types r: the base key of a shifted character,
+// the lowercase letter of an uppercase letter without a key of its own, or r itself.
+func (sl *SplitLayout) baseRune(r rune) rune {
+	if base, ok := sl.Shifted[r]; ok {
+		return base
+	}
+	if _, ok := sl.RuneInfo[r]; ok {
+		return r
+	}
+	return unicode.ToLower(r)
+}
+
+// isShifted reports whether r is typed with Shift: a shifted character, or an uppercase
+// letter of a cased corpus if the layout declares shifted characters. Layouts without them
+// type uppercase letters as their lowercase letters, as in a case-folded corpus.
+func (sl *SplitLayout) isShifted(r rune) bool {
+	if sl.Shifted == nil {
+		return false
+	}
+	if _, ok := sl.Shifted[r]; ok {
+		return true
+	}
+	_, ok := sl.RuneInfo[r]
+	return !ok && unicode.ToLower(r) != r
+}
+
+// shiftFold is a corpus with the shifted characters of a layout folded into their base
+// keys, together with the original n-grams needed for the shift metrics.
+type shiftFold struct {
+	corpus   *Corpus       // folded corpus, used for all other metrics
+	unigrams uint64        // occurrences of shifted characters in the original corpus
+	bigrams  []shiftBigram // original bigrams with at least one shifted character
+}
+
+// shiftBigram is a bigram of the original corpus with at least one shifted character.
+type shiftBigram struct {
+	runes Bigram
+	count uint64
+}
+
+// shiftFoldKey identifies a folded corpus.
+type shiftFoldKey struct {
+	corpus  *Corpus
+	shifted string
+}
+
+// shiftFolds caches folded corpora, as all layouts with the same shifted characters share
+// one, including all layouts scored during an optimisation.
+var shiftFolds sync.Map // shiftFoldKey -> *shiftFold
+
+// foldShifted returns the corpus with the layout's shifted characters, and the uppercase
+// letters of a cased corpus, replaced by their base keys. N-gram totals are unchanged.
+func (c *Corpus) foldShifted(sl *SplitLayout) *shiftFold {
+	key := shiftFoldKey{c, sl.shiftKey}
+	if fold, ok := shiftFolds.Load(key); ok {
+		return fold.(*shiftFold)
+	}
+
+	base := sl.baseRune
+	foldBigrams := func(src map[Bigram]uint64) map[Bigram]uint64 {
+		if src == nil {
+			return nil
+		}
+		dst := make(map[Bigram]uint64, len(src))
+		for bi, cnt := range src {
+			dst[Bigram{base(bi[0]), base(bi[1])}] += cnt
+		}
+		return dst
+	}
+
+	folded := &Corpus{
+		Name:                c.Name,
+		Unigrams:            make(map[Unigram]uint64, len(c.Unigrams)),
+		TotalUnigramsCount:  c.TotalUnigramsCount,
+		Bigrams:             foldBigrams(c.Bigrams),
+		TotalBigramsCount:   c.TotalBigramsCount,
+		Trigrams:            make(map[Trigram]uint64, len(c.Trigrams)),
+		TotalTrigramsCount:  c.TotalTrigramsCount,
+		Skipgrams:           make(map[Skipgram]uint64, len(c.Skipgrams)),
+		TotalSkipgramsCount: c.TotalSkipgramsCount,
+		Words:               c.Words,
+		TotalWordsCount:     c.TotalWordsCount,
+		WordInitialBigrams:  foldBigrams(c.WordInitialBigrams),
+		WordFinalBigrams:    foldBigrams(c.WordFinalBigrams),
+		Stream:              c.Stream, // Shifted runes are mapped by GetKeyInfo
+	}
+	fold := &shiftFold{corpus: folded}
+	for uni, cnt := range c.Unigrams {
+		r := rune(uni)
+		if sl.isShifted(r) {
+			fold.unigrams += cnt
+		}
+		folded.Unigrams[Unigram(base(r))] += cnt
+	}
+	for tri, cnt := range c.Trigrams {
+		folded.Trigrams[Trigram{base(tri[0]), base(tri[1]), base(tri[2])}] += cnt
+	}
+	for skp, cnt := range c.Skipgrams {
+		folded.Skipgrams[Skipgram{base(skp[0]), base(skp[1])}] += cnt
+	}
+	for bi, cnt := range c.Bigrams {
+		if sl.isShifted(bi[0]) || sl.isShifted(bi[1]) {
+			fold.bigrams = append(fold.bigrams, shiftBigram{bi, cnt})
+		}
+	}
+
+	actual, _ := shiftFolds.LoadOrStore(key, fold)
+	return actual.(*shiftFold)
+}
+
+// forgetShiftFolds drops the folded corpora of a corpus that is no longer used, such as a
+// discarded corpus sample.
+func forgetShiftFolds(c *Corpus) {
+	shiftFolds.Range(func(key, _ any) bool {
+		if key.(shiftFoldKey).corpus == c {
+			shiftFolds.Delete(key)
+		}
+		return true
+	})
+}
+
+// useShiftedCorpus switches the analyser to the corpus with the layout's shifted
+// characters, and the uppercase letters of a cased corpus, folded into their base keys,
+// keeping the fold for the shift metrics. It must be called before the metrics are computed.
+func (an *Analyser) useShiftedCorpus() {
+	if an.Layout.Shifted == nil && !an.Corpus.Cased {
+		return
+	}
+	an.shift = an.Corpus.foldShifted(an.Layout)
+	an.Corpus = an.shift.corpus
+}
+
+// analyseShift computes the shift metrics from the original corpus n-grams:
+//   - SHIFT: % of characters typed with Shift
+//   - SHIFT-SF: % of bigrams in which the finger holding Shift for one character also
+//     presses the other key (e.g. 'a' then ':' with Shift on the left pinky)
+//
+// Pressing two shifted characters while holding Shift with the same finger is not a conflict.
+func (an *Analyser) analyseShift() {
+	fold := an.shift
+	an.Metrics["SHIFT"] = 0
+	an.Metrics["SHIFT-SF"] = 0
+	if fold == nil {
+		return
+	}
+
+	holder := an.Layout.ShiftHolder
+	var conflicts uint64
+	for _, sb := range fold.bigrams {
+		k0, ok0 := an.Layout.GetKeyInfo(sb.runes[0])
+		k1, ok1 := an.Layout.GetKeyInfo(sb.runes[1])
+		if !ok0 || !ok1 {
+			continue
+		}
+		shift0, shift1 := an.Layout.isShifted(sb.runes[0]), an.Layout.isShifted(sb.runes[1])
+		h0, h1 := holder.finger(k0.Hand), holder.finger(k1.Hand)
+		held := shift0 && shift1 && h0 == h1 // Shift stays down for both keys
+
+		if !held && ((shift1 && k0.Finger == h1) || (shift0 && k1.Finger == h0)) {
+			conflicts += sb.count
+		}
+	}
+
+	if an.Corpus.TotalUnigramsCount > 0 {
+		an.Metrics["SHIFT"] = 100 * float64(fold.unigrams) / float64(an.Corpus.TotalUnigramsCount)
+	}
+	if an.Corpus.TotalBigramsCount > 0 {
+		an.Metrics["SHIFT-SF"] = 100 * float64(conflicts) / float64(an.Corpus.TotalBigramsCount)
+	}
+}
@@ -0,0 +1,185 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeShiftedLayout writes testLayoutKlf with extra setting lines and loads it.
+func writeShiftedLayout(t *testing.T, settings string) (*SplitLayout, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "a.klf")
+	if err := os.WriteFile(path, []byte(testLayoutKlf+settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewLayoutFromFile("a", path)
+}
+
+func TestNewLayoutFromFile_Shifted(t *testing.T) {
+	sl := Must(writeShiftedLayout(t, "\nshift: us\nshift-finger: left-thumb\n"))
+
+	for shifted, base := range sl.Shifted {
+		if _, ok := sl.RuneInfo[shifted]; ok {
+			t.Errorf("shifted %q is on the layout", shifted)
+		}
+		if _, ok := sl.RuneInfo[base]; !ok {
+			t.Errorf("base %q of %q is not on the layout", base, shifted)
+		}
+	}
+	if sl.ShiftHolder != HoldLeftThumb {
+		t.Errorf("shift holder = %v, want left-thumb", sl.ShiftHolder)
+	}
+
+	// Saving and loading keeps the shifted characters and the shift finger
+	path := filepath.Join(t.TempDir(), "saved.klf")
+	if err := sl.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := Must(NewLayoutFromFile("saved", path))
+	if loaded.ShiftedString() != sl.ShiftedString() || loaded.ShiftHolder != HoldLeftThumb {
+		t.Errorf("reloaded shift %q (%v), want %q (left-thumb)",
+			loaded.ShiftedString(), loaded.ShiftHolder, sl.ShiftedString())
+	}
+	if layoutCacheKey(loaded) == layoutCacheKey(Must(writeShiftedLayout(t, ""))) {
+		t.Error("cache key should depend on the shifted characters")
+	}
+}
+
+func TestNewLayoutFromFile_ShiftedErrors(t *testing.T) {
+	sl := Must(writeShiftedLayout(t, ""))
+	base := sl.Runes[13]
+	other := sl.Runes[14]
+
+	tests := []struct {
+		name, settings, want string
+	}{
+		{"not a pair", "\nshift: abc\n", "must be a base key"},
+		{"missing base", "\nshift: éÉ\n", "not on the layout"},
+		{"shifted on layout", "\nshift: " + string([]rune{base, other}) + "\n", "already has its own key"},
+		{"invalid finger", "\nshift-finger: left-index\n", "invalid shift finger"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := writeShiftedLayout(t, tt.settings)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyser_Shifted(t *testing.T) {
+	var runes [42]rune
+	runes[13], runes[19], runes[22], runes[37] = 'a', 'j', ';', ' '
+	sl := NewSplitLayout("test", ORTHO, runes)
+	if err := sl.SetShiftedFromString(";:"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ki, ok := sl.GetKeyInfo(':'); !ok || ki.Index != 22 {
+		t.Errorf("':' maps to %+v (%v), want key 22", ki, ok)
+	}
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("a: j:")
+	an := NewAnalyser(sl, corpus, nil)
+
+	// ':' occurs twice
+	if got, want := an.Metrics["SHIFT"], 200.0/float64(corpus.TotalUnigramsCount); got != want {
+		t.Errorf("SHIFT = %v, want %v", got, want)
+	}
+	// Shift for ':' is held by the left pinky, which also types 'a' in "a:"
+	if got, want := an.Metrics["SHIFT-SF"], 100.0/float64(corpus.TotalBigramsCount); got != want {
+		t.Errorf("SHIFT-SF = %v, want %v", got, want)
+	}
+	// ':' is analysed as ';', so no unigrams are lost
+	if got := an.Corpus.Unigrams[';']; got != 2 {
+		t.Errorf("folded ';' count = %d, want 2", got)
+	}
+	if corpus.Unigrams[';'] != 0 {
+		t.Error("original corpus should not be modified")
+	}
+
+	plain := NewAnalyser(NewSplitLayout("test", ORTHO, runes), corpus, nil)
+	if plain.Metrics["SHIFT"] != 0 || plain.Metrics["SHIFT-SF"] != 0 {
+		t.Errorf("layout without shifted characters has shift metrics %v, %v",
+			plain.Metrics["SHIFT"], plain.Metrics["SHIFT-SF"])
+	}
+}
+
+func TestAnalyser_CasedCorpus(t *testing.T) {
+	var runes [42]rune
+	runes[13], runes[19], runes[22], runes[37] = 'a', 'j', ';', ' '
+	cased := NewCorpus("cased")
+	cased.Cased = true
+	cased.addTextWithWords("Ja ja aJ")
+	lower := NewCorpus("lower")
+	lower.addTextWithWords("Ja ja aJ")
+
+	if cased.Unigrams['J'] != 2 || cased.Words["ja"] != 2 {
+		t.Errorf("cased corpus has %d 'J' and %d \"ja\", want 2 and 2", cased.Unigrams['J'], cased.Words["ja"])
+	}
+
+	// Without shifted characters, uppercase letters are typed as their lowercase letters
+	plain := NewSplitLayout("test", ORTHO, runes)
+	if ki, ok := plain.GetKeyInfo('J'); !ok || ki.Index != 19 {
+		t.Errorf("'J' maps to %+v (%v), want key 19", ki, ok)
+	}
+	an := NewAnalyser(plain, cased, nil)
+	assertSameMetrics(t, an, NewAnalyser(plain, lower, nil))
+	if an.Metrics["SHIFT"] != 0 {
+		t.Errorf("SHIFT = %v without shifted characters, want 0", an.Metrics["SHIFT"])
+	}
+
+	// With shifted characters, uppercase letters are typed with Shift
+	sl := NewSplitLayout("test", ORTHO, runes)
+	if err := sl.SetShiftedFromString(";:"); err != nil {
+		t.Fatal(err)
+	}
+	an = NewAnalyser(sl, cased, nil)
+	if got, want := an.Metrics["SHIFT"], 200.0/float64(cased.TotalUnigramsCount); got != want {
+		t.Errorf("SHIFT = %v, want %v", got, want)
+	}
+	// Shift for 'J' is held by the left pinky, which also types 'a' in "Ja" and "aJ"
+	if got, want := an.Metrics["SHIFT-SF"], 200.0/float64(cased.TotalBigramsCount); got != want {
+		t.Errorf("SHIFT-SF = %v, want %v", got, want)
+	}
+	if got, want := an.Metrics["SFB"], NewAnalyser(sl, lower, nil).Metrics["SFB"]; got != want {
+		t.Errorf("SFB = %v, want %v as in the lowercased corpus", got, want)
+	}
+}
+
+func TestNewCorpusFromFileWith_Cased(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte("The Lorem ipsum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := CorpusBuildOptions{Rebuild: true, Coverage: 100, Excluded: map[string]bool{"lorem": true}, Cased: true}
+	corpus := Must(NewCorpusFromFileWith("corpus", path, opts))
+	if !corpus.Cased || corpus.Unigrams['T'] != 1 || corpus.Unigrams['L'] != 0 || corpus.Words["the"] != 1 {
+		t.Errorf("cased corpus = %v, %v", corpus.Cased, corpus.Unigrams)
+	}
+
+	// The cache of the build keeps the case, and the default build is lowercased
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	opts.Rebuild = false
+	cached := Must(NewCorpusFromFileWith("corpus", path, opts))
+	if !cached.Cased || cached.Unigrams['T'] != 1 {
+		t.Errorf("cached corpus = %v, %v", cached.Cased, cached.Unigrams)
+	}
+	if plain := Must(NewCorpusFromFile("corpus", path, false, 100)); plain.Cased || plain.Unigrams['T'] != 0 {
+		t.Errorf("default corpus = %v, %v", plain.Cased, plain.Unigrams)
+	}
+
+	// A cased corpus in the cache of the default build, as older versions wrote it, is rebuilt
+	Must0(cached.SaveJSON(path + ".json"))
+	if plain := Must(NewCorpusFromFile("corpus", path, false, 100)); plain.Cased {
+		t.Error("the cased cache of the default build was used")
+	}
+}
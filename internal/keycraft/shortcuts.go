@@ -0,0 +1,382 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ModifierHolder names the finger that holds down a modifier key during a chord.
+// Modifiers are not part of a layout, so their position is configured per shortcut set.
+type ModifierHolder uint8
+
+const (
+	HoldLeftPinky     ModifierHolder = iota // Left pinky, e.g. Ctrl on a standard keyboard
+	HoldRightPinky                          // Right pinky
+	HoldLeftThumb                           // Left thumb, e.g. Alt or a thumb-cluster modifier
+	HoldRightThumb                          // Right thumb
+	HoldOppositePinky                       // Pinky of the hand not pressing the key, e.g. Shift
+	HoldOppositeThumb                       // Thumb of the hand not pressing the key
+)
+
+var modifierHolderNames = []string{
+	"left-pinky", "right-pinky", "left-thumb", "right-thumb", "opposite-pinky", "opposite-thumb",
+}
+
+// String returns the name of the holder as used in shortcut files.
+func (h ModifierHolder) String() string {
+	if int(h) < len(modifierHolderNames) {
+		return modifierHolderNames[h]
+	}
+	return fmt.Sprintf("ModifierHolder(%d)", h)
+}
+
+// finger returns the finger holding the modifier while a key is pressed with keyHand.
+func (h ModifierHolder) finger(keyHand uint8) uint8 {
+	switch h {
+	case HoldLeftPinky:
+		return LP
+	case HoldRightPinky:
+		return RP
+	case HoldLeftThumb:
+		return LT
+	case HoldRightThumb:
+		return RT
+	case HoldOppositePinky:
+		return IfThen(keyHand == LEFT, RP, LP)
+	default:
+		return IfThen(keyHand == LEFT, RT, LT)
+	}
+}
+
+// modifierAliases maps alternative modifier names to the canonical ones.
+var modifierAliases = map[string]string{
+	"control": "ctrl",
+	"option":  "alt",
+	"opt":     "alt",
+	"cmd":     "meta",
+	"super":   "meta",
+	"win":     "meta",
+}
+
+// DefaultModifierHolders returns the holders of a standard keyboard: Ctrl with the left
+// pinky, Shift with the opposite pinky, and Alt and Meta with the left thumb.
+func DefaultModifierHolders() map[string]ModifierHolder {
+	return map[string]ModifierHolder{
+		"ctrl":  HoldLeftPinky,
+		"shift": HoldOppositePinky,
+		"alt":   HoldLeftThumb,
+		"meta":  HoldLeftThumb,
+	}
+}
+
+// KeyEvent is a single key press, optionally chorded with modifiers held down at the
+// same time.
+type KeyEvent struct {
+	Key       rune
+	Modifiers []string // canonical modifier names, e.g. "ctrl"
+}
+
+// Shortcut is a frequently used command sequence of one or more key events.
+type Shortcut struct {
+	Text   string  // the sequence as written in the shortcut file
+	Count  float64 // relative frequency of the shortcut
+	Events []KeyEvent
+}
+
+// ShortcutSet is a collection of editor/IDE shortcuts with the fingers holding the
+// modifiers.
+type ShortcutSet struct {
+	Name      string
+	Holders   map[string]ModifierHolder
+	Shortcuts []Shortcut
+}
+
+// NewShortcutSetFromFile loads a shortcut set. Each line holds a count and a sequence of
+// key events separated by spaces; modifier fingers can be changed with "modifier" lines:
+//
+//	modifier ctrl = left-thumb # Ctrl on a thumb key
+//	100 ctrl+c                 # a chord of a modifier and a key
+//	40 ctrl+shift+t
+//	30 dd                      # keys pressed one after another (Vim)
+//	10 ciw
+//
+// A token without '+' is a sequence of single key presses. Keys are matched case
+// insensitively, and "space" names the space bar. Empty lines and '#' comments are ignored.
+func NewShortcutSetFromFile(name, path string) (*ShortcutSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open shortcuts file: %w", err)
+	}
+	defer CloseFile(file)
+
+	set := &ShortcutSet{Name: name, Holders: DefaultModifierHolders()}
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "modifier "); ok {
+			if err := set.setHolder(rest); err != nil {
+				return nil, fmt.Errorf("line %d of %s: %w", lineNum, path, err)
+			}
+			continue
+		}
+
+		countStr, text, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d of %s: expected a count and a key sequence", lineNum, path)
+		}
+		count, err := strconv.ParseFloat(countStr, 64)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("line %d of %s: invalid count %q", lineNum, path, countStr)
+		}
+		text = strings.TrimSpace(text)
+		events, err := set.parseKeyEvents(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: %w", lineNum, path, err)
+		}
+		set.Shortcuts = append(set.Shortcuts, Shortcut{Text: text, Count: count, Events: events})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading shortcuts file: %w", err)
+	}
+	if len(set.Shortcuts) == 0 {
+		return nil, fmt.Errorf("shortcuts file %s contains no shortcuts", path)
+	}
+	return set, nil
+}
+
+// setHolder parses a "<modifier> = <holder>" setting.
+func (set *ShortcutSet) setHolder(s string) error {
+	mod, holderName, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("expected 'modifier <name> = <finger>', got %q", s)
+	}
+	mod = canonicalModifier(mod)
+	holderName = strings.ToLower(strings.TrimSpace(holderName))
+	holder := slices.Index(modifierHolderNames, holderName)
+	if holder < 0 {
+		return fmt.Errorf("invalid finger %q for modifier %s; must be one of: %s",
+			holderName, mod, strings.Join(modifierHolderNames, ", "))
+	}
+	set.Holders[mod] = ModifierHolder(holder)
+	return nil
+}
+
+// canonicalModifier returns the lower-case canonical name of a modifier.
+func canonicalModifier(mod string) string {
+	mod = strings.ToLower(strings.TrimSpace(mod))
+	if alias, ok := modifierAliases[mod]; ok {
+		return alias
+	}
+	return mod
+}
+
+// parseKeyEvents parses a key sequence such as "ctrl+shift+t" or "ciw".
+func (set *ShortcutSet) parseKeyEvents(text string) ([]KeyEvent, error) {
+	var events []KeyEvent
+	for token := range strings.FieldsSeq(text) {
+		if token == "+" || !strings.Contains(token, "+") {
+			if strings.ToLower(token) == "space" {
+				events = append(events, KeyEvent{Key: ' '})
+				continue
+			}
+			for _, r := range token {
+				events = append(events, KeyEvent{Key: unicode.ToLower(r)})
+			}
+			continue
+		}
+
+		parts := strings.Split(token, "+")
+		keyPart := parts[len(parts)-1]
+		mods := parts[:len(parts)-1]
+		if keyPart == "" && len(mods) > 0 && mods[len(mods)-1] == "" {
+			keyPart, mods = "+", mods[:len(mods)-1] // "ctrl++" chords the '+' key
+		}
+
+		var key rune
+		switch {
+		case strings.ToLower(keyPart) == "space":
+			key = ' '
+		case utf8.RuneCountInString(keyPart) == 1:
+			r, _ := utf8.DecodeRuneInString(keyPart)
+			key = unicode.ToLower(r)
+		default:
+			return nil, fmt.Errorf("invalid key %q in %q; a chord ends in a single key", keyPart, token)
+		}
+
+		event := KeyEvent{Key: key}
+		for _, mod := range mods {
+			mod = canonicalModifier(mod)
+			if _, ok := set.Holders[mod]; !ok {
+				return nil, fmt.Errorf("unknown modifier %q in %q", mod, token)
+			}
+			event.Modifiers = append(event.Modifiers, mod)
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	return events, nil
+}
+
+// fingerHomeKeys is the home position of each finger, from which key travel is measured.
+var fingerHomeKeys = [10]uint8{13, 14, 15, 16, 37, 40, 19, 20, 21, 22}
+
+// ShortcutResult describes how a layout types one shortcut.
+type ShortcutResult struct {
+	Shortcut         *Shortcut
+	Missing          []rune  // keys of the shortcut that are not on the layout
+	Cost             float64 // effort score, see AnalyseShortcuts
+	OneHand          bool    // all of two or more keys and modifiers are pressed with one hand
+	LeftHand         bool    // all of two or more keys and modifiers are pressed with the left hand
+	SameFingerChords int     // chords in which a finger presses a key while holding a modifier
+	SameFingerBigram int     // consecutive presses of different keys with the same finger
+	OffHome          int     // key presses outside the home row and thumb keys
+}
+
+// ShortcutAnalysis is the result of typing a shortcut set on a layout.
+type ShortcutAnalysis struct {
+	Layout  *SplitLayout
+	Results []ShortcutResult // in the order of the shortcut set
+
+	// Summary metrics, weighted by shortcut count:
+	// COST is the average effort, MISSING the % of shortcuts with keys not on the layout,
+	// ONE-HAND and LEFT-HAND the % of multi-key shortcuts typed with one (the left) hand, SF-CHORD
+	// the % of chords that need a finger twice, SFB the % of key transitions on the same
+	// finger, and OFF-HOME the % of key presses outside the home row. Shortcuts with
+	// missing keys are left out of all metrics but MISSING.
+	Metrics map[string]float64
+}
+
+// ShortcutMetrics lists the summary metrics of a ShortcutAnalysis in display order.
+var ShortcutMetrics = []string{"COST", "MISSING", "ONE-HAND", "LEFT-HAND", "SF-CHORD", "SFB", "OFF-HOME"}
+
+// AnalyseShortcuts types each shortcut of the set on the layout. The effort of a shortcut
+// is the distance of each key press from the home key of its finger, plus 1 for each
+// same-finger transition and 2 for each same-finger chord.
+func AnalyseShortcuts(layout *SplitLayout, set *ShortcutSet) *ShortcutAnalysis {
+	sa := &ShortcutAnalysis{
+		Layout:  layout,
+		Results: make([]ShortcutResult, len(set.Shortcuts)),
+		Metrics: make(map[string]float64, len(ShortcutMetrics)),
+	}
+
+	var total, typed, multi, cost, oneHand, leftHand float64
+	var chords, sfChords, transitions, sfbs, presses, offHome float64
+	for i := range set.Shortcuts {
+		sc := &set.Shortcuts[i]
+		res := sa.typeShortcut(sc, set.Holders)
+		sa.Results[i] = res
+
+		total += sc.Count
+		if len(res.Missing) > 0 {
+			continue
+		}
+		typed += sc.Count
+		if res.Presses() > 1 {
+			multi += sc.Count
+		}
+		cost += sc.Count * res.Cost
+		oneHand += sc.Count * float64(b2i(res.OneHand))
+		leftHand += sc.Count * float64(b2i(res.LeftHand))
+		sfChords += sc.Count * float64(res.SameFingerChords)
+		sfbs += sc.Count * float64(res.SameFingerBigram)
+		offHome += sc.Count * float64(res.OffHome)
+		presses += sc.Count * float64(len(sc.Events))
+		transitions += sc.Count * float64(len(sc.Events)-1)
+		for _, ev := range sc.Events {
+			if len(ev.Modifiers) > 0 {
+				chords += sc.Count
+			}
+		}
+	}
+
+	pct := func(n, d float64) float64 { return IfThen(d > 0, 100*n/d, 0) }
+	sa.Metrics["COST"] = IfThen(typed > 0, cost/typed, 0)
+	sa.Metrics["MISSING"] = pct(total-typed, total)
+	sa.Metrics["ONE-HAND"] = pct(oneHand, multi)
+	sa.Metrics["LEFT-HAND"] = pct(leftHand, multi)
+	sa.Metrics["SF-CHORD"] = pct(sfChords, chords)
+	sa.Metrics["SFB"] = pct(sfbs, transitions)
+	sa.Metrics["OFF-HOME"] = pct(offHome, presses)
+	return sa
+}
+
+// typeShortcut computes the result of a single shortcut on the layout.
+func (sa *ShortcutAnalysis) typeShortcut(sc *Shortcut, holders map[string]ModifierHolder) ShortcutResult {
+	res := ShortcutResult{Shortcut: sc}
+	var hands [2]bool
+	var prev *KeyInfo
+	var prevKey rune
+	for _, ev := range sc.Events {
+		ki, ok := sa.Layout.GetKeyInfo(ev.Key)
+		if !ok {
+			if !slices.Contains(res.Missing, ev.Key) {
+				res.Missing = append(res.Missing, ev.Key)
+			}
+			prev = nil
+			continue
+		}
+
+		hands[ki.Hand] = true
+		if ki.Row < 3 && ki.Row != 1 {
+			res.OffHome++
+		}
+		if home := fingerHomeKeys[ki.Finger]; home != ki.Index {
+			res.Cost += sa.Layout.MustDistance(home, ki.Index).Distance
+		}
+
+		fingers := []uint8{ki.Finger}
+		for _, mod := range ev.Modifiers {
+			finger := holders[mod].finger(ki.Hand)
+			hands[IfThen(finger <= LT, LEFT, RIGHT)] = true
+			fingers = append(fingers, finger)
+		}
+		slices.Sort(fingers)
+		if len(slices.Compact(fingers)) < len(ev.Modifiers)+1 {
+			res.SameFingerChords++
+			res.Cost += 2
+		}
+
+		if prev != nil && prev.Finger == ki.Finger && prevKey != ev.Key {
+			res.SameFingerBigram++
+			res.Cost++
+		}
+		prev, prevKey = &ki, ev.Key
+	}
+
+	if res.Presses() > 1 {
+		res.OneHand = hands[LEFT] != hands[RIGHT]
+		res.LeftHand = hands[LEFT] && !hands[RIGHT]
+	}
+	return res
+}
+
+// Presses returns the number of keys pressed for the shortcut, including modifiers.
+func (res *ShortcutResult) Presses() int {
+	n := len(res.Shortcut.Events)
+	for _, ev := range res.Shortcut.Events {
+		n += len(ev.Modifiers)
+	}
+	return n
+}
+
+// b2i converts a bool to 0 or 1.
+func b2i(b bool) int {
+	return IfThen(b, 1, 0)
+}
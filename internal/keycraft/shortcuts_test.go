@@ -0,0 +1,133 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeShortcutsFile writes a shortcuts file to a temporary directory.
+func writeShortcutsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shortcuts.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newShortcutTestLayout returns a layout with a few keys at known positions: 'a' on the
+// left pinky home key, 'z' below it, 'c' on the left middle bottom key, 'e' on the left
+// middle top key, 'd' on the left middle home key, 'j' on the right index home key and
+// space on the left home thumb key.
+func newShortcutTestLayout() *SplitLayout {
+	var runes [42]rune
+	runes[13], runes[25], runes[27], runes[3], runes[15], runes[19], runes[37] = 'a', 'z', 'c', 'e', 'd', 'j', ' '
+	return NewSplitLayout("test", ORTHO, runes)
+}
+
+func TestNewShortcutSetFromFile(t *testing.T) {
+	path := writeShortcutsFile(t, `# editor shortcuts
+modifier Control = right-pinky
+100 Ctrl+C
+40 ctrl+shift+Space # new line
+10 cmd++
+30 dd
+5 ctrl+z  e
+`)
+	set := Must(NewShortcutSetFromFile("editor", path))
+
+	if set.Holders["ctrl"] != HoldRightPinky || set.Holders["shift"] != HoldOppositePinky {
+		t.Errorf("unexpected holders: %v", set.Holders)
+	}
+	want := [][]KeyEvent{
+		{{Key: 'c', Modifiers: []string{"ctrl"}}},
+		{{Key: ' ', Modifiers: []string{"ctrl", "shift"}}},
+		{{Key: '+', Modifiers: []string{"meta"}}},
+		{{Key: 'd'}, {Key: 'd'}},
+		{{Key: 'z', Modifiers: []string{"ctrl"}}, {Key: 'e'}},
+	}
+	if len(set.Shortcuts) != len(want) {
+		t.Fatalf("got %d shortcuts, want %d", len(set.Shortcuts), len(want))
+	}
+	for i, sc := range set.Shortcuts {
+		if !slices.EqualFunc(sc.Events, want[i], func(a, b KeyEvent) bool {
+			return a.Key == b.Key && slices.Equal(a.Modifiers, b.Modifiers)
+		}) {
+			t.Errorf("shortcut %q parsed as %v, want %v", sc.Text, sc.Events, want[i])
+		}
+	}
+	if set.Shortcuts[3].Count != 30 || set.Shortcuts[1].Text != "ctrl+shift+Space" {
+		t.Errorf("unexpected shortcut %+v", set.Shortcuts[1])
+	}
+}
+
+func TestNewShortcutSetFromFile_Errors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"no shortcuts", "# nothing\n", "no shortcuts"},
+		{"missing keys", "100\n", "expected a count"},
+		{"invalid count", "often ctrl+c\n", "invalid count"},
+		{"unknown modifier", "10 hyper+c\n", "unknown modifier"},
+		{"long chord key", "10 ctrl+tab\n", "single key"},
+		{"invalid holder", "modifier ctrl = left-index\n10 ctrl+c\n", "invalid finger"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewShortcutSetFromFile("s", writeShortcutsFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyseShortcuts(t *testing.T) {
+	set := Must(NewShortcutSetFromFile("s", writeShortcutsFile(t,
+		"10 ctrl+a\n10 ctrl+c\n10 shift+d\n10 ec\n10 ctrl+j\n10 ctrl+q\n")))
+	sa := AnalyseShortcuts(newShortcutTestLayout(), set)
+
+	ctrlA, ctrlC, shiftD, ec, ctrlJ, ctrlQ := &sa.Results[0], &sa.Results[1], &sa.Results[2],
+		&sa.Results[3], &sa.Results[4], &sa.Results[5]
+
+	// Ctrl and 'a' both need the left pinky
+	if ctrlA.SameFingerChords != 1 || ctrlA.Cost != 2 || !ctrlA.LeftHand {
+		t.Errorf("ctrl+a: %+v", ctrlA)
+	}
+	// 'c' is one row below the middle finger home key
+	if ctrlC.SameFingerChords != 0 || ctrlC.Cost != 1 || !ctrlC.OneHand || ctrlC.OffHome != 1 {
+		t.Errorf("ctrl+c: %+v", ctrlC)
+	}
+	// Shift is held by the opposite (right) pinky
+	if shiftD.OneHand || shiftD.Cost != 0 {
+		t.Errorf("shift+d: %+v", shiftD)
+	}
+	// 'e' and 'c' are on the left middle finger, two rows apart
+	if ec.SameFingerBigram != 1 || ec.Cost != 3 || ec.OffHome != 2 {
+		t.Errorf("ec: %+v", ec)
+	}
+	if ctrlJ.OneHand || ctrlJ.LeftHand {
+		t.Errorf("ctrl+j: %+v", ctrlJ)
+	}
+	if !slices.Equal(ctrlQ.Missing, []rune{'q'}) {
+		t.Errorf("ctrl+q missing %q, want q", ctrlQ.Missing)
+	}
+
+	wants := map[string]float64{
+		"MISSING":   100.0 / 6,
+		"COST":      6.0 / 5,
+		"ONE-HAND":  60,
+		"LEFT-HAND": 60,
+		"SF-CHORD":  25,
+		"SFB":       100,
+		"OFF-HOME":  50,
+	}
+	for metric, want := range wants {
+		if got := sa.Metrics[metric]; got < want-1e-9 || got > want+1e-9 {
+			t.Errorf("%s = %.4f, want %.4f", metric, got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package keycraft
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SimilarityInput contains parameters for comparing all layouts in a directory.
+type SimilarityInput struct {
+	LayoutsDir string
+	Corpus     *Corpus // Character frequencies that weight the shared key positions
+	Threshold  float64 // Minimum average similarity of the layouts in a cluster (0-1)
+	Mirror     bool    // Whether a layout and the mirror image of another count as the same
+}
+
+// ClusterMerge is a step of hierarchical clustering, in which two clusters are joined. Leaf
+// clusters 0..n-1 are the layouts; the cluster formed by merge k is n+k, as in the linkage
+// matrix of SciPy, so the merges can be drawn as a dendrogram.
+type ClusterMerge struct {
+	Left, Right int     // Clusters that are joined
+	Similarity  float64 // Average similarity between the layouts of the two clusters
+	Size        int     // Number of layouts in the joined cluster
+}
+
+// SimilarityCluster is a group of similar layouts.
+type SimilarityCluster struct {
+	Names      []string // Layout names, sorted
+	Similarity float64  // Average similarity between the layouts (1 for a single layout)
+}
+
+// SimilarityResult holds the pairwise similarity of layouts and their clustering.
+type SimilarityResult struct {
+	Names     []string            // Layout names, sorted
+	Matrix    [][]float64         // Similarity of every pair of layouts, in the order of Names
+	Merges    []ClusterMerge      // Merges from the most to the least similar clusters
+	Clusters  []SimilarityCluster // Clusters of layouts at the threshold, largest first
+	Threshold float64
+}
+
+// LayoutSimilarity returns the share of typed characters that the two layouts put on the
+// same key position, weighted by their frequency in the corpus: 1 for identical layouts,
+// and 0 for layouts that share no key. Characters that are on only one of the layouts count
+// as differing.
+func LayoutSimilarity(a, b *SplitLayout, corpus *Corpus) float64 {
+	var same, total uint64
+	for r, ka := range a.RuneInfo {
+		cnt := corpus.Unigrams[Unigram(r)]
+		total += cnt
+		if kb, ok := b.RuneInfo[r]; ok && kb.Index == ka.Index {
+			same += cnt
+		}
+	}
+	for r := range b.RuneInfo {
+		if _, ok := a.RuneInfo[r]; !ok {
+			total += corpus.Unigrams[Unigram(r)]
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(same) / float64(total)
+}
+
+// CompareLayouts computes the similarity of every pair of layouts in a directory, and groups
+// them by average-linkage hierarchical clustering: the two clusters with the highest average
+// similarity between their layouts are joined, until one cluster remains. The clusters at
+// the threshold are those joined at a similarity of at least the threshold.
+func CompareLayouts(input SimilarityInput) (*SimilarityResult, error) {
+	if input.Threshold < 0 || input.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1. Got: %g", input.Threshold)
+	}
+	layouts, err := loadLayoutsInDir(input.LayoutsDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(layouts) < 2 {
+		return nil, fmt.Errorf("need at least 2 layouts in %s, found %d", input.LayoutsDir, len(layouts))
+	}
+
+	n := len(layouts)
+	result := &SimilarityResult{Names: make([]string, n), Matrix: make([][]float64, n), Threshold: input.Threshold}
+	for i, layout := range layouts {
+		result.Names[i] = layout.Name
+		result.Matrix[i] = make([]float64, n)
+		result.Matrix[i][i] = 1
+	}
+	for i := range layouts {
+		for j := i + 1; j < n; j++ {
+			sim := LayoutSimilarity(layouts[i], layouts[j], input.Corpus)
+			if input.Mirror {
+				mirror := layouts[j].Clone()
+				mirror.FlipHorizontal()
+				sim = max(sim, LayoutSimilarity(layouts[i], mirror, input.Corpus))
+			}
+			result.Matrix[i][j], result.Matrix[j][i] = sim, sim
+		}
+	}
+
+	result.Merges = averageLinkage(result.Matrix)
+
+	// Cut the tree at the threshold
+	parent := make([]int, 2*n-1)
+	for i := range parent {
+		parent[i] = i
+	}
+	for k, m := range result.Merges {
+		if m.Similarity >= input.Threshold {
+			parent[m.Left], parent[m.Right] = n+k, n+k
+		}
+	}
+	var root func(int) int
+	root = func(c int) int {
+		if parent[c] != c {
+			parent[c] = root(parent[c])
+		}
+		return parent[c]
+	}
+	members := make(map[int][]string)
+	for i, name := range result.Names {
+		members[root(i)] = append(members[root(i)], name)
+	}
+	for c, names := range members {
+		cluster := SimilarityCluster{Names: names, Similarity: 1}
+		if c >= n {
+			cluster.Similarity = result.Merges[c-n].Similarity
+		}
+		result.Clusters = append(result.Clusters, cluster)
+	}
+	sort.Slice(result.Clusters, func(i, j int) bool {
+		ci, cj := result.Clusters[i].Names, result.Clusters[j].Names
+		if len(ci) != len(cj) {
+			return len(ci) > len(cj)
+		}
+		return ci[0] < cj[0]
+	})
+	return result, nil
+}
+
+// averageLinkage clusters items by their pairwise similarity, joining the two most similar
+// clusters at every step, with the similarity of clusters being the average similarity of
+// their items. Ties are broken by the order of the items.
+func averageLinkage(sim [][]float64) []ClusterMerge {
+	n := len(sim)
+	type cluster struct {
+		id   int
+		size int
+	}
+	active := make([]cluster, n)
+	// link[i][j] holds the sum of the similarities between the items of active clusters i and j
+	link := make([][]float64, n)
+	for i := range active {
+		active[i] = cluster{id: i, size: 1}
+		link[i] = append([]float64(nil), sim[i]...)
+	}
+
+	merges := make([]ClusterMerge, 0, n-1)
+	for len(merges) < n-1 {
+		bi, bj, best := -1, -1, -1.0
+		for i := range active {
+			for j := i + 1; j < len(active); j++ {
+				avg := link[i][j] / float64(active[i].size*active[j].size)
+				if avg > best {
+					bi, bj, best = i, j, avg
+				}
+			}
+		}
+
+		left, right := active[bi].id, active[bj].id
+		merges = append(merges, ClusterMerge{
+			Left:       min(left, right),
+			Right:      max(left, right),
+			Similarity: best,
+			Size:       active[bi].size + active[bj].size,
+		})
+
+		// The joined cluster takes the place of bi, and bj is removed
+		for k := range active {
+			link[bi][k] += link[bj][k]
+			link[k][bi] = link[bi][k]
+		}
+		active[bi] = cluster{id: n + len(merges) - 1, size: active[bi].size + active[bj].size}
+		active = append(active[:bj], active[bj+1:]...)
+		link = append(link[:bj], link[bj+1:]...)
+		for k := range link {
+			link[k] = append(link[k][:bj], link[k][bj+1:]...)
+		}
+	}
+	return merges
+}
@@ -0,0 +1,93 @@
+package keycraft
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayoutSimilarity(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf": testLayoutKlf,
+		"c.klf": testLayoutVariantKlf, // z and x swapped
+	})
+	a := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+	c := Must(NewLayoutFromFile("c", filepath.Join(dir, "c.klf")))
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("zax zax zax")
+
+	if got := LayoutSimilarity(a, a, corpus); got != 1 {
+		t.Errorf("similarity to itself = %v, want 1", got)
+	}
+	// Only a is on the same key: 3 of 9 characters
+	want := 3.0 / 9
+	if got := LayoutSimilarity(a, c, corpus); math.Abs(got-want) > 1e-9 {
+		t.Errorf("similarity = %v, want %v", got, want)
+	}
+	if got := LayoutSimilarity(c, a, corpus); math.Abs(got-want) > 1e-9 {
+		t.Errorf("similarity is not symmetric: %v, want %v", got, want)
+	}
+}
+
+func TestAverageLinkage(t *testing.T) {
+	sim := [][]float64{
+		{1, 0.9, 0.2, 0.1},
+		{0.9, 1, 0.4, 0.3},
+		{0.2, 0.4, 1, 0.8},
+		{0.1, 0.3, 0.8, 1},
+	}
+	want := []ClusterMerge{
+		{Left: 0, Right: 1, Similarity: 0.9, Size: 2},
+		{Left: 2, Right: 3, Similarity: 0.8, Size: 2},
+		{Left: 4, Right: 5, Similarity: 0.25, Size: 4},
+	}
+	got := averageLinkage(sim)
+	if len(got) != len(want) {
+		t.Fatalf("got %d merges, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Left != want[i].Left || got[i].Right != want[i].Right || got[i].Size != want[i].Size ||
+			math.Abs(got[i].Similarity-want[i].Similarity) > 1e-9 {
+			t.Errorf("merge %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCompareLayouts(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"c.klf":   testLayoutVariantKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog")
+
+	result, err := CompareLayouts(SimilarityInput{LayoutsDir: dir, Corpus: corpus, Threshold: 0.9})
+	if err != nil {
+		t.Fatalf("CompareLayouts failed: %v", err)
+	}
+	if len(result.Names) != 3 || len(result.Merges) != 2 {
+		t.Fatalf("got %d layouts and %d merges, want 3 and 2", len(result.Names), len(result.Merges))
+	}
+	if len(result.Clusters) != 2 {
+		t.Fatalf("got clusters %v, want a and c together, and far alone", result.Clusters)
+	}
+	family := result.Clusters[0]
+	if len(family.Names) != 2 || family.Names[0] != "a" || family.Names[1] != "c" {
+		t.Errorf("family = %v, want [a c]", family.Names)
+	}
+	if family.Similarity != result.Matrix[0][1] {
+		t.Errorf("family similarity = %v, want %v", family.Similarity, result.Matrix[0][1])
+	}
+
+	// Everything joins one family at a threshold of 0
+	all := Must(CompareLayouts(SimilarityInput{LayoutsDir: dir, Corpus: corpus}))
+	if len(all.Clusters) != 1 || len(all.Clusters[0].Names) != 3 {
+		t.Errorf("clusters at threshold 0 = %v, want one of 3", all.Clusters)
+	}
+
+	if _, err := CompareLayouts(SimilarityInput{LayoutsDir: dir, Corpus: corpus, Threshold: 1.5}); err == nil {
+		t.Error("expected error for threshold above 1")
+	}
+}
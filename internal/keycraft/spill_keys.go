@@ -0,0 +1,140 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+)
+
+// spillPlaceholder is the placeholder character on the first spill-over key during
+// optimization, and the next ones on the next keys. They are in a private use area, so no
+// corpus has them.
+const spillPlaceholder rune = 0xF0000
+
+// ParseSpillKeys parses a --spill-keys specification: a region (see ParseRegion) whose
+// empty keys the optimiser may move characters to, "all" for every empty key, or "none"
+// for no such keys.
+func ParseSpillKeys(spec string) (*Region, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "none":
+		return &Region{}, nil
+	case "all":
+		region := &Region{}
+		for i := range region {
+			region[i] = true
+		}
+		return region, nil
+	}
+	region, err := ParseRegion(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spill-over keys: %w", err)
+	}
+	return region, nil
+}
+
+// SpillKeys returns the spill-over keys of the layout in a region: its empty keys that the
+// board of the layout has. Its other empty keys are dead, including all keys that the board
+// doesn't have. It returns an error if a region with keys has no spill-over keys.
+func (sl *SplitLayout) SpillKeys(region *Region) (*Region, error) {
+	var board *BoardPreset
+	if sl.Board != "" {
+		board, _ = ParseBoardPreset(sl.Board)
+	}
+	spill := &Region{}
+	for i, r := range sl.Runes {
+		spill[i] = r == 0 && region[i] && (board == nil || board.Keys[i])
+	}
+	if *spill == (Region{}) && *region != (Region{}) {
+		if board != nil {
+			return nil, fmt.Errorf("%s has no empty keys there that the %s board has", sl.Name, board.Name)
+		}
+		return nil, fmt.Errorf("%s has no empty keys there", sl.Name)
+	}
+	return spill, nil
+}
+
+// SetSpillKeys frees the spill-over keys of the layout (see SplitLayout.SpillKeys), so that
+// the optimiser may move characters to them, and pins the other empty keys, which are dead.
+// Pins from a pins file (fromFile) must agree: pinning a spill-over key or freeing a dead key
+// is an error.
+func (p *PinnedKeys) SetSpillKeys(spill *Region, sl *SplitLayout, fromFile bool) error {
+	var pinnedSpill, freeDead []string
+	for i, r := range sl.Runes {
+		switch {
+		case !fromFile:
+		case spill[i] && p[i]:
+			pinnedSpill = append(pinnedSpill, keyPosName(uint8(i)))
+		case r == 0 && !spill[i] && !p[i]:
+			freeDead = append(freeDead, keyPosName(uint8(i)))
+		}
+	}
+	if len(pinnedSpill) > 0 {
+		return fmt.Errorf("the pins file pins spill-over keys %s; mark them free, or leave them out of the spill-over keys",
+			strings.Join(pinnedSpill, ", "))
+	}
+	if len(freeDead) > 0 {
+		return fmt.Errorf("the pins file frees empty keys %s, which are dead; pin them, or add them to the spill-over keys",
+			strings.Join(freeDead, ", "))
+	}
+
+	for i, r := range sl.Runes {
+		if r == 0 {
+			p[i] = !spill[i]
+		}
+	}
+	return nil
+}
+
+// CheckSpillKeys returns an error if a spill-over key is pinned, such as by --region or
+// --swap-classes, because the optimiser could then not move characters to it.
+func (p *PinnedKeys) CheckSpillKeys(spill *Region) error {
+	var pinned []string
+	for i := range spill {
+		if spill[i] && p[i] {
+			pinned = append(pinned, keyPosName(uint8(i)))
+		}
+	}
+	if len(pinned) > 0 {
+		return fmt.Errorf("spill-over keys %s are pinned by --region or --swap-classes", strings.Join(pinned, ", "))
+	}
+	return nil
+}
+
+// withSpillPlaceholders returns a copy of the layout with placeholder characters on its empty
+// spill-over keys. A swap needs characters on both keys, so the optimiser moves a character
+// to a spill-over key by swapping it with the placeholder.
+func (sl *SplitLayout) withSpillPlaceholders(spill *Region) *SplitLayout {
+	filled := sl.Clone()
+	for i, r := range sl.Runes {
+		if r == 0 && spill[i] {
+			filled.Runes[i] = spillPlaceholder + rune(i)
+			filled.RuneInfo[filled.Runes[i]] = NewKeyInfo(uint8(i/12), uint8(i%12), sl.LayoutType)
+		}
+	}
+	filled.initKeyCaches()
+	return filled
+}
+
+// withoutSpillPlaceholders returns the layout with its placeholder characters (see
+// withSpillPlaceholders) removed, leaving their keys empty.
+func (sl *SplitLayout) withoutSpillPlaceholders() *SplitLayout {
+	var emptied *SplitLayout
+	for i, r := range sl.Runes {
+		if isSpillPlaceholder(r) {
+			if emptied == nil {
+				emptied = sl.Clone()
+			}
+			emptied.Runes[i] = 0
+			delete(emptied.RuneInfo, r)
+		}
+	}
+	if emptied == nil {
+		return sl
+	}
+	emptied.initKeyCaches()
+	return emptied
+}
+
+// isSpillPlaceholder reports whether a character is a placeholder of withSpillPlaceholders.
+func isSpillPlaceholder(r rune) bool {
+	return r >= spillPlaceholder && r < spillPlaceholder+42
+}
@@ -0,0 +1,119 @@
+package keycraft
+
+import (
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSpillKeys(t *testing.T) {
+	for spec, want := range map[string]int{"none": 0, "All": 42, "cols:0,cols:11": 6, "thumbs": 6} {
+		region, err := ParseSpillKeys(spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+			continue
+		}
+		if got := len(slices.DeleteFunc(region[:], func(in bool) bool { return !in })); got != want {
+			t.Errorf("%q: %d keys, want %d", spec, got, want)
+		}
+	}
+	if _, err := ParseSpillKeys("outer"); err == nil {
+		t.Error("expected an error for an unknown region")
+	}
+}
+
+// TestSpillKeys checks that only the empty keys of the region that the board has are
+// spill-over keys, and that the pins of a pins file must agree with them.
+func TestSpillKeys(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+
+	// Of the outer columns, r0c0, r1c0, r2c0 and r2c11 are empty
+	spill := Must(layout.SpillKeys(Must(ParseSpillKeys("cols:0,cols:11"))))
+	for i, want := range map[int]bool{0: true, 12: true, 24: true, 35: true, 11: false, 36: false} {
+		if spill[i] != want {
+			t.Errorf("key %s spill-over = %v, want %v", keyPosName(uint8(i)), spill[i], want)
+		}
+	}
+	if _, err := layout.SpillKeys(Must(ParseSpillKeys("cols:1-10"))); err == nil {
+		t.Error("expected an error for a region without empty keys")
+	}
+	if got := Must(layout.SpillKeys(Must(ParseSpillKeys("none")))); *got != (Region{}) {
+		t.Errorf("none gives spill-over keys %v, want none", got)
+	}
+
+	// The ferris board has neither the outer columns nor the outer thumb keys
+	layout.Board = "ferris"
+	if _, err := layout.SpillKeys(Must(ParseSpillKeys("cols:0"))); err == nil ||
+		!strings.Contains(err.Error(), "ferris") {
+		t.Errorf("error %v for keys the board doesn't have, want one naming the board", err)
+	}
+	boardSpill := Must(layout.SpillKeys(Must(ParseSpillKeys("all"))))
+	if boardSpill[0] || boardSpill[36] || !boardSpill[37] {
+		t.Errorf("spill-over keys of all on ferris: r0c0 %v, t0 %v, t1 %v; want only t1",
+			boardSpill[0], boardSpill[36], boardSpill[37])
+	}
+
+	// Default pins free the spill-over keys and pin the dead keys
+	pinned := Must(LoadPinsFromParams("", "", "", layout))
+	Must0(pinned.SetSpillKeys(spill, layout, false))
+	if pinned[0] || pinned[35] || !pinned[36] {
+		t.Errorf("pins r0c0 %v, r2c11 %v, t0 %v; want spill-over keys free and dead keys pinned",
+			pinned[0], pinned[35], pinned[36])
+	}
+	if err := pinned.CheckSpillKeys(spill); err != nil {
+		t.Errorf("unexpected error for free spill-over keys: %v", err)
+	}
+	pinned.PinOutside(Must(ParseRegion("cols:0")))
+	if err := pinned.CheckSpillKeys(spill); err == nil || !strings.Contains(err.Error(), "r2c11") {
+		t.Errorf("error %v for a spill-over key outside the region, want one naming r2c11", err)
+	}
+
+	// A pins file must pin the dead keys and free the spill-over keys
+	fromFile := &PinnedKeys{}
+	if err := fromFile.SetSpillKeys(spill, layout, true); err == nil || !strings.Contains(err.Error(), "t0") {
+		t.Errorf("error %v for a freed dead key, want one naming t0", err)
+	}
+	for i := range fromFile {
+		fromFile[i] = true
+	}
+	if err := fromFile.SetSpillKeys(spill, layout, true); err == nil || !strings.Contains(err.Error(), "r0c0") {
+		t.Errorf("error %v for a pinned spill-over key, want one naming r0c0", err)
+	}
+}
+
+// TestOptimize_SpillKeys checks that the placeholders of the spill-over keys don't change
+// the metrics, that characters may be moved to spill-over keys but not to dead keys, and
+// that the best layout has no placeholders.
+func TestOptimize_SpillKeys(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 30
+	spill := Must(layout.SpillKeys(Must(ParseSpillKeys("cols:0"))))
+	Must0(bls.pinned.SetSpillKeys(spill, layout, false))
+
+	filled := layout.withSpillPlaceholders(spill)
+	before := NewAnalyser(layout, bls.corpus, NewTargetLoads())
+	after := NewAnalyser(filled, bls.corpus, NewTargetLoads())
+	for metric, value := range before.Metrics {
+		if after.Metrics[metric] != value {
+			t.Errorf("%s is %.4f with placeholders, want %.4f", metric, after.Metrics[metric], value)
+		}
+	}
+
+	best := bls.Optimize(context.Background(), filled, nil).withoutSpillPlaceholders()
+	for i, r := range best.Runes {
+		if isSpillPlaceholder(r) {
+			t.Errorf("placeholder left on key %s", keyPosName(uint8(i)))
+		}
+		if layout.Runes[i] == 0 && !spill[i] && r != 0 {
+			t.Errorf("%q moved to dead key %s", r, keyPosName(uint8(i)))
+		}
+	}
+	want, got := slices.Sorted(maps.Keys(layout.RuneInfo)), slices.Sorted(maps.Keys(best.RuneInfo))
+	if !slices.Equal(got, want) {
+		t.Errorf("best layout has characters %q, want %q", string(got), string(want))
+	}
+}
@@ -0,0 +1,227 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Subsample returns a random sample of the corpus in which every n-gram occurrence is kept
+// with probability fraction, so that each count is drawn from a binomial distribution. The
+// totals are sampled the same way, so that n-gram percentages stay unbiased. The word list
+// and stream are shared with the corpus and not sampled.
+func (c *Corpus) Subsample(fraction float64, rng *LockedSource) *Corpus {
+	sample := *c
+	sample.Name = fmt.Sprintf("%s (%.0f%% sample)", c.Name, fraction*100)
+	sample.Unigrams, sample.TotalUnigramsCount = thinCounts(c.Unigrams, c.TotalUnigramsCount, fraction, rng)
+	sample.Bigrams, sample.TotalBigramsCount = thinCounts(c.Bigrams, c.TotalBigramsCount, fraction, rng)
+	sample.Trigrams, sample.TotalTrigramsCount = thinCounts(c.Trigrams, c.TotalTrigramsCount, fraction, rng)
+	sample.Skipgrams, sample.TotalSkipgramsCount = thinCounts(c.Skipgrams, c.TotalSkipgramsCount, fraction, rng)
+	return &sample
+}
+
+// thinCounts samples every count of an n-gram table, and the total. Occurrences counted in
+// the total but not in the table (pruned by coverage filtering) are sampled as a whole.
+func thinCounts[K comparable](counts map[K]uint64, total uint64, p float64, rng *LockedSource) (map[K]uint64, uint64) {
+	thinned := make(map[K]uint64, len(counts))
+	var kept, sum uint64
+	for k, cnt := range counts {
+		sum += cnt
+		if n := binomial(cnt, p, rng); n > 0 {
+			thinned[k] = n
+			kept += n
+		}
+	}
+	if total > sum {
+		kept += binomial(total-sum, p, rng)
+	}
+	return thinned, kept
+}
+
+// Split splits the corpus into k disjoint folds of about equal size: every n-gram and word
+// occurrence is assigned to one of the folds at random, so that the counts of the folds add
+// up to those of the corpus. The lines of the stream are split into k consecutive parts.
+func (c *Corpus) Split(k int, rng *LockedSource) []*Corpus {
+	folds := make([]*Corpus, k)
+	for i := range folds {
+		fold := *c
+		fold.Name = fmt.Sprintf("%s (fold %d/%d)", c.Name, i+1, k)
+		folds[i] = &fold
+	}
+	unigrams, unigramTotals := splitCounts(c.Unigrams, c.TotalUnigramsCount, k, rng)
+	bigrams, bigramTotals := splitCounts(c.Bigrams, c.TotalBigramsCount, k, rng)
+	trigrams, trigramTotals := splitCounts(c.Trigrams, c.TotalTrigramsCount, k, rng)
+	skipgrams, skipgramTotals := splitCounts(c.Skipgrams, c.TotalSkipgramsCount, k, rng)
+	words, wordTotals := splitCounts(c.Words, c.TotalWordsCount, k, rng)
+	initial, _ := splitCounts(c.WordInitialBigrams, 0, k, rng)
+	final, _ := splitCounts(c.WordFinalBigrams, 0, k, rng)
+	lines := strings.SplitAfter(c.Stream, "\n")
+	for i, fold := range folds {
+		fold.Unigrams, fold.TotalUnigramsCount = unigrams[i], unigramTotals[i]
+		fold.Bigrams, fold.TotalBigramsCount = bigrams[i], bigramTotals[i]
+		fold.Trigrams, fold.TotalTrigramsCount = trigrams[i], trigramTotals[i]
+		fold.Skipgrams, fold.TotalSkipgramsCount = skipgrams[i], skipgramTotals[i]
+		fold.Words, fold.TotalWordsCount = words[i], wordTotals[i]
+		if c.WordInitialBigrams != nil {
+			fold.WordInitialBigrams, fold.WordFinalBigrams = initial[i], final[i]
+		}
+		fold.Stream = strings.Join(lines[i*len(lines)/k:(i+1)*len(lines)/k], "")
+	}
+	return folds
+}
+
+// splitCounts splits every count of an n-gram table, and the total, into k parts at random.
+// Occurrences counted in the total but not in the table are split as a whole.
+func splitCounts[K comparable](counts map[K]uint64, total uint64, k int, rng *LockedSource) ([]map[K]uint64, []uint64) {
+	parts := make([]map[K]uint64, k)
+	totals := make([]uint64, k)
+	for i := range parts {
+		parts[i] = make(map[K]uint64)
+	}
+	split := func(cnt uint64, add func(i int, n uint64)) {
+		for i := range k {
+			// Each remaining occurrence is in this part with probability 1/(number of parts left)
+			n := binomial(cnt, 1/float64(k-i), rng)
+			if n > 0 {
+				add(i, n)
+			}
+			cnt -= n
+		}
+	}
+	// The keys are split in a fixed order, so that the folds of a seed are reproducible
+	keys := make([]K, 0, len(counts))
+	names := make(map[K]string, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+		names[key] = fmt.Sprint(key)
+	}
+	slices.SortFunc(keys, func(a, b K) int { return strings.Compare(names[a], names[b]) })
+
+	var sum uint64
+	for _, key := range keys {
+		cnt := counts[key]
+		sum += cnt
+		split(cnt, func(i int, n uint64) {
+			parts[i][key] = n
+			totals[i] += n
+		})
+	}
+	if total > sum {
+		split(total-sum, func(i int, n uint64) { totals[i] += n })
+	}
+	return parts, totals
+}
+
+// thinTrigrams samples the counts of a trigram list, dropping trigrams that are not kept.
+func thinTrigrams(trigrams []TrigramInfo, p float64, rng *LockedSource) []TrigramInfo {
+	thinned := make([]TrigramInfo, 0, len(trigrams))
+	for _, ti := range trigrams {
+		if n := binomial(ti.Count, p, rng); n > 0 {
+			thinned = append(thinned, TrigramInfo{Count: n, Runes: ti.Runes})
+		}
+	}
+	return thinned
+}
+
+// binomial draws the number of successes in n trials with success probability p. Small
+// expected counts are drawn exactly by skipping geometrically distributed runs of failures;
+// larger ones use the normal approximation, which is accurate for them.
+func binomial(n uint64, p float64, rng *LockedSource) uint64 {
+	if n == 0 || p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return n
+	}
+	mean := float64(n) * p
+	if mean >= 30 {
+		v := math.Round(mean + math.Sqrt(mean*(1-p))*rng.NormFloat64())
+		return uint64(min(max(v, 0), float64(n)))
+	}
+
+	logq := math.Log1p(-p)
+	var successes, trials uint64
+	for {
+		// Number of trials up to and including the next success
+		skip := math.Floor(math.Log(1-rng.Float64())/logq) + 1
+		if skip > float64(n-trials) {
+			return successes
+		}
+		trials += uint64(skip)
+		successes++
+	}
+}
+
+// SetSubsample makes Score evaluate layouts against a random sample of fraction of the
+// corpus, which speeds up scoring of huge corpora at the cost of some noise. The sample is
+// drawn from seed, and a new one is drawn by every call to Resample. ScoreExact always
+// uses the full corpus. A fraction of 0 or 1 scores against the full corpus.
+//
+// SetSubsample must not be called concurrently with other methods of the scorer.
+func (sc *Scorer) SetSubsample(fraction float64, seed int64) error {
+	if fraction < 0 || fraction > 1 || math.IsNaN(fraction) {
+		return fmt.Errorf("invalid subsample fraction %g: must be between 0 and 1", fraction)
+	}
+	if fraction == 1 {
+		fraction = 0
+	}
+	sc.subsample = fraction
+	sc.sampleRng = NewLockedRNG(uint64(seed), 0)
+	for i, part := range sc.parts {
+		if err := part.scorer.SetSubsample(fraction, DeriveSeed(seed, i)); err != nil {
+			return err
+		}
+	}
+	sc.Resample()
+	return nil
+}
+
+// Subsampled reports whether Score evaluates layouts against a corpus sample.
+func (sc *Scorer) Subsampled() bool {
+	return sc.subsample > 0
+}
+
+// Resample draws a new corpus sample when subsampling is enabled, and forgets the scores
+// computed against the previous sample. It does nothing otherwise.
+//
+// Resample must not be called concurrently with other methods of the scorer.
+func (sc *Scorer) Resample() {
+	for _, part := range sc.parts {
+		part.scorer.Resample()
+	}
+	// The cached scores are only stale if they were computed against a sample
+	resampled := sc.sample != nil || sc.subsample > 0
+	if sc.sample != nil {
+		forgetShiftFolds(sc.sample)
+		forgetMagicFolds(sc.sample)
+		forgetNumberFolds(sc.sample)
+		sc.sample, sc.sampleTrigrams = nil, nil
+	}
+	if sc.subsample > 0 && len(sc.parts) == 0 {
+		sc.sample = sc.corpus.Subsample(sc.subsample, sc.sampleRng)
+		if sc.trigramCache != nil {
+			sc.sampleTrigrams = newTrigramTable(thinTrigrams(sc.trigramCache, sc.subsample, sc.sampleRng))
+		}
+	}
+	if resampled {
+		sc.scoreCache.clear()
+	}
+}
+
+// ScoreExact returns the cost of a layout against the full corpus. Without subsampling,
+// it is the same as Score.
+func (sc *Scorer) ScoreExact(layout *SplitLayout) float64 {
+	if sc.subsample == 0 {
+		return sc.Score(layout)
+	}
+	if len(sc.parts) > 0 {
+		var score float64
+		for _, part := range sc.parts {
+			score += part.weight * part.scorer.ScoreExact(layout)
+		}
+		return score + sc.penalty(layout)
+	}
+	sc.prepareNGramCaches(layout)
+	return sc.scoreAgainst(layout, sc.corpus, sc.trigramTable) + sc.penalty(layout)
+}
@@ -0,0 +1,178 @@
+package keycraft
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBinomial(t *testing.T) {
+	rng := NewLockedRNG(1, 0)
+	if got := binomial(0, 0.5, rng); got != 0 {
+		t.Errorf("binomial(0, 0.5) = %d, want 0", got)
+	}
+	if got := binomial(10, 0, rng); got != 0 {
+		t.Errorf("binomial(10, 0) = %d, want 0", got)
+	}
+	if got := binomial(10, 1, rng); got != 10 {
+		t.Errorf("binomial(10, 1) = %d, want 10", got)
+	}
+
+	// Both the exact method (small means) and the normal approximation have the right mean
+	for _, n := range []uint64{5, 40, 1000, 1_000_000} {
+		const p, draws = 0.3, 2000
+		var sum uint64
+		for range draws {
+			v := binomial(n, p, rng)
+			if v > n {
+				t.Fatalf("binomial(%d, %g) = %d, above n", n, p, v)
+			}
+			sum += v
+		}
+		mean, want := float64(sum)/draws, float64(n)*p
+		// Allow 5 standard errors of the mean
+		if tol := 5 * math.Sqrt(want*(1-p)/draws); math.Abs(mean-want) > tol {
+			t.Errorf("binomial(%d, %g) has mean %.2f, want %.2f ± %.2f", n, p, mean, want, tol)
+		}
+	}
+}
+
+func TestCorpusSubsample(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords(strings.Repeat("the quick brown fox jumps over the lazy dog ", 500))
+
+	sample := corpus.Subsample(0.25, NewLockedRNG(1, 0))
+	totals := []struct {
+		name         string
+		sample, full uint64
+	}{
+		{"unigrams", sample.TotalUnigramsCount, corpus.TotalUnigramsCount},
+		{"bigrams", sample.TotalBigramsCount, corpus.TotalBigramsCount},
+		{"trigrams", sample.TotalTrigramsCount, corpus.TotalTrigramsCount},
+		{"skipgrams", sample.TotalSkipgramsCount, corpus.TotalSkipgramsCount},
+	}
+	for _, tt := range totals {
+		if ratio := float64(tt.sample) / float64(tt.full); math.Abs(ratio-0.25) > 0.02 {
+			t.Errorf("sampled %s total is %.3f of the full total, want about 0.25", tt.name, ratio)
+		}
+	}
+	for bi, cnt := range sample.Bigrams {
+		if cnt > corpus.Bigrams[bi] {
+			t.Errorf("sampled count of %q is %d, above the full count %d", string(bi[:]), cnt, corpus.Bigrams[bi])
+		}
+	}
+	if len(sample.Words) != len(corpus.Words) || sample.Stream != corpus.Stream {
+		t.Error("words and stream should be shared with the full corpus")
+	}
+}
+
+func TestCorpusSplit(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 300))
+
+	folds := corpus.Split(3, NewLockedRNG(1, 0))
+	if len(folds) != 3 {
+		t.Fatalf("got %d folds, want 3", len(folds))
+	}
+	var unigrams, bigrams, words uint64
+	for _, fold := range folds {
+		unigrams += fold.TotalUnigramsCount
+		bigrams += fold.TotalBigramsCount
+		words += fold.TotalWordsCount
+		if ratio := float64(fold.TotalBigramsCount) / float64(corpus.TotalBigramsCount); math.Abs(ratio-1.0/3) > 0.03 {
+			t.Errorf("%s has %.3f of the bigrams, want about 1/3", fold.Name, ratio)
+		}
+	}
+	if unigrams != corpus.TotalUnigramsCount || bigrams != corpus.TotalBigramsCount || words != corpus.TotalWordsCount {
+		t.Errorf("fold totals %d/%d/%d do not add up to the corpus totals %d/%d/%d", unigrams, bigrams, words,
+			corpus.TotalUnigramsCount, corpus.TotalBigramsCount, corpus.TotalWordsCount)
+	}
+	for bi, cnt := range corpus.Bigrams {
+		var sum uint64
+		for _, fold := range folds {
+			sum += fold.Bigrams[bi]
+		}
+		if sum != cnt {
+			t.Errorf("fold counts of %q add up to %d, want %d", string(bi[:]), sum, cnt)
+		}
+	}
+
+	again := corpus.Split(3, NewLockedRNG(1, 0))
+	for i := range folds {
+		if folds[i].TotalBigramsCount != again[i].TotalBigramsCount || folds[i].Stream != again[i].Stream {
+			t.Errorf("fold %d differs between splits with the same seed", i+1)
+		}
+	}
+}
+
+func TestScorer_Subsample(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	scorer := bls.scorer
+	exact := scorer.Score(layout)
+
+	// Without subsampling, Resample keeps the cached scores
+	scorer.Resample()
+	if _, ok := scorer.scoreCache.get(layoutCacheKey(layout)); !ok {
+		t.Error("Resample without subsampling should keep the cached scores")
+	}
+
+	for _, fraction := range []float64{-0.1, 1.5, math.NaN()} {
+		if err := scorer.SetSubsample(fraction, 1); err == nil {
+			t.Errorf("SetSubsample(%g) should fail", fraction)
+		}
+	}
+
+	if err := scorer.SetSubsample(0.5, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !scorer.Subsampled() || scorer.sample == nil {
+		t.Fatal("scorer should score against a corpus sample")
+	}
+	if got, want := scorer.Score(layout), scorer.scoreAgainst(layout, scorer.sample, scorer.sampleTrigrams); got != want {
+		t.Errorf("Score = %v, want the cost against the sample %v", got, want)
+	}
+	if got := scorer.ScoreExact(layout); math.Abs(got-exact) > 1e-9 {
+		t.Errorf("ScoreExact = %v, want the full-corpus cost %v", got, exact)
+	}
+
+	first := scorer.sample
+	scorer.Score(layout)
+	scorer.Resample()
+	if scorer.sample == first {
+		t.Error("Resample should draw a new sample")
+	}
+	if _, ok := scorer.scoreCache.get(layoutCacheKey(layout)); ok {
+		t.Error("Resample should forget the scores against the previous sample")
+	}
+
+	if err := scorer.SetSubsample(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if scorer.Subsampled() || scorer.sample != nil {
+		t.Error("a fraction of 0 should disable subsampling")
+	}
+	if got := scorer.Score(layout); math.Abs(got-exact) > 1e-9 {
+		t.Errorf("Score without subsampling = %v, want %v", got, exact)
+	}
+}
+
+// TestOptimize_SubsampleKeepsBestCostExact checks that the best cost found while scoring
+// against corpus samples is the cost of the best layout against the full corpus.
+func TestOptimize_SubsampleKeepsBestCostExact(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 10
+	if err := bls.scorer.SetSubsample(0.5, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	best := bls.Optimize(context.Background(), layout, nil)
+
+	exact := bls.scorer.ScoreExact(best)
+	if math.Abs(bls.state.bestCost-exact) > 1e-9 {
+		t.Errorf("best cost %v, want the full-corpus cost of the best layout %v", bls.state.bestCost, exact)
+	}
+	if initial := bls.scorer.ScoreExact(layout); bls.state.bestCost > initial+1e-9 {
+		t.Errorf("best cost %v is worse than the initial cost %v", bls.state.bestCost, initial)
+	}
+}
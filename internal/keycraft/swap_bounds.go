@@ -0,0 +1,141 @@
+package keycraft
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// trigramMetrics are the metrics computed from the trigram classes by setTrigramMetrics, and
+// ROLLQ, to which every trigram adds at most its share as well.
+var trigramMetrics = func() []string {
+	an := &Analyser{Corpus: &Corpus{TotalTrigramsCount: 1}, Metrics: make(map[string]float64)}
+	an.setTrigramMetrics()
+	an.Metrics["ROLLQ"] = 0
+	return slices.Sorted(maps.Keys(an.Metrics))
+}()
+
+// SwapBounds bounds the trigram metrics of the layouts one swap away from a base layout.
+//
+// Swapping keys a and b only changes the class of trigrams typed with key a or key b, so
+// each trigram metric, being the percentage of trigrams in one or more classes, changes by
+// at most the percentage of trigrams typed with either key. ScoreSwap uses this to rule out
+// a swap from its cheap metrics alone, before analysing the trigrams.
+type SwapBounds struct {
+	metrics map[string]float64 // Metrics of the base layout
+	shares  [42]float64        // Percentage of trigrams typed with each key of the base layout
+}
+
+// NewSwapBounds analyses the base layout for bounding the costs of its swaps. It returns nil
+// if the costs cannot be bounded: for a multi-corpus scorer, and when the scorer weighs a
+// metric of the word list or stream, which a swap can change without a known limit.
+//
+// The bounds are only valid for the current sample of a subsampling scorer.
+func (sc *Scorer) NewSwapBounds(layout *SplitLayout) *SwapBounds {
+	if len(sc.parts) > 0 || sc.weighs(HandRunMetrics...) || sc.weighs("FATIGUE") {
+		return nil
+	}
+	sc.prepareNGramCaches(layout)
+
+	corpus, trigrams := sc.scoringCorpus()
+	an := sc.analyseCheap(layout, corpus, trigrams)
+	an.analyseTrigrams()
+	if sc.weighs("ROLLQ") {
+		an.analyseRollQuality()
+	}
+
+	b := &SwapBounds{metrics: an.Metrics}
+	factor := 100 / float64(an.Corpus.TotalTrigramsCount)
+	for _, ti := range an.trigrams() {
+		k0, _ := layout.GetKeyInfo(ti.Runes[0])
+		k1, _ := layout.GetKeyInfo(ti.Runes[1])
+		k2, _ := layout.GetKeyInfo(ti.Runes[2])
+		share := float64(ti.Count) * factor
+		// Count each key of a trigram once
+		b.shares[k0.Index] += share
+		if k1.Index != k0.Index {
+			b.shares[k1.Index] += share
+		}
+		if k2.Index != k0.Index && k2.Index != k1.Index {
+			b.shares[k2.Index] += share
+		}
+	}
+	return b
+}
+
+// trigramRange returns the range of a trigram metric after a swap that changes the class of
+// at most share percent of the trigrams.
+func (b *SwapBounds) trigramRange(metric string, share float64) (lo, hi float64) {
+	if metric == "IN:OUT" {
+		in := b.metrics["2RL-IN"] + b.metrics["3RL-IN"]
+		out := b.metrics["2RL-OUT"] + b.metrics["3RL-OUT"]
+		if out <= share {
+			return 0, math.Inf(1)
+		}
+		return max(in-share, 0) / (out + share), (in + share) / (out - share)
+	}
+	v := b.metrics[metric]
+	if metric == "RED-SEV" {
+		// Each redirection counts up to its severity
+		return max(v-maxRedirectSeverity*share, 0), v + maxRedirectSeverity*share
+	}
+	return max(v-share, 0), min(v+share, 100)
+}
+
+// trigramCostBound returns the lowest possible cost of the weighted trigram metrics after
+// swapping keys i and j of the base layout.
+func (sc *Scorer) trigramCostBound(b *SwapBounds, i, j uint8) float64 {
+	share := b.shares[i] + b.shares[j]
+	bound := 0.0
+	for _, metric := range trigramMetrics {
+		iqr, ok := sc.iqrs[metric]
+		if !ok {
+			continue
+		}
+		weight := sc.weights[metric]
+		lo, hi := b.trigramRange(metric, share)
+		best := lo // Value with the lowest cost
+		if weight > 0 {
+			best = hi
+		}
+		bound -= weight * (best - sc.medians[metric]) / iqr
+	}
+	return bound
+}
+
+// ScoreSwap scores a layout that is the base layout of the bounds with keys i and j swapped,
+// like Score, but evaluates the metrics progressively: when the cost of the cheap metrics
+// plus the lowest possible cost of the trigram metrics is not below limit, it returns that
+// lower bound and false without analysing the trigrams. Otherwise it returns the cost and
+// true. With nil bounds, it returns the result of Score.
+func (sc *Scorer) ScoreSwap(layout *SplitLayout, bounds *SwapBounds, i, j uint8, limit float64) (float64, bool) {
+	if bounds == nil {
+		return sc.Score(layout), true
+	}
+
+	var cacheKey string
+	if !sc.DisableScoreCache {
+		cacheKey = layoutCacheKey(layout)
+		if cachedScore, exists := sc.scoreCache.get(cacheKey); exists {
+			sc.cacheHits.Add(1)
+			return cachedScore, true
+		}
+		sc.cacheMisses.Add(1)
+	}
+
+	corpus, trigrams := sc.scoringCorpus()
+	an := sc.analyseCheap(layout, corpus, trigrams)
+	penalty := sc.penalty(layout)
+	// Allow for rounding, so that ties with the limit are still scored in full
+	if bound := sc.cost(an.Metrics) + penalty + sc.trigramCostBound(bounds, i, j); bound >= limit+1e-9 {
+		sc.cutShort.Add(1)
+		return bound, false
+	}
+
+	sc.analyseCostly(an)
+	score := sc.cost(an.Metrics) + penalty
+	if !sc.DisableScoreCache {
+		sc.scoreCache.put(cacheKey, score)
+	}
+	return score, true
+}
@@ -0,0 +1,119 @@
+package keycraft
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// newBoundsTestScorer creates a scorer that weighs all trigram metrics, half of them
+// positively, and SFB.
+func newBoundsTestScorer(corpus *Corpus) *Scorer {
+	medians := map[string]float64{"SFB": 1}
+	iqrs := map[string]float64{"SFB": 1}
+	weights := map[string]float64{"SFB": -1}
+	for i, metric := range trigramMetrics {
+		medians[metric] = 10
+		iqrs[metric] = 5
+		weights[metric] = float64(1 - 2*(i%2))
+	}
+	sc := NewScorerWithStats(corpus, NewTargetLoads(), medians, iqrs, weights)
+	sc.DisableScoreCache = true
+	return sc
+}
+
+func TestTrigramMetrics(t *testing.T) {
+	for _, metric := range []string{"ALT", "RED-WEAK", "3RL-IN", "FLW", "IN:OUT"} {
+		if !slices.Contains(trigramMetrics, metric) {
+			t.Errorf("trigram metrics %v lack %s", trigramMetrics, metric)
+		}
+	}
+	if slices.Contains(trigramMetrics, "SFB") {
+		t.Error("SFB is not a trigram metric")
+	}
+}
+
+// TestScoreSwap_LowerBound checks that ScoreSwap returns the exact cost of every swap unless
+// it rules the swap out, and that the bound of a ruled out swap is between the limit and the
+// exact cost.
+func TestScoreSwap_LowerBound(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	sc := newBoundsTestScorer(bls.corpus)
+
+	base := sc.Score(layout)
+	bounds := sc.NewSwapBounds(layout)
+	if bounds == nil {
+		t.Fatal("expected bounds for a scorer without word list or stream metrics")
+	}
+
+	cutShort := 0
+	for _, pair := range bls.validPairs {
+		i, j := pair[0], pair[1]
+		layout.Swap(i, j)
+		exact := sc.Score(layout)
+		for _, limit := range []float64{math.Inf(-1), base - 1, base, base + 1, math.Inf(1)} {
+			got, complete := sc.ScoreSwap(layout, bounds, i, j, limit)
+			switch {
+			case complete && math.Abs(got-exact) > 1e-9:
+				t.Errorf("swap %d-%d, limit %v: cost %v, want %v", i, j, limit, got, exact)
+			case !complete && (got < limit || got > exact+1e-9):
+				t.Errorf("swap %d-%d, limit %v: bound %v should be between the limit and %v",
+					i, j, limit, got, exact)
+			case !complete:
+				cutShort++
+			}
+		}
+		layout.Swap(i, j)
+	}
+	if cutShort == 0 {
+		t.Error("no swap was ruled out early")
+	}
+	if got := sc.GetStats().CutShort; got != int64(cutShort) {
+		t.Errorf("stats count %d swaps cut short, want %d", got, cutShort)
+	}
+}
+
+func TestNewSwapBounds_UnboundedMetrics(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	sc := newBoundsTestScorer(bls.corpus)
+	sc.medians["FATIGUE"], sc.iqrs["FATIGUE"], sc.weights["FATIGUE"] = 1, 1, -1
+	if sc.NewSwapBounds(layout) != nil {
+		t.Error("costs of a scorer that weighs FATIGUE cannot be bounded")
+	}
+	if got, complete := sc.ScoreSwap(layout, nil, 0, 1, math.Inf(-1)); !complete || math.Abs(got-sc.Score(layout)) > 1e-9 {
+		t.Errorf("ScoreSwap without bounds = %v, %v, want the result of Score", got, complete)
+	}
+}
+
+// TestSteepestDescent_SwapBounds checks that ruling out swaps early finds a swap as good as
+// the best swap, and that descent still ends in a local optimum.
+func TestSteepestDescent_SwapBounds(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.scorer = newBoundsTestScorer(bls.corpus)
+	bls.swapCache = nil
+
+	costBefore := bls.scorer.Score(layout)
+	bounds := bls.swapBounds(layout)
+	bestDelta, boundedDelta := 0.0, 0.0
+	for _, pair := range bls.validPairs {
+		bestDelta = min(bestDelta, bls.swapDelta(layout, pair[0], pair[1], costBefore))
+		boundedDelta = min(boundedDelta, bls.boundedSwapDelta(layout, bounds, pair[0], pair[1], costBefore, boundedDelta))
+	}
+	if bestDelta >= 0 {
+		t.Fatal("expected an improving swap")
+	}
+	if math.Abs(boundedDelta-bestDelta) > 1e-9 {
+		t.Errorf("best delta with swap bounds %v, want %v", boundedDelta, bestDelta)
+	}
+	if bls.scorer.GetStats().CutShort == 0 {
+		t.Error("no swap was ruled out early")
+	}
+
+	bls.steepestDescent(layout)
+	cost := bls.scorer.Score(layout)
+	for _, pair := range bls.validPairs {
+		if delta := bls.swapDelta(layout, pair[0], pair[1], cost); delta < -1e-9 {
+			t.Errorf("swap %d-%d improves the local optimum by %v", pair[0], pair[1], -delta)
+		}
+	}
+}
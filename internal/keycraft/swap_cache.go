@@ -0,0 +1,68 @@
+package keycraft
+
+import "sync/atomic"
+
+// SwapCache stores per-pair cost deltas computed during steepest descent so they
+// can be reused across descent rounds.
+//
+// After a swap (a, b) is applied, only deltas of pairs that involve key a or key b
+// are invalidated. Deltas of all other pairs are assumed to be unchanged, which
+// avoids re-scoring the majority of the ~800 candidate swaps in every round.
+// Because this assumption is an approximation, the descent always re-verifies a
+// cached best swap before applying it, and performs a fresh evaluation round
+// before declaring a local optimum.
+//
+// Get and Put may be called concurrently for distinct pairs; Invalidate and Reset
+// must not run concurrently with other calls.
+type SwapCache struct {
+	deltas [42][42]float64
+	valid  [42][42]bool
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewSwapCache returns an empty SwapCache.
+func NewSwapCache() *SwapCache {
+	return &SwapCache{}
+}
+
+// Get returns the cached delta for swapping keys i and j, and whether it was present.
+func (c *SwapCache) Get(i, j uint8) (float64, bool) {
+	if i > j {
+		i, j = j, i
+	}
+	if !c.valid[i][j] {
+		c.misses.Add(1)
+		return 0, false
+	}
+	c.hits.Add(1)
+	return c.deltas[i][j], true
+}
+
+// Put stores the delta for swapping keys i and j.
+func (c *SwapCache) Put(i, j uint8, delta float64) {
+	if i > j {
+		i, j = j, i
+	}
+	c.deltas[i][j] = delta
+	c.valid[i][j] = true
+}
+
+// Invalidate drops all cached deltas for pairs that involve key a or key b.
+func (c *SwapCache) Invalidate(a, b uint8) {
+	for k := range uint8(42) {
+		c.valid[min(a, k)][max(a, k)] = false
+		c.valid[min(b, k)][max(b, k)] = false
+	}
+}
+
+// Reset drops all cached deltas. Hit and miss counters are preserved.
+func (c *SwapCache) Reset() {
+	c.valid = [42][42]bool{}
+}
+
+// Stats returns the number of cache hits and misses since the cache was created.
+func (c *SwapCache) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
@@ -0,0 +1,119 @@
+package keycraft
+
+import "testing"
+
+func TestSwapCache_GetPut(t *testing.T) {
+	c := NewSwapCache()
+
+	if _, ok := c.Get(3, 7); ok {
+		t.Fatal("expected empty cache to miss")
+	}
+
+	c.Put(7, 3, -1.5)
+	if d, ok := c.Get(3, 7); !ok || d != -1.5 {
+		t.Errorf("Get(3, 7) = %v, %v; want -1.5, true", d, ok)
+	}
+	if d, ok := c.Get(7, 3); !ok || d != -1.5 {
+		t.Errorf("Get(7, 3) = %v, %v; want -1.5, true", d, ok)
+	}
+
+	hits, misses := c.Stats()
+	if hits != 2 || misses != 1 {
+		t.Errorf("Stats() = %d, %d; want 2, 1", hits, misses)
+	}
+}
+
+func TestSwapCache_Invalidate(t *testing.T) {
+	c := NewSwapCache()
+	c.Put(1, 2, 1)
+	c.Put(2, 5, 1)
+	c.Put(5, 9, 1)
+	c.Put(9, 10, 1)
+
+	c.Invalidate(2, 9)
+
+	if _, ok := c.Get(1, 2); ok {
+		t.Error("pair (1, 2) touches key 2 and should be invalidated")
+	}
+	if _, ok := c.Get(2, 5); ok {
+		t.Error("pair (2, 5) touches key 2 and should be invalidated")
+	}
+	if _, ok := c.Get(5, 9); ok {
+		t.Error("pair (5, 9) touches key 9 and should be invalidated")
+	}
+	if _, ok := c.Get(9, 10); ok {
+		t.Error("pair (9, 10) touches key 9 and should be invalidated")
+	}
+
+	c.Put(3, 4, 1)
+	c.Invalidate(0, 41)
+	if _, ok := c.Get(3, 4); !ok {
+		t.Error("pair (3, 4) does not touch keys 0 or 41 and should be kept")
+	}
+
+	c.Reset()
+	if _, ok := c.Get(3, 4); ok {
+		t.Error("Reset should drop all entries")
+	}
+}
+
+// TestSteepestDescentSwapCache verifies that descent with the swap cache still ends
+// in a true local optimum and uses fewer fresh evaluations than the number of pairs
+// per round would suggest.
+func TestSteepestDescentSwapCache(t *testing.T) {
+	corpus, err := NewCorpusFromFile("default", "../../data/corpus/default.txt", false, 0)
+	if err != nil {
+		t.Skipf("Skipping test - corpus not available: %v", err)
+	}
+
+	layout, err := NewLayoutFromFile("qwerty", "../../data/layouts/qwerty.klf")
+	if err != nil {
+		t.Skipf("Skipping test - layout not available: %v", err)
+	}
+
+	pinned := &PinnedKeys{}
+	numFree := 0
+	for i, r := range layout.Runes {
+		if r == 0 || r == ' ' {
+			pinned[i] = true
+		} else {
+			numFree++
+		}
+	}
+
+	scorer, err := NewScorer("../../data/layouts", corpus, &TargetLoads{TargetRowLoad: DefaultTargetRowLoad(), TargetFingerLoad: DefaultTargetFingerLoad(), TargetHandLoad: DefaultTargetHandLoad(), PinkyPenalties: DefaultPinkyPenalties()}, NewWeights(), nil)
+	if err != nil {
+		t.Skipf("Skipping test - layouts not available: %v", err)
+	}
+
+	params := DefaultBLSParams(numFree)
+	params.Seed = 42
+	params.UseParallel = false
+	params.UseSwapCache = true
+
+	bls := NewBLS(params, scorer, corpus, pinned)
+	bls.state = BLSState{tabuMatrix: make([][]int, 42)}
+	for i := range bls.state.tabuMatrix {
+		bls.state.tabuMatrix[i] = make([]int, 42)
+	}
+
+	optimum := layout.Clone()
+	bls.steepestDescentSequential(optimum)
+
+	// No single swap may improve the resulting layout
+	cost := scorer.Score(optimum)
+	for _, pair := range bls.validPairs {
+		optimum.Swap(pair[0], pair[1])
+		after := scorer.Score(optimum)
+		optimum.Swap(pair[0], pair[1])
+		if after < cost-1e-12 {
+			t.Fatalf("swap (%d, %d) improves cost %.6f -> %.6f; not a local optimum",
+				pair[0], pair[1], cost, after)
+		}
+	}
+
+	hits, misses := bls.SwapCacheStats()
+	if hits == 0 {
+		t.Errorf("expected cached deltas to be reused, got %d hits and %d misses", hits, misses)
+	}
+}
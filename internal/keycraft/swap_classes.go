@@ -0,0 +1,99 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// RuneClass is the class of the character on a key, for swapping keys only with keys of
+// the same class during optimization (see SwapClasses).
+type RuneClass uint8
+
+const (
+	OtherClass  RuneClass = iota // Empty keys, space, digits and other characters
+	LetterClass                  // Letters
+	PunctClass                   // Punctuation and symbols
+)
+
+// RuneClassNames are the names of the rune classes, as given to --swap-classes.
+var RuneClassNames = map[RuneClass]string{
+	LetterClass: "letters",
+	PunctClass:  "punct",
+}
+
+// RuneClassOf returns the class of a character.
+func RuneClassOf(r rune) RuneClass {
+	switch {
+	case unicode.IsLetter(r):
+		return LetterClass
+	case unicode.IsPunct(r) || unicode.IsSymbol(r):
+		return PunctClass
+	}
+	return OtherClass
+}
+
+// SwapClasses is the set of rune classes whose keys the optimiser may move. Each key is
+// only swapped with keys of the same class, so letters stay on letter positions and
+// punctuation on punctuation positions. Keys of other classes are pinned.
+type SwapClasses [3]bool
+
+// ParseSwapClasses parses a --swap-classes specification: "all" (nil, swaps are not
+// restricted), or one or more comma-separated classes: "letters" and "punct".
+func ParseSwapClasses(spec string) (*SwapClasses, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	if spec == "" || spec == "all" {
+		return nil, nil
+	}
+
+	classes := &SwapClasses{}
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		found := false
+		for class, name := range RuneClassNames {
+			if part == name {
+				classes[class] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid swap class %q; must be letters, punct or all", part)
+		}
+	}
+	return classes, nil
+}
+
+// PinOtherClasses pins every key of the layout whose character is not of one of the
+// classes, so only keys of the classes can be swapped. Other keys keep their pinned state.
+func (p *PinnedKeys) PinOtherClasses(classes *SwapClasses, sl *SplitLayout) {
+	for i, r := range sl.Runes {
+		if !classes[RuneClassOf(r)] {
+			p[i] = true
+		}
+	}
+}
+
+// RestrictSwapClasses makes the search swap keys only with keys of the same rune class as
+// on the layout, such as letters with letters. Every move preserves the classes of the
+// positions, so they are those of the layout throughout the search.
+func (bls *BLS) RestrictSwapClasses(layout *SplitLayout) {
+	classes := &[42]RuneClass{}
+	for i, r := range layout.Runes {
+		classes[i] = RuneClassOf(r)
+	}
+	bls.classes = classes
+
+	pairs := bls.validPairs[:0]
+	for _, pair := range bls.validPairs {
+		if bls.sameClass(pair[0], pair[1]) {
+			pairs = append(pairs, pair)
+		}
+	}
+	bls.validPairs = pairs
+}
+
+// sameClass reports whether the keys at positions i and j may be swapped with respect to
+// their rune classes, which is always the case if swaps are not restricted.
+func (bls *BLS) sameClass(i, j uint8) bool {
+	return bls.classes == nil || bls.classes[i] == bls.classes[j]
+}
@@ -0,0 +1,92 @@
+package keycraft
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseSwapClasses(t *testing.T) {
+	for _, spec := range []string{"", "all", " ALL "} {
+		if classes, err := ParseSwapClasses(spec); err != nil || classes != nil {
+			t.Errorf("%q: got %v, %v, want nil", spec, classes, err)
+		}
+	}
+
+	tests := map[string]SwapClasses{
+		"letters":        {LetterClass: true},
+		"punct":          {PunctClass: true},
+		"Letters, punct": {LetterClass: true, PunctClass: true},
+	}
+	for spec, want := range tests {
+		classes, err := ParseSwapClasses(spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+			continue
+		}
+		if *classes != want {
+			t.Errorf("%q: got %v, want %v", spec, *classes, want)
+		}
+	}
+
+	for _, bad := range []string{"digits", "letters,", "letters,all"} {
+		if _, err := ParseSwapClasses(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestRuneClassOf(t *testing.T) {
+	tests := map[rune]RuneClass{
+		'a': LetterClass, 'Z': LetterClass, 'é': LetterClass,
+		',': PunctClass, '\'': PunctClass, '/': PunctClass, '+': PunctClass,
+		0: OtherClass, ' ': OtherClass, '1': OtherClass,
+	}
+	for r, want := range tests {
+		if got := RuneClassOf(r); got != want {
+			t.Errorf("RuneClassOf(%q) = %d, want %d", r, got, want)
+		}
+	}
+}
+
+func TestPinnedKeys_PinOtherClasses(t *testing.T) {
+	layout := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	pinned := &PinnedKeys{}
+	pinned.PinOtherClasses(Must(ParseSwapClasses("punct")), layout)
+	for i, r := range layout.Runes {
+		if want := RuneClassOf(r) != PunctClass; pinned[i] != want {
+			t.Errorf("key %d (%q): pinned = %v, want %v", i, r, pinned[i], want)
+		}
+	}
+}
+
+func TestBLS_RestrictSwapClasses(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	original := layout.Clone()
+	bls.RestrictSwapClasses(layout)
+	for _, pair := range bls.validPairs {
+		if RuneClassOf(layout.Runes[pair[0]]) != RuneClassOf(layout.Runes[pair[1]]) {
+			t.Fatalf("pair %v swaps %q with %q", pair, layout.Runes[pair[0]], layout.Runes[pair[1]])
+		}
+	}
+
+	assertClasses := func(moved *SplitLayout) {
+		t.Helper()
+		assertPermutation(t, bls.pinned, original, moved)
+		for i := range 42 {
+			if RuneClassOf(moved.Runes[i]) != RuneClassOf(original.Runes[i]) {
+				t.Fatalf("key %d changed from %q to %q", i, original.Runes[i], moved.Runes[i])
+			}
+		}
+	}
+
+	for range 100 {
+		bls.applyRandomCycle(layout)
+		bls.applyRotation(layout)
+		bls.applyColumnSwap(layout)
+		assertClasses(layout)
+	}
+
+	bls.params.EnableCompoundMoves()
+	bls.params.MaxIterations = 20
+	assertClasses(bls.Optimize(context.Background(), original, nil))
+}
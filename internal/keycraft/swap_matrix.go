@@ -0,0 +1,111 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SwapMatrixInput contains parameters for computing the impact of every single swap.
+type SwapMatrixInput struct {
+	Layout     *SplitLayout
+	LayoutsDir string // Used to load reference layouts for normalisation
+	Corpus     *Corpus
+	Targets    *TargetLoads
+	Weights    *Weights
+	Reference  *ReferenceSet // Layouts used for normalisation stats (nil = default naming rule)
+}
+
+// SwapMatrix holds the score change of every possible swap of two key positions.
+// Deltas are score gains: positive values improve the layout and negative values
+// make it worse, on the same scale as the scorer used by the optimiser.
+type SwapMatrix struct {
+	Layout *SplitLayout
+	Cost   float64         // Scorer cost of the unmodified layout (lower is better)
+	Delta  [42][42]float64 // Score gain of swapping keys i and j (symmetric)
+	Valid  [42][42]bool    // False on the diagonal and for swaps involving an empty key
+	sorted []SwapImpact    // All valid swaps (i < j), best first
+}
+
+// SwapImpact describes the effect of swapping two key positions.
+type SwapImpact struct {
+	Idx1, Idx2   uint8
+	Rune1, Rune2 rune    // Runes at the positions before the swap
+	Delta        float64 // Score gain (positive = better)
+}
+
+// ComputeSwapMatrix scores the layout once for every pair of used key positions with
+// their runes swapped. Empty keys are skipped, as SplitLayout.Swap only exchanges
+// runes between used keys (layout pattern caches depend on which keys are used).
+func ComputeSwapMatrix(input SwapMatrixInput) (*SwapMatrix, error) {
+	if input.Layout == nil {
+		return nil, fmt.Errorf("no layout given")
+	}
+
+	scorer, err := NewScorer(input.LayoutsDir, input.Corpus, input.Targets, input.Weights, input.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("could not create scorer: %w", err)
+	}
+	scorer.DisableScoreCache = true // every swapped layout is scored exactly once
+
+	layout := input.Layout.Clone()
+	m := &SwapMatrix{Layout: input.Layout, Cost: scorer.Score(layout)}
+
+	for i := uint8(0); i < 42; i++ {
+		for j := i + 1; j < 42; j++ {
+			if layout.Runes[i] == 0 || layout.Runes[j] == 0 {
+				continue
+			}
+			layout.Swap(i, j)
+			delta := m.Cost - scorer.Score(layout)
+			layout.Swap(i, j)
+
+			m.Delta[i][j], m.Delta[j][i] = delta, delta
+			m.Valid[i][j], m.Valid[j][i] = true, true
+			m.sorted = append(m.sorted, SwapImpact{
+				Idx1: i, Idx2: j,
+				Rune1: layout.Runes[i], Rune2: layout.Runes[j],
+				Delta: delta,
+			})
+		}
+	}
+
+	sort.SliceStable(m.sorted, func(a, b int) bool { return m.sorted[a].Delta > m.sorted[b].Delta })
+	return m, nil
+}
+
+// Best returns up to n swaps with the highest score gain, best first.
+// Only swaps that improve the layout are returned.
+func (m *SwapMatrix) Best(n int) []SwapImpact {
+	var best []SwapImpact
+	for _, s := range m.sorted {
+		if len(best) >= n || s.Delta <= 0 {
+			break
+		}
+		best = append(best, s)
+	}
+	return best
+}
+
+// Worst returns up to n swaps with the lowest score gain, worst first.
+// Only swaps that make the layout worse are returned.
+func (m *SwapMatrix) Worst(n int) []SwapImpact {
+	var worst []SwapImpact
+	for k := len(m.sorted) - 1; k >= 0; k-- {
+		s := m.sorted[k]
+		if len(worst) >= n || s.Delta >= 0 {
+			break
+		}
+		worst = append(worst, s)
+	}
+	return worst
+}
+
+// MaxAbsDelta returns the largest absolute score gain in the matrix, for scaling heat maps.
+func (m *SwapMatrix) MaxAbsDelta() float64 {
+	var maxAbs float64
+	for _, s := range m.sorted {
+		maxAbs = math.Max(maxAbs, math.Abs(s.Delta))
+	}
+	return maxAbs
+}
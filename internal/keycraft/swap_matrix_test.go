@@ -0,0 +1,79 @@
+package keycraft
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestComputeSwapMatrix(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{
+		"a.klf":   testLayoutKlf,
+		"c.klf":   testLayoutVariantKlf,
+		"far.klf": testLayoutFarKlf,
+	})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+
+	m, err := ComputeSwapMatrix(SwapMatrixInput{
+		Layout:     layout,
+		LayoutsDir: dir,
+		Corpus:     corpus,
+		Targets:    NewTargetLoads(),
+		Weights:    NewWeights(),
+	})
+	if err != nil {
+		t.Fatalf("ComputeSwapMatrix failed: %v", err)
+	}
+
+	scorer := Must(NewScorer(dir, corpus, NewTargetLoads(), NewWeights(), nil))
+	if math.Abs(m.Cost-scorer.Score(layout)) > 1e-9 {
+		t.Errorf("matrix cost %.6f differs from scorer cost %.6f", m.Cost, scorer.Score(layout))
+	}
+
+	for i := range 42 {
+		if m.Valid[i][i] {
+			t.Errorf("diagonal entry %d should not be valid", i)
+		}
+		for j := range 42 {
+			empty := layout.Runes[i] == 0 || layout.Runes[j] == 0
+			if empty && m.Valid[i][j] {
+				t.Errorf("swap %d-%d involves an empty key but is valid", i, j)
+			}
+			if m.Delta[i][j] != m.Delta[j][i] {
+				t.Errorf("matrix not symmetric at %d-%d", i, j)
+			}
+		}
+	}
+
+	// A spot check: the gain of swapping z and x equals the cost difference of the variant layout
+	z, _ := layout.GetKeyInfo('z')
+	x, _ := layout.GetKeyInfo('x')
+	variant := Must(NewLayoutFromFile("c", filepath.Join(dir, "c.klf")))
+	want := scorer.Score(layout) - scorer.Score(variant)
+	if got := m.Delta[z.Index][x.Index]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("z<->x gain %.6f, want %.6f", got, want)
+	}
+
+	// The original layout must be left untouched
+	if layout.Runes != Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf"))).Runes {
+		t.Error("ComputeSwapMatrix modified the input layout")
+	}
+
+	best, worst := m.Best(5), m.Worst(5)
+	for i, s := range best {
+		if s.Delta <= 0 || (i > 0 && s.Delta > best[i-1].Delta) {
+			t.Errorf("best swaps not positive and descending at %d: %v", i, best)
+		}
+	}
+	for i, s := range worst {
+		if s.Delta >= 0 || (i > 0 && s.Delta < worst[i-1].Delta) {
+			t.Errorf("worst swaps not negative and ascending at %d: %v", i, worst)
+		}
+	}
+	if len(best)+len(worst) == 0 {
+		t.Error("expected some beneficial or harmful swaps")
+	}
+}
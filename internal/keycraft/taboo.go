@@ -0,0 +1,175 @@
+package keycraft
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tabooPenalty is the cost of every taboo bigram that a layout types as an SFB or scissor.
+// Like changeLimitPenalty, it outweighs any metric, so that the optimizer never keeps such a
+// layout as best.
+const tabooPenalty = 1000.0
+
+// TabooMetric is the name of the cost of taboo bigrams in MetricCosts.
+const TabooMetric = "TABOO"
+
+// TabooPatterns are bigrams and trigrams that an optimized layout must not type as
+// same-finger bigrams or scissors, such as "th" or "ing". A trigram is taboo if either of
+// its bigrams is, so "ing" forbids "in" and "ng".
+type TabooPatterns struct {
+	Patterns []string       // The patterns as declared, lowercased
+	bigrams  []Bigram       // The bigrams of the patterns, without duplicates
+	byRune   map[rune][]int // Indices in bigrams of the bigrams with a rune
+}
+
+// ParseTabooPatterns parses a list of bigrams and trigrams separated by commas or
+// whitespace, such as "th,ing". Patterns are lowercased, as the corpus is by default.
+func ParseTabooPatterns(spec string) (*TabooPatterns, error) {
+	tp := &TabooPatterns{byRune: make(map[rune][]int)}
+	seen := make(map[Bigram]bool)
+	fields := strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	for _, pattern := range fields {
+		if n := utf8.RuneCountInString(pattern); n < 2 || n > 3 {
+			return nil, fmt.Errorf("invalid taboo pattern %q: must be a bigram or trigram", pattern)
+		}
+		tp.Patterns = append(tp.Patterns, pattern)
+		runes := []rune(pattern)
+		for k := 0; k+1 < len(runes); k++ {
+			bi := Bigram{runes[k], runes[k+1]}
+			if bi[0] == bi[1] || seen[bi] {
+				continue // A repeated key is neither an SFB nor a scissor
+			}
+			seen[bi] = true
+			tp.byRune[bi[0]] = append(tp.byRune[bi[0]], len(tp.bigrams))
+			tp.byRune[bi[1]] = append(tp.byRune[bi[1]], len(tp.bigrams))
+			tp.bigrams = append(tp.bigrams, bi)
+		}
+	}
+	if len(tp.Patterns) == 0 {
+		return nil, fmt.Errorf("no taboo patterns in %q", spec)
+	}
+	return tp, nil
+}
+
+// String returns the patterns separated by commas, as read by ParseTabooPatterns.
+func (tp *TabooPatterns) String() string {
+	return strings.Join(tp.Patterns, ",")
+}
+
+// tabooCheck finds the taboo bigrams that layouts of one geometry type as SFBs or scissors.
+// The key pairs that do so depend on the positions only, so they are those of the layout
+// the check is made for, whatever the keys are swapped to.
+type tabooCheck struct {
+	patterns *TabooPatterns
+	keys     [42][42]bool // Whether a bigram typed with two keys is an SFB or scissor
+}
+
+// newTabooCheck returns a check of the taboo patterns for layouts of the geometry of layout.
+func newTabooCheck(tp *TabooPatterns, layout *SplitLayout) *tabooCheck {
+	tc := &tabooCheck{patterns: tp}
+	for i, r1 := range layout.Runes {
+		if r1 == 0 {
+			continue
+		}
+		for j, r2 := range layout.Runes {
+			if r2 == 0 || i == j {
+				continue
+			}
+			tc.keys[i][j] = layout.RuneInfo[r1].Finger == layout.RuneInfo[r2].Finger
+		}
+	}
+	for _, scissors := range [][]ScissorInfo{layout.FScissors, layout.HScissors} {
+		for _, s := range scissors {
+			tc.keys[s.keyIdx1][s.keyIdx2] = true
+		}
+	}
+	return tc
+}
+
+// positions returns the key positions of the runes of a bigram on the layout, and whether
+// the layout has both.
+func positions(layout *SplitLayout, bi Bigram) (uint8, uint8, bool) {
+	k1, ok1 := layout.GetKeyInfo(bi[0])
+	k2, ok2 := layout.GetKeyInfo(bi[1])
+	return k1.Index, k2.Index, ok1 && ok2
+}
+
+// violations returns the taboo bigrams that the layout types as SFBs or scissors.
+func (tc *tabooCheck) violations(layout *SplitLayout) []Bigram {
+	if tc == nil {
+		return nil
+	}
+	var violated []Bigram
+	for _, bi := range tc.patterns.bigrams {
+		if p1, p2, ok := positions(layout, bi); ok && tc.keys[p1][p2] {
+			violated = append(violated, bi)
+		}
+	}
+	return violated
+}
+
+// cost returns the taboo penalty of a layout.
+func (tc *tabooCheck) cost(layout *SplitLayout) float64 {
+	return tabooPenalty * float64(len(tc.violations(layout)))
+}
+
+// introduces reports whether swapping the keys at positions i and j makes the layout type a
+// taboo bigram as an SFB or scissor that it did not before. Only the bigrams of the two
+// swapped runes are checked, and the layout is not changed.
+func (tc *tabooCheck) introduces(layout *SplitLayout, i, j uint8) bool {
+	if tc == nil {
+		return false
+	}
+	swapped := func(p uint8) uint8 {
+		switch p {
+		case i:
+			return j
+		case j:
+			return i
+		}
+		return p
+	}
+	for _, r := range [2]rune{layout.Runes[i], layout.Runes[j]} {
+		for _, k := range tc.patterns.byRune[r] {
+			p1, p2, ok := positions(layout, tc.patterns.bigrams[k])
+			if ok && tc.keys[swapped(p1)][swapped(p2)] && !tc.keys[p1][p2] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetTabooPatterns makes the scorer penalise layouts that type a bigram of the taboo
+// patterns as an SFB or scissor, prohibitively for every such bigram, and makes the search
+// reject swaps that do. The SFB and scissor key pairs are those of layout, so scores then
+// only make sense for layouts of its type. Use nil to remove the patterns.
+//
+// SetTabooPatterns must not be called concurrently with other methods of the scorer.
+func (sc *Scorer) SetTabooPatterns(tp *TabooPatterns, layout *SplitLayout) {
+	sc.taboo = nil
+	if tp != nil {
+		sc.taboo = newTabooCheck(tp, layout)
+	}
+	sc.scoreCache.clear()
+}
+
+// TabooViolations returns the taboo bigrams that the layout types as SFBs or scissors, or
+// nil if the scorer has no taboo patterns.
+func (sc *Scorer) TabooViolations(layout *SplitLayout) []Bigram {
+	return sc.taboo.violations(layout)
+}
+
+// introducesTaboo reports whether swapping the keys at positions i and j makes the layout
+// type another taboo bigram as an SFB or scissor.
+func (sc *Scorer) introducesTaboo(layout *SplitLayout, i, j uint8) bool {
+	return sc.taboo.introduces(layout, i, j)
+}
+
+// penalty returns the costs of the layout that are not metrics: the change penalty and the
+// taboo penalty.
+func (sc *Scorer) penalty(layout *SplitLayout) float64 {
+	return sc.changes.cost(layout) + sc.taboo.cost(layout)
+}
@@ -0,0 +1,90 @@
+package keycraft
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTabooPatterns(t *testing.T) {
+	tp := Must(ParseTabooPatterns("TH, ing ll"))
+	if tp.String() != "th,ing,ll" {
+		t.Errorf("patterns %q, want th,ing,ll", tp.String())
+	}
+	want := []Bigram{{'t', 'h'}, {'i', 'n'}, {'n', 'g'}}
+	if len(tp.bigrams) != len(want) {
+		t.Fatalf("bigrams %v, want %v", tp.bigrams, want)
+	}
+	for i, bi := range want {
+		if tp.bigrams[i] != bi {
+			t.Errorf("bigram %d = %v, want %v", i, tp.bigrams[i], bi)
+		}
+	}
+
+	for _, spec := range []string{"", " , ", "t", "th,ings"} {
+		if _, err := ParseTabooPatterns(spec); err == nil {
+			t.Errorf("ParseTabooPatterns(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestTabooCheck(t *testing.T) {
+	layout := NewSplitLayout("test", ROWSTAG, qwertyRunes())
+	tc := newTabooCheck(Must(ParseTabooPatterns("ed,th")), layout)
+
+	if got := tc.violations(layout); len(got) != 1 || got[0] != (Bigram{'e', 'd'}) {
+		t.Errorf("violations %v, want ed", got)
+	}
+
+	key := func(r rune) uint8 { return layout.RuneInfo[r].Index }
+	if !tc.introduces(layout, key('h'), key('g')) {
+		t.Error("swapping h and g puts th on one finger, want it to introduce a taboo bigram")
+	}
+	if tc.introduces(layout, key('o'), key('p')) {
+		t.Error("swapping o and p introduces a taboo bigram, want none")
+	}
+	if tc.introduces(layout, key('e'), key('d')) {
+		t.Error("swapping e and d introduces a taboo bigram, want de to stay as it was")
+	}
+
+	var none *tabooCheck
+	if none.violations(layout) != nil || none.cost(layout) != 0 || none.introduces(layout, 0, 1) {
+		t.Error("nil check reports violations")
+	}
+}
+
+func TestScorer_SetTabooPatterns(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	sc := bls.scorer
+	before := sc.Score(layout)
+
+	sc.SetTabooPatterns(Must(ParseTabooPatterns("ed")), layout)
+	if got := sc.Score(layout) - before; got < tabooPenalty-1e-9 || got > tabooPenalty+1e-9 {
+		t.Errorf("score increased by %.3f, want the taboo penalty %.0f", got, tabooPenalty)
+	}
+	if got := sc.MetricCosts(layout)[TabooMetric]; got != tabooPenalty {
+		t.Errorf("%s cost %.3f, want %.0f", TabooMetric, got, tabooPenalty)
+	}
+
+	sc.SetTabooPatterns(nil, layout)
+	if got := sc.Score(layout); got != before {
+		t.Errorf("score %.6f after removing the patterns, want %.6f", got, before)
+	}
+	if _, ok := sc.MetricCosts(layout)[TabooMetric]; ok {
+		t.Errorf("metric costs include %s without taboo patterns", TabooMetric)
+	}
+}
+
+func TestOptimize_Taboo(t *testing.T) {
+	bls, layout := newTestMovesBLS(t)
+	bls.params.MaxIterations = 30
+	taboo := Must(ParseTabooPatterns("th,he,qu,ing,ov"))
+	bls.scorer.SetTabooPatterns(taboo, layout)
+	if got := bls.scorer.TabooViolations(layout); len(got) != 0 {
+		t.Fatalf("violations %v on the input layout, want none", got)
+	}
+
+	best := bls.Optimize(context.Background(), layout, nil)
+	if got := bls.scorer.TabooViolations(best); len(got) != 0 {
+		t.Errorf("best layout types %v as SFBs or scissors", got)
+	}
+}
@@ -16,6 +16,12 @@ func NewTargetLoads() *TargetLoads {
 		TargetFingerLoad: DefaultTargetFingerLoad(),
 		TargetRowLoad:    DefaultTargetRowLoad(),
 		PinkyPenalties:   DefaultPinkyPenalties(),
+		RingPenalties:    DefaultRingPenalties(),
+		MiddlePenalties:  DefaultMiddlePenalties(),
+		IndexPenalties:   DefaultIndexPenalties(),
+		RollQuality:      DefaultRollQuality(),
+		Fatigue:          DefaultFatigue(),
+		ComfortZones:     DefaultComfortZones(),
 	}
 }
 
@@ -64,6 +70,34 @@ func NewTargetLoadsFromFile(filePath string) (*TargetLoads, error) {
 			if err := targets.SetPinkyPenalties(value); err != nil {
 				return nil, fmt.Errorf("invalid pinky-penalties in config file: %w", err)
 			}
+		case "ring-penalties":
+			if err := targets.SetRingPenalties(value); err != nil {
+				return nil, fmt.Errorf("invalid ring-penalties in config file: %w", err)
+			}
+		case "middle-penalties":
+			if err := targets.SetMiddlePenalties(value); err != nil {
+				return nil, fmt.Errorf("invalid middle-penalties in config file: %w", err)
+			}
+		case "index-penalties":
+			if err := targets.SetIndexPenalties(value); err != nil {
+				return nil, fmt.Errorf("invalid index-penalties in config file: %w", err)
+			}
+		case "max-finger-load":
+			if err := targets.SetMaxFingerLoad(value); err != nil {
+				return nil, fmt.Errorf("invalid max-finger-load in config file: %w", err)
+			}
+		case "roll-quality":
+			if err := targets.SetRollQuality(value); err != nil {
+				return nil, fmt.Errorf("invalid roll-quality in config file: %w", err)
+			}
+		case "fatigue":
+			if err := targets.SetFatigue(value); err != nil {
+				return nil, fmt.Errorf("invalid fatigue in config file: %w", err)
+			}
+		case "comfort-zones":
+			if err := targets.SetComfortZones(value); err != nil {
+				return nil, fmt.Errorf("invalid comfort-zones in config file: %w", err)
+			}
 		}
 	}
 
@@ -84,6 +118,16 @@ func NewTargetLoadsFromFile(filePath string) (*TargetLoads, error) {
 	if targets.PinkyPenalties == nil {
 		targets.PinkyPenalties = DefaultPinkyPenalties()
 	}
+	targets.fillDefaultOffHomePenalties()
+	if targets.RollQuality == nil {
+		targets.RollQuality = DefaultRollQuality()
+	}
+	if targets.Fatigue == nil {
+		targets.Fatigue = DefaultFatigue()
+	}
+	if targets.ComfortZones == nil {
+		targets.ComfortZones = DefaultComfortZones()
+	}
 
 	return targets, nil
 }
@@ -96,6 +140,7 @@ func (tl *TargetLoads) SetHandLoad(s string) error {
 	if err != nil {
 		return fmt.Errorf("could not parse target hand load: %w", err)
 	}
+	tl.setGivenSum("target-hand-load", handLoad[:])
 	if err := scaleTargetHandLoad(handLoad); err != nil {
 		return fmt.Errorf("could not scale target hand load: %w", err)
 	}
@@ -111,6 +156,7 @@ func (tl *TargetLoads) SetFingerLoad(s string) error {
 	if err != nil {
 		return fmt.Errorf("could not parse finger load: %w", err)
 	}
+	tl.setGivenSum("target-finger-load", fingerLoad[:])
 	if err := scaleFingerLoad(fingerLoad); err != nil {
 		return fmt.Errorf("could not scale finger load: %w", err)
 	}
@@ -126,6 +172,7 @@ func (tl *TargetLoads) SetRowLoad(s string) error {
 	if err != nil {
 		return fmt.Errorf("could not parse row load: %w", err)
 	}
+	tl.setGivenSum("target-row-load", rowLoad[:])
 	if err := scaleRowLoad(rowLoad); err != nil {
 		return fmt.Errorf("could not scale row load: %w", err)
 	}
@@ -146,6 +193,43 @@ func (tl *TargetLoads) SetPinkyPenalties(s string) error {
 	return nil
 }
 
+// SetRingPenalties parses and sets the ring finger penalty weights from a string.
+// Accepts 3 values (mirrored for both hands) or 6 values (left then right).
+// Order per hand: top, home, bottom. Values are NOT scaled.
+func (tl *TargetLoads) SetRingPenalties(s string) error {
+	var ringPenalties [6]float64
+	if err := parsePenalties("ring-penalties", s, ringPenalties[:]); err != nil {
+		return fmt.Errorf("could not parse ring penalties: %w", err)
+	}
+	tl.RingPenalties = &ringPenalties
+	return nil
+}
+
+// SetMiddlePenalties parses and sets the middle finger penalty weights from a string.
+// Accepts 3 values (mirrored for both hands) or 6 values (left then right).
+// Order per hand: top, home, bottom. Values are NOT scaled.
+func (tl *TargetLoads) SetMiddlePenalties(s string) error {
+	var middlePenalties [6]float64
+	if err := parsePenalties("middle-penalties", s, middlePenalties[:]); err != nil {
+		return fmt.Errorf("could not parse middle penalties: %w", err)
+	}
+	tl.MiddlePenalties = &middlePenalties
+	return nil
+}
+
+// SetIndexPenalties parses and sets the index finger penalty weights from a string.
+// Accepts 6 values (mirrored for both hands) or 12 values (left then right).
+// Order per hand: top-outer, top-inner, home-outer, home-inner, bottom-outer, bottom-inner,
+// where the outer column is the home column of the index finger. Values are NOT scaled.
+func (tl *TargetLoads) SetIndexPenalties(s string) error {
+	var indexPenalties [12]float64
+	if err := parsePenalties("index-penalties", s, indexPenalties[:]); err != nil {
+		return fmt.Errorf("could not parse index penalties: %w", err)
+	}
+	tl.IndexPenalties = &indexPenalties
+	return nil
+}
+
 // parseTargetHandLoad parses hand load values from a comma-separated string.
 // Expects exactly 2 values for left hand and right hand.
 func parseTargetHandLoad(s string) (*[2]float64, error) {
@@ -309,31 +393,41 @@ func scaleRowLoad(vals *[3]float64) error {
 // Accepts 6 values (mirrored to 12) or 12 values directly.
 // Order per hand: top-outer, top-inner, home-outer, home-inner, bottom-outer, bottom-inner.
 func parsePinkyPenalties(s string) (*[12]float64, error) {
+	var pinkyVals [12]float64
+	if err := parsePenalties("pinky-penalties", s, pinkyVals[:]); err != nil {
+		return nil, err
+	}
+	return &pinkyVals, nil
+}
+
+// parsePenalties parses the off-home penalty values of a finger from a comma-separated
+// string into vals. Accepts the values of one hand (mirrored for both hands) or of both
+// hands (left then right).
+func parsePenalties(name, s string, vals []float64) error {
 	parts := strings.Split(s, ",")
-	if len(parts) != 6 && len(parts) != 12 {
-		return nil, fmt.Errorf("pinky-penalties must have 6 or 12 comma-separated values (got %d)", len(parts))
+	perHand := len(vals) / 2
+	if len(parts) != perHand && len(parts) != len(vals) {
+		return fmt.Errorf("%s must have %d or %d comma-separated values (got %d)", name, perHand, len(vals), len(parts))
 	}
 	for i := range parts {
 		parts[i] = strings.TrimSpace(parts[i])
 	}
 
-	// If the user provided 6 values, mirror them to create the 12-value representation.
-	if len(parts) == 6 {
+	// If the user provided the values of one hand, mirror them for the other hand.
+	if len(parts) == perHand {
 		parts = append(parts, parts...)
 	}
 
 	// convert values to float64
-	var pinkyVals [12]float64
 	for i, p := range parts {
 		if p == "" {
-			return nil, fmt.Errorf("empty value in pinky-penalties at position %d", i)
+			return fmt.Errorf("empty value in %s at position %d", name, i)
 		}
 		v, err := strconv.ParseFloat(p, 64)
 		if err != nil {
-			return nil, fmt.Errorf("invalid float in pinky-penalties at position %d: %w", i, err)
+			return fmt.Errorf("invalid float in %s at position %d: %w", name, i, err)
 		}
-		pinkyVals[i] = v
+		vals[i] = v
 	}
-
-	return &pinkyVals, nil
+	return nil
 }
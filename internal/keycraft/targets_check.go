@@ -0,0 +1,71 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// targetSumTolerance is how far, in percentage points, loads may be from the sum or share
+// they should have before they are reported.
+const targetSumTolerance = 0.5
+
+// setGivenSum records the sum of loads as given, before they are scaled to 100%.
+func (tl *TargetLoads) setGivenSum(name string, loads []float64) {
+	var sum float64
+	for _, v := range loads {
+		sum += v
+	}
+	if tl.givenSums == nil {
+		tl.givenSums = make(map[string]float64)
+	}
+	tl.givenSums[name] = sum
+}
+
+// Problems reports target loads that are configured in a way that quietly distorts HLD, FLD,
+// RLD or the off-home metrics: loads that were given with a sum other than 100% and were
+// scaled, finger loads that don't add up to the target hand loads, so that HLD and FLD can't
+// both be 0, targets above the maximum finger loads, and negative off-home penalties, which
+// reward keys off home. Returns nil if the targets are consistent.
+func (tl *TargetLoads) Problems() []string {
+	tl = withDefaultTargets(tl)
+	var problems []string
+
+	for _, name := range []string{"target-hand-load", "target-finger-load", "target-row-load"} {
+		if sum, ok := tl.givenSums[name]; ok && math.Abs(sum-100) > targetSumTolerance {
+			problems = append(problems, fmt.Sprintf("%s adds up to %.1f%%, not 100%%; it is scaled to 100%%", name, sum))
+		}
+	}
+
+	fingers, hand := tl.TargetFingerLoad, tl.TargetHandLoad
+	left := fingers[LP] + fingers[LR] + fingers[LM] + fingers[LI]
+	if math.Abs(left-hand[0]) > targetSumTolerance {
+		problems = append(problems, fmt.Sprintf("target-finger-load puts %.1f%% on the left hand and %.1f%% on the right, "+
+			"but target-hand-load puts %.1f%% and %.1f%%; HLD and FLD can't both be 0", left, 100-left, hand[0], hand[1]))
+	}
+
+	if caps := tl.MaxFingerLoad; caps != nil {
+		for f, label := range fingerLabels {
+			if fingers[f] > caps[f]+targetSumTolerance {
+				problems = append(problems, fmt.Sprintf("%s has a target load of %.1f%%, above its maximum load of %.1f%%",
+					label, fingers[f], caps[f]))
+			}
+		}
+	}
+
+	for _, penalties := range []struct {
+		name, finger string
+		vals         []float64
+	}{
+		{"pinky-penalties", "pinky", tl.PinkyPenalties[:]},
+		{"ring-penalties", "ring finger", tl.RingPenalties[:]},
+		{"middle-penalties", "middle finger", tl.MiddlePenalties[:]},
+		{"index-penalties", "index finger", tl.IndexPenalties[:]},
+	} {
+		if slices.ContainsFunc(penalties.vals, func(v float64) bool { return v < 0 }) {
+			problems = append(problems, fmt.Sprintf("%s has negative values, which reward %s keys off home",
+				penalties.name, penalties.finger))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,51 @@
+package keycraft
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTargetLoads_Problems(t *testing.T) {
+	if problems := NewTargetLoads().Problems(); len(problems) != 0 {
+		t.Errorf("default targets should have no problems, got %v", problems)
+	}
+
+	tests := []struct {
+		name string
+		set  func(tl *TargetLoads) error
+		want string
+	}{
+		{"scaled hand load", func(tl *TargetLoads) error { return tl.SetHandLoad("1,1") },
+			"target-hand-load adds up to 2.0%"},
+		{"scaled finger load", func(tl *TargetLoads) error { return tl.SetFingerLoad("14,20,32,34") },
+			"target-finger-load adds up to 200.0%"},
+		{"scaled row load", func(tl *TargetLoads) error { return tl.SetRowLoad("20,70,5") },
+			"target-row-load adds up to 95.0%"},
+		{"finger load against hand load", func(tl *TargetLoads) error { return tl.SetHandLoad("55,45") },
+			"puts 50.0% on the left hand and 50.0% on the right, but target-hand-load puts 55.0% and 45.0%"},
+		{"target above maximum", func(tl *TargetLoads) error { return tl.SetMaxFingerLoad("-,5,-,-,-,-,-,-") },
+			"LR has a target load of 10.0%, above its maximum load of 5.0%"},
+		{"negative pinky penalties", func(tl *TargetLoads) error { return tl.SetPinkyPenalties("2,1.5,1,-0.5,2,1.5") },
+			"pinky-penalties has negative values"},
+		{"negative ring penalties", func(tl *TargetLoads) error { return tl.SetRingPenalties("1.5,-1,1.5") },
+			"ring-penalties has negative values, which reward ring finger keys off home"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := NewTargetLoads()
+			Must0(tt.set(targets))
+			problems := targets.Problems()
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.want) {
+				t.Errorf("problems = %v, want one containing %q", problems, tt.want)
+			}
+		})
+	}
+
+	// An asymmetric split of the fingers that matches the hand loads is consistent
+	targets := NewTargetLoads()
+	Must0(targets.SetHandLoad("54, 46"))
+	Must0(targets.SetFingerLoad("8, 11, 17, 18, 16, 15, 9, 6"))
+	if problems := targets.Problems(); len(problems) != 0 {
+		t.Errorf("consistent targets should have no problems, got %v", problems)
+	}
+}
@@ -335,6 +335,33 @@ func TestSetPinkyPenalties(t *testing.T) {
 	}
 }
 
+func TestSetOffHomePenalties(t *testing.T) {
+	targets := NewTargetLoads()
+
+	Must0(targets.SetRingPenalties("2, 0, 1"))
+	if want := [6]float64{2, 0, 1, 2, 0, 1}; *targets.RingPenalties != want {
+		t.Errorf("ring penalties %v, want %v", *targets.RingPenalties, want)
+	}
+	Must0(targets.SetMiddlePenalties("1, 0, 2, 0, 0, 0"))
+	if want := [6]float64{1, 0, 2, 0, 0, 0}; *targets.MiddlePenalties != want {
+		t.Errorf("middle penalties %v, want %v", *targets.MiddlePenalties, want)
+	}
+	Must0(targets.SetIndexPenalties("1, 2, 0, 1, 1, 2"))
+	if want := [12]float64{1, 2, 0, 1, 1, 2, 1, 2, 0, 1, 1, 2}; *targets.IndexPenalties != want {
+		t.Errorf("index penalties %v, want %v", *targets.IndexPenalties, want)
+	}
+
+	for _, err := range []error{
+		targets.SetRingPenalties("1, 0, 1, 1"),
+		targets.SetMiddlePenalties("1, , 1"),
+		targets.SetIndexPenalties("1, 0, 2"),
+	} {
+		if err == nil {
+			t.Error("expected an error for invalid penalties")
+		}
+	}
+}
+
 func TestNewTargetLoadsFromFile(t *testing.T) {
 	// Create a temporary config file
 	tmpDir := t.TempDir()
@@ -345,6 +372,7 @@ target-hand-load= 55, 45
 target-finger-load= 8, 11, 15, 16
 target-row-load= 20, 70, 10
 pinky-penalties= 1.5, 1.0, 0.5, 0.0, 1.5, 1.0
+ring-penalties= 2.0, 0.0, 2.0
 `
 
 	err := os.WriteFile(configPath, []byte(content), 0644)
@@ -384,6 +412,14 @@ pinky-penalties= 1.5, 1.0, 0.5, 0.0, 1.5, 1.0
 			t.Errorf("Pinky penalty %d: expected %f, got %f", i, exp, targets.PinkyPenalties[i])
 		}
 	}
+
+	// Verify ring penalties (3 values mirrored) and default middle and index penalties
+	if want := [6]float64{2, 0, 2, 2, 0, 2}; *targets.RingPenalties != want {
+		t.Errorf("Ring penalties: expected %v, got %v", want, *targets.RingPenalties)
+	}
+	if *targets.MiddlePenalties != *DefaultMiddlePenalties() || *targets.IndexPenalties != *DefaultIndexPenalties() {
+		t.Error("Middle and index penalties should have defaults")
+	}
 }
 
 func TestNewTargetLoadsFromFile_MissingFile(t *testing.T) {
@@ -0,0 +1,98 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// thresholdOps are the comparison operators of a threshold, two-character operators first.
+var thresholdOps = []string{">=", "<=", ">", "<"}
+
+// Threshold is a condition on a metric, such as "SFB>1.5", that a layout violates when the
+// condition holds for its metric value.
+type Threshold struct {
+	Metric string  // Metric name, in upper case
+	Op     string  // One of >, >=, < and <=
+	Value  float64 // Value to compare the metric against
+}
+
+// ThresholdViolation is a threshold violated by a layout.
+type ThresholdViolation struct {
+	Threshold
+	Layout string  // Name of the layout
+	Actual float64 // Metric value of the layout
+}
+
+// ParseThresholds parses thresholds like "SFB>1.5" or "ALT<=30". Each spec may hold several
+// comma-separated thresholds.
+func ParseThresholds(specs []string) ([]Threshold, error) {
+	var thresholds []Threshold
+	for _, spec := range specs {
+		for part := range strings.SplitSeq(spec, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			th, err := parseThreshold(part)
+			if err != nil {
+				return nil, err
+			}
+			thresholds = append(thresholds, th)
+		}
+	}
+	return thresholds, nil
+}
+
+// parseThreshold parses a single threshold.
+func parseThreshold(s string) (Threshold, error) {
+	for _, op := range thresholdOps {
+		metric, value, found := strings.Cut(s, op)
+		if !found {
+			continue
+		}
+		metric = strings.ToUpper(strings.TrimSpace(metric))
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if metric == "" || err != nil || math.IsNaN(v) {
+			break
+		}
+		return Threshold{Metric: metric, Op: op, Value: v}, nil
+	}
+	return Threshold{}, fmt.Errorf("invalid threshold %q: expected <metric><op><value> with op one of %s",
+		s, strings.Join(thresholdOps, " "))
+}
+
+// String returns the threshold in the format of ParseThresholds.
+func (th Threshold) String() string {
+	return fmt.Sprintf("%s%s%g", th.Metric, th.Op, th.Value)
+}
+
+// ViolatedBy reports whether the condition of the threshold holds for the metric value.
+func (th Threshold) ViolatedBy(v float64) bool {
+	switch th.Op {
+	case ">":
+		return v > th.Value
+	case ">=":
+		return v >= th.Value
+	case "<":
+		return v < th.Value
+	case "<=":
+		return v <= th.Value
+	}
+	return false
+}
+
+// CheckThresholds returns the thresholds violated by the analysed layouts, in the order of
+// the analysers and thresholds. Metrics that were not computed for a layout are skipped.
+func CheckThresholds(thresholds []Threshold, analysers []*Analyser) []ThresholdViolation {
+	var violations []ThresholdViolation
+	for _, an := range analysers {
+		for _, th := range thresholds {
+			if v, ok := an.Metrics[th.Metric]; ok && th.ViolatedBy(v) {
+				violations = append(violations, ThresholdViolation{Threshold: th, Layout: an.Layout.Name, Actual: v})
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,50 @@
+package keycraft
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseThresholds(t *testing.T) {
+	got, err := ParseThresholds([]string{"SFB>1.5", " alt <= 30 , LSB<0.5", "HRUN-AVG>=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Threshold{
+		{"SFB", ">", 1.5},
+		{"ALT", "<=", 30},
+		{"LSB", "<", 0.5},
+		{"HRUN-AVG", ">=", 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseThresholds = %v, want %v", got, want)
+	}
+	if s := want[1].String(); s != "ALT<=30" {
+		t.Errorf("String() = %q, want ALT<=30", s)
+	}
+
+	for _, spec := range []string{"SFB", "SFB=1", ">1", "SFB>x", "SFB>NaN"} {
+		if _, err := ParseThresholds([]string{spec}); err == nil {
+			t.Errorf("expected error for %q, got nil", spec)
+		}
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	a := &Analyser{Layout: &SplitLayout{Name: "a"}, Metrics: map[string]float64{"SFB": 1.5, "ALT": 30}}
+	b := &Analyser{Layout: &SplitLayout{Name: "b"}, Metrics: map[string]float64{"SFB": 2, "ALT": 20}}
+	thresholds := Must(ParseThresholds([]string{"SFB>1.5,ALT<=30,XYZ>0"}))
+
+	got := CheckThresholds(thresholds, []*Analyser{a, b})
+	want := []ThresholdViolation{
+		{Threshold: thresholds[1], Layout: "a", Actual: 30},
+		{Threshold: thresholds[0], Layout: "b", Actual: 2},
+		{Threshold: thresholds[1], Layout: "b", Actual: 20},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckThresholds = %+v, want %+v", got, want)
+	}
+	if v := CheckThresholds(nil, []*Analyser{a, b}); len(v) != 0 {
+		t.Errorf("no thresholds gave violations %v", v)
+	}
+}
@@ -0,0 +1,376 @@
+package keycraft
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BigramTiming is the measured latency of a bigram: the mean time between its keystrokes.
+type BigramTiming struct {
+	Bigram Bigram
+	Ms     float64 // Mean latency in milliseconds
+	Count  uint64  // Number of measurements
+}
+
+// timingColumns lists the accepted column names (and JSON keys) of the fields of a timing
+// export, as used by common typing trainers.
+var timingColumns = struct{ bigram, ms, count []string }{
+	bigram: []string{"bigram", "ngram", "keys", "pair"},
+	ms:     []string{"ms", "latency", "latency_ms", "mean_ms", "avg_ms", "time"},
+	count:  []string{"count", "samples", "n", "hits"},
+}
+
+// LoadBigramTimings reads the bigram latencies of a typing-test export. Files ending in .json
+// hold either an array of objects with a bigram, a latency in ms and an optional count, such
+// as [{"bigram": "th", "ms": 112.5, "count": 40}], or an object that maps bigrams to their
+// latency or to such an object. Other files are CSV with a header row naming the bigram,
+// latency and optional count columns. Bigrams are lowercased, and measurements of the same
+// bigram are combined into their mean.
+func LoadBigramTimings(path string) ([]BigramTiming, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open timing export %s: %w", path, err)
+	}
+	defer CloseFile(file)
+
+	var timings []BigramTiming
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		timings, err = parseTimingsJSON(file)
+	} else {
+		timings, err = parseTimingsCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read timing export %s: %w", path, err)
+	}
+	return mergeTimings(timings), nil
+}
+
+// newBigramTiming validates the fields of a measured bigram.
+func newBigramTiming(bigram string, ms float64, count uint64) (BigramTiming, error) {
+	runes := []rune(strings.ToLower(bigram))
+	if len(runes) != 2 {
+		return BigramTiming{}, fmt.Errorf("bigram %q must have 2 characters", bigram)
+	}
+	if ms <= 0 || math.IsNaN(ms) || math.IsInf(ms, 0) {
+		return BigramTiming{}, fmt.Errorf("latency of bigram %q must be above 0 (got %g)", bigram, ms)
+	}
+	return BigramTiming{Bigram: Bigram{runes[0], runes[1]}, Ms: ms, Count: count}, nil
+}
+
+// parseTimingsCSV reads a CSV timing export with a header row.
+func parseTimingsCSV(r io.Reader) ([]BigramTiming, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+	column := func(names []string) int {
+		return slices.IndexFunc(header, func(h string) bool {
+			return slices.Contains(names, strings.ToLower(strings.TrimSpace(h)))
+		})
+	}
+	bigramCol, msCol, countCol := column(timingColumns.bigram), column(timingColumns.ms), column(timingColumns.count)
+	if bigramCol < 0 || msCol < 0 {
+		return nil, fmt.Errorf("header %v must name a bigram column (%s) and a latency column (%s)", header,
+			strings.Join(timingColumns.bigram, ", "), strings.Join(timingColumns.ms, ", "))
+	}
+
+	var timings []BigramTiming
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ms, err := strconv.ParseFloat(strings.TrimSpace(record[msCol]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latency %q", line, record[msCol])
+		}
+		count := uint64(1)
+		if countCol >= 0 {
+			if count, err = strconv.ParseUint(strings.TrimSpace(record[countCol]), 10, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid count %q", line, record[countCol])
+			}
+		}
+		timing, err := newBigramTiming(record[bigramCol], ms, count)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		timings = append(timings, timing)
+	}
+	return timings, nil
+}
+
+// parseTimingsJSON reads a JSON timing export.
+func parseTimingsJSON(r io.Reader) ([]BigramTiming, error) {
+	var data any
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	// field returns the number under any of the keys of an object
+	field := func(obj map[string]any, keys []string) (float64, bool) {
+		for k, v := range obj {
+			if f, ok := v.(float64); ok && slices.Contains(keys, strings.ToLower(k)) {
+				return f, true
+			}
+		}
+		return 0, false
+	}
+	fromObject := func(bigram string, obj map[string]any) (BigramTiming, error) {
+		ms, ok := field(obj, timingColumns.ms)
+		if !ok {
+			return BigramTiming{}, fmt.Errorf("bigram %q has no latency (%s)", bigram, strings.Join(timingColumns.ms, ", "))
+		}
+		count := 1.0
+		if c, ok := field(obj, timingColumns.count); ok {
+			count = c
+		}
+		if count < 0 || count != math.Trunc(count) {
+			return BigramTiming{}, fmt.Errorf("count of bigram %q must be a whole number (got %g)", bigram, count)
+		}
+		return newBigramTiming(bigram, ms, uint64(count))
+	}
+
+	var timings []BigramTiming
+	switch data := data.(type) {
+	case []any:
+		for i, item := range data {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("item %d is not an object", i+1)
+			}
+			var bigram string
+			for k, v := range obj {
+				if s, ok := v.(string); ok && slices.Contains(timingColumns.bigram, strings.ToLower(k)) {
+					bigram = s
+				}
+			}
+			timing, err := fromObject(bigram, obj)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i+1, err)
+			}
+			timings = append(timings, timing)
+		}
+	case map[string]any:
+		for bigram, v := range data {
+			var timing BigramTiming
+			var err error
+			switch v := v.(type) {
+			case float64:
+				timing, err = newBigramTiming(bigram, v, 1)
+			case map[string]any:
+				timing, err = fromObject(bigram, v)
+			default:
+				err = fmt.Errorf("bigram %q must map to a latency or an object", bigram)
+			}
+			if err != nil {
+				return nil, err
+			}
+			timings = append(timings, timing)
+		}
+	default:
+		return nil, fmt.Errorf("expected an array or an object of bigram latencies")
+	}
+	return timings, nil
+}
+
+// mergeTimings combines the measurements of the same bigram into their mean, weighted by
+// their counts, and sorts the bigrams. Measurements without a count are left out.
+func mergeTimings(timings []BigramTiming) []BigramTiming {
+	merged := make(map[Bigram]*BigramTiming)
+	var order []Bigram
+	for _, t := range timings {
+		if t.Count == 0 {
+			continue
+		}
+		m, ok := merged[t.Bigram]
+		if !ok {
+			m = &BigramTiming{Bigram: t.Bigram}
+			merged[t.Bigram] = m
+			order = append(order, t.Bigram)
+		}
+		total := m.Count + t.Count
+		m.Ms = (m.Ms*float64(m.Count) + t.Ms*float64(t.Count)) / float64(total)
+		m.Count = total
+	}
+	slices.SortFunc(order, func(a, b Bigram) int { return strings.Compare(a.String(), b.String()) })
+	result := make([]BigramTiming, len(order))
+	for i, bi := range order {
+		result[i] = *merged[bi]
+	}
+	return result
+}
+
+// TimingClasses lists the classes of bigrams that FitTimingCosts measures. A bigram belongs to
+// one class; one that is both a scissor and a lateral stretch counts as a scissor. ALT, bigrams
+// typed with both hands, is the baseline the costs of the other classes are measured from.
+// 2RL-IN and 2RL-OUT are the other bigrams typed with one hand, towards and away from the thumb.
+var TimingClasses = []string{"SFB", "FSB", "HSB", "LSB", "2RL-IN", "2RL-OUT", "ALT"}
+
+// TimingClass is the measured latency of a class of bigrams.
+type TimingClass struct {
+	Class   string
+	Bigrams int     // Number of measured bigrams in the class
+	Count   uint64  // Number of measurements
+	Ms      float64 // Mean latency of the class in milliseconds
+	CostMs  float64 // Mean latency minus that of ALT; negative if faster than alternation
+	Weight  float64 // Weight of the metric of the class
+}
+
+// TimingFitInput holds the parameters of fitting metric weights to bigram latencies.
+type TimingFitInput struct {
+	LayoutsDir string        // Layouts used to normalise metrics, like rank
+	Reference  *ReferenceSet // Layouts in LayoutsDir used for normalisation (nil = default)
+	Corpus     *Corpus       // Corpus the layouts are analysed against
+	Targets    *TargetLoads  // Load targets
+	Layout     *SplitLayout  // Layout the latencies were measured on
+	Timings    []BigramTiming
+	MinCount   uint64 // Minimum number of measurements of a class to fit its cost
+}
+
+// TimingFit is the result of FitTimingCosts.
+type TimingFit struct {
+	Classes     []TimingClass // Measured classes, in the order of TimingClasses
+	Unsupported int           // Measured bigrams with a character that is not on the layout
+	Weights     *Weights      // Weights of the measured metrics, in the scale of a weights file
+	Metrics     []string      // Metrics with a weight
+}
+
+// FitTimingCosts measures the personal cost of each class of bigrams: the mean latency of the
+// bigrams of the class on the layout they were typed on, minus the mean latency of bigrams
+// typed with both hands. The cost of a metric per % of bigrams is turned into a weight by
+// multiplying it with the spread (IQR) of the metric among the reference layouts, since the
+// scorer divides the metrics by their spread. A score is then proportional to the typing time
+// the metrics add or save. The weights are scaled so that the largest one is fitMaxWeight,
+// which does not change the ranking.
+func FitTimingCosts(input TimingFitInput) (*TimingFit, error) {
+	if len(input.Timings) == 0 {
+		return nil, fmt.Errorf("no bigram latencies")
+	}
+
+	// Key pairs of the patterns of the layout
+	pairs := make(map[[2]uint8]string)
+	for _, sci := range input.Layout.HScissors {
+		pairs[[2]uint8{sci.keyIdx1, sci.keyIdx2}] = "HSB"
+	}
+	for _, sci := range input.Layout.FScissors {
+		pairs[[2]uint8{sci.keyIdx1, sci.keyIdx2}] = "FSB"
+	}
+	for _, lsb := range input.Layout.LSBs {
+		if _, ok := pairs[[2]uint8{lsb.KeyIdx1, lsb.KeyIdx2}]; !ok {
+			pairs[[2]uint8{lsb.KeyIdx1, lsb.KeyIdx2}] = "LSB"
+		}
+	}
+	classify := func(k1, k2 KeyInfo) string {
+		switch {
+		case k1.Index == k2.Index:
+			return "" // Repeats are no pattern of the metrics
+		case k1.Hand != k2.Hand:
+			return "ALT"
+		case k1.Finger == k2.Finger:
+			return "SFB"
+		}
+		if class, ok := pairs[[2]uint8{k1.Index, k2.Index}]; ok {
+			return class
+		}
+		if (k1.Hand == LEFT) == (k2.Finger > k1.Finger) {
+			return "2RL-IN"
+		}
+		return "2RL-OUT"
+	}
+
+	fit := &TimingFit{}
+	sums := make(map[string]*TimingClass)
+	for _, t := range input.Timings {
+		k1, ok1 := input.Layout.GetKeyInfo(t.Bigram[0])
+		k2, ok2 := input.Layout.GetKeyInfo(t.Bigram[1])
+		if !ok1 || !ok2 {
+			fit.Unsupported++
+			continue
+		}
+		class := classify(k1, k2)
+		if class == "" {
+			continue
+		}
+		s, ok := sums[class]
+		if !ok {
+			s = &TimingClass{Class: class}
+			sums[class] = s
+		}
+		s.Bigrams++
+		s.Count += t.Count
+		s.Ms += t.Ms * float64(t.Count)
+	}
+	alt, ok := sums["ALT"]
+	if !ok || alt.Count < input.MinCount {
+		return nil, fmt.Errorf("need at least %d measurements of bigrams typed with both hands on layout %s",
+			max(input.MinCount, 1), input.Layout.Name)
+	}
+
+	reference, err := loadReferenceAnalysers(input.LayoutsDir, input.Corpus, input.Targets, input.Reference)
+	if err != nil {
+		return nil, err
+	}
+	_, iqrs := computeMediansAndIQR(reference, nil)
+
+	altMs := alt.Ms / float64(alt.Count)
+	raw := make(map[string]float64)
+	for _, class := range TimingClasses {
+		s, ok := sums[class]
+		if !ok || s.Count < max(input.MinCount, 1) {
+			continue
+		}
+		s.Ms /= float64(s.Count)
+		s.CostMs = s.Ms - altMs
+		if class != "ALT" && iqrs[class] > 1e-9 {
+			raw[class] = -s.CostMs * iqrs[class]
+			fit.Metrics = append(fit.Metrics, class)
+		}
+		fit.Classes = append(fit.Classes, *s)
+	}
+	if len(fit.Metrics) == 0 {
+		return nil, fmt.Errorf("no class of bigrams other than ALT has at least %d measurements", max(input.MinCount, 1))
+	}
+
+	// Scale to the range of a weights file and round, as FitWeights does
+	maxAbs := 0.0
+	for _, v := range raw {
+		maxAbs = max(maxAbs, math.Abs(v))
+	}
+	fit.Weights = NewWeights()
+	for _, metric := range fit.Metrics {
+		v := 0.0
+		if maxAbs > 0 {
+			v = math.Round(raw[metric]/maxAbs*fitMaxWeight*1000) / 1000
+		}
+		fit.Weights.weights[metric] = v
+	}
+	if !slices.Contains(fit.Metrics, "SFB") {
+		fit.Weights.weights["SFB"] = 0 // Override the default weight
+	}
+	for i, class := range fit.Classes {
+		if slices.Contains(fit.Metrics, class.Class) {
+			fit.Classes[i].Weight = fit.Weights.Get(class.Class)
+		}
+	}
+	return fit, nil
+}
+
+// SaveToFile writes the weights of the measured metrics in the format of a weights file,
+// after the header lines as comments.
+func (fit *TimingFit) SaveToFile(path string, header []string) error {
+	return (&WeightFit{Weights: fit.Weights, Metrics: fit.Metrics}).SaveToFile(path, header)
+}
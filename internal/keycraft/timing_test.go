@@ -0,0 +1,103 @@
+package keycraft
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBigramTimings(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"export.csv":  "Bigram, Latency, Samples\nTH,100,3\nth,120,1\nhe,90,2\nxx,80,0\n",
+		"array.json":  `[{"bigram": "TH", "ms": 100, "count": 3}, {"keys": "th", "latency": 120}, {"bigram": "he", "ms": 90, "count": 2}]`,
+		"object.json": `{"th": {"ms": 100, "count": 3}, "TH": {"ms": 120}, "he": {"mean_ms": 90, "n": 2}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		timings := Must(LoadBigramTimings(path))
+		want := []BigramTiming{{Bigram{'h', 'e'}, 90, 2}, {Bigram{'t', 'h'}, 105, 4}}
+		if len(timings) != len(want) || timings[0] != want[0] || timings[1] != want[1] {
+			t.Errorf("%s: timings %v, want %v", name, timings, want)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"header.csv":  "word,ms\nth,100\n",
+		"latency.csv": "bigram,ms\nth,fast\n",
+		"length.csv":  "bigram,ms\nthe,100\n",
+		"zero.json":   `{"th": 0}`,
+		"string.json": `"th"`,
+	} {
+		path := filepath.Join(dir, name)
+		Must0(os.WriteFile(path, []byte(content), 0644))
+		if _, err := LoadBigramTimings(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFitTimingCosts(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	corpus.addTextWithWords("sphinx of black quartz, judge my vow. how vexingly quick daft zebras jump!")
+	layout := NewSplitLayout("qwerty", ROWSTAG, qwertyRunes())
+
+	// Same-finger bigrams are slow, alternation is fast, and other bigrams are in between
+	var timings []BigramTiming
+	for _, r0 := range "abcdefghijklmnopqrstuvwxyz" {
+		for _, r1 := range "abcdefghijklmnopqrstuvwxyz" {
+			k0, _ := layout.GetKeyInfo(r0)
+			k1, _ := layout.GetKeyInfo(r1)
+			ms := 130.0
+			switch {
+			case k0.Hand != k1.Hand:
+				ms = 100
+			case k0.Finger == k1.Finger:
+				ms = 200
+			}
+			timings = append(timings, BigramTiming{Bigram{r0, r1}, ms, 10})
+		}
+	}
+	timings = append(timings, BigramTiming{Bigram{'a', 'é'}, 500, 10})
+
+	fit := Must(FitTimingCosts(TimingFitInput{
+		LayoutsDir: copyBundledLayouts(t, "qwerty", "dvorak", "colemak-dh", "canary", "sturdy"),
+		Corpus:     corpus,
+		Targets:    NewTargetLoads(),
+		Layout:     layout,
+		Timings:    timings,
+		MinCount:   20,
+	}))
+
+	classes := map[string]TimingClass{}
+	for _, c := range fit.Classes {
+		classes[c.Class] = c
+	}
+	if c := classes["SFB"]; c.CostMs != 100 || c.Weight >= 0 {
+		t.Errorf("SFB cost %v ms with weight %v, want 100 ms and a negative weight", c.CostMs, c.Weight)
+	}
+	if c := classes["ALT"]; c.Ms != 100 || c.CostMs != 0 {
+		t.Errorf("ALT %v ms with cost %v, want 100 ms and no cost", c.Ms, c.CostMs)
+	}
+	if c := classes["2RL-IN"]; c.CostMs != 30 {
+		t.Errorf("2RL-IN cost %v ms, want 30", c.CostMs)
+	}
+	if fit.Unsupported != 1 {
+		t.Errorf("%d unsupported bigrams, want 1", fit.Unsupported)
+	}
+
+	// Without alternation there is no baseline
+	var sameHand []BigramTiming
+	for _, timing := range timings {
+		k0, ok0 := layout.GetKeyInfo(timing.Bigram[0])
+		k1, ok1 := layout.GetKeyInfo(timing.Bigram[1])
+		if ok0 && ok1 && k0.Hand == k1.Hand {
+			sameHand = append(sameHand, timing)
+		}
+	}
+	if _, err := FitTimingCosts(TimingFitInput{Layout: layout, Timings: sameHand, MinCount: 20}); err == nil {
+		t.Error("expected an error without alternation")
+	}
+}
@@ -0,0 +1,66 @@
+package keycraft
+
+// trigramTable is a list of trigrams with their runes translated to indices into a table of
+// the distinct runes. Finding the keys of a trigram then takes three array lookups into a
+// table of keys built once per layout, instead of three rune lookups per trigram, which
+// dominated analyseTrigrams.
+type trigramTable struct {
+	list     []TrigramInfo    // The trigrams the table was built from
+	runes    []rune           // Distinct runes of the trigrams
+	trigrams []indexedTrigram // Trigrams of list, in the same order
+}
+
+// indexedTrigram is a trigram with its runes as indices into trigramTable.runes.
+type indexedTrigram struct {
+	count uint64
+	runes [3]uint16
+}
+
+// newTrigramTable translates a list of trigrams to a trigram table.
+func newTrigramTable(trigrams []TrigramInfo) *trigramTable {
+	t := &trigramTable{list: trigrams, trigrams: make([]indexedTrigram, len(trigrams))}
+	index := make(map[rune]uint16)
+	for i, ti := range trigrams {
+		t.trigrams[i].count = ti.Count
+		for k, r := range ti.Runes {
+			idx, ok := index[r]
+			if !ok {
+				idx = uint16(len(t.runes))
+				index[r] = idx
+				t.runes = append(t.runes, r)
+			}
+			t.trigrams[i].runes[k] = idx
+		}
+	}
+	return t
+}
+
+// infos returns the trigrams the table was built from, or nil for a nil table.
+func (t *trigramTable) infos() []TrigramInfo {
+	if t == nil {
+		return nil
+	}
+	return t.list
+}
+
+// keys returns the key of each rune of the table on the layout, in the order of the runes.
+// Runes that are not on the layout get the zero KeyInfo, as with GetKeyInfo.
+func (t *trigramTable) keys(sl *SplitLayout) []KeyInfo {
+	keys := make([]KeyInfo, len(t.runes))
+	for i, r := range t.runes {
+		keys[i], _ = sl.GetKeyInfo(r)
+	}
+	return keys
+}
+
+// classify adds the count of every trigram to the count of its class on the layout, and to
+// the span of the redirections.
+func (t *trigramTable) classify(sl *SplitLayout, counts *analyserCounts) {
+	keys := t.keys(sl)
+	for _, it := range t.trigrams {
+		k0, k1, k2 := keys[it.runes[0]], keys[it.runes[1]], keys[it.runes[2]]
+		class := classifyTrigram(k0, k1, k2)
+		counts.trigrams[class] += it.count
+		counts.redSpan += it.count * redirectSpan(class, k0, k1, k2)
+	}
+}
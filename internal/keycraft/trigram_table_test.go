@@ -0,0 +1,95 @@
+package keycraft
+
+import "testing"
+
+// TestTrigramTable checks that classifying trigrams through a trigram table counts the same
+// trigram classes as looking up the runes, also after swaps and with shifted characters.
+func TestTrigramTable(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("The Quick brown fox: jumps over the lazy dog; PACK my box with five dozen liquor jugs!")
+
+	for _, settings := range []string{"", "\nshift: us\n"} {
+		sl := Must(writeShiftedLayout(t, settings))
+		trigrams := (&Analyser{Layout: sl, Corpus: corpus}).trigrams()
+		table := newTrigramTable(trigrams)
+		if len(table.trigrams) != len(trigrams) {
+			t.Fatalf("table has %d trigrams, want %d", len(table.trigrams), len(trigrams))
+		}
+
+		keys := usedKeys(sl)
+		for step := range 20 {
+			if step > 0 {
+				sl.Swap(keys[(step*7)%len(keys)], keys[(step*11+3)%len(keys)])
+			}
+			want := &Analyser{Layout: sl, Corpus: corpus, Metrics: map[string]float64{}, relevantTrigrams: trigrams}
+			want.analyseTrigrams()
+			got := &Analyser{Layout: sl, Corpus: corpus, Metrics: map[string]float64{},
+				relevantTrigrams: trigrams, trigramTable: table}
+			got.analyseTrigrams()
+			if got.counts.trigrams != want.counts.trigrams {
+				t.Errorf("shift %q, step %d: trigram classes %v, want %v",
+					settings, step, got.counts.trigrams, want.counts.trigrams)
+			}
+			if got.counts.redSpan != want.counts.redSpan {
+				t.Errorf("shift %q, step %d: redirection span %d, want %d",
+					settings, step, got.counts.redSpan, want.counts.redSpan)
+			}
+		}
+	}
+}
+
+func TestAnalyser_3RLSFB(t *testing.T) {
+	var runes [42]rune
+	runes[3], runes[15], runes[16], runes[40] = 'e', 'd', 'f', ' '
+	sl := NewSplitLayout("test", ORTHO, runes)
+
+	// "ede" is typed with one finger, so its first and last keys are a same-finger skipgram,
+	// while "edf" only has a same-finger bigram of adjacent keys
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("ede edf")
+	an := NewAnalyser(sl, corpus, nil)
+	want := 100 / float64(corpus.TotalTrigramsCount)
+	if got := an.Metrics["3RL-SFB-SKP"]; got != want {
+		t.Errorf("3RL-SFB-SKP = %v, want %v", got, want)
+	}
+	if got := an.Metrics["3RL-SFB-ADJ"]; got != want {
+		t.Errorf("3RL-SFB-ADJ = %v, want %v", got, want)
+	}
+	if got := an.Metrics["3RL-SFB"]; got != 2*want {
+		t.Errorf("3RL-SFB = %v, want %v", got, 2*want)
+	}
+
+	_, _, rl3, _ := an.TrigramDetails()
+	if rl3.Custom["ede"]["Dir"] != "SFB-SKP" || rl3.Custom["edf"]["Dir"] != "SFB-ADJ" {
+		t.Errorf("3RL details = %v", rl3.Custom)
+	}
+}
+
+func TestAnalyser_RedirectSeverity(t *testing.T) {
+	var runes [42]rune
+	runes[13], runes[14], runes[15], runes[16], runes[39] = 'a', 's', 'd', 'f', ' '
+	sl := NewSplitLayout("test", ORTHO, runes)
+
+	// "dfd" is middle→index→middle, a span of 2, and "afs" is pinky→index→ring, a span of 5
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("dfd afs")
+	an := NewAnalyser(sl, corpus, nil)
+	pct := 100 / float64(corpus.TotalTrigramsCount)
+	if got := an.Metrics["RED"]; got != 2*pct {
+		t.Fatalf("RED = %v, want %v", got, 2*pct)
+	}
+	if got, want := an.Metrics["RED-SEV"], 3.5*pct; got != want {
+		t.Errorf("RED-SEV = %v, want %v", got, want)
+	}
+
+	_, _, _, red := an.TrigramDetails()
+	if red.Custom["dfd"]["Sev"] != 1.0 || red.Custom["afs"]["Sev"] != 2.5 {
+		t.Errorf("RED details = %v", red.Custom)
+	}
+
+	// Swapping keys updates the severity
+	an.ApplySwap(13, 15)
+	if got, want := an.Metrics["RED-SEV"], NewAnalyser(sl.Clone(), corpus, nil).Metrics["RED-SEV"]; got != want {
+		t.Errorf("RED-SEV after swap = %v, want %v", got, want)
+	}
+}
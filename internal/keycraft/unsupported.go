@@ -0,0 +1,90 @@
+package keycraft
+
+import "sort"
+
+// UnsupportedChar describes a corpus character that is not on the layout.
+// Such characters are excluded from all metrics, so a high percentage means the
+// metrics cover less of the corpus than they appear to.
+type UnsupportedChar struct {
+	Rune        rune
+	Count       uint64          // Occurrences in the corpus
+	Percentage  float64         // Share of all corpus unigrams (0..100)
+	Suggestions []KeySuggestion // Candidate positions, best first
+}
+
+// KeySuggestion is a candidate key position for an unsupported character.
+type KeySuggestion struct {
+	Index uint8  // Key position (0-41)
+	Rune  rune   // Current rune on the key (0 if the key is empty)
+	Count uint64 // Corpus occurrences of the current rune (0 if the key is empty)
+}
+
+// UnsupportedChars returns the corpus characters that are not on the layout, most
+// frequent first, each with up to maxSuggestions candidate positions. Candidates are
+// empty keys (main rows before thumbs, home row first), followed by keys whose
+// current rune is less frequent than the unsupported character. The best candidate
+// of a more frequent character is not offered again to less frequent characters.
+func (an *Analyser) UnsupportedChars(maxSuggestions int) []UnsupportedChar {
+	var chars []UnsupportedChar
+	for _, uni := range an.Corpus.TopUnigrams(0) {
+		r := rune(uni.Key)
+		if _, ok := an.Layout.GetKeyInfo(r); ok {
+			continue
+		}
+		chars = append(chars, UnsupportedChar{
+			Rune:       r,
+			Count:      uni.Count,
+			Percentage: 100 * float64(uni.Count) / float64(max(an.Corpus.TotalUnigramsCount, 1)),
+		})
+	}
+	if len(chars) == 0 || maxSuggestions <= 0 {
+		return chars
+	}
+
+	candidates := an.keyCandidates()
+	claimed := make(map[uint8]bool)
+	for i := range chars {
+		for _, cand := range candidates {
+			if len(chars[i].Suggestions) >= maxSuggestions {
+				break
+			}
+			if claimed[cand.Index] || (cand.Rune != 0 && cand.Count >= chars[i].Count) {
+				continue
+			}
+			chars[i].Suggestions = append(chars[i].Suggestions, cand)
+		}
+		if len(chars[i].Suggestions) > 0 {
+			claimed[chars[i].Suggestions[0].Index] = true
+		}
+	}
+	return chars
+}
+
+// keyCandidates lists all key positions in order of preference for placing a new
+// character: empty keys first, then occupied keys by ascending corpus frequency.
+// The space key is never offered.
+func (an *Analyser) keyCandidates() []KeySuggestion {
+	// Row preference for empty keys: home, top, bottom, thumbs
+	rowRank := [4]int{1, 0, 2, 3}
+
+	var empty, used []KeySuggestion
+	for idx, r := range an.Layout.Runes {
+		switch r {
+		case 0:
+			empty = append(empty, KeySuggestion{Index: uint8(idx)})
+			continue
+		case ' ':
+			continue
+		}
+		used = append(used, KeySuggestion{
+			Index: uint8(idx),
+			Rune:  r,
+			Count: an.Corpus.Unigrams[Unigram(r)],
+		})
+	}
+
+	row := func(idx uint8) int { return rowRank[min(int(idx)/12, 3)] }
+	sort.SliceStable(empty, func(i, j int) bool { return row(empty[i].Index) < row(empty[j].Index) })
+	sort.SliceStable(used, func(i, j int) bool { return used[i].Count < used[j].Count })
+	return append(empty, used...)
+}
@@ -0,0 +1,95 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUnsupportedChars(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog 1 22 333 ééé")
+
+	an := NewAnalyser(layout, corpus, nil)
+	chars := an.UnsupportedChars(2)
+
+	// testLayoutKlf has no digits or accented letters
+	want := map[rune]uint64{'1': 1, '2': 2, '3': 3, 'é': 3}
+	if len(chars) != len(want) {
+		t.Fatalf("expected %d unsupported chars, got %d: %v", len(want), len(chars), chars)
+	}
+	for i, uc := range chars {
+		if want[uc.Rune] != uc.Count {
+			t.Errorf("char %q: expected count %d, got %d", uc.Rune, want[uc.Rune], uc.Count)
+		}
+		if i > 0 && uc.Count > chars[i-1].Count {
+			t.Errorf("chars not sorted by frequency at %d", i)
+		}
+		if uc.Percentage <= 0 {
+			t.Errorf("char %q: expected positive percentage", uc.Rune)
+		}
+		if len(uc.Suggestions) == 0 {
+			t.Errorf("char %q: expected suggestions", uc.Rune)
+		}
+	}
+
+	// The best suggestion of each character is distinct, and empty keys come first
+	seen := make(map[uint8]bool)
+	for _, uc := range chars {
+		first := uc.Suggestions[0]
+		if seen[first.Index] {
+			t.Errorf("char %q: suggestion %d already offered to a more frequent char", uc.Rune, first.Index)
+		}
+		seen[first.Index] = true
+		if first.Rune != 0 {
+			t.Errorf("char %q: expected an empty key first, got %q", uc.Rune, first.Rune)
+		}
+	}
+}
+
+func TestUnsupportedChars_SuggestsOnlyRarerKeys(t *testing.T) {
+	layout := NewSplitLayout("full", ROWSTAG, [42]rune{
+		'q', 'w', 'e', 'r', 't', 'y', 'u', 'i', 'o', 'p', '[', ']',
+		'a', 's', 'd', 'f', 'g', 'h', 'j', 'k', 'l', ';', '\'', '\\',
+		'z', 'x', 'c', 'v', 'b', 'n', 'm', ',', '.', '/', '-', '=',
+		'1', '2', ' ', '3', '4', '5',
+	})
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("aaaa ssss ! q")
+
+	chars := NewAnalyser(layout, corpus, nil).UnsupportedChars(50)
+	if len(chars) != 1 || chars[0].Rune != '!' {
+		t.Fatalf("expected only '!' unsupported, got %v", chars)
+	}
+	for _, s := range chars[0].Suggestions {
+		if s.Rune == ' ' {
+			t.Error("space key should never be suggested")
+		}
+		if s.Count >= chars[0].Count {
+			t.Errorf("suggested key %q is not rarer than '!' (%d >= %d)", s.Rune, s.Count, chars[0].Count)
+		}
+	}
+	if len(chars[0].Suggestions) == 0 {
+		t.Error("expected keys with unused runes to be suggested")
+	}
+}
+
+func TestMetricDetails_Unsupported(t *testing.T) {
+	dir := writeTestLayouts(t, map[string]string{"a.klf": testLayoutKlf})
+	layout := Must(NewLayoutFromFile("a", filepath.Join(dir, "a.klf")))
+
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("abc a1c")
+
+	an := NewAnalyser(layout, corpus, nil)
+	if len(an.SFBiDetails().Unsupported) == 0 {
+		t.Error("expected unsupported bigrams in SFB details")
+	}
+	alt, _, _, _ := an.TrigramDetails()
+	if alt.Unsupported["a1c"] != 1 {
+		t.Errorf("expected trigram a1c unsupported once, got %d", alt.Unsupported["a1c"])
+	}
+}
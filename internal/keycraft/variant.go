@@ -0,0 +1,88 @@
+package keycraft
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// BoardVariant is the ANSI or ISO variant of a row-staggered board. Within the 42 keys of a
+// layout, the two differ in the bottom-left key only: ISO boards have an extra key between
+// Left Shift and Z, where ANSI boards have a wider Left Shift.
+type BoardVariant string
+
+const (
+	VariantNone BoardVariant = ""     // Not declared: the generic row stagger of 1u keys
+	VariantANSI BoardVariant = "ansi" // The bottom-left key is Left Shift, further out than ISO
+	VariantISO  BoardVariant = "iso"  // The bottom-left key is the ISO key, 1u left of Z
+)
+
+// BoardVariants lists the variants that a layout can declare.
+var BoardVariants = []BoardVariant{VariantANSI, VariantISO}
+
+// ansiShiftOffset is the horizontal offset of the bottom-left key on ANSI boards, in key
+// units as in rowStagOffsets. The 2.25u Left Shift centres 1.625 units left of Z, where the
+// ISO key, and the generic stagger, centre 1 unit left of Z.
+const ansiShiftOffset = 0.125
+
+// ansiKeyDistances contains precomputed key pair distances of the row-staggered layout
+// types on ANSI boards, using the default thumb geometry of the type. ISO boards use
+// keyDistances, as their keys are where the generic row stagger puts them.
+var ansiKeyDistances = map[LayoutType]map[KeyPair]KeyPairDistance{
+	ROWSTAG:  newKeyDistances(nil, ROWSTAG, VariantANSI, DefaultThumbGeometry(ROWSTAG)),
+	ANGLEMOD: newKeyDistances(nil, ANGLEMOD, VariantANSI, DefaultThumbGeometry(ANGLEMOD)),
+}
+
+// ParseBoardVariant parses the name of a board variant: "ansi" or "iso".
+func ParseBoardVariant(name string) (BoardVariant, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, v := range BoardVariants {
+		if name == string(v) {
+			return v, nil
+		}
+	}
+	return VariantNone, fmt.Errorf("invalid board variant %q: must be one of: ansi, iso", name)
+}
+
+// AbsColDistANSI computes horizontal distance accounting for row-stagger offsets and the
+// wide Left Shift of ANSI boards in the bottom-left key.
+func AbsColDistANSI(row1, col1, row2, col2 uint8) float64 {
+	x := func(row, col uint8) float64 {
+		if row == 2 && col == 0 {
+			return ansiShiftOffset
+		}
+		return float64(col) + rowStagOffsets[row]
+	}
+	return math.Abs(x(row1, col1) - x(row2, col2))
+}
+
+// SetVariant declares the board variant the layout is typed on, recomputing key distances
+// and the derived caches. Only row-staggered layouts have variants.
+func (sl *SplitLayout) SetVariant(v BoardVariant) error {
+	if v != VariantNone && sl.LayoutType != ROWSTAG && sl.LayoutType != ANGLEMOD {
+		return fmt.Errorf("variant %s only applies to row-staggered layouts (rowstag, anglemod), not %s",
+			v, LayoutTypeStrings[sl.LayoutType])
+	}
+	sl.Variant = v
+	thumbs := DefaultThumbGeometry(sl.LayoutType)
+	if sl.Thumbs != nil {
+		thumbs = *sl.Thumbs
+	}
+	sl.SetThumbGeometry(thumbs)
+	return nil
+}
+
+// presetDistances returns the shared key distances of the layout type and variant of the
+// layout, with the default thumb geometry, or those of the geometry of its board.
+func (sl *SplitLayout) presetDistances() *map[KeyPair]KeyPairDistance {
+	if g := sl.geometry(); g != boardGeometry {
+		distances := newKeyDistances(g, sl.LayoutType, sl.Variant, DefaultThumbGeometry(sl.LayoutType))
+		return &distances
+	}
+	if sl.Variant == VariantANSI {
+		if distances, ok := ansiKeyDistances[sl.LayoutType]; ok {
+			return &distances
+		}
+	}
+	return &keyDistances[sl.LayoutType]
+}
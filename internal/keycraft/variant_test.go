@@ -0,0 +1,70 @@
+package keycraft
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLayoutFromFile_Variant(t *testing.T) {
+	plain := Must(writeShiftedLayout(t, ""))
+	iso := Must(writeShiftedLayout(t, "\nvariant: ISO\n"))
+	ansi := Must(writeShiftedLayout(t, "\nvariant: ansi\n"))
+	if iso.Variant != VariantISO || ansi.Variant != VariantANSI || plain.Variant != VariantNone {
+		t.Fatalf("variants %q, %q and %q, want iso, ansi and none", iso.Variant, ansi.Variant, plain.Variant)
+	}
+
+	// The ISO key is 1u left of z as in the generic stagger; Left Shift of ANSI is further out.
+	// Key indices: w=2, s=14, bottom-left=24, z=25
+	if *iso.MustDistance(24, 25) != *plain.MustDistance(24, 25) {
+		t.Errorf("iso distance %+v, want the generic %+v", *iso.MustDistance(24, 25), *plain.MustDistance(24, 25))
+	}
+	if got := ansi.MustDistance(24, 25).ColDist; got != 1.625 {
+		t.Errorf("ansi bottom-left to z = %v, want 1.625", got)
+	}
+	if got := ansi.MustDistance(14, 13).ColDist; got != 1 {
+		t.Errorf("ansi s to a = %v, want 1 as on other boards", got)
+	}
+
+	// The wide Left Shift stretches from the ring finger on the home row, and from the top row
+	// as an edge case
+	generic := NewSplitLayout("generic", ROWSTAG, qwertyRunes())
+	shift := NewSplitLayout("shift", ROWSTAG, qwertyRunes())
+	Must0(shift.SetVariant(VariantANSI))
+	if !hasLSB(shift, 2, 24) || !hasLSB(shift, 14, 24) || hasLSB(generic, 2, 24) || hasLSB(generic, 14, 24) {
+		t.Error("ansi bottom-left key should stretch from w and s, unlike the generic stagger")
+	}
+
+	// Saving keeps the variant, and it changes the cache key
+	path := filepath.Join(t.TempDir(), "saved.klf")
+	Must0(ansi.SaveToFile(path))
+	loaded := Must(NewLayoutFromFile("saved", path))
+	if loaded.Variant != VariantANSI || *loaded.MustDistance(24, 25) != *ansi.MustDistance(24, 25) {
+		t.Errorf("reloaded variant %q, want ansi with its distances", loaded.Variant)
+	}
+	if layoutCacheKey(ansi) == layoutCacheKey(plain) || layoutCacheKey(ansi.Clone()) != layoutCacheKey(ansi) {
+		t.Error("cache key should depend on the variant, and be kept by clones")
+	}
+
+	// A custom thumb geometry keeps the variant distances
+	ansi.SetThumbGeometry(colStagThumbGeometry)
+	if got := ansi.MustDistance(24, 25).ColDist; got != 1.625 {
+		t.Errorf("ansi bottom-left to z with custom thumbs = %v, want 1.625", got)
+	}
+
+	tests := []struct {
+		name, klf, want string
+	}{
+		{"unknown", testLayoutKlf + "\nvariant: jis\n", "invalid board variant"},
+		{"ortho", strings.Replace(testLayoutKlf, "rowstag", "ortho", 1) + "\nvariant: iso\n", "row-staggered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTestLayouts(t, map[string]string{"a.klf": tt.klf})
+			_, err := NewLayoutFromFile("a", filepath.Join(dir, "a.klf"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package keycraft
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultVerifyTolerance is the absolute difference allowed between a computed metric and
+// its reference value when no tolerance is given.
+const DefaultVerifyTolerance = 0.001
+
+// VerifySpec is a set of reference metric values of well-known layouts against a corpus,
+// used to detect unintended changes to metric definitions between versions.
+type VerifySpec struct {
+	Corpus    string        // Corpus file the reference values were computed from
+	Tolerance float64       // Default absolute tolerance
+	Checks    []VerifyCheck // Reference values, in file order
+}
+
+// VerifyCheck is the reference value of one metric of one layout.
+type VerifyCheck struct {
+	Layout    string
+	Metric    string
+	Expected  float64
+	Tolerance float64 // Absolute tolerance (0 = the default of the spec)
+}
+
+// VerifyResult is the outcome of a check.
+type VerifyResult struct {
+	VerifyCheck
+	Actual float64 // Computed value (NaN if the metric was not computed)
+	Pass   bool
+}
+
+// NewVerifySpecFromFile loads reference values from a file with one setting or check per line:
+//
+//	corpus default.txt
+//	tolerance 0.001
+//	<layout> <metric> <expected> [<tolerance>]
+//
+// Lines starting with '#' are comments.
+func NewVerifySpecFromFile(path string) (*VerifySpec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open verify file: %w", err)
+	}
+	defer CloseFile(file)
+
+	spec := &VerifySpec{Tolerance: DefaultVerifyTolerance}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := spec.parseLine(line); err != nil {
+			return nil, fmt.Errorf("invalid line %d in %s: %w", lineNum, path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read verify file: %w", err)
+	}
+
+	if spec.Corpus == "" {
+		return nil, fmt.Errorf("missing corpus in %s", path)
+	}
+	if len(spec.Checks) == 0 {
+		return nil, fmt.Errorf("no reference values in %s", path)
+	}
+	return spec, nil
+}
+
+// parseLine parses a setting or a check.
+func (spec *VerifySpec) parseLine(line string) error {
+	fields := strings.Fields(line)
+	parseValue := func(s, what string) (float64, error) {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("invalid %s %q", what, s)
+		}
+		return v, nil
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "corpus":
+		if len(fields) != 2 {
+			return fmt.Errorf("expected \"corpus <file>\"")
+		}
+		spec.Corpus = fields[1]
+		return nil
+	case "tolerance":
+		if len(fields) != 2 {
+			return fmt.Errorf("expected \"tolerance <value>\"")
+		}
+		tol, err := parseValue(fields[1], "tolerance")
+		if err != nil || tol < 0 {
+			return fmt.Errorf("invalid tolerance %q: must be a number >= 0", fields[1])
+		}
+		spec.Tolerance = tol
+		return nil
+	}
+
+	if len(fields) != 3 && len(fields) != 4 {
+		return fmt.Errorf("expected \"<layout> <metric> <expected> [<tolerance>]\"")
+	}
+	check := VerifyCheck{Layout: fields[0], Metric: strings.ToUpper(fields[1])}
+	var err error
+	if check.Expected, err = parseValue(fields[2], "expected value"); err != nil {
+		return err
+	}
+	if len(fields) == 4 {
+		if check.Tolerance, err = parseValue(fields[3], "tolerance"); err != nil || check.Tolerance < 0 {
+			return fmt.Errorf("invalid tolerance %q: must be a number >= 0", fields[3])
+		}
+	}
+	spec.Checks = append(spec.Checks, check)
+	return nil
+}
+
+// Layouts returns the names of the layouts with reference values, in file order.
+func (spec *VerifySpec) Layouts() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, check := range spec.Checks {
+		if !seen[check.Layout] {
+			seen[check.Layout] = true
+			names = append(names, check.Layout)
+		}
+	}
+	return names
+}
+
+// Verify compares the metrics of the analysed layouts (by layout name) to the reference
+// values. A check of a layout without an analyser fails.
+func (spec *VerifySpec) Verify(analysers map[string]*Analyser) []VerifyResult {
+	results := make([]VerifyResult, len(spec.Checks))
+	for i, check := range spec.Checks {
+		if check.Tolerance == 0 {
+			check.Tolerance = spec.Tolerance
+		}
+		res := VerifyResult{VerifyCheck: check, Actual: math.NaN()}
+		if an, ok := analysers[check.Layout]; ok {
+			if v, ok := an.Metrics[check.Metric]; ok {
+				res.Actual = v
+				// Allow for rounding of the reference value in the file
+				res.Pass = math.Abs(v-check.Expected) <= check.Tolerance+1e-9
+			}
+		}
+		results[i] = res
+	}
+	return results
+}
+
+// Update replaces the reference values with the metrics of the analysed layouts.
+func (spec *VerifySpec) Update(analysers map[string]*Analyser) error {
+	for i, check := range spec.Checks {
+		an, ok := analysers[check.Layout]
+		if !ok {
+			return fmt.Errorf("layout %s was not analysed", check.Layout)
+		}
+		v, ok := an.Metrics[check.Metric]
+		if !ok {
+			return fmt.Errorf("unknown metric %s of layout %s", check.Metric, check.Layout)
+		}
+		spec.Checks[i].Expected = v
+	}
+	return nil
+}
+
+// SaveToFile writes the spec in the format of NewVerifySpecFromFile.
+func (spec *VerifySpec) SaveToFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create verify file: %w", err)
+	}
+	defer CloseFile(file)
+
+	writer := bufio.NewWriter(file)
+	defer FlushWriter(writer)
+
+	_, _ = fmt.Fprintln(writer, "# Reference metric values for 'keycraft verify'")
+	_, _ = fmt.Fprintln(writer, "# Format: <layout> <metric> <expected> [<tolerance>]")
+	_, _ = fmt.Fprintf(writer, "corpus %s\n", spec.Corpus)
+	_, _ = fmt.Fprintf(writer, "tolerance %g\n", spec.Tolerance)
+
+	prev := ""
+	for _, check := range spec.Checks {
+		if check.Layout != prev {
+			_, _ = fmt.Fprintln(writer)
+			prev = check.Layout
+		}
+		_, _ = fmt.Fprintf(writer, "%s %s %.4f", check.Layout, check.Metric, check.Expected)
+		if check.Tolerance != 0 {
+			_, _ = fmt.Fprintf(writer, " %g", check.Tolerance)
+		}
+		_, _ = fmt.Fprintln(writer)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package keycraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeVerifyFile writes a verify file and returns its path.
+func writeVerifyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "verify.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewVerifySpecFromFile(t *testing.T) {
+	path := writeVerifyFile(t, `# comment
+corpus test.txt
+tolerance 0.01
+
+a sfb 1.5
+a ALT 30 0.5
+b SFB 2
+`)
+	spec := Must(NewVerifySpecFromFile(path))
+
+	if spec.Corpus != "test.txt" || spec.Tolerance != 0.01 {
+		t.Errorf("corpus %q, tolerance %v, want test.txt, 0.01", spec.Corpus, spec.Tolerance)
+	}
+	if len(spec.Checks) != 3 {
+		t.Fatalf("got %d checks, want 3", len(spec.Checks))
+	}
+	if got := spec.Checks[0]; got != (VerifyCheck{"a", "SFB", 1.5, 0}) {
+		t.Errorf("check 0 = %+v", got)
+	}
+	if got := spec.Checks[1]; got != (VerifyCheck{"a", "ALT", 30, 0.5}) {
+		t.Errorf("check 1 = %+v", got)
+	}
+	if got := spec.Layouts(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("layouts = %v, want [a b]", got)
+	}
+
+	// Saving and loading gives the same spec
+	saved := filepath.Join(t.TempDir(), "saved.txt")
+	if err := spec.SaveToFile(saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded := Must(NewVerifySpecFromFile(saved))
+	if loaded.Corpus != spec.Corpus || loaded.Tolerance != spec.Tolerance || len(loaded.Checks) != 3 {
+		t.Fatalf("reloaded spec %+v, want %+v", loaded, spec)
+	}
+	for i := range spec.Checks {
+		if loaded.Checks[i] != spec.Checks[i] {
+			t.Errorf("reloaded check %d = %+v, want %+v", i, loaded.Checks[i], spec.Checks[i])
+		}
+	}
+}
+
+func TestNewVerifySpecFromFile_Errors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"missing corpus", "a SFB 1\n", "missing corpus"},
+		{"no checks", "corpus test.txt\n", "no reference values"},
+		{"bad line", "corpus test.txt\na SFB\n", "invalid line 2"},
+		{"bad value", "corpus test.txt\na SFB x\n", "invalid expected value"},
+		{"bad tolerance", "corpus test.txt\ntolerance -1\na SFB 1\n", "invalid tolerance"},
+		{"bad check tolerance", "corpus test.txt\na SFB 1 NaN\n", "invalid tolerance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifySpecFromFile(writeVerifyFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySpec_Verify(t *testing.T) {
+	an := &Analyser{Metrics: map[string]float64{"SFB": 1.2345, "ALT": 30}}
+	spec := &VerifySpec{
+		Tolerance: 0.001,
+		Checks: []VerifyCheck{
+			{Layout: "a", Metric: "SFB", Expected: 1.2345},
+			{Layout: "a", Metric: "SFB", Expected: 1.2335},
+			{Layout: "a", Metric: "ALT", Expected: 31, Tolerance: 1},
+			{Layout: "a", Metric: "ALT", Expected: 31},
+			{Layout: "a", Metric: "XYZ", Expected: 0},
+			{Layout: "b", Metric: "SFB", Expected: 0},
+		},
+	}
+
+	results := spec.Verify(map[string]*Analyser{"a": an})
+	wantPass := []bool{true, true, true, false, false, false}
+	for i, res := range results {
+		if res.Pass != wantPass[i] {
+			t.Errorf("check %d (%+v): pass = %v, want %v", i, res.VerifyCheck, res.Pass, wantPass[i])
+		}
+	}
+	if results[2].Tolerance != 1 || results[3].Tolerance != 0.001 {
+		t.Errorf("tolerances %v, %v, want 1, 0.001", results[2].Tolerance, results[3].Tolerance)
+	}
+	if !math.IsNaN(results[4].Actual) || !math.IsNaN(results[5].Actual) {
+		t.Error("missing metrics and layouts should have a NaN actual value")
+	}
+
+	// Updating to the computed values makes all checks of known metrics pass
+	if err := spec.Update(map[string]*Analyser{"a": an}); err == nil {
+		t.Error("expected error updating unknown metric, got nil")
+	}
+	spec.Checks = spec.Checks[:4]
+	if err := spec.Update(map[string]*Analyser{"a": an}); err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range spec.Verify(map[string]*Analyser{"a": an}) {
+		if !res.Pass {
+			t.Errorf("check %+v fails after update", res.VerifyCheck)
+		}
+	}
+}
+
+// TestBundledReferenceValues checks the metrics of the bundled layouts against the bundled
+// reference values, so that changes to the metric definitions are caught by the tests.
+func TestBundledReferenceValues(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping bundled reference values in short mode")
+	}
+	spec := Must(NewVerifySpecFromFile("../../data/config/verify.txt"))
+	corpus := Must(NewCorpusFromFile(spec.Corpus, filepath.Join("../../data/corpus", spec.Corpus), false, 98))
+
+	analysers := make(map[string]*Analyser)
+	for _, name := range spec.Layouts() {
+		layout := Must(NewLayoutFromFile(name, filepath.Join("../../data/layouts", name+".klf")))
+		analysers[name] = NewAnalyser(layout, corpus, NewTargetLoads())
+	}
+	for _, res := range spec.Verify(analysers) {
+		if !res.Pass {
+			t.Errorf("%s %s = %.4f, want %.4f ± %g",
+				res.Layout, res.Metric, res.Actual, res.Expected, res.Tolerance)
+		}
+	}
+}
@@ -1,41 +1,70 @@
 package keycraft
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
-	"strings"
 )
 
 // ViewInput contains parameters for viewing layout analysis.
 // This is pure computational input - no display/rendering concerns.
 type ViewInput struct {
-	LayoutFiles []string     // Full filepaths to layout files to view
-	Corpus      *Corpus      // Text corpus for analysis
-	Targets     *TargetLoads // User target loads
+	LayoutFiles []string       // Full filepaths to layout files to view
+	Layouts     []*SplitLayout // Layouts to view after the files, that need not be saved (e.g. with --dry-run)
+	Corpus      *Corpus        // Text corpus for analysis
+	Targets     *TargetLoads   // User target loads
+	PinsDir     string         // Directory of the pins files of the layouts, as <layout>.pin ("" = none)
 }
 
 // ViewResult contains the analysis results for viewing layouts.
 // Display-agnostic - just the data.
 type ViewResult struct {
-	Analysers []*Analyser // Analysis results for each layout
+	Analysers []*Analyser   // Analysis results for each layout
+	Pins      []*PinnedKeys // Pinned keys of each layout (nil = none)
 }
 
 // ViewLayouts performs layout analysis for viewing.
 // Pure computation - no I/O, no rendering, no display logic.
 func ViewLayouts(input ViewInput) (*ViewResult, error) {
-	analysers := make([]*Analyser, 0, len(input.LayoutFiles))
-	for _, path := range input.LayoutFiles {
-		// Extract layout name from filename (remove directory and extension)
-		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
-		layout, err := NewLayoutFromFile(name, path)
+	analysers, err := analyseLayoutFiles(input.LayoutFiles, input.Corpus, input.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("could not create new layout from file: %w", err)
+	}
+	for _, layout := range input.Layouts {
+		analysers = append(analysers, NewAnalyser(layout, input.Corpus, withDefaultTargets(input.Targets)))
+	}
+
+	pins := make([]*PinnedKeys, len(analysers))
+	for i, an := range analysers {
+		pins[i], err = layoutPins(an.Layout, input.PinsDir)
 		if err != nil {
-			return nil, fmt.Errorf("could not create new layout from file: %w", err)
+			return nil, err
 		}
-		analyser := NewAnalyser(layout, input.Corpus, input.Targets)
-		analysers = append(analysers, analyser)
 	}
 
 	return &ViewResult{
 		Analysers: analysers,
+		Pins:      pins,
 	}, nil
 }
+
+// layoutPins returns the keys that were pinned when optimize produced the layout, or else
+// the keys of the pins file of the layout in dir, if there is one.
+func layoutPins(sl *SplitLayout, dir string) (*PinnedKeys, error) {
+	if sl.Provenance != nil && sl.Provenance.Pins != nil {
+		return sl.Provenance.Pins, nil
+	}
+	if dir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(dir, sl.Name+".pin")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	pins, err := LoadPins(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load pins of %s: %w", sl.Name, err)
+	}
+	return pins, nil
+}
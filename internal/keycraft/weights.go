@@ -1,9 +1,11 @@
 package keycraft
 
 import (
+	"bufio"
 	"fmt"
 	"maps"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -12,7 +14,8 @@ import (
 // Metrics not explicitly set in the input string default to predefined values.
 // ALT, ROL, and ONE have default negative weights since they represent positive aspects.
 type Weights struct {
-	weights map[string]float64
+	weights  map[string]float64
+	explicit map[string]bool // Metrics with their own weight, which a group weight does not change
 }
 
 // DefaultMetrics contains built-in metric weights used as defaults when no custom weight is provided.
@@ -20,11 +23,46 @@ var DefaultMetrics = map[string]float64{
 	"SFB": -1.0,
 }
 
+// EqualWeightsName is the value of --weights for the built-in equal weights (see
+// NewEqualWeights), optionally followed by metric=weight pairs that override them.
+const EqualWeightsName = "equal"
+
+// equalWeights are the built-in equal weights: the metrics of the basic metric set except
+// IN:OUT, which is a ratio, each weighted -1 if lower is better or 1 if higher is better.
+var equalWeights = map[string]float64{
+	"SFB": -1, "LSB": -1, "FSB": -1, "HSB": -1,
+	"SFS": -1,
+	"RED": -1, "RED-WEAK": -1, "ALT": 1, "2RL": 1, "3RL": 1,
+	"FLW": 1,
+	"HLD": -1, "FLD": -1, "RLD": -1, "POH": -1,
+}
+
+// NewEqualWeights returns the built-in equal weights, which give every metric of a standard
+// set the same magnitude. As they don't depend on a weights file, they give a neutral
+// baseline for comparing rankings.
+func NewEqualWeights() *Weights {
+	w := &Weights{weights: maps.Clone(equalWeights), explicit: make(map[string]bool, len(equalWeights))}
+	for metric := range equalWeights {
+		w.explicit[metric] = true
+	}
+	return w
+}
+
+// UsesEqualWeights reports whether a --weights string selects the built-in equal weights,
+// and returns the metric=weight pairs that follow it.
+func UsesEqualWeights(weightsStr string) (bool, string) {
+	first, rest, _ := strings.Cut(weightsStr, ",")
+	if !strings.EqualFold(strings.TrimSpace(first), EqualWeightsName) {
+		return false, weightsStr
+	}
+	return true, rest
+}
+
 // NewWeights creates an empty Weights structure ready to be populated.
 func NewWeights() *Weights {
 	weights := make(map[string]float64)
 	maps.Copy(weights, DefaultMetrics)
-	return &Weights{weights}
+	return &Weights{weights: weights, explicit: make(map[string]bool)}
 }
 
 // NewWeightsFromString parses a comma-separated `metric=weight` string into a Weights instance.
@@ -38,8 +76,17 @@ func NewWeightsFromString(weightsStr string) (*Weights, error) {
 	return w, nil
 }
 
-// NewWeightsFromParams constructs weights from an optional file and CLI string.
+// NewWeightsFromParams constructs weights from an optional file and CLI string. A CLI
+// string that starts with "equal" uses the built-in equal weights instead of the file.
 func NewWeightsFromParams(path, weightsStr string) (*Weights, error) {
+	if equal, rest := UsesEqualWeights(weightsStr); equal {
+		weights := NewEqualWeights()
+		if err := weights.AddWeightsFromString(rest); err != nil {
+			return nil, fmt.Errorf("could not parse weights from string: %w", err)
+		}
+		return weights, nil
+	}
+
 	weights := NewWeights()
 
 	// Load weights from a file if specified.
@@ -58,6 +105,7 @@ func NewWeightsFromParams(path, weightsStr string) (*Weights, error) {
 }
 
 // AddWeightsFromFile reads weights from a file (ignoring comments/blanks) and applies them to the receiver.
+// Lines starting with "group" define group weights (see AddGroupFromString).
 func (w *Weights) AddWeightsFromFile(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -66,10 +114,58 @@ func (w *Weights) AddWeightsFromFile(path string) error {
 
 	for line := range strings.SplitSeq(string(data), "\n") {
 		line = strings.TrimSpace(line)
-		if !strings.HasPrefix(line, "#") && line != "" {
-			if err := w.AddWeightsFromString(line); err != nil {
-				return fmt.Errorf("could not parse weights from file %q: %w", path, err)
-			}
+		if strings.HasPrefix(line, "#") || line == "" {
+			continue
+		}
+		add := w.AddWeightsFromString
+		if isGroupLine(line) {
+			add = w.AddGroupFromString
+		}
+		if err := add(line); err != nil {
+			return fmt.Errorf("could not parse weights from file %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// isGroupLine reports whether a line of a weights file defines a group weight.
+func isGroupLine(line string) bool {
+	keyword, _, ok := strings.Cut(line, " ")
+	return ok && strings.EqualFold(keyword, "group")
+}
+
+// AddGroupFromString parses and applies a group weight of the form
+// "group bigrams = SFB,LSB,FSB,HSB @ -2.0", which sets the weight of every member of the
+// group. Members with their own weight, set before or after the group, keep it; so a group
+// weight is a single knob for the metrics of a group, with per-member overrides.
+func (w *Weights) AddGroupFromString(groupStr string) error {
+	def := strings.TrimSpace(groupStr)
+	if !isGroupLine(def) {
+		return fmt.Errorf("invalid group weight %q: must start with \"group\"", groupStr)
+	}
+	name, rest, ok := strings.Cut(strings.TrimSpace(def[len("group"):]), "=")
+	members, weightStr, ok2 := strings.Cut(rest, "@")
+	name = strings.TrimSpace(name)
+	if !ok || !ok2 || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid group weight %q: expected group <name> = <metric>,... @ <weight>", groupStr)
+	}
+
+	weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+	if err != nil {
+		return fmt.Errorf("invalid weight value for group %s", name)
+	}
+	metrics := make([]string, 0, 4)
+	for metric := range strings.SplitSeq(strings.ToUpper(members), ",") {
+		metric = strings.TrimSpace(metric)
+		if !slices.Contains(MetricsMap["all"], metric) {
+			return fmt.Errorf("invalid metric %q in group %s; run with --metrics=all to see all available metrics", metric, name)
+		}
+		metrics = append(metrics, metric)
+	}
+
+	for _, metric := range metrics {
+		if !w.explicit[metric] {
+			w.weights[metric] = weight
 		}
 	}
 	return nil
@@ -108,11 +204,17 @@ func (w *Weights) AddWeightsFromString(weightsStr string) error {
 			return fmt.Errorf("invalid weight value for metric %s", metric)
 		}
 		w.weights[metric] = weight
+		w.explicit[metric] = true
 	}
 
 	return nil
 }
 
+// Clone returns a copy of the weights that can be changed without changing w.
+func (w *Weights) Clone() *Weights {
+	return &Weights{weights: maps.Clone(w.weights), explicit: maps.Clone(w.explicit)}
+}
+
 // Get returns the weight for a metric or 0 if not present.
 func (w *Weights) Get(metric string) float64 {
 	if val, ok := w.weights[metric]; ok {
@@ -120,3 +222,32 @@ func (w *Weights) Get(metric string) float64 {
 	}
 	return 0.0
 }
+
+// SaveToFile writes the weights of the metrics in the format of a weights file, after the
+// header lines as comments. SFB is always written, so that its default weight does not
+// return when the file is loaded.
+func (w *Weights) SaveToFile(path string, header []string, metrics []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create weights file: %w", err)
+	}
+	defer CloseFile(file)
+
+	writer := bufio.NewWriter(file)
+	defer FlushWriter(writer)
+
+	for _, line := range header {
+		_, _ = fmt.Fprintf(writer, "# %s\n", line)
+	}
+	if !slices.Contains(metrics, "SFB") {
+		metrics = append(slices.Clone(metrics), "SFB")
+	}
+	width := 0
+	for _, metric := range metrics {
+		width = max(width, len(metric))
+	}
+	for _, metric := range metrics {
+		_, _ = fmt.Fprintf(writer, "%-*s = %g\n", width, metric, w.Get(metric))
+	}
+	return nil
+}
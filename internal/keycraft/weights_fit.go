@@ -0,0 +1,194 @@
+package keycraft
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+const (
+	// fitIterations is the number of gradient descent steps of FitWeights.
+	fitIterations = 2000
+	// fitLearningRate is the step size of the gradient descent of FitWeights.
+	fitLearningRate = 0.5
+	// fitMaxWeight is the largest absolute weight after scaling the fitted weights.
+	fitMaxWeight = 10
+)
+
+// FitWeightsInput contains the parameters for learning weights from preferred and avoided
+// layouts.
+type FitWeightsInput struct {
+	LayoutsDir     string        // Layouts used to normalise metrics, like rank
+	Reference      *ReferenceSet // Layouts in LayoutsDir used for normalisation (nil = default)
+	Corpus         *Corpus       // Corpus the layouts are analysed against
+	Targets        *TargetLoads  // Load targets
+	PreferredDir   string        // Directory with the layouts that should score high
+	AvoidedDir     string        // Directory with the layouts that should score low
+	Metrics        []string      // Metrics to learn weights for
+	Regularisation float64       // L2 penalty that keeps the weights small (0 = none)
+}
+
+// WeightFit is the result of FitWeights.
+type WeightFit struct {
+	Weights   *Weights      // Learned weights, in the scale of a weights file
+	Metrics   []string      // Metrics with learned weights (metrics without spread are left out)
+	Preferred []LayoutScore // Scores of the preferred layouts under the learned weights
+	Avoided   []LayoutScore // Scores of the avoided layouts under the learned weights
+	Pairs     int           // Number of (preferred, avoided) layout pairs
+	Ordered   int           // Pairs in which the preferred layout scores higher
+}
+
+// FitWeights learns metric weights under which the preferred layouts score higher than the
+// avoided layouts. It fits a pairwise logistic regression, the Bradley-Terry model, over the
+// metrics normalised like rank does: for every (preferred, avoided) pair, the probability
+// that the preferred layout wins grows with its score difference. The weights are scaled so
+// that the largest one is fitMaxWeight, which does not change the ranking.
+//
+// When no weights rank all preferred layouts above all avoided ones, the result orders as
+// many pairs correctly as it can; Ordered reports how many.
+func FitWeights(input FitWeightsInput) (*WeightFit, error) {
+	reference, err := loadReferenceAnalysers(input.LayoutsDir, input.Corpus, input.Targets, input.Reference)
+	if err != nil {
+		return nil, err
+	}
+	medians, iqrs := computeMediansAndIQR(reference, nil)
+
+	preferred, err := loadFitAnalysers(input.PreferredDir, input)
+	if err != nil {
+		return nil, err
+	}
+	avoided, err := loadFitAnalysers(input.AvoidedDir, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Metrics that do not vary among the reference layouts cannot be normalised
+	var metrics []string
+	for _, metric := range input.Metrics {
+		if iqrs[metric] > 1e-9 && !slices.Contains(metrics, metric) {
+			metrics = append(metrics, metric)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("none of the metrics vary among the layouts in %s", input.LayoutsDir)
+	}
+
+	normalise := func(an *Analyser) []float64 {
+		z := make([]float64, len(metrics))
+		for i, metric := range metrics {
+			z[i] = (an.Metrics[metric] - medians[metric]) / iqrs[metric]
+		}
+		return z
+	}
+	var diffs [][]float64
+	for _, p := range preferred {
+		zp := normalise(p)
+		for _, a := range avoided {
+			za := normalise(a)
+			d := make([]float64, len(metrics))
+			for i := range d {
+				d[i] = zp[i] - za[i]
+			}
+			diffs = append(diffs, d)
+		}
+	}
+
+	w := fitPairwiseLogistic(diffs, input.Regularisation)
+
+	// Scale to the range of a weights file and round, dropping negligible weights
+	maxAbs := 0.0
+	for _, v := range w {
+		maxAbs = max(maxAbs, math.Abs(v))
+	}
+	weights := NewWeights()
+	for i, metric := range metrics {
+		v := 0.0
+		if maxAbs > 0 {
+			v = math.Round(w[i]/maxAbs*fitMaxWeight*1000) / 1000
+		}
+		if math.Abs(v) < 0.01 {
+			v = 0
+		}
+		weights.weights[metric] = v
+	}
+	if !slices.Contains(metrics, "SFB") {
+		weights.weights["SFB"] = 0 // Override the default weight
+	}
+
+	fit := &WeightFit{
+		Weights:   weights,
+		Metrics:   metrics,
+		Preferred: sortedScores(computeScores(preferred, medians, iqrs, weights)),
+		Avoided:   sortedScores(computeScores(avoided, medians, iqrs, weights)),
+		Pairs:     len(diffs),
+	}
+	for _, p := range fit.Preferred {
+		for _, a := range fit.Avoided {
+			if p.Score > a.Score {
+				fit.Ordered++
+			}
+		}
+	}
+	return fit, nil
+}
+
+// loadFitAnalysers analyses all layouts in a directory, sorted by name.
+func loadFitAnalysers(dir string, input FitWeightsInput) ([]*Analyser, error) {
+	analysers, err := LoadAnalysers(dir, input.Corpus, input.Targets, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(analysers) == 0 {
+		return nil, fmt.Errorf("no layouts found in %s", dir)
+	}
+	slices.SortFunc(analysers, func(a, b *Analyser) int {
+		return strings.Compare(a.Layout.Name, b.Layout.Name)
+	})
+	return analysers, nil
+}
+
+// sortedScores sorts layout scores from high to low.
+func sortedScores(scores []LayoutScore) []LayoutScore {
+	slices.SortStableFunc(scores, func(a, b LayoutScore) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return scores
+}
+
+// fitPairwiseLogistic minimises the mean logistic loss log(1 + exp(-w·d)) over the score
+// differences d, plus an L2 penalty of lambda/2 * |w|², by gradient descent from w = 0.
+func fitPairwiseLogistic(diffs [][]float64, lambda float64) []float64 {
+	if len(diffs) == 0 {
+		return nil
+	}
+	w := make([]float64, len(diffs[0]))
+	grad := make([]float64, len(w))
+	for range fitIterations {
+		for i := range grad {
+			grad[i] = lambda * w[i]
+		}
+		for _, d := range diffs {
+			margin := 0.0
+			for i, v := range d {
+				margin += w[i] * v
+			}
+			// d/dw log(1 + exp(-m)) = -d / (1 + exp(m))
+			g := -1 / (1 + math.Exp(margin)) / float64(len(diffs))
+			for i, v := range d {
+				grad[i] += g * v
+			}
+		}
+		for i := range w {
+			w[i] -= fitLearningRate * grad[i]
+		}
+	}
+	return w
+}
+
+// SaveToFile writes the learned weights in the format of a weights file, after the header
+// lines as comments.
+func (fit *WeightFit) SaveToFile(path string, header []string) error {
+	return fit.Weights.SaveToFile(path, header, fit.Metrics)
+}
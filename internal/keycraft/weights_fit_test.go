@@ -0,0 +1,89 @@
+package keycraft
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyBundledLayouts copies bundled layouts into a new temporary directory.
+func copyBundledLayouts(t *testing.T, names ...string) string {
+	t.Helper()
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("../../data/layouts", name+".klf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name+".klf"] = string(data)
+	}
+	return writeTestLayouts(t, files)
+}
+
+func TestFitWeights(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+	corpus.addTextWithWords("sphinx of black quartz, judge my vow. how vexingly quick daft zebras jump!")
+
+	fit, err := FitWeights(FitWeightsInput{
+		LayoutsDir:     copyBundledLayouts(t, "qwerty", "dvorak", "colemak-dh", "canary", "sturdy"),
+		Corpus:         corpus,
+		Targets:        NewTargetLoads(),
+		PreferredDir:   copyBundledLayouts(t, "colemak-dh", "canary"),
+		AvoidedDir:     copyBundledLayouts(t, "qwerty", "dvorak"),
+		Metrics:        []string{"SFB", "LSB", "HLD", "SFB"},
+		Regularisation: 0.01,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fit.Metrics) != 3 {
+		t.Errorf("fitted metrics %v, want 3 distinct metrics", fit.Metrics)
+	}
+	if fit.Pairs != 4 || fit.Ordered != 4 {
+		t.Errorf("%d of %d pairs ordered, want 4 of 4", fit.Ordered, fit.Pairs)
+	}
+	for _, ls := range fit.Avoided {
+		if ls.Score >= fit.Preferred[len(fit.Preferred)-1].Score {
+			t.Errorf("avoided %s scores %v, not below all preferred layouts", ls.Name, ls.Score)
+		}
+	}
+	largest := 0.0
+	for _, metric := range fit.Metrics {
+		largest = max(largest, math.Abs(fit.Weights.Get(metric)))
+	}
+	if largest != fitMaxWeight {
+		t.Errorf("largest weight = %v, want %v", largest, fitMaxWeight)
+	}
+
+	// The saved weights load back as the same weights
+	path := filepath.Join(t.TempDir(), "weights.txt")
+	if err := fit.SaveToFile(path, []string{"test"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded := Must(NewWeightsFromParams(path, ""))
+	for _, metric := range fit.Metrics {
+		if loaded.Get(metric) != fit.Weights.Get(metric) {
+			t.Errorf("loaded %s weight = %v, want %v", metric, loaded.Get(metric), fit.Weights.Get(metric))
+		}
+	}
+}
+
+func TestFitPairwiseLogistic(t *testing.T) {
+	// The first feature separates the pairs, the second is noise
+	diffs := [][]float64{{1, 0.5}, {2, -0.5}, {0.5, 1}, {1.5, -1}}
+	w := fitPairwiseLogistic(diffs, 0.01)
+	for _, d := range diffs {
+		if w[0]*d[0]+w[1]*d[1] <= 0 {
+			t.Errorf("pair %v is not ordered by weights %v", d, w)
+		}
+	}
+	if math.Abs(w[1]) >= w[0] {
+		t.Errorf("noise weight %v should be smaller than %v", w[1], w[0])
+	}
+	if fitPairwiseLogistic(nil, 0) != nil {
+		t.Error("no pairs should give no weights")
+	}
+}
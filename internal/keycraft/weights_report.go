@@ -0,0 +1,148 @@
+package keycraft
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// minScoredWeightShare is the largest absolute weight that the scorer ignores, as a share
+	// of the sum of the absolute weights, so that scaling the weights ignores the same ones.
+	minScoredWeightShare = 1e-4
+	// minScoredIQR is the largest IQR of a metric among the reference layouts for which the
+	// scorer ignores the metric, as it cannot be normalised.
+	minScoredIQR = 1e-9
+)
+
+// WeightReportInput contains the parameters for reporting the influence of weights.
+type WeightReportInput struct {
+	LayoutsDir string        // Layouts used to normalise metrics, like rank
+	Reference  *ReferenceSet // Layouts in LayoutsDir used for normalisation (nil = default)
+	Corpus     *Corpus       // Corpus the layouts are analysed against
+	Targets    *TargetLoads  // Load targets
+	Weights    *Weights      // Weights to report on
+	Normalise  bool          // Scale the weights so that their absolute values sum to 1
+}
+
+// WeightInfluence is the influence of the weight of one metric on the scores.
+type WeightInfluence struct {
+	Metric    string
+	Weight    float64 // Weight, normalised if the report is
+	IQR       float64 // IQR of the metric among the reference layouts
+	Spread    float64 // Mean distance of the reference layouts from the median, in IQRs
+	Influence float64 // |Weight| × Spread: how much the metric moves a typical score
+	Share     float64 // Share of the influence of all weights, in %
+	Ignored   string  // Why the scorer ignores the weight ("" = it doesn't)
+}
+
+// WeightReport is the result of BuildWeightReport.
+type WeightReport struct {
+	Weights    *Weights          // Weights reported on, normalised if requested
+	Normalised bool              // Whether the weights were normalised
+	Metrics    []WeightInfluence // Weighted metrics, most influential first, then the ignored ones
+	References int               // Number of reference layouts
+}
+
+// BuildWeightReport reports the effective influence of each weighted metric on the scores.
+// As rank normalises every metric by its median and IQR among the reference layouts, a
+// weight says how much a metric counts per IQR, and the typical distance of the layouts
+// from the median in IQRs differs between metrics. The influence of a weight is the weight
+// times that distance. Weights that the scorer ignores, because they are tiny or because the
+// metric has no IQR among the reference layouts, are reported with the reason.
+func BuildWeightReport(input WeightReportInput) (*WeightReport, error) {
+	reference, err := loadReferenceAnalysers(input.LayoutsDir, input.Corpus, input.Targets, input.Reference)
+	if err != nil {
+		return nil, err
+	}
+	medians, iqrs := computeMediansAndIQR(reference, nil)
+
+	weights := input.Weights
+	if input.Normalise {
+		weights = weights.Normalised()
+	}
+	report := &WeightReport{Weights: weights, Normalised: input.Normalise, References: len(reference)}
+
+	var total float64
+	cutoff := weights.minScored()
+	for _, metric := range weights.Metrics() {
+		row := WeightInfluence{Metric: metric, Weight: weights.Get(metric), IQR: iqrs[metric]}
+		switch _, analysed := medians[metric]; {
+		case !analysed:
+			row.Ignored = "not a metric of the reference layouts"
+		case math.Abs(row.Weight) <= cutoff:
+			row.Ignored = fmt.Sprintf("|weight| at most %g%% of all weights", 100*minScoredWeightShare)
+		case row.IQR <= minScoredIQR:
+			row.Ignored = "IQR of 0 among the reference layouts"
+		default:
+			for _, an := range reference {
+				row.Spread += math.Abs(an.Metrics[metric]-medians[metric]) / row.IQR
+			}
+			row.Spread /= float64(len(reference))
+			row.Influence = math.Abs(row.Weight) * row.Spread
+			total += row.Influence
+		}
+		report.Metrics = append(report.Metrics, row)
+	}
+	for i := range report.Metrics {
+		if total > 0 {
+			report.Metrics[i].Share = 100 * report.Metrics[i].Influence / total
+		}
+	}
+	slices.SortStableFunc(report.Metrics, func(a, b WeightInfluence) int {
+		return cmp.Or(cmp.Compare(IfThen(a.Ignored != "", 1, 0), IfThen(b.Ignored != "", 1, 0)),
+			cmp.Compare(b.Influence, a.Influence))
+	})
+	return report, nil
+}
+
+// absSum returns the sum of the absolute weights.
+func (w *Weights) absSum() float64 {
+	var sum float64
+	for _, weight := range w.weights {
+		sum += math.Abs(weight)
+	}
+	return sum
+}
+
+// minScored returns the largest absolute weight that the scorer ignores. Often, tiny weights
+// are assigned to have metrics in the weights, but not to count towards anything. The cutoff
+// is relative to the sum of the absolute weights, so that scaling the weights does not
+// change which metrics count.
+func (w *Weights) minScored() float64 {
+	return minScoredWeightShare * w.absSum()
+}
+
+// Metrics returns the metrics with a non-zero weight, in the order of the "all" metric set
+// followed by the other metrics by name.
+func (w *Weights) Metrics() []string {
+	var metrics []string
+	for _, metric := range MetricsMap["all"] {
+		if w.weights[metric] != 0 {
+			metrics = append(metrics, metric)
+		}
+	}
+	var others []string
+	for metric, weight := range w.weights {
+		if weight != 0 && !slices.Contains(metrics, metric) {
+			others = append(others, metric)
+		}
+	}
+	slices.Sort(others)
+	return append(metrics, others...)
+}
+
+// Normalised returns a copy of the weights scaled so that their absolute values sum to 1,
+// which makes weights files comparable. The ranking of layouts does not change.
+func (w *Weights) Normalised() *Weights {
+	normalised := w.Clone()
+	sum := w.absSum()
+	if sum == 0 {
+		return normalised
+	}
+	for metric, weight := range w.weights {
+		normalised.weights[metric] = weight / sum
+	}
+	return normalised
+}
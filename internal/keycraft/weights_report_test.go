@@ -0,0 +1,74 @@
+package keycraft
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWeights_Normalised(t *testing.T) {
+	weights := Must(NewWeightsFromString("SFB=-3,LSB=-1,ALT=4"))
+	normalised := weights.Normalised()
+	for metric, want := range map[string]float64{"SFB": -0.375, "LSB": -0.125, "ALT": 0.5} {
+		if got := normalised.Get(metric); math.Abs(got-want) > 1e-12 {
+			t.Errorf("normalised %s = %v, want %v", metric, got, want)
+		}
+	}
+	if weights.Get("SFB") != -3 {
+		t.Errorf("normalising changed the original weights: SFB = %v", weights.Get("SFB"))
+	}
+	if got := weights.Metrics(); len(got) != 3 || got[0] != "SFB" || got[1] != "LSB" || got[2] != "ALT" {
+		t.Errorf("weighted metrics %v, want SFB, LSB, ALT", got)
+	}
+}
+
+// TestBuildWeightReport checks that the influence of a weight is the weight times the spread
+// of its metric, that the shares add up to 100%, and that the weights that the scorer
+// ignores are flagged and come last.
+func TestBuildWeightReport(t *testing.T) {
+	corpus := NewCorpus("test")
+	corpus.addTextWithWords("the quick brown fox jumps over the lazy dog; pack my box with five dozen liquor jugs")
+
+	input := WeightReportInput{
+		LayoutsDir: copyBundledLayouts(t, "qwerty", "dvorak", "colemak-dh", "canary", "sturdy"),
+		Corpus:     corpus,
+		Targets:    NewTargetLoads(),
+		Weights:    Must(NewWeightsFromString("SFB=-2,LSB=-1,HLD=-0.0001,NUM=-1")),
+	}
+	report := Must(BuildWeightReport(input))
+	if len(report.Metrics) != 4 || report.References != 5 {
+		t.Fatalf("%d metrics of %d reference layouts, want 4 of 5", len(report.Metrics), report.References)
+	}
+
+	var shares float64
+	for i, m := range report.Metrics {
+		if i < 2 {
+			if m.Ignored != "" {
+				t.Errorf("%s ignored (%s), want SFB and LSB first", m.Metric, m.Ignored)
+			}
+			if want := math.Abs(m.Weight) * m.Spread; m.Spread <= 0 || math.Abs(m.Influence-want) > 1e-12 {
+				t.Errorf("%s influence %v with spread %v, want %v", m.Metric, m.Influence, m.Spread, want)
+			}
+			shares += m.Share
+			continue
+		}
+		if m.Ignored == "" || m.Influence != 0 {
+			t.Errorf("%s not ignored, want HLD for its tiny weight and NUM for its IQR of 0", m.Metric)
+		}
+	}
+	if math.Abs(shares-100) > 1e-9 {
+		t.Errorf("shares add up to %v%%, want 100%%", shares)
+	}
+
+	// Normalising scales the weights, but not the shares or the ignored weights
+	input.Normalise = true
+	normalised := Must(BuildWeightReport(input))
+	for i, m := range normalised.Metrics {
+		if math.Abs(m.Share-report.Metrics[i].Share) > 1e-9 || m.Ignored != report.Metrics[i].Ignored {
+			t.Errorf("normalised %s share %v (ignored %q), want %v (ignored %q)", m.Metric, m.Share,
+				m.Ignored, report.Metrics[i].Share, report.Metrics[i].Ignored)
+		}
+	}
+	if got, want := normalised.Weights.Get("SFB"), -2/4.0001; math.Abs(got-want) > 1e-12 {
+		t.Errorf("normalised SFB weight %v, want %v", got, want)
+	}
+}
@@ -464,3 +464,74 @@ func BenchmarkAddWeightsFromString(b *testing.B) {
 		_ = weights.AddWeightsFromString(input)
 	}
 }
+
+func TestAddWeightsFromFile_Groups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weights.txt")
+	content := `LSB = -4.0
+group bigrams = SFB, lsb, FSB, HSB @ -2.0
+Group skipgrams = SFS,LSS @ -1
+HSB = -0.5
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	weights := NewWeights()
+	if err := weights.AddWeightsFromFile(path); err != nil {
+		t.Fatalf("AddWeightsFromFile failed: %v", err)
+	}
+
+	// Members with their own weight keep it, whether set before or after the group
+	for metric, want := range map[string]float64{"SFB": -2, "LSB": -4, "FSB": -2, "HSB": -0.5, "SFS": -1, "LSS": -1} {
+		if got := weights.Get(metric); got != want {
+			t.Errorf("Get(%q) = %v, want %v", metric, got, want)
+		}
+	}
+
+	for _, bad := range []string{
+		"group = SFB @ -1",
+		"group bigrams = SFB,LSB",
+		"group bigrams = SFB,XYZ @ -1",
+		"group bigrams = SFB @ lots",
+		"grouping = SFB @ -1",
+	} {
+		if err := NewWeights().AddGroupFromString(bad); err == nil {
+			t.Errorf("AddGroupFromString(%q): expected error", bad)
+		}
+	}
+}
+
+func TestNewWeightsFromParams_Equal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weights.txt")
+	if err := os.WriteFile(path, []byte("SFB = -8\nSFS = -3\nLSS = -1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The equal weights ignore the weights file
+	weights, err := NewWeightsFromParams(path, "Equal")
+	if err != nil {
+		t.Fatalf("NewWeightsFromParams failed: %v", err)
+	}
+	for metric, want := range map[string]float64{"SFB": -1, "SFS": -1, "ALT": 1, "FLW": 1, "HLD": -1, "LSS": 0, "IN:OUT": 0} {
+		if got := weights.Get(metric); got != want {
+			t.Errorf("Get(%q) = %v, want %v", metric, got, want)
+		}
+	}
+	for _, m := range MetricsMap["basic"] {
+		if w := weights.Get(m); w != 1 && w != -1 && m != "IN:OUT" {
+			t.Errorf("Get(%q) = %v, want a magnitude of 1", m, w)
+		}
+	}
+
+	// Pairs after "equal" override the equal weights
+	weights, err = NewWeightsFromParams(path, "equal, SFB=-2, LSS=-1")
+	if err != nil {
+		t.Fatalf("NewWeightsFromParams failed: %v", err)
+	}
+	if weights.Get("SFB") != -2 || weights.Get("LSS") != -1 || weights.Get("LSB") != -1 {
+		t.Errorf("overridden weights = %v", weights.weights)
+	}
+
+	if _, err := NewWeightsFromParams(path, "equal,XYZ=1"); err == nil {
+		t.Error("expected error for an invalid metric after equal")
+	}
+}
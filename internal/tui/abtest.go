@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderABTest renders the comparison of two layouts across the folds of a corpus: the
+// values on the whole corpus, the wins, losses and ties of the first layout, and the range
+// of the differences, followed by whether the first layout beats the second consistently.
+func RenderABTest(result *kc.ABTestResult, corpusName string) {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	tw.SetTitle(fmt.Sprintf("%s vs %s - %d folds of %s", result.A, result.B, result.Folds, corpusName))
+	tw.AppendHeader(table.Row{"Metric", result.A, result.B, "Wins", "Losses", "Ties", "Δ min", "Δ max", ""})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
+		{Number: 7, Align: text.AlignRight},
+		{Number: 8, Align: text.AlignRight},
+	})
+
+	format := func(metric string, val float64) string {
+		if metric == "Score" {
+			return fmt.Sprintf("%+.2f", val)
+		}
+		return formatMetricValue(metric, val)
+	}
+	addRow := func(row kc.ABTestRow) {
+		lo, hi := row.Deltas[0], row.Deltas[0]
+		for _, d := range row.Deltas {
+			lo, hi = min(lo, d), max(hi, d)
+		}
+		verdict := "mixed"
+		switch {
+		case row.Consistent() && row.Wins > 0:
+			verdict = text.FgGreen.Sprint(result.A + " better")
+		case row.Consistent() && row.Losses > 0:
+			verdict = text.FgRed.Sprint(result.B + " better")
+		case row.Ties() == len(row.Deltas):
+			verdict = "equal"
+		}
+		tw.AppendRow(table.Row{row.Metric, format(row.Metric, row.A), format(row.Metric, row.B),
+			row.Wins, row.Losses, row.Ties(), fmt.Sprintf("%+.2f", lo), fmt.Sprintf("%+.2f", hi), verdict})
+	}
+	addRow(result.Score)
+	tw.AppendSeparator()
+	for _, row := range result.Metrics {
+		addRow(row)
+	}
+	fmt.Println(tw.Render())
+
+	score := result.Score
+	switch {
+	case score.Consistent() && score.Wins > 0:
+		fmt.Printf("%s beats %s on all %d folds.\n", result.A, result.B, result.Folds)
+	case score.Consistent() && score.Losses > 0:
+		fmt.Printf("%s beats %s on all %d folds.\n", result.B, result.A, result.Folds)
+	default:
+		fmt.Printf("Neither layout wins consistently: %s wins %d and loses %d of %d folds.\n",
+			result.A, score.Wins, score.Losses, result.Folds)
+	}
+}
@@ -1,7 +1,17 @@
 package tui
 
 import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
@@ -25,71 +35,268 @@ func RenderAnalyse(result *kc.AnalyseResult, opts kc.AnalyseDisplayOptions) erro
 			AlignHeader: text.AlignCenter, Align: text.AlignCenter})
 	}
 	twOuter.SetColumnConfigs(colConfigs)
+	twOuter.SetTitle("Metrics v%d", result.Analysers[0].MetricVersion())
+
+	// Compute the costly cells of all layouts at once
+	columns := detailColumns(result.Analysers, opts)
 
 	// Add header
-	h := table.Row{""}
+	header := table.Row{""}
 	for _, an := range result.Analysers {
-		h = append(h, an.Layout.Name)
+		header = append(header, an.Layout.Name)
 	}
-	twOuter.AppendHeader(h)
+	twOuter.AppendHeader(header)
 
 	// Layout picture
-	h = table.Row{"Board"}
-	for _, an := range result.Analysers {
-		h = append(h, SplitLayoutString(an.Layout))
+	if opts.Shows("board") {
+		h := table.Row{"Board"}
+		for _, an := range result.Analysers {
+			h = append(h, SplitLayoutString(an.Layout))
+		}
+		twOuter.AppendRow(h)
 	}
-	twOuter.AppendRow(h)
 
 	// Hand load distribution
-	h = table.Row{"Hand"}
-	for _, an := range result.Analysers {
-		h = append(h, HandUsageString(an))
+	if opts.Shows("hand") {
+		h := table.Row{"Hand"}
+		for _, an := range result.Analysers {
+			h = append(h, HandUsageString(an))
+		}
+		twOuter.AppendRow(h)
 	}
-	twOuter.AppendRow(h)
 
 	// Row load distribution
-	h = table.Row{"Row"}
-	for _, an := range result.Analysers {
-		h = append(h, RowUsageString(an))
+	if opts.Shows("row") {
+		h := table.Row{"Row"}
+		for _, an := range result.Analysers {
+			h = append(h, RowUsageString(an))
+		}
+		twOuter.AppendRow(h)
 	}
-	twOuter.AppendRow(h)
 
 	// Metrics overview
-	h = table.Row{"Stats"}
-	for _, an := range result.Analysers {
-		h = append(h, MetricsString(an))
+	if opts.Shows("stats") {
+		h := table.Row{"Stats"}
+		for _, an := range result.Analysers {
+			h = append(h, MetricsString(an))
+		}
+		twOuter.AppendRow(h)
 	}
-	twOuter.AppendRow(h)
 
-	// Add detailed data rows
-	details := make([][]*kc.MetricDetails, 0, len(result.Analysers))
-	for _, an := range result.Analysers {
-		details = append(details, an.AllMetricsDetails())
+	// Comparison with random layouts
+	if opts.Shows("stats") && result.VsRandom != nil {
+		h := table.Row{"Vs random"}
+		for _, rc := range result.VsRandom {
+			h = append(h, RandomComparisonString(rc))
+		}
+		twOuter.AppendRow(h)
+	}
+
+	// Same-hand run length distribution
+	if opts.Shows("runs") {
+		h := table.Row{"Runs"}
+		for _, an := range result.Analysers {
+			h = append(h, HandRunsString(an))
+		}
+		twOuter.AppendRow(h)
+	}
+
+	// Finger repetition pressure
+	if opts.Shows("fatigue") {
+		h := table.Row{"Fatigue"}
+		for _, an := range result.Analysers {
+			h = append(h, FingerFatigueString(an))
+		}
+		twOuter.AppendRow(h)
+	}
+
+	// Per-key attribution boards
+	if opts.Shows("per-key") {
+		for i, label := range columns[0].perKeyLabels {
+			h := table.Row{label}
+			for _, col := range columns {
+				h = append(h, col.perKey[i])
+			}
+			twOuter.AppendRow(h)
+		}
 	}
 
-	metrics := details[0] // get the first entry to get the metrics
-	for i, ma := range metrics {
-		data := table.Row{ma.Metric}
-		for _, mas := range details {
-			data = append(data, MetricDetailsString(mas[i], opts.MaxRows))
+	// Role of the focus key
+	if opts.FocusKey != 0 {
+		h := table.Row{"Key " + FocusKeyString(opts.FocusKey)}
+		for _, col := range columns {
+			h = append(h, col.keyRole)
+		}
+		twOuter.AppendRow(h)
+	}
+
+	// Add detailed data rows
+	for i, metric := range columns[0].detailNames {
+		data := table.Row{metric}
+		for _, col := range columns {
+			data = append(data, col.details[i])
 		}
 		twOuter.AppendRow(data)
 	}
 
+	// Add unsupported characters row, only if any layout misses corpus characters
+	anyUnsupported := false
+	for _, col := range columns {
+		anyUnsupported = anyUnsupported || col.anyMissing
+	}
+	if anyUnsupported && opts.Shows("unsupported") {
+		h := table.Row{"Unsup"}
+		for _, col := range columns {
+			h = append(h, col.unsupported)
+		}
+		twOuter.AppendRow(h)
+	}
+
 	// Add trigram table row
-	h = table.Row{"Trigr"}
-	for _, an := range result.Analysers {
-		h = append(h, TopTrigramsString(an, opts.CompactTrigrams, opts.TrigramRows))
+	if opts.Shows("trigrams") {
+		h := table.Row{"Trigr"}
+		for _, col := range columns {
+			h = append(h, col.trigrams)
+		}
+		twOuter.AppendRow(h)
 	}
-	twOuter.AppendRow(h)
 
 	// Print layout(s) in the table
 	fmt.Println(twOuter.Render())
 	return nil
 }
 
-// MetricDetailsString renders metric details as a paginated table.
-func MetricDetailsString(ma *kc.MetricDetails, nrows int) string {
+// detailColumn holds the rendered cells of a layout that take the most time to compute.
+type detailColumn struct {
+	perKeyLabels []string // Labels of the per-key boards (only with opts.PerKey)
+	perKey       []string // Per-key boards (only with opts.PerKey)
+	details      []string // Metric detail tables, in the order of AllMetricsDetails
+	detailNames  []string // Metric of each detail table
+	unsupported  string   // Unsupported characters table
+	anyMissing   bool     // Whether the layout lacks corpus characters
+	trigrams     string   // Top trigrams table
+	keyRole      string   // Role of the focus key (only with opts.FocusKey)
+}
+
+// detailColumns renders the costly cells of the layouts concurrently, with at most
+// GOMAXPROCS layouts at a time. The columns are in the order of the analysers.
+func detailColumns(analysers []*kc.Analyser, opts kc.AnalyseDisplayOptions) []detailColumn {
+	columns := make([]detailColumn, len(analysers))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, an := range analysers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			col := &columns[i]
+			if opts.Shows("per-key") {
+				col.perKeyLabels, col.perKey = PerKeyBoards(an)
+			}
+			if slices.ContainsFunc(kc.AnalyseSectionGroups["details"], opts.Shows) {
+				for _, ma := range an.AllMetricsDetails() {
+					if opts.FocusKey != 0 {
+						ma = ma.Focused(opts.FocusKey)
+					}
+					if opts.Shows(ma.Metric) {
+						col.detailNames = append(col.detailNames, ma.Metric)
+						col.details = append(col.details, MetricDetailsString(ma, opts.MaxRows, opts.Page))
+					}
+				}
+			}
+			if opts.Shows("unsupported") {
+				chars := an.UnsupportedChars(3)
+				col.unsupported = UnsupportedCharsString(chars, opts.MaxRows, opts.Page)
+				col.anyMissing = len(chars) > 0
+			}
+			if opts.Shows("trigrams") {
+				col.trigrams = TopTrigramsString(an, opts.CompactTrigrams, opts.TrigramRows, opts.Page, opts.FocusKey)
+			}
+			if opts.FocusKey != 0 {
+				col.keyRole = KeyRoleString(an.KeyRole(opts.FocusKey))
+			}
+		}()
+	}
+	wg.Wait()
+	return columns
+}
+
+// handRunBuckets is the number of columns of the run length histogram; the last column
+// counts all longer runs.
+const handRunBuckets = 7
+
+// HandRunsString renders the distribution of same-hand run lengths as the % of runs of
+// each length, followed by HRUN-AVG and HRUN-P95.
+func HandRunsString(an *kc.Analyser) string {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Box.PaddingLeft = ""
+	tw.Style().Box.PaddingRight = ""
+
+	var total uint64
+	for _, cnt := range an.HandRuns {
+		total += cnt
+	}
+
+	header := make(table.Row, 0, handRunBuckets)
+	row := make(table.Row, 0, handRunBuckets)
+	colConfigs := make([]table.ColumnConfig, 0, handRunBuckets)
+	for length := 1; length <= handRunBuckets; length++ {
+		var cnt uint64
+		if length < handRunBuckets {
+			if length < len(an.HandRuns) {
+				cnt = an.HandRuns[length]
+			}
+			header = append(header, strconv.Itoa(length))
+		} else {
+			for l := length; l < len(an.HandRuns); l++ {
+				cnt += an.HandRuns[l]
+			}
+			header = append(header, strconv.Itoa(length)+"+")
+		}
+		pct := 0.0
+		if total > 0 {
+			pct = 100 * float64(cnt) / float64(total)
+		}
+		row = append(row, fmt.Sprintf("%.1f", pct))
+		colConfigs = append(colConfigs, table.ColumnConfig{Number: length,
+			AlignHeader: text.AlignCenter, Align: text.AlignCenter})
+	}
+	tw.SetColumnConfigs(colConfigs)
+	tw.AppendHeader(header)
+	tw.AppendRow(row)
+	tw.SetCaption("HRUN-AVG: %.2f  HRUN-P95: %.0f", an.Metrics["HRUN-AVG"], an.Metrics["HRUN-P95"])
+
+	return tw.Render()
+}
+
+// FingerFatigueString renders FATIGUE by finger, followed by the total.
+func FingerFatigueString(an *kc.Analyser) string {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Box.PaddingLeft = ""
+	tw.Style().Box.PaddingRight = ""
+
+	fingers := table.Row{"LP", "LR", "LM", "LI", "LT", "RT", "RI", "RM", "RR", "RP"}
+	row := make(table.Row, len(fingers))
+	colConfigs := make([]table.ColumnConfig, len(fingers))
+	for f := range fingers {
+		row[f] = fmt.Sprintf("%.2f", an.FingerFatigue[f])
+		colConfigs[f] = table.ColumnConfig{Number: f + 1, AlignHeader: text.AlignCenter, Align: text.AlignCenter}
+	}
+	tw.SetColumnConfigs(colConfigs)
+	tw.AppendHeader(fingers)
+	tw.AppendRow(row)
+	fatigue := an.FatigueSettings()
+	tw.SetCaption("FATIGUE: %.2f%% (>%d of %d keystrokes)", an.Metrics["FATIGUE"], fatigue.Limit, fatigue.Window)
+
+	return tw.Render()
+}
+
+// MetricDetailsString renders a page of metric details, with nrows n-grams per page.
+func MetricDetailsString(ma *kc.MetricDetails, nrows, page int) string {
 	t := createSimpleTable()
 
 	// Collect unique custom keys
@@ -137,7 +344,120 @@ func MetricDetailsString(ma *kc.MetricDetails, nrows int) string {
 	}
 	t.AppendFooter(footer)
 
-	return t.Pager(table.PageSize(nrows)).Render()
+	out := t.Pager(table.PageSize(nrows)).GoTo(max(page, 1))
+
+	// Report n-grams that were skipped because a character is not on the layout
+	if len(ma.Unsupported) > 0 {
+		var skipped uint64
+		for _, cnt := range ma.Unsupported {
+			skipped += cnt
+		}
+		out += fmt.Sprintf("\nSkipped (unsupported chars): %s (%s)",
+			Comma(skipped), Percentage(float64(skipped)/float64(ma.CorpusNGramC)))
+	}
+
+	return out
+}
+
+// WriteMetricDetailsCSV writes all n-grams of metric details as CSV, without rounding: the
+// n-gram, its count, its % of the corpus n-grams and its distance, followed by the custom
+// columns of the metric in alphabetical order. N-grams are ordered by count, descending.
+func WriteMetricDetailsCSV(w io.Writer, ma *kc.MetricDetails) error {
+	customKeys := make(map[string]bool)
+	for _, fields := range ma.Custom {
+		for k := range fields {
+			customKeys[k] = true
+		}
+	}
+	custom := slices.Sorted(maps.Keys(customKeys))
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{ma.Metric, "Count", "%", "Dist"}, custom...)); err != nil {
+		return err
+	}
+
+	ngrams := slices.SortedFunc(maps.Keys(ma.NGramCount), func(a, b string) int {
+		return cmp.Or(cmp.Compare(ma.NGramCount[b], ma.NGramCount[a]), strings.Compare(a, b))
+	})
+	for _, ngram := range ngrams {
+		pct := 0.0
+		if ma.CorpusNGramC > 0 {
+			pct = 100 * float64(ma.NGramCount[ngram]) / float64(ma.CorpusNGramC)
+		}
+		record := []string{
+			ngram,
+			strconv.FormatUint(ma.NGramCount[ngram], 10),
+			strconv.FormatFloat(pct, 'g', -1, 64),
+			strconv.FormatFloat(ma.NGramDist[ngram], 'g', -1, 64),
+		}
+		for _, ck := range custom {
+			switch v := ma.Custom[ngram][ck].(type) {
+			case nil:
+				record = append(record, "")
+			case float64:
+				record = append(record, strconv.FormatFloat(v, 'g', -1, 64))
+			default:
+				record = append(record, fmt.Sprint(v))
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMetricDetailsJSON writes metric details as indented JSON, in the stable JSON form of
+// kc.MetricDetailsJSON.
+func WriteMetricDetailsJSON(w io.Writer, ma *kc.MetricDetails) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(ma)
+}
+
+// UnsupportedCharsString renders a page of the corpus characters missing from a layout,
+// with their frequency and suggested key positions.
+func UnsupportedCharsString(chars []kc.UnsupportedChar, nrows, page int) string {
+	if len(chars) == 0 {
+		return "All corpus characters are on the layout"
+	}
+
+	t := createSimpleTable()
+	t.AppendHeader(table.Row{"orderby", "Char", "Count", "%", "Suggested keys"})
+
+	var total uint64
+	var pct float64
+	for _, uc := range chars {
+		suggestions := make([]string, 0, len(uc.Suggestions))
+		for _, s := range uc.Suggestions {
+			suggestions = append(suggestions, keySuggestionString(s))
+		}
+		t.AppendRow(table.Row{
+			uc.Count,
+			strconv.QuoteRune(uc.Rune),
+			uc.Count,
+			uc.Percentage / 100,
+			strings.Join(suggestions, ", "),
+		})
+		total += uc.Count
+		pct += uc.Percentage / 100
+	}
+	t.AppendFooter(table.Row{"", "", total, pct, ""})
+
+	return t.Pager(table.PageSize(nrows)).GoTo(max(page, 1))
+}
+
+// keySuggestionString describes a candidate key position, e.g. "r1c5 (empty)" or
+// "t2 ('q', 1,234)".
+func keySuggestionString(s kc.KeySuggestion) string {
+	pos := keyPosString(s.Index)
+	if s.Rune == 0 {
+		return pos + " (empty)"
+	}
+	return fmt.Sprintf("%s (%q, %s)", pos, s.Rune, Comma(s.Count))
 }
 
 // createSimpleTable returns a configured table writer with rounded style and common settings.
@@ -159,15 +479,18 @@ func createSimpleTable() table.Writer {
 		{Name: "Δrow", Transformer: Fraction, TransformerFooter: Fraction},
 		{Name: "Δcol", Transformer: Fraction, TransformerFooter: Fraction},
 		{Name: "Angle", Transformer: Angle, TransformerFooter: Angle},
+		{Name: "Sev", Transformer: Fraction},
 		{Name: "Length", Align: text.AlignRight},
 	})
 	tw.SortBy([]table.SortBy{{Name: "orderby", Mode: table.DscNumeric}})
 	return tw
 }
 
-// TopTrigramsString generates a table showing the top N trigrams with their
-// classifications (ALT, 2RL, 3RL, RED) and their specific categories.
-func TopTrigramsString(an *kc.Analyser, compactTrigrams bool, trigramRows int) string {
+// TopTrigramsString generates a table showing a page of the top trigrams with their
+// classifications (ALT, 2RL, 3RL, RED) and their specific categories. Page p shows the
+// trigrams ranked (p-1)*trigramRows+1 to p*trigramRows; Cumul% counts from the first. With a
+// focus key, only the trigrams with that character are shown.
+func TopTrigramsString(an *kc.Analyser, compactTrigrams bool, trigramRows, page int, focusKey rune) string {
 	t := createSimpleTable()
 
 	// Get trigram classifications from TrigramDetails
@@ -212,8 +535,20 @@ func TopTrigramsString(an *kc.Analyser, compactTrigrams bool, trigramRows int) s
 		"3RL-OUT": true,
 	}
 
-	// Get top N trigrams from corpus
-	topTrigrams := an.Corpus.TopTrigrams(trigramRows)
+	// Get the top trigrams from corpus, up to the last of the page
+	page = max(page, 1)
+	var topTrigrams []kc.CountPair[kc.Trigram]
+	if focusKey == 0 {
+		topTrigrams = an.Corpus.TopTrigrams(page * trigramRows)
+	} else {
+		for _, pair := range an.Corpus.TopTrigrams(0) {
+			if slices.Contains(pair.Key[:], focusKey) {
+				topTrigrams = append(topTrigrams, pair)
+			}
+		}
+		topTrigrams = topTrigrams[:min(len(topTrigrams), page*trigramRows)]
+	}
+	first := (page - 1) * trigramRows
 
 	// Header
 	header := table.Row{"orderby", "Tri", "Count", "%", "Cumul%", "Class"}
@@ -224,7 +559,7 @@ func TopTrigramsString(an *kc.Analyser, compactTrigrams bool, trigramRows int) s
 	cumulativeCount := uint64(0)
 	rowNum := 0
 
-	for _, pair := range topTrigrams {
+	for i, pair := range topTrigrams {
 		triStr := pair.Key.String()
 		count := pair.Count
 		classification := getClassification(triStr)
@@ -235,6 +570,9 @@ func TopTrigramsString(an *kc.Analyser, compactTrigrams bool, trigramRows int) s
 		}
 
 		cumulativeCount += count
+		if i < first {
+			continue
+		}
 		percentage := float64(count) / float64(totalTrigramCount)
 		cumulativePercentage := float64(cumulativeCount) / float64(totalTrigramCount)
 
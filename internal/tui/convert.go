@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderConvert renders the outcome of converting layout files to stdout: a table with the
+// status of each file, and a summary.
+func RenderConvert(result *kc.ConvertResult) error {
+	if len(result.Files) == 0 {
+		fmt.Printf("No %s layout files found.\n", result.From)
+		return nil
+	}
+
+	tw := table.NewWriter()
+	tw.SetAutoIndex(true)
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	tw.SetTitle(fmt.Sprintf("Convert %s to %s", result.From, result.To))
+	tw.AppendHeader(table.Row{"Source", "Target", "Status"})
+
+	skipped := 0
+	for _, f := range result.Files {
+		status := "converted"
+		switch {
+		case f.Err != nil:
+			status = "failed: " + f.Err.Error()
+		case f.Skipped:
+			status = "skipped: target exists (use --force)"
+			skipped++
+		}
+		tw.AppendRow(table.Row{f.Source, f.Target, status})
+	}
+	fmt.Println(tw.Render())
+
+	fmt.Printf("\nConverted %d of %d files", result.NumConverted(), len(result.Files))
+	if failed := result.NumFailed(); failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	if skipped > 0 {
+		fmt.Printf(", %d skipped", skipped)
+	}
+	fmt.Println()
+	return nil
+}
@@ -1,10 +1,13 @@
 package tui
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"maps"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -18,7 +21,31 @@ func RenderCorpus(result *kc.CorpusResult) error {
 	corpus := result.Corpus
 	nrows := result.NRows
 
-	fmt.Printf("Corpus: %s\n\n", corpus.Name)
+	var modes []string
+	if corpus.Cased {
+		modes = append(modes, "case-sensitive")
+	}
+	if corpus.Spaced {
+		modes = append(modes, "with space")
+	}
+	if policy := corpus.Skipgram.String(); policy != "" {
+		modes = append(modes, policy)
+	}
+	if len(modes) > 0 {
+		fmt.Printf("Corpus: %s (%s)\n\n", corpus.Name, strings.Join(modes, ", "))
+	} else {
+		fmt.Printf("Corpus: %s\n\n", corpus.Name)
+	}
+
+	if len(result.Substitutions) > 0 {
+		fmt.Println(corpusSubstitutionsStr(result))
+		fmt.Println()
+	}
+
+	if result.Preview != nil {
+		fmt.Println(corpusPreviewStr(result.Preview))
+		return nil
+	}
 
 	fmt.Println(corpusWordLenDistStr(corpus))
 	fmt.Println()
@@ -187,6 +214,97 @@ func displayChar(r rune) string {
 	}
 }
 
+// corpusSubstitutionsStr renders the characters that were replaced when the corpus was built,
+// or that a substitution table would replace, with their counts.
+func corpusSubstitutionsStr(result *kc.CorpusResult) string {
+	t := createSimpleTable()
+	t.AppendHeader(table.Row{"orderby", "Ch", "To", "Count", "%"})
+	var total uint64
+	for _, sub := range result.Substitutions {
+		t.AppendRow(table.Row{sub.Count, displayChar(rune(sub.From)), displayChar(rune(sub.To)), sub.Count,
+			float64(sub.Count) / float64(result.Corpus.TotalUnigramsCount)})
+		total += sub.Count
+	}
+	t.AppendFooter(table.Row{"", "", "", total, float64(total) / float64(result.Corpus.TotalUnigramsCount)})
+	if result.Substituted {
+		return renderOuterCorpusTable(t, "Substituted Chars", len(result.Substitutions), 1)
+	}
+	return renderOuterCorpusTable(t, "Substitutable Chars", len(result.Substitutions), 1) +
+		"\nBuild the corpus with --substitute to replace these characters."
+}
+
+// corpusPreviewStr renders the most frequent n-grams of each kind side by side, with the
+// share of the n-grams of their kind that they cover together.
+func corpusPreviewStr(preview *kc.CorpusPreview) string {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	header := table.Row{"#"}
+	configs := []table.ColumnConfig{{Number: 1, Align: text.AlignRight}}
+	rows := 0
+	for _, col := range preview.Columns {
+		header = append(header, col.Kind, "Count", "Cumul%")
+		configs = append(configs, table.ColumnConfig{Number: len(header) - 1, Align: text.AlignRight},
+			table.ColumnConfig{Number: len(header), Align: text.AlignRight})
+		rows = max(rows, len(col.NGrams))
+	}
+	tw.AppendHeader(header)
+	tw.SetColumnConfigs(configs)
+	for i := range rows {
+		row := table.Row{i + 1}
+		for _, col := range preview.Columns {
+			if i >= len(col.NGrams) {
+				row = append(row, "", "", "")
+				continue
+			}
+			ng := col.NGrams[i]
+			row = append(row, displayNGram(ng.NGram), Comma(ng.Count), fmt.Sprintf("%.2f%%", ng.Cumul))
+		}
+		tw.AppendRow(row)
+	}
+	tw.SetTitle(fmt.Sprintf("Top-%d N-grams with Cumulative Coverage", rows))
+	return tw.Render()
+}
+
+// displayNGram returns an n-gram with its characters made printable as by displayChar.
+func displayNGram(ngram string) string {
+	var sb strings.Builder
+	for _, r := range ngram {
+		sb.WriteString(displayChar(r))
+	}
+	return sb.String()
+}
+
+// WriteCorpusPreviewCSV writes the n-grams of a corpus preview as CSV, or as TSV if tab is
+// set, without rounding: one row per n-gram with its kind, rank, count, % of the n-grams of
+// its kind, and cumulative %.
+func WriteCorpusPreviewCSV(w io.Writer, preview *kc.CorpusPreview, tab bool) error {
+	cw := csv.NewWriter(w)
+	if tab {
+		cw.Comma = '\t'
+	}
+	if err := cw.Write([]string{"Kind", "Rank", "NGram", "Count", "%", "Cumul%"}); err != nil {
+		return err
+	}
+	for _, col := range preview.Columns {
+		for i, ng := range col.NGrams {
+			record := []string{
+				strings.ToLower(strings.TrimSuffix(col.Kind, "s")),
+				strconv.Itoa(i + 1),
+				ng.NGram,
+				strconv.FormatUint(ng.Count, 10),
+				strconv.FormatFloat(ng.Pct, 'g', -1, 64),
+				strconv.FormatFloat(ng.Cumul, 'g', -1, 64),
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 // corpusUnigramsStr renders the top unigrams as paginated tables.
 func corpusUnigramsStr(corpus *kc.Corpus, nrows int) string {
 	topUnigrams := corpus.TopUnigrams(nrows)
@@ -321,3 +439,21 @@ func corpusWordsStr(corpus *kc.Corpus, nrows int) string {
 		len(topWords), Comma(corpus.TotalWordsCount), Comma(len(corpus.Words)))
 	return renderOuterCorpusTable(t, title, rowsPerTable, numTables)
 }
+
+// RenderCorpusPresets renders the corpus presets that can be fetched, with their licenses.
+func RenderCorpusPresets(presets []kc.CorpusPreset) {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	tw.SetTitle("Corpus presets")
+	tw.AppendHeader(table.Row{"Preset", "File", "Checksum", "License", "URL"})
+	for _, p := range presets {
+		checksum := "not pinned"
+		if p.SHA256 != "" {
+			checksum = p.SHA256[:12] + "…"
+		}
+		tw.AppendRow(table.Row{p.Name, p.File, checksum, p.License, p.URL})
+	}
+	tw.SetColumnConfigs([]table.ColumnConfig{{Name: "License", WidthMax: 50, WidthMaxEnforcer: text.WrapSoft}})
+	fmt.Println(tw.Render())
+}
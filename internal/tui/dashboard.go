@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+const (
+	dashboardInterval = 250 * time.Millisecond // Minimum time between redraws
+	sparklineWidth    = 60                     // Number of costs shown in a sparkline
+	dashboardBarWidth = 20                     // Width of a bar, or of each side of a signed bar
+	dashboardMetrics  = 12                     // Number of metrics shown
+)
+
+// sparkTicks are the characters of a sparkline, from low to high.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Dashboard renders a live overview of a running optimization on a terminal, redrawing it in
+// place: the costs of the latest iterations as sparklines, the largest metric costs of the
+// best layout, the distribution of perturbation types, the cache hit rate, and the best
+// layout. Pass Update as the progress function of the optimization.
+type Dashboard struct {
+	w         io.Writer
+	name      string    // Name of the layout being optimized
+	current   []float64 // Costs of the latest local optima
+	best      []float64 // Best costs at the latest iterations
+	lastDraw  time.Time
+	lastLines int // Number of lines of the last drawing
+}
+
+// NewDashboard creates a dashboard for optimizing the named layout, drawn on w.
+func NewDashboard(w io.Writer, name string) *Dashboard {
+	return &Dashboard{w: w, name: name}
+}
+
+// Update records a snapshot of the search and redraws the dashboard, at most every 250ms
+// unless the search has ended.
+func (d *Dashboard) Update(p kc.BLSProgress) {
+	d.current = appendWindow(d.current, p.CurrentCost)
+	d.best = appendWindow(d.best, p.BestCost)
+	if !p.Done && time.Since(d.lastDraw) < dashboardInterval {
+		return
+	}
+	d.lastDraw = time.Now()
+
+	// Move the cursor to the first line of the last drawing, then overwrite it line by line
+	var sb strings.Builder
+	if d.lastLines > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dF", d.lastLines)
+	}
+	text := d.String(p)
+	for line := range strings.SplitSeq(text, "\n") {
+		sb.WriteString(line)
+		sb.WriteString("\x1b[K\n")
+	}
+	sb.WriteString("\x1b[J")
+	d.lastLines = strings.Count(text, "\n") + 1
+	kc.MustFprint(d.w, sb.String())
+}
+
+// String renders the dashboard for a snapshot of the search.
+func (d *Dashboard) String(p kc.BLSProgress) string {
+	var sb strings.Builder
+	status := "optimizing"
+	if p.Done {
+		status = "finished"
+	}
+	fmt.Fprintf(&sb, "Keycraft: %s %s\n", status, d.name)
+	fmt.Fprintf(&sb, "Iteration %s of %s, %v of %v\n\n",
+		Comma(p.Iteration), Comma(p.MaxIterations), p.Elapsed.Round(time.Second), p.MaxTime)
+
+	fmt.Fprintf(&sb, "Best cost     %10.4f  %s\n", p.BestCost, sparkline(d.best))
+	fmt.Fprintf(&sb, "Current cost  %10.4f  %s\n", p.CurrentCost, sparkline(d.current))
+	fmt.Fprintf(&sb, "Cache hits    %9.1f%%  of %s scores\n\n", p.Scorer.HitRate, Comma(p.Scorer.TotalCalls))
+
+	sb.WriteString("Metric costs of the best layout (negative is better)\n")
+	sb.WriteString(metricCostBars(p.BestCosts))
+
+	sb.WriteString("\nPerturbations\n")
+	sb.WriteString(perturbationBars(p.Perturbations))
+	sb.WriteString("\n")
+
+	if p.BestLayout != nil {
+		sb.WriteString(SplitLayoutString(p.BestLayout))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// appendWindow appends v to values, keeping the last sparklineWidth values.
+func appendWindow(values []float64, v float64) []float64 {
+	values = append(values, v)
+	if len(values) > sparklineWidth {
+		values = values[len(values)-sparklineWidth:]
+	}
+	return values
+}
+
+// sparkline renders values as a line of bars scaled between their minimum and maximum.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+	out := make([]rune, len(values))
+	for i, v := range values {
+		tick := 0
+		if hi > lo {
+			tick = int(math.Round((v - lo) / (hi - lo) * float64(len(sparkTicks)-1)))
+		}
+		out[i] = sparkTicks[tick]
+	}
+	return string(out)
+}
+
+// metricCostBars renders the largest metric costs as bars that grow left of the axis for
+// negative (good) costs and right of it for positive (bad) costs.
+func metricCostBars(costs map[string]float64) string {
+	metrics := slices.SortedFunc(maps.Keys(costs), func(a, b string) int {
+		return cmp.Or(cmp.Compare(math.Abs(costs[b]), math.Abs(costs[a])), strings.Compare(a, b))
+	})
+	metrics = metrics[:min(len(metrics), dashboardMetrics)]
+	scale := 0.0
+	for _, metric := range metrics {
+		scale = max(scale, math.Abs(costs[metric]))
+	}
+
+	var sb strings.Builder
+	for _, metric := range metrics {
+		cost := costs[metric]
+		n := 0
+		if scale > 0 {
+			n = int(math.Round(math.Abs(cost) / scale * dashboardBarWidth))
+		}
+		left, right := strings.Repeat(" ", dashboardBarWidth), strings.Repeat(" ", dashboardBarWidth)
+		if cost < 0 {
+			left = strings.Repeat(" ", dashboardBarWidth-n) + strings.Repeat("█", n)
+		} else {
+			right = strings.Repeat("█", n) + strings.Repeat(" ", dashboardBarWidth-n)
+		}
+		fmt.Fprintf(&sb, "  %-9s %+8.3f %s│%s\n", metric, cost, left, right)
+	}
+	return sb.String()
+}
+
+// perturbationBars renders the share of each perturbation type as a bar.
+func perturbationBars(counts map[string]int) string {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return "  none yet\n"
+	}
+
+	var sb strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		share := float64(counts[name]) / float64(total)
+		n := int(math.Round(share * dashboardBarWidth))
+		fmt.Fprintf(&sb, "  %-9s %5.1f%% %s\n", name, 100*share, strings.Repeat("█", n))
+	}
+	return sb.String()
+}
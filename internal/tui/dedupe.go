@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderDedupe renders the exact and near-duplicate layout clusters to stdout.
+func RenderDedupe(result *kc.DedupeResult) error {
+	fmt.Printf("Checked %d layouts\n\n", result.NumLayouts)
+
+	if len(result.Exact) == 0 {
+		fmt.Println("No identical layouts found.")
+	} else {
+		fmt.Println(dedupeTable("Identical layouts", result.Exact, false).Render())
+	}
+
+	if result.MaxDiff > 0 {
+		fmt.Println()
+		if len(result.Near) == 0 {
+			fmt.Printf("No layouts found that differ in %d keys or fewer.\n", result.MaxDiff)
+		} else {
+			title := fmt.Sprintf("Near-duplicate layouts (up to %d keys different)", result.MaxDiff)
+			fmt.Println(dedupeTable(title, result.Near, true).Render())
+		}
+	}
+
+	return nil
+}
+
+// dedupeTable builds a table with one row per cluster.
+func dedupeTable(title string, clusters []kc.LayoutCluster, showDiff bool) table.Writer {
+	tw := table.NewWriter()
+	tw.SetAutoIndex(true)
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	tw.SetTitle(title)
+
+	header := table.Row{"ID", "Count", "Layouts"}
+	if showDiff {
+		header = append(header, "Max Diff")
+	}
+	tw.AppendHeader(header)
+
+	for _, c := range clusters {
+		row := table.Row{c.ID, len(c.Names), strings.Join(c.Names, ", ")}
+		if showDiff {
+			row = append(row, c.MaxDiff)
+		}
+		tw.AppendRow(row)
+	}
+	return tw
+}
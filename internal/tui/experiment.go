@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderExperimentReport renders the groups of experiment results, with a column for each
+// dimension they are grouped by, highlighting the group with the lowest mean cost.
+func RenderExperimentReport(groups []kc.ExperimentGroup, by []string, records int) {
+	best := 0
+	for i, g := range groups {
+		if g.MeanCost < groups[best].MeanCost {
+			best = i
+		}
+	}
+
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.SetTitle(fmt.Sprintf("Experiment results (%s runs)", Comma(records)))
+	header := table.Row{}
+	for _, name := range by {
+		header = append(header, name)
+	}
+	header = append(header, "Runs", "Mean cost", "Std dev", "Best cost", "Mean gain", "Mean time")
+	tw.AppendHeader(header)
+
+	var configs []table.ColumnConfig
+	for n := len(by) + 1; n <= len(header); n++ {
+		configs = append(configs, table.ColumnConfig{Number: n, Align: text.AlignRight})
+	}
+	tw.SetColumnConfigs(configs)
+
+	for i, g := range groups {
+		row := table.Row{}
+		for _, v := range g.Values {
+			row = append(row, v)
+		}
+		row = append(row, g.Runs, fmt.Sprintf("%.4f", g.MeanCost), fmt.Sprintf("%.4f", g.StdDev),
+			fmt.Sprintf("%.4f", g.MinCost), fmt.Sprintf("%.4f", g.MeanGain), g.MeanElapsed.Round(100*time.Millisecond))
+		if i == best && len(groups) > 1 {
+			for j := range row {
+				row[j] = text.FgGreen.Sprint(row[j])
+			}
+		}
+		tw.AppendRow(row)
+	}
+	fmt.Println(tw.Render())
+	fmt.Println("Lower costs are better; only costs with the same corpus and weights are comparable.")
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// FocusKeyString quotes the character of a focus key, with ␣ for space.
+func FocusKeyString(r rune) string {
+	return "'" + keyCharString(r) + "'"
+}
+
+// keyCharString returns the character of a key, with ␣ for space.
+func keyCharString(r rune) string {
+	if r == ' ' {
+		return "␣"
+	}
+	return string(r)
+}
+
+// KeyRoleString renders the role of a key: its position, finger and load, followed by a
+// table of the metrics it takes part in, with the share of each metric that has the key and
+// its partners in the bigrams and skipgrams, or the categories of its trigrams.
+func KeyRoleString(role *kc.KeyRole) string {
+	if !role.OnKey {
+		return fmt.Sprintf("%s is not on the layout", FocusKeyString(role.Key))
+	}
+
+	t := createSimpleTable()
+	t.SetTitle("%s %s %s, load %.2f%%", FocusKeyString(role.Key), keyPosString(role.KeyInfo.Index),
+		fingerNames[role.KeyInfo.Finger], role.Load)
+	t.AppendHeader(table.Row{"orderby", "Metric", "%", "Share", "Partners"})
+	for i, metric := range role.Metrics {
+		var parts []string
+		for _, p := range metric.Partners {
+			parts = append(parts, fmt.Sprintf("%s %.0f%%", keyCharString(p.Key), 100*float64(p.Count)/float64(metric.Count)))
+		}
+		for _, d := range metric.Dirs {
+			parts = append(parts, fmt.Sprintf("%s %.0f%%", d.Key, 100*float64(d.Count)/float64(metric.Count)))
+		}
+		// The rows are sorted by orderby, descending, so that they stay in metric order
+		t.AppendRow(table.Row{len(role.Metrics) - i, metric.Metric, metric.Percent / 100,
+			fmt.Sprintf("%.1f%%", metric.Share), strings.Join(parts, ", ")})
+	}
+	return t.Render()
+}
@@ -6,6 +6,9 @@ import "fmt"
 func Comma[T ~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64](v T) string {
 	// Convert to uint64 for processing
 	val := uint64(v)
+	if val == 0 {
+		return "0"
+	}
 
 	// Calculate the number of digits and commas needed.
 	var count byte
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// fingerNames abbreviates the fingers 0-9, as in kc.LP to kc.RP.
+var fingerNames = [10]string{"LP", "LR", "LM", "LI", "LT", "RT", "RI", "RM", "RR", "RP"}
+
+// RenderGeometry writes the keys of a layout type with their fingers, and the distances
+// between the key pairs on the same hand, as tables or as CSV of the key pairs.
+func RenderGeometry(w io.Writer, layoutType kc.LayoutType, format OutputFormat) error {
+	keys, pairs := kc.KeyGeometry(layoutType)
+	var err error
+	switch format {
+	case OutputTable, "":
+		_, err = io.WriteString(w, geometryTables(layoutType, keys, pairs))
+	case OutputCSV:
+		err = writeGeometryCSV(w, pairs)
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write geometry: %w", err)
+	}
+	return nil
+}
+
+// geometryTables renders the finger of every key position as a board, followed by a table
+// of the key pair distances.
+func geometryTables(layoutType kc.LayoutType, keys []kc.KeyInfo, pairs []kc.KeyPairEntry) string {
+	var sb strings.Builder
+	name := kc.LayoutTypeStrings[layoutType]
+
+	board := table.NewWriter()
+	board.SetStyle(table.StyleRounded)
+	board.Style().Title.Align = text.AlignCenter
+	board.SetTitle(fmt.Sprintf("Fingers of %s keys", name))
+	header := table.Row{""}
+	for col := range 12 {
+		header = append(header, fmt.Sprintf("c%d", col))
+	}
+	board.AppendHeader(header)
+	for row := range 4 {
+		cells := table.Row{fmt.Sprintf("r%d", row)}
+		if row == 3 {
+			cells[0] = "t"
+		}
+		for _, ki := range keys {
+			if int(ki.Row) == row {
+				cells = append(cells, fingerNames[ki.Finger])
+			}
+		}
+		board.AppendRow(cells)
+	}
+	sb.WriteString(board.Render())
+	sb.WriteString("\n\n")
+
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	tw.SetTitle(fmt.Sprintf("Distances between %s keys (in key units)", name))
+	tw.AppendHeader(table.Row{"Key 1", "Key 2", "Fingers", "ΔRow", "ΔCol", "ΔFinger", "Distance"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
+		{Number: 7, Align: text.AlignRight},
+	})
+	for _, p := range pairs {
+		tw.AppendRow(table.Row{
+			keyPosString(p.Key1.Index),
+			keyPosString(p.Key2.Index),
+			fingerNames[p.Key1.Finger] + "-" + fingerNames[p.Key2.Finger],
+			fmt.Sprintf("%.2f", p.RowDist),
+			fmt.Sprintf("%.2f", p.ColDist),
+			p.FingerDist,
+			fmt.Sprintf("%.2f", p.Distance),
+		})
+	}
+	sb.WriteString(tw.Render())
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// writeGeometryCSV writes a CSV row for every key pair on the same hand, with the row,
+// column and finger of both keys and their distances.
+func writeGeometryCSV(w io.Writer, pairs []kc.KeyPairEntry) error {
+	cw := csv.NewWriter(w)
+	header := []string{"key1", "row1", "col1", "finger1", "key2", "row2", "col2", "finger2",
+		"row_dist", "col_dist", "finger_dist", "distance"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	float := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+	for _, p := range pairs {
+		record := []string{
+			keyPosString(p.Key1.Index), strconv.Itoa(int(p.Key1.Row)), strconv.Itoa(int(p.Key1.Column)), fingerNames[p.Key1.Finger],
+			keyPosString(p.Key2.Index), strconv.Itoa(int(p.Key2.Row)), strconv.Itoa(int(p.Key2.Column)), fingerNames[p.Key2.Finger],
+			float(p.RowDist), float(p.ColDist), strconv.Itoa(int(p.FingerDist)), float(p.Distance),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,199 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// HistoryFormat selects how an optimization history is plotted.
+type HistoryFormat string
+
+const (
+	HistoryASCII HistoryFormat = "ascii"
+	HistorySVG   HistoryFormat = "svg"
+)
+
+// HistoryPlotOptions controls the rendering of a convergence chart.
+type HistoryPlotOptions struct {
+	Format HistoryFormat
+	Metric string // Metric to plot; empty plots the BLS cost
+	Width  int    // Columns (ascii) or pixels (svg)
+	Height int    // Rows (ascii) or pixels (svg)
+}
+
+// historyPoint is a single (iteration, value) sample of the plotted series.
+type historyPoint struct {
+	x int
+	y float64
+}
+
+// RenderHistory writes a convergence chart of the optimization history to w.
+func RenderHistory(w io.Writer, entries []kc.HistoryEntry, opts HistoryPlotOptions) error {
+	points, err := historySeries(entries, opts.Metric)
+	if err != nil {
+		return err
+	}
+
+	label := "Cost"
+	if opts.Metric != "" {
+		label = strings.ToUpper(opts.Metric)
+	}
+
+	switch opts.Format {
+	case HistoryASCII, "":
+		_, err = io.WriteString(w, historyASCII(points, label, opts.Width, opts.Height))
+	case HistorySVG:
+		_, err = io.WriteString(w, historySVG(points, label, opts.Width, opts.Height))
+	default:
+		return fmt.Errorf("unsupported history format: %s", opts.Format)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write history chart: %w", err)
+	}
+	return nil
+}
+
+// historySeries extracts the values to plot from the history entries.
+func historySeries(entries []kc.HistoryEntry, metric string) ([]historyPoint, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("history is empty")
+	}
+
+	metric = strings.ToUpper(metric)
+	points := make([]historyPoint, 0, len(entries))
+	for _, e := range entries {
+		if metric == "" {
+			points = append(points, historyPoint{e.Iteration, e.Cost})
+			continue
+		}
+		v, ok := e.Metrics[metric]
+		if !ok {
+			return nil, fmt.Errorf("metric %s not found in history", metric)
+		}
+		points = append(points, historyPoint{e.Iteration, v})
+	}
+	return points, nil
+}
+
+// historyBounds returns the x and y ranges of the series, widened to avoid
+// zero-size ranges.
+func historyBounds(points []historyPoint) (xMin, xMax int, yMin, yMax float64) {
+	xMin, xMax = points[0].x, points[0].x
+	yMin, yMax = points[0].y, points[0].y
+	for _, p := range points[1:] {
+		xMin, xMax = min(xMin, p.x), max(xMax, p.x)
+		yMin, yMax = math.Min(yMin, p.y), math.Max(yMax, p.y)
+	}
+	if xMax == xMin {
+		xMax = xMin + 1
+	}
+	if yMax-yMin < 1e-12 {
+		yMin, yMax = yMin-0.5, yMax+0.5
+	}
+	return xMin, xMax, yMin, yMax
+}
+
+// historyASCII renders the series as a step chart using plain characters.
+// Columns holding a recorded new-best layout are marked with '*'.
+func historyASCII(points []historyPoint, label string, width, height int) string {
+	width = kc.IfThen(width > 0, width, 60)
+	height = kc.IfThen(height > 0, height, 15)
+	xMin, xMax, yMin, yMax := historyBounds(points)
+
+	grid := make([][]byte, height)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", width))
+	}
+
+	toCol := func(x int) int {
+		return int(math.Round(float64(x-xMin) / float64(xMax-xMin) * float64(width-1)))
+	}
+	toRow := func(y float64) int {
+		return height - 1 - int(math.Round((y-yMin)/(yMax-yMin)*float64(height-1)))
+	}
+
+	// Draw the step function: each value holds until the next recorded point
+	for i, p := range points {
+		start := toCol(p.x)
+		end := width - 1
+		if i+1 < len(points) {
+			end = toCol(points[i+1].x) - 1
+		}
+		row := toRow(p.y)
+		for c := start; c <= end; c++ {
+			grid[row][c] = '-'
+		}
+
+		// Vertical connector to the next point
+		if i+1 < len(points) && end+1 < width {
+			nextRow := toRow(points[i+1].y)
+			for r := min(row, nextRow) + 1; r < max(row, nextRow); r++ {
+				grid[r][end+1] = '|'
+			}
+		}
+	}
+	for _, p := range points {
+		grid[toRow(p.y)][toCol(p.x)] = '*'
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s by iteration (%d new-best layouts)\n\n", label, len(points))
+	for r, line := range grid {
+		axis := "          "
+		switch r {
+		case 0:
+			axis = fmt.Sprintf("%10.4f", yMax)
+		case height - 1:
+			axis = fmt.Sprintf("%10.4f", yMin)
+		}
+		fmt.Fprintf(&sb, "%s |%s\n", axis, string(line))
+	}
+	fmt.Fprintf(&sb, "%10s +%s\n", "", strings.Repeat("-", width))
+	xMaxLabel := fmt.Sprintf("%d", xMax)
+	fmt.Fprintf(&sb, "%10s  %-*d%s\n", "", width-len(xMaxLabel), xMin, xMaxLabel)
+	return sb.String()
+}
+
+// historySVG renders the series as a standalone SVG step chart.
+func historySVG(points []historyPoint, label string, width, height int) string {
+	width = kc.IfThen(width > 0, width, 800)
+	height = kc.IfThen(height > 0, height, 400)
+	const margin = 60.0
+	xMin, xMax, yMin, yMax := historyBounds(points)
+
+	plotW := float64(width) - 2*margin
+	plotH := float64(height) - 2*margin
+	toX := func(x int) float64 { return margin + float64(x-xMin)/float64(xMax-xMin)*plotW }
+	toY := func(y float64) float64 { return margin + (yMax-y)/(yMax-yMin)*plotH }
+
+	var path strings.Builder
+	for i, p := range points {
+		if i == 0 {
+			fmt.Fprintf(&path, "M%.1f,%.1f", toX(p.x), toY(p.y))
+			continue
+		}
+		fmt.Fprintf(&path, " H%.1f V%.1f", toX(p.x), toY(p.y))
+	}
+	fmt.Fprintf(&path, " H%.1f", margin+plotW)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" font-size="14">%s by iteration</text>`+"\n", margin, margin/2, label)
+	fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="black"/>`+"\n", margin, margin, margin, margin+plotH)
+	fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="black"/>`+"\n", margin, margin+plotH, margin+plotW, margin+plotH)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="end">%.4f</text>`+"\n", margin-4, margin+4, yMax)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="end">%.4f</text>`+"\n", margin-4, margin+plotH+4, yMin)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f">%d</text>`+"\n", margin, margin+plotH+16, xMin)
+	fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" text-anchor="end">%d</text>`+"\n", margin+plotW, margin+plotH+16, xMax)
+	fmt.Fprintf(&sb, `<path d="%s" fill="none" stroke="steelblue" stroke-width="2"/>`+"\n", path.String())
+	for _, p := range points {
+		fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="3" fill="steelblue"><title>iteration %d: %.4f</title></circle>`+"\n",
+			toX(p.x), toY(p.y), p.x, p.y)
+	}
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
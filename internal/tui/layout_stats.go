@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderLayoutStats renders the distribution of each metric across the layouts of a
+// directory: a table with the minimum, quartiles, maximum and a histogram per metric, or the
+// same values as CSV.
+func RenderLayoutStats(stats *kc.LayoutStats, dir, corpusName string, format OutputFormat) error {
+	switch format {
+	case OutputTable:
+		tw := table.NewWriter()
+		tw.SetStyle(table.StyleRounded)
+		tw.Style().Title.Align = text.AlignCenter
+		tw.SetTitle("%d layouts in %s - %s", stats.Layouts, dir, corpusName)
+		tw.AppendHeader(table.Row{"Metric", "Min", "Q1", "Median", "Q3", "Max", "Histogram", "Min layout", "Max layout"})
+		tw.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 2, Align: text.AlignRight},
+			{Number: 3, Align: text.AlignRight},
+			{Number: 4, Align: text.AlignRight},
+			{Number: 5, Align: text.AlignRight},
+			{Number: 6, Align: text.AlignRight},
+		})
+		for _, ms := range stats.Metrics {
+			tw.AppendRow(table.Row{ms.Metric,
+				formatMetricValue(ms.Metric, ms.Min), formatMetricValue(ms.Metric, ms.Q1),
+				formatMetricValue(ms.Metric, ms.Median), formatMetricValue(ms.Metric, ms.Q3),
+				formatMetricValue(ms.Metric, ms.Max), histogram(ms.Bins), ms.MinLayout, ms.MaxLayout})
+		}
+		fmt.Println(tw.Render())
+		if len(stats.Metrics) > 0 {
+			fmt.Printf("Each histogram has %d bins of equal width from the minimum to the maximum.\n",
+				len(stats.Metrics[0].Bins))
+		}
+		return nil
+
+	case OutputCSV:
+		writer := csv.NewWriter(os.Stdout)
+		defer writer.Flush()
+		if err := writer.Write([]string{"metric", "min", "q1", "median", "q3", "max", "bins", "min_layout", "max_layout"}); err != nil {
+			return fmt.Errorf("could not write csv header: %w", err)
+		}
+		for _, ms := range stats.Metrics {
+			bins := make([]string, len(ms.Bins))
+			for i, n := range ms.Bins {
+				bins[i] = fmt.Sprint(n)
+			}
+			record := []string{ms.Metric,
+				formatMetricValueCSV(ms.Metric, ms.Min), formatMetricValueCSV(ms.Metric, ms.Q1),
+				formatMetricValueCSV(ms.Metric, ms.Median), formatMetricValueCSV(ms.Metric, ms.Q3),
+				formatMetricValueCSV(ms.Metric, ms.Max), strings.Join(bins, " "), ms.MinLayout, ms.MaxLayout}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("could not write csv data row: %w", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// histogram renders the counts of the bins as bars, scaled to the largest count. Empty bins
+// are blank, so that a bin of a single layout remains visible.
+func histogram(bins []int) string {
+	most := slices.Max(bins)
+	out := make([]rune, len(bins))
+	for i, n := range bins {
+		if n == 0 {
+			out[i] = ' '
+			continue
+		}
+		out[i] = sparkTicks[int(math.Ceil(float64(n)/float64(most)*float64(len(sparkTicks))))-1]
+	}
+	return string(out)
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// fingerLongNames spells out the fingers 0-9, as in kc.LP to kc.RP.
+var fingerLongNames = [10]string{
+	"left pinky", "left ring", "left middle", "left index", "left thumb",
+	"right thumb", "right index", "right middle", "right ring", "right pinky",
+}
+
+// rowNames names the rows 0-3 of the keys, as in the R0-R3 metrics.
+var rowNames = [4]string{"Top", "Home", "Bottom", "Thumb"}
+
+// RenderLegend writes the legend of the finger, column and row names of a layout type.
+func RenderLegend(w io.Writer, layoutType kc.LayoutType) error {
+	if _, err := io.WriteString(w, LegendString(layoutType)+"\n"); err != nil {
+		return fmt.Errorf("could not write legend: %w", err)
+	}
+	return nil
+}
+
+// LegendString renders the finger (F0-F9) and name of every key of a layout type over a
+// board with the column (C0-C11) and row (R0-R3) names of the metrics, followed by a table
+// that spells out the finger names.
+func LegendString(layoutType kc.LayoutType) string {
+	keys, _ := kc.KeyGeometry(layoutType)
+
+	board := table.NewWriter()
+	board.SetStyle(table.StyleRounded)
+	board.Style().Title.Align = text.AlignCenter
+	board.SetTitle(fmt.Sprintf("Fingers of %s keys", kc.LayoutTypeStrings[layoutType]))
+	header := table.Row{""}
+	configs := make([]table.ColumnConfig, 0, 12)
+	for col := range 12 {
+		header = append(header, fmt.Sprintf("C%d", col))
+		configs = append(configs, table.ColumnConfig{Number: col + 2, AlignHeader: text.AlignCenter, Align: text.AlignCenter})
+	}
+	board.AppendHeader(header)
+	board.SetColumnConfigs(configs)
+	for row := range 4 {
+		cells := table.Row{fmt.Sprintf("R%d %s", row, rowNames[row])}
+		for range 12 {
+			cells = append(cells, "")
+		}
+		for _, ki := range keys {
+			if int(ki.Row) != row {
+				continue
+			}
+			cell := fmt.Sprintf("F%d %s", ki.Finger, fingerNames[ki.Finger])
+			if row < 3 {
+				cells[1+ki.Column] = cell
+			} else {
+				// The thumb keys are below the inner columns of their hands
+				cells[4+ki.Column] = keyPosString(ki.Index) + " " + cell
+			}
+		}
+		board.AppendRow(cells)
+	}
+	board.SetCaption("Keys are named r<row>c<column> (e.g. r1c5 for the inner left index key) and t0-t5 for thumb keys.")
+
+	fingers := table.NewWriter()
+	fingers.SetStyle(table.StyleRounded)
+	fingers.Style().Title.Align = text.AlignCenter
+	fingers.SetTitle("Fingers")
+	fingers.AppendHeader(table.Row{"Metric", "Finger", "Name", "Hand"})
+	for f := range 10 {
+		hand := "H0 left"
+		if f >= int(kc.RT) {
+			hand = "H1 right"
+		}
+		fingers.AppendRow(table.Row{fmt.Sprintf("F%d", f), fingerNames[f], fingerLongNames[f], hand})
+	}
+
+	var sb strings.Builder
+	sb.WriteString(board.Render())
+	sb.WriteString("\n\n")
+	sb.WriteString(fingers.Render())
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderMagicRules renders the suggested rules of a magic key with the SFB and SFS each
+// absorbs on its own, the metrics of the layout without a magic key and with all rules, and
+// the line to add to the layout file.
+func RenderMagicRules(result *kc.MagicRulesResult) {
+	magic := result.Layout.Magic.Rune
+
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.SetTitle(fmt.Sprintf("Magic key rules for %s", result.Layout.Name))
+	tw.AppendHeader(table.Row{"#", "Rule", "Uses", "SFB", "SFS"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+	})
+	for i, rule := range result.Rules {
+		tw.AppendRow(table.Row{
+			i + 1,
+			fmt.Sprintf("%c%c → %c%c", rule.Prev, rule.Typed, rule.Prev, magic),
+			fmt.Sprintf("%.2f%%", rule.Uses),
+			fmt.Sprintf("%+.2f%%", -rule.SFB),
+			fmt.Sprintf("%+.2f%%", -rule.SFS),
+		})
+	}
+	fmt.Println(tw.Render())
+
+	before, after := result.Before.Metrics, result.After.Metrics
+	metrics := table.NewWriter()
+	metrics.SetStyle(table.StyleRounded)
+	metrics.AppendHeader(table.Row{"Metric", "Without", "With rules", "Δ"})
+	metrics.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+	})
+	for _, metric := range []string{"SFB", "SFS", "LSB", "FSB", "HSB", "ALT", "2RL", "3RL", "RED"} {
+		metrics.AppendRow(table.Row{metric, fmt.Sprintf("%.2f%%", before[metric]),
+			fmt.Sprintf("%.2f%%", after[metric]), fmt.Sprintf("%+.2f%%", after[metric]-before[metric])})
+	}
+	metrics.AppendFooter(table.Row{"MAGIC", "", fmt.Sprintf("%.2f%%", after["MAGIC"]), ""})
+	fmt.Println(metrics.Render())
+
+	fmt.Println("SFB and SFS of a rule are its effect on its own; the table above analyses all rules together.")
+	fmt.Printf("Add this line to the layout file: magic: %s\n", result.Layout.Magic)
+}
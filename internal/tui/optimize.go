@@ -2,7 +2,11 @@ package tui
 
 import (
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"github.com/jedib0t/go-pretty/v6/table"
 	kc "github.com/rbscholtus/keycraft/internal/keycraft"
 )
 
@@ -42,3 +46,117 @@ func RenderOptimize(result *kc.OptimizeResult, rankingResult *kc.RankingResult,
 
 	return nil
 }
+
+// WriteOptimizeReport writes what changed from the input layout to the optimized layout as
+// Markdown: the board, the keys that moved, the metrics before and after, and a summary of
+// the costs of the new best layouts found during the search.
+func WriteOptimizeReport(w io.Writer, report *kc.OptimizeReport) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s: what changed from %s\n\n", report.Best.Name, report.Original.Name)
+	if report.Interrupted {
+		sb.WriteString("The optimization was interrupted; this is the best layout found so far.\n\n")
+	}
+	fmt.Fprintf(&sb, "```text\n%s\n```\n\n", report.Best)
+
+	// Key moves
+	sb.WriteString("## Key moves\n\n")
+	if len(report.Moves) == 0 {
+		sb.WriteString("No keys moved.\n\n")
+	} else {
+		fmt.Fprintf(&sb, "%d characters moved.\n\n", len(report.Moves))
+		tw := table.NewWriter()
+		tw.AppendHeader(table.Row{"Char", "From", "To", "Finger"})
+		for _, m := range report.Moves {
+			from, to := report.Original.RuneInfo[m.Rune], report.Best.RuneInfo[m.Rune]
+			finger := fingerNames[from.Finger]
+			if to.Finger != from.Finger {
+				finger += " → " + fingerNames[to.Finger]
+			}
+			tw.AppendRow(table.Row{markdownRune(m.Rune), keyPosString(m.From), keyPosString(m.To), finger})
+		}
+		sb.WriteString(tw.RenderMarkdown() + "\n\n")
+	}
+
+	// Characters added and left out by the placement of remaining characters
+	if len(report.Placed) > 0 {
+		sb.WriteString("## Placed characters\n\n")
+		tw := table.NewWriter()
+		tw.AppendHeader(table.Row{"Char", "Key", "Finger"})
+		for _, r := range report.Placed {
+			ki := report.Best.RuneInfo[r]
+			tw.AppendRow(table.Row{markdownRune(r), keyPosString(ki.Index), fingerNames[ki.Finger]})
+		}
+		sb.WriteString(tw.RenderMarkdown() + "\n\n")
+		dropped := make([]string, len(report.Dropped))
+		for i, r := range report.Dropped {
+			dropped[i] = markdownRune(r)
+		}
+		fmt.Fprintf(&sb, "Left out, as they are less frequent: %s.\n\n", strings.Join(dropped, ", "))
+	}
+
+	// Metrics before and after
+	sb.WriteString("## Metrics\n\n")
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"Metric", report.Original.Name, report.Best.Name, "Delta"})
+	for _, metric := range report.Metrics {
+		before, after := report.Before[metric], report.After[metric]
+		tw.AppendRow(table.Row{metric, formatMetricValue(metric, before), formatMetricValue(metric, after),
+			fmt.Sprintf("%+.2f", after-before)})
+	}
+	sb.WriteString(tw.RenderMarkdown() + "\n\n")
+
+	// Score trajectory
+	sb.WriteString("## Score trajectory\n\n")
+	sb.WriteString(trajectorySummary(report.Trajectory) + "\n")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("could not write optimization report: %w", err)
+	}
+	return nil
+}
+
+// trajectorySummary describes the costs of the new best layouts of a search: the first and
+// last cost, and when half and 90% of the improvement were reached.
+func trajectorySummary(points []kc.TrajectoryPoint) string {
+	if len(points) == 0 {
+		return "No costs were recorded.\n"
+	}
+	first, last := points[0], points[len(points)-1]
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "- Cost: %.4f → %.4f (lower is better)\n", first.Cost, last.Cost)
+	if len(points) == 1 {
+		sb.WriteString("- No better layout than the input layout was found\n")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "- New best layouts: %d, the last at iteration %d after %s\n",
+		len(points)-1, last.Iteration, last.Elapsed.Round(time.Millisecond))
+	gain := first.Cost - last.Cost
+	for _, share := range []float64{0.5, 0.9} {
+		for _, p := range points {
+			if gain > 0 && first.Cost-p.Cost >= share*gain {
+				fmt.Fprintf(&sb, "- %.0f%% of the improvement by iteration %d after %s\n",
+					100*share, p.Iteration, p.Elapsed.Round(time.Millisecond))
+				break
+			}
+		}
+	}
+	costs := make([]float64, len(points))
+	for i, p := range points {
+		costs[i] = -p.Cost // Higher bars are better
+	}
+	fmt.Fprintf(&sb, "- Progress of the new best layouts: %s\n", sparkline(costs))
+	return sb.String()
+}
+
+// markdownRune formats a character for a Markdown table cell.
+func markdownRune(r rune) string {
+	switch r {
+	case ' ':
+		return "space"
+	case '|':
+		return "`\\|`"
+	case '`':
+		return "`` ` ``"
+	}
+	return "`" + string(r) + "`"
+}
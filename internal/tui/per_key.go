@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// PerKeyBoards returns the row labels and boards of the per-key attribution view:
+// one board each for load, SFB, LSB and scissors, showing each key's share in %.
+func PerKeyBoards(an *kc.Analyser) (labels []string, boards []string) {
+	ka := an.PerKeyMetrics()
+	values := []*[42]float64{&ka.Load, &ka.SFB, &ka.LSB, &ka.Scissors}
+	labels = []string{"Load/key", "SFB/key", "LSB/key", "Sciss/key"}
+
+	boards = make([]string, 0, len(values))
+	for _, v := range values {
+		boards = append(boards, perKeyBoardString(an.Layout.LayoutType, v))
+	}
+	return labels, boards
+}
+
+// perKeyBoardString renders per-key percentages on the board of the given layout type.
+func perKeyBoardString(layoutType kc.LayoutType, values *[42]float64) string {
+	var cells [42]string
+	for i, v := range values {
+		cells[i] = perKeyCell(v)
+	}
+	return boardString(layoutType, cells)
+}
+
+// perKeyCell formats a percentage in at most 3 characters, e.g. " 12", "1.2" or ".13".
+// Shares that round to zero are left blank.
+func perKeyCell(v float64) string {
+	switch {
+	case v >= 9.95:
+		return fmt.Sprintf("%3.0f", v)
+	case v >= 0.995:
+		return fmt.Sprintf("%3.1f", v)
+	case v >= 0.005:
+		return fmt.Sprintf(".%02d", int(math.Round(v*100)))
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,212 @@
+package tui
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// OutputMarkdown is the Markdown format of reports, such as the publish report.
+const OutputMarkdown OutputFormat = "markdown"
+
+// WritePublishReport writes the report of a new layout as a Markdown or an HTML document:
+// the board, the metrics against the baselines, the most frequent SFBs and LSBs, the corpus,
+// how the layout was optimized if known, and the commands that reproduce the numbers.
+func WritePublishReport(w io.Writer, report *kc.PublishReport, format OutputFormat) error {
+	if format != OutputMarkdown && format != OutputHTML {
+		return fmt.Errorf("unsupported report format %q; must be markdown or html", format)
+	}
+	name := report.Layout.Name
+	doc := &publishDoc{html: format == OutputHTML}
+	doc.begin(name)
+	doc.heading(1, name)
+	baselines := make([]string, 0, len(report.Scores)-1)
+	for _, score := range report.Scores[1:] {
+		baselines = append(baselines, score.Name)
+	}
+	if len(baselines) > 0 {
+		doc.para(fmt.Sprintf("Metrics of %s on the %s corpus, compared with %s.",
+			name, report.Corpus.Name, strings.Join(baselines, ", ")))
+	} else {
+		doc.para(fmt.Sprintf("Metrics of %s on the %s corpus.", name, report.Corpus.Name))
+	}
+	doc.pre(report.Layout.String())
+
+	// Metrics against the baselines
+	doc.heading(2, "Metrics")
+	if len(baselines) > 0 {
+		doc.para(fmt.Sprintf("%s ranks %d of %d by weighted score (higher is better).",
+			name, report.Rank, len(report.Scores)))
+	}
+	tw := table.NewWriter()
+	header := table.Row{"Metric"}
+	scoreRow := table.Row{"Score"}
+	for _, score := range report.Scores {
+		header = append(header, score.Name)
+		scoreRow = append(scoreRow, fmt.Sprintf("%+.2f", score.Score))
+	}
+	tw.AppendHeader(header)
+	tw.AppendRow(scoreRow)
+	for _, metric := range report.Metrics {
+		row := table.Row{metric}
+		for _, score := range report.Scores {
+			row = append(row, formatMetricValue(metric, score.Analyser.Metrics[metric]))
+		}
+		tw.AppendRow(row)
+	}
+	doc.table(tw)
+
+	// Most frequent SFBs and LSBs
+	for _, list := range []struct {
+		metric string
+		shares []kc.NGramShare
+	}{{"SFBs", report.SFBs}, {"LSBs", report.LSBs}} {
+		doc.heading(2, "Most frequent "+list.metric)
+		if len(list.shares) == 0 {
+			doc.para(fmt.Sprintf("%s has no %s on this corpus.", name, list.metric))
+			continue
+		}
+		tw := table.NewWriter()
+		tw.AppendHeader(table.Row{"Bigram", "Count", "Share"})
+		for _, s := range list.shares {
+			tw.AppendRow(table.Row{doc.code(s.NGram), Comma(s.Count), fmt.Sprintf("%.3f%%", s.Pct)})
+		}
+		doc.table(tw)
+	}
+
+	// Corpus
+	corpus := report.Corpus
+	doc.heading(2, "Corpus")
+	items := []string{
+		"Name: " + corpus.Name,
+		fmt.Sprintf("Characters: %s (%s distinct)", Comma(corpus.TotalUnigramsCount), Comma(len(corpus.Unigrams))),
+		fmt.Sprintf("Words: %s (%s distinct)", Comma(corpus.TotalWordsCount), Comma(len(corpus.Words))),
+		fmt.Sprintf("Bigrams: %s (%s distinct)", Comma(corpus.TotalBigramsCount), Comma(len(corpus.Bigrams))),
+		fmt.Sprintf("Trigrams: %s (%s distinct)", Comma(corpus.TotalTrigramsCount), Comma(len(corpus.Trigrams))),
+	}
+	if len(corpus.Substitutions) > 0 {
+		items = append(items, fmt.Sprintf("Typographic characters substituted: %d", len(corpus.Substitutions)))
+	}
+	doc.list(items)
+
+	// How the layout was optimized, from the provenance of its file
+	if p := report.Layout.Provenance; p != nil {
+		doc.heading(2, "Provenance")
+		items := []string{"Optimized from: " + p.Source, fmt.Sprintf("Seed: %d", p.Seed)}
+		if p.Corpus != "" {
+			items = append(items, "Corpus: "+p.Corpus)
+		}
+		if p.Weights != "" {
+			items = append(items, "Weights: "+p.Weights)
+		}
+		doc.list(items)
+	}
+
+	// Commands that reproduce the numbers
+	if len(report.Commands) > 0 {
+		doc.heading(2, "Reproduce")
+		doc.para("The numbers of this report are computed with keycraft:")
+		doc.pre(strings.Join(report.Commands, "\n"))
+	}
+	doc.end()
+
+	if _, err := io.WriteString(w, doc.sb.String()); err != nil {
+		return fmt.Errorf("could not write publish report: %w", err)
+	}
+	return nil
+}
+
+// publishDoc builds a document of the publish report in Markdown or HTML.
+type publishDoc struct {
+	sb   strings.Builder
+	html bool
+}
+
+// publishHTMLStyle is the style sheet of the HTML publish report.
+const publishHTMLStyle = `<style>
+body { font-family: sans-serif; max-width: 60em; }
+table { border-collapse: collapse; font-size: 13px; }
+th, td { border: 1px solid #ddd; padding: 3px 6px; text-align: right; }
+th { background: #f4f4f4; }
+td:first-child { text-align: left; }
+pre { background: #f8f8f8; padding: 6px; }
+</style>
+`
+
+func (d *publishDoc) begin(title string) {
+	if d.html {
+		d.sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">\n")
+		fmt.Fprintf(&d.sb, "<title>%s</title>\n", html.EscapeString(title))
+		d.sb.WriteString(publishHTMLStyle)
+		d.sb.WriteString("</head><body>\n")
+	}
+}
+
+func (d *publishDoc) end() {
+	if d.html {
+		d.sb.WriteString("</body></html>\n")
+	}
+}
+
+func (d *publishDoc) heading(level int, text string) {
+	if d.html {
+		fmt.Fprintf(&d.sb, "<h%d>%s</h%d>\n", level, html.EscapeString(text), level)
+		return
+	}
+	fmt.Fprintf(&d.sb, "%s %s\n\n", strings.Repeat("#", level), text)
+}
+
+func (d *publishDoc) para(text string) {
+	if d.html {
+		fmt.Fprintf(&d.sb, "<p>%s</p>\n", html.EscapeString(text))
+		return
+	}
+	d.sb.WriteString(text + "\n\n")
+}
+
+func (d *publishDoc) list(items []string) {
+	if d.html {
+		d.sb.WriteString("<ul>\n")
+		for _, item := range items {
+			fmt.Fprintf(&d.sb, "<li>%s</li>\n", html.EscapeString(item))
+		}
+		d.sb.WriteString("</ul>\n")
+		return
+	}
+	for _, item := range items {
+		d.sb.WriteString("- " + item + "\n")
+	}
+	d.sb.WriteString("\n")
+}
+
+func (d *publishDoc) pre(text string) {
+	if d.html {
+		fmt.Fprintf(&d.sb, "<pre>%s</pre>\n", html.EscapeString(text))
+		return
+	}
+	fmt.Fprintf(&d.sb, "```text\n%s\n```\n\n", strings.TrimRight(text, "\n"))
+}
+
+func (d *publishDoc) table(tw table.Writer) {
+	if d.html {
+		d.sb.WriteString(tw.RenderHTML() + "\n")
+		return
+	}
+	d.sb.WriteString(tw.RenderMarkdown() + "\n\n")
+}
+
+// code formats an n-gram for a table cell, with spaces made visible.
+func (d *publishDoc) code(ngram string) string {
+	ngram = strings.ReplaceAll(ngram, " ", "␣")
+	if d.html {
+		return ngram // Escaped by the table
+	}
+	if strings.Contains(ngram, "`") {
+		return "`` " + ngram + " ``"
+	}
+	return "`" + ngram + "`"
+}
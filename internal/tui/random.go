@@ -0,0 +1,44 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderRandomBaseline renders the score distribution of the random layouts, and for each
+// of the given layouts the percentage of the random layouts that it beats.
+func RenderRandomBaseline(baseline *kc.RandomBaseline, scores []kc.LayoutScore) {
+	n := len(baseline.Scores)
+	if n == 0 {
+		return
+	}
+	fmt.Printf("Random baseline: %d layouts, scores from %.2f to %.2f (median %.2f)\n",
+		n, baseline.Scores[0], baseline.Scores[n-1], baseline.Median())
+	if len(scores) == 0 {
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignCenter
+	tw.SetTitle(fmt.Sprintf("Layouts vs %d random layouts", n))
+	tw.AppendHeader(table.Row{"Layout", "Score", "Better than"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+	})
+	for _, ls := range scores {
+		tw.AppendRow(table.Row{ls.Name, fmt.Sprintf("%.2f", ls.Score), fmt.Sprintf("%.1f%%", baseline.PercentBeaten(ls.Score))})
+	}
+	fmt.Println(tw.Render())
+}
+
+// RandomComparisonString returns the percentage of random layouts that a layout beats, with
+// its score and the median score of the random layouts.
+func RandomComparisonString(rc *kc.RandomComparison) string {
+	return fmt.Sprintf("Better than %.1f%%\nof %d random layouts\nScore %.2f (random median %.2f)",
+		rc.PercentBeaten(), len(rc.Baseline.Scores), rc.Score, rc.Baseline.Median())
+}
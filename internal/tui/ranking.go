@@ -3,13 +3,11 @@ package tui
 import (
 	"encoding/csv"
 	"fmt"
-	"html"
 	"io"
-	"net/url"
+	"math"
 	"os"
 	"slices"
 	"sort"
-	"strings"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
@@ -47,18 +45,34 @@ const (
 	DeltasCustom DeltasOption = "custom" // Compare to specific layout
 )
 
+// DeltaMode determines the unit in which deltas are displayed.
+type DeltaMode string
+
+const (
+	DeltaAbsolute   DeltaMode = "absolute"   // Difference of the values (percentage points for most metrics)
+	DeltaPercent    DeltaMode = "percent"    // Change relative to the compared value, in %
+	DeltaNormalised DeltaMode = "normalised" // Difference in IQRs of the metric across the reference layouts
+)
+
 // RankingDisplayOptions configures presentation and comparison behavior.
 // Supports predefined metric sets or custom lists, various delta modes, and multiple output formats.
 type RankingDisplayOptions struct {
 	OutputFormat   OutputFormat
 	MetricsOption  MetricsOption
-	CustomMetrics  []string     // Used when MetricsOption == MetricsCustom
-	ShowWeights    bool         // Display weight row in output
-	Weights        *kc.Weights  // Metric weights for display and delta coloring
-	DeltasOption   DeltasOption // "none", "rows", "median", "custom"
-	BaseLayoutName string       // Name of reference layout when DeltasOption == DeltasCustom
-	CorpusName     string       // Name of the corpus used for ranking
-	LinkBase       string       // When non-empty and OutputFormat == OutputHTML, wrap each Name cell in <a href="<LinkBase><name>.html">…</a>
+	CustomMetrics  []string           // Used when MetricsOption == MetricsCustom
+	ShowWeights    bool               // Display weight row in output
+	Weights        *kc.Weights        // Metric weights for display and delta coloring
+	DeltasOption   DeltasOption       // "none", "rows", "median", "custom"
+	BaseLayoutName string             // Name of reference layout when DeltasOption == DeltasCustom
+	DeltaMode      DeltaMode          // Unit of the deltas ("" = absolute)
+	IQRs           map[string]float64 // IQR of each metric, for normalised deltas (nil = those of the ranking)
+	CorpusName     string             // Name of the corpus used for ranking
+	MetricVersion  int                // Version of the metric definitions (0 = not shown)
+	LinkBase       string             // When non-empty and OutputFormat == OutputHTML, wrap each Name cell in <a href="<LinkBase><name>.html">…</a>
+	ExtraMetrics   []string           // Imported metric columns, displayed after the selected metrics unless MetricsCustom
+	GroupBy        kc.GroupBy         // Group the layouts under headers, with the best and median of each group (table and CSV only)
+	SortBy         string             // Metric to sort the layouts by, best first by the sign of its weight ("" = score)
+	Transpose      bool               // Show the metrics as rows and the layouts as columns (table and CSV only)
 	// baseLayoutScores *kc.LayoutScore // Cached reference to base layout scores (set during rendering)
 }
 
@@ -67,23 +81,41 @@ func (opts RankingDisplayOptions) GetMetrics() []string {
 	if opts.MetricsOption == MetricsCustom {
 		return opts.CustomMetrics
 	}
+	metrics := opts.selectedMetrics()
+	for _, metric := range opts.ExtraMetrics {
+		if !slices.Contains(metrics, metric) {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// selectedMetrics returns the metrics of the predefined set or the weighted metrics.
+func (opts RankingDisplayOptions) selectedMetrics() []string {
 	if opts.MetricsOption == MetricsWeighted {
-		// Return all metrics with absolute weight >= 0.01
-		allMetrics := kc.MetricsMap["all"]
-		var weightedMetrics []string
-		for _, metric := range allMetrics {
-			if weight := opts.Weights.Get(metric); weight >= 0.01 || weight <= -0.01 {
-				weightedMetrics = append(weightedMetrics, metric)
-			}
+		return WeightedMetrics(opts.Weights)
+	}
+	return slices.Clone(kc.MetricsMap[string(opts.MetricsOption)])
+}
+
+// WeightedMetrics returns the metrics with an absolute weight of at least 0.01, in the order
+// of the "all" metric set.
+func WeightedMetrics(weights *kc.Weights) []string {
+	var weightedMetrics []string
+	for _, metric := range kc.MetricsMap["all"] {
+		if weight := weights.Get(metric); weight >= 0.01 || weight <= -0.01 {
+			weightedMetrics = append(weightedMetrics, metric)
 		}
-		return weightedMetrics
 	}
-	return kc.MetricsMap[string(opts.MetricsOption)]
+	return weightedMetrics
 }
 
 // RenderRankingTable formats and prints ranking results.
 func RenderRankingTable(result *kc.RankingResult, opts RankingDisplayOptions) error {
 	metrics := opts.GetMetrics()
+	if opts.IQRs == nil {
+		opts.IQRs = result.IQRs
+	}
 
 	scores := result.Scores
 
@@ -95,17 +127,27 @@ func RenderRankingTable(result *kc.RankingResult, opts RankingDisplayOptions) er
 
 	// Sort scores once (higher is better)
 	sort.Slice(scores, func(i, j int) bool {
+		if opts.SortBy != "" {
+			return betterMetric(opts.SortBy, scores[i].Analyser.Metrics[opts.SortBy],
+				scores[j].Analyser.Metrics[opts.SortBy], opts.Weights)
+		}
 		return scores[i].Score > scores[j].Score
 	})
 
+	if opts.GroupBy != kc.GroupNone {
+		return renderGroupedRanking(os.Stdout, kc.GroupScores(scores, opts.GroupBy, opts.Weights), metrics, opts)
+	}
+	if opts.Transpose {
+		return renderTransposedRanking(os.Stdout, scores, metrics, opts)
+	}
+
 	// Render based on output format
 	switch opts.OutputFormat {
 	case OutputTable:
 		renderTableTerminal(scores, metrics, opts)
 		return nil
 	case OutputHTML:
-		renderTableHTML(scores, metrics, opts)
-		return nil
+		return renderRankingHTML(os.Stdout, scores, metrics, opts)
 	case OutputCSV:
 		return renderCSV(os.Stdout, scores, metrics, opts)
 	default:
@@ -113,53 +155,38 @@ func RenderRankingTable(result *kc.RankingResult, opts RankingDisplayOptions) er
 	}
 }
 
+// betterMetric reports whether value a of a metric is better than value b: lower is better
+// for a metric with a negative weight, and higher is better otherwise.
+func betterMetric(metric string, a, b float64, weights *kc.Weights) bool {
+	if weights.Get(metric) < 0 {
+		return a < b
+	}
+	return a > b
+}
+
 // renderTableTerminal renders to terminal with colors.
 func renderTableTerminal(scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) {
 	tw := buildTable(scores, metrics, opts)
 	fmt.Println(tw.Render())
 }
 
-// renderTableHTML uses go-pretty's built-in HTML rendering. When opts.LinkBase
-// is non-empty, each layout Name cell is wrapped in an anchor tag pointing to
-// "<LinkBase><url-escaped name>.html" so the rendered table can link to per-layout pages.
-func renderTableHTML(scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) {
-	tw := buildTable(scores, metrics, opts)
-	tw.SetHTMLCSSClass("keycraft-ranking-table")
-	out := tw.RenderHTML()
-
-	if opts.LinkBase != "" {
-		for _, score := range scores {
-			esc := html.EscapeString(score.Name)
-			link := fmt.Sprintf(`<a href="%s%s.html">%s</a>`,
-				opts.LinkBase, url.PathEscape(score.Name), esc)
-			out = strings.Replace(out, ">"+esc+"<", ">"+link+"<", 1)
-		}
-	}
-
-	fmt.Println(out)
-}
-
 // buildTable creates the table structure (shared by both HTML and terminal rendering).
 func buildTable(scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) table.Writer {
+	tw := newRankingTable(metrics, opts)
+	addDataRows(tw, scores, metrics, opts)
+	return tw
+}
+
+// newRankingTable creates the ranking table with its title and header, and the weight row
+// if requested.
+func newRankingTable(metrics []string, opts RankingDisplayOptions) table.Writer {
 	tw := table.NewWriter()
 	tw.SetStyle(table.StyleRounded)
 	tw.Style().Box.PaddingLeft = ""
 	tw.Style().Box.PaddingRight = ""
 	tw.Style().Title.Align = text.AlignLeft
 
-	// Set title based on delta mode
-	switch opts.DeltasOption {
-	case DeltasCustom:
-		tw.SetTitle(fmt.Sprintf("Layout Ranking (Compare to %s)", opts.BaseLayoutName))
-	case DeltasMedian:
-		tw.SetTitle("Layout Ranking (Compare to median)")
-	default:
-		if opts.CorpusName != "" {
-			tw.SetTitle(fmt.Sprintf("Layout Ranking - %s", opts.CorpusName))
-		} else {
-			tw.SetTitle("Layout Ranking")
-		}
-	}
+	tw.SetTitle(rankingTitle(opts))
 
 	// Configure column alignment
 	colConfigs := []table.ColumnConfig{
@@ -194,12 +221,42 @@ func buildTable(scores []kc.LayoutScore, metrics []string, opts RankingDisplayOp
 		tw.AppendHeader(weightRow)
 	}
 
-	// Add data rows
-	addDataRows(tw, scores, metrics, opts)
-
 	return tw
 }
 
+// rankingTitle returns the title of the ranking, naming the corpus, metric version and delta
+// mode.
+func rankingTitle(opts RankingDisplayOptions) string {
+	title := "Layout Ranking"
+	if opts.CorpusName != "" {
+		title += " - " + opts.CorpusName
+	}
+	if opts.MetricVersion != 0 {
+		title += fmt.Sprintf(" - metrics v%d", opts.MetricVersion)
+	}
+	unit := ""
+	switch opts.DeltaMode {
+	case DeltaPercent:
+		unit = ", % change"
+	case DeltaNormalised:
+		unit = ", in IQRs"
+	}
+	if opts.SortBy != "" {
+		title += " - sorted by " + opts.SortBy
+	}
+	switch opts.DeltasOption {
+	case DeltasCustom:
+		title += " (Compare to " + opts.BaseLayoutName + unit + ")"
+	case DeltasMedian:
+		title += " (Compare to median" + unit + ")"
+	case DeltasRows:
+		if unit != "" {
+			title += " (Deltas" + unit + ")"
+		}
+	}
+	return title
+}
+
 // addDataRows populates the table with data (shared logic).
 func addDataRows(tw table.Writer, scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) {
 	rowIdx := 1
@@ -232,17 +289,14 @@ func addDataRows(tw table.Writer, scores []kc.LayoutScore, metrics []string, opt
 		if i > 0 && opts.DeltasOption != DeltasNone {
 			deltaRow := table.Row{"", "", "", ""}
 			for idx, currMetric := range currMetrics {
-				var delta float64
+				curr, ref := currMetric, prevMetrics[idx]
 				if opts.DeltasOption == DeltasCustom || opts.DeltasOption == DeltasMedian {
 					if rowIdx <= 0 {
-						delta = prevMetrics[idx] - refMetrics[idx]
-					} else {
-						delta = currMetric - refMetrics[idx]
+						curr = prevMetrics[idx]
 					}
-				} else {
-					delta = currMetric - prevMetrics[idx]
+					ref = refMetrics[idx]
 				}
-				deltaRow = append(deltaRow, formatDelta(metrics[idx], delta, opts.Weights))
+				deltaRow = append(deltaRow, opts.formatDelta(metrics[idx], curr, ref))
 			}
 			tw.AppendRow(deltaRow)
 		}
@@ -354,17 +408,14 @@ func renderCSV(w io.Writer, scores []kc.LayoutScore, metrics []string, opts Rank
 		if i > 0 && opts.DeltasOption != DeltasNone {
 			deltaRow := []string{"", "", "", ""}
 			for idx, currMetric := range currMetrics {
-				var delta float64
+				curr, ref := currMetric, prevMetrics[idx]
 				if opts.DeltasOption == DeltasCustom || opts.DeltasOption == DeltasMedian {
 					if rowIdx <= 0 {
-						delta = prevMetrics[idx] - refMetrics[idx]
-					} else {
-						delta = currMetric - refMetrics[idx]
+						curr = prevMetrics[idx]
 					}
-				} else {
-					delta = currMetric - prevMetrics[idx]
+					ref = refMetrics[idx]
 				}
-				deltaRow = append(deltaRow, formatDeltaCSV(metrics[idx], delta))
+				deltaRow = append(deltaRow, opts.formatDeltaCSV(metrics[idx], curr, ref))
 			}
 			if err := writer.Write(deltaRow); err != nil {
 				return err
@@ -383,9 +434,9 @@ func renderCSV(w io.Writer, scores []kc.LayoutScore, metrics []string, opts Rank
 }
 
 // formatMetricValue formats a metric value for table display.
-// IN:OUT ratio is displayed as a plain number, others as percentages.
+// IN:OUT ratio and external metrics are displayed as plain numbers, others as percentages.
 func formatMetricValue(metric string, val float64) string {
-	if metric == "IN:OUT" {
+	if metric == "IN:OUT" || isPlainMetric(metric) {
 		return fmt.Sprintf("%.2f", val)
 	}
 	return fmt.Sprintf("%.2f%%", val)
@@ -397,19 +448,49 @@ func formatMetricValueCSV(_ string, val float64) string {
 	return fmt.Sprintf("%.2f", val)
 }
 
-// formatDeltaCSV formats delta for CSV (no color codes).
-func formatDeltaCSV(metric string, delta float64) string {
-	if metric == "IN:OUT" {
+// scaleDelta converts the change of a metric from ref to curr into the delta mode. It
+// reports false if the change cannot be expressed in that mode, such as a relative change
+// from 0.
+func (opts RankingDisplayOptions) scaleDelta(metric string, curr, ref float64) (float64, bool) {
+	delta := curr - ref
+	switch opts.DeltaMode {
+	case DeltaPercent:
+		if ref == 0 {
+			return 0, false
+		}
+		return 100 * delta / math.Abs(ref), true
+	case DeltaNormalised:
+		iqr := opts.IQRs[metric]
+		if iqr == 0 {
+			return 0, false
+		}
+		return delta / iqr, true
+	default:
+		return delta, true
+	}
+}
+
+// formatDeltaCSV formats the change of a metric from ref to curr for CSV (no color codes).
+func (opts RankingDisplayOptions) formatDeltaCSV(metric string, curr, ref float64) string {
+	delta, ok := opts.scaleDelta(metric, curr, ref)
+	if !ok {
+		return ""
+	}
+	if metric == "IN:OUT" && (opts.DeltaMode == DeltaAbsolute || opts.DeltaMode == "") {
 		return fmt.Sprintf("%.2f", delta)
 	}
 	return fmt.Sprintf("%+.2f", delta)
 }
 
-// formatDelta formats the delta between metrics with color based on weight polarity.
-// Green indicates improvement (positive delta for positive weight, or vice versa).
+// formatDelta formats the change of a metric from ref to curr with color based on weight
+// polarity. Green indicates improvement (positive delta for positive weight, or vice versa).
 // Red indicates degradation. Negligible changes (< 0.005) are shown in default color.
-func formatDelta(metric string, delta float64, weights *kc.Weights) string {
-	positive := weights.Get(metric) >= 0
+func (opts RankingDisplayOptions) formatDelta(metric string, curr, ref float64) string {
+	delta, ok := opts.scaleDelta(metric, curr, ref)
+	if !ok {
+		return ""
+	}
+	positive := opts.Weights.Get(metric) >= 0
 	var c text.Color
 
 	switch {
@@ -421,8 +502,20 @@ func formatDelta(metric string, delta float64, weights *kc.Weights) string {
 		c = text.Reset
 	}
 
-	if metric == "IN:OUT" {
+	switch {
+	case opts.DeltaMode == DeltaPercent:
+		return c.Sprintf("%+.1f%%", delta)
+	case opts.DeltaMode == DeltaNormalised:
+		return c.Sprintf("%+.2f", delta)
+	case metric == "IN:OUT":
 		return c.Sprintf("%.2f", delta)
+	case isPlainMetric(metric):
+		return c.Sprintf("%+.2f", delta)
 	}
 	return c.Sprintf("%+.2f%%", delta)
 }
+
+// isPlainMetric reports whether a metric is a plain number rather than a percentage.
+func isPlainMetric(metric string) bool {
+	return kc.IsExternalMetric(metric) || slices.Contains(kc.HandRunMetrics, metric) || metric == "MOVE"
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderCorpusRanking prints the scores of layouts ranked against several corpora: the
+// combined score, the worst score, and the score against each corpus, with the normalised
+// corpus weights in a header row.
+func RenderCorpusRanking(rows []kc.CorpusScores, corpora []kc.WeightedCorpus, format OutputFormat) error {
+	var total float64
+	for _, wc := range corpora {
+		total += wc.Weight
+	}
+
+	switch format {
+	case OutputTable:
+		tw := table.NewWriter()
+		tw.SetStyle(table.StyleRounded)
+		tw.Style().Box.PaddingLeft = ""
+		tw.Style().Box.PaddingRight = ""
+		tw.Style().Title.Align = text.AlignLeft
+		tw.SetTitle("Layout Ranking - %d corpora", len(corpora))
+
+		header := table.Row{"#", "Name", "Score", "Worst"}
+		weightRow := table.Row{"", "Weight", "", ""}
+		colConfigs := []table.ColumnConfig{
+			{Number: 1, Align: text.AlignRight},
+			{Number: 3, Align: text.AlignRight, AlignHeader: text.AlignRight},
+			{Number: 4, Align: text.AlignRight, AlignHeader: text.AlignRight},
+		}
+		for i, wc := range corpora {
+			header = append(header, wc.Corpus.Name)
+			weightRow = append(weightRow, fmt.Sprintf("%.2f", wc.Weight/total))
+			colConfigs = append(colConfigs, table.ColumnConfig{Number: 5 + i, Align: text.AlignRight, AlignHeader: text.AlignRight})
+		}
+		tw.AppendHeader(header)
+		tw.AppendHeader(weightRow)
+		tw.SetColumnConfigs(colConfigs)
+
+		for i, row := range rows {
+			tr := table.Row{i + 1, row.Name, fmt.Sprintf("%+.2f", row.Combined), fmt.Sprintf("%+.2f", row.Worst)}
+			for _, score := range row.Scores {
+				tr = append(tr, fmt.Sprintf("%+.2f", score))
+			}
+			tw.AppendRow(tr)
+		}
+		fmt.Println(tw.Render())
+		return nil
+
+	case OutputCSV:
+		writer := csv.NewWriter(os.Stdout)
+		defer writer.Flush()
+
+		header := []string{"Rank", "Name", "Score", "Worst"}
+		weightRow := []string{"", "Weight", "", ""}
+		for _, wc := range corpora {
+			header = append(header, wc.Corpus.Name)
+			weightRow = append(weightRow, fmt.Sprintf("%.4f", wc.Weight/total))
+		}
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("could not write csv header: %w", err)
+		}
+		if err := writer.Write(weightRow); err != nil {
+			return fmt.Errorf("could not write csv weights row: %w", err)
+		}
+		for i, row := range rows {
+			record := []string{fmt.Sprintf("%d", i+1), row.Name, fmt.Sprintf("%.2f", row.Combined), fmt.Sprintf("%.2f", row.Worst)}
+			for _, score := range row.Scores {
+				record = append(record, fmt.Sprintf("%.2f", score))
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("could not write csv data row: %w", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("output format %s is not supported when ranking against several corpora", format)
+	}
+}
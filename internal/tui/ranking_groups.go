@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// renderGroupedRanking renders the groups of a ranking: in a table, each group under a
+// header row and followed by its best and median rows, or in CSV with a Group column.
+func renderGroupedRanking(w io.Writer, groups []kc.RankingGroup, metrics []string, opts RankingDisplayOptions) error {
+	if opts.OutputFormat == OutputCSV {
+		return renderGroupedCSV(w, groups, metrics, opts)
+	}
+	if opts.OutputFormat != OutputTable {
+		return fmt.Errorf("grouped rankings support table and csv output, not %s", opts.OutputFormat)
+	}
+
+	tw := newRankingTable(metrics, opts)
+	for i, group := range groups {
+		if i > 0 {
+			tw.AppendSeparator()
+		}
+		title := fmt.Sprintf("%s: %s (%d)", opts.GroupBy, group.Name, len(group.Scores))
+		tw.AppendRow(table.Row{"", text.Bold.Sprint(title), "", ""})
+		addDataRows(tw, group.Scores, metrics, opts)
+		for _, row := range []kc.LayoutScore{group.Best, group.Median} {
+			tw.AppendRow(groupRowCells(row, metrics))
+		}
+	}
+	_, err := fmt.Fprintln(w, tw.Render())
+	return err
+}
+
+// groupRowCells returns the cells of the best or median row of a group, which are faint to
+// set them apart from the layouts.
+func groupRowCells(row kc.LayoutScore, metrics []string) table.Row {
+	cells := table.Row{"", text.Faint.Sprint(row.Name), "", text.Faint.Sprintf("%+.2f", row.Score)}
+	for i, val := range extractMetrics(&row, metrics) {
+		cells = append(cells, text.Faint.Sprint(formatMetricValue(metrics[i], val)))
+	}
+	return cells
+}
+
+// renderGroupedCSV writes the groups of a ranking in CSV, with the group of each layout in
+// the first column, and the best and median rows of each group after its layouts.
+func renderGroupedCSV(w io.Writer, groups []kc.RankingGroup, metrics []string, opts RankingDisplayOptions) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	header := append([]string{"Group", "Rank", "Name", "Th", "Score"}, metrics...)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("could not write csv header: %w", err)
+	}
+	if opts.ShowWeights {
+		weightRow := []string{"", "", "Weight", "", ""}
+		for _, metric := range metrics {
+			weightRow = append(weightRow, fmt.Sprintf("%.2f", opts.Weights.Get(metric)))
+		}
+		if err := writer.Write(weightRow); err != nil {
+			return fmt.Errorf("could not write csv weights row: %w", err)
+		}
+	}
+
+	for _, group := range groups {
+		rows := slices.Concat(group.Scores, []kc.LayoutScore{group.Best, group.Median})
+		for i, score := range rows {
+			rank := ""
+			if i < len(group.Scores) {
+				rank = fmt.Sprintf("%d", i+1)
+			}
+			row := []string{group.Name, rank, score.Name, getThumbChars(&score), fmt.Sprintf("%.2f", score.Score)}
+			for j, val := range extractMetrics(&score, metrics) {
+				row = append(row, formatMetricValueCSV(metrics[j], val))
+			}
+			if err := writer.Write(row); err != nil {
+				return fmt.Errorf("could not write csv data row: %w", err)
+			}
+		}
+	}
+	return nil
+}
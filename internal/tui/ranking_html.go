@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// renderRankingHTML writes the ranking as a self-contained HTML page with a sortable
+// table. Columns can be shown or hidden, deltas are highlighted green (better) or red
+// (worse) according to the metric weights, and each row has a small board preview.
+// Deltas compare each layout to the previous row (--deltas rows, in ranking order) or
+// to the reference layout (custom and median deltas).
+func renderRankingHTML(w io.Writer, scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) error {
+	var refMetrics []float64
+	refName := ""
+	if opts.DeltasOption == DeltasCustom || opts.DeltasOption == DeltasMedian {
+		refName = kc.IfThen(opts.DeltasOption == DeltasMedian, "median", opts.BaseLayoutName)
+		if idx := slices.IndexFunc(scores, func(ls kc.LayoutScore) bool { return ls.Name == refName }); idx >= 0 {
+			refMetrics = extractMetrics(&scores[idx], metrics)
+		}
+	}
+
+	title := rankingTitle(opts)
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n", html.EscapeString(title))
+	sb.WriteString(rankingHTMLStyle)
+	sb.WriteString("</head><body>\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n", html.EscapeString(title))
+	sb.WriteString("<p>Click a column header to sort. Higher scores are better.</p>\n")
+
+	// Column toggles (the rank and name columns are always shown)
+	columns := append([]string{"Th", "Board", "Score"}, metrics...)
+	sb.WriteString("<div class=\"columns\">Columns:")
+	for i, col := range columns {
+		fmt.Fprintf(&sb, ` <label><input type="checkbox" data-col="%d" checked> %s</label>`,
+			i+2, html.EscapeString(col))
+	}
+	sb.WriteString("</div>\n")
+
+	sb.WriteString("<table class=\"keycraft-ranking-table\">\n<thead><tr>")
+	sb.WriteString(`<th data-type="num">#</th><th data-type="text">Name</th>`)
+	sb.WriteString(`<th data-type="text">Th</th><th data-type="none">Board</th><th data-type="num">Score</th>`)
+	for _, metric := range metrics {
+		fmt.Fprintf(&sb, `<th data-type="num">%s</th>`, html.EscapeString(metric))
+	}
+	sb.WriteString("</tr>\n")
+	if opts.ShowWeights {
+		sb.WriteString(`<tr class="weights"><th></th><th>Weight</th><th></th><th></th><th></th>`)
+		for _, metric := range metrics {
+			fmt.Fprintf(&sb, "<th>%.2f</th>", opts.Weights.Get(metric))
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</thead>\n<tbody>\n")
+
+	rank := 1
+	if idx := slices.IndexFunc(scores, func(ls kc.LayoutScore) bool { return ls.Name == refName }); idx >= 0 {
+		rank -= 1 + idx // The reference layout is ranked 0, as in the terminal table
+	}
+	var prevMetrics []float64
+	for _, score := range scores {
+		currMetrics := extractMetrics(&score, metrics)
+		baseline := kc.IfThen(refMetrics != nil, refMetrics, prevMetrics)
+		isRef := refName != "" && score.Name == refName
+
+		sb.WriteString("<tr")
+		if isRef {
+			sb.WriteString(` class="reference"`)
+		}
+		sb.WriteString(">")
+		fmt.Fprintf(&sb, `<td data-sort="%d">%d</td>`, rank, rank)
+		fmt.Fprintf(&sb, `<td class="name" data-sort="%s">%s</td>`,
+			html.EscapeString(score.Name), rankingNameCell(score.Name, opts.LinkBase))
+		fmt.Fprintf(&sb, "<td class=\"thumbs\">%s</td>", html.EscapeString(getThumbChars(&score)))
+		sb.WriteString("<td>")
+		if score.Analyser.Layout != nil {
+			fmt.Fprintf(&sb, "<pre class=\"board\">%s</pre>", html.EscapeString(score.Analyser.Layout.String()))
+		}
+		sb.WriteString("</td>")
+		fmt.Fprintf(&sb, `<td data-sort="%.6f">%+.2f</td>`, score.Score, score.Score)
+
+		for j, metric := range metrics {
+			val := currMetrics[j]
+			fmt.Fprintf(&sb, `<td data-sort="%.6f">%s`, val, html.EscapeString(formatMetricValue(metric, val)))
+			if baseline != nil && !isRef && opts.DeltasOption != DeltasNone {
+				if delta, ok := opts.scaleDelta(metric, val, baseline[j]); ok {
+					fmt.Fprintf(&sb, `<span class="delta %s">%s</span>`,
+						deltaClass(metric, delta, opts.Weights), html.EscapeString(opts.formatDeltaCSV(metric, val, baseline[j])))
+				}
+			}
+			sb.WriteString("</td>")
+		}
+		sb.WriteString("</tr>\n")
+
+		prevMetrics = currMetrics
+		rank++
+	}
+	sb.WriteString("</tbody>\n</table>\n")
+	sb.WriteString(rankingHTMLScript)
+	sb.WriteString("</body></html>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// rankingNameCell returns the escaped layout name, linked to "<linkBase><name>.html"
+// when linkBase is non-empty.
+func rankingNameCell(name, linkBase string) string {
+	esc := html.EscapeString(name)
+	if linkBase == "" {
+		return esc
+	}
+	return fmt.Sprintf(`<a href="%s%s.html">%s</a>`, html.EscapeString(linkBase), url.PathEscape(name), esc)
+}
+
+// deltaClass returns "better", "worse" or "same" for a metric delta, following the
+// weight polarity as in formatDelta.
+func deltaClass(metric string, delta float64, weights *kc.Weights) string {
+	positive := weights.Get(metric) >= 0
+	switch {
+	case delta >= 0.005:
+		return kc.IfThen(positive, "better", "worse")
+	case delta <= -0.005:
+		return kc.IfThen(positive, "worse", "better")
+	default:
+		return "same"
+	}
+}
+
+const rankingHTMLStyle = `<style>
+body { font-family: sans-serif; }
+div.columns { margin-bottom: 8px; font-size: 13px; }
+div.columns label { margin-right: 6px; white-space: nowrap; }
+table.keycraft-ranking-table { border-collapse: collapse; font-size: 13px; }
+table.keycraft-ranking-table th, table.keycraft-ranking-table td { border: 1px solid #ddd; padding: 3px 6px; text-align: right; vertical-align: top; }
+table.keycraft-ranking-table th { background: #f4f4f4; cursor: pointer; user-select: none; position: sticky; top: 0; }
+table.keycraft-ranking-table th.asc::after { content: " \25B2"; }
+table.keycraft-ranking-table th.desc::after { content: " \25BC"; }
+table.keycraft-ranking-table td.name, table.keycraft-ranking-table td.thumbs { text-align: left; }
+table.keycraft-ranking-table tr.reference { background: #fff8dc; }
+table.keycraft-ranking-table tr.weights th { cursor: default; font-weight: normal; color: #666; }
+pre.board { margin: 0; font-size: 9px; line-height: 1.1; text-align: left; }
+span.delta { display: block; font-size: 11px; }
+span.better { color: #080; }
+span.worse { color: #c00; }
+span.same { color: #888; }
+</style>
+`
+
+const rankingHTMLScript = `<script>
+(function () {
+  var table = document.querySelector("table.keycraft-ranking-table");
+  var tbody = table.tBodies[0];
+  var headers = table.tHead.rows[0].cells;
+  for (var i = 0; i < headers.length; i++) {
+    (function (col, th) {
+      var type = th.getAttribute("data-type");
+      if (type === "none") { th.style.cursor = "default"; return; }
+      th.addEventListener("click", function () {
+        var desc = !th.classList.contains("desc");
+        for (var k = 0; k < headers.length; k++) { headers[k].classList.remove("asc", "desc"); }
+        th.classList.add(desc ? "desc" : "asc");
+        var rows = Array.prototype.slice.call(tbody.rows);
+        rows.sort(function (a, b) {
+          var x = a.cells[col].getAttribute("data-sort") || a.cells[col].textContent;
+          var y = b.cells[col].getAttribute("data-sort") || b.cells[col].textContent;
+          var cmp = type === "num" ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+          return desc ? -cmp : cmp;
+        });
+        rows.forEach(function (row) { tbody.appendChild(row); });
+      });
+    })(i, headers[i]);
+  }
+  document.querySelectorAll("div.columns input").forEach(function (box) {
+    box.addEventListener("change", function () {
+      var col = parseInt(box.getAttribute("data-col"), 10);
+      var display = box.checked ? "" : "none";
+      for (var r = 0; r < table.rows.length; r++) {
+        if (table.rows[r].cells[col]) { table.rows[r].cells[col].style.display = display; }
+      }
+    });
+  });
+})();
+</script>
+`
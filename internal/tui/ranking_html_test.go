@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// rankingHTMLScores returns two ranked layouts with fixed metrics.
+func rankingHTMLScores(t *testing.T) []kc.LayoutScore {
+	t.Helper()
+	var scores []kc.LayoutScore
+	for i, name := range []string{"colemak", "qwerty"} {
+		layout, err := kc.NewLayoutFromFile(name, "../../data/layouts/"+name+".klf")
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", name, err)
+		}
+		sfb := 1.0 + 5*float64(i)
+		scores = append(scores, kc.LayoutScore{
+			Name:     name,
+			Score:    -sfb,
+			Analyser: &kc.Analyser{Layout: layout, Metrics: map[string]float64{"SFB": sfb, "ALT": 30}},
+		})
+	}
+	return scores
+}
+
+func TestRenderRankingHTML(t *testing.T) {
+	weights := kc.Must(kc.NewWeightsFromString("SFB=-1,ALT=1"))
+	opts := RankingDisplayOptions{
+		OutputFormat: OutputHTML,
+		ShowWeights:  true,
+		Weights:      weights,
+		DeltasOption: DeltasRows,
+		CorpusName:   "default",
+		LinkBase:     "layouts/",
+	}
+
+	scores := rankingHTMLScores(t)
+	var buf bytes.Buffer
+	if err := renderRankingHTML(&buf, scores, []string{"SFB", "ALT"}, opts); err != nil {
+		t.Fatalf("renderRankingHTML failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		// A self-contained page with the title
+		"<!DOCTYPE html>",
+		"<title>Layout Ranking - default</title>",
+		"<style>",
+		"</body></html>",
+		// A sortable table with typed headers, and the script that sorts it
+		`<table class="keycraft-ranking-table">`,
+		`<th data-type="num">#</th><th data-type="text">Name</th>`,
+		`<th data-type="none">Board</th>`,
+		`<th data-type="num">SFB</th><th data-type="num">ALT</th>`,
+		`<td data-sort="1.000000">1.00%</td>`,
+		`th.addEventListener("click"`,
+		// One toggle for each column but the rank and name
+		`<input type="checkbox" data-col="2" checked> Th</label>`,
+		`<input type="checkbox" data-col="4" checked> Score</label>`,
+		`<input type="checkbox" data-col="5" checked> SFB</label>`,
+		`<input type="checkbox" data-col="6" checked> ALT</label>`,
+		`box.addEventListener("change"`,
+		// The weights row, the linked names and the deltas to the previous row
+		`<tr class="weights">`,
+		"<th>-1.00</th><th>1.00</th>",
+		`<a href="layouts/qwerty.html">qwerty</a>`,
+		`<span class="delta worse">`,
+		`<span class="delta same">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	// Each row has a board preview of its layout
+	if got := strings.Count(out, `<pre class="board">`); got != 2 {
+		t.Errorf("found %d board previews, want 2", got)
+	}
+	for _, score := range scores {
+		firstLine, _, _ := strings.Cut(score.Analyser.Layout.String(), "\n")
+		if !strings.Contains(out, `<pre class="board">`+firstLine) {
+			t.Errorf("board preview does not show the %s layout", score.Name)
+		}
+	}
+}
+
+func TestRenderRankingHTML_Reference(t *testing.T) {
+	opts := RankingDisplayOptions{
+		OutputFormat:   OutputHTML,
+		Weights:        kc.NewWeights(),
+		DeltasOption:   DeltasCustom,
+		BaseLayoutName: "qwerty",
+	}
+
+	var buf bytes.Buffer
+	if err := renderRankingHTML(&buf, rankingHTMLScores(t), []string{"SFB"}, opts); err != nil {
+		t.Fatalf("renderRankingHTML failed: %v", err)
+	}
+	out := buf.String()
+
+	// The reference layout is highlighted and ranked 0, without deltas of its own
+	if !strings.Contains(out, `<tr class="reference"><td data-sort="0">0</td>`) {
+		t.Errorf("reference layout is not highlighted and ranked 0")
+	}
+	if got := strings.Count(out, `<span class="delta`); got != 1 {
+		t.Errorf("found %d deltas, want 1", got)
+	}
+	if strings.Contains(out, `<tr class="weights">`) {
+		t.Errorf("weights row shown without ShowWeights")
+	}
+	if strings.Contains(out, "<a href=") {
+		t.Errorf("names linked without LinkBase")
+	}
+}
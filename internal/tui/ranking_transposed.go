@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// renderTransposedRanking renders a ranking with the metrics as rows and the layouts as
+// columns, which fits a few layouts with many metrics. In a table, the best value of each
+// metric is bold.
+func renderTransposedRanking(w io.Writer, scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) error {
+	if opts.OutputFormat == OutputCSV {
+		return renderTransposedCSV(w, scores, metrics, opts)
+	}
+	if opts.OutputFormat != OutputTable {
+		return fmt.Errorf("transposed rankings support table and csv output, not %s", opts.OutputFormat)
+	}
+
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Box.PaddingLeft = ""
+	tw.Style().Box.PaddingRight = ""
+	tw.Style().Title.Align = text.AlignLeft
+	// A few layouts make a narrow table, which would wrap the title
+	title := rankingTitle(opts)
+	tw.SetTitle(title)
+	tw.Style().Size.WidthMin = text.StringWidthWithoutEscSequences(title) + 2
+
+	header := transposedLabels("Metric", opts)
+	colConfigs := []table.ColumnConfig{{Number: 1, Align: text.AlignLeft}}
+	if opts.ShowWeights {
+		header[1] = "Weight"
+		colConfigs = append(colConfigs, table.ColumnConfig{Number: 2, Align: text.AlignRight})
+	}
+	for _, score := range scores {
+		header = append(header, score.Name)
+		colConfigs = append(colConfigs, table.ColumnConfig{
+			Number:      len(header),
+			Align:       text.AlignRight,
+			AlignHeader: text.AlignRight,
+		})
+	}
+	tw.SetColumnConfigs(colConfigs)
+	tw.AppendHeader(header)
+
+	rankRow, thumbRow, scoreRow := transposedLabels("#", opts), transposedLabels("Th", opts), transposedLabels("Score", opts)
+	for i, score := range scores {
+		rankRow = append(rankRow, i+1)
+		thumbRow = append(thumbRow, getThumbChars(&score))
+		scoreRow = append(scoreRow, fmt.Sprintf("%+.2f", score.Score))
+	}
+	tw.AppendRows([]table.Row{rankRow, thumbRow, scoreRow})
+	tw.AppendSeparator()
+
+	values := make([][]float64, len(scores))
+	for i := range scores {
+		values[i] = extractMetrics(&scores[i], metrics)
+	}
+	for j, metric := range metrics {
+		row := transposedLabels(metric, opts)
+		if opts.ShowWeights {
+			row[1] = fmt.Sprintf("%.2f", opts.Weights.Get(metric))
+		}
+		best := 0
+		for i := range scores {
+			if betterMetric(metric, values[i][j], values[best][j], opts.Weights) {
+				best = i
+			}
+		}
+		for i := range scores {
+			cell := formatMetricValue(metric, values[i][j])
+			if len(scores) > 1 && values[i][j] == values[best][j] {
+				cell = text.Bold.Sprint(cell)
+			}
+			row = append(row, cell)
+		}
+		tw.AppendRow(row)
+	}
+	_, err := fmt.Fprintln(w, tw.Render())
+	return err
+}
+
+// transposedLabels returns the first cells of a row of a transposed ranking: its label, and
+// an empty weight cell if weights are shown.
+func transposedLabels(label string, opts RankingDisplayOptions) table.Row {
+	if opts.ShowWeights {
+		return table.Row{label, ""}
+	}
+	return table.Row{label}
+}
+
+// renderTransposedCSV writes a ranking in CSV with the metrics as rows and the layouts as
+// columns, after rows with the rank, thumb keys and score of each layout.
+func renderTransposedCSV(w io.Writer, scores []kc.LayoutScore, metrics []string, opts RankingDisplayOptions) error {
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	labels := func(label string) []string {
+		if opts.ShowWeights {
+			return []string{label, ""}
+		}
+		return []string{label}
+	}
+	header := labels("Metric")
+	if opts.ShowWeights {
+		header[1] = "Weight"
+	}
+	rankRow, thumbRow, scoreRow := labels("Rank"), labels("Th"), labels("Score")
+	for i, score := range scores {
+		header = append(header, score.Name)
+		rankRow = append(rankRow, fmt.Sprintf("%d", i+1))
+		thumbRow = append(thumbRow, getThumbChars(&score))
+		scoreRow = append(scoreRow, fmt.Sprintf("%.2f", score.Score))
+	}
+	if err := writer.WriteAll([][]string{header, rankRow, thumbRow, scoreRow}); err != nil {
+		return fmt.Errorf("could not write csv header: %w", err)
+	}
+
+	values := make([][]float64, len(scores))
+	for i := range scores {
+		values[i] = extractMetrics(&scores[i], metrics)
+	}
+	for j, metric := range metrics {
+		row := labels(metric)
+		if opts.ShowWeights {
+			row[1] = fmt.Sprintf("%.2f", opts.Weights.Get(metric))
+		}
+		for i := range scores {
+			row = append(row, formatMetricValueCSV(metric, values[i][j]))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("could not write csv data row: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderRunComparison renders a comparison of two optimization runs: a summary of both runs,
+// the parameters that differ, their convergence curves, and the best layouts with the metrics
+// that differ between them.
+func RenderRunComparison(cmp *kc.RunComparison) {
+	runs := []*kc.Run{cmp.A, cmp.B}
+
+	summary := table.NewWriter()
+	summary.SetStyle(table.StyleRounded)
+	summary.SetTitle("Runs")
+	summary.AppendHeader(table.Row{"", "Run 1", "Run 2"})
+	summary.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+	})
+	summaryRow := func(label string, value func(r *kc.Run) string) {
+		summary.AppendRow(table.Row{label, value(runs[0]), value(runs[1])})
+	}
+	summaryRow("Directory", func(r *kc.Run) string { return r.Dir })
+	summaryRow("Layout", func(r *kc.Run) string { return r.Manifest.Layout })
+	summaryRow("Started", func(r *kc.Run) string { return r.Manifest.Started.Format(time.DateTime) })
+	summaryRow("Duration", func(r *kc.Run) string {
+		s := (time.Duration(r.Manifest.ElapsedMs) * time.Millisecond).Round(time.Second).String()
+		return kc.IfThen(r.Manifest.Interrupted, s+" (interrupted)", s)
+	})
+	summaryRow("New-best layouts", func(r *kc.Run) string { return Comma(len(r.History)) })
+	summaryRow("Initial cost", func(r *kc.Run) string { return fmt.Sprintf("%.4f", r.InitialCost()) })
+	summaryRow("Best cost", func(r *kc.Run) string { return fmt.Sprintf("%.4f", r.BestCost()) })
+	fmt.Println(summary.Render())
+
+	params := table.NewWriter()
+	params.SetStyle(table.StyleRounded)
+	params.SetTitle("Parameter differences")
+	params.AppendHeader(table.Row{"Parameter", "Run 1", "Run 2"})
+	for _, d := range cmp.ParamDiffs {
+		params.AppendRow(table.Row{d.Name, text.FgYellow.Sprint(paramValue(d.A)), text.FgYellow.Sprint(paramValue(d.B))})
+	}
+	if len(cmp.ParamDiffs) == 0 {
+		params.AppendRow(table.Row{"(none)", "", ""})
+	}
+	params.AppendFooter(table.Row{fmt.Sprintf("%d parameters are the same", cmp.SameParams), "", ""})
+	fmt.Println(params.Render())
+
+	fmt.Println()
+	fmt.Print(runsConvergenceASCII(historyCosts(cmp.A.History), historyCosts(cmp.B.History), 60, 15))
+	fmt.Println()
+
+	metricsA, metricsB := cmp.A.BestMetrics(), cmp.B.BestMetrics()
+	metrics := table.NewWriter()
+	metrics.SetStyle(table.StyleRounded)
+	metrics.AppendHeader(table.Row{"Metric", "Run 1", "Run 2", "Δ"})
+	metrics.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+	})
+	for _, metric := range cmp.Metrics {
+		a, b := metricsA[metric], metricsB[metric]
+		if math.Abs(b-a) < 0.005 {
+			continue
+		}
+		metrics.AppendRow(table.Row{metric, fmt.Sprintf("%.2f", a), fmt.Sprintf("%.2f", b), fmt.Sprintf("%+.2f", b-a)})
+	}
+	metrics.AppendFooter(table.Row{fmt.Sprintf("%d of %d differ", cmp.ChangedCount, len(cmp.Metrics)), "", "", ""})
+
+	outer := table.NewWriter()
+	outer.SetStyle(EmptyStyle())
+	outer.Style().Title.Align = text.AlignLeft
+	outer.SetTitle("Best layouts")
+	outer.AppendHeader(table.Row{cmp.A.Best.Name, cmp.B.Best.Name, "Metrics"})
+	outer.AppendRow(table.Row{SplitLayoutString(cmp.A.Best), SplitLayoutString(cmp.B.Best), metrics.Render()})
+	fmt.Println(outer.Render())
+}
+
+// paramValue returns a parameter value for display, marking a missing parameter.
+func paramValue(v string) string {
+	return kc.IfThen(v == "", "-", v)
+}
+
+// historyCosts returns the costs of the history entries by iteration. The cost of the initial
+// layout is left out if the run improved on it, since it would flatten the rest of the curve.
+func historyCosts(entries []kc.HistoryEntry) []historyPoint {
+	if len(entries) > 1 {
+		entries = entries[1:]
+	}
+	points := make([]historyPoint, len(entries))
+	for i, e := range entries {
+		points[i] = historyPoint{e.Iteration, e.Cost}
+	}
+	return points
+}
+
+// runsConvergenceASCII renders the cost curves of two runs in one step chart, drawing run 1
+// with '1' and run 2 with '2'. Columns where the curves overlap are drawn with '#'.
+func runsConvergenceASCII(a, b []historyPoint, width, height int) string {
+	xMin, xMax, yMin, yMax := historyBounds(append(append([]historyPoint{}, a...), b...))
+
+	grid := make([][]byte, height)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", width))
+	}
+	toCol := func(x int) int {
+		return int(math.Round(float64(x-xMin) / float64(xMax-xMin) * float64(width-1)))
+	}
+	toRow := func(y float64) int {
+		return height - 1 - int(math.Round((y-yMin)/(yMax-yMin)*float64(height-1)))
+	}
+	draw := func(points []historyPoint, ch byte) {
+		// Each cost holds until the next new-best layout
+		for i, p := range points {
+			end := width - 1
+			if i+1 < len(points) {
+				end = toCol(points[i+1].x) - 1
+			}
+			row := toRow(p.y)
+			for c := toCol(p.x); c <= end; c++ {
+				grid[row][c] = kc.IfThen(grid[row][c] == ' ' || grid[row][c] == ch, ch, '#')
+			}
+		}
+	}
+	draw(a, '1')
+	draw(b, '2')
+
+	var sb strings.Builder
+	sb.WriteString("Cost by iteration after the initial layout (1 = run 1, 2 = run 2, # = both)\n\n")
+	for r, line := range grid {
+		axis := "          "
+		switch r {
+		case 0:
+			axis = fmt.Sprintf("%10.4f", yMax)
+		case height - 1:
+			axis = fmt.Sprintf("%10.4f", yMin)
+		}
+		fmt.Fprintf(&sb, "%s |%s\n", axis, string(line))
+	}
+	fmt.Fprintf(&sb, "%10s +%s\n", "", strings.Repeat("-", width))
+	xMaxLabel := fmt.Sprintf("%d", xMax)
+	fmt.Fprintf(&sb, "%10s  %-*d%s\n", "", width-len(xMaxLabel), xMin, xMaxLabel)
+	return sb.String()
+}
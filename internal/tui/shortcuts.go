@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderShortcuts renders a summary of the shortcut metrics with one column per layout,
+// followed by the maxRows most frequent shortcuts (all if maxRows <= 0).
+func RenderShortcuts(set *kc.ShortcutSet, analyses []*kc.ShortcutAnalysis, maxRows int) error {
+	fmt.Println(shortcutSummaryTable(set, analyses).Render())
+	fmt.Println()
+	fmt.Println(shortcutDetailTable(set, analyses, maxRows).Render())
+	fmt.Println("Cells show the effort of each shortcut (lower is better): " +
+		"1H = one hand, SFC = same-finger chord, SFB = same-finger bigram, - = keys missing.")
+	return nil
+}
+
+// shortcutSummaryTable builds the table of summary metrics.
+func shortcutSummaryTable(set *kc.ShortcutSet, analyses []*kc.ShortcutAnalysis) table.Writer {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignLeft
+	tw.SetTitle(fmt.Sprintf("Shortcuts - %s (%d sequences)", set.Name, len(set.Shortcuts)))
+
+	header := table.Row{"Metric"}
+	colConfigs := []table.ColumnConfig{{Number: 1, Align: text.AlignLeft}}
+	for i, sa := range analyses {
+		header = append(header, sa.Layout.Name)
+		colConfigs = append(colConfigs, table.ColumnConfig{Number: i + 2, Align: text.AlignRight, AlignHeader: text.AlignRight})
+	}
+	tw.AppendHeader(header)
+	tw.SetColumnConfigs(colConfigs)
+
+	for _, metric := range kc.ShortcutMetrics {
+		row := table.Row{metric}
+		for _, sa := range analyses {
+			if metric == "COST" {
+				row = append(row, fmt.Sprintf("%.2f", sa.Metrics[metric]))
+			} else {
+				row = append(row, fmt.Sprintf("%.1f%%", sa.Metrics[metric]))
+			}
+		}
+		tw.AppendRow(row)
+	}
+	return tw
+}
+
+// shortcutDetailTable builds the table of the most frequent shortcuts.
+func shortcutDetailTable(set *kc.ShortcutSet, analyses []*kc.ShortcutAnalysis, maxRows int) table.Writer {
+	tw := table.NewWriter()
+	tw.SetAutoIndex(true)
+	tw.SetStyle(table.StyleRounded)
+
+	header := table.Row{"Shortcut", "Count"}
+	colConfigs := []table.ColumnConfig{{Number: 2, Align: text.AlignRight}}
+	for i, sa := range analyses {
+		header = append(header, sa.Layout.Name)
+		colConfigs = append(colConfigs, table.ColumnConfig{Number: i + 3, Align: text.AlignRight, AlignHeader: text.AlignRight})
+	}
+	tw.AppendHeader(header)
+	tw.SetColumnConfigs(colConfigs)
+
+	order := make([]int, len(set.Shortcuts))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch ca, cb := set.Shortcuts[a].Count, set.Shortcuts[b].Count; {
+		case ca > cb:
+			return -1
+		case ca < cb:
+			return 1
+		}
+		return 0
+	})
+	if maxRows > 0 && maxRows < len(order) {
+		order = order[:maxRows]
+	}
+
+	for _, i := range order {
+		sc := &set.Shortcuts[i]
+		row := table.Row{sc.Text, fmt.Sprintf("%g", sc.Count)}
+		for _, sa := range analyses {
+			row = append(row, shortcutCell(&sa.Results[i]))
+		}
+		tw.AppendRow(row)
+	}
+	return tw
+}
+
+// shortcutCell formats the effort of a shortcut with flags for its notable properties.
+func shortcutCell(res *kc.ShortcutResult) string {
+	if len(res.Missing) > 0 {
+		return text.FgHiBlack.Sprint("-")
+	}
+
+	var flags []string
+	if res.OneHand {
+		flags = append(flags, text.FgGreen.Sprint("1H"))
+	}
+	if res.SameFingerChords > 0 {
+		flags = append(flags, text.FgRed.Sprint("SFC"))
+	}
+	if res.SameFingerBigram > 0 {
+		flags = append(flags, text.FgRed.Sprint("SFB"))
+	}
+	cell := fmt.Sprintf("%.2f", res.Cost)
+	if len(flags) > 0 {
+		cell = strings.Join(flags, " ") + " " + cell
+	}
+	return cell
+}
@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// Output formats of the similarity command, besides OutputTable and OutputCSV (the matrix).
+const (
+	OutputLinkage OutputFormat = "linkage" // Merge steps of the clustering, as CSV
+)
+
+// RenderSimilarity renders the clusters of similar layouts as a table, the similarity matrix
+// as CSV, or the merge steps of the clustering as CSV, for drawing a dendrogram.
+func RenderSimilarity(result *kc.SimilarityResult, format OutputFormat) error {
+	switch format {
+	case OutputTable:
+		fmt.Printf("Compared %d layouts\n\n", len(result.Names))
+		tw := table.NewWriter()
+		tw.SetAutoIndex(true)
+		tw.SetStyle(table.StyleRounded)
+		tw.Style().Title.Align = text.AlignCenter
+		tw.SetTitle("Layout families (similarity of at least %.0f%%)", 100*result.Threshold)
+		tw.AppendHeader(table.Row{"Count", "Similarity", "Layouts"})
+		tw.SetColumnConfigs([]table.ColumnConfig{
+			{Number: 2, Align: text.AlignRight},
+			{Number: 3, WidthMax: 80},
+		})
+		var distinct []string
+		for _, c := range result.Clusters {
+			if len(c.Names) == 1 {
+				distinct = append(distinct, c.Names[0])
+				continue
+			}
+			tw.AppendRow(table.Row{len(c.Names), fmt.Sprintf("%.1f%%", 100*c.Similarity), strings.Join(c.Names, ", ")})
+		}
+		if tw.Length() > 0 {
+			fmt.Println(tw.Render())
+		} else {
+			fmt.Println("No layout families found.")
+		}
+		if len(distinct) > 0 {
+			fmt.Printf("\nLayouts without a family (%d):\n", len(distinct))
+			line := ""
+			for i, name := range distinct {
+				if i < len(distinct)-1 {
+					name += ","
+				}
+				if line != "" && len(line)+1+len(name) > 100 {
+					fmt.Println(line)
+					line = ""
+				}
+				line = strings.TrimPrefix(line+" "+name, " ")
+			}
+			fmt.Println(line)
+		}
+		return nil
+
+	case OutputCSV:
+		writer := csv.NewWriter(os.Stdout)
+		defer writer.Flush()
+		if err := writer.Write(append([]string{"layout"}, result.Names...)); err != nil {
+			return fmt.Errorf("could not write csv header: %w", err)
+		}
+		for i, name := range result.Names {
+			record := []string{name}
+			for _, sim := range result.Matrix[i] {
+				record = append(record, fmt.Sprintf("%.4f", sim))
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("could not write csv data row: %w", err)
+			}
+		}
+		return nil
+
+	case OutputLinkage:
+		writer := csv.NewWriter(os.Stdout)
+		defer writer.Flush()
+		if err := writer.Write([]string{"cluster", "left", "right", "similarity", "size", "left_name", "right_name"}); err != nil {
+			return fmt.Errorf("could not write csv header: %w", err)
+		}
+		n := len(result.Names)
+		name := func(c int) string {
+			if c < n {
+				return result.Names[c]
+			}
+			return ""
+		}
+		for k, m := range result.Merges {
+			record := []string{fmt.Sprint(n + k), fmt.Sprint(m.Left), fmt.Sprint(m.Right),
+				fmt.Sprintf("%.4f", m.Similarity), fmt.Sprint(m.Size), name(m.Left), name(m.Right)}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("could not write csv data row: %w", err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
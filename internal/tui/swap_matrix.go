@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// SwapMatrixOptions controls how a swap impact matrix is rendered.
+type SwapMatrixOptions struct {
+	OutputFormat OutputFormat // table (best/worst swaps), csv or html (full matrix)
+	Top          int          // Number of best and worst swaps to list or highlight
+}
+
+// RenderSwapMatrix writes the swap impact matrix to w in the requested format.
+func RenderSwapMatrix(w io.Writer, m *kc.SwapMatrix, opts SwapMatrixOptions) error {
+	var err error
+	switch opts.OutputFormat {
+	case OutputTable, "":
+		_, err = io.WriteString(w, swapImpactTables(m, opts.Top))
+	case OutputCSV:
+		err = writeSwapMatrixCSV(w, m)
+	case OutputHTML:
+		_, err = io.WriteString(w, swapMatrixHTML(m, opts.Top))
+	default:
+		return fmt.Errorf("unsupported output format: %s", opts.OutputFormat)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write swap matrix: %w", err)
+	}
+	return nil
+}
+
+// keyPosString names a key position: "r1c5" for rows r0-r2 (top, home, bottom)
+// and columns c0-c11, or "t2" for thumb keys t0-t5.
+func keyPosString(idx uint8) string {
+	if idx >= 36 {
+		return fmt.Sprintf("t%d", idx-36)
+	}
+	return fmt.Sprintf("r%dc%d", idx/12, idx%12)
+}
+
+// keyLabel names a key position together with its rune, e.g. "r1c5 'h'".
+func keyLabel(sl *kc.SplitLayout, idx uint8) string {
+	r := sl.Runes[idx]
+	if r == 0 {
+		return keyPosString(idx) + " (empty)"
+	}
+	return fmt.Sprintf("%s %q", keyPosString(idx), r)
+}
+
+// swapImpactTables renders the best and worst swaps as two tables.
+func swapImpactTables(m *kc.SwapMatrix, n int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Layout %s: cost %.4f (lower is better); gain > 0 improves the layout\n\n", m.Layout.Name, m.Cost)
+
+	tables := []struct {
+		title  string
+		swaps  []kc.SwapImpact
+		colour text.Color
+	}{
+		{fmt.Sprintf("Top %d beneficial swaps", n), m.Best(n), text.FgHiGreen},
+		{fmt.Sprintf("Top %d harmful swaps", n), m.Worst(n), text.FgHiRed},
+	}
+	for _, tb := range tables {
+		if len(tb.swaps) == 0 {
+			fmt.Fprintf(&sb, "%s: none\n\n", tb.title)
+			continue
+		}
+		tw := table.NewWriter()
+		tw.SetAutoIndex(true)
+		tw.SetStyle(table.StyleRounded)
+		tw.Style().Title.Align = text.AlignCenter
+		tw.SetTitle(tb.title)
+		tw.AppendHeader(table.Row{"Key 1", "Key 2", "Gain"})
+		for _, s := range tb.swaps {
+			tw.AppendRow(table.Row{
+				keyLabel(m.Layout, s.Idx1),
+				keyLabel(m.Layout, s.Idx2),
+				tb.colour.Sprintf("%+.4f", s.Delta),
+			})
+		}
+		sb.WriteString(tw.Render())
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// writeSwapMatrixCSV writes the full 42x42 matrix of score gains as CSV.
+// Cells without a swap (diagonal, empty keys) are left empty.
+func writeSwapMatrixCSV(w io.Writer, m *kc.SwapMatrix) error {
+	cw := csv.NewWriter(w)
+
+	header := make([]string, 0, 43)
+	header = append(header, "key")
+	for j := range uint8(42) {
+		header = append(header, keyLabel(m.Layout, j))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i := range uint8(42) {
+		record := make([]string, 0, 43)
+		record = append(record, keyLabel(m.Layout, i))
+		for j := range 42 {
+			if m.Valid[i][j] {
+				record = append(record, strconv.FormatFloat(m.Delta[i][j], 'f', 6, 64))
+			} else {
+				record = append(record, "")
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// swapMatrixHTML renders the matrix as a standalone HTML heat map. Beneficial swaps
+// are green and harmful swaps red, with intensity scaled to the largest gain or loss.
+// The top n swaps in either direction are outlined.
+func swapMatrixHTML(m *kc.SwapMatrix, n int) string {
+	highlight := make(map[[2]uint8]bool)
+	for _, s := range append(m.Best(n), m.Worst(n)...) {
+		highlight[[2]uint8{s.Idx1, s.Idx2}] = true
+		highlight[[2]uint8{s.Idx2, s.Idx1}] = true
+	}
+	maxAbs := math.Max(m.MaxAbsDelta(), 1e-12)
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>Swap impact: %s</title>\n", html.EscapeString(m.Layout.Name))
+	sb.WriteString(`<style>
+table.keycraft-swap-matrix { border-collapse: collapse; font-family: monospace; font-size: 11px; }
+table.keycraft-swap-matrix th, table.keycraft-swap-matrix td { border: 1px solid #ddd; padding: 2px 4px; text-align: right; }
+table.keycraft-swap-matrix td.top { outline: 2px solid black; font-weight: bold; }
+</style></head><body>
+`)
+	fmt.Fprintf(&sb, "<h1>Swap impact: %s</h1>\n", html.EscapeString(m.Layout.Name))
+	fmt.Fprintf(&sb, "<p>Cost %.4f (lower is better). Cells show the score gain of swapping two keys; "+
+		"green improves the layout, red makes it worse. The top %d swaps in either direction are outlined.</p>\n",
+		m.Cost, n)
+
+	sb.WriteString("<table class=\"keycraft-swap-matrix\">\n<tr><th></th>")
+	for j := range uint8(42) {
+		fmt.Fprintf(&sb, "<th>%s</th>", html.EscapeString(keyLabel(m.Layout, j)))
+	}
+	sb.WriteString("</tr>\n")
+
+	for i := range uint8(42) {
+		fmt.Fprintf(&sb, "<tr><th>%s</th>", html.EscapeString(keyLabel(m.Layout, i)))
+		for j := range uint8(42) {
+			if !m.Valid[i][j] {
+				sb.WriteString("<td></td>")
+				continue
+			}
+			d := m.Delta[i][j]
+			alpha := math.Abs(d) / maxAbs
+			colour := fmt.Sprintf("rgba(0,160,0,%.2f)", alpha)
+			if d < 0 {
+				colour = fmt.Sprintf("rgba(220,0,0,%.2f)", alpha)
+			}
+			class := ""
+			if highlight[[2]uint8{i, j}] {
+				class = ` class="top"`
+			}
+			title := html.EscapeString(fmt.Sprintf("%s <-> %s: %+.4f",
+				keyLabel(m.Layout, i), keyLabel(m.Layout, j), d))
+			fmt.Fprintf(&sb, `<td%s style="background:%s" title="%s">%.2f</td>`, class, colour, title, d)
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</table>\n</body></html>\n")
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderTuneResult renders the best parameter sets of a BLS parameter search, with the mean
+// and best cost of their probes, and recommends the best set. The default parameters are
+// always shown. Use rows 0 to show all sets.
+func RenderTuneResult(result *kc.TuneResult, rows int) {
+	var def kc.TuneTrial
+	for _, trial := range result.Trials {
+		if trial.Default {
+			def = trial
+		}
+	}
+
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.SetTitle(fmt.Sprintf("BLS parameter sets (initial cost %.4f)", result.InitialCost))
+	tw.AppendHeader(table.Row{"#", "L0", "T", "Tabu", "Random", "Mean cost", "Best cost", "Δ default"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, Align: text.AlignRight},
+		{Number: 2, Align: text.AlignRight},
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
+		{Number: 7, Align: text.AlignRight},
+		{Number: 8, Align: text.AlignRight},
+	})
+	for i, trial := range result.Trials {
+		if rows > 0 && i >= rows && !trial.Default {
+			continue
+		}
+		t := trial.Tuning
+		row := table.Row{i + 1, fmt.Sprintf("%g", t.L0), t.T, fmt.Sprintf("%g", t.Tabu), fmt.Sprintf("%g", t.RandomWeight),
+			fmt.Sprintf("%.4f", trial.Mean), fmt.Sprintf("%.4f", trial.Best), fmt.Sprintf("%+.4f", trial.Mean-def.Mean)}
+		if trial.Default {
+			for j := range row {
+				row[j] = text.FgYellow.Sprint(row[j])
+			}
+		}
+		tw.AppendRow(row)
+	}
+	fmt.Println(tw.Render())
+
+	fmt.Println("The default parameters are highlighted. L0 and Tabu are fractions of the free keys;")
+	fmt.Println("Random is the weight of random perturbation.")
+	best := result.Trials[0]
+	if best.Default {
+		fmt.Println("The default parameters performed best.")
+		return
+	}
+	fmt.Printf("Best parameters: --bls-params %s (mean cost %.4f lower than the defaults)\n",
+		best.Tuning, def.Mean-best.Mean)
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderVerify renders the failed checks, or all checks if all is true, followed by a
+// summary of the number of failures.
+func RenderVerify(spec *kc.VerifySpec, results []kc.VerifyResult, all bool) {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Title.Align = text.AlignLeft
+	tw.SetTitle(fmt.Sprintf("Verify - %s", spec.Corpus))
+	tw.AppendHeader(table.Row{"Layout", "Metric", "Expected", "Actual", "Diff", "Tolerance", "Result"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
+	})
+
+	failed := 0
+	for _, res := range results {
+		if !res.Pass {
+			failed++
+		}
+		if res.Pass && !all {
+			continue
+		}
+
+		actual, diff := "-", "-"
+		if !math.IsNaN(res.Actual) {
+			actual = fmt.Sprintf("%.4f", res.Actual)
+			diff = fmt.Sprintf("%+.4f", res.Actual-res.Expected)
+		}
+		result := text.FgGreen.Sprint("ok")
+		if !res.Pass {
+			result = text.FgRed.Sprint("FAIL")
+		}
+		tw.AppendRow(table.Row{res.Layout, res.Metric, fmt.Sprintf("%.4f", res.Expected),
+			actual, diff, fmt.Sprintf("%g", res.Tolerance), result})
+	}
+
+	if tw.Length() > 0 {
+		fmt.Println(tw.Render())
+	}
+	if failed == 0 {
+		fmt.Printf("All %d checks of %d layouts passed.\n", len(results), len(spec.Layouts()))
+	} else {
+		fmt.Printf("%d of %d checks failed.\n", failed, len(results))
+	}
+}
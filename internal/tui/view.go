@@ -76,13 +76,29 @@ func RenderView(result *kc.ViewResult) error {
 	}
 	twOuter.AppendHeader(h)
 
-	// Layout picture
+	// Layout picture, with the pinned keys marked
 	h = table.Row{"Board"}
-	for _, an := range result.Analysers {
-		h = append(h, SplitLayoutString(an.Layout))
+	for i, an := range result.Analysers {
+		if pins := layoutPins(result, i); pins != nil {
+			h[0] = "Board\n(* pinned)"
+			h = append(h, PinnedLayoutString(an.Layout, pins))
+		} else {
+			h = append(h, SplitLayoutString(an.Layout))
+		}
 	}
 	twOuter.AppendRow(h)
 
+	// How optimize produced the layouts, if any
+	h = table.Row{"Origin"}
+	hasProvenance := false
+	for _, an := range result.Analysers {
+		h = append(h, ProvenanceString(an.Layout.Provenance))
+		hasProvenance = hasProvenance || an.Layout.Provenance != nil
+	}
+	if hasProvenance {
+		twOuter.AppendRow(h)
+	}
+
 	// Hand load distribution
 	h = table.Row{"Hand"}
 	for _, an := range result.Analysers {
@@ -109,13 +125,93 @@ func RenderView(result *kc.ViewResult) error {
 	return nil
 }
 
+// layoutPins returns the pinned keys of the i-th layout of the result, if any.
+func layoutPins(result *kc.ViewResult, i int) *kc.PinnedKeys {
+	if i < len(result.Pins) {
+		return result.Pins[i]
+	}
+	return nil
+}
+
 // SplitLayoutString returns a formatted ASCII representation of a keyboard layout.
 func SplitLayoutString(sl *kc.SplitLayout) string {
-	switch sl.LayoutType {
+	return PinnedLayoutString(sl, nil)
+}
+
+// PinnedLayoutString returns a formatted ASCII representation of a keyboard layout, with a
+// '*' after the character of each pinned key. Empty keys are not marked. The number row of
+// the layout, if any, is listed above the board.
+func PinnedLayoutString(sl *kc.SplitLayout, pins *kc.PinnedKeys) string {
+	var cells [42]string
+	for i, r := range sl.Runes {
+		marker := " "
+		if pins != nil && pins[i] {
+			marker = "*"
+		}
+		switch r {
+		case 0:
+			cells[i] = " "
+		case ' ':
+			cells[i] = " _" + marker
+		default:
+			cells[i] = string(r) + marker
+		}
+	}
+	board := boardString(sl.LayoutType, cells)
+	if sl.NumberRow != nil {
+		return numberRowString(sl.NumberRow) + "\n" + board
+	}
+	return board
+}
+
+// numberRowString renders the number row of a layout as a line of keys, with a gap between
+// the hands.
+func numberRowString(row *[12]rune) string {
+	var sb strings.Builder
+	sb.WriteString("Numbers:")
+	for col, r := range row {
+		if col == 6 {
+			sb.WriteString(" ")
+		}
+		switch r {
+		case 0:
+			sb.WriteString("  ")
+		case ' ':
+			sb.WriteString(" _")
+		default:
+			sb.WriteString(" " + string(r))
+		}
+	}
+	return sb.String()
+}
+
+// ProvenanceString renders where a layout was optimized from and with what seed, corpus
+// and weights, or "" if that is unknown.
+func ProvenanceString(p *kc.Provenance) string {
+	if p == nil {
+		return ""
+	}
+	lines := []string{
+		"Optimized from: " + p.Source,
+		fmt.Sprintf("Seed: %d", p.Seed),
+	}
+	if p.Corpus != "" {
+		lines = append(lines, "Corpus: "+p.Corpus)
+	}
+	if p.Weights != "" {
+		lines = append(lines, "Weights: "+text.WrapSoft(p.Weights, 40))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// boardString renders one cell of at most 3 characters per key position, using
+// the ASCII template for the layout type.
+func boardString(layoutType kc.LayoutType, cells [42]string) string {
+	switch layoutType {
 	case kc.ANGLEMOD:
-		return genLayoutStringFor(sl, anglemodTempl, nil)
+		return genLayoutStringFor(cells, anglemodTempl, nil)
 	case kc.ORTHO:
-		return genLayoutStringFor(sl, orthoTempl, nil)
+		return genLayoutStringFor(cells, orthoTempl, nil)
 	case kc.COLSTAG:
 		mapper := [42]int{
 			2, 3, 4, 7, 8, 9,
@@ -127,29 +223,20 @@ func SplitLayoutString(sl *kc.SplitLayout) string {
 			36, 37, 40, 41,
 			38, 39,
 		}
-		return genLayoutStringFor(sl, colstagTempl, mapper[:])
+		return genLayoutStringFor(cells, colstagTempl, mapper[:])
 	default: // kc.ROWSTAG
-		return genLayoutStringFor(sl, rowstagTempl, nil)
+		return genLayoutStringFor(cells, rowstagTempl, nil)
 	}
 }
 
-// genLayoutStringFor applies runes to an ASCII template, optionally reordering via mapper.
-func genLayoutStringFor(sl *kc.SplitLayout, template string, mapper []int) string {
-	args := make([]any, len(sl.Runes))
-	for i, r := range sl.Runes {
-		var m rune
+// genLayoutStringFor applies cells to an ASCII template, optionally reordering via mapper.
+func genLayoutStringFor(cells [42]string, template string, mapper []int) string {
+	args := make([]any, len(cells))
+	for i := range cells {
 		if mapper != nil {
-			m = sl.Runes[mapper[i]]
+			args[i] = cells[mapper[i]]
 		} else {
-			m = r
-		}
-		switch m {
-		case 0:
-			args[i] = " "
-		case ' ':
-			args[i] = " _ "
-		default:
-			args[i] = string(m) + " "
+			args[i] = cells[i]
 		}
 	}
 	return fmt.Sprintf(strings.ReplaceAll(template, " ", "\u00A0"), args...)
@@ -287,6 +374,22 @@ func MetricsString(an *kc.Analyser) string {
 			fmt.Sprintf("POH: %.2f%%", an.Metrics["POH"]),
 		},
 	}
+	if an.Layout.NumberRow != nil {
+		data = append(data, table.Row{
+			fmt.Sprintf("NUM: %.2f%%", an.Metrics["NUM"]),
+			fmt.Sprintf(".SFB: %.2f%%", an.Metrics["NUM-SFB"]),
+			fmt.Sprintf(".MISS: %.2f%%", an.Metrics["NUM-MISS"]),
+			"",
+		})
+	}
+	if an.Layout.Magic != nil {
+		data = append(data, table.Row{
+			fmt.Sprintf("MAG: %.2f%%", an.Metrics["MAGIC"]),
+			fmt.Sprintf(".SFB: %.2f%%", an.Metrics["MAGIC-SFB"]),
+			fmt.Sprintf(".SFS: %.2f%%", an.Metrics["MAGIC-SFS"]),
+			"",
+		})
+	}
 	tw.AppendRows(data)
 
 	return tw.Render()
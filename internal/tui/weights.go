@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	kc "github.com/rbscholtus/keycraft/internal/keycraft"
+)
+
+// RenderWeightFit renders the learned weights next to the scores of the preferred and
+// avoided layouts under those weights.
+func RenderWeightFit(fit *kc.WeightFit) {
+	weights := table.NewWriter()
+	weights.SetStyle(table.StyleRounded)
+	weights.AppendHeader(table.Row{"Metric", "Weight"})
+	weights.SetColumnConfigs([]table.ColumnConfig{{Number: 2, Align: text.AlignRight}})
+	for _, metric := range fit.Metrics {
+		weights.AppendRow(table.Row{metric, fmt.Sprintf("%.3f", fit.Weights.Get(metric))})
+	}
+
+	scores := table.NewWriter()
+	scores.SetStyle(table.StyleRounded)
+	scores.AppendHeader(table.Row{"Layout", "Group", "Score"})
+	scores.SetColumnConfigs([]table.ColumnConfig{{Number: 3, Align: text.AlignRight}})
+	for _, ls := range fit.Preferred {
+		scores.AppendRow(table.Row{ls.Name, text.FgGreen.Sprint("prefer"), fmt.Sprintf("%+.2f", ls.Score)})
+	}
+	scores.AppendSeparator()
+	for _, ls := range fit.Avoided {
+		scores.AppendRow(table.Row{ls.Name, text.FgRed.Sprint("avoid"), fmt.Sprintf("%+.2f", ls.Score)})
+	}
+
+	outer := table.NewWriter()
+	outer.SetStyle(EmptyStyle())
+	outer.Style().Title.Align = text.AlignLeft
+	outer.SetTitle("Learned weights")
+	outer.AppendRow(table.Row{weights.Render(), scores.Render()})
+	fmt.Println(outer.Render())
+
+	if fit.Ordered == fit.Pairs {
+		fmt.Printf("All %d preferred layouts outscore all %d avoided layouts.\n", len(fit.Preferred), len(fit.Avoided))
+	} else {
+		fmt.Printf("%d of %d (preferred, avoided) layout pairs are ordered as preferred; "+
+			"no weights for these metrics separate the groups completely.\n", fit.Ordered, fit.Pairs)
+	}
+}
+
+// RenderTimingFit renders the measured latency and cost of each class of bigrams, and the
+// weights fitted to them.
+func RenderTimingFit(fit *kc.TimingFit) {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.SetTitle("Personal bigram costs")
+	tw.AppendHeader(table.Row{"Class", "Bigrams", "Samples", "Mean ms", "Cost ms", "Weight"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight}, {Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight}, {Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
+	})
+	for _, c := range fit.Classes {
+		cost, weight := fmt.Sprintf("%+.1f", c.CostMs), fmt.Sprintf("%.3f", c.Weight)
+		if c.Class == "ALT" {
+			cost, weight = "baseline", ""
+		}
+		tw.AppendRow(table.Row{c.Class, c.Bigrams, Comma(c.Count), fmt.Sprintf("%.1f", c.Ms), cost, weight})
+	}
+	fmt.Println(tw.Render())
+	if fit.Unsupported > 0 {
+		fmt.Printf("%d measured bigrams have a character that is not on the layout, and were left out.\n", fit.Unsupported)
+	}
+}
+
+// RenderWeightReport renders the effective influence of each weight on the scores, with the
+// weights that the scorer ignores in faint at the bottom.
+func RenderWeightReport(report *kc.WeightReport) {
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleRounded)
+	tw.SetTitle(fmt.Sprintf("Influence of the weights, normalised by %d reference layouts", report.References))
+	weightHeader := kc.IfThen(report.Normalised, "Weight (normalised)", "Weight")
+	tw.AppendHeader(table.Row{"Metric", weightHeader, "IQR", "Spread", "Influence", "Share", "Note"})
+	tw.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight}, {Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight}, {Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
+	})
+	ignored := 0
+	for _, m := range report.Metrics {
+		if m.Ignored != "" {
+			if ignored == 0 {
+				tw.AppendSeparator()
+			}
+			ignored++
+			tw.AppendRow(table.Row{text.Faint.Sprint(m.Metric), text.Faint.Sprintf("%.3f", m.Weight),
+				text.Faint.Sprintf("%.3f", m.IQR), "", "", "", text.FgYellow.Sprint("ignored: " + m.Ignored)})
+			continue
+		}
+		tw.AppendRow(table.Row{m.Metric, fmt.Sprintf("%.3f", m.Weight), fmt.Sprintf("%.3f", m.IQR),
+			fmt.Sprintf("%.2f", m.Spread), fmt.Sprintf("%.3f", m.Influence), fmt.Sprintf("%.1f%%", m.Share), ""})
+	}
+	fmt.Println(tw.Render())
+	fmt.Println("Spread is the mean distance of the reference layouts from the median, in IQRs; " +
+		"influence is |weight| × spread.")
+	if ignored > 0 {
+		fmt.Printf("%d weighted metrics are ignored by the scorer, so their weights have no effect.\n", ignored)
+	}
+}